@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -12,23 +13,57 @@ import (
 	"commercetools-replica/internal/config"
 	"commercetools-replica/internal/db"
 	"commercetools-replica/internal/httpserver"
+	"commercetools-replica/internal/mailer"
+	associaterolerepo "commercetools-replica/internal/repository/associaterole"
+	businessunitrepo "commercetools-replica/internal/repository/businessunit"
 	cartrepo "commercetools-replica/internal/repository/cart"
+	cartdiscountrepo "commercetools-replica/internal/repository/cartdiscount"
 	categoryrepo "commercetools-replica/internal/repository/category"
+	channelrepo "commercetools-replica/internal/repository/channel"
 	customerrepo "commercetools-replica/internal/repository/customer"
+	customergrouprepo "commercetools-replica/internal/repository/customergroup"
+	discountcoderepo "commercetools-replica/internal/repository/discountcode"
+	inventoryrepo "commercetools-replica/internal/repository/inventory"
+	loginattemptrepo "commercetools-replica/internal/repository/loginattempt"
+	orderrepo "commercetools-replica/internal/repository/order"
 	productrepo "commercetools-replica/internal/repository/product"
+	productdiscountrepo "commercetools-replica/internal/repository/productdiscount"
+	productselectionrepo "commercetools-replica/internal/repository/productselection"
+	producttyperepo "commercetools-replica/internal/repository/producttype"
 	projectrepo "commercetools-replica/internal/repository/project"
+	shippingmethodrepo "commercetools-replica/internal/repository/shippingmethod"
+	storerepo "commercetools-replica/internal/repository/store"
+	taxcategoryrepo "commercetools-replica/internal/repository/taxcategory"
 	tokenrepo "commercetools-replica/internal/repository/token"
+	zonerepo "commercetools-replica/internal/repository/zone"
+	adminsvc "commercetools-replica/internal/service/admin"
 	anonymoussvc "commercetools-replica/internal/service/anonymous"
+	associaterolesvc "commercetools-replica/internal/service/associaterole"
+	businessunitsvc "commercetools-replica/internal/service/businessunit"
 	cartsvc "commercetools-replica/internal/service/cart"
+	cartdiscountsvc "commercetools-replica/internal/service/cartdiscount"
 	categorysvc "commercetools-replica/internal/service/category"
+	channelsvc "commercetools-replica/internal/service/channel"
 	customersvc "commercetools-replica/internal/service/customer"
+	customergroupsvc "commercetools-replica/internal/service/customergroup"
+	discountcodesvc "commercetools-replica/internal/service/discountcode"
+	inventorysvc "commercetools-replica/internal/service/inventory"
+	ordersvc "commercetools-replica/internal/service/order"
+	privacysvc "commercetools-replica/internal/service/privacy"
 	productsvc "commercetools-replica/internal/service/product"
+	productdiscountsvc "commercetools-replica/internal/service/productdiscount"
+	productselectionsvc "commercetools-replica/internal/service/productselection"
+	producttypesvc "commercetools-replica/internal/service/producttype"
+	shippingmethodsvc "commercetools-replica/internal/service/shippingmethod"
+	storesvc "commercetools-replica/internal/service/store"
+	taxcategorysvc "commercetools-replica/internal/service/taxcategory"
+	zonesvc "commercetools-replica/internal/service/zone"
 )
 
 func main() {
 	cfg := config.FromEnv()
 	logger := log.New(os.Stdout, "[api] ", log.LstdFlags|log.LUTC|log.Lshortfile)
-	logger.Println(">>>>>>> config %v", cfg)
+	logger.Printf("config http_addr=%s file_url_host=%q", cfg.HTTPAddr, cfg.FileURLHost)
 
 	ctx := context.Background()
 	dbpool, err := db.Connect(ctx, cfg.DBConnString)
@@ -39,23 +74,93 @@ func main() {
 
 	projectRepo := projectrepo.NewPostgres(dbpool, logger)
 	productRepo := productrepo.NewPostgres(dbpool, logger)
-	productService := productsvc.New(productRepo)
-	cartRepo := cartrepo.NewPostgres(dbpool)
-	cartService := cartsvc.New(cartRepo, productRepo)
 	categoryRepo := categoryrepo.NewPostgres(dbpool)
 	categoryService := categorysvc.New(categoryRepo)
+	productTypeRepo := producttyperepo.NewPostgres(dbpool)
+	productTypeService := producttypesvc.New(productTypeRepo)
+	taxCategoryRepo := taxcategoryrepo.NewPostgres(dbpool)
+	taxCategoryService := taxcategorysvc.New(taxCategoryRepo)
+	zoneRepo := zonerepo.NewPostgres(dbpool)
+	zoneService := zonesvc.New(zoneRepo)
+	shippingMethodService := shippingmethodsvc.New(shippingmethodrepo.NewPostgres(dbpool), taxCategoryRepo, zoneRepo)
+	customerGroupRepo := customergrouprepo.NewPostgres(dbpool)
+	customerGroupService := customergroupsvc.New(customerGroupRepo)
+	productService := productsvc.New(productRepo, categoryRepo, productTypeRepo, taxCategoryRepo, customerGroupRepo)
+	productDiscountService := productdiscountsvc.New(productdiscountrepo.NewPostgres(dbpool))
+	cartDiscountService := cartdiscountsvc.New(cartdiscountrepo.NewPostgres(dbpool))
+	discountCodeService := discountcodesvc.New(discountcoderepo.NewPostgres(dbpool), cartDiscountService)
+	channelRepo := channelrepo.NewPostgres(dbpool)
+	channelService := channelsvc.New(channelRepo)
+	productSelectionRepo := productselectionrepo.NewPostgres(dbpool)
+	productSelectionService := productselectionsvc.New(productSelectionRepo, productRepo)
+	storeService := storesvc.New(storerepo.NewPostgres(dbpool), channelRepo, productSelectionRepo)
+	cartRepo := cartrepo.NewPostgres(dbpool)
 	customerRepo := customerrepo.NewPostgres(dbpool, logger)
+	associateRoleRepo := associaterolerepo.NewPostgres(dbpool)
+	associateRoleService := associaterolesvc.New(associateRoleRepo)
+	businessUnitService := businessunitsvc.New(businessunitrepo.NewPostgres(dbpool), associateRoleRepo, customerRepo)
+	cartService := cartsvc.New(cartRepo, productRepo, productDiscountService, cartDiscountService, discountCodeService, customerRepo, taxCategoryRepo, shippingMethodService, zoneService, storeService).
+		WithBusinessUnits(businessUnitService)
+	inventoryService := inventorysvc.New(inventoryrepo.NewPostgres(dbpool), channelRepo)
+	orderRepo := orderrepo.NewPostgres(dbpool)
+	orderService := ordersvc.New(orderRepo, cartService)
 	tokenRepo := tokenrepo.NewPostgres(dbpool)
-	customerService := customersvc.New(customerRepo, tokenRepo)
+	outbox, err := newOutbox(cfg)
+	if err != nil {
+		logger.Fatalf("init mailer: %v", err)
+	}
+	logger.Printf("mail sink=%q", cfg.MailSink)
+	hashing := customersvc.PasswordHashing{
+		Algorithm:     cfg.PasswordHash,
+		BcryptCost:    cfg.BcryptCost,
+		Argon2Memory:  uint32(cfg.Argon2MemoryKiB),
+		Argon2Time:    uint32(cfg.Argon2Iterations),
+		Argon2Threads: uint8(cfg.Argon2Threads),
+	}
+	if err := hashing.Validate(); err != nil {
+		logger.Fatalf("password hashing: %v", err)
+	}
+	customerService := customersvc.New(customerRepo, tokenRepo, customerGroupRepo, outbox).
+		WithPasswordHashing(hashing).
+		WithPasswordPolicies(projectRepo, cfg.PasswordDenylistFile).
+		WithLoginThrottle(loginattemptrepo.NewPostgres(dbpool), customersvc.LoginPolicy{
+			FreeAttempts:  cfg.LoginFreeAttempts,
+			BackoffBase:   cfg.LoginBackoffBase,
+			BackoffMax:    cfg.LoginBackoffMax,
+			MaxAttempts:   cfg.LoginMaxAttempts,
+			IPMaxAttempts: cfg.LoginIPMaxAttempts,
+			Lockout:       cfg.LoginLockout,
+			Window:        cfg.LoginAttemptWindow,
+		})
 	anonymousService := anonymoussvc.New(tokenRepo)
+	adminService := adminsvc.New(tokenRepo, cfg.AdminClientID, cfg.AdminClientSecret)
+	privacyService := privacysvc.New(customerRepo, cartRepo, orderRepo, tokenRepo)
 
 	srv, err := httpserver.New(cfg.HTTPAddr, logger, dbpool, httpserver.Deps{
-		ProjectRepo:  projectRepo,
-		ProductSvc:   productService,
-		CartSvc:      cartService,
-		CategorySvc:  categoryService,
-		CustomerSvc:  customerService,
-		AnonymousSvc: anonymousService,
+		ProjectRepo:         projectRepo,
+		ProductSvc:          productService,
+		ProductTypeSvc:      productTypeService,
+		ProductDiscountSvc:  productDiscountService,
+		ProductSelectionSvc: productSelectionService,
+		CartDiscountSvc:     cartDiscountService,
+		DiscountCodeSvc:     discountCodeService,
+		TaxCategorySvc:      taxCategoryService,
+		CustomerGroupSvc:    customerGroupService,
+		ZoneSvc:             zoneService,
+		ShippingMethodSvc:   shippingMethodService,
+		InventorySvc:        inventoryService,
+		OrderSvc:            orderService,
+		ChannelSvc:          channelService,
+		StoreSvc:            storeService,
+		CartSvc:             cartService,
+		CategorySvc:         categoryService,
+		CustomerSvc:         customerService,
+		AnonymousSvc:        anonymousService,
+		AdminSvc:            adminService,
+		PrivacySvc:          privacyService,
+		BusinessUnitSvc:     businessUnitService,
+		AssociateRoleSvc:    associateRoleService,
+		TrustedProxies:      cfg.TrustedProxies,
 	}, cfg.FileURLHost)
 	if err != nil {
 		logger.Fatalf("init server: %v", err)
@@ -90,3 +195,34 @@ func main() {
 
 	// TODO: wire DB connection and dependency injection when repositories/services are added.
 }
+
+// newOutbox builds the mailer selected by MAIL_SINK; without one, tokens are
+// only returned by the API.
+func newOutbox(cfg config.Config) (*mailer.Outbox, error) {
+	var sink mailer.Mailer
+	switch cfg.MailSink {
+	case "":
+		return nil, nil
+	case "file":
+		f, err := mailer.NewFile(cfg.MailDir)
+		if err != nil {
+			return nil, err
+		}
+		sink = f
+	case "smtp":
+		smtpSink, err := mailer.NewSMTP(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword)
+		if err != nil {
+			return nil, err
+		}
+		sink = smtpSink
+	default:
+		return nil, fmt.Errorf("unknown MAIL_SINK %q", cfg.MailSink)
+	}
+	templates := mailer.NewTemplates()
+	if cfg.MailTemplateDir != "" {
+		if err := templates.LoadDir(cfg.MailTemplateDir); err != nil {
+			return nil, err
+		}
+	}
+	return mailer.NewOutbox(sink, templates, cfg.MailFrom), nil
+}
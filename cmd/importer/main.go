@@ -23,7 +23,9 @@ import (
 	"commercetools-replica/internal/migrate"
 	category "commercetools-replica/internal/repository/category"
 	"commercetools-replica/internal/repository/product"
+	"commercetools-replica/internal/repository/producttype"
 	"commercetools-replica/internal/repository/project"
+	"commercetools-replica/internal/repository/taxcategory"
 )
 
 func main() {
@@ -77,19 +79,23 @@ func main() {
 
 	productRepo := product.NewPostgres(pool, logger)
 	categoryRepo := category.NewPostgres(pool)
+	productTypeRepo := producttype.NewPostgres(pool)
+	taxCategoryRepo := taxcategory.NewPostgres(pool)
 	mediaRoot := envOrDefault("MEDIA_ROOT", "media")
 	mediaBaseURL := envOrDefault("MEDIA_BASE_URL", "media")
 	archiveDir := archiveDirForInput(inputPath)
 
 	if info.IsDir() {
 		runner := importerRunner{
-			projectKey:   projectKey,
-			projectID:    proj.ID,
-			productRepo:  productRepo,
-			categoryRepo: categoryRepo,
-			mediaRoot:    mediaRoot,
-			mediaBaseURL: mediaBaseURL,
-			archiveDir:   archiveDir,
+			projectKey:    projectKey,
+			projectID:     proj.ID,
+			productRepo:   productRepo,
+			categoryRepo:  categoryRepo,
+			productTypes:  productTypeRepo,
+			taxCategories: taxCategoryRepo,
+			mediaRoot:     mediaRoot,
+			mediaBaseURL:  mediaBaseURL,
+			archiveDir:    archiveDir,
 		}
 		if err := runner.importDirectory(ctx, inputPath); err != nil {
 			log.Fatalf("import directory: %v", err)
@@ -98,13 +104,15 @@ func main() {
 	}
 
 	runner := importerRunner{
-		projectKey:   projectKey,
-		projectID:    proj.ID,
-		productRepo:  productRepo,
-		categoryRepo: categoryRepo,
-		mediaRoot:    mediaRoot,
-		mediaBaseURL: mediaBaseURL,
-		archiveDir:   archiveDir,
+		projectKey:    projectKey,
+		projectID:     proj.ID,
+		productRepo:   productRepo,
+		categoryRepo:  categoryRepo,
+		productTypes:  productTypeRepo,
+		taxCategories: taxCategoryRepo,
+		mediaRoot:     mediaRoot,
+		mediaBaseURL:  mediaBaseURL,
+		archiveDir:    archiveDir,
 	}
 	count, kind, dur, err := runner.importFile(ctx, inputPath)
 	if err != nil {
@@ -127,13 +135,15 @@ func ensureProject(ctx context.Context, repo project.Repository, key string) (*d
 }
 
 type importerRunner struct {
-	projectKey   string
-	projectID    string
-	productRepo  importer.ProductWriter
-	categoryRepo importer.CategoryWriter
-	mediaRoot    string
-	mediaBaseURL string
-	archiveDir   string
+	projectKey    string
+	projectID     string
+	productRepo   importer.ProductWriter
+	categoryRepo  importer.CategoryWriter
+	productTypes  importer.ProductTypeStore
+	taxCategories importer.TaxCategoryStore
+	mediaRoot     string
+	mediaBaseURL  string
+	archiveDir    string
 }
 
 func (r importerRunner) importDirectory(ctx context.Context, dir string) error {
@@ -189,7 +199,7 @@ func (r importerRunner) importFileCore(ctx context.Context, path string) (int, s
 	}
 	defer f.Close()
 
-	imp := importer.NewCSVImporter(f, r.productRepo, r.categoryRepo, r.projectID, r.projectKey, importer.WithMedia(r.mediaRoot, r.mediaBaseURL))
+	imp := importer.NewCSVImporter(f, r.productRepo, r.categoryRepo, r.projectID, r.projectKey, importer.WithMedia(r.mediaRoot, r.mediaBaseURL), importer.WithProductTypes(r.productTypes), importer.WithTaxCategories(r.taxCategories))
 
 	start := time.Now()
 	count, err := imp.Run(ctx)
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"commercetools-replica/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Querier is the QueryRow part of a pool, connection or transaction.
+type Querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// IsUniqueViolation reports whether err is a unique constraint violation.
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// IsForeignKeyViolation reports whether err is a foreign key violation.
+func IsForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
+
+// MissingOrStale tells a version mismatch apart from a missing row after a
+// conditional write matched no row. exists is a SELECT EXISTS query taking
+// the project id and the row id.
+func MissingOrStale(ctx context.Context, q Querier, exists, projectID, id string) error {
+	var found bool
+	if err := q.QueryRow(ctx, exists, projectID, id).Scan(&found); err != nil {
+		return err
+	}
+	if found {
+		return domain.ErrConcurrentModification
+	}
+	return domain.ErrNotFound
+}
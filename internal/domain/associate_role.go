@@ -0,0 +1,55 @@
+package domain
+
+import "time"
+
+// Associate permissions. Only the cart permissions are enforced, by the
+// as-associate cart routes; the others can be assigned for compatibility.
+const (
+	PermissionAddChildUnits             = "AddChildUnits"
+	PermissionUpdateBusinessUnitDetails = "UpdateBusinessUnitDetails"
+	PermissionUpdateAssociates          = "UpdateAssociates"
+	PermissionUpdateParentUnit          = "UpdateParentUnit"
+	PermissionCreateMyCarts             = "CreateMyCarts"
+	PermissionCreateOthersCarts         = "CreateOthersCarts"
+	PermissionUpdateMyCarts             = "UpdateMyCarts"
+	PermissionUpdateOthersCarts         = "UpdateOthersCarts"
+	PermissionDeleteMyCarts             = "DeleteMyCarts"
+	PermissionDeleteOthersCarts         = "DeleteOthersCarts"
+	PermissionViewMyCarts               = "ViewMyCarts"
+	PermissionViewOthersCarts           = "ViewOthersCarts"
+	PermissionCreateMyOrdersFromMyCarts = "CreateMyOrdersFromMyCarts"
+	PermissionViewMyOrders              = "ViewMyOrders"
+	PermissionViewOthersOrders          = "ViewOthersOrders"
+)
+
+// Permissions lists every permission a role can have.
+var Permissions = []string{
+	PermissionAddChildUnits, PermissionUpdateBusinessUnitDetails, PermissionUpdateAssociates, PermissionUpdateParentUnit,
+	PermissionCreateMyCarts, PermissionCreateOthersCarts, PermissionUpdateMyCarts, PermissionUpdateOthersCarts,
+	PermissionDeleteMyCarts, PermissionDeleteOthersCarts, PermissionViewMyCarts, PermissionViewOthersCarts,
+	PermissionCreateMyOrdersFromMyCarts, PermissionViewMyOrders, PermissionViewOthersOrders,
+}
+
+// AssociateRole is a named set of permissions that associates of business
+// units are given.
+type AssociateRole struct {
+	ID              string    `json:"id"`
+	ProjectID       string    `json:"-"`
+	Key             string    `json:"key"`
+	Version         int       `json:"version"`
+	Name            string    `json:"name,omitempty"`
+	BuyerAssignable bool      `json:"buyerAssignable"`
+	Permissions     []string  `json:"permissions"`
+	CreatedAt       time.Time `json:"createdAt"`
+	LastModifiedAt  time.Time `json:"lastModifiedAt"`
+}
+
+// HasPermission reports whether the role grants permission.
+func (r AssociateRole) HasPermission(permission string) bool {
+	for _, p := range r.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package domain
+
+import "time"
+
+// Business unit types and states.
+const (
+	BusinessUnitCompany  = "Company"
+	BusinessUnitDivision = "Division"
+
+	BusinessUnitActive   = "Active"
+	BusinessUnitInactive = "Inactive"
+)
+
+// Associate modes decide whether a division also takes the associates of its
+// parent units; companies are always Explicit.
+const (
+	AssociateModeExplicit              = "Explicit"
+	AssociateModeExplicitAndFromParent = "ExplicitAndFromParent"
+)
+
+// Inheritance of a role assignment: Enabled roles also apply in divisions
+// that take associates from their parent.
+const (
+	InheritanceEnabled  = "Enabled"
+	InheritanceDisabled = "Disabled"
+)
+
+// BusinessUnit is a company or one of its divisions. ParentUnitID is empty
+// for companies; TopLevelUnitID is the company of a division's tree.
+type BusinessUnit struct {
+	ID                       string            `json:"id"`
+	ProjectID                string            `json:"-"`
+	Key                      string            `json:"key"`
+	Version                  int               `json:"version"`
+	UnitType                 string            `json:"unitType"`
+	Name                     string            `json:"name"`
+	ContactEmail             string            `json:"contactEmail,omitempty"`
+	Status                   string            `json:"status"`
+	AssociateMode            string            `json:"associateMode"`
+	Addresses                []CustomerAddress `json:"addresses"`
+	DefaultShippingAddressID string            `json:"defaultShippingAddressId,omitempty"`
+	DefaultBillingAddressID  string            `json:"defaultBillingAddressId,omitempty"`
+	ParentUnitID             string            `json:"parentUnitId,omitempty"`
+	ParentUnitKey            string            `json:"parentUnitKey,omitempty"`
+	TopLevelUnitID           string            `json:"topLevelUnitId,omitempty"`
+	TopLevelUnitKey          string            `json:"topLevelUnitKey"`
+	Associates               []Associate       `json:"associates"`
+	CreatedAt                time.Time         `json:"createdAt"`
+	LastModifiedAt           time.Time         `json:"lastModifiedAt"`
+}
+
+// Associate is a customer acting for a business unit with one or more roles.
+type Associate struct {
+	CustomerID      string                    `json:"customerId"`
+	RoleAssignments []AssociateRoleAssignment `json:"associateRoleAssignments"`
+}
+
+// AssociateRoleAssignment gives an associate the role with RoleKey.
+type AssociateRoleAssignment struct {
+	RoleKey     string `json:"associateRoleKey"`
+	Inheritance string `json:"inheritance"`
+}
+
+// Associate returns the associate of the unit that is the customer, if any.
+func (u BusinessUnit) Associate(customerID string) (Associate, bool) {
+	for _, a := range u.Associates {
+		if a.CustomerID == customerID {
+			return a, true
+		}
+	}
+	return Associate{}, false
+}
@@ -1,26 +1,200 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"commercetools-replica/internal/money"
+)
+
+const (
+	LineItemModeStandard     = "Standard"
+	LineItemModeGiftLineItem = "GiftLineItem"
+
+	ShippingModeSingle   = "Single"
+	ShippingModeMultiple = "Multiple"
+
+	InventoryModeNone           = "None"
+	InventoryModeTrackOnly      = "TrackOnly"
+	InventoryModeReserveOnOrder = "ReserveOnOrder"
+
+	// CartStateFrozen is the state of the active carts of a deleted or
+	// erased customer; they keep their customerId but can no longer be
+	// updated or ordered.
+	CartStateFrozen = "frozen"
+)
 
 type Cart struct {
-	ID          string     `json:"id"`
-	ProjectID   string     `json:"-"`
-	CustomerID  *string    `json:"customerId,omitempty"`
-	AnonymousID *string    `json:"-"`
-	Currency    string     `json:"currency"`
-	TotalCents  int64      `json:"totalCents"`
-	State       string     `json:"state"`
-	CreatedAt   time.Time  `json:"createdAt"`
-	Lines       []CartLine `json:"lineItems,omitempty"`
+	ID              string             `json:"id"`
+	ProjectID       string             `json:"-"`
+	CustomerID      *string            `json:"customerId,omitempty"`
+	AnonymousID     *string            `json:"-"`
+	Currency        string             `json:"currency"`
+	Country         string             `json:"country,omitempty"`
+	TotalCents      int64              `json:"totalCents"`
+	State           string             `json:"state"`
+	CreatedAt       time.Time          `json:"createdAt"`
+	Lines           []CartLine         `json:"lineItems,omitempty"`
+	DiscountCodes   []CartDiscountCode `json:"discountCodes,omitempty"`
+	DirectDiscounts []DirectDiscount   `json:"directDiscounts,omitempty"`
+	DiscountOnTotal *DiscountOnTotal   `json:"discountOnTotalPrice,omitempty"`
+	// RefusedGifts lists the gift discounts whose line items the customer removed.
+	RefusedGifts []string `json:"refusedGifts,omitempty"`
+	// ShippingAddress decides the tax rates of the line items.
+	ShippingAddress    *CustomerAddress `json:"shippingAddress,omitempty"`
+	TaxMode            string           `json:"taxMode,omitempty"`
+	TaxRoundingMode    string           `json:"taxRoundingMode,omitempty"`
+	TaxCalculationMode string           `json:"taxCalculationMode,omitempty"`
+	// TaxedPrice is set once every line item and the shipping have a tax rate.
+	TaxedPrice *TaxedPrice `json:"taxedPrice,omitempty"`
+	// ShippingInfo is set by setShippingMethod; its price is part of the total.
+	ShippingInfo *ShippingInfo `json:"shippingInfo,omitempty"`
+	// ShippingMode is Single or Multiple; in Multiple mode the cart has one
+	// Shipping per addShippingMethod instead of ShippingInfo.
+	ShippingMode string     `json:"shippingMode,omitempty"`
+	Shipping     []Shipping `json:"shipping,omitempty"`
+	// ItemShippingAddresses are the addresses line items can be shipped to,
+	// referenced by key from the lines' ShippingDetails.
+	ItemShippingAddresses []CustomerAddress `json:"itemShippingAddresses,omitempty"`
+	// InventoryMode decides whether ordering the cart lowers the available
+	// stock (TrackOnly) or also requires it (ReserveOnOrder).
+	InventoryMode string `json:"inventoryMode,omitempty"`
+	// StoreKey is the store the cart was created in; empty for none.
+	StoreKey string `json:"storeKey,omitempty"`
+	// BusinessUnitKey is the business unit an associate created the cart
+	// for; empty for none.
+	BusinessUnitKey string `json:"businessUnitKey,omitempty"`
+}
+
+// ErasePersonalData anonymises every address of the cart; prices, taxes and
+// shipping costs stay.
+func (c *Cart) ErasePersonalData() {
+	if c.ShippingAddress != nil {
+		anon := c.ShippingAddress.Anonymized()
+		c.ShippingAddress = &anon
+	}
+	for i := range c.ItemShippingAddresses {
+		c.ItemShippingAddresses[i] = c.ItemShippingAddresses[i].Anonymized()
+	}
+	for i := range c.Shipping {
+		c.Shipping[i].ShippingAddress = c.Shipping[i].ShippingAddress.Anonymized()
+	}
+}
+
+// ItemShippingAddress returns the item shipping address with key, or nil.
+func (c Cart) ItemShippingAddress(key string) *CustomerAddress {
+	for i := range c.ItemShippingAddresses {
+		if c.ItemShippingAddresses[i].Key == key {
+			return &c.ItemShippingAddresses[i]
+		}
+	}
+	return nil
+}
+
+// ShippingByKey returns the Multiple mode shipping with key, or nil.
+func (c Cart) ShippingByKey(key string) *Shipping {
+	for i := range c.Shipping {
+		if c.Shipping[i].ShippingKey == key {
+			return &c.Shipping[i]
+		}
+	}
+	return nil
 }
 
 type CartLine struct {
 	ID             string                 `json:"id"`
 	CartID         string                 `json:"cartId"`
 	ProductID      string                 `json:"productId"`
+	VariantID      int                    `json:"variantId"`
 	Quantity       int                    `json:"quantity"`
 	UnitPriceCents int64                  `json:"unitPriceCents"`
 	TotalCents     int64                  `json:"totalCents"`
 	Snapshot       map[string]interface{} `json:"snapshot,omitempty"`
 	CreatedAt      time.Time              `json:"createdAt"`
+	// LineItemMode is Standard or GiftLineItem; gift lines are managed by gift discounts.
+	LineItemMode               string               `json:"lineItemMode,omitempty"`
+	DiscountedPricePerQuantity []DiscountedQuantity `json:"discountedPricePerQuantity,omitempty"`
+	TaxRate                    *TaxRate             `json:"taxRate,omitempty"`
+	TaxedPrice                 *TaxedPrice          `json:"taxedPrice,omitempty"`
+	// ShippingDetails splits the quantity over item shipping addresses.
+	ShippingDetails *ItemShippingDetails `json:"shippingDetails,omitempty"`
+}
+
+// ItemShippingDetails lists how many units of a line item go to which item
+// shipping address and, in Multiple shipping mode, with which shipping.
+type ItemShippingDetails struct {
+	Targets []ItemShippingTarget `json:"targets"`
+}
+
+type ItemShippingTarget struct {
+	AddressKey        string `json:"addressKey"`
+	Quantity          int    `json:"quantity"`
+	ShippingMethodKey string `json:"shippingMethodKey,omitempty"`
+}
+
+// Quantity returns the number of units the targets ship.
+func (d ItemShippingDetails) Quantity() int {
+	var n int
+	for _, t := range d.Targets {
+		n += t.Quantity
+	}
+	return n
+}
+
+// QuantityFor returns the number of units shipped with the shipping shippingKey.
+func (d ItemShippingDetails) QuantityFor(shippingKey string) int {
+	var n int
+	for _, t := range d.Targets {
+		if t.ShippingMethodKey == shippingKey {
+			n += t.Quantity
+		}
+	}
+	return n
+}
+
+// IsGift reports whether the line was added by a gift line item discount.
+func (l CartLine) IsGift() bool {
+	return l.LineItemMode == LineItemModeGiftLineItem
+}
+
+// PreciseUnitPrice returns the high-precision price the line snapshotted, or
+// nil if its price had no more decimals than the currency or a product
+// discount replaced it.
+func (l CartLine) PreciseUnitPrice() *money.Amount {
+	if id, _ := l.Snapshot["productDiscountId"].(string); id != "" {
+		return nil
+	}
+	currency, _ := l.Snapshot["currency"].(string)
+	m := Money{
+		CurrencyCode:   currency,
+		PreciseAmount:  snapshotInt(l.Snapshot["preciseAmount"]),
+		FractionDigits: int(snapshotInt(l.Snapshot["fractionDigits"])),
+	}
+	if !m.HighPrecision() {
+		return nil
+	}
+	amount := m.Amount()
+	return &amount
+}
+
+// PriceTotal returns the unit price times the quantity, before cart discounts.
+// High-precision prices are multiplied first and rounded half even once.
+func (l CartLine) PriceTotal() int64 {
+	if precise := l.PreciseUnitPrice(); precise != nil {
+		return precise.Mul(int64(l.Quantity)).CentAmount(money.HalfEven)
+	}
+	return l.UnitPriceCents * int64(l.Quantity)
+}
+
+// snapshotInt reads a number from a line snapshot, which holds float64 values
+// once it went through JSON.
+func snapshotInt(v interface{}) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case int:
+		return int64(n)
+	case float64:
+		return int64(n)
+	}
+	return 0
 }
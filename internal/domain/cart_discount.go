@@ -0,0 +1,178 @@
+package domain
+
+import (
+	"time"
+
+	"commercetools-replica/internal/money"
+)
+
+const (
+	CartDiscountRelative     = "relative"
+	CartDiscountAbsolute     = "absolute"
+	CartDiscountFixed        = "fixed"
+	CartDiscountGiftLineItem = "giftLineItem"
+
+	CartDiscountTargetLineItems  = "lineItems"
+	CartDiscountTargetTotalPrice = "totalPrice"
+	CartDiscountTargetShipping   = "shipping"
+
+	StackingModeStacking              = "Stacking"
+	StackingModeStopAfterThisDiscount = "StopAfterThisDiscount"
+)
+
+// States of a discount code on a cart, as reported by commercetools.
+const (
+	DiscountCodeMatchesCart                          = "MatchesCart"
+	DiscountCodeDoesNotMatchCart                     = "DoesNotMatchCart"
+	DiscountCodeNotActive                            = "NotActive"
+	DiscountCodeNotValid                             = "NotValid"
+	DiscountCodeMaxApplicationReached                = "MaxApplicationReached"
+	DiscountCodeApplicationStoppedByPreviousDiscount = "ApplicationStoppedByPreviousDiscount"
+)
+
+// CartDiscount lowers cart prices when CartPredicate matches the cart. Discounts
+// apply in descending sortOrder; StopAfterThisDiscount ends the chain once the
+// discount has applied.
+type CartDiscount struct {
+	ID                   string              `json:"id"`
+	ProjectID            string              `json:"-"`
+	Key                  string              `json:"key,omitempty"`
+	Version              int                 `json:"version"`
+	Name                 LocalizedString     `json:"name"`
+	Description          LocalizedString     `json:"description,omitempty"`
+	Value                CartDiscountValue   `json:"value"`
+	CartPredicate        string              `json:"cartPredicate"`
+	Target               *CartDiscountTarget `json:"target,omitempty"`
+	SortOrder            string              `json:"sortOrder"`
+	IsActive             bool                `json:"isActive"`
+	ValidFrom            *time.Time          `json:"validFrom,omitempty"`
+	ValidUntil           *time.Time          `json:"validUntil,omitempty"`
+	RequiresDiscountCode bool                `json:"requiresDiscountCode"`
+	StackingMode         string              `json:"stackingMode"`
+	CreatedAt            time.Time           `json:"createdAt"`
+	LastModifiedAt       time.Time           `json:"lastModifiedAt"`
+}
+
+// CartDiscountValue is stored as JSONB. Relative values use Permyriad, absolute
+// and fixed values one amount per currency, and gift values name the product
+// variant added to the cart for free.
+type CartDiscountValue struct {
+	Type      string  `json:"type"`
+	Permyriad int     `json:"permyriad,omitempty"`
+	Money     []Money `json:"money,omitempty"`
+	ProductID string  `json:"productId,omitempty"`
+	VariantID int     `json:"variantId,omitempty"`
+}
+
+// CartDiscountTarget selects what a discount lowers; Predicate selects the line
+// items of a lineItems target.
+type CartDiscountTarget struct {
+	Type      string `json:"type"`
+	Predicate string `json:"predicate,omitempty"`
+}
+
+// ValidAt reports whether the discount is active and within its validity window.
+func (d CartDiscount) ValidAt(now time.Time) bool {
+	return d.IsActive && withinWindow(d.ValidFrom, d.ValidUntil, now)
+}
+
+// SortOrderValue parses the decimal sortOrder; higher values take precedence.
+func (d CartDiscount) SortOrderValue() float64 {
+	return sortOrderValue(d.SortOrder)
+}
+
+// AmountFor returns the absolute or fixed amount of the value in currency.
+func (v CartDiscountValue) AmountFor(currency string) (int64, bool) {
+	for _, m := range v.Money {
+		if m.CurrencyCode == currency {
+			return m.CentAmount, true
+		}
+	}
+	return 0, false
+}
+
+// Off returns how much the value takes off amount cents in currency; the result
+// never exceeds amount. Gift values take nothing off existing prices.
+func (v CartDiscountValue) Off(amount int64, currency string) int64 {
+	var off int64
+	switch v.Type {
+	case CartDiscountRelative:
+		off = money.Cents(currency, amount).MulRat(money.Permyriad(v.Permyriad)).CentAmount(money.HalfEven)
+	case CartDiscountAbsolute:
+		off, _ = v.AmountFor(currency)
+	case CartDiscountFixed:
+		if fixed, ok := v.AmountFor(currency); ok && fixed < amount {
+			off = amount - fixed
+		}
+	}
+	if off > amount {
+		off = amount
+	}
+	if off < 0 {
+		off = 0
+	}
+	return off
+}
+
+// DiscountCode unlocks the cart discounts it references once added to a cart.
+// MaxApplications counts the carts holding the code; MaxApplicationsPerCustomer
+// counts those of one customer.
+type DiscountCode struct {
+	ID                         string          `json:"id"`
+	ProjectID                  string          `json:"-"`
+	Key                        string          `json:"key,omitempty"`
+	Code                       string          `json:"code"`
+	Version                    int             `json:"version"`
+	Name                       LocalizedString `json:"name,omitempty"`
+	Description                LocalizedString `json:"description,omitempty"`
+	CartDiscountIDs            []string        `json:"cartDiscounts"`
+	CartPredicate              string          `json:"cartPredicate,omitempty"`
+	IsActive                   bool            `json:"isActive"`
+	MaxApplications            *int            `json:"maxApplications,omitempty"`
+	MaxApplicationsPerCustomer *int            `json:"maxApplicationsPerCustomer,omitempty"`
+	ValidFrom                  *time.Time      `json:"validFrom,omitempty"`
+	ValidUntil                 *time.Time      `json:"validUntil,omitempty"`
+	CreatedAt                  time.Time       `json:"createdAt"`
+	LastModifiedAt             time.Time       `json:"lastModifiedAt"`
+}
+
+// ValidAt reports whether the code is within its validity window; IsActive is
+// checked separately because it maps to its own cart state.
+func (c DiscountCode) ValidAt(now time.Time) bool {
+	return withinWindow(c.ValidFrom, c.ValidUntil, now)
+}
+
+// CartDiscountCode is a discount code added to a cart with its current state.
+type CartDiscountCode struct {
+	DiscountCodeID string `json:"discountCodeId"`
+	State          string `json:"state"`
+}
+
+// DirectDiscount is a cart discount set on one cart only. Carts with direct
+// discounts ignore cart discounts and discount codes.
+type DirectDiscount struct {
+	ID     string              `json:"id"`
+	Value  CartDiscountValue   `json:"value"`
+	Target *CartDiscountTarget `json:"target,omitempty"`
+}
+
+// DiscountPortion is the share one discount took off a price. Direct marks
+// portions of direct discounts rather than cart discounts.
+type DiscountPortion struct {
+	DiscountID string `json:"discountId"`
+	Direct     bool   `json:"direct,omitempty"`
+	Amount     Money  `json:"amount"`
+}
+
+// DiscountedQuantity is the discounted unit price of Quantity units of a line.
+type DiscountedQuantity struct {
+	Quantity          int               `json:"quantity"`
+	Value             Money             `json:"value"`
+	IncludedDiscounts []DiscountPortion `json:"includedDiscounts"`
+}
+
+// DiscountOnTotal is what totalPrice discounts took off the cart total.
+type DiscountOnTotal struct {
+	DiscountedAmount  Money             `json:"discountedAmount"`
+	IncludedDiscounts []DiscountPortion `json:"includedDiscounts"`
+}
@@ -3,15 +3,16 @@ package domain
 import "time"
 
 type Category struct {
-	ID              string    `json:"id"`
-	ProjectID       string    `json:"-"`
-	Key             string    `json:"key"`
-	Name            string    `json:"name"`
-	Slug            string    `json:"slug,omitempty"`
-	OrderHint       string    `json:"orderHint,omitempty"`
-	ParentKey       string    `json:"parentKey,omitempty"`
-	Description     string    `json:"description,omitempty"`
-	MetaTitle       string    `json:"metaTitle,omitempty"`
-	MetaDescription string    `json:"metaDescription,omitempty"`
-	CreatedAt       time.Time `json:"createdAt"`
+	ID              string          `json:"id"`
+	ProjectID       string          `json:"-"`
+	Key             string          `json:"key"`
+	Name            LocalizedString `json:"name"`
+	Slug            LocalizedString `json:"slug,omitempty"`
+	OrderHint       string          `json:"orderHint,omitempty"`
+	ParentID        string          `json:"parentId,omitempty"`
+	AncestorIDs     []string        `json:"ancestorIds,omitempty"` // root first, maintained on write
+	Description     LocalizedString `json:"description,omitempty"`
+	MetaTitle       LocalizedString `json:"metaTitle,omitempty"`
+	MetaDescription LocalizedString `json:"metaDescription,omitempty"`
+	CreatedAt       time.Time       `json:"createdAt"`
 }
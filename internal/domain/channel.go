@@ -0,0 +1,37 @@
+package domain
+
+import "time"
+
+// Channel roles decide where a channel may be used: stores take supply
+// channels with InventorySupply and distribution channels with
+// ProductDistribution.
+const (
+	ChannelRoleInventorySupply     = "InventorySupply"
+	ChannelRoleProductDistribution = "ProductDistribution"
+	ChannelRoleOrderExport         = "OrderExport"
+	ChannelRoleOrderImport         = "OrderImport"
+	ChannelRolePrimary             = "Primary"
+)
+
+// Channel is a source of stock or a distribution route of products.
+type Channel struct {
+	ID             string          `json:"id"`
+	ProjectID      string          `json:"-"`
+	Key            string          `json:"key"`
+	Version        int             `json:"version"`
+	Roles          []string        `json:"roles"`
+	Name           LocalizedString `json:"name,omitempty"`
+	Description    LocalizedString `json:"description,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	LastModifiedAt time.Time       `json:"lastModifiedAt"`
+}
+
+// HasRole reports whether the channel has role.
+func (c Channel) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
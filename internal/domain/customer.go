@@ -5,21 +5,37 @@ import "time"
 // CustomerAddress stores address fields returned to clients.
 type CustomerAddress struct {
 	ID         string `json:"id"`
+	Key        string `json:"key,omitempty"`
 	FirstName  string `json:"firstName,omitempty"`
 	LastName   string `json:"lastName,omitempty"`
 	Country    string `json:"country,omitempty"`
 	StreetName string `json:"streetName,omitempty"`
 	PostalCode string `json:"postalCode,omitempty"`
 	City       string `json:"city,omitempty"`
+	State      string `json:"state,omitempty"`
 	Email      string `json:"email,omitempty"`
 	Department string `json:"department,omitempty"`
 }
 
+// Anonymized keeps what tax and shipping accounting need: the ids, the
+// country and the state.
+func (a CustomerAddress) Anonymized() CustomerAddress {
+	return CustomerAddress{ID: a.ID, Key: a.Key, Country: a.Country, State: a.State}
+}
+
+// Who asked for a customer erasure.
+const (
+	ErasureByCustomer = "customer"
+	ErasureByAdmin    = "admin"
+)
+
 // Customer represents a registered user tied to a project.
 type Customer struct {
 	ID                       string            `json:"id"`
 	ProjectID                string            `json:"projectId"`
+	Version                  int               `json:"version"`
 	Email                    string            `json:"email"`
+	IsEmailVerified          bool              `json:"isEmailVerified"`
 	PasswordHash             string            `json:"-"`
 	FirstName                string            `json:"firstName,omitempty"`
 	LastName                 string            `json:"lastName,omitempty"`
@@ -29,5 +45,7 @@ type Customer struct {
 	DefaultBillingAddressID  string            `json:"defaultBillingAddressId,omitempty"`
 	ShippingAddressIDs       []string          `json:"shippingAddressIds,omitempty"`
 	BillingAddressIDs        []string          `json:"billingAddressIds,omitempty"`
+	CustomerGroupID          string            `json:"customerGroupId,omitempty"`
 	CreatedAt                time.Time         `json:"createdAt"`
+	LastModifiedAt           time.Time         `json:"lastModifiedAt"`
 }
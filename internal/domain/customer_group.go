@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// CustomerGroup groups customers that get their own prices, such as
+// wholesale buyers.
+type CustomerGroup struct {
+	ID             string    `json:"id"`
+	ProjectID      string    `json:"-"`
+	Key            string    `json:"key,omitempty"`
+	Version        int       `json:"version"`
+	Name           string    `json:"name"`
+	CreatedAt      time.Time `json:"createdAt"`
+	LastModifiedAt time.Time `json:"lastModifiedAt"`
+}
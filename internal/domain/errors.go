@@ -1,10 +1,33 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"strings"
+)
 
 var (
 	// ErrNotFound indicates the requested entity was not found.
 	ErrNotFound = errors.New("not found")
 	// ErrAlreadyExists indicates a conflicting entity already exists.
 	ErrAlreadyExists = errors.New("already exists")
+	// ErrConcurrentModification indicates the expected version no longer matches the stored one.
+	ErrConcurrentModification = errors.New("concurrent modification")
+	// ErrReferenceExists indicates the entity cannot be deleted while others reference it.
+	ErrReferenceExists = errors.New("still referenced by another resource")
 )
+
+// ErrOutOfStock indicates an order could not reserve stock; see OutOfStockError.
+var ErrOutOfStock = errors.New("out of stock")
+
+// OutOfStockError lists the SKUs without enough available stock.
+type OutOfStockError struct {
+	SKUs []string
+}
+
+func (e *OutOfStockError) Error() string {
+	return "out of stock: " + strings.Join(e.SKUs, ", ")
+}
+
+func (e *OutOfStockError) Is(target error) bool {
+	return target == ErrOutOfStock
+}
@@ -0,0 +1,45 @@
+package domain
+
+import "time"
+
+// InventoryEntry is the stock of a SKU, optionally in one supply channel.
+// Orders of carts that track inventory lower AvailableQuantity; only the
+// quantity actions change QuantityOnStock.
+type InventoryEntry struct {
+	ID                string     `json:"id"`
+	ProjectID         string     `json:"-"`
+	Key               string     `json:"key,omitempty"`
+	Version           int        `json:"version"`
+	SKU               string     `json:"sku"`
+	SupplyChannelID   string     `json:"supplyChannelId,omitempty"`
+	QuantityOnStock   int64      `json:"quantityOnStock"`
+	AvailableQuantity int64      `json:"availableQuantity"`
+	RestockableInDays *int       `json:"restockableInDays,omitempty"`
+	ExpectedDelivery  *time.Time `json:"expectedDelivery,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	LastModifiedAt    time.Time  `json:"lastModifiedAt"`
+}
+
+// VariantAvailability is the stock of a product variant: the entry without a
+// supply channel at the top, the others by channel id.
+type VariantAvailability struct {
+	IsOnStock         bool                           `json:"isOnStock"`
+	RestockableInDays *int                           `json:"restockableInDays,omitempty"`
+	AvailableQuantity int64                          `json:"availableQuantity"`
+	Channels          map[string]ChannelAvailability `json:"channels,omitempty"`
+}
+
+type ChannelAvailability struct {
+	IsOnStock         bool  `json:"isOnStock"`
+	RestockableInDays *int  `json:"restockableInDays,omitempty"`
+	AvailableQuantity int64 `json:"availableQuantity"`
+}
+
+// Availability returns the availability the entry reports.
+func (e InventoryEntry) Availability() ChannelAvailability {
+	return ChannelAvailability{
+		IsOnStock:         e.AvailableQuantity > 0,
+		RestockableInDays: e.RestockableInDays,
+		AvailableQuantity: e.AvailableQuantity,
+	}
+}
@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultLocale is used when a value has to be picked without an explicit locale.
+const DefaultLocale = "en"
+
+// LocalizedString maps a locale (e.g. "en", "de-DE") to a value, like commercetools LocalizedString.
+type LocalizedString map[string]string
+
+// Localized builds a LocalizedString with a single locale, skipping empty values.
+func Localized(locale, value string) LocalizedString {
+	if strings.TrimSpace(value) == "" {
+		return LocalizedString{}
+	}
+	return LocalizedString{locale: value}
+}
+
+// Get returns the value for locale, falling back to the language part of the
+// locale ("de" for "de-AT"), to DefaultLocale and finally to any value.
+func (l LocalizedString) Get(locale string) string {
+	if v, ok := l.Lookup(locale); ok {
+		return v
+	}
+	if v, ok := l[DefaultLocale]; ok && v != "" {
+		return v
+	}
+	for _, key := range sortedLocales(l) {
+		if v := l[key]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Lookup returns the value for locale, matching on language when there is no exact entry.
+func (l LocalizedString) Lookup(locale string) (string, bool) {
+	if locale == "" {
+		return "", false
+	}
+	if v, ok := l[locale]; ok && v != "" {
+		return v, true
+	}
+	lang := LocaleLanguage(locale)
+	for _, key := range sortedLocales(l) {
+		if LocaleLanguage(key) == lang && l[key] != "" {
+			return l[key], true
+		}
+	}
+	return "", false
+}
+
+// IsEmpty reports whether no locale carries a non-empty value.
+func (l LocalizedString) IsEmpty() bool {
+	for _, v := range l {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Clone returns a non-nil copy.
+func (l LocalizedString) Clone() LocalizedString {
+	out := make(LocalizedString, len(l))
+	for k, v := range l {
+		out[k] = v
+	}
+	return out
+}
+
+// LocaleLanguage returns the lower-cased language part of a locale ("de" for "de-DE").
+func LocaleLanguage(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if idx := strings.IndexAny(locale, "-_"); idx >= 0 {
+		return locale[:idx]
+	}
+	return locale
+}
+
+// LocalizedKeywords holds search keywords per locale.
+type LocalizedKeywords map[string][]string
+
+func sortedLocales(l LocalizedString) []string {
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
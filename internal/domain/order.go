@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+const (
+	OrderStateOpen      = "Open"
+	OrderStateConfirmed = "Confirmed"
+	OrderStateComplete  = "Complete"
+	OrderStateCancelled = "Cancelled"
+)
+
+// Order is a cart that was checked out. The cart is kept as it was when the
+// order was created, prices, discounts, taxes and shipping included.
+type Order struct {
+	ID             string    `json:"id"`
+	ProjectID      string    `json:"-"`
+	Version        int       `json:"version"`
+	OrderNumber    string    `json:"orderNumber,omitempty"`
+	OrderState     string    `json:"orderState"`
+	Cart           Cart      `json:"cart"`
+	CreatedAt      time.Time `json:"createdAt"`
+	LastModifiedAt time.Time `json:"lastModifiedAt"`
+}
@@ -1,16 +1,207 @@
 package domain
 
-import "time"
+import (
+	"time"
 
+	"commercetools-replica/internal/money"
+)
+
+// Product keeps the published (current) and the editable (staged) data side by side.
 type Product struct {
-	ID          string                 `json:"id"`
-	ProjectID   string                 `json:"-"`
-	Key         string                 `json:"key"`
-	SKU         string                 `json:"sku"`
-	Name        string                 `json:"name"`
-	Description string                 `json:"description,omitempty"`
-	PriceCents  int64                  `json:"priceCents"`
-	Currency    string                 `json:"currency"`
-	Attributes  map[string]interface{} `json:"attributes,omitempty"`
-	CreatedAt   time.Time              `json:"createdAt"`
+	ID               string      `json:"id"`
+	ProjectID        string      `json:"-"`
+	Key              string      `json:"key"`
+	ProductTypeID    string      `json:"productTypeId,omitempty"`
+	TaxCategoryID    string      `json:"taxCategoryId,omitempty"`
+	Version          int         `json:"version"`
+	Published        bool        `json:"published"`
+	HasStagedChanges bool        `json:"hasStagedChanges"`
+	Current          ProductData `json:"current"`
+	Staged           ProductData `json:"staged"`
+	CreatedAt        time.Time   `json:"createdAt"`
+	LastModifiedAt   time.Time   `json:"lastModifiedAt"`
+}
+
+// ProductData is one projection of a product; it is stored as JSONB.
+type ProductData struct {
+	Name            LocalizedString   `json:"name"`
+	Slug            LocalizedString   `json:"slug"`
+	Description     LocalizedString   `json:"description,omitempty"`
+	MetaTitle       LocalizedString   `json:"metaTitle,omitempty"`
+	MetaDescription LocalizedString   `json:"metaDescription,omitempty"`
+	SearchKeywords  LocalizedKeywords `json:"searchKeywords,omitempty"`
+	CategoryIDs     []string          `json:"categoryIds,omitempty"`
+	MasterVariant   ProductVariant    `json:"masterVariant"`
+	Variants        []ProductVariant  `json:"variants,omitempty"`
+}
+
+type ProductVariant struct {
+	ID         int                    `json:"id"`
+	SKU        string                 `json:"sku,omitempty"`
+	Key        string                 `json:"key,omitempty"`
+	Prices     []Price                `json:"prices,omitempty"`
+	Images     []string               `json:"images,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	// Availability is set from the inventory when the product is rendered and
+	// never stored with the product.
+	Availability *VariantAvailability `json:"-"`
+	// SelectedPrice is the price picked for a price selection when the product
+	// is rendered; it is never stored either.
+	SelectedPrice *Price `json:"-"`
+}
+
+type Price struct {
+	ID    string `json:"id"`
+	Value Money  `json:"value"`
+	// CustomerGroupID scopes the price to the customers of one group.
+	CustomerGroupID string `json:"customerGroupId,omitempty"`
+	// Discounted is only persisted for external product discounts; relative and
+	// absolute discounts are applied when the product is read.
+	Discounted *DiscountedPrice `json:"discounted,omitempty"`
+}
+
+// Money is an amount in a currency. CentAmount is in the currency's minor unit
+// and always set; high-precision amounts also carry PreciseAmount in
+// FractionDigits decimals, with CentAmount rounded from it.
+type Money struct {
+	CurrencyCode   string `json:"currencyCode"`
+	CentAmount     int64  `json:"centAmount"`
+	PreciseAmount  int64  `json:"preciseAmount,omitempty"`
+	FractionDigits int    `json:"fractionDigits,omitempty"`
+}
+
+// HighPrecision reports whether m has more decimals than its currency.
+func (m Money) HighPrecision() bool {
+	return m.FractionDigits > money.FractionDigits(m.CurrencyCode)
+}
+
+// Amount returns m as an exact amount, from the precise amount if there is one.
+func (m Money) Amount() money.Amount {
+	if m.HighPrecision() {
+		return money.Precise(m.CurrencyCode, m.PreciseAmount, m.FractionDigits)
+	}
+	return money.Cents(m.CurrencyCode, m.CentAmount)
+}
+
+// WithAmount returns a in the precision of m: high-precision money keeps its
+// fraction digits and CentAmount is rounded half even, like commercetools does.
+func (m Money) WithAmount(a money.Amount) Money {
+	out := Money{CurrencyCode: m.CurrencyCode, CentAmount: a.CentAmount(money.HalfEven)}
+	if m.HighPrecision() {
+		out.PreciseAmount = a.PreciseAmount(m.FractionDigits, money.HalfEven)
+		out.FractionDigits = m.FractionDigits
+	}
+	return out
+}
+
+// AllVariants returns the master variant followed by the other variants.
+func (d ProductData) AllVariants() []ProductVariant {
+	out := make([]ProductVariant, 0, len(d.Variants)+1)
+	out = append(out, d.MasterVariant)
+	return append(out, d.Variants...)
+}
+
+// Variant returns a pointer into d for the variant with the given id.
+func (d *ProductData) Variant(id int) *ProductVariant {
+	if d.MasterVariant.ID == id {
+		return &d.MasterVariant
+	}
+	for i := range d.Variants {
+		if d.Variants[i].ID == id {
+			return &d.Variants[i]
+		}
+	}
+	return nil
+}
+
+// VariantBySKU returns a pointer into d for the variant with the given sku.
+func (d *ProductData) VariantBySKU(sku string) *ProductVariant {
+	if sku == "" {
+		return nil
+	}
+	if d.MasterVariant.SKU == sku {
+		return &d.MasterVariant
+	}
+	for i := range d.Variants {
+		if d.Variants[i].SKU == sku {
+			return &d.Variants[i]
+		}
+	}
+	return nil
+}
+
+// PriceFor returns the first price in the given currency that is not scoped
+// to a customer group.
+func (v ProductVariant) PriceFor(currency string) (Price, bool) {
+	return v.PriceForGroup(currency, "")
+}
+
+// PriceForGroup returns the price in currency for the customers of a group,
+// falling back to the price without a customer group.
+func (v ProductVariant) PriceForGroup(currency, customerGroupID string) (Price, bool) {
+	var fallback *Price
+	for i, p := range v.Prices {
+		if p.Value.CurrencyCode != currency {
+			continue
+		}
+		if p.CustomerGroupID == customerGroupID {
+			return p, true
+		}
+		if p.CustomerGroupID == "" && fallback == nil {
+			fallback = &v.Prices[i]
+		}
+	}
+	if fallback == nil {
+		return Price{}, false
+	}
+	return *fallback, true
+}
+
+// SelectPrices sets SelectedPrice on the variants of both projections to the
+// price PriceForGroup picks, leaving it nil where there is none.
+func (p *Product) SelectPrices(currency, customerGroupID string) {
+	for _, data := range []*ProductData{&p.Current, &p.Staged} {
+		variants := []*ProductVariant{&data.MasterVariant}
+		for i := range data.Variants {
+			variants = append(variants, &data.Variants[i])
+		}
+		for _, v := range variants {
+			v.SelectedPrice = nil
+			if price, ok := v.PriceForGroup(currency, customerGroupID); ok {
+				v.SelectedPrice = &price
+			}
+		}
+	}
+}
+
+// SKUs lists the skus used by either projection of the product.
+func (p Product) SKUs() []string {
+	seen := map[string]struct{}{}
+	var out []string
+	for _, data := range []ProductData{p.Current, p.Staged} {
+		for _, v := range data.AllVariants() {
+			if v.SKU == "" {
+				continue
+			}
+			if _, ok := seen[v.SKU]; ok {
+				continue
+			}
+			seen[v.SKU] = struct{}{}
+			out = append(out, v.SKU)
+		}
+	}
+	return out
+}
+
+// LastVariantID returns the highest variant id across both projections.
+func (p Product) LastVariantID() int {
+	last := 0
+	for _, data := range []ProductData{p.Current, p.Staged} {
+		for _, v := range data.AllVariants() {
+			if v.ID > last {
+				last = v.ID
+			}
+		}
+	}
+	return last
 }
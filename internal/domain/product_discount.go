@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"strconv"
+	"time"
+
+	"commercetools-replica/internal/money"
+)
+
+const (
+	ProductDiscountRelative = "relative"
+	ProductDiscountAbsolute = "absolute"
+	ProductDiscountExternal = "external"
+)
+
+// ProductDiscount lowers variant prices matching its predicate. Of all matching
+// discounts only the one with the highest sortOrder applies.
+type ProductDiscount struct {
+	ID             string               `json:"id"`
+	ProjectID      string               `json:"-"`
+	Key            string               `json:"key,omitempty"`
+	Version        int                  `json:"version"`
+	Name           LocalizedString      `json:"name"`
+	Description    LocalizedString      `json:"description,omitempty"`
+	Value          ProductDiscountValue `json:"value"`
+	Predicate      string               `json:"predicate"`
+	SortOrder      string               `json:"sortOrder"`
+	IsActive       bool                 `json:"isActive"`
+	ValidFrom      *time.Time           `json:"validFrom,omitempty"`
+	ValidUntil     *time.Time           `json:"validUntil,omitempty"`
+	CreatedAt      time.Time            `json:"createdAt"`
+	LastModifiedAt time.Time            `json:"lastModifiedAt"`
+}
+
+// ProductDiscountValue is stored as JSONB; Permyriad is used by relative values and
+// Money (one amount per currency) by absolute ones. External values carry neither.
+type ProductDiscountValue struct {
+	Type      string  `json:"type"`
+	Permyriad int     `json:"permyriad,omitempty"`
+	Money     []Money `json:"money,omitempty"`
+}
+
+// DiscountedPrice is the price after a product discount, kept next to the original.
+type DiscountedPrice struct {
+	Value      Money  `json:"value"`
+	DiscountID string `json:"discountId"`
+}
+
+// ValidAt reports whether the discount is active and within its validity window.
+func (d ProductDiscount) ValidAt(now time.Time) bool {
+	return d.IsActive && withinWindow(d.ValidFrom, d.ValidUntil, now)
+}
+
+// SortOrderValue parses the decimal sortOrder; higher values take precedence.
+func (d ProductDiscount) SortOrderValue() float64 {
+	return sortOrderValue(d.SortOrder)
+}
+
+// withinWindow reports whether now lies in [from, until); nil bounds are open.
+func withinWindow(from, until *time.Time, now time.Time) bool {
+	if from != nil && now.Before(*from) {
+		return false
+	}
+	if until != nil && !now.Before(*until) {
+		return false
+	}
+	return true
+}
+
+func sortOrderValue(s string) float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// Apply returns the discounted amount of m, or false if the value has no amount
+// for the currency. External values never apply on their own.
+func (v ProductDiscountValue) Apply(m Money) (Money, bool) {
+	switch v.Type {
+	case ProductDiscountRelative:
+		off := m.Amount().MulRat(money.Permyriad(v.Permyriad))
+		return m.WithAmount(m.Amount().Sub(off)), true
+	case ProductDiscountAbsolute:
+		for _, amount := range v.Money {
+			if amount.CurrencyCode != m.CurrencyCode {
+				continue
+			}
+			rest := m.Amount().Sub(amount.Amount())
+			if rest.Sign() < 0 {
+				rest = money.Cents(m.CurrencyCode, 0)
+			}
+			return m.WithAmount(rest), true
+		}
+	}
+	return Money{}, false
+}
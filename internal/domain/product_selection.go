@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// ProductSelection is a named set of products stores can sell. Its products
+// are stored apart; ProductCount is how many there are.
+type ProductSelection struct {
+	ID             string          `json:"id"`
+	ProjectID      string          `json:"-"`
+	Key            string          `json:"key,omitempty"`
+	Version        int             `json:"version"`
+	Name           LocalizedString `json:"name"`
+	ProductCount   int             `json:"productCount"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	LastModifiedAt time.Time       `json:"lastModifiedAt"`
+}
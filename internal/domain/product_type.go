@@ -0,0 +1,377 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Attribute type names, as used by commercetools attribute definitions.
+const (
+	AttributeTypeBoolean   = "boolean"
+	AttributeTypeText      = "text"
+	AttributeTypeLText     = "ltext"
+	AttributeTypeEnum      = "enum"
+	AttributeTypeLEnum     = "lenum"
+	AttributeTypeNumber    = "number"
+	AttributeTypeMoney     = "money"
+	AttributeTypeDate      = "date"
+	AttributeTypeTime      = "time"
+	AttributeTypeDateTime  = "datetime"
+	AttributeTypeReference = "reference"
+	AttributeTypeSet       = "set"
+)
+
+// Attribute constraints across the variants of one product.
+const (
+	AttributeConstraintNone              = "None"
+	AttributeConstraintUnique            = "Unique"
+	AttributeConstraintCombinationUnique = "CombinationUnique"
+	AttributeConstraintSameForAll        = "SameForAll"
+)
+
+type ProductType struct {
+	ID             string                `json:"id"`
+	ProjectID      string                `json:"-"`
+	Key            string                `json:"key,omitempty"`
+	Version        int                   `json:"version"`
+	Name           string                `json:"name"`
+	Description    string                `json:"description"`
+	Attributes     []AttributeDefinition `json:"attributes"`
+	CreatedAt      time.Time             `json:"createdAt"`
+	LastModifiedAt time.Time             `json:"lastModifiedAt"`
+}
+
+// AttributeDefinition describes one attribute of a product type; definitions are stored as JSONB.
+type AttributeDefinition struct {
+	Name                string          `json:"name"`
+	Label               LocalizedString `json:"label"`
+	Type                AttributeType   `json:"type"`
+	AttributeConstraint string          `json:"attributeConstraint"`
+	IsRequired          bool            `json:"isRequired"`
+	IsSearchable        bool            `json:"isSearchable"`
+	InputHint           string          `json:"inputHint,omitempty"`
+}
+
+type AttributeType struct {
+	Name            string               `json:"name"`
+	Values          []AttributeEnumValue `json:"values,omitempty"`
+	ElementType     *AttributeType       `json:"elementType,omitempty"`
+	ReferenceTypeID string               `json:"referenceTypeId,omitempty"`
+}
+
+// AttributeEnumValue is a value of an enum or lenum type. Plain enum labels are kept
+// under DefaultLocale so both kinds share one shape.
+type AttributeEnumValue struct {
+	Key   string          `json:"key"`
+	Label LocalizedString `json:"label"`
+}
+
+// UnmarshalJSON accepts the label either as a plain string (enum) or as a locale map (lenum).
+func (v *AttributeEnumValue) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Key   string          `json:"key"`
+		Label json.RawMessage `json:"label"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	v.Key = raw.Key
+	v.Label = LocalizedString{}
+	if len(raw.Label) == 0 || string(raw.Label) == "null" {
+		return nil
+	}
+	var plain string
+	if err := json.Unmarshal(raw.Label, &plain); err == nil {
+		v.Label = Localized(DefaultLocale, plain)
+		return nil
+	}
+	return json.Unmarshal(raw.Label, &v.Label)
+}
+
+// Localizable reports whether values of the type carry one value per locale.
+func (t AttributeType) Localizable() bool {
+	switch t.Name {
+	case AttributeTypeLText, AttributeTypeLEnum:
+		return true
+	case AttributeTypeSet:
+		return t.ElementType != nil && t.ElementType.Localizable()
+	}
+	return false
+}
+
+// Attribute returns the definition with the given name.
+func (t ProductType) Attribute(name string) (AttributeDefinition, bool) {
+	for _, def := range t.Attributes {
+		if def.Name == name {
+			return def, true
+		}
+	}
+	return AttributeDefinition{}, false
+}
+
+// ValidateAttributes checks the attributes of every variant in data against the
+// definitions and their constraints. Values are normalized in place, so an enum
+// key becomes {"key","label"} and numbers are stored as float64.
+func (t ProductType) ValidateAttributes(data *ProductData) error {
+	variants := []*ProductVariant{&data.MasterVariant}
+	for i := range data.Variants {
+		variants = append(variants, &data.Variants[i])
+	}
+
+	for _, v := range variants {
+		for name, value := range v.Attributes {
+			def, ok := t.Attribute(name)
+			if !ok {
+				return fmt.Errorf("variant %d: attribute %q is not defined on product type %s", v.ID, name, t.displayName())
+			}
+			normalized, err := def.Type.normalize(value)
+			if err != nil {
+				return fmt.Errorf("variant %d: attribute %q: %w", v.ID, name, err)
+			}
+			v.Attributes[name] = normalized
+		}
+		for _, def := range t.Attributes {
+			if _, ok := v.Attributes[def.Name]; def.IsRequired && !ok {
+				return fmt.Errorf("variant %d: attribute %q is required", v.ID, def.Name)
+			}
+		}
+	}
+
+	var combination []string
+	for _, def := range t.Attributes {
+		switch def.AttributeConstraint {
+		case AttributeConstraintSameForAll:
+			first, firstSet := attributeKey(variants[0], def.Name)
+			for _, v := range variants[1:] {
+				if value, set := attributeKey(v, def.Name); set != firstSet || value != first {
+					return fmt.Errorf("attribute %q must have the same value in all variants", def.Name)
+				}
+			}
+		case AttributeConstraintUnique:
+			seen := map[string]int{}
+			for _, v := range variants {
+				value, set := attributeKey(v, def.Name)
+				if !set {
+					continue
+				}
+				if other, dup := seen[value]; dup {
+					return fmt.Errorf("attribute %q must be unique, variants %d and %d share a value", def.Name, other, v.ID)
+				}
+				seen[value] = v.ID
+			}
+		case AttributeConstraintCombinationUnique:
+			combination = append(combination, def.Name)
+		}
+	}
+	if len(combination) > 0 {
+		seen := map[string]int{}
+		for _, v := range variants {
+			parts := make([]string, 0, len(combination))
+			for _, name := range combination {
+				value, _ := attributeKey(v, name)
+				parts = append(parts, value)
+			}
+			key := strings.Join(parts, "\x00")
+			if other, dup := seen[key]; dup {
+				return fmt.Errorf("attributes %s must be unique in combination, variants %d and %d share values", strings.Join(combination, ", "), other, v.ID)
+			}
+			seen[key] = v.ID
+		}
+	}
+	return nil
+}
+
+func (t ProductType) displayName() string {
+	if t.Key != "" {
+		return t.Key
+	}
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.ID
+}
+
+// attributeKey returns a comparable form of an attribute value.
+func attributeKey(v *ProductVariant, name string) (string, bool) {
+	value, ok := v.Attributes[name]
+	if !ok {
+		return "", false
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value), true
+	}
+	return string(b), true
+}
+
+func (t AttributeType) normalize(value interface{}) (interface{}, error) {
+	switch t.Name {
+	case AttributeTypeBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, errors.New("expected a boolean")
+		}
+		return b, nil
+	case AttributeTypeText:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("expected a string")
+		}
+		return s, nil
+	case AttributeTypeLText:
+		l, ok := localizedValue(value)
+		if !ok {
+			return nil, errors.New("expected a localized string")
+		}
+		return l, nil
+	case AttributeTypeEnum, AttributeTypeLEnum:
+		key, ok := enumKey(value)
+		if !ok {
+			return nil, errors.New("expected an enum key")
+		}
+		for _, ev := range t.Values {
+			if ev.Key != key {
+				continue
+			}
+			if t.Name == AttributeTypeEnum {
+				return map[string]interface{}{"key": ev.Key, "label": ev.Label.Get(DefaultLocale)}, nil
+			}
+			return map[string]interface{}{"key": ev.Key, "label": map[string]string(ev.Label.Clone())}, nil
+		}
+		return nil, fmt.Errorf("unknown enum value %q", key)
+	case AttributeTypeNumber:
+		n, ok := numberValue(value)
+		if !ok {
+			return nil, errors.New("expected a number")
+		}
+		return n, nil
+	case AttributeTypeMoney:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("expected a money value")
+		}
+		currency, _ := m["currencyCode"].(string)
+		cents, okCents := numberValue(m["centAmount"])
+		if len(currency) != 3 || !okCents || cents != math.Trunc(cents) {
+			return nil, errors.New("expected a money value with currencyCode and centAmount")
+		}
+		return map[string]interface{}{"currencyCode": strings.ToUpper(currency), "centAmount": int64(cents)}, nil
+	case AttributeTypeDate, AttributeTypeTime, AttributeTypeDateTime:
+		s, ok := value.(string)
+		if !ok || !validTemporal(t.Name, s) {
+			return nil, fmt.Errorf("expected a %s value", t.Name)
+		}
+		return s, nil
+	case AttributeTypeReference:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("expected a reference")
+		}
+		typeID, _ := m["typeId"].(string)
+		id, _ := m["id"].(string)
+		if id == "" {
+			return nil, errors.New("expected a reference with an id")
+		}
+		if t.ReferenceTypeID != "" && typeID != "" && typeID != t.ReferenceTypeID {
+			return nil, fmt.Errorf("expected a %s reference", t.ReferenceTypeID)
+		}
+		if typeID == "" {
+			typeID = t.ReferenceTypeID
+		}
+		return map[string]interface{}{"typeId": typeID, "id": id}, nil
+	case AttributeTypeSet:
+		if t.ElementType == nil {
+			return nil, errors.New("set type without element type")
+		}
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, errors.New("expected a set")
+		}
+		out := make([]interface{}, 0, len(items))
+		seen := map[string]struct{}{}
+		for _, item := range items {
+			normalized, err := t.ElementType.normalize(item)
+			if err != nil {
+				return nil, err
+			}
+			b, _ := json.Marshal(normalized)
+			if _, dup := seen[string(b)]; dup {
+				continue
+			}
+			seen[string(b)] = struct{}{}
+			out = append(out, normalized)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported attribute type %q", t.Name)
+}
+
+func localizedValue(value interface{}) (map[string]string, bool) {
+	switch v := value.(type) {
+	case LocalizedString:
+		return map[string]string(v.Clone()), true
+	case map[string]string:
+		return map[string]string(LocalizedString(v).Clone()), true
+	case map[string]interface{}:
+		out := make(map[string]string, len(v))
+		for locale, raw := range v {
+			s, ok := raw.(string)
+			if !ok {
+				return nil, false
+			}
+			out[locale] = s
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+func enumKey(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, v != ""
+	case map[string]interface{}:
+		key, ok := v["key"].(string)
+		return key, ok && key != ""
+	}
+	return "", false
+}
+
+func numberValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func validTemporal(kind, s string) bool {
+	var layouts []string
+	switch kind {
+	case AttributeTypeDate:
+		layouts = []string{"2006-01-02"}
+	case AttributeTypeTime:
+		layouts = []string{"15:04:05", "15:04:05.000", "15:04"}
+	default:
+		layouts = []string{time.RFC3339, time.RFC3339Nano}
+	}
+	for _, layout := range layouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
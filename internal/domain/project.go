@@ -3,8 +3,28 @@ package domain
 import "time"
 
 type Project struct {
-	ID        string
-	Key       string
-	Name      string
-	CreatedAt time.Time
+	ID   string
+	Key  string
+	Name string
+	// RequireEmailVerification refuses customer logins until the email is verified.
+	RequireEmailVerification bool
+	PasswordPolicy           PasswordPolicy
+	CreatedAt                time.Time
+}
+
+// PasswordPolicy is what customer passwords of a project must satisfy.
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// DenylistFile lists refused passwords one per line; empty means the
+	// server default.
+	DenylistFile string
+}
+
+// DefaultPasswordPolicy matches the column defaults of projects.
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{MinLength: 8, RequireUpper: true, RequireLower: true, RequireDigit: true}
 }
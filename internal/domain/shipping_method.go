@@ -0,0 +1,109 @@
+package domain
+
+import "time"
+
+// Shipping method states of a cart's shipping info.
+const (
+	ShippingMethodMatchesCart      = "MatchesCart"
+	ShippingMethodDoesNotMatchCart = "DoesNotMatchCart"
+)
+
+// ShippingMethod has one price per currency for each zone it ships to.
+// Predicate is a cart predicate that limits the carts the method is offered for.
+type ShippingMethod struct {
+	ID             string     `json:"id"`
+	ProjectID      string     `json:"-"`
+	Key            string     `json:"key,omitempty"`
+	Version        int        `json:"version"`
+	Name           string     `json:"name"`
+	Description    string     `json:"description,omitempty"`
+	TaxCategoryID  string     `json:"taxCategoryId"`
+	ZoneRates      []ZoneRate `json:"zoneRates"`
+	IsDefault      bool       `json:"isDefault"`
+	Predicate      string     `json:"predicate,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	LastModifiedAt time.Time  `json:"lastModifiedAt"`
+}
+
+// ZoneRate holds the rates of one zone, at most one per currency.
+type ZoneRate struct {
+	ZoneID        string         `json:"zoneId"`
+	ShippingRates []ShippingRate `json:"shippingRates"`
+}
+
+// ShippingRate is the shipping price in one currency; shipping is free for carts
+// worth at least FreeAbove.
+type ShippingRate struct {
+	Price     Money  `json:"price"`
+	FreeAbove *Money `json:"freeAbove,omitempty"`
+}
+
+// RateFor returns the rate in currency of the first zone rate for one of
+// zoneIDs, or nil if the method does not ship there in that currency.
+func (m ShippingMethod) RateFor(zoneIDs []string, currency string) *ShippingRate {
+	for _, zr := range m.ZoneRates {
+		if !containsString(zoneIDs, zr.ZoneID) {
+			continue
+		}
+		for i := range zr.ShippingRates {
+			if zr.ShippingRates[i].Price.CurrencyCode == currency {
+				rate := zr.ShippingRates[i]
+				return &rate
+			}
+		}
+	}
+	return nil
+}
+
+// PriceFor returns the price of shipping a cart worth total cents.
+func (r ShippingRate) PriceFor(total int64) Money {
+	if r.FreeAbove != nil && total >= r.FreeAbove.CentAmount {
+		return Money{CurrencyCode: r.Price.CurrencyCode}
+	}
+	return Money{CurrencyCode: r.Price.CurrencyCode, CentAmount: r.Price.CentAmount}
+}
+
+// ShippingInfo is the shipping method set on a cart and what it costs for the
+// cart. DiscountedPrice is set once a shipping cart discount lowered Price.
+type ShippingInfo struct {
+	ShippingMethodID    string                   `json:"shippingMethodId"`
+	ShippingMethodName  string                   `json:"shippingMethodName"`
+	Price               Money                    `json:"price"`
+	ShippingRate        ShippingRate             `json:"shippingRate"`
+	DiscountedPrice     *DiscountedShippingPrice `json:"discountedPrice,omitempty"`
+	TaxCategoryID       string                   `json:"taxCategoryId,omitempty"`
+	TaxRate             *TaxRate                 `json:"taxRate,omitempty"`
+	TaxedPrice          *TaxedPrice              `json:"taxedPrice,omitempty"`
+	ShippingMethodState string                   `json:"shippingMethodState"`
+}
+
+// Shipping is one of the shipping methods of a cart in Multiple shipping mode,
+// identified within the cart by ShippingKey.
+type Shipping struct {
+	ShippingKey     string          `json:"shippingKey"`
+	ShippingInfo    ShippingInfo    `json:"shippingInfo"`
+	ShippingAddress CustomerAddress `json:"shippingAddress"`
+}
+
+// DiscountedShippingPrice is the shipping price after shipping cart discounts.
+type DiscountedShippingPrice struct {
+	Value             Money             `json:"value"`
+	IncludedDiscounts []DiscountPortion `json:"includedDiscounts"`
+}
+
+// Cost returns the shipping price after discounts.
+func (s ShippingInfo) Cost() Money {
+	if s.DiscountedPrice != nil {
+		return s.DiscountedPrice.Value
+	}
+	return s.Price
+}
+
+func containsString(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
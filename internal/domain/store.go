@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Store is one storefront of a project. Empty Languages and Countries do not
+// restrict anything; a store without product selections sells every product.
+type Store struct {
+	ID                     string                  `json:"id"`
+	ProjectID              string                  `json:"-"`
+	Key                    string                  `json:"key"`
+	Version                int                     `json:"version"`
+	Name                   LocalizedString         `json:"name,omitempty"`
+	Languages              []string                `json:"languages"`
+	Countries              []string                `json:"countries"`
+	DistributionChannelIDs []string                `json:"distributionChannelIds"`
+	SupplyChannelIDs       []string                `json:"supplyChannelIds"`
+	ProductSelections      []StoreProductSelection `json:"productSelections"`
+	CreatedAt              time.Time               `json:"createdAt"`
+	LastModifiedAt         time.Time               `json:"lastModifiedAt"`
+}
+
+// StoreProductSelection adds the products of a selection to a store's
+// assortment while Active.
+type StoreProductSelection struct {
+	ProductSelectionID string `json:"productSelectionId"`
+	Active             bool   `json:"active"`
+}
+
+// SellsInCountry reports whether carts of the store may use country.
+func (s Store) SellsInCountry(country string) bool {
+	if len(s.Countries) == 0 {
+		return true
+	}
+	for _, c := range s.Countries {
+		if strings.EqualFold(c, country) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasSupplyChannel reports whether the store takes stock from the channel.
+func (s Store) HasSupplyChannel(id string) bool {
+	for _, c := range s.SupplyChannelIDs {
+		if c == id {
+			return true
+		}
+	}
+	return false
+}
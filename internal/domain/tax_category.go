@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"commercetools-replica/internal/money"
+)
+
+// Tax modes, rounding modes and calculation modes of carts.
+const (
+	TaxModePlatform = "Platform"
+	TaxModeDisabled = "Disabled"
+
+	RoundingHalfEven = string(money.HalfEven)
+	RoundingHalfUp   = string(money.HalfUp)
+	RoundingHalfDown = string(money.HalfDown)
+
+	TaxCalculationLineItemLevel  = "LineItemLevel"
+	TaxCalculationUnitPriceLevel = "UnitPriceLevel"
+)
+
+// TaxCategory groups the tax rates products are taxed with, one per country
+// (and optionally state).
+type TaxCategory struct {
+	ID             string    `json:"id"`
+	ProjectID      string    `json:"-"`
+	Key            string    `json:"key,omitempty"`
+	Version        int       `json:"version"`
+	Name           string    `json:"name"`
+	Description    string    `json:"description,omitempty"`
+	Rates          []TaxRate `json:"rates"`
+	CreatedAt      time.Time `json:"createdAt"`
+	LastModifiedAt time.Time `json:"lastModifiedAt"`
+}
+
+// TaxRate is a rate such as 0.19 for a country. IncludedInPrice rates are
+// already part of the price (gross prices); others are added on top.
+type TaxRate struct {
+	ID              string       `json:"id,omitempty"`
+	Name            string       `json:"name"`
+	Amount          float64      `json:"amount"`
+	IncludedInPrice bool         `json:"includedInPrice"`
+	Country         string       `json:"country"`
+	State           string       `json:"state,omitempty"`
+	SubRates        []TaxSubRate `json:"subRates,omitempty"`
+}
+
+// TaxSubRate is a part of a rate, e.g. the state share of a sales tax; the
+// sub rates add up to the rate amount.
+type TaxSubRate struct {
+	Name   string  `json:"name"`
+	Amount float64 `json:"amount"`
+}
+
+// RateFor returns the rate for country and state; a rate for the state wins
+// over one for the whole country.
+func (c TaxCategory) RateFor(country, state string) *TaxRate {
+	var match *TaxRate
+	for i := range c.Rates {
+		r := &c.Rates[i]
+		if !strings.EqualFold(r.Country, country) {
+			continue
+		}
+		if r.State != "" {
+			if strings.EqualFold(r.State, state) {
+				return r
+			}
+			continue
+		}
+		match = r
+	}
+	return match
+}
+
+// TaxPortion is the tax of one rate or sub rate.
+type TaxPortion struct {
+	Name   string  `json:"name,omitempty"`
+	Rate   float64 `json:"rate"`
+	Amount Money   `json:"amount"`
+}
+
+// TaxedPrice is the net and gross amount of a line item or cart.
+type TaxedPrice struct {
+	TotalNet    Money        `json:"totalNet"`
+	TotalGross  Money        `json:"totalGross"`
+	TotalTax    Money        `json:"totalTax"`
+	TaxPortions []TaxPortion `json:"taxPortions"`
+}
@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Zone groups the locations shipping methods have rates for.
+type Zone struct {
+	ID             string     `json:"id"`
+	ProjectID      string     `json:"-"`
+	Key            string     `json:"key,omitempty"`
+	Version        int        `json:"version"`
+	Name           string     `json:"name"`
+	Description    string     `json:"description,omitempty"`
+	Locations      []Location `json:"locations"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	LastModifiedAt time.Time  `json:"lastModifiedAt"`
+}
+
+// Location is a country, optionally narrowed down to one of its states.
+type Location struct {
+	Country string `json:"country"`
+	State   string `json:"state,omitempty"`
+}
+
+// Contains reports whether the zone covers an address in country and state;
+// locations without a state cover the whole country.
+func (z Zone) Contains(country, state string) bool {
+	for _, l := range z.Locations {
+		if !strings.EqualFold(l.Country, country) {
+			continue
+		}
+		if l.State == "" || strings.EqualFold(l.State, state) {
+			return true
+		}
+	}
+	return false
+}
+
+// ZonesContaining returns the ids of the zones covering country and state.
+func ZonesContaining(zones []Zone, country, state string) []string {
+	var ids []string
+	for _, z := range zones {
+		if z.Contains(country, state) {
+			ids = append(ids, z.ID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,203 @@
+package httpserver
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"commercetools-replica/internal/domain"
+	businessunitsvc "commercetools-replica/internal/service/businessunit"
+	cartsvc "commercetools-replica/internal/service/cart"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	associateCtxKey    ctxKey = "associate"
+	businessUnitCtxKey ctxKey = "businessUnit"
+)
+
+// errBusinessUnitCart refuses business unit carts outside the as-associate
+// routes, which check the associate's permissions.
+var errBusinessUnitCart = errors.New("carts of a business unit are managed on /as-associate/{associateId}/in-business-unit/key={buKey}/carts")
+
+// associateMiddleware authenticates the associate of the as-associate routes
+// with the associate's own customer token and loads the business unit named
+// by the buKey path parameter.
+func associateMiddleware(logger *log.Logger, customers customerService, units businessUnitService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		project := mustProject(c)
+		customer, ok := authorizeCustomer(c, project, customers)
+		if !ok {
+			c.Abort()
+			return
+		}
+		if customer.ID != c.Param("associateId") {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "token does not belong to the associate"})
+			return
+		}
+		key := c.Param("buKey")
+		unit, err := units.GetByKey(c.Request.Context(), project.ID, key)
+		if err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "business unit not found"})
+				return
+			}
+			logger.Printf("business unit lookup error project_id=%s key=%s error=%v", project.ID, key, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "business unit lookup failed"})
+			return
+		}
+		c.Set(string(associateCtxKey), customer)
+		c.Set(string(businessUnitCtxKey), unit)
+		c.Next()
+	}
+}
+
+// currentAssociate returns the associate and business unit of an
+// as-associate route.
+func currentAssociate(c *gin.Context) (*domain.Customer, *domain.BusinessUnit) {
+	customer, _ := c.MustGet(string(associateCtxKey)).(*domain.Customer)
+	unit, _ := c.MustGet(string(businessUnitCtxKey)).(*domain.BusinessUnit)
+	return customer, unit
+}
+
+// registerAsAssociateRoutes registers the carts of the as-associate routes.
+// Each handler checks the associate's permissions in the unit: the My
+// permission for the associate's own carts, the Others one for carts of
+// other customers.
+func registerAsAssociateRoutes(group *gin.RouterGroup, logger *log.Logger, deps Deps, fileURLHost string) {
+	// authorize writes 403 unless the associate has the permission for carts
+	// of owner.
+	authorize := func(c *gin.Context, owner, mine, others string) bool {
+		associate, unit := currentAssociate(c)
+		permission := mine
+		if owner != associate.ID {
+			permission = others
+		}
+		err := deps.BusinessUnitSvc.Authorize(c.Request.Context(), unit, associate.ID, permission)
+		if err != nil {
+			if errors.Is(err, businessunitsvc.ErrNotAssociate) || errors.Is(err, businessunitsvc.ErrPermissionDenied) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return false
+			}
+			logger.Printf("associate permissions error business_unit=%s customer_id=%s error=%v", unit.Key, associate.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "resolve permissions failed"})
+			return false
+		}
+		return true
+	}
+
+	// unitCart loads a cart of the business unit; carts of other units or
+	// without a customer are not found.
+	unitCart := func(c *gin.Context, projectID, id string) (*domain.Cart, error) {
+		_, unit := currentAssociate(c)
+		cart, err := deps.CartSvc.Get(c.Request.Context(), projectID, id)
+		if err != nil {
+			return nil, err
+		}
+		if cart.BusinessUnitKey != unit.Key || cart.CustomerID == nil {
+			return nil, domain.ErrNotFound
+		}
+		return cart, nil
+	}
+
+	writeCartError := func(c *gin.Context, op, projectID, id string, err error) {
+		if errors.Is(err, domain.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "cart not found"})
+			return
+		}
+		logger.Printf("associate cart %s error project_id=%s cart_id=%s error=%v", op, projectID, id, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+
+	// Creating a cart for another customer needs CreateOthersCarts, and the
+	// customer has to be an associate of the unit as well.
+	group.POST("/carts", func(c *gin.Context) {
+		project := mustProject(c)
+		associate, unit := currentAssociate(c)
+		var req cartsvc.CreateInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+		if req.CustomerID == nil || *req.CustomerID == "" {
+			req.CustomerID = &associate.ID
+		}
+		if !authorize(c, *req.CustomerID, domain.PermissionCreateMyCarts, domain.PermissionCreateOthersCarts) {
+			return
+		}
+		if *req.CustomerID != associate.ID {
+			if _, err := deps.BusinessUnitSvc.Permissions(c.Request.Context(), unit, *req.CustomerID); err != nil {
+				if errors.Is(err, businessunitsvc.ErrNotAssociate) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "customer " + *req.CustomerID + " is not an associate of the business unit"})
+					return
+				}
+				logger.Printf("associate permissions error business_unit=%s customer_id=%s error=%v", unit.Key, *req.CustomerID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "resolve permissions failed"})
+				return
+			}
+		}
+		req.AnonymousID = nil
+		req.BusinessUnit = &cartsvc.BusinessUnitReference{TypeID: "business-unit", Key: unit.Key}
+		cart, err := deps.CartSvc.Create(c.Request.Context(), project.ID, req)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, toCTCart(*cart, nil, fileURLHost, localeFromRequest(c)))
+	})
+	group.GET("/carts/:id", func(c *gin.Context) {
+		project := mustProject(c)
+		id := c.Param("id")
+		cart, err := unitCart(c, project.ID, id)
+		if err != nil {
+			writeCartError(c, "get", project.ID, id, err)
+			return
+		}
+		if !authorize(c, *cart.CustomerID, domain.PermissionViewMyCarts, domain.PermissionViewOthersCarts) {
+			return
+		}
+		c.JSON(http.StatusOK, toCTCart(*cart, nil, fileURLHost, localeFromRequest(c)))
+	})
+	group.POST("/carts/:id", func(c *gin.Context) {
+		project := mustProject(c)
+		id := c.Param("id")
+		var req cartsvc.UpdateInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+		cart, err := unitCart(c, project.ID, id)
+		if err != nil {
+			writeCartError(c, "update", project.ID, id, err)
+			return
+		}
+		if !authorize(c, *cart.CustomerID, domain.PermissionUpdateMyCarts, domain.PermissionUpdateOthersCarts) {
+			return
+		}
+		cart, err = deps.CartSvc.Update(c.Request.Context(), project.ID, *cart.CustomerID, id, req)
+		if err != nil {
+			writeCartError(c, "update", project.ID, id, err)
+			return
+		}
+		c.JSON(http.StatusOK, toCTCart(*cart, nil, fileURLHost, localeFromRequest(c)))
+	})
+	group.DELETE("/carts/:id", func(c *gin.Context) {
+		project := mustProject(c)
+		id := c.Param("id")
+		cart, err := unitCart(c, project.ID, id)
+		if err != nil {
+			writeCartError(c, "delete", project.ID, id, err)
+			return
+		}
+		if !authorize(c, *cart.CustomerID, domain.PermissionDeleteMyCarts, domain.PermissionDeleteOthersCarts) {
+			return
+		}
+		cart, err = deps.CartSvc.Delete(c.Request.Context(), project.ID, *cart.CustomerID, id)
+		if err != nil {
+			writeCartError(c, "delete", project.ID, id, err)
+			return
+		}
+		c.JSON(http.StatusOK, toCTCart(*cart, nil, fileURLHost, localeFromRequest(c)))
+	})
+}
@@ -0,0 +1,38 @@
+package httpserver
+
+import (
+	"context"
+	"log"
+
+	"commercetools-replica/internal/domain"
+	associaterolesvc "commercetools-replica/internal/service/associaterole"
+
+	"github.com/gin-gonic/gin"
+)
+
+type associateRoleService interface {
+	ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.AssociateRole, int, error)
+	Get(ctx context.Context, projectID, id string) (*domain.AssociateRole, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.AssociateRole, error)
+	Create(ctx context.Context, projectID string, draft associaterolesvc.AssociateRoleDraft) (*domain.AssociateRole, error)
+	Update(ctx context.Context, projectID, id string, in associaterolesvc.UpdateInput) (*domain.AssociateRole, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.AssociateRole, error)
+}
+
+// registerAssociateRoleRoutes registers the /associate-roles routes.
+func registerAssociateRoleRoutes(read, write *gin.RouterGroup, logger *log.Logger, svc associateRoleService) {
+	routes := crudRoutes[domain.AssociateRole, associaterolesvc.AssociateRoleDraft, associaterolesvc.UpdateInput]{
+		path:       "/associate-roles",
+		singular:   "associate role",
+		plural:     "associate roles",
+		svc:        svc,
+		id:         func(a *domain.AssociateRole) string { return a.ID },
+		draftField: func(d associaterolesvc.AssociateRoleDraft) string { return "key=" + d.Key },
+		conflict:   "associate role with this key already exists",
+		render:     func(_ *gin.Context, a domain.AssociateRole) any { return toCTAssociateRole(a) },
+		list: func(_ *gin.Context, items []domain.AssociateRole, total, limit, offset int) any {
+			return buildAssociateRoleList(items, total, limit, offset)
+		},
+	}
+	routes.register(read, write, logger)
+}
@@ -2,10 +2,12 @@ package httpserver
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"commercetools-replica/internal/domain"
 	cartsvc "commercetools-replica/internal/service/cart"
@@ -14,17 +16,27 @@ import (
 )
 
 type stubCustomerAuthSvc struct {
-	customer *domain.Customer
-	loginErr error
-	signErr  error
-	meErr    error
+	customer     *domain.Customer
+	loginErr     error
+	loginIP      string
+	signErr      error
+	meErr        error
+	updateErr    error
+	passwordErr  error
+	keptToken    string
+	confirmedFor string
+	tokenOpts    customersvc.TokenOptions
 }
 
 func (s *stubCustomerAuthSvc) Signup(_ context.Context, _ string, _ customersvc.SignupInput) (*domain.Customer, error) {
 	return s.customer, s.signErr
 }
 
-func (s *stubCustomerAuthSvc) Login(_ context.Context, _ string, _ string, _ string) (*domain.Customer, string, string, error) {
+func (s *stubCustomerAuthSvc) Login(_ context.Context, _ string, _ string, _ string, clientIP string, requireVerifiedEmail bool) (*domain.Customer, string, string, error) {
+	s.loginIP = clientIP
+	if requireVerifiedEmail && !s.customer.IsEmailVerified {
+		return nil, "", "", customersvc.ErrEmailNotVerified
+	}
 	return s.customer, "access", "refresh", s.loginErr
 }
 
@@ -32,6 +44,75 @@ func (s *stubCustomerAuthSvc) LookupByToken(_ context.Context, _ string, _ strin
 	return s.customer, s.meErr
 }
 
+func (s *stubCustomerAuthSvc) Update(_ context.Context, _, _ string, _ customersvc.UpdateInput) (*domain.Customer, error) {
+	return s.customer, nil
+}
+
+func (s *stubCustomerAuthSvc) ChangePassword(_ context.Context, _, _ string, keepToken string, _ customersvc.ChangePasswordInput) (*domain.Customer, error) {
+	s.keptToken = keepToken
+	if s.passwordErr != nil {
+		return nil, s.passwordErr
+	}
+	return s.customer, nil
+}
+
+func (s *stubCustomerAuthSvc) CreatePasswordToken(_ context.Context, _, _ string, opts customersvc.TokenOptions) (*customersvc.CustomerToken, error) {
+	s.tokenOpts = opts
+	if s.passwordErr != nil {
+		return nil, s.passwordErr
+	}
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &customersvc.CustomerToken{Value: "reset-token", CustomerID: s.customer.ID, CreatedAt: created, ExpiresAt: created.Add(opts.TTL)}, nil
+}
+
+func (s *stubCustomerAuthSvc) ResetPassword(_ context.Context, _ string, _ customersvc.ResetPasswordInput) (*domain.Customer, error) {
+	if s.passwordErr != nil {
+		return nil, s.passwordErr
+	}
+	return s.customer, nil
+}
+
+func (s *stubCustomerAuthSvc) CreateEmailToken(_ context.Context, _, _ string, _ int, opts customersvc.TokenOptions) (*customersvc.CustomerToken, error) {
+	if s.passwordErr != nil {
+		return nil, s.passwordErr
+	}
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &customersvc.CustomerToken{Value: "email-token", CustomerID: s.customer.ID, CreatedAt: created, ExpiresAt: created.Add(opts.TTL)}, nil
+}
+
+func (s *stubCustomerAuthSvc) ConfirmEmail(_ context.Context, _, customerID string, _ customersvc.ConfirmEmailInput) (*domain.Customer, error) {
+	s.confirmedFor = customerID
+	if s.passwordErr != nil {
+		return nil, s.passwordErr
+	}
+	verified := *s.customer
+	verified.IsEmailVerified = true
+	return &verified, nil
+}
+
+func (s *stubCustomerAuthSvc) ListPage(_ context.Context, _, _ string, _, _ int) ([]domain.Customer, int, error) {
+	return nil, 0, nil
+}
+
+func (s *stubCustomerAuthSvc) Get(_ context.Context, _, _ string) (*domain.Customer, error) {
+	return s.customer, nil
+}
+
+func (s *stubCustomerAuthSvc) Create(_ context.Context, _ string, _ customersvc.CustomerDraft) (*domain.Customer, error) {
+	return s.customer, nil
+}
+
+func (s *stubCustomerAuthSvc) Delete(_ context.Context, _, _ string, _ int) (*domain.Customer, error) {
+	return s.customer, nil
+}
+
+func (s *stubCustomerAuthSvc) UpdateMe(_ context.Context, _, _ string, _ customersvc.UpdateInput) (*domain.Customer, error) {
+	if s.updateErr != nil {
+		return nil, s.updateErr
+	}
+	return s.customer, nil
+}
+
 func (s *stubCustomerAuthSvc) AccessTTLSeconds() int {
 	return 3600
 }
@@ -61,6 +142,14 @@ func (s *stubLoginCartService) GetActiveAnonymous(_ context.Context, _ string, _
 	return nil, nil
 }
 
+func (s *stubLoginCartService) GetActiveInStore(_ context.Context, _, _, _ string) (*domain.Cart, error) {
+	return nil, nil
+}
+
+func (s *stubLoginCartService) GetActiveAnonymousInStore(_ context.Context, _, _, _ string) (*domain.Cart, error) {
+	return nil, nil
+}
+
 func (s *stubLoginCartService) UpdateAnonymous(_ context.Context, _ string, _ string, _ string, _ cartsvc.UpdateInput) (*domain.Cart, error) {
 	return nil, nil
 }
@@ -69,6 +158,18 @@ func (s *stubLoginCartService) AssignCustomerFromAnonymous(_ context.Context, _
 	return nil, nil
 }
 
+func (s *stubLoginCartService) Delete(_ context.Context, _ string, _ string, _ string) (*domain.Cart, error) {
+	return nil, nil
+}
+
+func (s *stubLoginCartService) DeleteAnonymous(_ context.Context, _ string, _ string, _ string) (*domain.Cart, error) {
+	return nil, nil
+}
+
+func (s *stubLoginCartService) MatchingShippingMethods(_ context.Context, _ string, _ string) ([]domain.ShippingMethod, error) {
+	return nil, nil
+}
+
 func TestSignupHandler_Created(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
@@ -83,7 +184,7 @@ func TestSignupHandler_Created(t *testing.T) {
 		CategorySvc:  &stubCategoryService{},
 		CustomerSvc:  authSvc,
 		AnonymousSvc: &stubAnonymousService{},
-	})
+	}, "")
 	if err != nil {
 		t.Fatalf("build router: %v", err)
 	}
@@ -117,7 +218,7 @@ func TestTokenHandler_InvalidCredentials(t *testing.T) {
 		CategorySvc:  &stubCategoryService{},
 		CustomerSvc:  authSvc,
 		AnonymousSvc: &stubAnonymousService{},
-	})
+	}, "")
 	if err != nil {
 		t.Fatalf("build router: %v", err)
 	}
@@ -146,7 +247,7 @@ func TestMeHandler_UnauthorizedWithoutToken(t *testing.T) {
 		CategorySvc:  &stubCategoryService{},
 		CustomerSvc:  authSvc,
 		AnonymousSvc: &stubAnonymousService{},
-	})
+	}, "")
 	if err != nil {
 		t.Fatalf("build router: %v", err)
 	}
@@ -175,7 +276,7 @@ func TestMeHandler_Success(t *testing.T) {
 		CategorySvc:  &stubCategoryService{},
 		CustomerSvc:  authSvc,
 		AnonymousSvc: &stubAnonymousService{},
-	})
+	}, "")
 	if err != nil {
 		t.Fatalf("build router: %v", err)
 	}
@@ -194,6 +295,199 @@ func TestMeHandler_Success(t *testing.T) {
 	}
 }
 
+func TestMeUpdateHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
+	authSvc := &stubCustomerAuthSvc{
+		customer: &domain.Customer{ID: "cust-id", ProjectID: proj.ID, Version: 2, Email: "me@example.com", FirstName: "Ada"},
+	}
+	router, err := buildRouter(logDiscard(), nil, Deps{
+		ProjectRepo:  &stubProjectRepo{project: proj},
+		ProductSvc:   &stubProductService{},
+		CartSvc:      &stubCartService{},
+		CategorySvc:  &stubCategoryService{},
+		CustomerSvc:  authSvc,
+		AnonymousSvc: &stubAnonymousService{},
+	}, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	cases := []struct {
+		name      string
+		token     string
+		updateErr error
+		status    int
+		contains  string
+	}{
+		{name: "updated", token: "token", status: http.StatusOK, contains: `"firstName":"Ada"`},
+		{name: "without token", status: http.StatusUnauthorized},
+		{name: "stale version", token: "token", updateErr: domain.ErrConcurrentModification, status: http.StatusConflict},
+		{name: "email taken", token: "token", updateErr: domain.ErrAlreadyExists, status: http.StatusConflict},
+		{name: "unknown address", token: "token", updateErr: errors.New("address nope not found"), status: http.StatusBadRequest, contains: "address nope not found"},
+	}
+	for _, tc := range cases {
+		authSvc.updateErr = tc.updateErr
+		body := `{"version":1,"actions":[{"action":"setFirstName","firstName":"Ada"}]}`
+		req := httptest.NewRequest(http.MethodPost, "/proj-key/me", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected %d, got %d body=%s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		if !strings.Contains(rec.Body.String(), tc.contains) {
+			t.Fatalf("%s: expected %s in %s", tc.name, tc.contains, rec.Body.String())
+		}
+	}
+}
+
+func TestPasswordHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
+	authSvc := &stubCustomerAuthSvc{
+		customer: &domain.Customer{ID: "cust-id", ProjectID: proj.ID, Version: 3, Email: "me@example.com"},
+	}
+	router, err := buildRouter(logDiscard(), nil, Deps{
+		ProjectRepo:  &stubProjectRepo{project: proj},
+		ProductSvc:   &stubProductService{},
+		CartSvc:      &stubCartService{},
+		CategorySvc:  &stubCategoryService{},
+		CustomerSvc:  authSvc,
+		AnonymousSvc: &stubAnonymousService{},
+		AdminSvc:     &stubAdminService{token: "admin-token"},
+	}, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	cases := []struct {
+		name        string
+		url         string
+		body        string
+		token       string
+		passwordErr error
+		status      int
+		contains    string
+	}{
+		{name: "change password", url: "/proj-key/me/password", token: "token", body: `{"version":2,"currentPassword":"old-secret","newPassword":"new-secret"}`, status: http.StatusOK, contains: `"version":3`},
+		{name: "change without token", url: "/proj-key/me/password", body: `{}`, status: http.StatusUnauthorized},
+		{name: "wrong current password", url: "/proj-key/me/password", token: "token", body: `{"version":2}`, passwordErr: customersvc.ErrInvalidCurrentPassword, status: http.StatusBadRequest, contains: customersvc.ErrInvalidCurrentPassword.Error()},
+		{name: "stale version", url: "/proj-key/me/password", token: "token", body: `{"version":1}`, passwordErr: domain.ErrConcurrentModification, status: http.StatusConflict},
+		{name: "create token", url: "/proj-key/customers/password-token", token: "admin-token", body: `{"email":"me@example.com","ttlMinutes":30}`, status: http.StatusOK, contains: `"expiresAt":"2024-01-01T00:30:00Z"`},
+		{name: "unknown email", url: "/proj-key/customers/password-token", token: "admin-token", body: `{"email":"nobody@example.com"}`, passwordErr: domain.ErrNotFound, status: http.StatusNotFound},
+		{name: "reset", url: "/proj-key/customers/password/reset", token: "admin-token", body: `{"tokenValue":"reset-token","newPassword":"new-secret"}`, status: http.StatusOK, contains: `"id":"cust-id"`},
+		{name: "reset with used token", url: "/proj-key/customers/password/reset", token: "admin-token", body: `{"tokenValue":"reset-token","newPassword":"new-secret"}`, passwordErr: customersvc.ErrInvalidToken, status: http.StatusNotFound},
+		{name: "reset as customer", url: "/proj-key/me/password/reset", body: `{"tokenValue":"reset-token","newPassword":"new-secret"}`, status: http.StatusOK, contains: `"id":"cust-id"`},
+		{name: "create token with customer token", url: "/proj-key/customers/password-token", token: "token", body: `{"email":"me@example.com"}`, status: http.StatusForbidden},
+		{name: "create token without token", url: "/proj-key/customers/password-token", body: `{"email":"me@example.com"}`, status: http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		authSvc.passwordErr = tc.passwordErr
+		req := httptest.NewRequest(http.MethodPost, tc.url, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected %d, got %d body=%s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		if !strings.Contains(rec.Body.String(), tc.contains) {
+			t.Fatalf("%s: expected %s in %s", tc.name, tc.contains, rec.Body.String())
+		}
+	}
+	if authSvc.keptToken != "token" {
+		t.Fatalf("expected request token to be kept, got %q", authSvc.keptToken)
+	}
+
+	// The mail template follows the project and the request language.
+	authSvc.passwordErr = nil
+	req := httptest.NewRequest(http.MethodPost, "/proj-key/customers/password-token", strings.NewReader(`{"email":"me@example.com","ttlMinutes":5}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "de-DE,de;q=0.9")
+	req.Header.Set("Authorization", "Bearer admin-token")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	want := customersvc.TokenOptions{TTL: 5 * time.Minute, ProjectKey: "proj-key", Locale: "de-DE"}
+	if rec.Code != http.StatusOK || authSvc.tokenOpts != want {
+		t.Fatalf("expected %+v, got %+v (status %d)", want, authSvc.tokenOpts, rec.Code)
+	}
+}
+
+func TestEmailVerificationHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key", RequireEmailVerification: true}
+	authSvc := &stubCustomerAuthSvc{
+		customer: &domain.Customer{ID: "cust-id", ProjectID: proj.ID, Version: 1, Email: "me@example.com"},
+	}
+	router, err := buildRouter(logDiscard(), nil, Deps{
+		ProjectRepo:  &stubProjectRepo{project: proj},
+		ProductSvc:   &stubProductService{},
+		CartSvc:      &stubLoginCartService{err: domain.ErrNotFound},
+		CategorySvc:  &stubCategoryService{},
+		CustomerSvc:  authSvc,
+		AnonymousSvc: &stubAnonymousService{},
+		AdminSvc:     &stubAdminService{token: "admin-token"},
+	}, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	cases := []struct {
+		name         string
+		url          string
+		body         string
+		token        string
+		errOut       error
+		status       int
+		contains     string
+		confirmedFor string
+	}{
+		{name: "create token", url: "/proj-key/customers/email-token", token: "admin-token", body: `{"id":"cust-id","ttlMinutes":60}`, status: http.StatusOK, contains: `"value":"email-token"`},
+		{name: "unknown customer", url: "/proj-key/customers/email-token", token: "admin-token", body: `{"id":"nope"}`, errOut: domain.ErrNotFound, status: http.StatusNotFound},
+		{name: "stale version", url: "/proj-key/customers/email-token", token: "admin-token", body: `{"id":"cust-id","version":9}`, errOut: domain.ErrConcurrentModification, status: http.StatusConflict},
+		{name: "confirm", url: "/proj-key/customers/email/confirm", token: "admin-token", body: `{"tokenValue":"email-token"}`, status: http.StatusOK, contains: `"isEmailVerified":true`},
+		{name: "confirm with used token", url: "/proj-key/customers/email/confirm", token: "admin-token", body: `{"tokenValue":"email-token"}`, errOut: customersvc.ErrInvalidToken, status: http.StatusNotFound},
+		{name: "confirm me", url: "/proj-key/me/email/confirm", token: "token", body: `{"tokenValue":"email-token"}`, status: http.StatusOK, contains: `"isEmailVerified":true`, confirmedFor: "cust-id"},
+		{name: "confirm me without token", url: "/proj-key/me/email/confirm", body: `{"tokenValue":"email-token"}`, status: http.StatusUnauthorized},
+		{name: "login unverified", url: "/proj-key/me/login", body: `{"email":"me@example.com","password":"secret"}`, status: http.StatusForbidden, contains: customersvc.ErrEmailNotVerified.Error()},
+	}
+	for _, tc := range cases {
+		authSvc.passwordErr = tc.errOut
+		authSvc.confirmedFor = ""
+		req := httptest.NewRequest(http.MethodPost, tc.url, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected %d, got %d body=%s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		if !strings.Contains(rec.Body.String(), tc.contains) {
+			t.Fatalf("%s: expected %s in %s", tc.name, tc.contains, rec.Body.String())
+		}
+		if authSvc.confirmedFor != tc.confirmedFor {
+			t.Fatalf("%s: expected confirm for %q, got %q", tc.name, tc.confirmedFor, authSvc.confirmedFor)
+		}
+	}
+
+	body := `grant_type=password&username=me%40example.com&password=secret&scope=manage_project:proj-key`
+	req := httptest.NewRequest(http.MethodPost, "/oauth/proj-key/customers/token", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for unverified token request, got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
 func TestLoginHandler_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
@@ -209,7 +503,7 @@ func TestLoginHandler_Success(t *testing.T) {
 		CategorySvc:  &stubCategoryService{},
 		CustomerSvc:  authSvc,
 		AnonymousSvc: &stubAnonymousService{},
-	})
+	}, "")
 	if err != nil {
 		t.Fatalf("build router: %v", err)
 	}
@@ -242,7 +536,7 @@ func TestLoginHandler_InvalidBody(t *testing.T) {
 		CategorySvc:  &stubCategoryService{},
 		CustomerSvc:  authSvc,
 		AnonymousSvc: &stubAnonymousService{},
-	})
+	}, "")
 	if err != nil {
 		t.Fatalf("build router: %v", err)
 	}
@@ -273,7 +567,7 @@ func TestLoginHandler_InvalidCredentials(t *testing.T) {
 		CategorySvc:  &stubCategoryService{},
 		CustomerSvc:  authSvc,
 		AnonymousSvc: &stubAnonymousService{},
-	})
+	}, "")
 	if err != nil {
 		t.Fatalf("build router: %v", err)
 	}
@@ -289,3 +583,149 @@ func TestLoginHandler_InvalidCredentials(t *testing.T) {
 		t.Fatalf("expected 401, got %d body=%s", rec.Code, rec.Body.String())
 	}
 }
+
+func TestLoginHandlers_TooManyAttempts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
+	authSvc := &stubCustomerAuthSvc{
+		loginErr: &customersvc.TooManyAttemptsError{RetryAfter: 1500 * time.Millisecond},
+	}
+	router, err := buildRouter(logDiscard(), nil, Deps{
+		ProjectRepo:  &stubProjectRepo{project: proj},
+		ProductSvc:   &stubProductService{},
+		CartSvc:      &stubLoginCartService{},
+		CategorySvc:  &stubCategoryService{},
+		CustomerSvc:  authSvc,
+		AnonymousSvc: &stubAnonymousService{},
+	}, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/proj-key/me/login", strings.NewReader(`{"email":"user@example.com","password":"bad"}`)),
+		httptest.NewRequest(http.MethodPost, "/oauth/proj-key/customers/token",
+			strings.NewReader(`grant_type=password&username=user%40example.com&password=bad&scope=manage_project:proj-key`)),
+	}
+	requests[0].Header.Set("Content-Type", "application/json")
+	requests[1].Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, req := range requests {
+		req.RemoteAddr = "192.0.2.7:4321"
+		authSvc.loginIP = ""
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("%s: expected 429, got %d body=%s", req.URL.Path, rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get("Retry-After"); got != "2" {
+			t.Fatalf("%s: expected Retry-After 2, got %q", req.URL.Path, got)
+		}
+		for _, want := range []string{`"statusCode":429`, `"code":"TooManyRequests"`, `"retryAfterSeconds":2`} {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Fatalf("%s: expected %s in %s", req.URL.Path, want, rec.Body.String())
+			}
+		}
+		if authSvc.loginIP != "192.0.2.7" {
+			t.Fatalf("%s: expected the client IP to reach Login, got %q", req.URL.Path, authSvc.loginIP)
+		}
+	}
+}
+
+func TestLoginHandlers_ClientIPIgnoresSpoofedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
+	authSvc := &stubCustomerAuthSvc{loginErr: customersvc.ErrInvalidCredentials}
+	deps := Deps{
+		ProjectRepo:  &stubProjectRepo{project: proj},
+		ProductSvc:   &stubProductService{},
+		CartSvc:      &stubLoginCartService{},
+		CategorySvc:  &stubCategoryService{},
+		CustomerSvc:  authSvc,
+		AnonymousSvc: &stubAnonymousService{},
+	}
+	direct, err := buildRouter(logDiscard(), nil, deps, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+	deps.TrustedProxies = []string{"10.0.0.1"}
+	proxied, err := buildRouter(logDiscard(), nil, deps, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	cases := []struct {
+		name      string
+		router    *gin.Engine
+		remote    string
+		forwarded string
+		want      string
+	}{
+		{name: "no header", router: direct, remote: "192.0.2.7:4321", want: "192.0.2.7"},
+		{name: "spoofed header", router: direct, remote: "192.0.2.7:4321", forwarded: "198.51.100.1", want: "192.0.2.7"},
+		{name: "another spoofed header", router: direct, remote: "192.0.2.7:4321", forwarded: "198.51.100.2, 10.0.0.1", want: "192.0.2.7"},
+		{name: "untrusted peer with a proxy configured", router: proxied, remote: "192.0.2.7:4321", forwarded: "198.51.100.3", want: "192.0.2.7"},
+		{name: "trusted proxy", router: proxied, remote: "10.0.0.1:4321", forwarded: "198.51.100.4", want: "198.51.100.4"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/proj-key/me/login", strings.NewReader(`{"email":"user@example.com","password":"bad"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = tc.remote
+		if tc.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tc.forwarded)
+		}
+		authSvc.loginIP = ""
+		rec := httptest.NewRecorder()
+		tc.router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("%s: expected status 401, got %d: %s", tc.name, rec.Code, rec.Body.String())
+		}
+		if authSvc.loginIP != tc.want {
+			t.Fatalf("%s: expected client IP %q, got %q", tc.name, tc.want, authSvc.loginIP)
+		}
+	}
+}
+
+func TestPasswordPolicyErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
+	invalid := &customersvc.ValidationError{Errors: []customersvc.FieldError{
+		{Field: "password", Rule: "minLength", Message: "password must be at least 12 characters"},
+		{Field: "password", Rule: "symbol", Message: "password must contain a symbol"},
+	}}
+	authSvc := &stubCustomerAuthSvc{signErr: invalid, passwordErr: invalid}
+	router, err := buildRouter(logDiscard(), nil, Deps{
+		ProjectRepo:  &stubProjectRepo{project: proj},
+		ProductSvc:   &stubProductService{},
+		CartSvc:      &stubCartService{},
+		CategorySvc:  &stubCategoryService{},
+		CustomerSvc:  authSvc,
+		AnonymousSvc: &stubAnonymousService{},
+	}, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	for _, tc := range []struct{ url, body string }{
+		{"/proj-key/me/signup", `{"email":"user@example.com","password":"short"}`},
+		{"/proj-key/me/password/reset", `{"tokenValue":"tok","newPassword":"short"}`},
+	} {
+		req := httptest.NewRequest(http.MethodPost, tc.url, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d body=%s", tc.url, rec.Code, rec.Body.String())
+		}
+		for _, want := range []string{
+			`"statusCode":400`,
+			`{"code":"InvalidField","message":"password must be at least 12 characters","field":"password","rule":"minLength"}`,
+			`"rule":"symbol"`,
+		} {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Fatalf("%s: expected %s in %s", tc.url, want, rec.Body.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package httpserver
+
+import (
+	"context"
+	"log"
+
+	"commercetools-replica/internal/domain"
+	businessunitsvc "commercetools-replica/internal/service/businessunit"
+
+	"github.com/gin-gonic/gin"
+)
+
+type businessUnitService interface {
+	ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.BusinessUnit, int, error)
+	Get(ctx context.Context, projectID, id string) (*domain.BusinessUnit, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.BusinessUnit, error)
+	Create(ctx context.Context, projectID string, draft businessunitsvc.BusinessUnitDraft) (*domain.BusinessUnit, error)
+	Update(ctx context.Context, projectID, id string, in businessunitsvc.UpdateInput) (*domain.BusinessUnit, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.BusinessUnit, error)
+	// Permissions fails with businessunitsvc.ErrNotAssociate for customers
+	// without a role in the unit; Authorize also fails with
+	// businessunitsvc.ErrPermissionDenied.
+	Permissions(ctx context.Context, u *domain.BusinessUnit, customerID string) (map[string]bool, error)
+	Authorize(ctx context.Context, u *domain.BusinessUnit, customerID, permission string) error
+}
+
+// registerBusinessUnitRoutes registers the /business-units routes.
+func registerBusinessUnitRoutes(read, write *gin.RouterGroup, logger *log.Logger, svc businessUnitService) {
+	routes := crudRoutes[domain.BusinessUnit, businessunitsvc.BusinessUnitDraft, businessunitsvc.UpdateInput]{
+		path:       "/business-units",
+		singular:   "business unit",
+		plural:     "business units",
+		svc:        svc,
+		id:         func(b *domain.BusinessUnit) string { return b.ID },
+		draftField: func(d businessunitsvc.BusinessUnitDraft) string { return "key=" + d.Key },
+		conflict:   "business unit with this key already exists",
+		render:     func(_ *gin.Context, b domain.BusinessUnit) any { return toCTBusinessUnit(b) },
+		list: func(_ *gin.Context, items []domain.BusinessUnit, total, limit, offset int) any {
+			return buildBusinessUnitList(items, total, limit, offset)
+		},
+	}
+	routes.register(read, write, logger)
+}
@@ -0,0 +1,40 @@
+package httpserver
+
+import (
+	"context"
+	"log"
+
+	"commercetools-replica/internal/domain"
+	cartdiscountsvc "commercetools-replica/internal/service/cartdiscount"
+
+	"github.com/gin-gonic/gin"
+)
+
+type cartDiscountService interface {
+	ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.CartDiscount, int, error)
+	Get(ctx context.Context, projectID, id string) (*domain.CartDiscount, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.CartDiscount, error)
+	Create(ctx context.Context, projectID string, draft cartdiscountsvc.CartDiscountDraft) (*domain.CartDiscount, error)
+	Update(ctx context.Context, projectID, id string, in cartdiscountsvc.UpdateInput) (*domain.CartDiscount, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.CartDiscount, error)
+}
+
+// registerCartDiscountRoutes registers the /cart-discounts routes.
+func registerCartDiscountRoutes(read, write *gin.RouterGroup, logger *log.Logger, svc cartDiscountService) {
+	routes := crudRoutes[domain.CartDiscount, cartdiscountsvc.CartDiscountDraft, cartdiscountsvc.UpdateInput]{
+		path:       "/cart-discounts",
+		singular:   "cart discount",
+		plural:     "cart discounts",
+		svc:        svc,
+		id:         func(d *domain.CartDiscount) string { return d.ID },
+		draftField: func(d cartdiscountsvc.CartDiscountDraft) string { return "key=" + d.Key },
+		conflict:   "cart discount with this key or sortOrder already exists",
+		render: func(c *gin.Context, d domain.CartDiscount) any {
+			return toCTCartDiscount(d, localeFromRequest(c))
+		},
+		list: func(c *gin.Context, items []domain.CartDiscount, total, limit, offset int) any {
+			return buildCartDiscountList(items, total, limit, offset, localeFromRequest(c))
+		},
+	}
+	routes.register(read, write, logger)
+}
@@ -34,7 +34,7 @@ func TestCategoriesHandler_IntegrationReturnsHierarchy(t *testing.T) {
 	root, err := catSvc.Upsert(ctx, domain.Category{
 		ProjectID: projectID,
 		Key:       "root",
-		Name:      "Root",
+		Name:      domain.LocalizedString{"en": "Root"},
 	})
 	if err != nil {
 		t.Fatalf("upsert root: %v", err)
@@ -42,8 +42,8 @@ func TestCategoriesHandler_IntegrationReturnsHierarchy(t *testing.T) {
 	child, err := catSvc.Upsert(ctx, domain.Category{
 		ProjectID: projectID,
 		Key:       "child",
-		Name:      "Child",
-		ParentKey: root.Key,
+		Name:      domain.LocalizedString{"en": "Child"},
+		ParentID:  root.ID,
 	})
 	if err != nil {
 		t.Fatalf("upsert child: %v", err)
@@ -57,7 +57,7 @@ func TestCategoriesHandler_IntegrationReturnsHierarchy(t *testing.T) {
 		CategorySvc:  catSvc,
 		CustomerSvc:  &stubCustomerService{customer: &domain.Customer{ID: "cust", ProjectID: projectID}},
 		AnonymousSvc: &stubAnonymousService{},
-	})
+	}, "")
 	if err != nil {
 		t.Fatalf("build router: %v", err)
 	}
@@ -0,0 +1,40 @@
+package httpserver
+
+import (
+	"context"
+	"log"
+
+	"commercetools-replica/internal/domain"
+	channelsvc "commercetools-replica/internal/service/channel"
+
+	"github.com/gin-gonic/gin"
+)
+
+type channelService interface {
+	ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.Channel, int, error)
+	Get(ctx context.Context, projectID, id string) (*domain.Channel, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.Channel, error)
+	Create(ctx context.Context, projectID string, draft channelsvc.ChannelDraft) (*domain.Channel, error)
+	Update(ctx context.Context, projectID, id string, in channelsvc.UpdateInput) (*domain.Channel, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.Channel, error)
+}
+
+// registerChannelRoutes registers the /channels routes.
+func registerChannelRoutes(read, write *gin.RouterGroup, logger *log.Logger, svc channelService) {
+	routes := crudRoutes[domain.Channel, channelsvc.ChannelDraft, channelsvc.UpdateInput]{
+		path:       "/channels",
+		singular:   "channel",
+		plural:     "channels",
+		svc:        svc,
+		id:         func(ch *domain.Channel) string { return ch.ID },
+		draftField: func(d channelsvc.ChannelDraft) string { return "key=" + d.Key },
+		conflict:   "channel with this key already exists",
+		render: func(c *gin.Context, ch domain.Channel) any {
+			return toCTChannel(ch, localeFromRequest(c))
+		},
+		list: func(c *gin.Context, items []domain.Channel, total, limit, offset int) any {
+			return buildChannelList(items, total, limit, offset, localeFromRequest(c))
+		},
+	}
+	routes.register(read, write, logger)
+}
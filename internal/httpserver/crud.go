@@ -0,0 +1,167 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"commercetools-replica/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// crudService is the service side of a resource with the standard list, get,
+// create, update and delete routes.
+type crudService[T, D, U any] interface {
+	ListPage(ctx context.Context, projectID string, limit, offset int) ([]T, int, error)
+	Get(ctx context.Context, projectID, id string) (*T, error)
+	GetByKey(ctx context.Context, projectID, key string) (*T, error)
+	Create(ctx context.Context, projectID string, draft D) (*T, error)
+	Update(ctx context.Context, projectID, id string, in U) (*T, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*T, error)
+}
+
+// crudRoutes serves a resource of type T created from a D draft and updated
+// with a U input at path and path/:id, where :id is an id or "key=<key>".
+type crudRoutes[T, D, U any] struct {
+	path string
+	// singular and plural name the resource in log lines and error bodies.
+	singular string
+	plural   string
+	svc      crudService[T, D, U]
+	id       func(*T) string
+	// draftField is logged when a create fails, e.g. "key=<key>".
+	draftField func(D) string
+	// conflict is the error body of a create that hits ErrAlreadyExists.
+	conflict string
+	render   func(c *gin.Context, v T) any
+	list     func(c *gin.Context, items []T, total, limit, offset int) any
+}
+
+// register adds the read routes to read and the create, update and delete
+// routes to write. A nil group leaves its routes out.
+func (r crudRoutes[T, D, U]) register(read, write *gin.RouterGroup, logger *log.Logger) {
+	if read != nil {
+		read.GET(r.path, func(c *gin.Context) { r.listHandler(c, logger) })
+		read.GET(r.path+"/:id", func(c *gin.Context) { r.getHandler(c, logger) })
+	}
+	if write != nil {
+		write.POST(r.path, func(c *gin.Context) { r.createHandler(c, logger) })
+		write.POST(r.path+"/:id", func(c *gin.Context) { r.updateHandler(c, logger) })
+		write.DELETE(r.path+"/:id", func(c *gin.Context) { r.deleteHandler(c, logger) })
+	}
+}
+
+// lookup resolves an id or "key=<key>" path segment.
+func (r crudRoutes[T, D, U]) lookup(c *gin.Context, projectID, id string) (*T, error) {
+	if key, ok := keyFromPathParam(id); ok {
+		return r.svc.GetByKey(c.Request.Context(), projectID, key)
+	}
+	return r.svc.Get(c.Request.Context(), projectID, id)
+}
+
+func (r crudRoutes[T, D, U]) listHandler(c *gin.Context, logger *log.Logger) {
+	project := mustProject(c)
+	limit, offset := parseLimitOffset(c.Query("limit"), c.Query("offset"))
+	items, total, err := r.svc.ListPage(c.Request.Context(), project.ID, limit, offset)
+	if err != nil {
+		logger.Printf("%s list error project_id=%s error=%v", r.plural, project.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "list " + r.plural + " failed"})
+		return
+	}
+	c.JSON(http.StatusOK, r.list(c, items, total, limit, offset))
+}
+
+func (r crudRoutes[T, D, U]) getHandler(c *gin.Context, logger *log.Logger) {
+	project := mustProject(c)
+	id := c.Param("id")
+	v, err := r.lookup(c, project.ID, id)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": r.singular + " not found"})
+			return
+		}
+		logger.Printf("%s get error project_id=%s id=%s error=%v", r.singular, project.ID, id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "get " + r.singular + " failed"})
+		return
+	}
+	c.JSON(http.StatusOK, r.render(c, *v))
+}
+
+func (r crudRoutes[T, D, U]) createHandler(c *gin.Context, logger *log.Logger) {
+	project := mustProject(c)
+	var req D
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	v, err := r.svc.Create(c.Request.Context(), project.ID, req)
+	if err != nil {
+		logger.Printf("%s create error project_id=%s %s error=%v", r.singular, project.ID, r.draftField(req), err)
+		if errors.Is(err, domain.ErrAlreadyExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": r.conflict})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, r.render(c, *v))
+}
+
+func (r crudRoutes[T, D, U]) updateHandler(c *gin.Context, logger *log.Logger) {
+	project := mustProject(c)
+	id := c.Param("id")
+	var req U
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+	existing, err := r.lookup(c, project.ID, id)
+	var v *T
+	if err == nil {
+		v, err = r.svc.Update(c.Request.Context(), project.ID, r.id(existing), req)
+	}
+	if err != nil {
+		logger.Printf("%s update error project_id=%s id=%s error=%v", r.singular, project.ID, id, err)
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": r.singular + " not found"})
+		case errors.Is(err, domain.ErrConcurrentModification), errors.Is(err, domain.ErrAlreadyExists):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, r.render(c, *v))
+}
+
+func (r crudRoutes[T, D, U]) deleteHandler(c *gin.Context, logger *log.Logger) {
+	project := mustProject(c)
+	id := c.Param("id")
+	version, err := strconv.Atoi(c.Query("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "version query parameter required"})
+		return
+	}
+	existing, err := r.lookup(c, project.ID, id)
+	var v *T
+	if err == nil {
+		v, err = r.svc.Delete(c.Request.Context(), project.ID, r.id(existing), version)
+	}
+	if err != nil {
+		logger.Printf("%s delete error project_id=%s id=%s error=%v", r.singular, project.ID, id, err)
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": r.singular + " not found"})
+		case errors.Is(err, domain.ErrConcurrentModification):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, r.render(c, *v))
+}
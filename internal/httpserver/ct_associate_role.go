@@ -0,0 +1,63 @@
+package httpserver
+
+import (
+	"time"
+
+	"commercetools-replica/internal/domain"
+)
+
+type ctAssociateRole struct {
+	ID              string    `json:"id"`
+	Key             string    `json:"key"`
+	Version         int       `json:"version"`
+	Name            string    `json:"name,omitempty"`
+	BuyerAssignable bool      `json:"buyerAssignable"`
+	Permissions     []string  `json:"permissions"`
+	CreatedAt       time.Time `json:"createdAt"`
+	LastModifiedAt  time.Time `json:"lastModifiedAt"`
+}
+
+type ctAssociateRoleList struct {
+	Limit   int               `json:"limit"`
+	Offset  int               `json:"offset"`
+	Count   int               `json:"count"`
+	Total   int               `json:"total"`
+	Results []ctAssociateRole `json:"results"`
+}
+
+func buildAssociateRoleList(roles []domain.AssociateRole, total, limit, offset int) ctAssociateRoleList {
+	if limit <= 0 {
+		limit = total
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	out := ctAssociateRoleList{
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		Count:   len(roles),
+		Results: []ctAssociateRole{},
+	}
+	for _, r := range roles {
+		out.Results = append(out.Results, toCTAssociateRole(r))
+	}
+	return out
+}
+
+func toCTAssociateRole(r domain.AssociateRole) ctAssociateRole {
+	permissions := r.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return ctAssociateRole{
+		ID:              r.ID,
+		Key:             r.Key,
+		Version:         r.Version,
+		Name:            r.Name,
+		BuyerAssignable: r.BuyerAssignable,
+		Permissions:     permissions,
+		CreatedAt:       r.CreatedAt,
+		LastModifiedAt:  r.LastModifiedAt,
+	}
+}
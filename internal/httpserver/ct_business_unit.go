@@ -0,0 +1,116 @@
+package httpserver
+
+import (
+	"time"
+
+	"commercetools-replica/internal/domain"
+)
+
+type ctBusinessUnit struct {
+	ID                       string        `json:"id"`
+	Key                      string        `json:"key"`
+	Version                  int           `json:"version"`
+	UnitType                 string        `json:"unitType"`
+	Name                     string        `json:"name"`
+	ContactEmail             string        `json:"contactEmail,omitempty"`
+	Status                   string        `json:"status"`
+	StoreMode                string        `json:"storeMode"`
+	AssociateMode            string        `json:"associateMode"`
+	Addresses                []ctAddress   `json:"addresses"`
+	ShippingAddressIDs       []string      `json:"shippingAddressIds"`
+	BillingAddressIDs        []string      `json:"billingAddressIds"`
+	DefaultShippingAddressID string        `json:"defaultShippingAddressId,omitempty"`
+	DefaultBillingAddressID  string        `json:"defaultBillingAddressId,omitempty"`
+	Associates               []ctAssociate `json:"associates"`
+	ParentUnit               *ctRef        `json:"parentUnit,omitempty"`
+	TopLevelUnit             ctRef         `json:"topLevelUnit"`
+	CreatedAt                time.Time     `json:"createdAt"`
+	LastModifiedAt           time.Time     `json:"lastModifiedAt"`
+}
+
+type ctAssociate struct {
+	Customer                 ctRef                       `json:"customer"`
+	AssociateRoleAssignments []ctAssociateRoleAssignment `json:"associateRoleAssignments"`
+}
+
+type ctAssociateRoleAssignment struct {
+	AssociateRole ctRef  `json:"associateRole"`
+	Inheritance   string `json:"inheritance"`
+}
+
+type ctBusinessUnitList struct {
+	Limit   int              `json:"limit"`
+	Offset  int              `json:"offset"`
+	Count   int              `json:"count"`
+	Total   int              `json:"total"`
+	Results []ctBusinessUnit `json:"results"`
+}
+
+func buildBusinessUnitList(units []domain.BusinessUnit, total, limit, offset int) ctBusinessUnitList {
+	if limit <= 0 {
+		limit = total
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	out := ctBusinessUnitList{
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		Count:   len(units),
+		Results: []ctBusinessUnit{},
+	}
+	for _, u := range units {
+		out.Results = append(out.Results, toCTBusinessUnit(u))
+	}
+	return out
+}
+
+// toCTBusinessUnit renders the unit; stores are not supported, so the store
+// mode is always Explicit.
+func toCTBusinessUnit(u domain.BusinessUnit) ctBusinessUnit {
+	associates := make([]ctAssociate, 0, len(u.Associates))
+	for _, a := range u.Associates {
+		assignments := make([]ctAssociateRoleAssignment, 0, len(a.RoleAssignments))
+		for _, ra := range a.RoleAssignments {
+			assignments = append(assignments, ctAssociateRoleAssignment{
+				AssociateRole: ctRef{TypeID: "associate-role", Key: ra.RoleKey},
+				Inheritance:   ra.Inheritance,
+			})
+		}
+		associates = append(associates, ctAssociate{
+			Customer:                 ctRef{TypeID: "customer", ID: a.CustomerID},
+			AssociateRoleAssignments: assignments,
+		})
+	}
+	out := ctBusinessUnit{
+		ID:                       u.ID,
+		Key:                      u.Key,
+		Version:                  u.Version,
+		UnitType:                 u.UnitType,
+		Name:                     u.Name,
+		ContactEmail:             u.ContactEmail,
+		Status:                   u.Status,
+		StoreMode:                "Explicit",
+		AssociateMode:            u.AssociateMode,
+		Addresses:                toCTAddresses(u.Addresses),
+		ShippingAddressIDs:       []string{},
+		BillingAddressIDs:        []string{},
+		DefaultShippingAddressID: u.DefaultShippingAddressID,
+		DefaultBillingAddressID:  u.DefaultBillingAddressID,
+		Associates:               associates,
+		TopLevelUnit:             ctRef{TypeID: "business-unit", Key: u.TopLevelUnitKey},
+		CreatedAt:                u.CreatedAt,
+		LastModifiedAt:           u.LastModifiedAt,
+	}
+	if u.DefaultShippingAddressID != "" {
+		out.ShippingAddressIDs = []string{u.DefaultShippingAddressID}
+	}
+	if u.DefaultBillingAddressID != "" {
+		out.BillingAddressIDs = []string{u.DefaultBillingAddressID}
+	}
+	if u.ParentUnitKey != "" {
+		out.ParentUnit = &ctRef{TypeID: "business-unit", Key: u.ParentUnitKey}
+	}
+	return out
+}
@@ -23,20 +23,27 @@ type ctCart struct {
 	LineItems                       []ctLineItem              `json:"lineItems"`
 	CartState                       string                    `json:"cartState"`
 	TotalPrice                      ctPriceValue              `json:"totalPrice"`
+	TaxedPrice                      *ctTaxedPrice             `json:"taxedPrice,omitempty"`
+	Country                         string                    `json:"country,omitempty"`
+	ShippingAddress                 *ctAddress                `json:"shippingAddress,omitempty"`
+	Store                           *ctRef                    `json:"store,omitempty"`
+	BusinessUnit                    *ctRef                    `json:"businessUnit,omitempty"`
 	ShippingMode                    string                    `json:"shippingMode"`
-	Shipping                        []interface{}             `json:"shipping"`
+	ShippingInfo                    *ctShippingInfo           `json:"shippingInfo,omitempty"`
+	Shipping                        []ctShipping              `json:"shipping"`
 	CustomLineItems                 []interface{}             `json:"customLineItems"`
-	DiscountCodes                   []interface{}             `json:"discountCodes"`
-	DirectDiscounts                 []interface{}             `json:"directDiscounts"`
+	DiscountCodes                   []ctDiscountCodeInfo      `json:"discountCodes"`
+	DirectDiscounts                 []ctDirectDiscount        `json:"directDiscounts"`
+	DiscountOnTotalPrice            *ctDiscountOnTotalPrice   `json:"discountOnTotalPrice,omitempty"`
 	InventoryMode                   string                    `json:"inventoryMode"`
 	PriceRoundingMode               string                    `json:"priceRoundingMode"`
 	TaxMode                         string                    `json:"taxMode"`
 	TaxRoundingMode                 string                    `json:"taxRoundingMode"`
 	TaxCalculationMode              string                    `json:"taxCalculationMode"`
 	DeleteDaysAfterLastModification int                       `json:"deleteDaysAfterLastModification"`
-	RefusedGifts                    []interface{}             `json:"refusedGifts"`
+	RefusedGifts                    []ctRef                   `json:"refusedGifts"`
 	Origin                          string                    `json:"origin"`
-	ItemShippingAddresses           []interface{}             `json:"itemShippingAddresses"`
+	ItemShippingAddresses           []ctAddress               `json:"itemShippingAddresses"`
 	DiscountTypeCombination         ctDiscountTypeCombination `json:"discountTypeCombination"`
 	TotalLineItemQuantity           int                       `json:"totalLineItemQuantity,omitempty"`
 }
@@ -52,25 +59,89 @@ type ctDiscountTypeCombination struct {
 }
 
 type ctLineItem struct {
-	ID                         string            `json:"id"`
-	ProductID                  string            `json:"productId"`
-	ProductKey                 string            `json:"productKey,omitempty"`
-	ProductType                *ctProductType    `json:"productType,omitempty"`
-	ProductSlug                map[string]string `json:"productSlug,omitempty"`
-	Name                       map[string]string `json:"name"`
-	Variant                    ctVariant         `json:"variant"`
-	Price                      ctPrice           `json:"price"`
-	Quantity                   int               `json:"quantity"`
-	DiscountedPricePerQuantity []interface{}     `json:"discountedPricePerQuantity"`
-	PerMethodTaxRate           []interface{}     `json:"perMethodTaxRate"`
-	AddedAt                    time.Time         `json:"addedAt"`
-	LastModifiedAt             time.Time         `json:"lastModifiedAt"`
-	State                      []interface{}     `json:"state"`
-	PriceMode                  string            `json:"priceMode"`
-	LineItemMode               string            `json:"lineItemMode"`
-	PriceRoundingMode          string            `json:"priceRoundingMode"`
-	TotalPrice                 ctPriceValue      `json:"totalPrice"`
-	TaxedPricePortions         []interface{}     `json:"taxedPricePortions"`
+	ID                         string                                 `json:"id"`
+	ProductID                  string                                 `json:"productId"`
+	ProductKey                 string                                 `json:"productKey,omitempty"`
+	ProductType                *ctProductType                         `json:"productType,omitempty"`
+	ProductSlug                map[string]string                      `json:"productSlug,omitempty"`
+	Name                       map[string]string                      `json:"name"`
+	Variant                    ctVariant                              `json:"variant"`
+	Price                      ctPrice                                `json:"price"`
+	Quantity                   int                                    `json:"quantity"`
+	DiscountedPricePerQuantity []ctDiscountedLineItemPriceForQuantity `json:"discountedPricePerQuantity"`
+	PerMethodTaxRate           []interface{}                          `json:"perMethodTaxRate"`
+	AddedAt                    time.Time                              `json:"addedAt"`
+	LastModifiedAt             time.Time                              `json:"lastModifiedAt"`
+	State                      []interface{}                          `json:"state"`
+	PriceMode                  string                                 `json:"priceMode"`
+	LineItemMode               string                                 `json:"lineItemMode"`
+	PriceRoundingMode          string                                 `json:"priceRoundingMode"`
+	TotalPrice                 ctPriceValue                           `json:"totalPrice"`
+	TaxRate                    *ctTaxRate                             `json:"taxRate,omitempty"`
+	TaxedPrice                 *ctTaxedItemPrice                      `json:"taxedPrice,omitempty"`
+	TaxedPricePortions         []interface{}                          `json:"taxedPricePortions"`
+	ShippingDetails            *ctItemShippingDetails                 `json:"shippingDetails,omitempty"`
+}
+
+type ctItemShippingDetails struct {
+	Targets []ctItemShippingTarget `json:"targets"`
+	Valid   bool                   `json:"valid"`
+}
+
+type ctItemShippingTarget struct {
+	AddressKey        string `json:"addressKey"`
+	Quantity          int    `json:"quantity"`
+	ShippingMethodKey string `json:"shippingMethodKey,omitempty"`
+}
+
+type ctTaxedPrice struct {
+	TotalNet    ctPriceValue   `json:"totalNet"`
+	TotalGross  ctPriceValue   `json:"totalGross"`
+	TotalTax    *ctPriceValue  `json:"totalTax,omitempty"`
+	TaxPortions []ctTaxPortion `json:"taxPortions"`
+}
+
+type ctTaxedItemPrice struct {
+	TotalNet   ctPriceValue  `json:"totalNet"`
+	TotalGross ctPriceValue  `json:"totalGross"`
+	TotalTax   *ctPriceValue `json:"totalTax,omitempty"`
+}
+
+type ctTaxPortion struct {
+	Name   string       `json:"name,omitempty"`
+	Rate   float64      `json:"rate"`
+	Amount ctPriceValue `json:"amount"`
+}
+
+type ctDiscountCodeInfo struct {
+	DiscountCode ctRef  `json:"discountCode"`
+	State        string `json:"state"`
+}
+
+type ctDirectDiscount struct {
+	ID     string                `json:"id"`
+	Value  ctCartDiscountValue   `json:"value"`
+	Target *ctCartDiscountTarget `json:"target,omitempty"`
+}
+
+type ctDiscountedLineItemPriceForQuantity struct {
+	Quantity        int                       `json:"quantity"`
+	DiscountedPrice ctDiscountedLineItemPrice `json:"discountedPrice"`
+}
+
+type ctDiscountedLineItemPrice struct {
+	Value             ctPriceValue                  `json:"value"`
+	IncludedDiscounts []ctDiscountedLineItemPortion `json:"includedDiscounts"`
+}
+
+type ctDiscountedLineItemPortion struct {
+	Discount         ctRef        `json:"discount"`
+	DiscountedAmount ctPriceValue `json:"discountedAmount"`
+}
+
+type ctDiscountOnTotalPrice struct {
+	DiscountedAmount  ctPriceValue                  `json:"discountedAmount"`
+	IncludedDiscounts []ctDiscountedLineItemPortion `json:"includedDiscounts"`
 }
 
 type ctProductType struct {
@@ -80,16 +151,25 @@ type ctProductType struct {
 }
 
 type cartLineSnapshot struct {
-	ProductKey  string
-	ProductName string
-	SKU         string
-	ProductSlug string
-	Currency    string
-	PriceCents  int64
-	Images      []string
+	ProductKey    string
+	ProductTypeID string
+	ProductName   domain.LocalizedString
+	SKU           string
+	ProductSlug   domain.LocalizedString
+	Currency      string
+	PriceCents    int64
+	// PreciseAmount and FractionDigits are set for high-precision prices.
+	PreciseAmount  int64
+	FractionDigits int
+	// DiscountedCents and ProductDiscountID are set when a product discount applied
+	// at the time the line was added.
+	DiscountedCents   int64
+	ProductDiscountID string
+	Images            []string
+	Attributes        map[string]interface{}
 }
 
-func toCTCart(cart domain.Cart, customer *domain.Customer, fileURLHost string) ctCart {
+func toCTCart(cart domain.Cart, customer *domain.Customer, fileURLHost string, loc localeSelector) ctCart {
 	state := strings.TrimSpace(cart.State)
 	if state == "" {
 		state = "Active"
@@ -97,6 +177,10 @@ func toCTCart(cart domain.Cart, customer *domain.Customer, fileURLHost string) c
 		state = "Active"
 	} else if strings.EqualFold(state, "deleted") {
 		state = "Deleted"
+	} else if strings.EqualFold(state, "ordered") {
+		state = "Ordered"
+	} else if strings.EqualFold(state, domain.CartStateFrozen) {
+		state = "Frozen"
 	}
 
 	customerID := ""
@@ -110,16 +194,20 @@ func toCTCart(cart domain.Cart, customer *domain.Customer, fileURLHost string) c
 	totalQty := 0
 	for _, line := range cart.Lines {
 		snap := parseLineSnapshot(line.Snapshot)
-		name := snap.ProductName
-		if name == "" {
-			name = snap.ProductKey
+		name := loc.project(snap.ProductName)
+		if len(name) == 0 {
+			fallback := snap.ProductKey
+			if fallback == "" {
+				fallback = line.ProductID
+			}
+			name = map[string]string{domain.DefaultLocale: fallback}
 		}
-		if name == "" {
-			name = line.ProductID
+		productSlug := loc.project(snap.ProductSlug)
+		if len(productSlug) == 0 && snap.ProductKey != "" {
+			productSlug = map[string]string{domain.DefaultLocale: snap.ProductKey}
 		}
-		slug := snap.ProductSlug
-		if slug == "" {
-			slug = snap.ProductKey
+		if len(productSlug) == 0 {
+			productSlug = nil
 		}
 		price := line.UnitPriceCents
 		if snap.PriceCents > 0 {
@@ -131,55 +219,59 @@ func toCTCart(cart domain.Cart, customer *domain.Customer, fileURLHost string) c
 		}
 
 		images := imagesFromURLs(snap.Images, fileURLHost)
+		variantID := line.VariantID
+		if variantID == 0 {
+			variantID = 1
+		}
+		linePrice := domain.Price{Value: domain.Money{CurrencyCode: currency, CentAmount: price, PreciseAmount: snap.PreciseAmount, FractionDigits: snap.FractionDigits}}
+		if snap.ProductDiscountID != "" {
+			linePrice.Discounted = &domain.DiscountedPrice{
+				Value:      domain.Money{CurrencyCode: currency, CentAmount: snap.DiscountedCents},
+				DiscountID: snap.ProductDiscountID,
+			}
+		}
 		variant := ctVariant{
-			ID:         1,
+			ID:         variantID,
 			SKU:        snap.SKU,
-			Prices:     []ctPrice{{Value: ctPriceValue{Type: "centPrecision", CurrencyCode: currency, CentAmount: price, FractionDigits: 2}}},
+			Prices:     []ctPrice{toCTPrice(linePrice)},
 			Images:     images,
 			Assets:     []interface{}{},
-			Attributes: []interface{}{},
+			Attributes: toCTAttributes(snap.Attributes),
 		}
-
-		var productSlug map[string]string
-		if slug != "" {
-			productSlug = map[string]string{"en": slug}
+		var productType *ctProductType
+		if snap.ProductTypeID != "" {
+			productType = &ctProductType{TypeID: "product-type", ID: snap.ProductTypeID}
 		}
 
 		lineItems = append(lineItems, ctLineItem{
 			ID:                         line.ID,
 			ProductID:                  line.ProductID,
 			ProductKey:                 snap.ProductKey,
+			ProductType:                productType,
 			ProductSlug:                productSlug,
-			Name:                       map[string]string{"en": name},
+			Name:                       name,
 			Variant:                    variant,
-			Price:                      ctPrice{Value: ctPriceValue{Type: "centPrecision", CurrencyCode: currency, CentAmount: price, FractionDigits: 2}},
+			Price:                      toCTPrice(linePrice),
 			Quantity:                   line.Quantity,
-			DiscountedPricePerQuantity: []interface{}{},
+			DiscountedPricePerQuantity: toCTDiscountedPerQuantity(line.DiscountedPricePerQuantity),
 			PerMethodTaxRate:           []interface{}{},
 			AddedAt:                    line.CreatedAt,
 			LastModifiedAt:             line.CreatedAt,
 			State:                      []interface{}{},
 			PriceMode:                  "Platform",
-			LineItemMode:               "Standard",
+			LineItemMode:               lineItemMode(line),
 			PriceRoundingMode:          "HalfEven",
-			TotalPrice: ctPriceValue{
-				Type:           "centPrecision",
-				CurrencyCode:   currency,
-				CentAmount:     line.TotalCents,
-				FractionDigits: 2,
-			},
-			TaxedPricePortions: []interface{}{},
+			TotalPrice:                 toCTMoney(domain.Money{CurrencyCode: currency, CentAmount: line.TotalCents}),
+			TaxRate:                    toCTLineTaxRate(line.TaxRate),
+			TaxedPrice:                 toCTTaxedItemPrice(line.TaxedPrice),
+			TaxedPricePortions:         []interface{}{},
+			ShippingDetails:            toCTItemShippingDetails(line),
 		})
 		totalQty += line.Quantity
 	}
 
 	actor := buildActor(customerID)
-	totalPrice := ctPriceValue{
-		Type:           "centPrecision",
-		CurrencyCode:   cart.Currency,
-		CentAmount:     cart.TotalCents,
-		FractionDigits: 2,
-	}
+	totalPrice := toCTMoney(domain.Money{CurrencyCode: cart.Currency, CentAmount: cart.TotalCents})
 
 	out := ctCart{
 		Type:                            "Cart",
@@ -195,25 +287,177 @@ func toCTCart(cart domain.Cart, customer *domain.Customer, fileURLHost string) c
 		LineItems:                       lineItems,
 		CartState:                       state,
 		TotalPrice:                      totalPrice,
-		ShippingMode:                    "Single",
-		Shipping:                        []interface{}{},
+		TaxedPrice:                      toCTTaxedPrice(cart.TaxedPrice),
+		Country:                         cart.Country,
+		ShippingAddress:                 toCTShippingAddress(cart.ShippingAddress),
+		ShippingMode:                    valueOr(cart.ShippingMode, domain.ShippingModeSingle),
+		ShippingInfo:                    toCTShippingInfo(cart.ShippingInfo),
+		Shipping:                        toCTShipping(cart.Shipping),
 		CustomLineItems:                 []interface{}{},
-		DiscountCodes:                   []interface{}{},
-		DirectDiscounts:                 []interface{}{},
-		InventoryMode:                   "None",
+		DiscountCodes:                   toCTDiscountCodeInfos(cart.DiscountCodes),
+		DirectDiscounts:                 toCTDirectDiscounts(cart.DirectDiscounts),
+		DiscountOnTotalPrice:            toCTDiscountOnTotalPrice(cart.DiscountOnTotal),
+		InventoryMode:                   valueOr(cart.InventoryMode, domain.InventoryModeNone),
 		PriceRoundingMode:               "HalfEven",
-		TaxMode:                         "Platform",
-		TaxRoundingMode:                 "HalfEven",
-		TaxCalculationMode:              "LineItemLevel",
+		TaxMode:                         valueOr(cart.TaxMode, domain.TaxModePlatform),
+		TaxRoundingMode:                 valueOr(cart.TaxRoundingMode, domain.RoundingHalfEven),
+		TaxCalculationMode:              valueOr(cart.TaxCalculationMode, domain.TaxCalculationLineItemLevel),
 		DeleteDaysAfterLastModification: 90,
-		RefusedGifts:                    []interface{}{},
+		RefusedGifts:                    refusedGiftRefs(cart.RefusedGifts),
 		Origin:                          "Customer",
-		ItemShippingAddresses:           []interface{}{},
+		ItemShippingAddresses:           toCTAddresses(cart.ItemShippingAddresses),
 		DiscountTypeCombination:         ctDiscountTypeCombination{Type: "Stacking"},
 	}
 	if totalQty > 0 {
 		out.TotalLineItemQuantity = totalQty
 	}
+	if cart.StoreKey != "" {
+		out.Store = &ctRef{TypeID: "store", Key: cart.StoreKey}
+	}
+	if cart.BusinessUnitKey != "" {
+		out.BusinessUnit = &ctRef{TypeID: "business-unit", Key: cart.BusinessUnitKey}
+	}
+	return out
+}
+
+// toCTItemShippingDetails reports the details as valid while the target
+// quantities add up to the line quantity, which changeLineItemQuantity can undo.
+func toCTItemShippingDetails(line domain.CartLine) *ctItemShippingDetails {
+	if line.ShippingDetails == nil {
+		return nil
+	}
+	out := &ctItemShippingDetails{
+		Targets: make([]ctItemShippingTarget, 0, len(line.ShippingDetails.Targets)),
+		Valid:   line.ShippingDetails.Quantity() == line.Quantity,
+	}
+	for _, t := range line.ShippingDetails.Targets {
+		out.Targets = append(out.Targets, ctItemShippingTarget{AddressKey: t.AddressKey, Quantity: t.Quantity, ShippingMethodKey: t.ShippingMethodKey})
+	}
+	return out
+}
+
+func lineItemMode(line domain.CartLine) string {
+	if line.LineItemMode == "" {
+		return domain.LineItemModeStandard
+	}
+	return line.LineItemMode
+}
+
+func toCTDiscountCodeInfos(codes []domain.CartDiscountCode) []ctDiscountCodeInfo {
+	out := make([]ctDiscountCodeInfo, 0, len(codes))
+	for _, c := range codes {
+		out = append(out, ctDiscountCodeInfo{
+			DiscountCode: ctRef{TypeID: "discount-code", ID: c.DiscountCodeID},
+			State:        c.State,
+		})
+	}
+	return out
+}
+
+func toCTDirectDiscounts(discounts []domain.DirectDiscount) []ctDirectDiscount {
+	out := make([]ctDirectDiscount, 0, len(discounts))
+	for _, d := range discounts {
+		out = append(out, ctDirectDiscount{
+			ID:     d.ID,
+			Value:  toCTCartDiscountValue(d.Value),
+			Target: toCTCartDiscountTarget(d.Target),
+		})
+	}
+	return out
+}
+
+func toCTDiscountedPerQuantity(items []domain.DiscountedQuantity) []ctDiscountedLineItemPriceForQuantity {
+	out := make([]ctDiscountedLineItemPriceForQuantity, 0, len(items))
+	for _, item := range items {
+		out = append(out, ctDiscountedLineItemPriceForQuantity{
+			Quantity: item.Quantity,
+			DiscountedPrice: ctDiscountedLineItemPrice{
+				Value:             toCTMoney(item.Value),
+				IncludedDiscounts: toCTDiscountPortions(item.IncludedDiscounts),
+			},
+		})
+	}
+	return out
+}
+
+func toCTDiscountOnTotalPrice(d *domain.DiscountOnTotal) *ctDiscountOnTotalPrice {
+	if d == nil {
+		return nil
+	}
+	return &ctDiscountOnTotalPrice{
+		DiscountedAmount:  toCTMoney(d.DiscountedAmount),
+		IncludedDiscounts: toCTDiscountPortions(d.IncludedDiscounts),
+	}
+}
+
+func toCTDiscountPortions(portions []domain.DiscountPortion) []ctDiscountedLineItemPortion {
+	out := make([]ctDiscountedLineItemPortion, 0, len(portions))
+	for _, p := range portions {
+		typeID := "cart-discount"
+		if p.Direct {
+			typeID = "direct-discount"
+		}
+		out = append(out, ctDiscountedLineItemPortion{
+			Discount:         ctRef{TypeID: typeID, ID: p.DiscountID},
+			DiscountedAmount: toCTMoney(p.Amount),
+		})
+	}
+	return out
+}
+
+func toCTTaxedPrice(t *domain.TaxedPrice) *ctTaxedPrice {
+	if t == nil {
+		return nil
+	}
+	tax := toCTMoney(t.TotalTax)
+	out := &ctTaxedPrice{
+		TotalNet:    toCTMoney(t.TotalNet),
+		TotalGross:  toCTMoney(t.TotalGross),
+		TotalTax:    &tax,
+		TaxPortions: make([]ctTaxPortion, 0, len(t.TaxPortions)),
+	}
+	for _, p := range t.TaxPortions {
+		out.TaxPortions = append(out.TaxPortions, ctTaxPortion{Name: p.Name, Rate: p.Rate, Amount: toCTMoney(p.Amount)})
+	}
+	return out
+}
+
+func toCTTaxedItemPrice(t *domain.TaxedPrice) *ctTaxedItemPrice {
+	if t == nil {
+		return nil
+	}
+	tax := toCTMoney(t.TotalTax)
+	return &ctTaxedItemPrice{TotalNet: toCTMoney(t.TotalNet), TotalGross: toCTMoney(t.TotalGross), TotalTax: &tax}
+}
+
+func toCTLineTaxRate(r *domain.TaxRate) *ctTaxRate {
+	if r == nil {
+		return nil
+	}
+	out := toCTTaxRate(*r)
+	return &out
+}
+
+func toCTShippingAddress(a *domain.CustomerAddress) *ctAddress {
+	if a == nil {
+		return nil
+	}
+	out := toCTAddress(*a)
+	return &out
+}
+
+func valueOr(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+func refusedGiftRefs(ids []string) []ctRef {
+	out := make([]ctRef, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, ctRef{TypeID: "cart-discount", ID: id})
+	}
 	return out
 }
 
@@ -236,34 +480,69 @@ func parseLineSnapshot(raw map[string]interface{}) cartLineSnapshot {
 	if v, ok := raw["productKey"].(string); ok {
 		out.ProductKey = v
 	}
-	if v, ok := raw["productName"].(string); ok {
-		out.ProductName = v
+	if v, ok := raw["productTypeId"].(string); ok {
+		out.ProductTypeID = v
+	}
+	if v, ok := raw["attributes"].(map[string]interface{}); ok {
+		out.Attributes = v
 	}
+	out.ProductName = parseLocalized(raw["productName"])
 	if v, ok := raw["sku"].(string); ok {
 		out.SKU = v
 	}
-	if v, ok := raw["productSlug"].(string); ok {
-		out.ProductSlug = v
+	out.ProductSlug = parseLocalized(raw["productSlug"])
+	out.PriceCents = parseCents(raw["priceCents"])
+	out.DiscountedCents = parseCents(raw["discountedCents"])
+	out.PreciseAmount = parseCents(raw["preciseAmount"])
+	out.FractionDigits = int(parseCents(raw["fractionDigits"]))
+	if v, ok := raw["productDiscountId"].(string); ok {
+		out.ProductDiscountID = v
 	}
-	switch v := raw["priceCents"].(type) {
+	if v, ok := raw["currency"].(string); ok {
+		out.Currency = v
+	}
+	out.Images = parseImageList(raw["images"])
+	return out
+}
+
+func parseCents(raw interface{}) int64 {
+	switch v := raw.(type) {
 	case int64:
-		out.PriceCents = v
+		return v
 	case int32:
-		out.PriceCents = int64(v)
+		return int64(v)
 	case int:
-		out.PriceCents = int64(v)
+		return int64(v)
 	case float64:
-		out.PriceCents = int64(v)
+		return int64(v)
 	case string:
 		if parsed, err := strconv.ParseInt(v, 10, 64); err == nil {
-			out.PriceCents = parsed
+			return parsed
 		}
 	}
-	if v, ok := raw["currency"].(string); ok {
-		out.Currency = v
+	return 0
+}
+
+// parseLocalized accepts a localized map or a plain string stored by older snapshots.
+func parseLocalized(raw interface{}) domain.LocalizedString {
+	switch v := raw.(type) {
+	case string:
+		return domain.Localized(domain.DefaultLocale, v)
+	case map[string]string:
+		return domain.LocalizedString(v).Clone()
+	case map[string]interface{}:
+		out := domain.LocalizedString{}
+		for locale, value := range v {
+			if s, ok := value.(string); ok && s != "" {
+				out[locale] = s
+			}
+		}
+		return out
+	case domain.LocalizedString:
+		return v.Clone()
+	default:
+		return domain.LocalizedString{}
 	}
-	out.Images = parseImageList(raw["images"])
-	return out
 }
 
 func parseImageList(raw interface{}) []string {
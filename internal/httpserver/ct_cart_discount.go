@@ -0,0 +1,194 @@
+package httpserver
+
+import (
+	"time"
+
+	"commercetools-replica/internal/domain"
+)
+
+type ctCartDiscount struct {
+	ID                   string                `json:"id"`
+	Key                  string                `json:"key,omitempty"`
+	Name                 map[string]string     `json:"name"`
+	Description          map[string]string     `json:"description,omitempty"`
+	Value                ctCartDiscountValue   `json:"value"`
+	CartPredicate        string                `json:"cartPredicate"`
+	Target               *ctCartDiscountTarget `json:"target,omitempty"`
+	SortOrder            string                `json:"sortOrder"`
+	IsActive             bool                  `json:"isActive"`
+	ValidFrom            *time.Time            `json:"validFrom,omitempty"`
+	ValidUntil           *time.Time            `json:"validUntil,omitempty"`
+	RequiresDiscountCode bool                  `json:"requiresDiscountCode"`
+	StackingMode         string                `json:"stackingMode"`
+	References           []ctRef               `json:"references"`
+	Version              int                   `json:"version"`
+	CreatedAt            time.Time             `json:"createdAt"`
+	LastModifiedAt       time.Time             `json:"lastModifiedAt"`
+}
+
+type ctCartDiscountValue struct {
+	Type      string         `json:"type"`
+	Permyriad int            `json:"permyriad,omitempty"`
+	Money     []ctPriceValue `json:"money,omitempty"`
+	Product   *ctRef         `json:"product,omitempty"`
+	VariantID int            `json:"variantId,omitempty"`
+}
+
+type ctCartDiscountTarget struct {
+	Type      string `json:"type"`
+	Predicate string `json:"predicate,omitempty"`
+}
+
+type ctCartDiscountList struct {
+	Limit   int              `json:"limit"`
+	Offset  int              `json:"offset"`
+	Count   int              `json:"count"`
+	Total   int              `json:"total"`
+	Results []ctCartDiscount `json:"results"`
+}
+
+type ctDiscountCode struct {
+	ID                         string            `json:"id"`
+	Key                        string            `json:"key,omitempty"`
+	Code                       string            `json:"code"`
+	Name                       map[string]string `json:"name,omitempty"`
+	Description                map[string]string `json:"description,omitempty"`
+	CartDiscounts              []ctRef           `json:"cartDiscounts"`
+	CartPredicate              string            `json:"cartPredicate,omitempty"`
+	IsActive                   bool              `json:"isActive"`
+	MaxApplications            *int              `json:"maxApplications,omitempty"`
+	MaxApplicationsPerCustomer *int              `json:"maxApplicationsPerCustomer,omitempty"`
+	ValidFrom                  *time.Time        `json:"validFrom,omitempty"`
+	ValidUntil                 *time.Time        `json:"validUntil,omitempty"`
+	Groups                     []string          `json:"groups"`
+	References                 []ctRef           `json:"references"`
+	Version                    int               `json:"version"`
+	CreatedAt                  time.Time         `json:"createdAt"`
+	LastModifiedAt             time.Time         `json:"lastModifiedAt"`
+}
+
+type ctDiscountCodeList struct {
+	Limit   int              `json:"limit"`
+	Offset  int              `json:"offset"`
+	Count   int              `json:"count"`
+	Total   int              `json:"total"`
+	Results []ctDiscountCode `json:"results"`
+}
+
+func buildCartDiscountList(discounts []domain.CartDiscount, total, limit, offset int, loc localeSelector) ctCartDiscountList {
+	if limit <= 0 {
+		limit = total
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	out := ctCartDiscountList{
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		Count:   len(discounts),
+		Results: []ctCartDiscount{},
+	}
+	for _, d := range discounts {
+		out.Results = append(out.Results, toCTCartDiscount(d, loc))
+	}
+	return out
+}
+
+func toCTCartDiscount(d domain.CartDiscount, loc localeSelector) ctCartDiscount {
+	description := loc.project(d.Description)
+	if len(description) == 0 {
+		description = nil
+	}
+	return ctCartDiscount{
+		ID:                   d.ID,
+		Key:                  d.Key,
+		Name:                 loc.project(d.Name),
+		Description:          description,
+		Value:                toCTCartDiscountValue(d.Value),
+		CartPredicate:        d.CartPredicate,
+		Target:               toCTCartDiscountTarget(d.Target),
+		SortOrder:            d.SortOrder,
+		IsActive:             d.IsActive,
+		ValidFrom:            d.ValidFrom,
+		ValidUntil:           d.ValidUntil,
+		RequiresDiscountCode: d.RequiresDiscountCode,
+		StackingMode:         d.StackingMode,
+		References:           []ctRef{},
+		Version:              d.Version,
+		CreatedAt:            d.CreatedAt,
+		LastModifiedAt:       d.LastModifiedAt,
+	}
+}
+
+func toCTCartDiscountValue(v domain.CartDiscountValue) ctCartDiscountValue {
+	out := ctCartDiscountValue{Type: v.Type, Permyriad: v.Permyriad, VariantID: v.VariantID}
+	for _, m := range v.Money {
+		out.Money = append(out.Money, toCTMoney(m))
+	}
+	if v.ProductID != "" {
+		out.Product = &ctRef{TypeID: "product", ID: v.ProductID}
+	}
+	return out
+}
+
+func toCTCartDiscountTarget(t *domain.CartDiscountTarget) *ctCartDiscountTarget {
+	if t == nil {
+		return nil
+	}
+	return &ctCartDiscountTarget{Type: t.Type, Predicate: t.Predicate}
+}
+
+func buildDiscountCodeList(codes []domain.DiscountCode, total, limit, offset int, loc localeSelector) ctDiscountCodeList {
+	if limit <= 0 {
+		limit = total
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	out := ctDiscountCodeList{
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		Count:   len(codes),
+		Results: []ctDiscountCode{},
+	}
+	for _, c := range codes {
+		out.Results = append(out.Results, toCTDiscountCode(c, loc))
+	}
+	return out
+}
+
+func toCTDiscountCode(c domain.DiscountCode, loc localeSelector) ctDiscountCode {
+	name := loc.project(c.Name)
+	if len(name) == 0 {
+		name = nil
+	}
+	description := loc.project(c.Description)
+	if len(description) == 0 {
+		description = nil
+	}
+	refs := make([]ctRef, 0, len(c.CartDiscountIDs))
+	for _, id := range c.CartDiscountIDs {
+		refs = append(refs, ctRef{TypeID: "cart-discount", ID: id})
+	}
+	return ctDiscountCode{
+		ID:                         c.ID,
+		Key:                        c.Key,
+		Code:                       c.Code,
+		Name:                       name,
+		Description:                description,
+		CartDiscounts:              refs,
+		CartPredicate:              c.CartPredicate,
+		IsActive:                   c.IsActive,
+		MaxApplications:            c.MaxApplications,
+		MaxApplicationsPerCustomer: c.MaxApplicationsPerCustomer,
+		ValidFrom:                  c.ValidFrom,
+		ValidUntil:                 c.ValidUntil,
+		Groups:                     []string{},
+		References:                 []ctRef{},
+		Version:                    c.Version,
+		CreatedAt:                  c.CreatedAt,
+		LastModifiedAt:             c.LastModifiedAt,
+	}
+}
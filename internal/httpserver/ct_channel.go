@@ -0,0 +1,71 @@
+package httpserver
+
+import (
+	"time"
+
+	"commercetools-replica/internal/domain"
+)
+
+type ctChannel struct {
+	ID             string            `json:"id"`
+	Key            string            `json:"key"`
+	Version        int               `json:"version"`
+	Roles          []string          `json:"roles"`
+	Name           map[string]string `json:"name,omitempty"`
+	Description    map[string]string `json:"description,omitempty"`
+	CreatedAt      time.Time         `json:"createdAt"`
+	LastModifiedAt time.Time         `json:"lastModifiedAt"`
+}
+
+type ctChannelList struct {
+	Limit   int         `json:"limit"`
+	Offset  int         `json:"offset"`
+	Count   int         `json:"count"`
+	Total   int         `json:"total"`
+	Results []ctChannel `json:"results"`
+}
+
+func buildChannelList(channels []domain.Channel, total, limit, offset int, loc localeSelector) ctChannelList {
+	if limit <= 0 {
+		limit = total
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	out := ctChannelList{
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		Count:   len(channels),
+		Results: []ctChannel{},
+	}
+	for _, ch := range channels {
+		out.Results = append(out.Results, toCTChannel(ch, loc))
+	}
+	return out
+}
+
+func toCTChannel(ch domain.Channel, loc localeSelector) ctChannel {
+	roles := ch.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+	name := loc.project(ch.Name)
+	if len(name) == 0 {
+		name = nil
+	}
+	description := loc.project(ch.Description)
+	if len(description) == 0 {
+		description = nil
+	}
+	return ctChannel{
+		ID:             ch.ID,
+		Key:            ch.Key,
+		Version:        ch.Version,
+		Roles:          roles,
+		Name:           name,
+		Description:    description,
+		CreatedAt:      ch.CreatedAt,
+		LastModifiedAt: ch.LastModifiedAt,
+	}
+}
@@ -0,0 +1,55 @@
+package httpserver
+
+import (
+	"time"
+
+	"commercetools-replica/internal/domain"
+)
+
+type ctCustomerGroup struct {
+	ID             string    `json:"id"`
+	Key            string    `json:"key,omitempty"`
+	Name           string    `json:"name"`
+	Version        int       `json:"version"`
+	CreatedAt      time.Time `json:"createdAt"`
+	LastModifiedAt time.Time `json:"lastModifiedAt"`
+}
+
+type ctCustomerGroupList struct {
+	Limit   int               `json:"limit"`
+	Offset  int               `json:"offset"`
+	Count   int               `json:"count"`
+	Total   int               `json:"total"`
+	Results []ctCustomerGroup `json:"results"`
+}
+
+func buildCustomerGroupList(groups []domain.CustomerGroup, total, limit, offset int) ctCustomerGroupList {
+	if limit <= 0 {
+		limit = total
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	out := ctCustomerGroupList{
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		Count:   len(groups),
+		Results: []ctCustomerGroup{},
+	}
+	for _, g := range groups {
+		out.Results = append(out.Results, toCTCustomerGroup(g))
+	}
+	return out
+}
+
+func toCTCustomerGroup(g domain.CustomerGroup) ctCustomerGroup {
+	return ctCustomerGroup{
+		ID:             g.ID,
+		Key:            g.Key,
+		Name:           g.Name,
+		Version:        g.Version,
+		CreatedAt:      g.CreatedAt,
+		LastModifiedAt: g.LastModifiedAt,
+	}
+}
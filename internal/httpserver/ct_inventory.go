@@ -0,0 +1,100 @@
+package httpserver
+
+import (
+	"time"
+
+	"commercetools-replica/internal/domain"
+)
+
+type ctInventoryEntry struct {
+	ID                string     `json:"id"`
+	Key               string     `json:"key,omitempty"`
+	Version           int        `json:"version"`
+	SKU               string     `json:"sku"`
+	SupplyChannel     *ctRef     `json:"supplyChannel,omitempty"`
+	QuantityOnStock   int64      `json:"quantityOnStock"`
+	AvailableQuantity int64      `json:"availableQuantity"`
+	RestockableInDays *int       `json:"restockableInDays,omitempty"`
+	ExpectedDelivery  *time.Time `json:"expectedDelivery,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	LastModifiedAt    time.Time  `json:"lastModifiedAt"`
+}
+
+type ctInventoryEntryList struct {
+	Limit   int                `json:"limit"`
+	Offset  int                `json:"offset"`
+	Count   int                `json:"count"`
+	Total   int                `json:"total"`
+	Results []ctInventoryEntry `json:"results"`
+}
+
+// ctVariantAvailability is the availability of a product variant.
+type ctVariantAvailability struct {
+	IsOnStock         bool                             `json:"isOnStock"`
+	RestockableInDays *int                             `json:"restockableInDays,omitempty"`
+	AvailableQuantity int64                            `json:"availableQuantity"`
+	Channels          map[string]ctChannelAvailability `json:"channels,omitempty"`
+}
+
+type ctChannelAvailability struct {
+	IsOnStock         bool  `json:"isOnStock"`
+	RestockableInDays *int  `json:"restockableInDays,omitempty"`
+	AvailableQuantity int64 `json:"availableQuantity"`
+}
+
+func buildInventoryEntryList(entries []domain.InventoryEntry, total, limit, offset int) ctInventoryEntryList {
+	if limit <= 0 {
+		limit = total
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	out := ctInventoryEntryList{
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		Count:   len(entries),
+		Results: []ctInventoryEntry{},
+	}
+	for _, e := range entries {
+		out.Results = append(out.Results, toCTInventoryEntry(e))
+	}
+	return out
+}
+
+func toCTInventoryEntry(e domain.InventoryEntry) ctInventoryEntry {
+	out := ctInventoryEntry{
+		ID:                e.ID,
+		Key:               e.Key,
+		Version:           e.Version,
+		SKU:               e.SKU,
+		QuantityOnStock:   e.QuantityOnStock,
+		AvailableQuantity: e.AvailableQuantity,
+		RestockableInDays: e.RestockableInDays,
+		ExpectedDelivery:  e.ExpectedDelivery,
+		CreatedAt:         e.CreatedAt,
+		LastModifiedAt:    e.LastModifiedAt,
+	}
+	if e.SupplyChannelID != "" {
+		out.SupplyChannel = &ctRef{TypeID: "channel", ID: e.SupplyChannelID}
+	}
+	return out
+}
+
+func toCTVariantAvailability(a *domain.VariantAvailability) *ctVariantAvailability {
+	if a == nil {
+		return nil
+	}
+	out := &ctVariantAvailability{
+		IsOnStock:         a.IsOnStock,
+		RestockableInDays: a.RestockableInDays,
+		AvailableQuantity: a.AvailableQuantity,
+	}
+	for id, c := range a.Channels {
+		if out.Channels == nil {
+			out.Channels = map[string]ctChannelAvailability{}
+		}
+		out.Channels[id] = ctChannelAvailability{IsOnStock: c.IsOnStock, RestockableInDays: c.RestockableInDays, AvailableQuantity: c.AvailableQuantity}
+	}
+	return out
+}
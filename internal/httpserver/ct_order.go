@@ -0,0 +1,60 @@
+package httpserver
+
+import (
+	"commercetools-replica/internal/domain"
+)
+
+// ctOrder renders an order as the cart it was created from plus the order
+// fields.
+type ctOrder struct {
+	ctCart
+	OrderNumber string `json:"orderNumber,omitempty"`
+	OrderState  string `json:"orderState"`
+	Cart        ctRef  `json:"cart"`
+	// CartState hides the embedded cart's state; orders have none.
+	CartState string `json:"cartState,omitempty"`
+}
+
+type ctOrderList struct {
+	Limit   int       `json:"limit"`
+	Offset  int       `json:"offset"`
+	Count   int       `json:"count"`
+	Total   int       `json:"total"`
+	Results []ctOrder `json:"results"`
+}
+
+func buildOrderList(orders []domain.Order, total, limit, offset int, customer *domain.Customer, fileURLHost string, loc localeSelector) ctOrderList {
+	if limit <= 0 {
+		limit = total
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	out := ctOrderList{
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		Count:   len(orders),
+		Results: []ctOrder{},
+	}
+	for _, o := range orders {
+		out.Results = append(out.Results, toCTOrder(o, customer, fileURLHost, loc))
+	}
+	return out
+}
+
+func toCTOrder(o domain.Order, customer *domain.Customer, fileURLHost string, loc localeSelector) ctOrder {
+	cart := toCTCart(o.Cart, customer, fileURLHost, loc)
+	cart.Type = "Order"
+	cart.ID = o.ID
+	cart.Version = o.Version
+	cart.CreatedAt = o.CreatedAt
+	cart.VersionModifiedAt = o.LastModifiedAt
+	cart.LastModifiedAt = o.LastModifiedAt
+	return ctOrder{
+		ctCart:      cart,
+		OrderNumber: o.OrderNumber,
+		OrderState:  o.OrderState,
+		Cart:        ctRef{TypeID: "cart", ID: o.Cart.ID},
+	}
+}
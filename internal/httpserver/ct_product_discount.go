@@ -1,6 +1,10 @@
 package httpserver
 
-import "time"
+import (
+	"time"
+
+	"commercetools-replica/internal/domain"
+)
 
 type ctProductDiscount struct {
 	ID             string                 `json:"id"`
@@ -11,14 +15,18 @@ type ctProductDiscount struct {
 	Predicate      string                 `json:"predicate"`
 	IsActive       bool                   `json:"isActive"`
 	SortOrder      string                 `json:"sortOrder"`
+	ValidFrom      *time.Time             `json:"validFrom,omitempty"`
+	ValidUntil     *time.Time             `json:"validUntil,omitempty"`
+	References     []ctRef                `json:"references"`
 	Version        int                    `json:"version"`
 	CreatedAt      time.Time              `json:"createdAt"`
 	LastModifiedAt time.Time              `json:"lastModifiedAt"`
 }
 
 type ctProductDiscountValue struct {
-	Type      string `json:"type"`
-	Permyriad int    `json:"permyriad"`
+	Type      string         `json:"type"`
+	Permyriad int            `json:"permyriad,omitempty"`
+	Money     []ctPriceValue `json:"money,omitempty"`
 }
 
 type ctProductDiscountList struct {
@@ -29,47 +37,49 @@ type ctProductDiscountList struct {
 	Results []ctProductDiscount `json:"results"`
 }
 
-func buildProductDiscountList(limit, offset int) ctProductDiscountList {
-	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
-	discounts := []ctProductDiscount{
-		{
-			ID:          "disc-1",
-			Key:         "spring-15",
-			Name:        map[string]string{"en": "Spring 15%"},
-			Description: map[string]string{"en": "15% off selected categories"},
-			Value: ctProductDiscountValue{
-				Type:      "relative",
-				Permyriad: 1500,
-			},
-			Predicate:      `product.categories.id containsAny ("cactus", "cat-uuid-2")`,
-			IsActive:       true,
-			SortOrder:      "0.2",
-			Version:        1,
-			CreatedAt:      createdAt,
-			LastModifiedAt: createdAt,
-		},
-	}
-
+func buildProductDiscountList(discounts []domain.ProductDiscount, total, limit, offset int, loc localeSelector) ctProductDiscountList {
 	if limit <= 0 {
-		limit = 20
+		limit = total
 	}
 	if offset < 0 {
 		offset = 0
 	}
-	end := offset + limit
-	if end > len(discounts) {
-		end = len(discounts)
+	out := ctProductDiscountList{
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		Count:   len(discounts),
+		Results: []ctProductDiscount{},
 	}
-	sliced := []ctProductDiscount{}
-	if offset < len(discounts) {
-		sliced = discounts[offset:end]
+	for _, d := range discounts {
+		out.Results = append(out.Results, toCTProductDiscount(d, loc))
 	}
+	return out
+}
 
-	return ctProductDiscountList{
-		Limit:   limit,
-		Offset:  offset,
-		Count:   len(sliced),
-		Total:   len(discounts),
-		Results: sliced,
+func toCTProductDiscount(d domain.ProductDiscount, loc localeSelector) ctProductDiscount {
+	value := ctProductDiscountValue{Type: d.Value.Type, Permyriad: d.Value.Permyriad}
+	for _, m := range d.Value.Money {
+		value.Money = append(value.Money, toCTMoney(m))
+	}
+	description := loc.project(d.Description)
+	if len(description) == 0 {
+		description = nil
+	}
+	return ctProductDiscount{
+		ID:             d.ID,
+		Key:            d.Key,
+		Name:           loc.project(d.Name),
+		Description:    description,
+		Value:          value,
+		Predicate:      d.Predicate,
+		IsActive:       d.IsActive,
+		SortOrder:      d.SortOrder,
+		ValidFrom:      d.ValidFrom,
+		ValidUntil:     d.ValidUntil,
+		References:     []ctRef{},
+		Version:        d.Version,
+		CreatedAt:      d.CreatedAt,
+		LastModifiedAt: d.LastModifiedAt,
 	}
 }
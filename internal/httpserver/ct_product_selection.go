@@ -0,0 +1,92 @@
+package httpserver
+
+import (
+	"time"
+
+	"commercetools-replica/internal/domain"
+)
+
+type ctProductSelection struct {
+	ID             string            `json:"id"`
+	Key            string            `json:"key,omitempty"`
+	Version        int               `json:"version"`
+	Name           map[string]string `json:"name"`
+	ProductCount   int               `json:"productCount"`
+	Mode           string            `json:"mode"`
+	CreatedAt      time.Time         `json:"createdAt"`
+	LastModifiedAt time.Time         `json:"lastModifiedAt"`
+}
+
+type ctProductSelectionList struct {
+	Limit   int                  `json:"limit"`
+	Offset  int                  `json:"offset"`
+	Count   int                  `json:"count"`
+	Total   int                  `json:"total"`
+	Results []ctProductSelection `json:"results"`
+}
+
+// ctProductSelectionProduct is one entry of GET /product-selections/:id/products.
+type ctProductSelectionProduct struct {
+	Product ctRef `json:"product"`
+}
+
+type ctProductSelectionProductList struct {
+	Limit   int                         `json:"limit"`
+	Offset  int                         `json:"offset"`
+	Count   int                         `json:"count"`
+	Total   int                         `json:"total"`
+	Results []ctProductSelectionProduct `json:"results"`
+}
+
+func buildProductSelectionList(selections []domain.ProductSelection, total, limit, offset int, loc localeSelector) ctProductSelectionList {
+	if limit <= 0 {
+		limit = total
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	out := ctProductSelectionList{
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		Count:   len(selections),
+		Results: []ctProductSelection{},
+	}
+	for _, sel := range selections {
+		out.Results = append(out.Results, toCTProductSelection(sel, loc))
+	}
+	return out
+}
+
+func toCTProductSelection(sel domain.ProductSelection, loc localeSelector) ctProductSelection {
+	return ctProductSelection{
+		ID:             sel.ID,
+		Key:            sel.Key,
+		Version:        sel.Version,
+		Name:           loc.project(sel.Name),
+		ProductCount:   sel.ProductCount,
+		Mode:           "Individual",
+		CreatedAt:      sel.CreatedAt,
+		LastModifiedAt: sel.LastModifiedAt,
+	}
+}
+
+func buildProductSelectionProductList(productIDs []string, total, limit, offset int) ctProductSelectionProductList {
+	if limit <= 0 {
+		limit = total
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	out := ctProductSelectionProductList{
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		Count:   len(productIDs),
+		Results: []ctProductSelectionProduct{},
+	}
+	for _, id := range productIDs {
+		out.Results = append(out.Results, ctProductSelectionProduct{Product: ctRef{TypeID: "product", ID: id}})
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+package httpserver
+
+import (
+	"time"
+
+	"commercetools-replica/internal/domain"
+)
+
+type ctProductTypeResource struct {
+	ID             string                  `json:"id"`
+	Key            string                  `json:"key,omitempty"`
+	Version        int                     `json:"version"`
+	CreatedAt      time.Time               `json:"createdAt"`
+	LastModifiedAt time.Time               `json:"lastModifiedAt"`
+	Name           string                  `json:"name"`
+	Description    string                  `json:"description"`
+	Attributes     []ctAttributeDefinition `json:"attributes"`
+}
+
+type ctAttributeDefinition struct {
+	Type                ctAttributeType   `json:"type"`
+	Name                string            `json:"name"`
+	Label               map[string]string `json:"label"`
+	IsRequired          bool              `json:"isRequired"`
+	AttributeConstraint string            `json:"attributeConstraint"`
+	InputHint           string            `json:"inputHint"`
+	IsSearchable        bool              `json:"isSearchable"`
+}
+
+type ctAttributeType struct {
+	Name            string           `json:"name"`
+	Values          []ctEnumValue    `json:"values,omitempty"`
+	ElementType     *ctAttributeType `json:"elementType,omitempty"`
+	ReferenceTypeID string           `json:"referenceTypeId,omitempty"`
+}
+
+// ctEnumValue carries a plain label for enum types and a locale map for lenum types.
+type ctEnumValue struct {
+	Key   string      `json:"key"`
+	Label interface{} `json:"label"`
+}
+
+type ctProductTypeList struct {
+	Limit   int                     `json:"limit"`
+	Offset  int                     `json:"offset"`
+	Count   int                     `json:"count"`
+	Total   int                     `json:"total"`
+	Results []ctProductTypeResource `json:"results"`
+}
+
+func buildProductTypeList(types []domain.ProductType, total, limit, offset int, loc localeSelector) ctProductTypeList {
+	if limit <= 0 {
+		limit = total
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	out := ctProductTypeList{
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		Count:   len(types),
+		Results: []ctProductTypeResource{},
+	}
+	for _, t := range types {
+		out.Results = append(out.Results, toCTProductType(t, loc))
+	}
+	return out
+}
+
+func toCTProductType(t domain.ProductType, loc localeSelector) ctProductTypeResource {
+	attrs := make([]ctAttributeDefinition, 0, len(t.Attributes))
+	for _, def := range t.Attributes {
+		attrs = append(attrs, ctAttributeDefinition{
+			Type:                toCTAttributeType(def.Type, loc),
+			Name:                def.Name,
+			Label:               loc.project(def.Label),
+			IsRequired:          def.IsRequired,
+			AttributeConstraint: def.AttributeConstraint,
+			InputHint:           def.InputHint,
+			IsSearchable:        def.IsSearchable,
+		})
+	}
+	return ctProductTypeResource{
+		ID:             t.ID,
+		Key:            t.Key,
+		Version:        t.Version,
+		CreatedAt:      t.CreatedAt,
+		LastModifiedAt: t.LastModifiedAt,
+		Name:           t.Name,
+		Description:    t.Description,
+		Attributes:     attrs,
+	}
+}
+
+func toCTAttributeType(t domain.AttributeType, loc localeSelector) ctAttributeType {
+	out := ctAttributeType{Name: t.Name, ReferenceTypeID: t.ReferenceTypeID}
+	for _, v := range t.Values {
+		var label interface{} = v.Label.Get(domain.DefaultLocale)
+		if t.Name == domain.AttributeTypeLEnum {
+			label = loc.project(v.Label)
+		}
+		out.Values = append(out.Values, ctEnumValue{Key: v.Key, Label: label})
+	}
+	if t.ElementType != nil {
+		elem := toCTAttributeType(*t.ElementType, loc)
+		out.ElementType = &elem
+	}
+	return out
+}
@@ -8,32 +8,33 @@ import (
 	"time"
 
 	"commercetools-replica/internal/domain"
+	"commercetools-replica/internal/money"
 	"log"
 )
 
 type ctProduct struct {
-	ID                  string                   `json:"id"`
-	Key                 string                   `json:"key,omitempty"`
-	Version             int                      `json:"version"`
-	CreatedAt           time.Time                `json:"createdAt"`
-	LastModifiedAt      time.Time                `json:"lastModifiedAt"`
-	LastMessageSequence int                      `json:"lastMessageSequenceNumber,omitempty"`
-	ProductType         *ctRef                   `json:"productType,omitempty"`
-	MasterData          ctMasterData             `json:"masterData"`
-	PriceMode           string                   `json:"priceMode,omitempty"`
-	TaxCategory         *ctRef                   `json:"taxCategory,omitempty"`
-	State               *ctRef                   `json:"state,omitempty"`
-	LastVariantID       int                      `json:"lastVariantId,omitempty"`
-	HasStagedChanges    bool                     `json:"hasStagedChanges"`
-	Published           bool                     `json:"published"`
-	Slug                map[string]string        `json:"slug,omitempty"`
-	MasterVariantID     int                      `json:"masterVariantId,omitempty"`
-	LastModifiedBy      interface{}              `json:"lastModifiedBy,omitempty"`
-	CreatedBy           interface{}              `json:"createdBy,omitempty"`
-	MetaTitle           map[string]string        `json:"metaTitle,omitempty"`
-	MetaDescription     map[string]string        `json:"metaDescription,omitempty"`
-	MetaKeywords        map[string]string        `json:"metaKeywords,omitempty"`
-	SearchKeywords      map[string][]interface{} `json:"searchKeywords,omitempty"`
+	ID                  string                       `json:"id"`
+	Key                 string                       `json:"key,omitempty"`
+	Version             int                          `json:"version"`
+	CreatedAt           time.Time                    `json:"createdAt"`
+	LastModifiedAt      time.Time                    `json:"lastModifiedAt"`
+	LastMessageSequence int                          `json:"lastMessageSequenceNumber,omitempty"`
+	ProductType         *ctRef                       `json:"productType,omitempty"`
+	MasterData          ctMasterData                 `json:"masterData"`
+	PriceMode           string                       `json:"priceMode,omitempty"`
+	TaxCategory         *ctRef                       `json:"taxCategory,omitempty"`
+	State               *ctRef                       `json:"state,omitempty"`
+	LastVariantID       int                          `json:"lastVariantId,omitempty"`
+	HasStagedChanges    bool                         `json:"hasStagedChanges"`
+	Published           bool                         `json:"published"`
+	Slug                map[string]string            `json:"slug,omitempty"`
+	MasterVariantID     int                          `json:"masterVariantId,omitempty"`
+	LastModifiedBy      interface{}                  `json:"lastModifiedBy,omitempty"`
+	CreatedBy           interface{}                  `json:"createdBy,omitempty"`
+	MetaTitle           map[string]string            `json:"metaTitle,omitempty"`
+	MetaDescription     map[string]string            `json:"metaDescription,omitempty"`
+	MetaKeywords        map[string]string            `json:"metaKeywords,omitempty"`
+	SearchKeywords      map[string][]ctSearchKeyword `json:"searchKeywords,omitempty"`
 }
 
 type ctMasterData struct {
@@ -44,38 +45,91 @@ type ctMasterData struct {
 }
 
 type ctProductData struct {
-	Name            map[string]string        `json:"name"`
-	Description     map[string]string        `json:"description,omitempty"`
-	Slug            map[string]string        `json:"slug,omitempty"`
-	MetaTitle       map[string]string        `json:"metaTitle,omitempty"`
-	MetaDescription map[string]string        `json:"metaDescription,omitempty"`
-	MasterVariant   ctVariant                `json:"masterVariant"`
-	Variants        []ctVariant              `json:"variants"`
-	SearchKeywords  map[string][]interface{} `json:"searchKeywords,omitempty"`
-	Attributes      []interface{}            `json:"attributes"`
-	Assets          []interface{}            `json:"assets"`
-	Categories      []interface{}            `json:"categories"`
-	CategoryOrder   map[string]string        `json:"categoryOrderHints,omitempty"`
+	Name            map[string]string            `json:"name"`
+	Description     map[string]string            `json:"description,omitempty"`
+	Slug            map[string]string            `json:"slug,omitempty"`
+	MetaTitle       map[string]string            `json:"metaTitle,omitempty"`
+	MetaDescription map[string]string            `json:"metaDescription,omitempty"`
+	MasterVariant   ctVariant                    `json:"masterVariant"`
+	Variants        []ctVariant                  `json:"variants"`
+	SearchKeywords  map[string][]ctSearchKeyword `json:"searchKeywords,omitempty"`
+	Attributes      []interface{}                `json:"attributes"`
+	Assets          []interface{}                `json:"assets"`
+	Categories      []ctRef                      `json:"categories"`
+	CategoryOrder   map[string]string            `json:"categoryOrderHints,omitempty"`
+}
+
+type ctProductProjection struct {
+	ID                 string                       `json:"id"`
+	Key                string                       `json:"key,omitempty"`
+	Version            int                          `json:"version"`
+	CreatedAt          time.Time                    `json:"createdAt"`
+	LastModifiedAt     time.Time                    `json:"lastModifiedAt"`
+	ProductType        *ctRef                       `json:"productType,omitempty"`
+	Name               map[string]string            `json:"name"`
+	Description        map[string]string            `json:"description,omitempty"`
+	Slug               map[string]string            `json:"slug"`
+	MetaTitle          map[string]string            `json:"metaTitle,omitempty"`
+	MetaDescription    map[string]string            `json:"metaDescription,omitempty"`
+	SearchKeywords     map[string][]ctSearchKeyword `json:"searchKeywords,omitempty"`
+	Categories         []ctRef                      `json:"categories"`
+	CategoryOrderHints map[string]string            `json:"categoryOrderHints"`
+	MasterVariant      ctVariant                    `json:"masterVariant"`
+	Variants           []ctVariant                  `json:"variants"`
+	HasStagedChanges   bool                         `json:"hasStagedChanges"`
+	Published          bool                         `json:"published"`
+	PriceMode          string                       `json:"priceMode,omitempty"`
+	TaxCategory        *ctRef                       `json:"taxCategory,omitempty"`
+}
+
+type ctProductProjectionList struct {
+	Limit   int                   `json:"limit"`
+	Offset  int                   `json:"offset"`
+	Count   int                   `json:"count"`
+	Total   int                   `json:"total"`
+	Results []ctProductProjection `json:"results"`
+}
+
+type ctSearchKeyword struct {
+	Text string `json:"text"`
 }
 
 type ctVariant struct {
 	ID         int           `json:"id"`
 	SKU        string        `json:"sku"`
+	Key        string        `json:"key,omitempty"`
 	Prices     []ctPrice     `json:"prices"`
 	Images     []ctImage     `json:"images"`
 	Assets     []interface{} `json:"assets"`
-	Attributes []interface{} `json:"attributes"`
+	Attributes []ctAttribute `json:"attributes"`
+	// Price is only set when the request selects a price with priceCurrency.
+	Price *ctPrice `json:"price,omitempty"`
+	// Availability is only set for variants with inventory entries.
+	Availability *ctVariantAvailability `json:"availability,omitempty"`
+}
+
+type ctAttribute struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
 }
 
 type ctPrice struct {
-	ID    string       `json:"id,omitempty"`
-	Value ctPriceValue `json:"value"`
+	ID            string             `json:"id,omitempty"`
+	Value         ctPriceValue       `json:"value"`
+	CustomerGroup *ctRef             `json:"customerGroup,omitempty"`
+	Discounted    *ctDiscountedPrice `json:"discounted,omitempty"`
+}
+
+type ctDiscountedPrice struct {
+	Value    ctPriceValue `json:"value"`
+	Discount ctRef        `json:"discount"`
 }
 
 type ctPriceValue struct {
 	Type           string `json:"type"`
 	CurrencyCode   string `json:"currencyCode"`
 	CentAmount     int64  `json:"centAmount"`
+	PreciseAmount  *int64 `json:"preciseAmount,omitempty"`
 	FractionDigits int    `json:"fractionDigits"`
 }
 
@@ -164,37 +218,22 @@ type ctCategoryList struct {
 	Results []ctCategory `json:"results"`
 }
 
-func buildCategoryList(cats []domain.Category, limit, offset int) ctCategoryList {
-	keyToCat := make(map[string]domain.Category, len(cats))
-	keyToID := make(map[string]string, len(cats))
-	for _, c := range cats {
-		keyToCat[c.Key] = c
-		keyToID[c.Key] = c.ID
-	}
+func buildCategoryList(cats []domain.Category, total, limit, offset int, loc localeSelector) ctCategoryList {
 	if limit <= 0 {
-		limit = len(cats)
+		limit = total
 	}
 	if offset < 0 {
 		offset = 0
 	}
-	end := offset + limit
-	if end > len(cats) {
-		end = len(cats)
-	}
-	sliced := []domain.Category{}
-	if offset < len(cats) {
-		sliced = cats[offset:end]
-	}
 	out := ctCategoryList{
-		Limit:  limit,
-		Offset: offset,
-		Total:  len(cats),
-		Count:  len(sliced),
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		Count:   len(cats),
+		Results: []ctCategory{},
 	}
-	for _, c := range sliced {
-		parentID := keyToID[c.ParentKey]
-		ancestors := buildAncestors(c, keyToCat, keyToID)
-		out.Results = append(out.Results, toCTCategory(c, parentID, ancestors))
+	for _, c := range cats {
+		out.Results = append(out.Results, toCTCategory(c, loc))
 	}
 	return out
 }
@@ -215,50 +254,29 @@ func parseLimitOffset(qLimit, qOffset string) (int, int) {
 	return limit, offset
 }
 
-func buildAncestors(c domain.Category, keyToCat map[string]domain.Category, keyToID map[string]string) []ctRef {
-	var ancestors []ctRef
-	seen := make(map[string]struct{})
-	current := c.ParentKey
-	for current != "" {
-		if _, ok := seen[current]; ok {
-			break
-		}
-		seen[current] = struct{}{}
-		id := keyToID[current]
-		if id == "" {
-			break
-		}
-		ancestors = append([]ctRef{{TypeID: "category", ID: id}}, ancestors...)
-		next := keyToCat[current].ParentKey
-		current = next
-	}
-	return ancestors
-}
-
-func toCTCategory(c domain.Category, parentID string, ancestors []ctRef) ctCategory {
-	name := c.Name
-	if name == "" {
-		name = c.Key
+func toCTCategory(c domain.Category, loc localeSelector) ctCategory {
+	nameMap := loc.project(c.Name)
+	if len(nameMap) == 0 {
+		nameMap = map[string]string{domain.DefaultLocale: c.Key}
 	}
-	nameMap := map[string]string{"en": name}
-	slugVal := c.Slug
-	if slugVal == "" {
-		slugVal = c.Key
+	slugMap := loc.project(c.Slug)
+	if len(slugMap) == 0 {
+		slugMap = map[string]string{domain.DefaultLocale: c.Key}
 	}
-	slugMap := map[string]string{"en": slugVal}
 	var parentRef *ctRef
-	if parentID != "" {
-		parentRef = &ctRef{TypeID: "category", ID: parentID}
+	if c.ParentID != "" {
+		parentRef = &ctRef{TypeID: "category", ID: c.ParentID}
 	}
-	metaTitle := nameMap
-	metaDesc := map[string]string{}
-	if c.MetaDescription != "" {
-		metaDesc["en"] = c.MetaDescription
+	ancestors := make([]ctRef, 0, len(c.AncestorIDs))
+	for _, id := range c.AncestorIDs {
+		ancestors = append(ancestors, ctRef{TypeID: "category", ID: id})
 	}
-	descMap := map[string]string{}
-	if c.Description != "" {
-		descMap["en"] = c.Description
+	metaTitle := loc.project(c.MetaTitle)
+	if len(metaTitle) == 0 {
+		metaTitle = nameMap
 	}
+	metaDesc := loc.project(c.MetaDescription)
+	descMap := loc.project(c.Description)
 	return ctCategory{
 		ID:              c.ID,
 		Key:             c.Key,
@@ -276,84 +294,196 @@ func toCTCategory(c domain.Category, parentID string, ancestors []ctRef) ctCateg
 	}
 }
 
-func toCTProduct(logger *log.Logger, p domain.Product, fileURLHost string) ctProduct {
-	name := map[string]string{"en": p.Name}
-	desc := map[string]string{}
-	if p.Description != "" {
-		desc["en"] = p.Description
-	}
-
-	slug := map[string]string{}
-	if p.Key != "" {
-		slug["en"] = strings.ReplaceAll(strings.ToLower(p.Key), " ", "-")
-	}
-
-	images := extractImages(logger, p.Attributes, fileURLHost)
-
-	variant := ctVariant{
-		ID:         1,
-		SKU:        p.SKU,
-		Prices:     []ctPrice{{Value: ctPriceValue{Type: "centPrecision", CurrencyCode: p.Currency, CentAmount: p.PriceCents, FractionDigits: 2}}},
-		Images:     images,
-		Assets:     []interface{}{},
-		Attributes: []interface{}{},
-	}
-
-	data := ctProductData{
-		Name:            name,
-		Description:     desc,
-		Slug:            slug,
-		MetaTitle:       map[string]string{"en": ""},
-		MetaDescription: map[string]string{"en": ""},
-		MasterVariant:   variant,
-		Variants:        []ctVariant{},
-		SearchKeywords:  map[string][]interface{}{},
-		Attributes:      []interface{}{},
-		Assets:          []interface{}{},
-		Categories:      []interface{}{},
-		CategoryOrder:   map[string]string{},
-	}
+func toCTProduct(logger *log.Logger, p domain.Product, fileURLHost string, loc localeSelector) ctProduct {
+	current := toCTProductData(logger, p.Current, fileURLHost, loc)
+	staged := toCTProductData(logger, p.Staged, fileURLHost, loc)
 
 	return ctProduct{
 		ID:             p.ID,
 		Key:            p.Key,
-		Version:        1,
+		Version:        p.Version,
 		CreatedAt:      p.CreatedAt,
-		LastModifiedAt: p.CreatedAt,
+		LastModifiedAt: p.LastModifiedAt,
+		ProductType:    productTypeRef(p),
 		MasterData: ctMasterData{
-			Current:          data,
-			Staged:           data,
-			Published:        true,
-			HasStagedChanges: false,
+			Current:          current,
+			Staged:           staged,
+			Published:        p.Published,
+			HasStagedChanges: p.HasStagedChanges,
 		},
-		HasStagedChanges: false,
-		Published:        true,
-		Slug:             slug,
-		MetaTitle:        map[string]string{"en": ""},
-		MetaDescription:  map[string]string{"en": ""},
+		HasStagedChanges: p.HasStagedChanges,
+		Published:        p.Published,
+		Slug:             current.Slug,
+		MetaTitle:        current.MetaTitle,
+		MetaDescription:  current.MetaDescription,
 		MetaKeywords:     map[string]string{},
-		SearchKeywords:   map[string][]interface{}{},
+		SearchKeywords:   current.SearchKeywords,
 		PriceMode:        "Embedded",
-		LastVariantID:    1,
+		TaxCategory:      taxCategoryRef(p),
+		MasterVariantID:  p.Current.MasterVariant.ID,
+		LastVariantID:    p.LastVariantID(),
 	}
 }
 
-func extractImages(logger *log.Logger, attrs map[string]interface{}, fileURLHost string) []ctImage {
-	raw, ok := attrs["images"]
-	if !ok {
+func toCTProductData(logger *log.Logger, d domain.ProductData, fileURLHost string, loc localeSelector) ctProductData {
+	categories := make([]ctRef, 0, len(d.CategoryIDs))
+	for _, id := range d.CategoryIDs {
+		categories = append(categories, ctRef{TypeID: "category", ID: id})
+	}
+	variants := make([]ctVariant, 0, len(d.Variants))
+	for _, v := range d.Variants {
+		variants = append(variants, toCTVariant(logger, v, fileURLHost))
+	}
+	return ctProductData{
+		Name:            loc.project(d.Name),
+		Description:     loc.project(d.Description),
+		Slug:            loc.project(d.Slug),
+		MetaTitle:       loc.project(d.MetaTitle),
+		MetaDescription: loc.project(d.MetaDescription),
+		MasterVariant:   toCTVariant(logger, d.MasterVariant, fileURLHost),
+		Variants:        variants,
+		SearchKeywords:  loc.projectKeywords(d.SearchKeywords),
+		Attributes:      []interface{}{},
+		Assets:          []interface{}{},
+		Categories:      categories,
+		CategoryOrder:   map[string]string{},
+	}
+}
+
+func productTypeRef(p domain.Product) *ctRef {
+	if p.ProductTypeID == "" {
 		return nil
 	}
-	var urls []string
-	switch v := raw.(type) {
-	case []interface{}:
-		for _, item := range v {
-			if s, ok := item.(string); ok {
-				urls = append(urls, s)
-			}
+	return &ctRef{TypeID: "product-type", ID: p.ProductTypeID}
+}
+
+func taxCategoryRef(p domain.Product) *ctRef {
+	if p.TaxCategoryID == "" {
+		return nil
+	}
+	return &ctRef{TypeID: "tax-category", ID: p.TaxCategoryID}
+}
+
+// toCTProductProjection flattens the current data, or the staged data when staged is set.
+func toCTProductProjection(logger *log.Logger, p domain.Product, staged bool, fileURLHost string, loc localeSelector) ctProductProjection {
+	data := p.Current
+	if staged {
+		data = p.Staged
+	}
+	d := toCTProductData(logger, data, fileURLHost, loc)
+	return ctProductProjection{
+		ID:                 p.ID,
+		Key:                p.Key,
+		Version:            p.Version,
+		CreatedAt:          p.CreatedAt,
+		LastModifiedAt:     p.LastModifiedAt,
+		ProductType:        productTypeRef(p),
+		Name:               d.Name,
+		Description:        d.Description,
+		Slug:               d.Slug,
+		MetaTitle:          d.MetaTitle,
+		MetaDescription:    d.MetaDescription,
+		SearchKeywords:     d.SearchKeywords,
+		Categories:         d.Categories,
+		CategoryOrderHints: d.CategoryOrder,
+		MasterVariant:      d.MasterVariant,
+		Variants:           d.Variants,
+		HasStagedChanges:   p.HasStagedChanges,
+		Published:          p.Published,
+		PriceMode:          "Embedded",
+		TaxCategory:        taxCategoryRef(p),
+	}
+}
+
+func buildProductProjectionList(logger *log.Logger, products []domain.Product, staged bool, limit, offset int, fileURLHost string, loc localeSelector) ctProductProjectionList {
+	total := len(products)
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	out := ctProductProjectionList{
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		Results: []ctProductProjection{},
+	}
+	if offset < total {
+		for _, p := range products[offset:end] {
+			out.Results = append(out.Results, toCTProductProjection(logger, p, staged, fileURLHost, loc))
+		}
+	}
+	out.Count = len(out.Results)
+	return out
+}
+
+func toCTVariant(logger *log.Logger, v domain.ProductVariant, fileURLHost string) ctVariant {
+	prices := make([]ctPrice, 0, len(v.Prices))
+	for _, price := range v.Prices {
+		prices = append(prices, toCTPrice(price))
+	}
+	var selected *ctPrice
+	if v.SelectedPrice != nil {
+		p := toCTPrice(*v.SelectedPrice)
+		selected = &p
+	}
+	return ctVariant{
+		ID:           v.ID,
+		SKU:          v.SKU,
+		Key:          v.Key,
+		Prices:       prices,
+		Images:       extractImages(logger, v.Images, fileURLHost),
+		Assets:       []interface{}{},
+		Attributes:   toCTAttributes(v.Attributes),
+		Price:        selected,
+		Availability: toCTVariantAvailability(v.Availability),
+	}
+}
+
+func toCTPrice(p domain.Price) ctPrice {
+	out := ctPrice{ID: p.ID, Value: toCTMoney(p.Value)}
+	if p.CustomerGroupID != "" {
+		out.CustomerGroup = &ctRef{TypeID: "customer-group", ID: p.CustomerGroupID}
+	}
+	if p.Discounted != nil {
+		out.Discounted = &ctDiscountedPrice{
+			Value:    toCTMoney(p.Discounted.Value),
+			Discount: ctRef{TypeID: "product-discount", ID: p.Discounted.DiscountID},
 		}
-	case []string:
-		urls = v
 	}
+	return out
+}
+
+// toCTMoney renders m as centPrecision money with the fraction digits of its
+// currency, or as highPrecision money when it carries more decimals.
+func toCTMoney(m domain.Money) ctPriceValue {
+	if m.HighPrecision() {
+		precise := m.PreciseAmount
+		return ctPriceValue{Type: "highPrecision", CurrencyCode: m.CurrencyCode, CentAmount: m.CentAmount, PreciseAmount: &precise, FractionDigits: m.FractionDigits}
+	}
+	return ctPriceValue{Type: "centPrecision", CurrencyCode: m.CurrencyCode, CentAmount: m.CentAmount, FractionDigits: money.FractionDigits(m.CurrencyCode)}
+}
+
+// toCTAttributes lists attributes sorted by name so responses are stable.
+func toCTAttributes(attributes map[string]interface{}) []ctAttribute {
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	attrs := make([]ctAttribute, 0, len(names))
+	for _, name := range names {
+		attrs = append(attrs, ctAttribute{Name: name, Value: attributes[name]})
+	}
+	return attrs
+}
+
+func extractImages(logger *log.Logger, urls []string, fileURLHost string) []ctImage {
 	var images []ctImage
 	for _, u := range urls {
 		if strings.TrimSpace(u) == "" {
@@ -446,11 +576,16 @@ func filterProducts(products []domain.Product, req searchRequest, categoryIDToKe
 
 	var filtered []domain.Product
 	for _, p := range products {
+		// Search runs over current data, which only exists for published products.
+		if !p.Published {
+			continue
+		}
 		if prange != nil {
-			if prange.GTE != nil && p.PriceCents < *prange.GTE {
+			price := searchPrice(p)
+			if prange.GTE != nil && price < *prange.GTE {
 				continue
 			}
-			if prange.LTE != nil && p.PriceCents > *prange.LTE {
+			if prange.LTE != nil && price > *prange.LTE {
 				continue
 			}
 		}
@@ -465,12 +600,19 @@ func filterProducts(products []domain.Product, req searchRequest, categoryIDToKe
 }
 
 func sortProducts(products []domain.Product, req searchRequest) {
+	nameOf := func(p domain.Product, locale string) string {
+		return strings.ToLower(p.Current.Name.Get(locale))
+	}
 	if len(req.Sort) == 0 {
 		sort.Slice(products, func(i, j int) bool {
-			return strings.ToLower(products[i].Name) < strings.ToLower(products[j].Name)
+			return nameOf(products[i], domain.DefaultLocale) < nameOf(products[j], domain.DefaultLocale)
 		})
 		return
 	}
+	locale := req.Sort[0].Language
+	if locale == "" {
+		locale = domain.DefaultLocale
+	}
 
 	// Support name asc/desc and price asc/desc
 	field := strings.ToLower(req.Sort[0].Field)
@@ -483,8 +625,8 @@ func sortProducts(products []domain.Product, req searchRequest) {
 	switch field {
 	case "name":
 		less = func(i, j int) bool {
-			li := strings.ToLower(products[i].Name)
-			lj := strings.ToLower(products[j].Name)
+			li := nameOf(products[i], locale)
+			lj := nameOf(products[j], locale)
 			if order == "desc" {
 				return li > lj
 			}
@@ -493,48 +635,32 @@ func sortProducts(products []domain.Product, req searchRequest) {
 	case "variants.prices.centamount", "price", "variants.prices.value.centamount":
 		less = func(i, j int) bool {
 			if order == "desc" {
-				return products[i].PriceCents > products[j].PriceCents
+				return searchPrice(products[i]) > searchPrice(products[j])
 			}
-			return products[i].PriceCents < products[j].PriceCents
+			return searchPrice(products[i]) < searchPrice(products[j])
 		}
 	default:
 		less = func(i, j int) bool {
-			return strings.ToLower(products[i].Name) < strings.ToLower(products[j].Name)
+			return nameOf(products[i], locale) < nameOf(products[j], locale)
 		}
 	}
 
 	sort.Slice(products, less)
 }
 
+// searchPrice is the first price of the current master variant.
+func searchPrice(p domain.Product) int64 {
+	prices := p.Current.MasterVariant.Prices
+	if len(prices) == 0 {
+		return 0
+	}
+	return prices[0].Value.CentAmount
+}
+
 func containsAnyCategory(p domain.Product, candidates []string) bool {
-	raw, ok := p.Attributes["categories"]
-	if !ok {
-		return false
-	}
-	switch v := raw.(type) {
-	case []interface{}:
-		for _, c := range v {
-			s, ok := c.(string)
-			if !ok {
-				continue
-			}
-			for _, candidate := range candidates {
-				if s == candidate {
-					return true
-				}
-			}
-		}
-	case []string:
-		for _, s := range v {
-			for _, candidate := range candidates {
-				if s == candidate {
-					return true
-				}
-			}
-		}
-	case string:
+	for _, id := range p.Current.CategoryIDs {
 		for _, candidate := range candidates {
-			if v == candidate {
+			if id == candidate {
 				return true
 			}
 		}
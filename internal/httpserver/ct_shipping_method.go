@@ -0,0 +1,160 @@
+package httpserver
+
+import (
+	"time"
+
+	"commercetools-replica/internal/domain"
+)
+
+type ctShippingMethod struct {
+	ID             string       `json:"id"`
+	Key            string       `json:"key,omitempty"`
+	Name           string       `json:"name"`
+	Description    string       `json:"description,omitempty"`
+	TaxCategory    ctRef        `json:"taxCategory"`
+	ZoneRates      []ctZoneRate `json:"zoneRates"`
+	Active         bool         `json:"active"`
+	IsDefault      bool         `json:"isDefault"`
+	Predicate      string       `json:"predicate,omitempty"`
+	Version        int          `json:"version"`
+	CreatedAt      time.Time    `json:"createdAt"`
+	LastModifiedAt time.Time    `json:"lastModifiedAt"`
+}
+
+type ctZoneRate struct {
+	Zone          ctRef            `json:"zone"`
+	ShippingRates []ctShippingRate `json:"shippingRates"`
+}
+
+type ctShippingRate struct {
+	Price     ctPriceValue  `json:"price"`
+	FreeAbove *ctPriceValue `json:"freeAbove,omitempty"`
+	Tiers     []interface{} `json:"tiers"`
+}
+
+type ctShippingMethodList struct {
+	Limit   int                `json:"limit"`
+	Offset  int                `json:"offset"`
+	Count   int                `json:"count"`
+	Total   int                `json:"total"`
+	Results []ctShippingMethod `json:"results"`
+}
+
+// ctShipping is one shipping of a cart in Multiple shipping mode.
+type ctShipping struct {
+	ShippingKey     string          `json:"shippingKey"`
+	ShippingInfo    *ctShippingInfo `json:"shippingInfo"`
+	ShippingAddress ctAddress       `json:"shippingAddress"`
+}
+
+// ctShippingInfo is the shipping method set on a cart.
+type ctShippingInfo struct {
+	ShippingMethodName  string                     `json:"shippingMethodName"`
+	Price               ctPriceValue               `json:"price"`
+	ShippingRate        ctShippingRate             `json:"shippingRate"`
+	TaxedPrice          *ctTaxedItemPrice          `json:"taxedPrice,omitempty"`
+	TaxRate             *ctTaxRate                 `json:"taxRate,omitempty"`
+	TaxCategory         *ctRef                     `json:"taxCategory,omitempty"`
+	ShippingMethod      ctRef                      `json:"shippingMethod"`
+	Deliveries          []interface{}              `json:"deliveries"`
+	DiscountedPrice     *ctDiscountedShippingPrice `json:"discountedPrice,omitempty"`
+	ShippingMethodState string                     `json:"shippingMethodState"`
+}
+
+type ctDiscountedShippingPrice struct {
+	Value             ctPriceValue                  `json:"value"`
+	IncludedDiscounts []ctDiscountedLineItemPortion `json:"includedDiscounts"`
+}
+
+func buildShippingMethodList(methods []domain.ShippingMethod, total, limit, offset int) ctShippingMethodList {
+	if limit <= 0 {
+		limit = total
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	out := ctShippingMethodList{
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		Count:   len(methods),
+		Results: []ctShippingMethod{},
+	}
+	for _, m := range methods {
+		out.Results = append(out.Results, toCTShippingMethod(m))
+	}
+	return out
+}
+
+func toCTShippingMethod(m domain.ShippingMethod) ctShippingMethod {
+	zoneRates := make([]ctZoneRate, 0, len(m.ZoneRates))
+	for _, zr := range m.ZoneRates {
+		rates := make([]ctShippingRate, 0, len(zr.ShippingRates))
+		for _, r := range zr.ShippingRates {
+			rates = append(rates, toCTShippingRate(r))
+		}
+		zoneRates = append(zoneRates, ctZoneRate{Zone: ctRef{TypeID: "zone", ID: zr.ZoneID}, ShippingRates: rates})
+	}
+	return ctShippingMethod{
+		ID:             m.ID,
+		Key:            m.Key,
+		Name:           m.Name,
+		Description:    m.Description,
+		TaxCategory:    ctRef{TypeID: "tax-category", ID: m.TaxCategoryID},
+		ZoneRates:      zoneRates,
+		Active:         true,
+		IsDefault:      m.IsDefault,
+		Predicate:      m.Predicate,
+		Version:        m.Version,
+		CreatedAt:      m.CreatedAt,
+		LastModifiedAt: m.LastModifiedAt,
+	}
+}
+
+func toCTShippingRate(r domain.ShippingRate) ctShippingRate {
+	out := ctShippingRate{Price: toCTMoney(r.Price), Tiers: []interface{}{}}
+	if r.FreeAbove != nil {
+		freeAbove := toCTMoney(*r.FreeAbove)
+		out.FreeAbove = &freeAbove
+	}
+	return out
+}
+
+func toCTShipping(shipping []domain.Shipping) []ctShipping {
+	out := make([]ctShipping, 0, len(shipping))
+	for _, sh := range shipping {
+		info := sh.ShippingInfo
+		out = append(out, ctShipping{
+			ShippingKey:     sh.ShippingKey,
+			ShippingInfo:    toCTShippingInfo(&info),
+			ShippingAddress: toCTAddress(sh.ShippingAddress),
+		})
+	}
+	return out
+}
+
+func toCTShippingInfo(info *domain.ShippingInfo) *ctShippingInfo {
+	if info == nil {
+		return nil
+	}
+	out := &ctShippingInfo{
+		ShippingMethodName:  info.ShippingMethodName,
+		Price:               toCTMoney(info.Price),
+		ShippingRate:        toCTShippingRate(info.ShippingRate),
+		TaxedPrice:          toCTTaxedItemPrice(info.TaxedPrice),
+		TaxRate:             toCTLineTaxRate(info.TaxRate),
+		ShippingMethod:      ctRef{TypeID: "shipping-method", ID: info.ShippingMethodID},
+		Deliveries:          []interface{}{},
+		ShippingMethodState: info.ShippingMethodState,
+	}
+	if info.TaxCategoryID != "" {
+		out.TaxCategory = &ctRef{TypeID: "tax-category", ID: info.TaxCategoryID}
+	}
+	if d := info.DiscountedPrice; d != nil {
+		out.DiscountedPrice = &ctDiscountedShippingPrice{
+			Value:             toCTMoney(d.Value),
+			IncludedDiscounts: toCTDiscountPortions(d.IncludedDiscounts),
+		}
+	}
+	return out
+}
@@ -0,0 +1,101 @@
+package httpserver
+
+import (
+	"time"
+
+	"commercetools-replica/internal/domain"
+)
+
+type ctStore struct {
+	ID                   string                      `json:"id"`
+	Key                  string                      `json:"key"`
+	Version              int                         `json:"version"`
+	Name                 map[string]string           `json:"name,omitempty"`
+	Languages            []string                    `json:"languages"`
+	Countries            []ctStoreCountry            `json:"countries"`
+	DistributionChannels []ctRef                     `json:"distributionChannels"`
+	SupplyChannels       []ctRef                     `json:"supplyChannels"`
+	ProductSelections    []ctProductSelectionSetting `json:"productSelections"`
+	CreatedAt            time.Time                   `json:"createdAt"`
+	LastModifiedAt       time.Time                   `json:"lastModifiedAt"`
+}
+
+type ctStoreCountry struct {
+	Code string `json:"code"`
+}
+
+type ctProductSelectionSetting struct {
+	ProductSelection ctRef `json:"productSelection"`
+	Active           bool  `json:"active"`
+}
+
+type ctStoreList struct {
+	Limit   int       `json:"limit"`
+	Offset  int       `json:"offset"`
+	Count   int       `json:"count"`
+	Total   int       `json:"total"`
+	Results []ctStore `json:"results"`
+}
+
+func buildStoreList(stores []domain.Store, total, limit, offset int, loc localeSelector) ctStoreList {
+	if limit <= 0 {
+		limit = total
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	out := ctStoreList{
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		Count:   len(stores),
+		Results: []ctStore{},
+	}
+	for _, st := range stores {
+		out.Results = append(out.Results, toCTStore(st, loc))
+	}
+	return out
+}
+
+func toCTStore(st domain.Store, loc localeSelector) ctStore {
+	name := loc.project(st.Name)
+	if len(name) == 0 {
+		name = nil
+	}
+	languages := st.Languages
+	if languages == nil {
+		languages = []string{}
+	}
+	countries := make([]ctStoreCountry, 0, len(st.Countries))
+	for _, code := range st.Countries {
+		countries = append(countries, ctStoreCountry{Code: code})
+	}
+	selections := make([]ctProductSelectionSetting, 0, len(st.ProductSelections))
+	for _, sel := range st.ProductSelections {
+		selections = append(selections, ctProductSelectionSetting{
+			ProductSelection: ctRef{TypeID: "product-selection", ID: sel.ProductSelectionID},
+			Active:           sel.Active,
+		})
+	}
+	return ctStore{
+		ID:                   st.ID,
+		Key:                  st.Key,
+		Version:              st.Version,
+		Name:                 name,
+		Languages:            languages,
+		Countries:            countries,
+		DistributionChannels: channelRefs(st.DistributionChannelIDs),
+		SupplyChannels:       channelRefs(st.SupplyChannelIDs),
+		ProductSelections:    selections,
+		CreatedAt:            st.CreatedAt,
+		LastModifiedAt:       st.LastModifiedAt,
+	}
+}
+
+func channelRefs(ids []string) []ctRef {
+	out := make([]ctRef, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, ctRef{TypeID: "channel", ID: id})
+	}
+	return out
+}
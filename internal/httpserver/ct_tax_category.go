@@ -0,0 +1,94 @@
+package httpserver
+
+import (
+	"time"
+
+	"commercetools-replica/internal/domain"
+)
+
+type ctTaxCategory struct {
+	ID             string      `json:"id"`
+	Key            string      `json:"key,omitempty"`
+	Name           string      `json:"name"`
+	Description    string      `json:"description,omitempty"`
+	Rates          []ctTaxRate `json:"rates"`
+	Version        int         `json:"version"`
+	CreatedAt      time.Time   `json:"createdAt"`
+	LastModifiedAt time.Time   `json:"lastModifiedAt"`
+}
+
+type ctTaxRate struct {
+	ID              string         `json:"id,omitempty"`
+	Name            string         `json:"name"`
+	Amount          float64        `json:"amount"`
+	IncludedInPrice bool           `json:"includedInPrice"`
+	Country         string         `json:"country"`
+	State           string         `json:"state,omitempty"`
+	SubRates        []ctTaxSubRate `json:"subRates"`
+}
+
+type ctTaxSubRate struct {
+	Name   string  `json:"name"`
+	Amount float64 `json:"amount"`
+}
+
+type ctTaxCategoryList struct {
+	Limit   int             `json:"limit"`
+	Offset  int             `json:"offset"`
+	Count   int             `json:"count"`
+	Total   int             `json:"total"`
+	Results []ctTaxCategory `json:"results"`
+}
+
+func buildTaxCategoryList(categories []domain.TaxCategory, total, limit, offset int) ctTaxCategoryList {
+	if limit <= 0 {
+		limit = total
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	out := ctTaxCategoryList{
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		Count:   len(categories),
+		Results: []ctTaxCategory{},
+	}
+	for _, c := range categories {
+		out.Results = append(out.Results, toCTTaxCategory(c))
+	}
+	return out
+}
+
+func toCTTaxCategory(c domain.TaxCategory) ctTaxCategory {
+	rates := make([]ctTaxRate, 0, len(c.Rates))
+	for _, r := range c.Rates {
+		rates = append(rates, toCTTaxRate(r))
+	}
+	return ctTaxCategory{
+		ID:             c.ID,
+		Key:            c.Key,
+		Name:           c.Name,
+		Description:    c.Description,
+		Rates:          rates,
+		Version:        c.Version,
+		CreatedAt:      c.CreatedAt,
+		LastModifiedAt: c.LastModifiedAt,
+	}
+}
+
+func toCTTaxRate(r domain.TaxRate) ctTaxRate {
+	subRates := make([]ctTaxSubRate, 0, len(r.SubRates))
+	for _, sub := range r.SubRates {
+		subRates = append(subRates, ctTaxSubRate{Name: sub.Name, Amount: sub.Amount})
+	}
+	return ctTaxRate{
+		ID:              r.ID,
+		Name:            r.Name,
+		Amount:          r.Amount,
+		IncludedInPrice: r.IncludedInPrice,
+		Country:         r.Country,
+		State:           r.State,
+		SubRates:        subRates,
+	}
+}
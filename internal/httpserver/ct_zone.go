@@ -0,0 +1,68 @@
+package httpserver
+
+import (
+	"time"
+
+	"commercetools-replica/internal/domain"
+)
+
+type ctZone struct {
+	ID             string       `json:"id"`
+	Key            string       `json:"key,omitempty"`
+	Name           string       `json:"name"`
+	Description    string       `json:"description,omitempty"`
+	Locations      []ctLocation `json:"locations"`
+	Version        int          `json:"version"`
+	CreatedAt      time.Time    `json:"createdAt"`
+	LastModifiedAt time.Time    `json:"lastModifiedAt"`
+}
+
+type ctLocation struct {
+	Country string `json:"country"`
+	State   string `json:"state,omitempty"`
+}
+
+type ctZoneList struct {
+	Limit   int      `json:"limit"`
+	Offset  int      `json:"offset"`
+	Count   int      `json:"count"`
+	Total   int      `json:"total"`
+	Results []ctZone `json:"results"`
+}
+
+func buildZoneList(zones []domain.Zone, total, limit, offset int) ctZoneList {
+	if limit <= 0 {
+		limit = total
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	out := ctZoneList{
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		Count:   len(zones),
+		Results: []ctZone{},
+	}
+	for _, z := range zones {
+		out.Results = append(out.Results, toCTZone(z))
+	}
+	return out
+}
+
+func toCTZone(z domain.Zone) ctZone {
+	locations := make([]ctLocation, 0, len(z.Locations))
+	for _, l := range z.Locations {
+		locations = append(locations, ctLocation{Country: l.Country, State: l.State})
+	}
+	return ctZone{
+		ID:             z.ID,
+		Key:            z.Key,
+		Name:           z.Name,
+		Description:    z.Description,
+		Locations:      locations,
+		Version:        z.Version,
+		CreatedAt:      z.CreatedAt,
+		LastModifiedAt: z.LastModifiedAt,
+	}
+}
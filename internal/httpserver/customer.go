@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"commercetools-replica/internal/domain"
+	customersvc "commercetools-replica/internal/service/customer"
+	privacysvc "commercetools-replica/internal/service/privacy"
 )
 
 type signupRequest struct {
@@ -24,16 +26,108 @@ type loginRequest struct {
 }
 
 type addressRequest struct {
+	Key        string `json:"key"`
 	FirstName  string `json:"firstName"`
 	LastName   string `json:"lastName"`
 	Country    string `json:"country"`
 	StreetName string `json:"streetName"`
 	PostalCode string `json:"postalCode"`
 	City       string `json:"city"`
+	State      string `json:"state"`
 	Email      string `json:"email"`
 	Department string `json:"department"`
 }
 
+type passwordTokenRequest struct {
+	Email      string `json:"email"`
+	TTLMinutes int    `json:"ttlMinutes"`
+}
+
+type ctCustomerToken struct {
+	CustomerID string    `json:"customerId"`
+	Value      string    `json:"value"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type emailTokenRequest struct {
+	ID         string `json:"id"`
+	Version    int    `json:"version"`
+	TTLMinutes int    `json:"ttlMinutes"`
+}
+
+type ctCustomerList struct {
+	Limit   int          `json:"limit"`
+	Offset  int          `json:"offset"`
+	Count   int          `json:"count"`
+	Total   int          `json:"total"`
+	Results []ctCustomer `json:"results"`
+}
+
+// ctCustomerExport is the data export of GET /me/export.
+type ctCustomerExport struct {
+	Customer   ctCustomer      `json:"customer"`
+	Carts      []ctCart        `json:"carts"`
+	Orders     []ctOrder       `json:"orders"`
+	Tokens     []ctExportToken `json:"tokens"`
+	ExportedAt time.Time       `json:"exportedAt"`
+}
+
+type ctExportToken struct {
+	Kind      string    `json:"kind"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func toCTCustomerExport(e privacysvc.Export, fileURLHost string, loc localeSelector) ctCustomerExport {
+	out := ctCustomerExport{
+		Customer:   toCTCustomer(e.Customer),
+		Carts:      []ctCart{},
+		Orders:     []ctOrder{},
+		Tokens:     []ctExportToken{},
+		ExportedAt: e.ExportedAt,
+	}
+	for _, cart := range e.Carts {
+		out.Carts = append(out.Carts, toCTCart(cart, &e.Customer, fileURLHost, loc))
+	}
+	for _, o := range e.Orders {
+		out.Orders = append(out.Orders, toCTOrder(o, &e.Customer, fileURLHost, loc))
+	}
+	for _, t := range e.Tokens {
+		out.Tokens = append(out.Tokens, ctExportToken{Kind: t.Kind, CreatedAt: t.CreatedAt, ExpiresAt: t.ExpiresAt})
+	}
+	return out
+}
+
+func buildCustomerList(customers []domain.Customer, total, limit, offset int) ctCustomerList {
+	if limit <= 0 {
+		limit = total
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	out := ctCustomerList{
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		Count:   len(customers),
+		Results: []ctCustomer{},
+	}
+	for _, c := range customers {
+		out.Results = append(out.Results, toCTCustomer(c))
+	}
+	return out
+}
+
+func toCTCustomerToken(t customersvc.CustomerToken) ctCustomerToken {
+	return ctCustomerToken{
+		CustomerID: t.CustomerID,
+		Value:      t.Value,
+		ExpiresAt:  t.ExpiresAt,
+		CreatedAt:  t.CreatedAt,
+	}
+}
+
 type tokenRequest struct {
 	GrantType string `form:"grant_type" binding:"required"`
 	Username  string `form:"username" binding:"required"`
@@ -46,6 +140,30 @@ type anonymousTokenRequest struct {
 	Scope     string `form:"scope" binding:"required"`
 }
 
+type clientTokenRequest struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	Scope        string `form:"scope" binding:"required"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// ctError is the commercetools error body; error repeats the message as in
+// the other error responses.
+type ctError struct {
+	StatusCode int             `json:"statusCode"`
+	Message    string          `json:"message"`
+	Error      string          `json:"error"`
+	Errors     []ctErrorObject `json:"errors"`
+}
+
+type ctErrorObject struct {
+	Code              string `json:"code"`
+	Message           string `json:"message"`
+	Field             string `json:"field,omitempty"`
+	Rule              string `json:"rule,omitempty"`
+	RetryAfterSeconds int    `json:"retryAfterSeconds,omitempty"`
+}
+
 type customerResponse struct {
 	Customer ctCustomer `json:"customer"`
 }
@@ -56,28 +174,33 @@ type loginResponse struct {
 }
 
 type ctCustomer struct {
-	ID                        string        `json:"id"`
-	Version                   int           `json:"version"`
-	VersionModifiedAt         time.Time     `json:"versionModifiedAt"`
-	LastMessageSequenceNumber int           `json:"lastMessageSequenceNumber"`
-	CreatedAt                 time.Time     `json:"createdAt"`
-	LastModifiedAt            time.Time     `json:"lastModifiedAt"`
-	LastModifiedBy            auditInfo     `json:"lastModifiedBy"`
-	CreatedBy                 auditInfo     `json:"createdBy"`
-	Email                     string        `json:"email"`
-	FirstName                 string        `json:"firstName,omitempty"`
-	LastName                  string        `json:"lastName,omitempty"`
-	DateOfBirth               string        `json:"dateOfBirth,omitempty"`
-	Password                  string        `json:"password,omitempty"`
-	Addresses                 []ctAddress   `json:"addresses"`
-	DefaultShippingAddressID  string        `json:"defaultShippingAddressId,omitempty"`
-	DefaultBillingAddressID   string        `json:"defaultBillingAddressId,omitempty"`
-	ShippingAddressIDs        []string      `json:"shippingAddressIds"`
-	BillingAddressIDs         []string      `json:"billingAddressIds"`
-	IsEmailVerified           bool          `json:"isEmailVerified"`
-	CustomerGroupAssignments  []interface{} `json:"customerGroupAssignments"`
-	Stores                    []interface{} `json:"stores"`
-	AuthenticationMode        string        `json:"authenticationMode"`
+	ID                        string                      `json:"id"`
+	Version                   int                         `json:"version"`
+	VersionModifiedAt         time.Time                   `json:"versionModifiedAt"`
+	LastMessageSequenceNumber int                         `json:"lastMessageSequenceNumber"`
+	CreatedAt                 time.Time                   `json:"createdAt"`
+	LastModifiedAt            time.Time                   `json:"lastModifiedAt"`
+	LastModifiedBy            auditInfo                   `json:"lastModifiedBy"`
+	CreatedBy                 auditInfo                   `json:"createdBy"`
+	Email                     string                      `json:"email"`
+	FirstName                 string                      `json:"firstName,omitempty"`
+	LastName                  string                      `json:"lastName,omitempty"`
+	DateOfBirth               string                      `json:"dateOfBirth,omitempty"`
+	Password                  string                      `json:"password,omitempty"`
+	Addresses                 []ctAddress                 `json:"addresses"`
+	DefaultShippingAddressID  string                      `json:"defaultShippingAddressId,omitempty"`
+	DefaultBillingAddressID   string                      `json:"defaultBillingAddressId,omitempty"`
+	ShippingAddressIDs        []string                    `json:"shippingAddressIds"`
+	BillingAddressIDs         []string                    `json:"billingAddressIds"`
+	IsEmailVerified           bool                        `json:"isEmailVerified"`
+	CustomerGroup             *ctRef                      `json:"customerGroup,omitempty"`
+	CustomerGroupAssignments  []ctCustomerGroupAssignment `json:"customerGroupAssignments"`
+	Stores                    []interface{}               `json:"stores"`
+	AuthenticationMode        string                      `json:"authenticationMode"`
+}
+
+type ctCustomerGroupAssignment struct {
+	CustomerGroup ctRef `json:"customerGroup"`
 }
 
 type auditInfo struct {
@@ -87,16 +210,42 @@ type auditInfo struct {
 
 type ctAddress struct {
 	ID         string `json:"id"`
+	Key        string `json:"key,omitempty"`
 	FirstName  string `json:"firstName,omitempty"`
 	LastName   string `json:"lastName,omitempty"`
 	Country    string `json:"country,omitempty"`
 	StreetName string `json:"streetName,omitempty"`
 	PostalCode string `json:"postalCode,omitempty"`
 	City       string `json:"city,omitempty"`
+	State      string `json:"state,omitempty"`
 	Email      string `json:"email,omitempty"`
 	Department string `json:"department,omitempty"`
 }
 
+func toCTAddress(a domain.CustomerAddress) ctAddress {
+	return ctAddress{
+		ID:         a.ID,
+		Key:        a.Key,
+		FirstName:  a.FirstName,
+		LastName:   a.LastName,
+		Country:    a.Country,
+		StreetName: a.StreetName,
+		PostalCode: a.PostalCode,
+		City:       a.City,
+		State:      a.State,
+		Email:      a.Email,
+		Department: a.Department,
+	}
+}
+
+func toCTAddresses(addresses []domain.CustomerAddress) []ctAddress {
+	out := make([]ctAddress, 0, len(addresses))
+	for _, a := range addresses {
+		out = append(out, toCTAddress(a))
+	}
+	return out
+}
+
 var auditDefaults = auditInfo{
 	ClientID:         "G-q8-RwsnGEU-laJdMCAWR6Z",
 	IsPlatformClient: false,
@@ -107,20 +256,7 @@ func toCTCustomer(c domain.Customer) ctCustomer {
 	if created.IsZero() {
 		created = time.Now().UTC()
 	}
-	addresses := make([]ctAddress, 0, len(c.Addresses))
-	for _, a := range c.Addresses {
-		addresses = append(addresses, ctAddress{
-			ID:         a.ID,
-			FirstName:  a.FirstName,
-			LastName:   a.LastName,
-			Country:    a.Country,
-			StreetName: a.StreetName,
-			PostalCode: a.PostalCode,
-			City:       a.City,
-			Email:      a.Email,
-			Department: a.Department,
-		})
-	}
+	addresses := toCTAddresses(c.Addresses)
 
 	shipping := c.ShippingAddressIDs
 	if shipping == nil {
@@ -130,14 +266,28 @@ func toCTCustomer(c domain.Customer) ctCustomer {
 	if billing == nil {
 		billing = []string{}
 	}
+	version := c.Version
+	if version == 0 {
+		version = 1
+	}
+	modified := c.LastModifiedAt
+	if modified.IsZero() {
+		modified = created
+	}
+	var group *ctRef
+	assignments := []ctCustomerGroupAssignment{}
+	if c.CustomerGroupID != "" {
+		group = &ctRef{TypeID: "customer-group", ID: c.CustomerGroupID}
+		assignments = append(assignments, ctCustomerGroupAssignment{CustomerGroup: *group})
+	}
 
 	return ctCustomer{
 		ID:                        c.ID,
-		Version:                   1,
-		VersionModifiedAt:         created,
+		Version:                   version,
+		VersionModifiedAt:         modified,
 		LastMessageSequenceNumber: 1,
 		CreatedAt:                 created,
-		LastModifiedAt:            created,
+		LastModifiedAt:            modified,
 		LastModifiedBy:            auditDefaults,
 		CreatedBy:                 auditDefaults,
 		Email:                     c.Email,
@@ -150,8 +300,9 @@ func toCTCustomer(c domain.Customer) ctCustomer {
 		DefaultBillingAddressID:   c.DefaultBillingAddressID,
 		ShippingAddressIDs:        shipping,
 		BillingAddressIDs:         billing,
-		IsEmailVerified:           false,
-		CustomerGroupAssignments:  []interface{}{},
+		IsEmailVerified:           c.IsEmailVerified,
+		CustomerGroup:             group,
+		CustomerGroupAssignments:  assignments,
 		Stores:                    []interface{}{},
 		AuthenticationMode:        "Password",
 	}
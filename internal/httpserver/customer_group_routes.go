@@ -0,0 +1,38 @@
+package httpserver
+
+import (
+	"context"
+	"log"
+
+	"commercetools-replica/internal/domain"
+	customergroupsvc "commercetools-replica/internal/service/customergroup"
+
+	"github.com/gin-gonic/gin"
+)
+
+type customerGroupService interface {
+	ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.CustomerGroup, int, error)
+	Get(ctx context.Context, projectID, id string) (*domain.CustomerGroup, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.CustomerGroup, error)
+	Create(ctx context.Context, projectID string, draft customergroupsvc.CustomerGroupDraft) (*domain.CustomerGroup, error)
+	Update(ctx context.Context, projectID, id string, in customergroupsvc.UpdateInput) (*domain.CustomerGroup, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.CustomerGroup, error)
+}
+
+// registerCustomerGroupRoutes registers the /customer-groups routes.
+func registerCustomerGroupRoutes(read, write *gin.RouterGroup, logger *log.Logger, svc customerGroupService) {
+	routes := crudRoutes[domain.CustomerGroup, customergroupsvc.CustomerGroupDraft, customergroupsvc.UpdateInput]{
+		path:       "/customer-groups",
+		singular:   "customer group",
+		plural:     "customer groups",
+		svc:        svc,
+		id:         func(c *domain.CustomerGroup) string { return c.ID },
+		draftField: func(d customergroupsvc.CustomerGroupDraft) string { return "key=" + d.Key },
+		conflict:   "customer group with this key already exists",
+		render:     func(_ *gin.Context, c domain.CustomerGroup) any { return toCTCustomerGroup(c) },
+		list: func(_ *gin.Context, items []domain.CustomerGroup, total, limit, offset int) any {
+			return buildCustomerGroupList(items, total, limit, offset)
+		},
+	}
+	routes.register(read, write, logger)
+}
@@ -0,0 +1,214 @@
+package httpserver
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"commercetools-replica/internal/domain"
+	customersvc "commercetools-replica/internal/service/customer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerCustomerRoutes registers the project API for customers on admin,
+// a group that requires an admin token.
+func registerCustomerRoutes(admin *gin.RouterGroup, logger *log.Logger, deps Deps) {
+	admin.GET("/customers", func(c *gin.Context) {
+		project := mustProject(c)
+		email := ""
+		if where := c.Query("where"); where != "" {
+			var err error
+			if email, err = parseEmailPredicate(where); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		limit, offset := parseLimitOffset(c.Query("limit"), c.Query("offset"))
+		customers, total, err := deps.CustomerSvc.ListPage(c.Request.Context(), project.ID, email, limit, offset)
+		if err != nil {
+			logger.Printf("customers list error project_id=%s error=%v", project.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "list customers failed"})
+			return
+		}
+		c.JSON(http.StatusOK, buildCustomerList(customers, total, limit, offset))
+	})
+	admin.GET("/customers/:id", func(c *gin.Context) {
+		project := mustProject(c)
+		id := c.Param("id")
+		customer, err := deps.CustomerSvc.Get(c.Request.Context(), project.ID, id)
+		if err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "customer not found"})
+				return
+			}
+			logger.Printf("customer get error project_id=%s id=%s error=%v", project.ID, id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "get customer failed"})
+			return
+		}
+		c.JSON(http.StatusOK, toCTCustomer(*customer))
+	})
+	admin.POST("/customers", func(c *gin.Context) {
+		project := mustProject(c)
+		var req customersvc.CustomerDraft
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+		customer, err := deps.CustomerSvc.Create(c.Request.Context(), project.ID, req)
+		if err != nil {
+			logger.Printf("customer create error project_id=%s error=%v", project.ID, err)
+			if invalidFields(c, err) {
+				return
+			}
+			if errors.Is(err, domain.ErrAlreadyExists) {
+				c.JSON(http.StatusConflict, gin.H{"error": "customer with this email already exists"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, customerResponse{Customer: toCTCustomer(*customer)})
+	})
+	admin.POST("/customers/:id", func(c *gin.Context) {
+		project := mustProject(c)
+		id := c.Param("id")
+		var req customersvc.UpdateInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+		customer, err := deps.CustomerSvc.Update(c.Request.Context(), project.ID, id, req)
+		if err != nil {
+			logger.Printf("customer update error project_id=%s id=%s error=%v", project.ID, id, err)
+			switch {
+			case errors.Is(err, domain.ErrNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "customer not found"})
+			case errors.Is(err, domain.ErrConcurrentModification), errors.Is(err, domain.ErrAlreadyExists):
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			default:
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			}
+			return
+		}
+		c.JSON(http.StatusOK, toCTCustomer(*customer))
+	})
+	admin.POST("/customers/password-token", func(c *gin.Context) {
+		project := mustProject(c)
+		var req passwordTokenRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+		token, err := deps.CustomerSvc.CreatePasswordToken(c.Request.Context(), project.ID, req.Email, tokenOptions(c, project, req.TTLMinutes))
+		if err != nil {
+			logger.Printf("password token error project_id=%s error=%v", project.ID, err)
+			switch {
+			case errors.Is(err, domain.ErrNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "customer not found"})
+			case errors.Is(err, customersvc.ErrMailFailed):
+				c.JSON(http.StatusInternalServerError, gin.H{"error": customersvc.ErrMailFailed.Error()})
+			default:
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			}
+			return
+		}
+		c.JSON(http.StatusOK, toCTCustomerToken(*token))
+	})
+	admin.POST("/customers/email-token", func(c *gin.Context) {
+		project := mustProject(c)
+		var req emailTokenRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+		token, err := deps.CustomerSvc.CreateEmailToken(c.Request.Context(), project.ID, req.ID, req.Version, tokenOptions(c, project, req.TTLMinutes))
+		if err != nil {
+			logger.Printf("email token error project_id=%s customer_id=%s error=%v", project.ID, req.ID, err)
+			switch {
+			case errors.Is(err, domain.ErrNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "customer not found"})
+			case errors.Is(err, domain.ErrConcurrentModification):
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			case errors.Is(err, customersvc.ErrMailFailed):
+				c.JSON(http.StatusInternalServerError, gin.H{"error": customersvc.ErrMailFailed.Error()})
+			default:
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			}
+			return
+		}
+		c.JSON(http.StatusOK, toCTCustomerToken(*token))
+	})
+	admin.POST("/customers/email/confirm", func(c *gin.Context) {
+		project := mustProject(c)
+		var req customersvc.ConfirmEmailInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+		confirmEmail(c, logger, deps.CustomerSvc, project, "", req)
+	})
+	admin.POST("/customers/password/reset", resetPassword(logger, deps.CustomerSvc))
+	admin.DELETE("/customers/:id", func(c *gin.Context) {
+		project := mustProject(c)
+		id := c.Param("id")
+		version, err := strconv.Atoi(c.Query("version"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "version query parameter required"})
+			return
+		}
+		var customer *domain.Customer
+		switch {
+		case c.Query("dataErasure") != "true":
+			customer, err = deps.CustomerSvc.Delete(c.Request.Context(), project.ID, id, version)
+		case deps.PrivacySvc != nil:
+			customer, err = deps.PrivacySvc.Erase(c.Request.Context(), project.ID, id, version, domain.ErasureByAdmin)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "dataErasure is not supported"})
+			return
+		}
+		if err != nil {
+			logger.Printf("customer delete error project_id=%s id=%s error=%v", project.ID, id, err)
+			switch {
+			case errors.Is(err, domain.ErrNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "customer not found"})
+			case errors.Is(err, domain.ErrConcurrentModification):
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			default:
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			}
+			return
+		}
+		c.JSON(http.StatusOK, toCTCustomer(*customer))
+	})
+}
+
+// resetPassword resets a password with the token of a password-token request.
+func resetPassword(logger *log.Logger, svc customerService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		project := mustProject(c)
+		var req customersvc.ResetPasswordInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+		customer, err := svc.ResetPassword(c.Request.Context(), project.ID, req)
+		if err != nil {
+			logger.Printf("password reset error project_id=%s error=%v", project.ID, err)
+			if invalidFields(c, err) {
+				return
+			}
+			switch {
+			case errors.Is(err, customersvc.ErrInvalidToken):
+				c.JSON(http.StatusNotFound, gin.H{"error": "password token not found or expired"})
+			case errors.Is(err, domain.ErrConcurrentModification):
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			default:
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			}
+			return
+		}
+		c.JSON(http.StatusOK, toCTCustomer(*customer))
+	}
+}
@@ -0,0 +1,40 @@
+package httpserver
+
+import (
+	"context"
+	"log"
+
+	"commercetools-replica/internal/domain"
+	discountcodesvc "commercetools-replica/internal/service/discountcode"
+
+	"github.com/gin-gonic/gin"
+)
+
+type discountCodeService interface {
+	ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.DiscountCode, int, error)
+	Get(ctx context.Context, projectID, id string) (*domain.DiscountCode, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.DiscountCode, error)
+	Create(ctx context.Context, projectID string, draft discountcodesvc.DiscountCodeDraft) (*domain.DiscountCode, error)
+	Update(ctx context.Context, projectID, id string, in discountcodesvc.UpdateInput) (*domain.DiscountCode, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.DiscountCode, error)
+}
+
+// registerDiscountCodeRoutes registers the /discount-codes routes.
+func registerDiscountCodeRoutes(read, write *gin.RouterGroup, logger *log.Logger, svc discountCodeService) {
+	routes := crudRoutes[domain.DiscountCode, discountcodesvc.DiscountCodeDraft, discountcodesvc.UpdateInput]{
+		path:       "/discount-codes",
+		singular:   "discount code",
+		plural:     "discount codes",
+		svc:        svc,
+		id:         func(d *domain.DiscountCode) string { return d.ID },
+		draftField: func(d discountcodesvc.DiscountCodeDraft) string { return "code=" + d.Code },
+		conflict:   "discount code with this code or key already exists",
+		render: func(c *gin.Context, d domain.DiscountCode) any {
+			return toCTDiscountCode(d, localeFromRequest(c))
+		},
+		list: func(c *gin.Context, items []domain.DiscountCode, total, limit, offset int) any {
+			return buildDiscountCodeList(items, total, limit, offset, localeFromRequest(c))
+		},
+	}
+	routes.register(read, write, logger)
+}
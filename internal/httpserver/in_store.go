@@ -0,0 +1,99 @@
+package httpserver
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"commercetools-replica/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+const storeCtxKey ctxKey = "store"
+
+// storeMiddleware loads the store named by the storeKey path parameter of the
+// in-store routes.
+func storeMiddleware(logger *log.Logger, svc storeService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		project := mustProject(c)
+		key := c.Param("storeKey")
+		st, err := svc.GetByKey(c.Request.Context(), project.ID, key)
+		if err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "store not found"})
+				c.Abort()
+				return
+			}
+			logger.Printf("store lookup error project_id=%s store_key=%s error=%v", project.ID, key, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "store lookup failed"})
+			c.Abort()
+			return
+		}
+		c.Set(string(storeCtxKey), st)
+		c.Next()
+	}
+}
+
+// currentStore returns the store of an in-store route, or nil elsewhere.
+func currentStore(c *gin.Context) *domain.Store {
+	v, ok := c.Get(string(storeCtxKey))
+	if !ok {
+		return nil
+	}
+	st, _ := v.(*domain.Store)
+	return st
+}
+
+// storeKey returns the key of the current store, or "" outside the in-store routes.
+func storeKey(st *domain.Store) string {
+	if st == nil {
+		return ""
+	}
+	return st.Key
+}
+
+// storeLocales narrows loc to the languages of the store. Requested locales
+// the store serves keep their order; without any, every store language is used.
+func storeLocales(loc localeSelector, st *domain.Store) localeSelector {
+	if st == nil || len(st.Languages) == 0 {
+		return loc
+	}
+	languages := localeSelector{locales: st.Languages}
+	var kept []string
+	for _, locale := range loc.locales {
+		if languages.matches(locale) {
+			kept = append(kept, locale)
+		}
+	}
+	if len(kept) == 0 {
+		kept = st.Languages
+	}
+	return localeSelector{locales: kept, strict: true}
+}
+
+// restrictAvailability drops the channel availability of channels that do not
+// supply the store.
+func restrictAvailability(products []domain.Product, st *domain.Store) {
+	if st == nil {
+		return
+	}
+	restrict := func(v *domain.ProductVariant) {
+		if v.Availability == nil {
+			return
+		}
+		for id := range v.Availability.Channels {
+			if !st.HasSupplyChannel(id) {
+				delete(v.Availability.Channels, id)
+			}
+		}
+	}
+	for i := range products {
+		for _, data := range []*domain.ProductData{&products[i].Current, &products[i].Staged} {
+			restrict(&data.MasterVariant)
+			for j := range data.Variants {
+				restrict(&data.Variants[j])
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package httpserver
+
+import (
+	"context"
+	"log"
+
+	"commercetools-replica/internal/domain"
+	inventorysvc "commercetools-replica/internal/service/inventory"
+
+	"github.com/gin-gonic/gin"
+)
+
+type inventoryService interface {
+	ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.InventoryEntry, int, error)
+	Get(ctx context.Context, projectID, id string) (*domain.InventoryEntry, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.InventoryEntry, error)
+	Create(ctx context.Context, projectID string, draft inventorysvc.InventoryEntryDraft) (*domain.InventoryEntry, error)
+	Update(ctx context.Context, projectID, id string, in inventorysvc.UpdateInput) (*domain.InventoryEntry, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.InventoryEntry, error)
+	ApplyToProducts(ctx context.Context, projectID string, products []domain.Product) error
+}
+
+// registerInventoryRoutes registers the /inventory routes.
+func registerInventoryRoutes(read, write *gin.RouterGroup, logger *log.Logger, svc inventoryService) {
+	routes := crudRoutes[domain.InventoryEntry, inventorysvc.InventoryEntryDraft, inventorysvc.UpdateInput]{
+		path:       "/inventory",
+		singular:   "inventory entry",
+		plural:     "inventory",
+		svc:        svc,
+		id:         func(i *domain.InventoryEntry) string { return i.ID },
+		draftField: func(d inventorysvc.InventoryEntryDraft) string { return "sku=" + d.SKU },
+		conflict:   "inventory entry with this key or sku and supply channel already exists",
+		render:     func(_ *gin.Context, i domain.InventoryEntry) any { return toCTInventoryEntry(i) },
+		list: func(_ *gin.Context, items []domain.InventoryEntry, total, limit, offset int) any {
+			return buildInventoryEntryList(items, total, limit, offset)
+		},
+	}
+	routes.register(read, write, logger)
+}
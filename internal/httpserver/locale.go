@@ -0,0 +1,120 @@
+package httpserver
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"commercetools-replica/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// localeSelector decides which locales of a LocalizedString end up in a response.
+// An explicit localeProjection is strict; Accept-Language is best effort and falls
+// back to every locale when none of the preferred ones is present.
+type localeSelector struct {
+	locales []string
+	strict  bool
+}
+
+func localeFromRequest(c *gin.Context) localeSelector {
+	var projected []string
+	for _, raw := range c.QueryArray("localeProjection") {
+		for _, part := range strings.Split(raw, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				projected = append(projected, part)
+			}
+		}
+	}
+	if len(projected) > 0 {
+		return localeSelector{locales: projected, strict: true}
+	}
+	return localeSelector{locales: parseAcceptLanguage(c.GetHeader("Accept-Language"))}
+}
+
+// primary returns the preferred locale, or DefaultLocale when nothing was requested.
+func (l localeSelector) primary() string {
+	if len(l.locales) == 0 {
+		return domain.DefaultLocale
+	}
+	return l.locales[0]
+}
+
+func (l localeSelector) matches(locale string) bool {
+	for _, want := range l.locales {
+		if strings.EqualFold(want, locale) || domain.LocaleLanguage(want) == domain.LocaleLanguage(locale) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l localeSelector) project(v domain.LocalizedString) map[string]string {
+	out := map[string]string{}
+	for locale, value := range v {
+		if len(l.locales) == 0 || l.matches(locale) {
+			out[locale] = value
+		}
+	}
+	if len(out) == 0 && !l.strict {
+		for locale, value := range v {
+			out[locale] = value
+		}
+	}
+	return out
+}
+
+func (l localeSelector) projectKeywords(v domain.LocalizedKeywords) map[string][]ctSearchKeyword {
+	out := map[string][]ctSearchKeyword{}
+	add := func(locale string, words []string) {
+		for _, w := range words {
+			out[locale] = append(out[locale], ctSearchKeyword{Text: w})
+		}
+	}
+	for locale, words := range v {
+		if len(l.locales) == 0 || l.matches(locale) {
+			add(locale, words)
+		}
+	}
+	if len(out) == 0 && !l.strict {
+		for locale, words := range v {
+			add(locale, words)
+		}
+	}
+	return out
+}
+
+// parseAcceptLanguage returns the locales of an Accept-Language header ordered by quality.
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+	var items []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		locale := strings.TrimSpace(fields[0])
+		if locale == "" || locale == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		items = append(items, weighted{locale: locale, q: q})
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].q > items[j].q })
+	out := make([]string, 0, len(items))
+	for _, it := range items {
+		out = append(out, it.locale)
+	}
+	return out
+}
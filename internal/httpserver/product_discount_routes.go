@@ -0,0 +1,41 @@
+package httpserver
+
+import (
+	"context"
+	"log"
+
+	"commercetools-replica/internal/domain"
+	productdiscountsvc "commercetools-replica/internal/service/productdiscount"
+
+	"github.com/gin-gonic/gin"
+)
+
+type productDiscountService interface {
+	ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.ProductDiscount, int, error)
+	Get(ctx context.Context, projectID, id string) (*domain.ProductDiscount, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.ProductDiscount, error)
+	Create(ctx context.Context, projectID string, draft productdiscountsvc.ProductDiscountDraft) (*domain.ProductDiscount, error)
+	Update(ctx context.Context, projectID, id string, in productdiscountsvc.UpdateInput) (*domain.ProductDiscount, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.ProductDiscount, error)
+	ApplyToProducts(ctx context.Context, projectID string, products []domain.Product) error
+}
+
+// registerProductDiscountRoutes registers the /product-discounts routes.
+func registerProductDiscountRoutes(read, write *gin.RouterGroup, logger *log.Logger, svc productDiscountService) {
+	routes := crudRoutes[domain.ProductDiscount, productdiscountsvc.ProductDiscountDraft, productdiscountsvc.UpdateInput]{
+		path:       "/product-discounts",
+		singular:   "product discount",
+		plural:     "product discounts",
+		svc:        svc,
+		id:         func(p *domain.ProductDiscount) string { return p.ID },
+		draftField: func(d productdiscountsvc.ProductDiscountDraft) string { return "key=" + d.Key },
+		conflict:   "product discount with this key or sortOrder already exists",
+		render: func(c *gin.Context, p domain.ProductDiscount) any {
+			return toCTProductDiscount(p, localeFromRequest(c))
+		},
+		list: func(c *gin.Context, items []domain.ProductDiscount, total, limit, offset int) any {
+			return buildProductDiscountList(items, total, limit, offset, localeFromRequest(c))
+		},
+	}
+	routes.register(read, write, logger)
+}
@@ -0,0 +1,68 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"commercetools-replica/internal/domain"
+	productselectionsvc "commercetools-replica/internal/service/productselection"
+
+	"github.com/gin-gonic/gin"
+)
+
+type productSelectionService interface {
+	ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.ProductSelection, int, error)
+	Get(ctx context.Context, projectID, id string) (*domain.ProductSelection, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.ProductSelection, error)
+	ListProducts(ctx context.Context, projectID, id string, limit, offset int) ([]string, int, error)
+	Create(ctx context.Context, projectID string, draft productselectionsvc.ProductSelectionDraft) (*domain.ProductSelection, error)
+	Update(ctx context.Context, projectID, id string, in productselectionsvc.UpdateInput) (*domain.ProductSelection, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.ProductSelection, error)
+}
+
+// registerProductSelectionRoutes registers the /product-selections routes and
+// the listing of the product ids in a selection.
+func registerProductSelectionRoutes(read, write *gin.RouterGroup, logger *log.Logger, svc productSelectionService) {
+	routes := crudRoutes[domain.ProductSelection, productselectionsvc.ProductSelectionDraft, productselectionsvc.UpdateInput]{
+		path:       "/product-selections",
+		singular:   "product selection",
+		plural:     "product selections",
+		svc:        svc,
+		id:         func(p *domain.ProductSelection) string { return p.ID },
+		draftField: func(d productselectionsvc.ProductSelectionDraft) string { return "key=" + d.Key },
+		conflict:   "product selection with this key already exists",
+		render: func(c *gin.Context, p domain.ProductSelection) any {
+			return toCTProductSelection(p, localeFromRequest(c))
+		},
+		list: func(c *gin.Context, items []domain.ProductSelection, total, limit, offset int) any {
+			return buildProductSelectionList(items, total, limit, offset, localeFromRequest(c))
+		},
+	}
+	routes.register(read, write, logger)
+	if read == nil {
+		return
+	}
+	read.GET("/product-selections/:id/products", func(c *gin.Context) {
+		project := mustProject(c)
+		id := c.Param("id")
+		limit, offset := parseLimitOffset(c.Query("limit"), c.Query("offset"))
+		sel, err := routes.lookup(c, project.ID, id)
+		var ids []string
+		var total int
+		if err == nil {
+			ids, total, err = svc.ListProducts(c.Request.Context(), project.ID, sel.ID, limit, offset)
+		}
+		if err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "product selection not found"})
+				return
+			}
+			logger.Printf("product selection products error project_id=%s id=%s error=%v", project.ID, id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "list product selection products failed"})
+			return
+		}
+		c.JSON(http.StatusOK, buildProductSelectionProductList(ids, total, limit, offset))
+	})
+}
@@ -0,0 +1,73 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"commercetools-replica/internal/domain"
+	producttypesvc "commercetools-replica/internal/service/producttype"
+
+	"github.com/gin-gonic/gin"
+)
+
+type productTypeService interface {
+	ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.ProductType, int, error)
+	Get(ctx context.Context, projectID, id string) (*domain.ProductType, error)
+	Create(ctx context.Context, projectID string, draft producttypesvc.ProductTypeDraft) (*domain.ProductType, error)
+}
+
+// registerProductTypeRoutes registers the /product-types routes. Product types
+// have no key, update or delete yet, so they do not use crudRoutes; a nil
+// write group leaves the create route out.
+func registerProductTypeRoutes(read, write *gin.RouterGroup, logger *log.Logger, svc productTypeService) {
+	read.GET("/product-types", func(c *gin.Context) {
+		project := mustProject(c)
+		limit, offset := parseLimitOffset(c.Query("limit"), c.Query("offset"))
+		types, total, err := svc.ListPage(c.Request.Context(), project.ID, limit, offset)
+		if err != nil {
+			logger.Printf("product types list error project_id=%s error=%v", project.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "list product types failed"})
+			return
+		}
+		c.JSON(http.StatusOK, buildProductTypeList(types, total, limit, offset, localeFromRequest(c)))
+	})
+	read.GET("/product-types/:id", func(c *gin.Context) {
+		project := mustProject(c)
+		id := c.Param("id")
+		t, err := svc.Get(c.Request.Context(), project.ID, id)
+		if err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "product type not found"})
+				return
+			}
+			logger.Printf("product type get error project_id=%s id=%s error=%v", project.ID, id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "get product type failed"})
+			return
+		}
+		c.JSON(http.StatusOK, toCTProductType(*t, localeFromRequest(c)))
+	})
+	if write == nil {
+		return
+	}
+	write.POST("/product-types", func(c *gin.Context) {
+		project := mustProject(c)
+		var req producttypesvc.ProductTypeDraft
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+		t, err := svc.Create(c.Request.Context(), project.ID, req)
+		if err != nil {
+			logger.Printf("product type create error project_id=%s key=%s error=%v", project.ID, req.Key, err)
+			if errors.Is(err, domain.ErrAlreadyExists) {
+				c.JSON(http.StatusConflict, gin.H{"error": "product type already exists"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, toCTProductType(*t, localeFromRequest(c)))
+	})
+}
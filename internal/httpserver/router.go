@@ -3,16 +3,22 @@ package httpserver
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"commercetools-replica/internal/domain"
 	projectrepo "commercetools-replica/internal/repository/project"
+	adminsvc "commercetools-replica/internal/service/admin"
 	anonymoussvc "commercetools-replica/internal/service/anonymous"
 	cartsvc "commercetools-replica/internal/service/cart"
 	customersvc "commercetools-replica/internal/service/customer"
+	ordersvc "commercetools-replica/internal/service/order"
+	privacysvc "commercetools-replica/internal/service/privacy"
+	productsvc "commercetools-replica/internal/service/product"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -22,6 +28,20 @@ import (
 type productService interface {
 	List(ctx context.Context, projectID string) ([]domain.Product, error)
 	Get(ctx context.Context, projectID, id string) (*domain.Product, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.Product, error)
+	GetBySlug(ctx context.Context, projectID, locale, slug string, staged bool) (*domain.Product, error)
+	Create(ctx context.Context, projectID string, draft productsvc.ProductDraft) (*domain.Product, error)
+	Update(ctx context.Context, projectID, id string, in productsvc.UpdateInput) (*domain.Product, error)
+}
+
+// orderService takes an empty storeKey outside of the in-store routes.
+type orderService interface {
+	Create(ctx context.Context, projectID, customerID, storeKey string, draft ordersvc.OrderFromCartDraft) (*domain.Order, error)
+	CreateAnonymous(ctx context.Context, projectID, anonymousID, storeKey string, draft ordersvc.OrderFromCartDraft) (*domain.Order, error)
+	Get(ctx context.Context, projectID, customerID, storeKey, id string) (*domain.Order, error)
+	GetAnonymous(ctx context.Context, projectID, anonymousID, storeKey, id string) (*domain.Order, error)
+	ListPage(ctx context.Context, projectID, customerID, storeKey string, limit, offset int) ([]domain.Order, int, error)
+	ListPageAnonymous(ctx context.Context, projectID, anonymousID, storeKey string, limit, offset int) ([]domain.Order, int, error)
 }
 
 type cartService interface {
@@ -30,24 +50,53 @@ type cartService interface {
 	GetActive(ctx context.Context, projectID, customerID string) (*domain.Cart, error)
 	Update(ctx context.Context, projectID, customerID, cartID string, in cartsvc.UpdateInput) (*domain.Cart, error)
 	GetActiveAnonymous(ctx context.Context, projectID, anonymousID string) (*domain.Cart, error)
+	GetActiveInStore(ctx context.Context, projectID, storeKey, customerID string) (*domain.Cart, error)
+	GetActiveAnonymousInStore(ctx context.Context, projectID, storeKey, anonymousID string) (*domain.Cart, error)
 	UpdateAnonymous(ctx context.Context, projectID, anonymousID, cartID string, in cartsvc.UpdateInput) (*domain.Cart, error)
 	AssignCustomerFromAnonymous(ctx context.Context, projectID, anonymousID, customerID string) (*domain.Cart, error)
 	Delete(ctx context.Context, projectID, customerID, cartID string) (*domain.Cart, error)
 	DeleteAnonymous(ctx context.Context, projectID, anonymousID, cartID string) (*domain.Cart, error)
+	MatchingShippingMethods(ctx context.Context, projectID, cartID string) ([]domain.ShippingMethod, error)
 }
 
 type categoryService interface {
 	List(ctx context.Context, projectID string) ([]domain.Category, error)
+	ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.Category, int, error)
+	Get(ctx context.Context, projectID, id string) (*domain.Category, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.Category, error)
+	GetBySlug(ctx context.Context, projectID, locale, slug string) (*domain.Category, error)
 	Upsert(ctx context.Context, c domain.Category) (*domain.Category, error)
 }
 
 type customerService interface {
 	Signup(ctx context.Context, projectID string, in customersvc.SignupInput) (*domain.Customer, error)
-	Login(ctx context.Context, projectID, email, password string) (*domain.Customer, string, string, error)
+	Login(ctx context.Context, projectID, email, password, clientIP string, requireVerifiedEmail bool) (*domain.Customer, string, string, error)
 	LookupByToken(ctx context.Context, projectID, token string) (*domain.Customer, error)
+	Update(ctx context.Context, projectID, id string, in customersvc.UpdateInput) (*domain.Customer, error)
+	UpdateMe(ctx context.Context, projectID, id string, in customersvc.UpdateInput) (*domain.Customer, error)
+	ChangePassword(ctx context.Context, projectID, customerID, keepToken string, in customersvc.ChangePasswordInput) (*domain.Customer, error)
+	CreatePasswordToken(ctx context.Context, projectID, email string, opts customersvc.TokenOptions) (*customersvc.CustomerToken, error)
+	ResetPassword(ctx context.Context, projectID string, in customersvc.ResetPasswordInput) (*domain.Customer, error)
+	CreateEmailToken(ctx context.Context, projectID, customerID string, version int, opts customersvc.TokenOptions) (*customersvc.CustomerToken, error)
+	ConfirmEmail(ctx context.Context, projectID, customerID string, in customersvc.ConfirmEmailInput) (*domain.Customer, error)
+	ListPage(ctx context.Context, projectID, email string, limit, offset int) ([]domain.Customer, int, error)
+	Get(ctx context.Context, projectID, id string) (*domain.Customer, error)
+	Create(ctx context.Context, projectID string, draft customersvc.CustomerDraft) (*domain.Customer, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.Customer, error)
 	AccessTTLSeconds() int
 }
 
+type adminService interface {
+	Issue(ctx context.Context, projectID, clientID, clientSecret string) (string, error)
+	Authorize(ctx context.Context, projectID, token string) error
+	AccessTTLSeconds() int
+}
+
+type privacyService interface {
+	Export(ctx context.Context, projectID, customerID string) (*privacysvc.Export, error)
+	Erase(ctx context.Context, projectID, customerID string, version int, requestedBy string) (*domain.Customer, error)
+}
+
 type anonymousService interface {
 	Issue(ctx context.Context, projectID string) (string, string, string, error)
 	LookupByToken(ctx context.Context, projectID, token string) (string, error)
@@ -61,6 +110,49 @@ type Deps struct {
 	CategorySvc  categoryService
 	CustomerSvc  customerService
 	AnonymousSvc anonymousService
+	// AdminSvc is optional; it issues admin tokens on /oauth/token and
+	// registers the routes that require one, such as /customers and the
+	// product writes. Without it those routes are left out.
+	AdminSvc adminService
+	// PrivacySvc is optional; it registers GET /me/export and DELETE /me and
+	// enables dataErasure on the admin customer delete.
+	PrivacySvc privacyService
+	// ProductTypeSvc is optional; the product-types routes are only registered when set.
+	ProductTypeSvc productTypeService
+	// ProductDiscountSvc is optional; without it no product-discounts routes are
+	// registered and prices are returned without discounts.
+	ProductDiscountSvc productDiscountService
+	// CartDiscountSvc and DiscountCodeSvc are optional and register the
+	// cart-discounts and discount-codes routes.
+	CartDiscountSvc cartDiscountService
+	DiscountCodeSvc discountCodeService
+	// TaxCategorySvc is optional and registers the tax-categories routes.
+	TaxCategorySvc taxCategoryService
+	// CustomerGroupSvc is optional and registers the customer-groups routes.
+	CustomerGroupSvc customerGroupService
+	// ZoneSvc and ShippingMethodSvc are optional and register the zones and
+	// shipping-methods routes.
+	ZoneSvc           zoneService
+	ShippingMethodSvc shippingMethodService
+	// InventorySvc is optional; it registers the inventory routes and sets the
+	// availability of product variants.
+	InventorySvc inventoryService
+	// OrderSvc is optional and registers the me/orders routes.
+	OrderSvc orderService
+	// ChannelSvc, ProductSelectionSvc and StoreSvc are optional and register
+	// their routes; StoreSvc also registers the in-store routes.
+	ChannelSvc          channelService
+	ProductSelectionSvc productSelectionService
+	StoreSvc            storeService
+	// BusinessUnitSvc and AssociateRoleSvc are optional. With AdminSvc they
+	// register the business-units and associate-roles routes, which take admin
+	// tokens; BusinessUnitSvc also registers the as-associate cart routes.
+	BusinessUnitSvc  businessUnitService
+	AssociateRoleSvc associateRoleService
+	// TrustedProxies are the IPs or CIDRs of reverse proxies whose
+	// X-Forwarded-For is believed. Without any, the client IP used by the
+	// login throttle is the peer address.
+	TrustedProxies []string
 }
 
 func buildRouter(logger *log.Logger, db *pgxpool.Pool, deps Deps, fileURLHost string) (*gin.Engine, error) {
@@ -84,6 +176,9 @@ func buildRouter(logger *log.Logger, db *pgxpool.Pool, deps Deps, fileURLHost st
 	}
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
+	if err := router.SetTrustedProxies(deps.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("trusted proxies: %w", err)
+	}
 	router.Use(gin.Recovery())
 	router.Use(cors.New(cors.Config{
 		AllowOriginFunc: func(origin string) bool {
@@ -101,7 +196,406 @@ func buildRouter(logger *log.Logger, db *pgxpool.Pool, deps Deps, fileURLHost st
 	router.GET("/healthz", healthHandler)
 	router.GET("/readyz", readyHandler(db))
 
+	// prepareProducts sets the discounted prices and the variant availability on
+	// products before they are rendered; it writes the error response itself and
+	// reports whether to go on.
+	prepareProducts := func(c *gin.Context, projectID string, products []domain.Product) bool {
+		if deps.ProductDiscountSvc != nil {
+			if err := deps.ProductDiscountSvc.ApplyToProducts(c.Request.Context(), projectID, products); err != nil {
+				logger.Printf("product discounts apply error project_id=%s error=%v", projectID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "apply product discounts failed"})
+				return false
+			}
+		}
+		if deps.InventorySvc != nil {
+			if err := deps.InventorySvc.ApplyToProducts(c.Request.Context(), projectID, products); err != nil {
+				logger.Printf("inventory apply error project_id=%s error=%v", projectID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "apply inventory failed"})
+				return false
+			}
+		}
+		// Price selection picks the price of the customer group, falling back
+		// to the price without a group.
+		if currency := strings.ToUpper(strings.TrimSpace(c.Query("priceCurrency"))); currency != "" {
+			for i := range products {
+				products[i].SelectPrices(currency, strings.TrimSpace(c.Query("priceCustomerGroup")))
+			}
+		}
+		return true
+	}
+
+	// checkMyCart hides carts of other stores from the in-store routes and
+	// refuses carts of a business unit, which only the as-associate routes
+	// change.
+	checkMyCart := func(c *gin.Context, projectID, id string) error {
+		cart, err := deps.CartSvc.Get(c.Request.Context(), projectID, id)
+		if err != nil {
+			return err
+		}
+		if st := currentStore(c); st != nil && cart.StoreKey != st.Key {
+			return domain.ErrNotFound
+		}
+		if cart.BusinessUnitKey != "" {
+			return errBusinessUnitCart
+		}
+		return nil
+	}
+
+	// inAssortment keeps the products the current store sells; outside the
+	// in-store routes, or for stores without product selections, it keeps all.
+	inAssortment := func(c *gin.Context, projectID string, products []domain.Product) ([]domain.Product, error) {
+		st := currentStore(c)
+		if st == nil || deps.StoreSvc == nil {
+			return products, nil
+		}
+		ids, err := deps.StoreSvc.ProductIDs(c.Request.Context(), projectID, st.ID)
+		if err != nil || ids == nil {
+			return products, err
+		}
+		kept := products[:0:0]
+		for _, p := range products {
+			if ids[p.ID] {
+				kept = append(kept, p)
+			}
+		}
+		return kept, nil
+	}
+
+	// The me/carts, me/orders and product-projections handlers below also serve
+	// the in-store routes, where currentStore returns the store of the path.
+	createMyCart := func(c *gin.Context) {
+		project := mustProject(c)
+		actor, ok := authorizeActor(c, project, deps.CustomerSvc, deps.AnonymousSvc)
+		if !ok {
+			return
+		}
+		var req cartsvc.CreateInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+		if req.BusinessUnit != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errBusinessUnitCart.Error()})
+			return
+		}
+		if actor.Customer != nil {
+			req.CustomerID = &actor.Customer.ID
+		} else if actor.AnonymousID != "" {
+			req.AnonymousID = &actor.AnonymousID
+		}
+		if st := currentStore(c); st != nil {
+			req.Store = &cartsvc.StoreReference{TypeID: "store", Key: st.Key}
+		}
+		cart, err := deps.CartSvc.Create(c.Request.Context(), project.ID, req)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, toCTCart(*cart, actor.Customer, fileURLHost, localeFromRequest(c)))
+	}
+
+	updateMyCart := func(c *gin.Context) {
+		project := mustProject(c)
+		actor, ok := authorizeActor(c, project, deps.CustomerSvc, deps.AnonymousSvc)
+		if !ok {
+			return
+		}
+		id := c.Param("id")
+		var req cartsvc.UpdateInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+		var cart *domain.Cart
+		err := checkMyCart(c, project.ID, id)
+		if err == nil {
+			if actor.Customer != nil {
+				cart, err = deps.CartSvc.Update(c.Request.Context(), project.ID, actor.Customer.ID, id, req)
+			} else {
+				cart, err = deps.CartSvc.UpdateAnonymous(c.Request.Context(), project.ID, actor.AnonymousID, id, req)
+			}
+		}
+		if err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "cart not found"})
+				return
+			}
+			if errors.Is(err, errBusinessUnitCart) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			logger.Printf("cart update error project_id=%s cart_id=%s error=%v", project.ID, id, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, toCTCart(*cart, actor.Customer, fileURLHost, localeFromRequest(c)))
+	}
+
+	deleteMyCart := func(c *gin.Context) {
+		project := mustProject(c)
+		actor, ok := authorizeActor(c, project, deps.CustomerSvc, deps.AnonymousSvc)
+		if !ok {
+			return
+		}
+		id := c.Param("id")
+		var cart *domain.Cart
+		err := checkMyCart(c, project.ID, id)
+		if err == nil {
+			if actor.Customer != nil {
+				cart, err = deps.CartSvc.Delete(c.Request.Context(), project.ID, actor.Customer.ID, id)
+			} else {
+				cart, err = deps.CartSvc.DeleteAnonymous(c.Request.Context(), project.ID, actor.AnonymousID, id)
+			}
+		}
+		if err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "cart not found"})
+				return
+			}
+			if errors.Is(err, errBusinessUnitCart) {
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
+			logger.Printf("cart delete error project_id=%s cart_id=%s error=%v", project.ID, id, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, toCTCart(*cart, actor.Customer, fileURLHost, localeFromRequest(c)))
+	}
+
+	getMyActiveCart := func(c *gin.Context) {
+		project := mustProject(c)
+		actor, ok := authorizeActor(c, project, deps.CustomerSvc, deps.AnonymousSvc)
+		if !ok {
+			return
+		}
+		var cart *domain.Cart
+		var err error
+		st := currentStore(c)
+		switch {
+		case st != nil && actor.Customer != nil:
+			cart, err = deps.CartSvc.GetActiveInStore(c.Request.Context(), project.ID, st.Key, actor.Customer.ID)
+		case st != nil:
+			cart, err = deps.CartSvc.GetActiveAnonymousInStore(c.Request.Context(), project.ID, st.Key, actor.AnonymousID)
+		case actor.Customer != nil:
+			cart, err = deps.CartSvc.GetActive(c.Request.Context(), project.ID, actor.Customer.ID)
+		default:
+			cart, err = deps.CartSvc.GetActiveAnonymous(c.Request.Context(), project.ID, actor.AnonymousID)
+		}
+		if err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "cart not found"})
+				return
+			}
+			logger.Printf("active cart error project_id=%s error=%v", project.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "get active cart failed"})
+			return
+		}
+		c.JSON(http.StatusOK, toCTCart(*cart, actor.Customer, fileURLHost, localeFromRequest(c)))
+	}
+
+	createMyOrder := func(c *gin.Context) {
+		project := mustProject(c)
+		actor, ok := authorizeActor(c, project, deps.CustomerSvc, deps.AnonymousSvc)
+		if !ok {
+			return
+		}
+		var req ordersvc.OrderFromCartDraft
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+		var order *domain.Order
+		var err error
+		key := storeKey(currentStore(c))
+		if actor.Customer != nil {
+			order, err = deps.OrderSvc.Create(c.Request.Context(), project.ID, actor.Customer.ID, key, req)
+		} else {
+			order, err = deps.OrderSvc.CreateAnonymous(c.Request.Context(), project.ID, actor.AnonymousID, key, req)
+		}
+		if err != nil {
+			logger.Printf("order create error project_id=%s cart_id=%s error=%v", project.ID, req.ID, err)
+			var outOfStock *domain.OutOfStockError
+			switch {
+			case errors.Is(err, domain.ErrNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "cart not found"})
+			case errors.As(err, &outOfStock):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "skus": outOfStock.SKUs})
+			case errors.Is(err, domain.ErrAlreadyExists):
+				c.JSON(http.StatusConflict, gin.H{"error": "order with this orderNumber already exists"})
+			case errors.Is(err, domain.ErrConcurrentModification):
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			default:
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			}
+			return
+		}
+		c.JSON(http.StatusCreated, toCTOrder(*order, actor.Customer, fileURLHost, localeFromRequest(c)))
+	}
+
+	listMyOrders := func(c *gin.Context) {
+		project := mustProject(c)
+		actor, ok := authorizeActor(c, project, deps.CustomerSvc, deps.AnonymousSvc)
+		if !ok {
+			return
+		}
+		limit, offset := parseLimitOffset(c.Query("limit"), c.Query("offset"))
+		var orders []domain.Order
+		var total int
+		var err error
+		key := storeKey(currentStore(c))
+		if actor.Customer != nil {
+			orders, total, err = deps.OrderSvc.ListPage(c.Request.Context(), project.ID, actor.Customer.ID, key, limit, offset)
+		} else {
+			orders, total, err = deps.OrderSvc.ListPageAnonymous(c.Request.Context(), project.ID, actor.AnonymousID, key, limit, offset)
+		}
+		if err != nil {
+			logger.Printf("orders list error project_id=%s error=%v", project.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "list orders failed"})
+			return
+		}
+		c.JSON(http.StatusOK, buildOrderList(orders, total, limit, offset, actor.Customer, fileURLHost, localeFromRequest(c)))
+	}
+
+	getMyOrder := func(c *gin.Context) {
+		project := mustProject(c)
+		actor, ok := authorizeActor(c, project, deps.CustomerSvc, deps.AnonymousSvc)
+		if !ok {
+			return
+		}
+		id := c.Param("id")
+		var order *domain.Order
+		var err error
+		key := storeKey(currentStore(c))
+		if actor.Customer != nil {
+			order, err = deps.OrderSvc.Get(c.Request.Context(), project.ID, actor.Customer.ID, key, id)
+		} else {
+			order, err = deps.OrderSvc.GetAnonymous(c.Request.Context(), project.ID, actor.AnonymousID, key, id)
+		}
+		if err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+				return
+			}
+			logger.Printf("order get error project_id=%s id=%s error=%v", project.ID, id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "get order failed"})
+			return
+		}
+		c.JSON(http.StatusOK, toCTOrder(*order, actor.Customer, fileURLHost, localeFromRequest(c)))
+	}
+
+	listProductProjections := func(c *gin.Context) {
+		project := mustProject(c)
+		staged := c.Query("staged") == "true"
+		limit, offset := parseLimitOffset(c.Query("limit"), c.Query("offset"))
+		st := currentStore(c)
+		loc := storeLocales(localeFromRequest(c), st)
+
+		var products []domain.Product
+		if where := c.QueryArray("where"); len(where) > 0 {
+			if len(where) > 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "only one where predicate is supported"})
+				return
+			}
+			pred, err := parseLookupPredicate(where[0])
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			var p *domain.Product
+			switch pred.Field {
+			case "slug":
+				p, err = deps.ProductSvc.GetBySlug(c.Request.Context(), project.ID, pred.Locale, pred.Value, staged)
+			case "key":
+				p, err = deps.ProductSvc.GetByKey(c.Request.Context(), project.ID, pred.Value)
+			default:
+				p, err = deps.ProductSvc.Get(c.Request.Context(), project.ID, pred.Value)
+			}
+			if err != nil && !errors.Is(err, domain.ErrNotFound) {
+				logger.Printf("product projections lookup error project_id=%s where=%q error=%v", project.ID, where[0], err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "query product projections failed"})
+				return
+			}
+			if p != nil {
+				products = []domain.Product{*p}
+			}
+		} else {
+			var err error
+			products, err = deps.ProductSvc.List(c.Request.Context(), project.ID)
+			if err != nil {
+				logger.Printf("product projections list error project_id=%s error=%v", project.ID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "query product projections failed"})
+				return
+			}
+		}
+
+		visible := products[:0:0]
+		for _, p := range products {
+			if staged || p.Published {
+				visible = append(visible, p)
+			}
+		}
+		visible, err := inAssortment(c, project.ID, visible)
+		if err != nil {
+			logger.Printf("product projections assortment error project_id=%s error=%v", project.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "query product projections failed"})
+			return
+		}
+		if !prepareProducts(c, project.ID, visible) {
+			return
+		}
+		restrictAvailability(visible, st)
+		c.JSON(http.StatusOK, buildProductProjectionList(logger, visible, staged, limit, offset, fileURLHost, loc))
+	}
+
+	getProductProjection := func(c *gin.Context) {
+		project := mustProject(c)
+		id := c.Param("id")
+		staged := c.Query("staged") == "true"
+		var (
+			p   *domain.Product
+			err error
+		)
+		if key, ok := keyFromPathParam(id); ok {
+			p, err = deps.ProductSvc.GetByKey(c.Request.Context(), project.ID, key)
+		} else {
+			p, err = deps.ProductSvc.Get(c.Request.Context(), project.ID, id)
+		}
+		if err == nil && !staged && !p.Published {
+			err = domain.ErrNotFound
+		}
+		var products []domain.Product
+		if err == nil {
+			products, err = inAssortment(c, project.ID, []domain.Product{*p})
+		}
+		if err == nil && len(products) == 0 {
+			err = domain.ErrNotFound
+		}
+		if err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "product projection not found"})
+				return
+			}
+			logger.Printf("product projection get error project_id=%s id=%s error=%v", project.ID, id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "get product projection failed"})
+			return
+		}
+		if !prepareProducts(c, project.ID, products) {
+			return
+		}
+		st := currentStore(c)
+		restrictAvailability(products, st)
+		c.JSON(http.StatusOK, toCTProductProjection(logger, products[0], staged, fileURLHost, storeLocales(localeFromRequest(c), st)))
+	}
+
 	registerProjectRoutes := func(group *gin.RouterGroup) {
+		// admin holds the routes that take admin tokens only; customer tokens
+		// are rejected by requireAdmin. Without AdminSvc it is nil and those
+		// routes are left out.
+		var admin *gin.RouterGroup
+		if deps.AdminSvc != nil {
+			admin = group.Group("", requireAdmin(deps.AdminSvc))
+		}
 		group.POST("/me/signup", func(c *gin.Context) {
 			project := mustProject(c)
 
@@ -122,12 +616,14 @@ func buildRouter(logger *log.Logger, db *pgxpool.Pool, deps Deps, fileURLHost st
 			}
 			for _, a := range req.Addresses {
 				in.Addresses = append(in.Addresses, customersvc.AddressInput{
+					Key:        a.Key,
 					FirstName:  a.FirstName,
 					LastName:   a.LastName,
 					Country:    a.Country,
 					StreetName: a.StreetName,
 					PostalCode: a.PostalCode,
 					City:       a.City,
+					State:      a.State,
 					Email:      a.Email,
 					Department: a.Department,
 				})
@@ -136,6 +632,9 @@ func buildRouter(logger *log.Logger, db *pgxpool.Pool, deps Deps, fileURLHost st
 			customer, err := deps.CustomerSvc.Signup(c.Request.Context(), project.ID, in)
 			if err != nil {
 				logger.Printf("customer signup error project_id=%s email=%s error=%v", project.ID, req.Email, err)
+				if invalidFields(c, err) {
+					return
+				}
 				status := http.StatusInternalServerError
 				msg := "signup failed"
 				switch {
@@ -173,6 +672,129 @@ func buildRouter(logger *log.Logger, db *pgxpool.Pool, deps Deps, fileURLHost st
 			}
 			c.JSON(http.StatusOK, toCTCustomer(*customer))
 		})
+		group.POST("/me", func(c *gin.Context) {
+			project := mustProject(c)
+			customer, ok := authorizeCustomer(c, project, deps.CustomerSvc)
+			if !ok {
+				return
+			}
+			var req customersvc.UpdateInput
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+				return
+			}
+			updated, err := deps.CustomerSvc.UpdateMe(c.Request.Context(), project.ID, customer.ID, req)
+			if err != nil {
+				logger.Printf("me update error project_id=%s customer_id=%s error=%v", project.ID, customer.ID, err)
+				switch {
+				case errors.Is(err, domain.ErrNotFound):
+					c.JSON(http.StatusNotFound, gin.H{"error": "customer not found"})
+				case errors.Is(err, domain.ErrConcurrentModification), errors.Is(err, domain.ErrAlreadyExists):
+					c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				default:
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				}
+				return
+			}
+			c.JSON(http.StatusOK, toCTCustomer(*updated))
+		})
+		if deps.PrivacySvc != nil {
+			group.GET("/me/export", func(c *gin.Context) {
+				project := mustProject(c)
+				customer, ok := authorizeCustomer(c, project, deps.CustomerSvc)
+				if !ok {
+					return
+				}
+				export, err := deps.PrivacySvc.Export(c.Request.Context(), project.ID, customer.ID)
+				if err != nil {
+					logger.Printf("me export error project_id=%s customer_id=%s error=%v", project.ID, customer.ID, err)
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "export failed"})
+					return
+				}
+				c.JSON(http.StatusOK, toCTCustomerExport(*export, fileURLHost, localeFromRequest(c)))
+			})
+			group.DELETE("/me", func(c *gin.Context) {
+				project := mustProject(c)
+				customer, ok := authorizeCustomer(c, project, deps.CustomerSvc)
+				if !ok {
+					return
+				}
+				version, err := strconv.Atoi(c.Query("version"))
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "version query parameter required"})
+					return
+				}
+				erased, err := deps.PrivacySvc.Erase(c.Request.Context(), project.ID, customer.ID, version, domain.ErasureByCustomer)
+				if err != nil {
+					logger.Printf("me erase error project_id=%s customer_id=%s error=%v", project.ID, customer.ID, err)
+					switch {
+					case errors.Is(err, domain.ErrNotFound):
+						c.JSON(http.StatusNotFound, gin.H{"error": "customer not found"})
+					case errors.Is(err, domain.ErrConcurrentModification):
+						c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+					default:
+						c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					}
+					return
+				}
+				c.JSON(http.StatusOK, toCTCustomer(*erased))
+			})
+		}
+		group.POST("/me/password", func(c *gin.Context) {
+			project := mustProject(c)
+			customer, ok := authorizeCustomer(c, project, deps.CustomerSvc)
+			if !ok {
+				return
+			}
+			var req customersvc.ChangePasswordInput
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+				return
+			}
+			// The token of this request stays valid; other sessions are revoked.
+			token := extractBearerToken(c.GetHeader("Authorization"))
+			updated, err := deps.CustomerSvc.ChangePassword(c.Request.Context(), project.ID, customer.ID, token, req)
+			if err != nil {
+				logger.Printf("me password error project_id=%s customer_id=%s error=%v", project.ID, customer.ID, err)
+				if invalidFields(c, err) {
+					return
+				}
+				switch {
+				case errors.Is(err, domain.ErrNotFound):
+					c.JSON(http.StatusNotFound, gin.H{"error": "customer not found"})
+				case errors.Is(err, domain.ErrConcurrentModification):
+					c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				default:
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				}
+				return
+			}
+			c.JSON(http.StatusOK, toCTCustomer(*updated))
+		})
+		group.POST("/me/email/confirm", func(c *gin.Context) {
+			project := mustProject(c)
+			customer, ok := authorizeCustomer(c, project, deps.CustomerSvc)
+			if !ok {
+				return
+			}
+			var req customersvc.ConfirmEmailInput
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+				return
+			}
+			confirmEmail(c, logger, deps.CustomerSvc, project, customer.ID, req)
+		})
+		// The reset token is the credential, so no bearer token is needed.
+		group.POST("/me/password/reset", resetPassword(logger, deps.CustomerSvc))
+		if admin != nil {
+			registerCustomerRoutes(admin, logger, deps)
+		}
+		if deps.AssociateRoleSvc != nil {
+			registerAssociateRoleRoutes(admin, admin, logger, deps.AssociateRoleSvc)
+		}
+		if deps.BusinessUnitSvc != nil {
+			registerBusinessUnitRoutes(admin, admin, logger, deps.BusinessUnitSvc)
+		}
 		group.POST("/me/login", func(c *gin.Context) {
 			project := mustProject(c)
 
@@ -182,11 +804,18 @@ func buildRouter(logger *log.Logger, db *pgxpool.Pool, deps Deps, fileURLHost st
 				return
 			}
 
-			customer, _, _, err := deps.CustomerSvc.Login(c.Request.Context(), project.ID, req.Email, req.Password)
+			customer, _, _, err := deps.CustomerSvc.Login(c.Request.Context(), project.ID, req.Email, req.Password, c.ClientIP(), project.RequireEmailVerification)
 			if err != nil {
+				if tooManyLoginAttempts(c, err) {
+					return
+				}
 				status := http.StatusUnauthorized
 				msg := "invalid credentials"
-				if err != customersvc.ErrInvalidCredentials {
+				switch {
+				case errors.Is(err, customersvc.ErrEmailNotVerified):
+					status = http.StatusForbidden
+					msg = err.Error()
+				case err != customersvc.ErrInvalidCredentials:
 					status = http.StatusInternalServerError
 					msg = "login failed"
 				}
@@ -203,7 +832,7 @@ func buildRouter(logger *log.Logger, db *pgxpool.Pool, deps Deps, fileURLHost st
 					return
 				}
 			} else {
-				ct := toCTCart(*cart, customer, fileURLHost)
+				ct := toCTCart(*cart, customer, fileURLHost, localeFromRequest(c))
 				cartResp = &ct
 			}
 
@@ -220,16 +849,28 @@ func buildRouter(logger *log.Logger, db *pgxpool.Pool, deps Deps, fileURLHost st
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "list products failed"})
 				return
 			}
+			if !prepareProducts(c, project.ID, products) {
+				return
+			}
+			loc := localeFromRequest(c)
 			var resp []ctProduct
 			for _, p := range products {
-				resp = append(resp, toCTProduct(logger, p, fileURLHost))
+				resp = append(resp, toCTProduct(logger, p, fileURLHost, loc))
 			}
 			c.JSON(http.StatusOK, resp)
 		})
 		group.GET("/products/:id", func(c *gin.Context) {
 			project := mustProject(c)
 			id := c.Param("id")
-			p, err := deps.ProductSvc.Get(c.Request.Context(), project.ID, id)
+			var (
+				p   *domain.Product
+				err error
+			)
+			if key, ok := keyFromPathParam(id); ok {
+				p, err = deps.ProductSvc.GetByKey(c.Request.Context(), project.ID, key)
+			} else {
+				p, err = deps.ProductSvc.Get(c.Request.Context(), project.ID, id)
+			}
 			if err != nil {
 				if err == domain.ErrNotFound {
 					logger.Printf("product get not found project_id=%s id=%s", project.ID, id)
@@ -240,8 +881,66 @@ func buildRouter(logger *log.Logger, db *pgxpool.Pool, deps Deps, fileURLHost st
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "get product failed"})
 				return
 			}
-			c.JSON(http.StatusOK, toCTProduct(logger, *p, fileURLHost))
+			products := []domain.Product{*p}
+			if !prepareProducts(c, project.ID, products) {
+				return
+			}
+			c.JSON(http.StatusOK, toCTProduct(logger, products[0], fileURLHost, localeFromRequest(c)))
 		})
+		// Products are read publicly; creating and updating them takes an admin
+		// token.
+		if admin != nil {
+			admin.POST("/products", func(c *gin.Context) {
+				project := mustProject(c)
+				var req productsvc.ProductDraft
+				if err := c.ShouldBindJSON(&req); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+					return
+				}
+				p, err := deps.ProductSvc.Create(c.Request.Context(), project.ID, req)
+				if err != nil {
+					logger.Printf("product create error project_id=%s key=%s error=%v", project.ID, req.Key, err)
+					if errors.Is(err, domain.ErrAlreadyExists) {
+						c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+						return
+					}
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				products := []domain.Product{*p}
+				if !prepareProducts(c, project.ID, products) {
+					return
+				}
+				c.JSON(http.StatusCreated, toCTProduct(logger, products[0], fileURLHost, localeFromRequest(c)))
+			})
+			admin.POST("/products/:id", func(c *gin.Context) {
+				project := mustProject(c)
+				id := c.Param("id")
+				var req productsvc.UpdateInput
+				if err := c.ShouldBindJSON(&req); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+					return
+				}
+				p, err := deps.ProductSvc.Update(c.Request.Context(), project.ID, id, req)
+				if err != nil {
+					logger.Printf("product update error project_id=%s id=%s error=%v", project.ID, id, err)
+					switch {
+					case errors.Is(err, domain.ErrNotFound):
+						c.JSON(http.StatusNotFound, gin.H{"error": "product not found"})
+					case errors.Is(err, domain.ErrConcurrentModification), errors.Is(err, domain.ErrAlreadyExists):
+						c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+					default:
+						c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					}
+					return
+				}
+				products := []domain.Product{*p}
+				if !prepareProducts(c, project.ID, products) {
+					return
+				}
+				c.JSON(http.StatusOK, toCTProduct(logger, products[0], fileURLHost, localeFromRequest(c)))
+			})
+		}
 		group.POST("/products/search", func(c *gin.Context) {
 			project := mustProject(c)
 
@@ -269,139 +968,137 @@ func buildRouter(logger *log.Logger, db *pgxpool.Pool, deps Deps, fileURLHost st
 			resp := buildSearchResponse(products, cats, req)
 			c.JSON(http.StatusOK, resp)
 		})
-		group.GET("/product-discounts", func(c *gin.Context) {
-			_ = mustProject(c)
-			limit, offset := parseLimitOffset(c.Query("limit"), c.Query("offset"))
-			c.JSON(http.StatusOK, buildProductDiscountList(limit, offset))
-		})
+		group.GET("/product-projections", listProductProjections)
+		group.GET("/product-projections/:id", getProductProjection)
+		if deps.ProductTypeSvc != nil {
+			registerProductTypeRoutes(group, admin, logger, deps.ProductTypeSvc)
+		}
+		if deps.ProductDiscountSvc != nil {
+			registerProductDiscountRoutes(admin, admin, logger, deps.ProductDiscountSvc)
+		}
+		if deps.CartDiscountSvc != nil {
+			registerCartDiscountRoutes(admin, admin, logger, deps.CartDiscountSvc)
+		}
+		if deps.DiscountCodeSvc != nil {
+			registerDiscountCodeRoutes(admin, admin, logger, deps.DiscountCodeSvc)
+		}
+		if deps.TaxCategorySvc != nil {
+			registerTaxCategoryRoutes(group, admin, logger, deps.TaxCategorySvc)
+		}
+		if deps.CustomerGroupSvc != nil {
+			registerCustomerGroupRoutes(group, admin, logger, deps.CustomerGroupSvc)
+		}
+		if deps.ZoneSvc != nil {
+			registerZoneRoutes(group, admin, logger, deps.ZoneSvc)
+		}
+		if deps.ShippingMethodSvc != nil {
+			registerShippingMethodRoutes(group, admin, logger, deps.ShippingMethodSvc, deps.CartSvc)
+		}
+		if deps.InventorySvc != nil {
+			registerInventoryRoutes(group, admin, logger, deps.InventorySvc)
+		}
+		if deps.ChannelSvc != nil {
+			registerChannelRoutes(group, admin, logger, deps.ChannelSvc)
+		}
+		if deps.ProductSelectionSvc != nil {
+			registerProductSelectionRoutes(group, admin, logger, deps.ProductSelectionSvc)
+		}
+		if deps.StoreSvc != nil {
+			registerStoreRoutes(group, admin, logger, deps.StoreSvc)
+		}
 		group.GET("/categories", func(c *gin.Context) {
 			project := mustProject(c)
-			cats, err := deps.CategorySvc.List(c.Request.Context(), project.ID)
-			if err != nil {
-				logger.Printf("categories list error project_id=%s error=%v", project.ID, err)
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "list categories failed"})
-				return
-			}
 			limit, offset := parseLimitOffset(c.Query("limit"), c.Query("offset"))
-			resp := buildCategoryList(cats, limit, offset)
-			c.JSON(http.StatusOK, resp)
-		})
-		group.POST("/carts", func(c *gin.Context) {
-			project := mustProject(c)
-			var req cartsvc.CreateInput
-			if err := c.ShouldBindJSON(&req); err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
-				return
-			}
-			cart, err := deps.CartSvc.Create(c.Request.Context(), project.ID, req)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-				return
-			}
-			c.JSON(http.StatusCreated, cart)
-		})
-		group.POST("/me/carts", func(c *gin.Context) {
-			project := mustProject(c)
-			actor, ok := authorizeActor(c, project, deps.CustomerSvc, deps.AnonymousSvc)
-			if !ok {
-				return
-			}
-			var req cartsvc.CreateInput
-			if err := c.ShouldBindJSON(&req); err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			if where := c.QueryArray("where"); len(where) > 0 {
+				if len(where) > 1 {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "only one where predicate is supported"})
+					return
+				}
+				pred, err := parseLookupPredicate(where[0])
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				var cat *domain.Category
+				switch pred.Field {
+				case "slug":
+					cat, err = deps.CategorySvc.GetBySlug(c.Request.Context(), project.ID, pred.Locale, pred.Value)
+				case "key":
+					cat, err = deps.CategorySvc.GetByKey(c.Request.Context(), project.ID, pred.Value)
+				default:
+					cat, err = deps.CategorySvc.Get(c.Request.Context(), project.ID, pred.Value)
+				}
+				if err != nil && !errors.Is(err, domain.ErrNotFound) {
+					logger.Printf("categories lookup error project_id=%s where=%q error=%v", project.ID, where[0], err)
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "list categories failed"})
+					return
+				}
+				var cats []domain.Category
+				if cat != nil {
+					cats = []domain.Category{*cat}
+				}
+				c.JSON(http.StatusOK, buildCategoryList(cats, len(cats), limit, offset, localeFromRequest(c)))
 				return
 			}
-			if actor.Customer != nil {
-				req.CustomerID = &actor.Customer.ID
-			} else if actor.AnonymousID != "" {
-				req.AnonymousID = &actor.AnonymousID
-			}
-			cart, err := deps.CartSvc.Create(c.Request.Context(), project.ID, req)
+			cats, total, err := deps.CategorySvc.ListPage(c.Request.Context(), project.ID, limit, offset)
 			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				logger.Printf("categories list error project_id=%s error=%v", project.ID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "list categories failed"})
 				return
 			}
-			c.JSON(http.StatusCreated, toCTCart(*cart, actor.Customer, fileURLHost))
+			resp := buildCategoryList(cats, total, limit, offset, localeFromRequest(c))
+			c.JSON(http.StatusOK, resp)
 		})
-		group.POST("/me/carts/:id", func(c *gin.Context) {
+		group.GET("/categories/:id", func(c *gin.Context) {
 			project := mustProject(c)
-			actor, ok := authorizeActor(c, project, deps.CustomerSvc, deps.AnonymousSvc)
-			if !ok {
-				return
-			}
 			id := c.Param("id")
-			var req cartsvc.UpdateInput
-			if err := c.ShouldBindJSON(&req); err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
-				return
-			}
-			var cart *domain.Cart
-			var err error
-			if actor.Customer != nil {
-				cart, err = deps.CartSvc.Update(c.Request.Context(), project.ID, actor.Customer.ID, id, req)
+			var (
+				cat *domain.Category
+				err error
+			)
+			if key, ok := keyFromPathParam(id); ok {
+				cat, err = deps.CategorySvc.GetByKey(c.Request.Context(), project.ID, key)
 			} else {
-				cart, err = deps.CartSvc.UpdateAnonymous(c.Request.Context(), project.ID, actor.AnonymousID, id, req)
+				cat, err = deps.CategorySvc.Get(c.Request.Context(), project.ID, id)
 			}
 			if err != nil {
 				if errors.Is(err, domain.ErrNotFound) {
-					c.JSON(http.StatusNotFound, gin.H{"error": "cart not found"})
+					c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
 					return
 				}
-				logger.Printf("cart update error project_id=%s cart_id=%s error=%v", project.ID, id, err)
-				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				logger.Printf("category get error project_id=%s id=%s error=%v", project.ID, id, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "get category failed"})
 				return
 			}
-			c.JSON(http.StatusOK, toCTCart(*cart, actor.Customer, fileURLHost))
+			c.JSON(http.StatusOK, toCTCategory(*cat, localeFromRequest(c)))
 		})
-		group.DELETE("/me/carts/:id", func(c *gin.Context) {
+		group.POST("/carts", func(c *gin.Context) {
 			project := mustProject(c)
-			actor, ok := authorizeActor(c, project, deps.CustomerSvc, deps.AnonymousSvc)
-			if !ok {
-				return
-			}
-			id := c.Param("id")
-			var cart *domain.Cart
-			var err error
-			if actor.Customer != nil {
-				cart, err = deps.CartSvc.Delete(c.Request.Context(), project.ID, actor.Customer.ID, id)
-			} else {
-				cart, err = deps.CartSvc.DeleteAnonymous(c.Request.Context(), project.ID, actor.AnonymousID, id)
-			}
-			if err != nil {
-				if errors.Is(err, domain.ErrNotFound) {
-					c.JSON(http.StatusNotFound, gin.H{"error": "cart not found"})
-					return
-				}
-				logger.Printf("cart delete error project_id=%s cart_id=%s error=%v", project.ID, id, err)
-				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			var req cartsvc.CreateInput
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
 				return
 			}
-			c.JSON(http.StatusOK, toCTCart(*cart, actor.Customer, fileURLHost))
-		})
-		group.GET("/me/active-cart", func(c *gin.Context) {
-			project := mustProject(c)
-			actor, ok := authorizeActor(c, project, deps.CustomerSvc, deps.AnonymousSvc)
-			if !ok {
+			if req.BusinessUnit != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": errBusinessUnitCart.Error()})
 				return
 			}
-			var cart *domain.Cart
-			var err error
-			if actor.Customer != nil {
-				cart, err = deps.CartSvc.GetActive(c.Request.Context(), project.ID, actor.Customer.ID)
-			} else {
-				cart, err = deps.CartSvc.GetActiveAnonymous(c.Request.Context(), project.ID, actor.AnonymousID)
-			}
+			cart, err := deps.CartSvc.Create(c.Request.Context(), project.ID, req)
 			if err != nil {
-				if errors.Is(err, domain.ErrNotFound) {
-					c.JSON(http.StatusNotFound, gin.H{"error": "cart not found"})
-					return
-				}
-				logger.Printf("active cart error project_id=%s error=%v", project.ID, err)
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "get active cart failed"})
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			c.JSON(http.StatusOK, toCTCart(*cart, actor.Customer, fileURLHost))
+			c.JSON(http.StatusCreated, cart)
 		})
+		group.POST("/me/carts", createMyCart)
+		group.POST("/me/carts/:id", updateMyCart)
+		group.DELETE("/me/carts/:id", deleteMyCart)
+		group.GET("/me/active-cart", getMyActiveCart)
+		if deps.OrderSvc != nil {
+			group.POST("/me/orders", createMyOrder)
+			group.GET("/me/orders", listMyOrders)
+			group.GET("/me/orders/:id", getMyOrder)
+		}
 		group.GET("/carts/:id", func(c *gin.Context) {
 			project := mustProject(c)
 			id := c.Param("id")
@@ -424,6 +1121,32 @@ func buildRouter(logger *log.Logger, db *pgxpool.Pool, deps Deps, fileURLHost st
 	ctStyle := router.Group("/:projectKey", projectMiddleware(logger, deps.ProjectRepo))
 	registerProjectRoutes(ctStyle)
 
+	// /{projectKey}/in-store/key={storeKey}/... restricts carts, orders and
+	// product projections to one store.
+	if deps.StoreSvc != nil {
+		inStore := router.Group("/:projectKey/in-store/key=:storeKey", projectMiddleware(logger, deps.ProjectRepo), storeMiddleware(logger, deps.StoreSvc))
+		inStore.POST("/me/carts", createMyCart)
+		inStore.POST("/me/carts/:id", updateMyCart)
+		inStore.DELETE("/me/carts/:id", deleteMyCart)
+		inStore.GET("/me/active-cart", getMyActiveCart)
+		if deps.OrderSvc != nil {
+			inStore.POST("/me/orders", createMyOrder)
+			inStore.GET("/me/orders", listMyOrders)
+			inStore.GET("/me/orders/:id", getMyOrder)
+		}
+		inStore.GET("/product-projections", listProductProjections)
+		inStore.GET("/product-projections/:id", getProductProjection)
+	}
+
+	// /{projectKey}/as-associate/{associateId}/in-business-unit/key={buKey}/...
+	// lets associates manage the carts of a business unit within their
+	// permissions.
+	if deps.BusinessUnitSvc != nil {
+		asAssociate := router.Group("/:projectKey/as-associate/:associateId/in-business-unit/key=:buKey",
+			projectMiddleware(logger, deps.ProjectRepo), associateMiddleware(logger, deps.CustomerSvc, deps.BusinessUnitSvc))
+		registerAsAssociateRoutes(asAssociate, logger, deps, fileURLHost)
+	}
+
 	oauth := router.Group("/oauth/:projectKey", projectMiddleware(logger, deps.ProjectRepo))
 	oauth.POST("/customers/token", func(c *gin.Context) {
 		project := mustProject(c)
@@ -442,11 +1165,18 @@ func buildRouter(logger *log.Logger, db *pgxpool.Pool, deps Deps, fileURLHost st
 			return
 		}
 
-		customer, accessToken, refreshToken, err := deps.CustomerSvc.Login(c.Request.Context(), project.ID, req.Username, req.Password)
+		customer, accessToken, refreshToken, err := deps.CustomerSvc.Login(c.Request.Context(), project.ID, req.Username, req.Password, c.ClientIP(), project.RequireEmailVerification)
 		if err != nil {
+			if tooManyLoginAttempts(c, err) {
+				return
+			}
 			status := http.StatusUnauthorized
 			msg := "invalid credentials"
-			if err != customersvc.ErrInvalidCredentials {
+			switch {
+			case errors.Is(err, customersvc.ErrEmailNotVerified):
+				status = http.StatusForbidden
+				msg = err.Error()
+			case err != customersvc.ErrInvalidCredentials:
 				status = http.StatusInternalServerError
 				msg = "token issuance failed"
 			}
@@ -498,11 +1228,53 @@ func buildRouter(logger *log.Logger, db *pgxpool.Pool, deps Deps, fileURLHost st
 	})
 
 	router.POST("/oauth/token", func(c *gin.Context) {
+		var req clientTokenRequest
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token request"})
+			return
+		}
+		if strings.ToLower(req.GrantType) != "client_credentials" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported grant_type"})
+			return
+		}
+		projectKey, ok := adminScopeProject(req.Scope)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid scope"})
+			return
+		}
+		if deps.AdminSvc == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid client"})
+			return
+		}
+		project, err := deps.ProjectRepo.GetByKey(c.Request.Context(), projectKey)
+		if err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid scope"})
+				return
+			}
+			logger.Printf("admin token project lookup error key=%s error=%v", projectKey, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "token issuance failed"})
+			return
+		}
+		clientID, clientSecret, ok := c.Request.BasicAuth()
+		if !ok {
+			clientID, clientSecret = req.ClientID, req.ClientSecret
+		}
+		token, err := deps.AdminSvc.Issue(c.Request.Context(), project.ID, clientID, clientSecret)
+		if err != nil {
+			if errors.Is(err, adminsvc.ErrInvalidClient) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid client"})
+				return
+			}
+			logger.Printf("admin token error project_id=%s error=%v", project.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "token issuance failed"})
+			return
+		}
 		c.JSON(http.StatusOK, gin.H{
-			"access_token": "NOT_IMPLEMENTED",
-			"expires_in":   172800,
+			"access_token": token,
+			"expires_in":   deps.AdminSvc.AccessTTLSeconds(),
 			"token_type":   "Bearer",
-			"scope":        "manage_project:petal_pot",
+			"scope":        adminsvc.Scope + ":" + project.Key,
 		})
 	})
 
@@ -610,6 +1382,110 @@ func authorizeCustomer(c *gin.Context, project *domain.Project, svc customerServ
 	return customer, true
 }
 
+// adminScopeProject returns the project key of a manage_customers or
+// manage_project scope.
+func adminScopeProject(scope string) (string, bool) {
+	for _, part := range strings.Fields(scope) {
+		for _, prefix := range []string{adminsvc.Scope + ":", "manage_project:"} {
+			if key := strings.TrimPrefix(part, prefix); key != part && key != "" {
+				return key, true
+			}
+		}
+	}
+	return "", false
+}
+
+// requireAdmin rejects requests without an admin token of the project.
+func requireAdmin(svc adminService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		project := mustProject(c)
+		token := extractBearerToken(c.GetHeader("Authorization"))
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+			return
+		}
+		if err := svc.Authorize(c.Request.Context(), project.ID, token); err != nil {
+			if errors.Is(err, adminsvc.ErrInvalidToken) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient scope"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to check token"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// invalidFields answers 400 with one InvalidField error per rule the input
+// breaks.
+func invalidFields(c *gin.Context, err error) bool {
+	var invalid *customersvc.ValidationError
+	if !errors.As(err, &invalid) {
+		return false
+	}
+	errs := make([]ctErrorObject, 0, len(invalid.Errors))
+	for _, f := range invalid.Errors {
+		errs = append(errs, ctErrorObject{Code: "InvalidField", Message: f.Message, Field: f.Field, Rule: f.Rule})
+	}
+	c.JSON(http.StatusBadRequest, ctError{
+		StatusCode: http.StatusBadRequest,
+		Message:    invalid.Error(),
+		Error:      invalid.Error(),
+		Errors:     errs,
+	})
+	return true
+}
+
+// tooManyLoginAttempts answers 429 with Retry-After if err is a throttled
+// login.
+func tooManyLoginAttempts(c *gin.Context, err error) bool {
+	var throttled *customersvc.TooManyAttemptsError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+	seconds := throttled.RetryAfterSeconds()
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, ctError{
+		StatusCode: http.StatusTooManyRequests,
+		Message:    throttled.Error(),
+		Error:      throttled.Error(),
+		Errors: []ctErrorObject{{
+			Code:              "TooManyRequests",
+			Message:           throttled.Error(),
+			RetryAfterSeconds: seconds,
+		}},
+	})
+	return true
+}
+
+// tokenOptions picks the mail template of the project and request locale.
+func tokenOptions(c *gin.Context, project *domain.Project, ttlMinutes int) customersvc.TokenOptions {
+	return customersvc.TokenOptions{
+		TTL:        time.Duration(ttlMinutes) * time.Minute,
+		ProjectKey: project.Key,
+		Locale:     localeFromRequest(c).primary(),
+	}
+}
+
+// confirmEmail serves both email confirm routes; customerID is empty on the
+// project route.
+func confirmEmail(c *gin.Context, logger *log.Logger, svc customerService, project *domain.Project, customerID string, req customersvc.ConfirmEmailInput) {
+	customer, err := svc.ConfirmEmail(c.Request.Context(), project.ID, customerID, req)
+	if err != nil {
+		logger.Printf("email confirm error project_id=%s error=%v", project.ID, err)
+		switch {
+		case errors.Is(err, customersvc.ErrInvalidToken):
+			c.JSON(http.StatusNotFound, gin.H{"error": "email token not found or expired"})
+		case errors.Is(err, domain.ErrConcurrentModification):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, toCTCustomer(*customer))
+}
+
 type authActor struct {
 	Customer    *domain.Customer
 	AnonymousID string
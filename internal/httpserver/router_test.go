@@ -2,17 +2,37 @@ package httpserver
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"commercetools-replica/internal/domain"
+	adminsvc "commercetools-replica/internal/service/admin"
+	businessunitsvc "commercetools-replica/internal/service/businessunit"
 	cartsvc "commercetools-replica/internal/service/cart"
+	cartdiscountsvc "commercetools-replica/internal/service/cartdiscount"
+	channelsvc "commercetools-replica/internal/service/channel"
 	customersvc "commercetools-replica/internal/service/customer"
+	customergroupsvc "commercetools-replica/internal/service/customergroup"
+	discountcodesvc "commercetools-replica/internal/service/discountcode"
+	inventorysvc "commercetools-replica/internal/service/inventory"
+	ordersvc "commercetools-replica/internal/service/order"
+	privacysvc "commercetools-replica/internal/service/privacy"
+	productsvc "commercetools-replica/internal/service/product"
+	productdiscountsvc "commercetools-replica/internal/service/productdiscount"
+	productselectionsvc "commercetools-replica/internal/service/productselection"
+	producttypesvc "commercetools-replica/internal/service/producttype"
+	shippingmethodsvc "commercetools-replica/internal/service/shippingmethod"
+	storesvc "commercetools-replica/internal/service/store"
+	taxcategorysvc "commercetools-replica/internal/service/taxcategory"
+	zonesvc "commercetools-replica/internal/service/zone"
 	"github.com/gin-gonic/gin"
 )
 
@@ -25,6 +45,10 @@ func (s *stubProjectRepo) GetByKey(_ context.Context, _ string) (*domain.Project
 	return s.project, s.err
 }
 
+func (s *stubProjectRepo) GetByID(_ context.Context, _ string) (*domain.Project, error) {
+	return s.project, s.err
+}
+
 func (s *stubProjectRepo) Create(_ context.Context, p *domain.Project) (*domain.Project, error) {
 	return p, nil
 }
@@ -121,12 +145,12 @@ func TestProjectMiddleware_MissingKey(t *testing.T) {
 func TestBuildRouter_RequiresDependencies(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	deps := Deps{}
-	if _, err := buildRouter(logDiscard(), nil, deps); err == nil {
+	if _, err := buildRouter(logDiscard(), nil, deps, ""); err == nil {
 		t.Fatalf("expected error for missing deps")
 	}
 	// missing ProductSvc
 	deps.ProjectRepo = &stubProjectRepo{project: &domain.Project{ID: "id", Key: "key"}}
-	if _, err := buildRouter(logDiscard(), nil, deps); err == nil {
+	if _, err := buildRouter(logDiscard(), nil, deps, ""); err == nil {
 		t.Fatalf("expected error for missing product service")
 	}
 }
@@ -145,22 +169,81 @@ func (s *stubProductService) Get(_ context.Context, _ string, _ string) (*domain
 	return s.getResult, s.err
 }
 
-type stubCartService struct{}
+func (s *stubProductService) GetByKey(_ context.Context, _ string, key string) (*domain.Product, error) {
+	for i := range s.listResult {
+		if s.listResult[i].Key == key {
+			return &s.listResult[i], s.err
+		}
+	}
+	return nil, domain.ErrNotFound
+}
 
-func (s *stubCartService) Create(_ context.Context, _ string, _ cartsvc.CreateInput) (*domain.Cart, error) {
-	return nil, nil
+func (s *stubProductService) GetBySlug(_ context.Context, _ string, locale, slug string, staged bool) (*domain.Product, error) {
+	for i := range s.listResult {
+		p := &s.listResult[i]
+		data := p.Current
+		if staged {
+			data = p.Staged
+		}
+		if v, ok := data.Slug.Lookup(locale); ok && v == slug && (staged || p.Published) {
+			return p, s.err
+		}
+	}
+	return nil, domain.ErrNotFound
 }
 
-func (s *stubCartService) Get(_ context.Context, _ string, _ string) (*domain.Cart, error) {
-	return nil, nil
+func (s *stubProductService) Create(_ context.Context, _ string, _ productsvc.ProductDraft) (*domain.Product, error) {
+	return s.getResult, s.err
+}
+
+func (s *stubProductService) Update(_ context.Context, _ string, _ string, _ productsvc.UpdateInput) (*domain.Product, error) {
+	return s.getResult, s.err
+}
+
+type stubCartService struct {
+	shippingMethods []domain.ShippingMethod
+	carts           []domain.Cart
+}
+
+func (s *stubCartService) Create(_ context.Context, _ string, in cartsvc.CreateInput) (*domain.Cart, error) {
+	cart := domain.Cart{ID: "cart-new", Currency: in.Currency, CustomerID: in.CustomerID, State: "active"}
+	if in.Store != nil {
+		cart.StoreKey = in.Store.Key
+	}
+	if in.BusinessUnit != nil {
+		cart.BusinessUnitKey = in.BusinessUnit.Key
+	}
+	return &cart, nil
+}
+
+func (s *stubCartService) Get(_ context.Context, _ string, id string) (*domain.Cart, error) {
+	for i := range s.carts {
+		if s.carts[i].ID == id {
+			return &s.carts[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubCartService) GetActiveInStore(_ context.Context, _, storeKey, _ string) (*domain.Cart, error) {
+	for i := range s.carts {
+		if s.carts[i].StoreKey == storeKey {
+			return &s.carts[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubCartService) GetActiveAnonymousInStore(_ context.Context, _, _, _ string) (*domain.Cart, error) {
+	return nil, domain.ErrNotFound
 }
 
 func (s *stubCartService) GetActive(_ context.Context, _ string, _ string) (*domain.Cart, error) {
 	return nil, nil
 }
 
-func (s *stubCartService) Update(_ context.Context, _ string, _ string, _ string, _ cartsvc.UpdateInput) (*domain.Cart, error) {
-	return nil, nil
+func (s *stubCartService) Update(ctx context.Context, projectID string, _ string, cartID string, _ cartsvc.UpdateInput) (*domain.Cart, error) {
+	return s.Get(ctx, projectID, cartID)
 }
 
 func (s *stubCartService) GetActiveAnonymous(_ context.Context, _ string, _ string) (*domain.Cart, error) {
@@ -175,14 +258,21 @@ func (s *stubCartService) AssignCustomerFromAnonymous(_ context.Context, _ strin
 	return nil, nil
 }
 
-func (s *stubCartService) Delete(_ context.Context, _ string, _ string, _ string) (*domain.Cart, error) {
-	return nil, nil
+func (s *stubCartService) Delete(ctx context.Context, projectID string, _ string, cartID string) (*domain.Cart, error) {
+	return s.Get(ctx, projectID, cartID)
 }
 
 func (s *stubCartService) DeleteAnonymous(_ context.Context, _ string, _ string, _ string) (*domain.Cart, error) {
 	return nil, nil
 }
 
+func (s *stubCartService) MatchingShippingMethods(_ context.Context, _ string, cartID string) ([]domain.ShippingMethod, error) {
+	if cartID != "cart-1" {
+		return nil, domain.ErrNotFound
+	}
+	return s.shippingMethods, nil
+}
+
 type stubCategoryService struct {
 	list []domain.Category
 	err  error
@@ -192,11 +282,72 @@ func (s *stubCategoryService) List(_ context.Context, _ string) ([]domain.Catego
 	return s.list, s.err
 }
 
+func (s *stubCategoryService) ListPage(_ context.Context, _ string, limit, offset int) ([]domain.Category, int, error) {
+	if offset > len(s.list) {
+		offset = len(s.list)
+	}
+	end := len(s.list)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return s.list[offset:end], len(s.list), s.err
+}
+
+func (s *stubCategoryService) Get(_ context.Context, _ string, id string) (*domain.Category, error) {
+	for i := range s.list {
+		if s.list[i].ID == id {
+			return &s.list[i], s.err
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubCategoryService) GetByKey(_ context.Context, _ string, key string) (*domain.Category, error) {
+	for i := range s.list {
+		if s.list[i].Key == key {
+			return &s.list[i], s.err
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubCategoryService) GetBySlug(_ context.Context, _ string, locale, slug string) (*domain.Category, error) {
+	for i := range s.list {
+		if v, ok := s.list[i].Slug.Lookup(locale); ok && v == slug {
+			return &s.list[i], s.err
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
 func (s *stubCategoryService) Upsert(_ context.Context, c domain.Category) (*domain.Category, error) {
 	s.list = append(s.list, c)
 	return &c, s.err
 }
 
+// stubAdminService accepts only its token.
+type stubAdminService struct {
+	token string
+}
+
+func (s *stubAdminService) Issue(_ context.Context, _, clientID, clientSecret string) (string, error) {
+	if clientID != "client" || clientSecret != "secret" {
+		return "", adminsvc.ErrInvalidClient
+	}
+	return s.token, nil
+}
+
+func (s *stubAdminService) Authorize(_ context.Context, _, token string) error {
+	if token != s.token {
+		return adminsvc.ErrInvalidToken
+	}
+	return nil
+}
+
+func (s *stubAdminService) AccessTTLSeconds() int {
+	return 3600
+}
+
 type stubCustomerService struct {
 	customer *domain.Customer
 	err      error
@@ -206,7 +357,7 @@ func (s *stubCustomerService) Signup(_ context.Context, _ string, _ customersvc.
 	return s.customer, s.err
 }
 
-func (s *stubCustomerService) Login(_ context.Context, _ string, _ string, _ string) (*domain.Customer, string, string, error) {
+func (s *stubCustomerService) Login(_ context.Context, _ string, _ string, _ string, _ string, _ bool) (*domain.Customer, string, string, error) {
 	return s.customer, "access-token", "refresh-token", s.err
 }
 
@@ -214,6 +365,59 @@ func (s *stubCustomerService) LookupByToken(_ context.Context, _ string, _ strin
 	return s.customer, s.err
 }
 
+func (s *stubCustomerService) Update(_ context.Context, _, _ string, _ customersvc.UpdateInput) (*domain.Customer, error) {
+	return s.customer, s.err
+}
+
+func (s *stubCustomerService) UpdateMe(_ context.Context, _, _ string, _ customersvc.UpdateInput) (*domain.Customer, error) {
+	return s.customer, s.err
+}
+
+func (s *stubCustomerService) ChangePassword(_ context.Context, _, _, _ string, _ customersvc.ChangePasswordInput) (*domain.Customer, error) {
+	return s.customer, s.err
+}
+
+func (s *stubCustomerService) CreatePasswordToken(_ context.Context, _, _ string, _ customersvc.TokenOptions) (*customersvc.CustomerToken, error) {
+	return nil, s.err
+}
+
+func (s *stubCustomerService) CreateEmailToken(_ context.Context, _, _ string, _ int, _ customersvc.TokenOptions) (*customersvc.CustomerToken, error) {
+	return nil, s.err
+}
+
+func (s *stubCustomerService) ConfirmEmail(_ context.Context, _, _ string, _ customersvc.ConfirmEmailInput) (*domain.Customer, error) {
+	return s.customer, s.err
+}
+
+func (s *stubCustomerService) ResetPassword(_ context.Context, _ string, _ customersvc.ResetPasswordInput) (*domain.Customer, error) {
+	return s.customer, s.err
+}
+
+func (s *stubCustomerService) ListPage(_ context.Context, _, email string, _, _ int) ([]domain.Customer, int, error) {
+	if s.err != nil {
+		return nil, 0, s.err
+	}
+	if s.customer == nil || (email != "" && !strings.EqualFold(email, s.customer.Email)) {
+		return nil, 0, nil
+	}
+	return []domain.Customer{*s.customer}, 1, nil
+}
+
+func (s *stubCustomerService) Get(_ context.Context, _, _ string) (*domain.Customer, error) {
+	return s.customer, s.err
+}
+
+func (s *stubCustomerService) Create(_ context.Context, projectID string, draft customersvc.CustomerDraft) (*domain.Customer, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &domain.Customer{ID: "new", ProjectID: projectID, Version: 1, Email: draft.Email, IsEmailVerified: draft.IsEmailVerified}, nil
+}
+
+func (s *stubCustomerService) Delete(_ context.Context, _, _ string, _ int) (*domain.Customer, error) {
+	return s.customer, s.err
+}
+
 func (s *stubCustomerService) AccessTTLSeconds() int {
 	return 3600
 }
@@ -241,7 +445,7 @@ func TestProductsHandler_List(t *testing.T) {
 	projectRepo := &stubProjectRepo{project: proj}
 	productSvc := &stubProductService{
 		listResult: []domain.Product{
-			{ID: "p1", ProjectID: proj.ID, Name: "Demo", Key: "demo", SKU: "SKU1", PriceCents: 100, Currency: "EUR"},
+			testProduct("p1", "demo", "Demo", "SKU1", 100, "EUR"),
 		},
 	}
 	cartSvc := &stubCartService{}
@@ -254,7 +458,7 @@ func TestProductsHandler_List(t *testing.T) {
 		CategorySvc:  categorySvc,
 		CustomerSvc:  customerSvc,
 		AnonymousSvc: &stubAnonymousService{},
-	})
+	}, "")
 	if err != nil {
 		t.Fatalf("build router: %v", err)
 	}
@@ -272,6 +476,61 @@ func TestProductsHandler_List(t *testing.T) {
 	}
 }
 
+func TestProductsHandler_ListLocaleProjection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
+	product := testProduct("p1", "demo", "Demo", "SKU1", 100, "EUR")
+	product.Current.Name = domain.LocalizedString{"en": "Demo", "de-DE": "Demo DE"}
+	product.Staged = product.Current
+	productSvc := &stubProductService{listResult: []domain.Product{product}}
+	router, err := buildRouter(logDiscard(), nil, Deps{
+		ProjectRepo:  &stubProjectRepo{project: proj},
+		ProductSvc:   productSvc,
+		CartSvc:      &stubCartService{},
+		CategorySvc:  &stubCategoryService{},
+		CustomerSvc:  &stubCustomerService{},
+		AnonymousSvc: &stubAnonymousService{},
+	}, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		url      string
+		header   string
+		contains []string
+		excludes []string
+	}{
+		{name: "projection", url: "/proj-key/products?localeProjection=de", contains: []string{`"de-DE":"Demo DE"`}, excludes: []string{`"en":"Demo"`}},
+		{name: "accept-language", url: "/proj-key/products", header: "de;q=0.9, en;q=0.1", contains: []string{`"de-DE":"Demo DE"`, `"en":"Demo"`}},
+		{name: "accept-language fallback", url: "/proj-key/products", header: "fr", contains: []string{`"de-DE":"Demo DE"`, `"en":"Demo"`}},
+		{name: "strict projection", url: "/proj-key/products?localeProjection=fr", excludes: []string{`"Demo`}},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+		if tc.header != "" {
+			req.Header.Set("Accept-Language", tc.header)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", tc.name, rec.Code)
+		}
+		body := rec.Body.String()
+		for _, want := range tc.contains {
+			if !strings.Contains(body, want) {
+				t.Fatalf("%s: expected %s in %q", tc.name, want, body)
+			}
+		}
+		for _, unwanted := range tc.excludes {
+			if strings.Contains(body, unwanted) {
+				t.Fatalf("%s: did not expect %s in %q", tc.name, unwanted, body)
+			}
+		}
+	}
+}
+
 func TestProductsHandler_Get_NotFound(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
@@ -289,7 +548,7 @@ func TestProductsHandler_Get_NotFound(t *testing.T) {
 		CategorySvc:  categorySvc,
 		CustomerSvc:  customerSvc,
 		AnonymousSvc: &stubAnonymousService{},
-	})
+	}, "")
 	if err != nil {
 		t.Fatalf("build router: %v", err)
 	}
@@ -304,6 +563,218 @@ func TestProductsHandler_Get_NotFound(t *testing.T) {
 	}
 }
 
+func TestProductsHandler_GetByKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
+	productSvc := &stubProductService{
+		listResult: []domain.Product{testProduct("p1", "demo", "Demo", "SKU1", 100, "EUR")},
+	}
+	router, err := buildRouter(logDiscard(), nil, Deps{
+		ProjectRepo:  &stubProjectRepo{project: proj},
+		ProductSvc:   productSvc,
+		CartSvc:      &stubCartService{},
+		CategorySvc:  &stubCategoryService{},
+		CustomerSvc:  &stubCustomerService{},
+		AnonymousSvc: &stubAnonymousService{},
+	}, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/proj-key/products/key=demo", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"id":"p1"`) {
+		t.Fatalf("expected product p1, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/proj-key/products/key=missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestProductsHandler_WritesRequireAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
+	p := testProduct("p1", "demo", "Demo", "SKU1", 100, "EUR")
+	router, err := buildRouter(logDiscard(), nil, Deps{
+		ProjectRepo:  &stubProjectRepo{project: proj},
+		ProductSvc:   &stubProductService{listResult: []domain.Product{p}, getResult: &p},
+		CartSvc:      &stubCartService{},
+		CategorySvc:  &stubCategoryService{},
+		CustomerSvc:  &stubCustomerService{},
+		AnonymousSvc: &stubAnonymousService{},
+		AdminSvc:     &stubAdminService{token: "admin-token"},
+	}, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+		token  string
+		status int
+	}{
+		{name: "list without token", method: http.MethodGet, url: "/proj-key/products", status: http.StatusOK},
+		{name: "create without token", method: http.MethodPost, url: "/proj-key/products", body: `{"key":"demo"}`, status: http.StatusUnauthorized},
+		{name: "create with customer token", method: http.MethodPost, url: "/proj-key/products", body: `{"key":"demo"}`, token: "customer-token", status: http.StatusForbidden},
+		{name: "create", method: http.MethodPost, url: "/proj-key/products", body: `{"key":"demo"}`, token: "admin-token", status: http.StatusCreated},
+		{name: "update without token", method: http.MethodPost, url: "/proj-key/products/p1", body: `{"version":1,"actions":[]}`, status: http.StatusUnauthorized},
+		{name: "update", method: http.MethodPost, url: "/proj-key/products/p1", body: `{"version":1,"actions":[]}`, token: "admin-token", status: http.StatusOK},
+		{name: "search without token", method: http.MethodPost, url: "/proj-key/products/search", body: `{}`, status: http.StatusOK},
+	}
+	for _, tc := range cases {
+		var body io.Reader
+		if tc.body != "" {
+			body = strings.NewReader(tc.body)
+		}
+		req := httptest.NewRequest(tc.method, tc.url, body)
+		req.Header.Set("Content-Type", "application/json")
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+type stubProductTypeService struct {
+	types []domain.ProductType
+}
+
+func (s *stubProductTypeService) ListPage(_ context.Context, _ string, _, _ int) ([]domain.ProductType, int, error) {
+	return s.types, len(s.types), nil
+}
+
+func (s *stubProductTypeService) Get(_ context.Context, _ string, id string) (*domain.ProductType, error) {
+	for i := range s.types {
+		if s.types[i].ID == id {
+			return &s.types[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubProductTypeService) Create(_ context.Context, _ string, draft producttypesvc.ProductTypeDraft) (*domain.ProductType, error) {
+	t := domain.ProductType{ID: "pt-" + draft.Key, Key: draft.Key, Name: draft.Name, Version: 1}
+	s.types = append(s.types, t)
+	return &t, nil
+}
+
+func TestProductTypeHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
+	router, err := buildRouter(logDiscard(), nil, Deps{
+		ProjectRepo:    &stubProjectRepo{project: proj},
+		ProductSvc:     &stubProductService{},
+		CartSvc:        &stubCartService{},
+		CategorySvc:    &stubCategoryService{},
+		CustomerSvc:    &stubCustomerService{},
+		AnonymousSvc:   &stubAnonymousService{},
+		AdminSvc:       &stubAdminService{token: "admin-token"},
+		ProductTypeSvc: &stubProductTypeService{types: []domain.ProductType{{ID: "pt-1", Key: "plant", Name: "Plant", Version: 1}}},
+	}, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		method   string
+		url      string
+		body     string
+		token    string
+		status   int
+		contains string
+	}{
+		{name: "list without token", method: http.MethodGet, url: "/proj-key/product-types", status: http.StatusOK, contains: `"id":"pt-1"`},
+		{name: "get without token", method: http.MethodGet, url: "/proj-key/product-types/pt-1", status: http.StatusOK, contains: `"name":"Plant"`},
+		{name: "create without token", method: http.MethodPost, url: "/proj-key/product-types", body: `{"key":"pot","name":"Pot"}`, status: http.StatusUnauthorized},
+		{name: "create with customer token", method: http.MethodPost, url: "/proj-key/product-types", body: `{"key":"pot","name":"Pot"}`, token: "customer-token", status: http.StatusForbidden},
+		{name: "create", method: http.MethodPost, url: "/proj-key/product-types", body: `{"key":"pot","name":"Pot"}`, token: "admin-token", status: http.StatusCreated, contains: `"id":"pt-pot"`},
+	}
+	for _, tc := range cases {
+		var body io.Reader
+		if tc.body != "" {
+			body = strings.NewReader(tc.body)
+		}
+		req := httptest.NewRequest(tc.method, tc.url, body)
+		req.Header.Set("Content-Type", "application/json")
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		if tc.contains != "" && !strings.Contains(rec.Body.String(), tc.contains) {
+			t.Fatalf("%s: expected %s in %s", tc.name, tc.contains, rec.Body.String())
+		}
+	}
+}
+
+func TestProductProjectionsHandler_Lookups(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
+	published := testProduct("p1", "demo", "Demo", "SKU1", 100, "EUR")
+	published.Current.Slug = domain.LocalizedString{"en": "demo-shirt", "de-DE": "demo-hemd"}
+	published.Staged = published.Current
+	draft := testProduct("p2", "draft", "Draft", "SKU2", 100, "EUR")
+	draft.Published = false
+	draft.Staged.Slug = domain.LocalizedString{"en": "draft-shirt"}
+	router, err := buildRouter(logDiscard(), nil, Deps{
+		ProjectRepo:  &stubProjectRepo{project: proj},
+		ProductSvc:   &stubProductService{listResult: []domain.Product{published, draft}},
+		CartSvc:      &stubCartService{},
+		CategorySvc:  &stubCategoryService{},
+		CustomerSvc:  &stubCustomerService{},
+		AnonymousSvc: &stubAnonymousService{},
+	}, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		url      string
+		status   int
+		contains []string
+	}{
+		{name: "list published", url: "/proj-key/product-projections", status: http.StatusOK, contains: []string{`"total":1`, `"id":"p1"`}},
+		{name: "list staged", url: "/proj-key/product-projections?staged=true", status: http.StatusOK, contains: []string{`"total":2`, `"id":"p2"`}},
+		{name: "slug", url: "/proj-key/product-projections?where=" + url.QueryEscape(`slug(en="demo-shirt")`), status: http.StatusOK, contains: []string{`"count":1`, `"id":"p1"`}},
+		{name: "slug other locale", url: "/proj-key/product-projections?where=" + url.QueryEscape(`slug(de-DE="demo-hemd")`), status: http.StatusOK, contains: []string{`"count":1`}},
+		{name: "slug no match", url: "/proj-key/product-projections?where=" + url.QueryEscape(`slug(en="nope")`), status: http.StatusOK, contains: []string{`"count":0`, `"results":[]`}},
+		{name: "unpublished slug", url: "/proj-key/product-projections?where=" + url.QueryEscape(`slug(en="draft-shirt")`), status: http.StatusOK, contains: []string{`"count":0`}},
+		{name: "staged slug", url: "/proj-key/product-projections?staged=true&where=" + url.QueryEscape(`slug(en="draft-shirt")`), status: http.StatusOK, contains: []string{`"id":"p2"`}},
+		{name: "key predicate", url: "/proj-key/product-projections?where=" + url.QueryEscape(`key="demo"`), status: http.StatusOK, contains: []string{`"id":"p1"`}},
+		{name: "unsupported predicate", url: "/proj-key/product-projections?where=" + url.QueryEscape(`name(en="Demo")`), status: http.StatusBadRequest},
+		{name: "by key", url: "/proj-key/product-projections/key=demo", status: http.StatusOK, contains: []string{`"slug":{`, `"demo-shirt"`}},
+		{name: "unpublished by key", url: "/proj-key/product-projections/key=draft", status: http.StatusNotFound},
+		{name: "staged by key", url: "/proj-key/product-projections/key=draft?staged=true", status: http.StatusOK, contains: []string{`"draft-shirt"`}},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.url, nil))
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		for _, want := range tc.contains {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Fatalf("%s: expected %s in %s", tc.name, want, rec.Body.String())
+			}
+		}
+	}
+}
+
 // CT-style prefix is the default path shape; covered by the list test above.
 
 func TestProductsHandler_Search(t *testing.T) {
@@ -312,13 +783,13 @@ func TestProductsHandler_Search(t *testing.T) {
 	projectRepo := &stubProjectRepo{project: proj}
 	productSvc := &stubProductService{
 		listResult: []domain.Product{
-			{ID: "b-id", ProjectID: proj.ID, Name: "Beta", Key: "b", SKU: "SKU2", PriceCents: 100, Currency: "EUR", Attributes: map[string]interface{}{"categories": []string{"cactus"}}},
-			{ID: "a-id", ProjectID: proj.ID, Name: "Alpha", Key: "a", SKU: "SKU1", PriceCents: 200, Currency: "EUR", Attributes: map[string]interface{}{"categories": []string{"cat-2"}}},
+			testProduct("b-id", "b", "Beta", "SKU2", 100, "EUR", "cactus"),
+			testProduct("a-id", "a", "Alpha", "SKU1", 200, "EUR", "cat-2"),
 		},
 	}
 	cartSvc := &stubCartService{}
 	categorySvc := &stubCategoryService{
-		list: []domain.Category{{ID: "cat-uuid-1", Key: "cactus", Name: "Cactus", ProjectID: proj.ID}},
+		list: []domain.Category{{ID: "cat-uuid-1", Key: "cactus", Name: domain.LocalizedString{"en": "Cactus"}, ProjectID: proj.ID}},
 	}
 	customerSvc := &stubCustomerService{customer: &domain.Customer{ID: "cust-id", ProjectID: proj.ID}}
 	router, err := buildRouter(logDiscard(), nil, Deps{
@@ -328,7 +799,7 @@ func TestProductsHandler_Search(t *testing.T) {
 		CategorySvc:  categorySvc,
 		CustomerSvc:  customerSvc,
 		AnonymousSvc: &stubAnonymousService{},
-	})
+	}, "")
 	if err != nil {
 		t.Fatalf("build router: %v", err)
 	}
@@ -354,8 +825,8 @@ func TestProductsHandler_SearchSortByPriceDesc(t *testing.T) {
 	projectRepo := &stubProjectRepo{project: proj}
 	productSvc := &stubProductService{
 		listResult: []domain.Product{
-			{ID: "cheap", ProjectID: proj.ID, Name: "Cheap", Key: "c", SKU: "SKU1", PriceCents: 100, Currency: "EUR"},
-			{ID: "exp", ProjectID: proj.ID, Name: "Expensive", Key: "e", SKU: "SKU2", PriceCents: 500, Currency: "EUR"},
+			testProduct("cheap", "c", "Cheap", "SKU1", 100, "EUR"),
+			testProduct("exp", "e", "Expensive", "SKU2", 500, "EUR"),
 		},
 	}
 	cartSvc := &stubCartService{}
@@ -368,7 +839,7 @@ func TestProductsHandler_SearchSortByPriceDesc(t *testing.T) {
 		CategorySvc:  categorySvc,
 		CustomerSvc:  customerSvc,
 		AnonymousSvc: &stubAnonymousService{},
-	})
+	}, "")
 	if err != nil {
 		t.Fatalf("build router: %v", err)
 	}
@@ -402,8 +873,8 @@ func TestCategoriesHandler_List(t *testing.T) {
 	cartSvc := &stubCartService{}
 	categorySvc := &stubCategoryService{
 		list: []domain.Category{
-			{ID: "cat-1", Key: "cat-1", Name: "Cat 1", Slug: "cat-1", ProjectID: proj.ID},
-			{ID: "cat-2", Key: "cat-2", Name: "Cat 2", Slug: "cat-2", ProjectID: proj.ID},
+			{ID: "cat-1", Key: "cat-1", Name: domain.LocalizedString{"en": "Cat 1"}, Slug: domain.LocalizedString{"en": "cat-1"}, ProjectID: proj.ID},
+			{ID: "cat-2", Key: "cat-2", Name: domain.LocalizedString{"en": "Cat 2"}, Slug: domain.LocalizedString{"en": "cat-2"}, ProjectID: proj.ID},
 		},
 	}
 	customerSvc := &stubCustomerService{customer: &domain.Customer{ID: "cust-id", ProjectID: proj.ID}}
@@ -415,7 +886,7 @@ func TestCategoriesHandler_List(t *testing.T) {
 		CategorySvc:  categorySvc,
 		CustomerSvc:  customerSvc,
 		AnonymousSvc: &stubAnonymousService{},
-	})
+	}, "")
 	if err != nil {
 		t.Fatalf("build router: %v", err)
 	}
@@ -432,6 +903,1874 @@ func TestCategoriesHandler_List(t *testing.T) {
 	}
 }
 
-func logDiscard() *log.Logger {
-	return log.New(io.Discard, "", 0)
+func TestCategoriesHandler_Lookups(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
+	categorySvc := &stubCategoryService{
+		list: []domain.Category{
+			{ID: "cat-1", Key: "shirts", Name: domain.LocalizedString{"en": "Shirts"}, Slug: domain.LocalizedString{"en": "shirts", "de": "hemden"}, ProjectID: proj.ID},
+			{ID: "cat-2", Key: "pants", Name: domain.LocalizedString{"en": "Pants"}, Slug: domain.LocalizedString{"en": "pants"}, ProjectID: proj.ID},
+		},
+	}
+	router, err := buildRouter(logDiscard(), nil, Deps{
+		ProjectRepo:  &stubProjectRepo{project: proj},
+		ProductSvc:   &stubProductService{},
+		CartSvc:      &stubCartService{},
+		CategorySvc:  categorySvc,
+		CustomerSvc:  &stubCustomerService{},
+		AnonymousSvc: &stubAnonymousService{},
+	}, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		url      string
+		status   int
+		contains []string
+	}{
+		{name: "by id", url: "/proj-key/categories/cat-2", status: http.StatusOK, contains: []string{`"Pants"`}},
+		{name: "by key", url: "/proj-key/categories/key=shirts", status: http.StatusOK, contains: []string{`"id":"cat-1"`}},
+		{name: "missing key", url: "/proj-key/categories/key=hats", status: http.StatusNotFound},
+		{name: "slug", url: "/proj-key/categories?where=" + url.QueryEscape(`slug(de="hemden")`), status: http.StatusOK, contains: []string{`"count":1`, `"id":"cat-1"`}},
+		{name: "slug no match", url: "/proj-key/categories?where=" + url.QueryEscape(`slug(de="hosen")`), status: http.StatusOK, contains: []string{`"count":0`}},
+		{name: "key predicate", url: "/proj-key/categories?where=" + url.QueryEscape(`key="pants"`), status: http.StatusOK, contains: []string{`"id":"cat-2"`}},
+		{name: "bad predicate", url: "/proj-key/categories?where=" + url.QueryEscape(`slug(de=hemden)`), status: http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.url, nil))
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		for _, want := range tc.contains {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Fatalf("%s: expected %s in %s", tc.name, want, rec.Body.String())
+			}
+		}
+	}
+}
+
+type stubProductDiscountService struct {
+	discounts []domain.ProductDiscount
+	err       error
+}
+
+func (s *stubProductDiscountService) ListPage(_ context.Context, _ string, _, _ int) ([]domain.ProductDiscount, int, error) {
+	return s.discounts, len(s.discounts), s.err
+}
+
+func (s *stubProductDiscountService) Get(_ context.Context, _ string, id string) (*domain.ProductDiscount, error) {
+	for i := range s.discounts {
+		if s.discounts[i].ID == id {
+			return &s.discounts[i], s.err
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubProductDiscountService) GetByKey(_ context.Context, _ string, key string) (*domain.ProductDiscount, error) {
+	for i := range s.discounts {
+		if s.discounts[i].Key == key {
+			return &s.discounts[i], s.err
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubProductDiscountService) Create(_ context.Context, _ string, draft productdiscountsvc.ProductDiscountDraft) (*domain.ProductDiscount, error) {
+	d := domain.ProductDiscount{ID: "new", Key: draft.Key, Name: draft.Name, Value: draft.Value, Predicate: draft.Predicate, SortOrder: draft.SortOrder, IsActive: true, Version: 1}
+	s.discounts = append(s.discounts, d)
+	return &d, s.err
+}
+
+func (s *stubProductDiscountService) Update(ctx context.Context, projectID, id string, in productdiscountsvc.UpdateInput) (*domain.ProductDiscount, error) {
+	d, err := s.Get(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	d.Version++
+	return d, s.err
+}
+
+func (s *stubProductDiscountService) Delete(ctx context.Context, projectID, id string, version int) (*domain.ProductDiscount, error) {
+	d, err := s.Get(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.Version != version {
+		return nil, domain.ErrConcurrentModification
+	}
+	return d, s.err
+}
+
+func (s *stubProductDiscountService) ApplyToProducts(_ context.Context, _ string, products []domain.Product) error {
+	productdiscountsvc.Apply(s.discounts, products, time.Now())
+	return s.err
+}
+
+func TestProductDiscountsHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
+	discountSvc := &stubProductDiscountService{discounts: []domain.ProductDiscount{{
+		ID: "disc-1", Key: "spring", Version: 1, IsActive: true, SortOrder: "0.5",
+		Name:      domain.LocalizedString{"en": "Spring"},
+		Predicate: `sku = "SKU1"`,
+		Value:     domain.ProductDiscountValue{Type: domain.ProductDiscountRelative, Permyriad: 1500},
+	}}}
+	router, err := buildRouter(logDiscard(), nil, Deps{
+		ProjectRepo:        &stubProjectRepo{project: proj},
+		ProductSvc:         &stubProductService{listResult: []domain.Product{testProduct("p1", "demo", "Demo", "SKU1", 1000, "EUR"), testProduct("p2", "other", "Other", "SKU2", 1000, "EUR")}},
+		CartSvc:            &stubCartService{},
+		CategorySvc:        &stubCategoryService{},
+		CustomerSvc:        &stubCustomerService{},
+		AnonymousSvc:       &stubAnonymousService{},
+		AdminSvc:           &stubAdminService{token: "admin-token"},
+		ProductDiscountSvc: discountSvc,
+	}, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		method   string
+		url      string
+		body     string
+		token    string
+		status   int
+		contains []string
+	}{
+		{name: "list without token", method: http.MethodGet, url: "/proj-key/product-discounts", status: http.StatusUnauthorized},
+		{name: "create with customer token", method: http.MethodPost, url: "/proj-key/product-discounts", token: "customer-token", body: `{"key":"abs"}`, status: http.StatusForbidden},
+		{name: "list", method: http.MethodGet, url: "/proj-key/product-discounts", token: "admin-token", status: http.StatusOK, contains: []string{`"total":1`, `"permyriad":1500`, `"references":[]`}},
+		{name: "get by key", method: http.MethodGet, url: "/proj-key/product-discounts/key=spring", token: "admin-token", status: http.StatusOK, contains: []string{`"id":"disc-1"`}},
+		{name: "missing", method: http.MethodGet, url: "/proj-key/product-discounts/nope", token: "admin-token", status: http.StatusNotFound},
+		{name: "create", method: http.MethodPost, url: "/proj-key/product-discounts", token: "admin-token", status: http.StatusCreated,
+			body:     `{"key":"abs","name":{"en":"Abs"},"value":{"type":"absolute","money":[{"currencyCode":"EUR","centAmount":100}]},"predicate":"1 = 1","sortOrder":"0.1"}`,
+			contains: []string{`"money":[{"type":"centPrecision","currencyCode":"EUR","centAmount":100,"fractionDigits":2}]`}},
+		{name: "update stale", method: http.MethodPost, url: "/proj-key/product-discounts/disc-1", token: "admin-token", body: `{"version":7,"actions":[{"action":"changeIsActive","isActive":false}]}`, status: http.StatusConflict},
+		{name: "delete without version", method: http.MethodDelete, url: "/proj-key/product-discounts/disc-1", token: "admin-token", status: http.StatusBadRequest},
+		{name: "delete", method: http.MethodDelete, url: "/proj-key/product-discounts/key=spring?version=1", token: "admin-token", status: http.StatusOK},
+		{name: "discounted product", method: http.MethodGet, url: "/proj-key/products/key=demo", status: http.StatusOK,
+			contains: []string{`"discounted":{"value":{"type":"centPrecision","currencyCode":"EUR","centAmount":850,"fractionDigits":2},"discount":{"typeId":"product-discount","id":"disc-1"}}`}},
+		{name: "discounted projection", method: http.MethodGet, url: "/proj-key/product-projections", status: http.StatusOK, contains: []string{`"centAmount":850`}},
+	}
+	for _, tc := range cases {
+		var body io.Reader
+		if tc.body != "" {
+			body = strings.NewReader(tc.body)
+		}
+		req := httptest.NewRequest(tc.method, tc.url, body)
+		req.Header.Set("Content-Type", "application/json")
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		for _, want := range tc.contains {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Fatalf("%s: expected %s in %s", tc.name, want, rec.Body.String())
+			}
+		}
+	}
+}
+
+type stubCartDiscountService struct {
+	discounts []domain.CartDiscount
+	err       error
+}
+
+func (s *stubCartDiscountService) ListPage(_ context.Context, _ string, _, _ int) ([]domain.CartDiscount, int, error) {
+	return s.discounts, len(s.discounts), s.err
+}
+
+func (s *stubCartDiscountService) Get(_ context.Context, _ string, id string) (*domain.CartDiscount, error) {
+	for i := range s.discounts {
+		if s.discounts[i].ID == id {
+			return &s.discounts[i], s.err
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubCartDiscountService) GetByKey(_ context.Context, _ string, key string) (*domain.CartDiscount, error) {
+	for i := range s.discounts {
+		if s.discounts[i].Key == key {
+			return &s.discounts[i], s.err
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubCartDiscountService) Create(_ context.Context, _ string, draft cartdiscountsvc.CartDiscountDraft) (*domain.CartDiscount, error) {
+	d := domain.CartDiscount{ID: "new", Key: draft.Key, Name: draft.Name, Value: draft.Value.ToValue(), CartPredicate: draft.CartPredicate,
+		Target: draft.Target, SortOrder: draft.SortOrder, IsActive: true, StackingMode: domain.StackingModeStacking, Version: 1}
+	s.discounts = append(s.discounts, d)
+	return &d, s.err
+}
+
+func (s *stubCartDiscountService) Update(ctx context.Context, projectID, id string, in cartdiscountsvc.UpdateInput) (*domain.CartDiscount, error) {
+	d, err := s.Get(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	d.Version++
+	return d, s.err
+}
+
+func (s *stubCartDiscountService) Delete(ctx context.Context, projectID, id string, version int) (*domain.CartDiscount, error) {
+	d, err := s.Get(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.Version != version {
+		return nil, domain.ErrConcurrentModification
+	}
+	return d, s.err
+}
+
+type stubDiscountCodeService struct {
+	codes []domain.DiscountCode
+	err   error
+}
+
+func (s *stubDiscountCodeService) ListPage(_ context.Context, _ string, _, _ int) ([]domain.DiscountCode, int, error) {
+	return s.codes, len(s.codes), s.err
+}
+
+func (s *stubDiscountCodeService) Get(_ context.Context, _ string, id string) (*domain.DiscountCode, error) {
+	for i := range s.codes {
+		if s.codes[i].ID == id {
+			return &s.codes[i], s.err
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubDiscountCodeService) GetByKey(_ context.Context, _ string, key string) (*domain.DiscountCode, error) {
+	for i := range s.codes {
+		if s.codes[i].Key == key {
+			return &s.codes[i], s.err
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubDiscountCodeService) Create(_ context.Context, _ string, draft discountcodesvc.DiscountCodeDraft) (*domain.DiscountCode, error) {
+	if draft.Code == "" {
+		return nil, errors.New("code required")
+	}
+	c := domain.DiscountCode{ID: "new", Code: draft.Code, IsActive: true, Version: 1}
+	for _, ref := range draft.CartDiscounts {
+		c.CartDiscountIDs = append(c.CartDiscountIDs, ref.ID)
+	}
+	s.codes = append(s.codes, c)
+	return &c, s.err
+}
+
+func (s *stubDiscountCodeService) Update(ctx context.Context, projectID, id string, in discountcodesvc.UpdateInput) (*domain.DiscountCode, error) {
+	c, err := s.Get(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	c.Version++
+	return c, s.err
+}
+
+func (s *stubDiscountCodeService) Delete(ctx context.Context, projectID, id string, version int) (*domain.DiscountCode, error) {
+	c, err := s.Get(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.Version != version {
+		return nil, domain.ErrConcurrentModification
+	}
+	return c, s.err
+}
+
+func TestCartDiscountAndDiscountCodeHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
+	router, err := buildRouter(logDiscard(), nil, Deps{
+		ProjectRepo:  &stubProjectRepo{project: proj},
+		ProductSvc:   &stubProductService{},
+		CartSvc:      &stubCartService{},
+		CategorySvc:  &stubCategoryService{},
+		CustomerSvc:  &stubCustomerService{},
+		AnonymousSvc: &stubAnonymousService{},
+		AdminSvc:     &stubAdminService{token: "admin-token"},
+		CartDiscountSvc: &stubCartDiscountService{discounts: []domain.CartDiscount{{
+			ID: "cd-1", Key: "spring", Version: 1, IsActive: true, SortOrder: "0.5", StackingMode: domain.StackingModeStacking,
+			Name:          domain.LocalizedString{"en": "Spring"},
+			CartPredicate: "1 = 1",
+			Value:         domain.CartDiscountValue{Type: domain.CartDiscountRelative, Permyriad: 1000},
+			Target:        &domain.CartDiscountTarget{Type: domain.CartDiscountTargetLineItems, Predicate: `sku = "SKU1"`},
+		}}},
+		DiscountCodeSvc: &stubDiscountCodeService{codes: []domain.DiscountCode{{
+			ID: "code-1", Key: "spring-code", Code: "SPRING", Version: 1, IsActive: true, CartDiscountIDs: []string{"cd-1"},
+		}}},
+	}, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		method   string
+		url      string
+		body     string
+		token    string
+		status   int
+		contains []string
+	}{
+		{name: "list cart discounts without token", method: http.MethodGet, url: "/proj-key/cart-discounts", status: http.StatusUnauthorized},
+		{name: "create discount code with customer token", method: http.MethodPost, url: "/proj-key/discount-codes", token: "customer-token", body: `{"code":"SUMMER"}`, status: http.StatusForbidden},
+		{name: "list cart discounts", method: http.MethodGet, url: "/proj-key/cart-discounts", token: "admin-token", status: http.StatusOK,
+			contains: []string{`"total":1`, `"target":{"type":"lineItems","predicate":"sku = \"SKU1\""}`, `"stackingMode":"Stacking"`}},
+		{name: "get cart discount by key", method: http.MethodGet, url: "/proj-key/cart-discounts/key=spring", token: "admin-token", status: http.StatusOK, contains: []string{`"id":"cd-1"`}},
+		{name: "missing cart discount", method: http.MethodGet, url: "/proj-key/cart-discounts/nope", token: "admin-token", status: http.StatusNotFound},
+		{name: "create gift cart discount", method: http.MethodPost, url: "/proj-key/cart-discounts", token: "admin-token", status: http.StatusCreated,
+			body:     `{"key":"gift","name":{"en":"Gift"},"value":{"type":"giftLineItem","product":{"typeId":"product","id":"p9"},"variantId":1},"cartPredicate":"1 = 1","sortOrder":"0.1"}`,
+			contains: []string{`"product":{"typeId":"product","id":"p9"}`}},
+		{name: "update stale cart discount", method: http.MethodPost, url: "/proj-key/cart-discounts/cd-1", token: "admin-token", body: `{"version":7,"actions":[{"action":"changeIsActive","isActive":false}]}`, status: http.StatusConflict},
+		{name: "delete cart discount without version", method: http.MethodDelete, url: "/proj-key/cart-discounts/cd-1", token: "admin-token", status: http.StatusBadRequest},
+		{name: "delete cart discount", method: http.MethodDelete, url: "/proj-key/cart-discounts/key=spring?version=1", token: "admin-token", status: http.StatusOK},
+		{name: "list discount codes", method: http.MethodGet, url: "/proj-key/discount-codes", token: "admin-token", status: http.StatusOK,
+			contains: []string{`"code":"SPRING"`, `"cartDiscounts":[{"typeId":"cart-discount","id":"cd-1"}]`}},
+		{name: "get discount code by key", method: http.MethodGet, url: "/proj-key/discount-codes/key=spring-code", token: "admin-token", status: http.StatusOK, contains: []string{`"id":"code-1"`}},
+		{name: "create discount code without code", method: http.MethodPost, url: "/proj-key/discount-codes", token: "admin-token", body: `{"cartDiscounts":[{"id":"cd-1"}]}`, status: http.StatusBadRequest},
+		{name: "create discount code", method: http.MethodPost, url: "/proj-key/discount-codes", token: "admin-token", body: `{"code":"SUMMER","cartDiscounts":[{"id":"cd-1"}]}`, status: http.StatusCreated,
+			contains: []string{`"code":"SUMMER"`}},
+		{name: "delete discount code", method: http.MethodDelete, url: "/proj-key/discount-codes/code-1?version=1", token: "admin-token", status: http.StatusOK},
+	}
+	for _, tc := range cases {
+		var body io.Reader
+		if tc.body != "" {
+			body = strings.NewReader(tc.body)
+		}
+		req := httptest.NewRequest(tc.method, tc.url, body)
+		req.Header.Set("Content-Type", "application/json")
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		for _, want := range tc.contains {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Fatalf("%s: expected %s in %s", tc.name, want, rec.Body.String())
+			}
+		}
+	}
+}
+
+type stubTaxCategoryService struct {
+	categories []domain.TaxCategory
+}
+
+func (s *stubTaxCategoryService) ListPage(_ context.Context, _ string, _, _ int) ([]domain.TaxCategory, int, error) {
+	return s.categories, len(s.categories), nil
+}
+
+func (s *stubTaxCategoryService) Get(_ context.Context, _ string, id string) (*domain.TaxCategory, error) {
+	for i := range s.categories {
+		if s.categories[i].ID == id {
+			return &s.categories[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubTaxCategoryService) GetByKey(_ context.Context, _ string, key string) (*domain.TaxCategory, error) {
+	for i := range s.categories {
+		if s.categories[i].Key == key {
+			return &s.categories[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubTaxCategoryService) Create(_ context.Context, _ string, draft taxcategorysvc.TaxCategoryDraft) (*domain.TaxCategory, error) {
+	if draft.Name == "" {
+		return nil, errors.New("name required")
+	}
+	c := domain.TaxCategory{ID: "new", Key: draft.Key, Name: draft.Name, Rates: draft.Rates, Version: 1}
+	s.categories = append(s.categories, c)
+	return &c, nil
+}
+
+func (s *stubTaxCategoryService) Update(ctx context.Context, projectID, id string, in taxcategorysvc.UpdateInput) (*domain.TaxCategory, error) {
+	c, err := s.Get(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	c.Version++
+	return c, nil
+}
+
+func (s *stubTaxCategoryService) Delete(ctx context.Context, projectID, id string, version int) (*domain.TaxCategory, error) {
+	c, err := s.Get(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.Version != version {
+		return nil, domain.ErrConcurrentModification
+	}
+	return c, nil
+}
+
+func TestTaxCategoryHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
+	router, err := buildRouter(logDiscard(), nil, Deps{
+		ProjectRepo:  &stubProjectRepo{project: proj},
+		ProductSvc:   &stubProductService{},
+		CartSvc:      &stubCartService{},
+		CategorySvc:  &stubCategoryService{},
+		CustomerSvc:  &stubCustomerService{},
+		AnonymousSvc: &stubAnonymousService{},
+		AdminSvc:     &stubAdminService{token: "admin-token"},
+		TaxCategorySvc: &stubTaxCategoryService{categories: []domain.TaxCategory{{
+			ID: "tc-1", Key: "standard", Name: "Standard", Version: 1,
+			Rates: []domain.TaxRate{{ID: "r1", Name: "19% MwSt", Amount: 0.19, IncludedInPrice: true, Country: "DE"}},
+		}}},
+	}, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		method   string
+		url      string
+		body     string
+		token    string
+		status   int
+		contains []string
+	}{
+		{name: "create tax category without token", method: http.MethodPost, url: "/proj-key/tax-categories", body: `{"key":"zero","name":"Zero"}`, status: http.StatusUnauthorized},
+		{name: "list tax categories", method: http.MethodGet, url: "/proj-key/tax-categories", status: http.StatusOK,
+			contains: []string{`"total":1`, `"rates":[{"id":"r1","name":"19% MwSt","amount":0.19,"includedInPrice":true,"country":"DE","subRates":[]}]`}},
+		{name: "get tax category by key", method: http.MethodGet, url: "/proj-key/tax-categories/key=standard", status: http.StatusOK, contains: []string{`"id":"tc-1"`}},
+		{name: "missing tax category", method: http.MethodGet, url: "/proj-key/tax-categories/nope", status: http.StatusNotFound},
+		{name: "create tax category without name", method: http.MethodPost, url: "/proj-key/tax-categories", token: "admin-token", body: `{"key":"zero"}`, status: http.StatusBadRequest},
+		{name: "create tax category", method: http.MethodPost, url: "/proj-key/tax-categories", token: "admin-token", body: `{"key":"zero","name":"Zero"}`, status: http.StatusCreated,
+			contains: []string{`"name":"Zero"`, `"rates":[]`}},
+		{name: "update stale tax category", method: http.MethodPost, url: "/proj-key/tax-categories/tc-1", token: "admin-token", body: `{"version":3,"actions":[{"action":"changeName","name":"x"}]}`, status: http.StatusConflict},
+		{name: "delete tax category without version", method: http.MethodDelete, url: "/proj-key/tax-categories/tc-1", token: "admin-token", status: http.StatusBadRequest},
+		{name: "delete tax category", method: http.MethodDelete, url: "/proj-key/tax-categories/key=standard?version=1", token: "admin-token", status: http.StatusOK},
+	}
+	for _, tc := range cases {
+		var body io.Reader
+		if tc.body != "" {
+			body = strings.NewReader(tc.body)
+		}
+		req := httptest.NewRequest(tc.method, tc.url, body)
+		req.Header.Set("Content-Type", "application/json")
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		for _, want := range tc.contains {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Fatalf("%s: expected %s in %s", tc.name, want, rec.Body.String())
+			}
+		}
+	}
+}
+
+type stubShippingMethodService struct {
+	methods []domain.ShippingMethod
+}
+
+func (s *stubShippingMethodService) ListPage(_ context.Context, _ string, _, _ int) ([]domain.ShippingMethod, int, error) {
+	return s.methods, len(s.methods), nil
+}
+
+func (s *stubShippingMethodService) Get(_ context.Context, _ string, id string) (*domain.ShippingMethod, error) {
+	for i := range s.methods {
+		if s.methods[i].ID == id {
+			return &s.methods[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubShippingMethodService) GetByKey(_ context.Context, _ string, key string) (*domain.ShippingMethod, error) {
+	for i := range s.methods {
+		if s.methods[i].Key == key {
+			return &s.methods[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubShippingMethodService) Create(_ context.Context, _ string, draft shippingmethodsvc.ShippingMethodDraft) (*domain.ShippingMethod, error) {
+	if draft.Name == "" {
+		return nil, errors.New("name required")
+	}
+	if draft.IsDefault {
+		return nil, domain.ErrAlreadyExists
+	}
+	m := domain.ShippingMethod{ID: "new", Key: draft.Key, Name: draft.Name, TaxCategoryID: draft.TaxCategory.ID, Version: 1}
+	s.methods = append(s.methods, m)
+	return &m, nil
+}
+
+func (s *stubShippingMethodService) Update(ctx context.Context, projectID, id string, in shippingmethodsvc.UpdateInput) (*domain.ShippingMethod, error) {
+	m, err := s.Get(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if m.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	m.Version++
+	return m, nil
+}
+
+func (s *stubShippingMethodService) Delete(ctx context.Context, projectID, id string, version int) (*domain.ShippingMethod, error) {
+	m, err := s.Get(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if m.Version != version {
+		return nil, domain.ErrConcurrentModification
+	}
+	return m, nil
+}
+
+type stubZoneService struct {
+	zones []domain.Zone
+}
+
+func (s *stubZoneService) ListPage(_ context.Context, _ string, _, _ int) ([]domain.Zone, int, error) {
+	return s.zones, len(s.zones), nil
+}
+
+func (s *stubZoneService) Get(_ context.Context, _ string, id string) (*domain.Zone, error) {
+	for i := range s.zones {
+		if s.zones[i].ID == id {
+			return &s.zones[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubZoneService) GetByKey(_ context.Context, _ string, key string) (*domain.Zone, error) {
+	for i := range s.zones {
+		if s.zones[i].Key == key {
+			return &s.zones[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubZoneService) Create(_ context.Context, _ string, draft zonesvc.ZoneDraft) (*domain.Zone, error) {
+	z := domain.Zone{ID: "zone-" + draft.Key, Key: draft.Key, Name: draft.Name, Version: 1}
+	s.zones = append(s.zones, z)
+	return &z, nil
+}
+
+func (s *stubZoneService) Update(ctx context.Context, projectID, id string, in zonesvc.UpdateInput) (*domain.Zone, error) {
+	z, err := s.Get(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if z.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	z.Version++
+	return z, nil
+}
+
+func (s *stubZoneService) Delete(ctx context.Context, projectID, id string, version int) (*domain.Zone, error) {
+	z, err := s.Get(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if z.Version != version {
+		return nil, domain.ErrConcurrentModification
+	}
+	return z, nil
+}
+
+func TestShippingMethodHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
+	standard := domain.ShippingMethod{
+		ID: "sm-1", Key: "standard", Name: "Standard", TaxCategoryID: "tc-1", Version: 1,
+		ZoneRates: []domain.ZoneRate{{ZoneID: "zone-1", ShippingRates: []domain.ShippingRate{{
+			Price:     domain.Money{CurrencyCode: "EUR", CentAmount: 499},
+			FreeAbove: &domain.Money{CurrencyCode: "EUR", CentAmount: 5000},
+		}}}},
+	}
+	router, err := buildRouter(logDiscard(), nil, Deps{
+		ProjectRepo:       &stubProjectRepo{project: proj},
+		ProductSvc:        &stubProductService{},
+		CartSvc:           &stubCartService{shippingMethods: []domain.ShippingMethod{standard}},
+		CategorySvc:       &stubCategoryService{},
+		CustomerSvc:       &stubCustomerService{},
+		AnonymousSvc:      &stubAnonymousService{},
+		AdminSvc:          &stubAdminService{token: "admin-token"},
+		ShippingMethodSvc: &stubShippingMethodService{methods: []domain.ShippingMethod{standard}},
+		ZoneSvc:           &stubZoneService{zones: []domain.Zone{{ID: "zone-1", Key: "eu", Name: "Europe", Version: 1}}},
+	}, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		method   string
+		url      string
+		body     string
+		token    string
+		status   int
+		contains []string
+	}{
+		{name: "list zones without token", method: http.MethodGet, url: "/proj-key/zones", status: http.StatusOK, contains: []string{`"key":"eu"`}},
+		{name: "create zone without token", method: http.MethodPost, url: "/proj-key/zones", body: `{"key":"uk","name":"UK"}`, status: http.StatusUnauthorized},
+		{name: "create zone with customer token", method: http.MethodPost, url: "/proj-key/zones", token: "customer-token", body: `{"key":"uk","name":"UK"}`, status: http.StatusForbidden},
+		{name: "create zone", method: http.MethodPost, url: "/proj-key/zones", token: "admin-token", body: `{"key":"uk","name":"UK"}`, status: http.StatusCreated, contains: []string{`"id":"zone-uk"`}},
+		{name: "delete zone", method: http.MethodDelete, url: "/proj-key/zones/key=eu?version=1", token: "admin-token", status: http.StatusOK},
+		{name: "create shipping method without token", method: http.MethodPost, url: "/proj-key/shipping-methods", body: `{"key":"express","name":"Express"}`, status: http.StatusUnauthorized},
+		{name: "list shipping methods", method: http.MethodGet, url: "/proj-key/shipping-methods", status: http.StatusOK,
+			contains: []string{`"total":1`, `"taxCategory":{"typeId":"tax-category","id":"tc-1"}`, `"zone":{"typeId":"zone","id":"zone-1"}`,
+				`"freeAbove":{"type":"centPrecision","currencyCode":"EUR","centAmount":5000,"fractionDigits":2}`}},
+		{name: "get shipping method by key", method: http.MethodGet, url: "/proj-key/shipping-methods/key=standard", status: http.StatusOK, contains: []string{`"id":"sm-1"`}},
+		{name: "missing shipping method", method: http.MethodGet, url: "/proj-key/shipping-methods/nope", status: http.StatusNotFound},
+		{name: "matching cart", method: http.MethodGet, url: "/proj-key/shipping-methods/matching-cart?cartId=cart-1", status: http.StatusOK, contains: []string{`"count":1`, `"id":"sm-1"`}},
+		{name: "matching cart without cart id", method: http.MethodGet, url: "/proj-key/shipping-methods/matching-cart", status: http.StatusBadRequest},
+		{name: "matching missing cart", method: http.MethodGet, url: "/proj-key/shipping-methods/matching-cart?cartId=nope", status: http.StatusNotFound},
+		{name: "create shipping method without name", method: http.MethodPost, url: "/proj-key/shipping-methods", token: "admin-token", body: `{"key":"express"}`, status: http.StatusBadRequest},
+		{name: "create second default", method: http.MethodPost, url: "/proj-key/shipping-methods", token: "admin-token", body: `{"name":"Express","isDefault":true}`, status: http.StatusConflict},
+		{name: "create shipping method", method: http.MethodPost, url: "/proj-key/shipping-methods", token: "admin-token", body: `{"key":"express","name":"Express","taxCategory":{"typeId":"tax-category","id":"tc-1"}}`, status: http.StatusCreated,
+			contains: []string{`"name":"Express"`, `"zoneRates":[]`}},
+		{name: "update stale shipping method", method: http.MethodPost, url: "/proj-key/shipping-methods/sm-1", token: "admin-token", body: `{"version":3,"actions":[{"action":"changeName","name":"x"}]}`, status: http.StatusConflict},
+		{name: "delete shipping method", method: http.MethodDelete, url: "/proj-key/shipping-methods/key=standard?version=1", token: "admin-token", status: http.StatusOK},
+	}
+	for _, tc := range cases {
+		var body io.Reader
+		if tc.body != "" {
+			body = strings.NewReader(tc.body)
+		}
+		req := httptest.NewRequest(tc.method, tc.url, body)
+		req.Header.Set("Content-Type", "application/json")
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		for _, want := range tc.contains {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Fatalf("%s: expected %s in %s", tc.name, want, rec.Body.String())
+			}
+		}
+	}
+}
+
+func TestToCTCart_ShippingInfo(t *testing.T) {
+	cart := domain.Cart{
+		ID: "cart-1", Currency: "EUR", TotalCents: 5250,
+		ShippingInfo: &domain.ShippingInfo{
+			ShippingMethodID:   "sm-1",
+			ShippingMethodName: "Standard",
+			Price:              domain.Money{CurrencyCode: "EUR", CentAmount: 500},
+			ShippingRate:       domain.ShippingRate{Price: domain.Money{CurrencyCode: "EUR", CentAmount: 500}},
+			DiscountedPrice: &domain.DiscountedShippingPrice{
+				Value:             domain.Money{CurrencyCode: "EUR", CentAmount: 250},
+				IncludedDiscounts: []domain.DiscountPortion{{DiscountID: "cd-1", Amount: domain.Money{CurrencyCode: "EUR", CentAmount: 250}}},
+			},
+			TaxCategoryID:       "tc-1",
+			ShippingMethodState: domain.ShippingMethodMatchesCart,
+		},
+	}
+	body, err := json.Marshal(toCTCart(cart, nil, "", localeSelector{}))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	for _, want := range []string{
+		`"shippingMethod":{"typeId":"shipping-method","id":"sm-1"}`,
+		`"shippingMethodName":"Standard"`,
+		`"discountedPrice":{"value":{"type":"centPrecision","currencyCode":"EUR","centAmount":250,"fractionDigits":2},"includedDiscounts":[{"discount":{"typeId":"cart-discount","id":"cd-1"}`,
+		`"shippingMethodState":"MatchesCart"`,
+		`"deliveries":[]`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Fatalf("expected %s in %s", want, body)
+		}
+	}
+}
+
+func TestToCTCart_MultipleShipping(t *testing.T) {
+	cart := domain.Cart{
+		ID: "cart-1", Currency: "EUR", ShippingMode: domain.ShippingModeMultiple,
+		Lines: []domain.CartLine{{
+			ID: "line-1", Quantity: 3,
+			ShippingDetails: &domain.ItemShippingDetails{Targets: []domain.ItemShippingTarget{{AddressKey: "friend", Quantity: 2, ShippingMethodKey: "gift"}}},
+		}},
+		ItemShippingAddresses: []domain.CustomerAddress{{Key: "friend", Country: "DE"}},
+		Shipping: []domain.Shipping{{
+			ShippingKey:     "gift",
+			ShippingInfo:    domain.ShippingInfo{ShippingMethodID: "sm-1", ShippingMethodState: domain.ShippingMethodMatchesCart},
+			ShippingAddress: domain.CustomerAddress{Country: "DE"},
+		}},
+	}
+	body, err := json.Marshal(toCTCart(cart, nil, "", localeSelector{}))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	for _, want := range []string{
+		`"shippingMode":"Multiple"`,
+		`"shipping":[{"shippingKey":"gift","shippingInfo":{`,
+		`"itemShippingAddresses":[{"id":"","key":"friend","country":"DE"}]`,
+		`"shippingDetails":{"targets":[{"addressKey":"friend","quantity":2,"shippingMethodKey":"gift"}],"valid":false}`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Fatalf("expected %s in %s", want, body)
+		}
+	}
+}
+
+type stubInventoryService struct {
+	entries []domain.InventoryEntry
+}
+
+func (s *stubInventoryService) ListPage(_ context.Context, _ string, _, _ int) ([]domain.InventoryEntry, int, error) {
+	return s.entries, len(s.entries), nil
+}
+
+func (s *stubInventoryService) Get(_ context.Context, _ string, id string) (*domain.InventoryEntry, error) {
+	for i := range s.entries {
+		if s.entries[i].ID == id {
+			return &s.entries[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubInventoryService) GetByKey(_ context.Context, _ string, key string) (*domain.InventoryEntry, error) {
+	for i := range s.entries {
+		if s.entries[i].Key == key {
+			return &s.entries[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubInventoryService) Create(_ context.Context, _ string, draft inventorysvc.InventoryEntryDraft) (*domain.InventoryEntry, error) {
+	if draft.SKU == "" {
+		return nil, errors.New("sku required")
+	}
+	for _, e := range s.entries {
+		if e.SKU == draft.SKU {
+			return nil, domain.ErrAlreadyExists
+		}
+	}
+	e := domain.InventoryEntry{ID: "new", Key: draft.Key, SKU: draft.SKU, QuantityOnStock: draft.QuantityOnStock, AvailableQuantity: draft.QuantityOnStock, Version: 1}
+	if draft.SupplyChannel != nil {
+		e.SupplyChannelID = draft.SupplyChannel.ID
+	}
+	s.entries = append(s.entries, e)
+	return &e, nil
+}
+
+func (s *stubInventoryService) Update(ctx context.Context, projectID, id string, in inventorysvc.UpdateInput) (*domain.InventoryEntry, error) {
+	e, err := s.Get(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if e.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	e.Version++
+	return e, nil
+}
+
+func (s *stubInventoryService) Delete(ctx context.Context, projectID, id string, version int) (*domain.InventoryEntry, error) {
+	e, err := s.Get(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if e.Version != version {
+		return nil, domain.ErrConcurrentModification
+	}
+	return e, nil
+}
+
+func (s *stubInventoryService) ApplyToProducts(_ context.Context, _ string, products []domain.Product) error {
+	inventorysvc.Apply(s.entries, products)
+	return nil
+}
+
+type stubOrderService struct {
+	orders []domain.Order
+}
+
+func (s *stubOrderService) Create(_ context.Context, _, customerID, _ string, draft ordersvc.OrderFromCartDraft) (*domain.Order, error) {
+	switch draft.ID {
+	case "cart-oos":
+		return nil, &domain.OutOfStockError{SKUs: []string{"SKU1"}}
+	case "cart-1":
+		o := domain.Order{ID: "order-new", Version: 1, OrderNumber: draft.OrderNumber, OrderState: domain.OrderStateOpen,
+			Cart: domain.Cart{ID: "cart-1", CustomerID: &customerID, Currency: "EUR", State: "ordered", InventoryMode: domain.InventoryModeReserveOnOrder}}
+		return &o, nil
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubOrderService) CreateAnonymous(_ context.Context, _, _, _ string, _ ordersvc.OrderFromCartDraft) (*domain.Order, error) {
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubOrderService) Get(_ context.Context, _, _, storeKey, id string) (*domain.Order, error) {
+	for i := range s.orders {
+		if s.orders[i].ID == id && (storeKey == "" || s.orders[i].Cart.StoreKey == storeKey) {
+			return &s.orders[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubOrderService) GetAnonymous(_ context.Context, _, _, _, _ string) (*domain.Order, error) {
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubOrderService) ListPage(_ context.Context, _, _, _ string, _, _ int) ([]domain.Order, int, error) {
+	return s.orders, len(s.orders), nil
+}
+
+func (s *stubOrderService) ListPageAnonymous(_ context.Context, _, _, _ string, _, _ int) ([]domain.Order, int, error) {
+	return nil, 0, nil
+}
+
+func TestInventoryAndOrderHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
+	entries := []domain.InventoryEntry{
+		{ID: "inv-1", Key: "sku1-stock", SKU: "SKU1", QuantityOnStock: 10, AvailableQuantity: 7, Version: 1},
+		{ID: "inv-2", SKU: "SKU1", SupplyChannelID: "channel-1", Version: 1},
+	}
+	customerID := "cust-id"
+	router, err := buildRouter(logDiscard(), nil, Deps{
+		ProjectRepo:  &stubProjectRepo{project: proj},
+		ProductSvc:   &stubProductService{listResult: []domain.Product{testProduct("p1", "demo", "Demo", "SKU1", 100, "EUR")}},
+		CartSvc:      &stubCartService{},
+		CategorySvc:  &stubCategoryService{},
+		CustomerSvc:  &stubCustomerService{customer: &domain.Customer{ID: customerID, ProjectID: proj.ID}},
+		AnonymousSvc: &stubAnonymousService{},
+		AdminSvc:     &stubAdminService{token: "admin-token"},
+		InventorySvc: &stubInventoryService{entries: entries},
+		OrderSvc: &stubOrderService{orders: []domain.Order{{ID: "order-1", Version: 1, OrderState: domain.OrderStateOpen,
+			Cart: domain.Cart{ID: "cart-0", CustomerID: &customerID, Currency: "EUR", State: "ordered"}}}},
+	}, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		method   string
+		url      string
+		body     string
+		token    string
+		status   int
+		contains []string
+	}{
+		{name: "create inventory with customer token", method: http.MethodPost, url: "/proj-key/inventory", body: `{"sku":"SKU2","quantityOnStock":3}`, status: http.StatusForbidden},
+		{name: "list inventory", method: http.MethodGet, url: "/proj-key/inventory", status: http.StatusOK,
+			contains: []string{`"total":2`, `"availableQuantity":7`, `"supplyChannel":{"typeId":"channel","id":"channel-1"}`}},
+		{name: "get inventory by key", method: http.MethodGet, url: "/proj-key/inventory/key=sku1-stock", status: http.StatusOK, contains: []string{`"id":"inv-1"`}},
+		{name: "missing inventory", method: http.MethodGet, url: "/proj-key/inventory/nope", status: http.StatusNotFound},
+		{name: "create inventory without sku", method: http.MethodPost, url: "/proj-key/inventory", token: "admin-token", body: `{"quantityOnStock":1}`, status: http.StatusBadRequest},
+		{name: "create duplicate inventory", method: http.MethodPost, url: "/proj-key/inventory", token: "admin-token", body: `{"sku":"SKU1"}`, status: http.StatusConflict},
+		{name: "create inventory", method: http.MethodPost, url: "/proj-key/inventory", token: "admin-token", body: `{"sku":"SKU2","quantityOnStock":3}`, status: http.StatusCreated,
+			contains: []string{`"sku":"SKU2"`, `"quantityOnStock":3`, `"availableQuantity":3`}},
+		{name: "update stale inventory", method: http.MethodPost, url: "/proj-key/inventory/inv-1", token: "admin-token", body: `{"version":3,"actions":[{"action":"addQuantity","quantity":1}]}`, status: http.StatusConflict},
+		{name: "delete inventory", method: http.MethodDelete, url: "/proj-key/inventory/key=sku1-stock?version=1", token: "admin-token", status: http.StatusOK},
+		{name: "product availability", method: http.MethodGet, url: "/proj-key/products", status: http.StatusOK,
+			contains: []string{`"availability":{"isOnStock":true,"availableQuantity":7,"channels":{"channel-1":{"isOnStock":false,"availableQuantity":0}}}`}},
+		{name: "order out of stock", method: http.MethodPost, url: "/proj-key/me/orders", body: `{"id":"cart-oos"}`, status: http.StatusBadRequest,
+			contains: []string{`"skus":["SKU1"]`}},
+		{name: "order missing cart", method: http.MethodPost, url: "/proj-key/me/orders", body: `{"id":"nope"}`, status: http.StatusNotFound},
+		{name: "create order", method: http.MethodPost, url: "/proj-key/me/orders", body: `{"id":"cart-1","orderNumber":"1001"}`, status: http.StatusCreated,
+			contains: []string{`"type":"Order"`, `"orderNumber":"1001"`, `"orderState":"Open"`, `"cart":{"typeId":"cart","id":"cart-1"}`, `"inventoryMode":"ReserveOnOrder"`}},
+		{name: "list my orders", method: http.MethodGet, url: "/proj-key/me/orders", status: http.StatusOK, contains: []string{`"total":1`, `"id":"order-1"`}},
+		{name: "get my order", method: http.MethodGet, url: "/proj-key/me/orders/order-1", status: http.StatusOK, contains: []string{`"id":"order-1"`}},
+	}
+	for _, tc := range cases {
+		var body io.Reader
+		if tc.body != "" {
+			body = strings.NewReader(tc.body)
+		}
+		req := httptest.NewRequest(tc.method, tc.url, body)
+		req.Header.Set("Content-Type", "application/json")
+		// Cases without a token use the customer's.
+		token := "token"
+		if tc.token != "" {
+			token = tc.token
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		for _, want := range tc.contains {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Fatalf("%s: expected %s in %s", tc.name, want, rec.Body.String())
+			}
+		}
+	}
+}
+
+type stubChannelService struct {
+	channels []domain.Channel
+}
+
+func (s *stubChannelService) ListPage(_ context.Context, _ string, _, _ int) ([]domain.Channel, int, error) {
+	return s.channels, len(s.channels), nil
+}
+
+func (s *stubChannelService) Get(_ context.Context, _ string, id string) (*domain.Channel, error) {
+	for i := range s.channels {
+		if s.channels[i].ID == id {
+			return &s.channels[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubChannelService) GetByKey(_ context.Context, _ string, key string) (*domain.Channel, error) {
+	for i := range s.channels {
+		if s.channels[i].Key == key {
+			return &s.channels[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubChannelService) Create(_ context.Context, _ string, draft channelsvc.ChannelDraft) (*domain.Channel, error) {
+	if draft.Key == "" {
+		return nil, errors.New("key required")
+	}
+	if _, err := s.GetByKey(context.Background(), "", draft.Key); err == nil {
+		return nil, domain.ErrAlreadyExists
+	}
+	ch := domain.Channel{ID: "new", Key: draft.Key, Roles: draft.Roles, Name: draft.Name, Version: 1}
+	s.channels = append(s.channels, ch)
+	return &ch, nil
+}
+
+func (s *stubChannelService) Update(ctx context.Context, projectID, id string, in channelsvc.UpdateInput) (*domain.Channel, error) {
+	ch, err := s.Get(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if in.Version != ch.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	return ch, nil
+}
+
+func (s *stubChannelService) Delete(ctx context.Context, projectID, id string, version int) (*domain.Channel, error) {
+	ch, err := s.Get(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if version != ch.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	return nil, domain.ErrReferenceExists
+}
+
+type stubStoreService struct {
+	stores []domain.Store
+	// assortments holds the product ids of stores with product selections.
+	assortments map[string]map[string]bool
+}
+
+func (s *stubStoreService) ListPage(_ context.Context, _ string, _, _ int) ([]domain.Store, int, error) {
+	return s.stores, len(s.stores), nil
+}
+
+func (s *stubStoreService) Get(_ context.Context, _ string, id string) (*domain.Store, error) {
+	for i := range s.stores {
+		if s.stores[i].ID == id {
+			return &s.stores[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubStoreService) GetByKey(_ context.Context, _ string, key string) (*domain.Store, error) {
+	for i := range s.stores {
+		if s.stores[i].Key == key {
+			return &s.stores[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubStoreService) Create(_ context.Context, _ string, draft storesvc.StoreDraft) (*domain.Store, error) {
+	if len(draft.Key) < 2 {
+		return nil, errors.New("key must be 2 to 256 letters, digits, '-' or '_'")
+	}
+	st := domain.Store{ID: "new", Key: draft.Key, Languages: draft.Languages, Version: 1}
+	return &st, nil
+}
+
+func (s *stubStoreService) Update(ctx context.Context, projectID, id string, _ storesvc.UpdateInput) (*domain.Store, error) {
+	return s.Get(ctx, projectID, id)
+}
+
+func (s *stubStoreService) Delete(ctx context.Context, projectID, id string, _ int) (*domain.Store, error) {
+	return s.Get(ctx, projectID, id)
+}
+
+func (s *stubStoreService) ProductIDs(_ context.Context, _, storeID string) (map[string]bool, error) {
+	return s.assortments[storeID], nil
+}
+
+type stubProductSelectionService struct {
+	selections []domain.ProductSelection
+	products   map[string][]string
+}
+
+func (s *stubProductSelectionService) ListPage(_ context.Context, _ string, _, _ int) ([]domain.ProductSelection, int, error) {
+	return s.selections, len(s.selections), nil
+}
+
+func (s *stubProductSelectionService) Get(_ context.Context, _ string, id string) (*domain.ProductSelection, error) {
+	for i := range s.selections {
+		if s.selections[i].ID == id {
+			return &s.selections[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubProductSelectionService) GetByKey(_ context.Context, _ string, key string) (*domain.ProductSelection, error) {
+	for i := range s.selections {
+		if s.selections[i].Key == key {
+			return &s.selections[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubProductSelectionService) ListProducts(_ context.Context, _, id string, _, _ int) ([]string, int, error) {
+	return s.products[id], len(s.products[id]), nil
+}
+
+func (s *stubProductSelectionService) Create(_ context.Context, _ string, draft productselectionsvc.ProductSelectionDraft) (*domain.ProductSelection, error) {
+	if len(draft.Name) == 0 {
+		return nil, errors.New("name required")
+	}
+	sel := domain.ProductSelection{ID: "new", Key: draft.Key, Name: draft.Name, Version: 1}
+	return &sel, nil
+}
+
+func (s *stubProductSelectionService) Update(ctx context.Context, projectID, id string, _ productselectionsvc.UpdateInput) (*domain.ProductSelection, error) {
+	return s.Get(ctx, projectID, id)
+}
+
+func (s *stubProductSelectionService) Delete(ctx context.Context, projectID, id string, _ int) (*domain.ProductSelection, error) {
+	return s.Get(ctx, projectID, id)
+}
+
+func TestChannelsAndStoresHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
+	customerID := "cust-id"
+	bilingual := testProduct("p1", "tee", "Tee", "SKU1", 100, "EUR")
+	bilingual.Current.Name = domain.LocalizedString{"en-GB": "Tee", "de-DE": "T-Shirt"}
+	entries := []domain.InventoryEntry{
+		{ID: "inv-1", SKU: "SKU1", SupplyChannelID: "ch-uk", AvailableQuantity: 2, Version: 1},
+		{ID: "inv-2", SKU: "SKU1", SupplyChannelID: "ch-eu", AvailableQuantity: 5, Version: 1},
+	}
+	router, err := buildRouter(logDiscard(), nil, Deps{
+		ProjectRepo: &stubProjectRepo{project: proj},
+		ProductSvc: &stubProductService{listResult: []domain.Product{
+			bilingual,
+			testProduct("p2", "mug", "Mug", "SKU2", 200, "EUR"),
+		}},
+		CartSvc: &stubCartService{carts: []domain.Cart{
+			{ID: "cart-uk", CustomerID: &customerID, Currency: "GBP", State: "active", StoreKey: "uk"},
+			{ID: "cart-eu", CustomerID: &customerID, Currency: "EUR", State: "active", StoreKey: "eu"},
+			{ID: "cart-uk-unit", CustomerID: &customerID, Currency: "GBP", State: "active", StoreKey: "uk", BusinessUnitKey: "acme"},
+		}},
+		CategorySvc:  &stubCategoryService{},
+		CustomerSvc:  &stubCustomerService{customer: &domain.Customer{ID: customerID, ProjectID: proj.ID}},
+		AnonymousSvc: &stubAnonymousService{},
+		AdminSvc:     &stubAdminService{token: "admin-token"},
+		InventorySvc: &stubInventoryService{entries: entries},
+		OrderSvc: &stubOrderService{orders: []domain.Order{{ID: "order-uk", Version: 1, OrderState: domain.OrderStateOpen,
+			Cart: domain.Cart{ID: "cart-0", CustomerID: &customerID, Currency: "GBP", State: "ordered", StoreKey: "uk"}}}},
+		ChannelSvc: &stubChannelService{channels: []domain.Channel{
+			{ID: "ch-uk", Key: "uk-warehouse", Roles: []string{domain.ChannelRoleInventorySupply}, Version: 1},
+		}},
+		StoreSvc: &stubStoreService{
+			stores: []domain.Store{
+				{ID: "store-uk", Key: "uk", Languages: []string{"en-GB"}, Countries: []string{"GB"}, SupplyChannelIDs: []string{"ch-uk"},
+					ProductSelections: []domain.StoreProductSelection{{ProductSelectionID: "sel-1", Active: true}}, Version: 1},
+				{ID: "store-eu", Key: "eu", Version: 1},
+			},
+			assortments: map[string]map[string]bool{"store-uk": {"p1": true}},
+		},
+		ProductSelectionSvc: &stubProductSelectionService{
+			selections: []domain.ProductSelection{{ID: "sel-1", Key: "uk-range", Name: domain.LocalizedString{"en": "UK range"}, ProductCount: 1, Version: 1}},
+			products:   map[string][]string{"sel-1": {"p1"}},
+		},
+	}, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		method   string
+		url      string
+		body     string
+		token    string
+		status   int
+		contains []string
+		excludes []string
+	}{
+		{name: "create channel with customer token", method: http.MethodPost, url: "/proj-key/channels", body: `{"key":"fr-warehouse"}`, status: http.StatusForbidden},
+		{name: "create store with customer token", method: http.MethodPost, url: "/proj-key/stores", body: `{"key":"fr"}`, status: http.StatusForbidden},
+		{name: "create store", method: http.MethodPost, url: "/proj-key/stores", token: "admin-token", body: `{"key":"fr","languages":["fr"]}`, status: http.StatusCreated, contains: []string{`"key":"fr"`}},
+		{name: "delete product selection with customer token", method: http.MethodDelete, url: "/proj-key/product-selections/key=uk-range?version=1", status: http.StatusForbidden},
+		{name: "create product selection", method: http.MethodPost, url: "/proj-key/product-selections", token: "admin-token", body: `{"key":"fr-range","name":{"en":"FR"}}`, status: http.StatusCreated, contains: []string{`"key":"fr-range"`}},
+		{name: "list channels", method: http.MethodGet, url: "/proj-key/channels", status: http.StatusOK,
+			contains: []string{`"total":1`, `"roles":["InventorySupply"]`}},
+		{name: "create channel without key", method: http.MethodPost, url: "/proj-key/channels", token: "admin-token", body: `{"roles":["Primary"]}`, status: http.StatusBadRequest},
+		{name: "create duplicate channel", method: http.MethodPost, url: "/proj-key/channels", token: "admin-token", body: `{"key":"uk-warehouse"}`, status: http.StatusConflict},
+		{name: "delete referenced channel", method: http.MethodDelete, url: "/proj-key/channels/key=uk-warehouse?version=1", token: "admin-token", status: http.StatusBadRequest,
+			contains: []string{"still referenced"}},
+		{name: "get store", method: http.MethodGet, url: "/proj-key/stores/key=uk", status: http.StatusOK,
+			contains: []string{`"languages":["en-GB"]`, `"countries":[{"code":"GB"}]`, `"supplyChannels":[{"typeId":"channel","id":"ch-uk"}]`,
+				`"productSelections":[{"productSelection":{"typeId":"product-selection","id":"sel-1"},"active":true}]`}},
+		{name: "missing store", method: http.MethodGet, url: "/proj-key/stores/key=fr", status: http.StatusNotFound},
+		{name: "get product selection", method: http.MethodGet, url: "/proj-key/product-selections/key=uk-range", status: http.StatusOK,
+			contains: []string{`"productCount":1`, `"mode":"Individual"`}},
+		{name: "product selection products", method: http.MethodGet, url: "/proj-key/product-selections/sel-1/products", status: http.StatusOK,
+			contains: []string{`"product":{"typeId":"product","id":"p1"}`}},
+		{name: "unknown store", method: http.MethodGet, url: "/proj-key/in-store/key=fr/product-projections", status: http.StatusNotFound},
+		{name: "store assortment", method: http.MethodGet, url: "/proj-key/in-store/key=uk/product-projections", status: http.StatusOK,
+			contains: []string{`"total":1`, `"name":{"en-GB":"Tee"}`, `"channels":{"ch-uk":`}, excludes: []string{`"de-DE"`, `"ch-eu"`}},
+		{name: "store without selections", method: http.MethodGet, url: "/proj-key/in-store/key=eu/product-projections", status: http.StatusOK,
+			contains: []string{`"total":2`}},
+		{name: "projection outside assortment", method: http.MethodGet, url: "/proj-key/in-store/key=uk/product-projections/key=mug", status: http.StatusNotFound},
+		{name: "projection in assortment", method: http.MethodGet, url: "/proj-key/in-store/key=uk/product-projections/key=tee", status: http.StatusOK,
+			contains: []string{`"id":"p1"`}},
+		{name: "create cart in store", method: http.MethodPost, url: "/proj-key/in-store/key=uk/me/carts", body: `{"currency":"GBP"}`, status: http.StatusCreated,
+			contains: []string{`"store":{"typeId":"store","key":"uk"}`}},
+		{name: "active cart in store", method: http.MethodGet, url: "/proj-key/in-store/key=eu/me/active-cart", status: http.StatusOK,
+			contains: []string{`"id":"cart-eu"`}},
+		{name: "update cart of another store", method: http.MethodPost, url: "/proj-key/in-store/key=eu/me/carts/cart-uk", body: `{"version":1,"actions":[]}`, status: http.StatusNotFound},
+		{name: "update cart in store", method: http.MethodPost, url: "/proj-key/in-store/key=uk/me/carts/cart-uk", body: `{"version":1,"actions":[]}`, status: http.StatusOK},
+		{name: "update unit cart in store", method: http.MethodPost, url: "/proj-key/in-store/key=uk/me/carts/cart-uk-unit", body: `{"version":1,"actions":[]}`, status: http.StatusForbidden},
+		{name: "delete unit cart in store", method: http.MethodDelete, url: "/proj-key/in-store/key=uk/me/carts/cart-uk-unit", status: http.StatusForbidden},
+		{name: "order in store", method: http.MethodGet, url: "/proj-key/in-store/key=uk/me/orders/order-uk", status: http.StatusOK,
+			contains: []string{`"store":{"typeId":"store","key":"uk"}`}},
+		{name: "order of another store", method: http.MethodGet, url: "/proj-key/in-store/key=eu/me/orders/order-uk", status: http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		var body io.Reader
+		if tc.body != "" {
+			body = strings.NewReader(tc.body)
+		}
+		req := httptest.NewRequest(tc.method, tc.url, body)
+		req.Header.Set("Content-Type", "application/json")
+		// Cases without a token use the customer's.
+		token := "token"
+		if tc.token != "" {
+			token = tc.token
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		for _, want := range tc.contains {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Fatalf("%s: expected %s in %s", tc.name, want, rec.Body.String())
+			}
+		}
+		for _, unwanted := range tc.excludes {
+			if strings.Contains(rec.Body.String(), unwanted) {
+				t.Fatalf("%s: unexpected %s in %s", tc.name, unwanted, rec.Body.String())
+			}
+		}
+	}
+}
+
+type stubCustomerGroupService struct {
+	groups []domain.CustomerGroup
+}
+
+func (s *stubCustomerGroupService) ListPage(_ context.Context, _ string, _, _ int) ([]domain.CustomerGroup, int, error) {
+	return s.groups, len(s.groups), nil
+}
+
+func (s *stubCustomerGroupService) Get(_ context.Context, _ string, id string) (*domain.CustomerGroup, error) {
+	for i := range s.groups {
+		if s.groups[i].ID == id {
+			return &s.groups[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubCustomerGroupService) GetByKey(_ context.Context, _ string, key string) (*domain.CustomerGroup, error) {
+	for i := range s.groups {
+		if s.groups[i].Key == key {
+			return &s.groups[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubCustomerGroupService) Create(_ context.Context, _ string, draft customergroupsvc.CustomerGroupDraft) (*domain.CustomerGroup, error) {
+	if draft.GroupName == "" {
+		return nil, errors.New("name required")
+	}
+	g := domain.CustomerGroup{ID: "new", Key: draft.Key, Name: draft.GroupName, Version: 1}
+	return &g, nil
+}
+
+func (s *stubCustomerGroupService) Update(ctx context.Context, projectID, id string, _ customergroupsvc.UpdateInput) (*domain.CustomerGroup, error) {
+	return s.Get(ctx, projectID, id)
+}
+
+func (s *stubCustomerGroupService) Delete(ctx context.Context, projectID, id string, _ int) (*domain.CustomerGroup, error) {
+	if _, err := s.Get(ctx, projectID, id); err != nil {
+		return nil, err
+	}
+	return nil, domain.ErrReferenceExists
+}
+
+func TestCustomerGroupsHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
+	mug := testProduct("p1", "mug", "Mug", "SKU1", 1000, "EUR")
+	mug.Current.MasterVariant.Prices = append(mug.Current.MasterVariant.Prices,
+		domain.Price{ID: "price-b2b", Value: domain.Money{CurrencyCode: "EUR", CentAmount: 800}, CustomerGroupID: "group-1"})
+	router, err := buildRouter(logDiscard(), nil, Deps{
+		ProjectRepo:  &stubProjectRepo{project: proj},
+		ProductSvc:   &stubProductService{listResult: []domain.Product{mug}},
+		CartSvc:      &stubCartService{},
+		CategorySvc:  &stubCategoryService{},
+		CustomerSvc:  &stubCustomerService{customer: &domain.Customer{ID: "cust-id", ProjectID: proj.ID, Version: 2, CustomerGroupID: "group-1"}},
+		AnonymousSvc: &stubAnonymousService{},
+		AdminSvc:     &stubAdminService{token: "admin-token"},
+		CustomerGroupSvc: &stubCustomerGroupService{groups: []domain.CustomerGroup{
+			{ID: "group-1", Key: "wholesale", Name: "Wholesale", Version: 1},
+		}},
+	}, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		method   string
+		url      string
+		body     string
+		token    string
+		status   int
+		contains []string
+		excludes []string
+	}{
+		{name: "create customer group without token", method: http.MethodPost, url: "/proj-key/customer-groups", body: `{"key":"vip","groupName":"VIP"}`, status: http.StatusUnauthorized},
+		{name: "list customer groups", method: http.MethodGet, url: "/proj-key/customer-groups", status: http.StatusOK,
+			contains: []string{`"total":1`, `"name":"Wholesale"`}},
+		{name: "get customer group by key", method: http.MethodGet, url: "/proj-key/customer-groups/key=wholesale", status: http.StatusOK,
+			contains: []string{`"id":"group-1"`}},
+		{name: "missing customer group", method: http.MethodGet, url: "/proj-key/customer-groups/key=retail", status: http.StatusNotFound},
+		{name: "create customer group", method: http.MethodPost, url: "/proj-key/customer-groups", token: "admin-token", body: `{"key":"vip","groupName":"VIP"}`, status: http.StatusCreated,
+			contains: []string{`"name":"VIP"`}},
+		{name: "create customer group without name", method: http.MethodPost, url: "/proj-key/customer-groups", token: "admin-token", body: `{"key":"vip"}`, status: http.StatusBadRequest},
+		{name: "delete customer group with customers", method: http.MethodDelete, url: "/proj-key/customer-groups/group-1?version=1", token: "admin-token", status: http.StatusBadRequest,
+			contains: []string{"still referenced"}},
+		{name: "set customer group", method: http.MethodPost, url: "/proj-key/customers/cust-id", token: "admin-token",
+			body:   `{"version":1,"actions":[{"action":"setCustomerGroup","customerGroup":{"typeId":"customer-group","key":"wholesale"}}]}`,
+			status: http.StatusOK,
+			contains: []string{`"version":2`, `"customerGroup":{"typeId":"customer-group","id":"group-1"}`,
+				`"customerGroupAssignments":[{"customerGroup":{"typeId":"customer-group","id":"group-1"}}]`}},
+		{name: "group price selection", method: http.MethodGet, url: "/proj-key/product-projections?priceCurrency=EUR&priceCustomerGroup=group-1", status: http.StatusOK,
+			contains: []string{`"price":{"id":"price-b2b"`, `"customerGroup":{"typeId":"customer-group","id":"group-1"}`}},
+		{name: "price selection without group", method: http.MethodGet, url: "/proj-key/product-projections?priceCurrency=eur", status: http.StatusOK,
+			contains: []string{`"price":{"id":"price-p1"`}},
+		{name: "no price selection", method: http.MethodGet, url: "/proj-key/product-projections", status: http.StatusOK,
+			excludes: []string{`"price":`}},
+	}
+	for _, tc := range cases {
+		var body io.Reader
+		if tc.body != "" {
+			body = strings.NewReader(tc.body)
+		}
+		req := httptest.NewRequest(tc.method, tc.url, body)
+		req.Header.Set("Content-Type", "application/json")
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		for _, want := range tc.contains {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Fatalf("%s: expected %s in %s", tc.name, want, rec.Body.String())
+			}
+		}
+		for _, unwanted := range tc.excludes {
+			if strings.Contains(rec.Body.String(), unwanted) {
+				t.Fatalf("%s: unexpected %s in %s", tc.name, unwanted, rec.Body.String())
+			}
+		}
+	}
+}
+
+func logDiscard() *log.Logger {
+	return log.New(io.Discard, "", 0)
+}
+
+func TestToCTCart_Discounts(t *testing.T) {
+	cart := domain.Cart{
+		ID: "cart-1", Currency: "EUR", TotalCents: 1620,
+		DiscountCodes: []domain.CartDiscountCode{{DiscountCodeID: "code-1", State: domain.DiscountCodeMatchesCart}},
+		DiscountOnTotal: &domain.DiscountOnTotal{
+			DiscountedAmount:  domain.Money{CurrencyCode: "EUR", CentAmount: 180},
+			IncludedDiscounts: []domain.DiscountPortion{{DiscountID: "cd-2", Amount: domain.Money{CurrencyCode: "EUR", CentAmount: 180}}},
+		},
+		RefusedGifts: []string{"cd-3"},
+		Lines: []domain.CartLine{{
+			ID: "l1", ProductID: "p1", VariantID: 1, Quantity: 2, UnitPriceCents: 1000, TotalCents: 1800,
+			Snapshot: map[string]interface{}{"sku": "SKU1", "currency": "EUR"},
+			DiscountedPricePerQuantity: []domain.DiscountedQuantity{{
+				Quantity: 2,
+				Value:    domain.Money{CurrencyCode: "EUR", CentAmount: 900},
+				IncludedDiscounts: []domain.DiscountPortion{
+					{DiscountID: "cd-1", Amount: domain.Money{CurrencyCode: "EUR", CentAmount: 100}},
+				},
+			}},
+		}},
+	}
+	out := toCTCart(cart, nil, "", localeSelector{})
+	if len(out.DiscountCodes) != 1 || out.DiscountCodes[0].DiscountCode.TypeID != "discount-code" || out.DiscountCodes[0].State != domain.DiscountCodeMatchesCart {
+		t.Fatalf("unexpected discount codes %+v", out.DiscountCodes)
+	}
+	if out.DiscountOnTotalPrice == nil || out.DiscountOnTotalPrice.DiscountedAmount.CentAmount != 180 {
+		t.Fatalf("unexpected discount on total %+v", out.DiscountOnTotalPrice)
+	}
+	discounted := out.LineItems[0].DiscountedPricePerQuantity
+	if len(discounted) != 1 || discounted[0].DiscountedPrice.Value.CentAmount != 900 ||
+		discounted[0].DiscountedPrice.IncludedDiscounts[0].Discount != (ctRef{TypeID: "cart-discount", ID: "cd-1"}) {
+		t.Fatalf("unexpected discounted price %+v", discounted)
+	}
+	if len(out.RefusedGifts) != 1 || out.RefusedGifts[0].ID != "cd-3" || out.DirectDiscounts == nil {
+		t.Fatalf("unexpected refused gifts %+v", out.RefusedGifts)
+	}
+}
+
+func TestToCTCart_Taxes(t *testing.T) {
+	eur := func(cents int64) domain.Money { return domain.Money{CurrencyCode: "EUR", CentAmount: cents} }
+	rate := domain.TaxRate{ID: "r1", Name: "19% MwSt", Amount: 0.19, IncludedInPrice: true, Country: "DE"}
+	taxed := &domain.TaxedPrice{
+		TotalNet: eur(1681), TotalGross: eur(2000), TotalTax: eur(319),
+		TaxPortions: []domain.TaxPortion{{Name: "19% MwSt", Rate: 0.19, Amount: eur(319)}},
+	}
+	cart := domain.Cart{
+		ID:                 "cart-1",
+		Currency:           "EUR",
+		TotalCents:         2000,
+		ShippingAddress:    &domain.CustomerAddress{Country: "DE", City: "Berlin"},
+		TaxRoundingMode:    domain.RoundingHalfUp,
+		TaxCalculationMode: domain.TaxCalculationUnitPriceLevel,
+		TaxedPrice:         taxed,
+		Lines: []domain.CartLine{{
+			ID: "line-1", ProductID: "p1", VariantID: 1, Quantity: 2, UnitPriceCents: 1000, TotalCents: 2000,
+			TaxRate: &rate, TaxedPrice: taxed,
+		}},
+	}
+	out := toCTCart(cart, nil, "", localeSelector{})
+	if out.TaxMode != domain.TaxModePlatform || out.TaxRoundingMode != domain.RoundingHalfUp || out.TaxCalculationMode != domain.TaxCalculationUnitPriceLevel {
+		t.Fatalf("unexpected tax modes %s %s %s", out.TaxMode, out.TaxRoundingMode, out.TaxCalculationMode)
+	}
+	if out.ShippingAddress == nil || out.ShippingAddress.Country != "DE" {
+		t.Fatalf("unexpected shipping address %+v", out.ShippingAddress)
+	}
+	if out.TaxedPrice == nil || out.TaxedPrice.TotalNet.CentAmount != 1681 || out.TaxedPrice.TotalTax.CentAmount != 319 || len(out.TaxedPrice.TaxPortions) != 1 {
+		t.Fatalf("unexpected taxed price %+v", out.TaxedPrice)
+	}
+	line := out.LineItems[0]
+	if line.TaxRate == nil || line.TaxRate.ID != "r1" || !line.TaxRate.IncludedInPrice || line.TaxedPrice == nil || line.TaxedPrice.TotalGross.CentAmount != 2000 {
+		t.Fatalf("unexpected line taxes %+v %+v", line.TaxRate, line.TaxedPrice)
+	}
+
+	out = toCTCart(domain.Cart{ID: "cart-2", Currency: "EUR"}, nil, "", localeSelector{})
+	if out.TaxedPrice != nil || out.ShippingAddress != nil || out.TaxMode != domain.TaxModePlatform {
+		t.Fatalf("expected an untaxed cart, got %+v", out)
+	}
+}
+
+func TestToCTMoney(t *testing.T) {
+	tests := []struct {
+		in   domain.Money
+		want string
+	}{
+		{domain.Money{CurrencyCode: "EUR", CentAmount: 1000}, `{"type":"centPrecision","currencyCode":"EUR","centAmount":1000,"fractionDigits":2}`},
+		{domain.Money{CurrencyCode: "JPY", CentAmount: 1500}, `{"type":"centPrecision","currencyCode":"JPY","centAmount":1500,"fractionDigits":0}`},
+		{domain.Money{CurrencyCode: "KWD", CentAmount: 1500}, `{"type":"centPrecision","currencyCode":"KWD","centAmount":1500,"fractionDigits":3}`},
+		{domain.Money{CurrencyCode: "EUR", CentAmount: 1234, PreciseAmount: 12345, FractionDigits: 3}, `{"type":"highPrecision","currencyCode":"EUR","centAmount":1234,"preciseAmount":12345,"fractionDigits":3}`},
+	}
+	for _, tt := range tests {
+		raw, err := json.Marshal(toCTMoney(tt.in))
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		if string(raw) != tt.want {
+			t.Fatalf("expected %s, got %s", tt.want, raw)
+		}
+	}
+
+	out := toCTCart(domain.Cart{ID: "cart-1", Currency: "JPY", TotalCents: 3000, Lines: []domain.CartLine{{
+		ID: "line-1", ProductID: "p1", VariantID: 1, Quantity: 3, UnitPriceCents: 1000, TotalCents: 3000,
+		Snapshot: map[string]interface{}{"currency": "JPY", "priceCents": float64(1000), "preciseAmount": float64(9995), "fractionDigits": float64(1)},
+	}}}, nil, "", localeSelector{})
+	if out.TotalPrice.FractionDigits != 0 || out.LineItems[0].TotalPrice.FractionDigits != 0 {
+		t.Fatalf("expected yen totals without decimals, got %+v", out.TotalPrice)
+	}
+	price := out.LineItems[0].Price.Value
+	if price.Type != "highPrecision" || price.PreciseAmount == nil || *price.PreciseAmount != 9995 || price.FractionDigits != 1 {
+		t.Fatalf("expected the high-precision line price, got %+v", price)
+	}
+}
+
+func TestCustomerAdminHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
+	customers := &stubCustomerService{customer: &domain.Customer{ID: "cust-id", ProjectID: proj.ID, Version: 3, Email: "me@example.com"}}
+	router, err := buildRouter(logDiscard(), nil, Deps{
+		ProjectRepo:  &stubProjectRepo{project: proj},
+		ProductSvc:   &stubProductService{},
+		CartSvc:      &stubCartService{},
+		CategorySvc:  &stubCategoryService{},
+		CustomerSvc:  customers,
+		AnonymousSvc: &stubAnonymousService{},
+		AdminSvc:     &stubAdminService{token: "admin-token"},
+	}, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		method   string
+		url      string
+		body     string
+		token    string
+		err      error
+		status   int
+		contains []string
+	}{
+		{name: "list", method: http.MethodGet, url: "/proj-key/customers?limit=10", token: "admin-token", status: http.StatusOK,
+			contains: []string{`"limit":10`, `"total":1`, `"email":"me@example.com"`}},
+		{name: "list by email", method: http.MethodGet, url: `/proj-key/customers?where=email%3D%22ME%40example.com%22`, token: "admin-token", status: http.StatusOK,
+			contains: []string{`"count":1`}},
+		{name: "list by other email", method: http.MethodGet, url: `/proj-key/customers?where=email%3D%22you%40example.com%22`, token: "admin-token", status: http.StatusOK,
+			contains: []string{`"count":0`, `"results":[]`}},
+		{name: "list by key", method: http.MethodGet, url: `/proj-key/customers?where=key%3D%22me%22`, token: "admin-token", status: http.StatusBadRequest},
+		{name: "list with customer token", method: http.MethodGet, url: "/proj-key/customers", token: "token", status: http.StatusForbidden},
+		{name: "list without token", method: http.MethodGet, url: "/proj-key/customers", status: http.StatusUnauthorized},
+		{name: "get", method: http.MethodGet, url: "/proj-key/customers/cust-id", token: "admin-token", status: http.StatusOK,
+			contains: []string{`"id":"cust-id"`, `"version":3`}},
+		{name: "get missing", method: http.MethodGet, url: "/proj-key/customers/nope", token: "admin-token", err: domain.ErrNotFound, status: http.StatusNotFound},
+		{name: "create", method: http.MethodPost, url: "/proj-key/customers", token: "admin-token",
+			body: `{"email":"new@example.com","password":"Abcdefg1","isEmailVerified":true}`, status: http.StatusCreated,
+			contains: []string{`"customer":{`, `"email":"new@example.com"`, `"isEmailVerified":true`}},
+		{name: "create taken email", method: http.MethodPost, url: "/proj-key/customers", token: "admin-token",
+			body: `{"email":"me@example.com","password":"Abcdefg1"}`, err: domain.ErrAlreadyExists, status: http.StatusConflict},
+		{name: "update", method: http.MethodPost, url: "/proj-key/customers/cust-id", token: "admin-token",
+			body: `{"version":3,"actions":[{"action":"setFirstName","firstName":"Ada"}]}`, status: http.StatusOK},
+		{name: "delete without version", method: http.MethodDelete, url: "/proj-key/customers/cust-id", token: "admin-token", status: http.StatusBadRequest},
+		{name: "delete stale", method: http.MethodDelete, url: "/proj-key/customers/cust-id?version=1", token: "admin-token", err: domain.ErrConcurrentModification, status: http.StatusConflict},
+		{name: "delete", method: http.MethodDelete, url: "/proj-key/customers/cust-id?version=3", token: "admin-token", status: http.StatusOK,
+			contains: []string{`"id":"cust-id"`}},
+		{name: "delete with customer token", method: http.MethodDelete, url: "/proj-key/customers/cust-id?version=3", token: "token", status: http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		customers.err = tc.err
+		var body io.Reader
+		if tc.body != "" {
+			body = strings.NewReader(tc.body)
+		}
+		req := httptest.NewRequest(tc.method, tc.url, body)
+		req.Header.Set("Content-Type", "application/json")
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		for _, want := range tc.contains {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Fatalf("%s: expected %s in %s", tc.name, want, rec.Body.String())
+			}
+		}
+	}
+}
+
+func TestAdminTokenHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
+	deps := Deps{
+		ProjectRepo:  &stubProjectRepo{project: proj},
+		ProductSvc:   &stubProductService{},
+		CartSvc:      &stubCartService{},
+		CategorySvc:  &stubCategoryService{},
+		CustomerSvc:  &stubCustomerService{},
+		AnonymousSvc: &stubAnonymousService{},
+		AdminSvc:     &stubAdminService{token: "admin-token"},
+	}
+	router, err := buildRouter(logDiscard(), nil, deps, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+	deps.AdminSvc = nil
+	withoutAdmin, err := buildRouter(logDiscard(), nil, deps, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		router   *gin.Engine
+		body     string
+		user     string
+		status   int
+		contains string
+	}{
+		{name: "basic auth", router: router, body: "grant_type=client_credentials&scope=manage_customers:proj-key", user: "client:secret",
+			status: http.StatusOK, contains: `"access_token":"admin-token"`},
+		{name: "form credentials", router: router, body: "grant_type=client_credentials&scope=manage_project:proj-key&client_id=client&client_secret=secret",
+			status: http.StatusOK, contains: `"scope":"manage_customers:proj-key"`},
+		{name: "wrong secret", router: router, body: "grant_type=client_credentials&scope=manage_customers:proj-key", user: "client:nope", status: http.StatusUnauthorized},
+		{name: "password grant", router: router, body: "grant_type=password&scope=manage_customers:proj-key", user: "client:secret", status: http.StatusBadRequest},
+		{name: "scope without project", router: router, body: "grant_type=client_credentials&scope=view_products", user: "client:secret", status: http.StatusBadRequest},
+		{name: "no admin client", router: withoutAdmin, body: "grant_type=client_credentials&scope=manage_customers:proj-key", user: "client:secret", status: http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if user, pass, ok := strings.Cut(tc.user, ":"); ok {
+			req.SetBasicAuth(user, pass)
+		}
+		rec := httptest.NewRecorder()
+		tc.router.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		if !strings.Contains(rec.Body.String(), tc.contains) {
+			t.Fatalf("%s: expected %s in %s", tc.name, tc.contains, rec.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/proj-key/customers", nil)
+	req.Header.Set("Authorization", "Bearer admin-token")
+	rec := httptest.NewRecorder()
+	withoutAdmin.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected no customers routes without AdminSvc, got %d", rec.Code)
+	}
+}
+
+type stubPrivacyService struct {
+	requestedBy string
+	err         error
+}
+
+func (s *stubPrivacyService) Export(_ context.Context, _, customerID string) (*privacysvc.Export, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	customer := domain.Customer{ID: customerID, Version: 3, Email: "me@example.com"}
+	return &privacysvc.Export{
+		Customer: customer,
+		Carts:    []domain.Cart{{ID: "cart-id", Currency: "EUR", CustomerID: &customer.ID}},
+		Orders:   []domain.Order{{ID: "order-id", OrderNumber: "1001", Cart: domain.Cart{ID: "cart-id", Currency: "EUR"}}},
+		Tokens:   []privacysvc.TokenInfo{{Kind: "access"}},
+	}, nil
+}
+
+func (s *stubPrivacyService) Erase(_ context.Context, _, customerID string, version int, requestedBy string) (*domain.Customer, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.requestedBy = requestedBy
+	return &domain.Customer{ID: customerID, Version: version + 1, Email: "erased-" + customerID + "@invalid"}, nil
+}
+
+func TestCustomerPrivacyHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
+	privacy := &stubPrivacyService{}
+	deps := Deps{
+		ProjectRepo:  &stubProjectRepo{project: proj},
+		ProductSvc:   &stubProductService{},
+		CartSvc:      &stubCartService{},
+		CategorySvc:  &stubCategoryService{},
+		CustomerSvc:  &stubCustomerService{customer: &domain.Customer{ID: "cust-id", ProjectID: proj.ID, Version: 3, Email: "me@example.com"}},
+		AnonymousSvc: &stubAnonymousService{},
+		AdminSvc:     &stubAdminService{token: "admin-token"},
+		PrivacySvc:   privacy,
+	}
+	router, err := buildRouter(logDiscard(), nil, deps, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+	deps.PrivacySvc = nil
+	withoutPrivacy, err := buildRouter(logDiscard(), nil, deps, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	cases := []struct {
+		name        string
+		router      *gin.Engine
+		method      string
+		url         string
+		token       string
+		err         error
+		status      int
+		requestedBy string
+		contains    []string
+	}{
+		{name: "export", router: router, method: http.MethodGet, url: "/proj-key/me/export", token: "token", status: http.StatusOK,
+			contains: []string{`"customer":{`, `"carts":[{`, `"id":"cart-id"`, `"orderNumber":"1001"`, `"tokens":[{"kind":"access"`}},
+		{name: "export without token", router: router, method: http.MethodGet, url: "/proj-key/me/export", status: http.StatusUnauthorized},
+		{name: "export failure", router: router, method: http.MethodGet, url: "/proj-key/me/export", token: "token", err: errors.New("db down"), status: http.StatusInternalServerError},
+		{name: "export unregistered", router: withoutPrivacy, method: http.MethodGet, url: "/proj-key/me/export", token: "token", status: http.StatusNotFound},
+		{name: "erase me without version", router: router, method: http.MethodDelete, url: "/proj-key/me", token: "token", status: http.StatusBadRequest},
+		{name: "erase me stale", router: router, method: http.MethodDelete, url: "/proj-key/me?version=2", token: "token", err: domain.ErrConcurrentModification, status: http.StatusConflict},
+		{name: "erase me", router: router, method: http.MethodDelete, url: "/proj-key/me?version=3", token: "token", status: http.StatusOK,
+			requestedBy: domain.ErasureByCustomer, contains: []string{`"email":"erased-cust-id@invalid"`, `"version":4`}},
+		{name: "admin erase", router: router, method: http.MethodDelete, url: "/proj-key/customers/cust-id?version=3&dataErasure=true", token: "admin-token", status: http.StatusOK,
+			requestedBy: domain.ErasureByAdmin, contains: []string{`"email":"erased-cust-id@invalid"`}},
+		{name: "admin erase missing", router: router, method: http.MethodDelete, url: "/proj-key/customers/nope?version=3&dataErasure=true", token: "admin-token", err: domain.ErrNotFound, status: http.StatusNotFound},
+		{name: "admin erase unsupported", router: withoutPrivacy, method: http.MethodDelete, url: "/proj-key/customers/cust-id?version=3&dataErasure=true", token: "admin-token", status: http.StatusBadRequest},
+		{name: "admin hard delete", router: router, method: http.MethodDelete, url: "/proj-key/customers/cust-id?version=3", token: "admin-token", status: http.StatusOK,
+			contains: []string{`"email":"me@example.com"`}},
+	}
+	for _, tc := range cases {
+		privacy.err = tc.err
+		privacy.requestedBy = ""
+		req := httptest.NewRequest(tc.method, tc.url, nil)
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		rec := httptest.NewRecorder()
+		tc.router.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		if privacy.requestedBy != tc.requestedBy {
+			t.Fatalf("%s: expected erasure by %q, got %q", tc.name, tc.requestedBy, privacy.requestedBy)
+		}
+		for _, want := range tc.contains {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Fatalf("%s: expected %s in %s", tc.name, want, rec.Body.String())
+			}
+		}
+	}
+}
+
+// stubBusinessUnitService grants the permissions listed per customer in every
+// unit.
+type stubBusinessUnitService struct {
+	units       []domain.BusinessUnit
+	permissions map[string][]string
+}
+
+func (s *stubBusinessUnitService) ListPage(_ context.Context, _ string, _, _ int) ([]domain.BusinessUnit, int, error) {
+	return s.units, len(s.units), nil
+}
+
+func (s *stubBusinessUnitService) Get(_ context.Context, _, id string) (*domain.BusinessUnit, error) {
+	for i := range s.units {
+		if s.units[i].ID == id {
+			return &s.units[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubBusinessUnitService) GetByKey(_ context.Context, _, key string) (*domain.BusinessUnit, error) {
+	for i := range s.units {
+		if s.units[i].Key == key {
+			return &s.units[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubBusinessUnitService) Create(_ context.Context, _ string, _ businessunitsvc.BusinessUnitDraft) (*domain.BusinessUnit, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *stubBusinessUnitService) Update(_ context.Context, _, _ string, _ businessunitsvc.UpdateInput) (*domain.BusinessUnit, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *stubBusinessUnitService) Delete(_ context.Context, _, _ string, _ int) (*domain.BusinessUnit, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *stubBusinessUnitService) Permissions(_ context.Context, _ *domain.BusinessUnit, customerID string) (map[string]bool, error) {
+	granted, ok := s.permissions[customerID]
+	if !ok {
+		return nil, businessunitsvc.ErrNotAssociate
+	}
+	out := map[string]bool{}
+	for _, p := range granted {
+		out[p] = true
+	}
+	return out, nil
+}
+
+func (s *stubBusinessUnitService) Authorize(ctx context.Context, u *domain.BusinessUnit, customerID, permission string) error {
+	permissions, err := s.Permissions(ctx, u, customerID)
+	if err != nil {
+		return err
+	}
+	if !permissions[permission] {
+		return businessunitsvc.ErrPermissionDenied
+	}
+	return nil
+}
+
+func TestAsAssociateCarts(t *testing.T) {
+	proj := &domain.Project{ID: "proj-id", Key: "proj-key"}
+	buyer, colleague := "buyer", "colleague"
+	router, err := buildRouter(logDiscard(), nil, Deps{
+		ProjectRepo: &stubProjectRepo{project: proj},
+		ProductSvc:  &stubProductService{},
+		CartSvc: &stubCartService{carts: []domain.Cart{
+			{ID: "cart-mine", CustomerID: &buyer, Currency: "EUR", State: "active", BusinessUnitKey: "acme"},
+			{ID: "cart-colleague", CustomerID: &colleague, Currency: "EUR", State: "active", BusinessUnitKey: "acme"},
+			{ID: "cart-other-unit", CustomerID: &buyer, Currency: "EUR", State: "active", BusinessUnitKey: "globex"},
+			{ID: "cart-private", CustomerID: &buyer, Currency: "EUR", State: "active"},
+		}},
+		CategorySvc:  &stubCategoryService{},
+		CustomerSvc:  &stubCustomerService{customer: &domain.Customer{ID: buyer, ProjectID: proj.ID}},
+		AnonymousSvc: &stubAnonymousService{},
+		AdminSvc:     &stubAdminService{token: "admin-token"},
+		BusinessUnitSvc: &stubBusinessUnitService{
+			units: []domain.BusinessUnit{{ID: "bu-1", Key: "acme", Version: 1, UnitType: domain.BusinessUnitCompany, Name: "ACME",
+				Status: domain.BusinessUnitActive, AssociateMode: domain.AssociateModeExplicit, TopLevelUnitKey: "acme",
+				Associates: []domain.Associate{{CustomerID: buyer, RoleAssignments: []domain.AssociateRoleAssignment{{RoleKey: "buyer", Inheritance: domain.InheritanceEnabled}}}}}},
+			permissions: map[string][]string{
+				buyer: {domain.PermissionCreateMyCarts, domain.PermissionViewMyCarts, domain.PermissionViewOthersCarts,
+					domain.PermissionUpdateMyCarts, domain.PermissionDeleteMyCarts},
+				colleague: {domain.PermissionCreateMyCarts},
+			},
+		},
+	}, "")
+	if err != nil {
+		t.Fatalf("build router: %v", err)
+	}
+
+	base := "/proj-key/as-associate/buyer/in-business-unit/key=acme"
+	cases := []struct {
+		name     string
+		method   string
+		url      string
+		body     string
+		token    string
+		status   int
+		contains []string
+	}{
+		{name: "create my cart", method: http.MethodPost, url: base + "/carts", body: `{"currency":"EUR"}`, status: http.StatusCreated,
+			contains: []string{`"businessUnit":{"typeId":"business-unit","key":"acme"}`, `"customerId":"buyer"`}},
+		{name: "create cart for a colleague", method: http.MethodPost, url: base + "/carts", body: `{"currency":"EUR","customerId":"colleague"}`, status: http.StatusForbidden,
+			contains: []string{"associate lacks the permission"}},
+		{name: "unknown unit", method: http.MethodPost, url: "/proj-key/as-associate/buyer/in-business-unit/key=initech/carts", body: `{"currency":"EUR"}`, status: http.StatusNotFound},
+		{name: "token of another customer", method: http.MethodPost, url: "/proj-key/as-associate/colleague/in-business-unit/key=acme/carts", body: `{"currency":"EUR"}`, status: http.StatusForbidden},
+		{name: "view my cart", method: http.MethodGet, url: base + "/carts/cart-mine", status: http.StatusOK},
+		{name: "view a colleague's cart", method: http.MethodGet, url: base + "/carts/cart-colleague", status: http.StatusOK},
+		{name: "cart of another unit", method: http.MethodGet, url: base + "/carts/cart-other-unit", status: http.StatusNotFound},
+		{name: "cart without unit", method: http.MethodGet, url: base + "/carts/cart-private", status: http.StatusNotFound},
+		{name: "update my cart", method: http.MethodPost, url: base + "/carts/cart-mine", body: `{"version":1,"actions":[]}`, status: http.StatusOK},
+		{name: "update a colleague's cart", method: http.MethodPost, url: base + "/carts/cart-colleague", body: `{"version":1,"actions":[]}`, status: http.StatusForbidden,
+			contains: []string{"associate lacks the permission"}},
+		{name: "delete a colleague's cart", method: http.MethodDelete, url: base + "/carts/cart-colleague", status: http.StatusForbidden},
+		{name: "delete my cart", method: http.MethodDelete, url: base + "/carts/cart-mine", status: http.StatusOK},
+		{name: "business unit on me/carts", method: http.MethodPost, url: "/proj-key/me/carts", body: `{"currency":"EUR","businessUnit":{"typeId":"business-unit","key":"acme"}}`,
+			status: http.StatusBadRequest, contains: []string{"/as-associate/"}},
+		{name: "business unit on carts", method: http.MethodPost, url: "/proj-key/carts", body: `{"currency":"EUR","businessUnit":{"typeId":"business-unit","key":"acme"}}`,
+			status: http.StatusBadRequest, contains: []string{"/as-associate/"}},
+		{name: "update unit cart on me/carts", method: http.MethodPost, url: "/proj-key/me/carts/cart-mine", body: `{"version":1,"actions":[]}`,
+			status: http.StatusForbidden, contains: []string{"/as-associate/"}},
+		{name: "delete unit cart on me/carts", method: http.MethodDelete, url: "/proj-key/me/carts/cart-mine", status: http.StatusForbidden},
+		{name: "update private cart on me/carts", method: http.MethodPost, url: "/proj-key/me/carts/cart-private", body: `{"version":1,"actions":[]}`, status: http.StatusOK},
+		{name: "business units need an admin token", method: http.MethodGet, url: "/proj-key/business-units/key=acme", status: http.StatusForbidden},
+		{name: "get business unit", method: http.MethodGet, url: "/proj-key/business-units/key=acme", token: "admin-token", status: http.StatusOK,
+			contains: []string{`"topLevelUnit":{"typeId":"business-unit","key":"acme"}`, `"associateRole":{"typeId":"associate-role","key":"buyer"}`}},
+	}
+	for _, tc := range cases {
+		var body io.Reader
+		if tc.body != "" {
+			body = strings.NewReader(tc.body)
+		}
+		token := tc.token
+		if token == "" {
+			token = "customer-token"
+		}
+		req := httptest.NewRequest(tc.method, tc.url, body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		for _, want := range tc.contains {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Fatalf("%s: expected %s in %s", tc.name, want, rec.Body.String())
+			}
+		}
+	}
 }
@@ -37,35 +37,43 @@ func TestSearchHandler_IntegrationFiltersByCategory(t *testing.T) {
 	cat, err := catSvc.Upsert(ctx, domain.Category{
 		ProjectID: projectID,
 		Key:       "cactus",
-		Name:      "Cactus",
+		Name:      domain.LocalizedString{"en": "Cactus"},
 	})
 	if err != nil {
 		t.Fatalf("upsert category: %v", err)
 	}
 
 	prodRepo := productrepo.NewPostgres(pool, log.New(os.Stdout, "[test] ", log.LstdFlags))
-	prodSvc := productsvc.New(prodRepo)
+	prodSvc := productsvc.New(prodRepo, nil, nil, nil, nil)
 
 	_, err = prodRepo.Upsert(ctx, domain.Product{
-		ProjectID:  projectID,
-		Key:        "p1",
-		SKU:        "SKU1",
-		Name:       "With Cat",
-		PriceCents: 100,
-		Currency:   "EUR",
-		Attributes: map[string]interface{}{"categories": []string{cat.Key}},
+		ProjectID: projectID,
+		Key:       "p1",
+		Current: domain.ProductData{
+			Name:        domain.LocalizedString{"en": "With Cat"},
+			CategoryIDs: []string{cat.Key},
+			MasterVariant: domain.ProductVariant{
+				ID:     1,
+				SKU:    "SKU1",
+				Prices: []domain.Price{{Value: domain.Money{CurrencyCode: "EUR", CentAmount: 100}}},
+			},
+		},
 	})
 	if err != nil {
 		t.Fatalf("upsert product with cat: %v", err)
 	}
 	_, err = prodRepo.Upsert(ctx, domain.Product{
-		ProjectID:  projectID,
-		Key:        "p2",
-		SKU:        "SKU2",
-		Name:       "No Cat",
-		PriceCents: 50,
-		Currency:   "EUR",
-		Attributes: map[string]interface{}{"categories": []string{"other"}},
+		ProjectID: projectID,
+		Key:       "p2",
+		Current: domain.ProductData{
+			Name:        domain.LocalizedString{"en": "No Cat"},
+			CategoryIDs: []string{"other"},
+			MasterVariant: domain.ProductVariant{
+				ID:     1,
+				SKU:    "SKU2",
+				Prices: []domain.Price{{Value: domain.Money{CurrencyCode: "EUR", CentAmount: 50}}},
+			},
+		},
 	})
 	if err != nil {
 		t.Fatalf("upsert product without cat: %v", err)
@@ -79,7 +87,7 @@ func TestSearchHandler_IntegrationFiltersByCategory(t *testing.T) {
 		CategorySvc:  catSvc,
 		CustomerSvc:  &stubCustomerService{customer: &domain.Customer{ID: "cust", ProjectID: projectID}},
 		AnonymousSvc: &stubAnonymousService{},
-	})
+	}, "")
 	if err != nil {
 		t.Fatalf("build router: %v", err)
 	}
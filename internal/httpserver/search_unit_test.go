@@ -8,10 +8,10 @@ import (
 
 func TestBuildSearchResponse_FiltersByPriceAndCategory(t *testing.T) {
 	products := []domain.Product{
-		{ID: "cheap", Name: "Cheap", PriceCents: 50, Attributes: map[string]interface{}{"categories": []string{"cat-key"}}},
-		{ID: "costly", Name: "Costly", PriceCents: 500, Attributes: map[string]interface{}{"categories": []string{"other"}}},
+		testProduct("cheap", "", "Cheap", "", 50, "EUR", "cat-key"),
+		testProduct("costly", "", "Costly", "", 500, "EUR", "other"),
 	}
-	categories := []domain.Category{{ID: "cat-id", Key: "cat-key", Name: "Cat"}}
+	categories := []domain.Category{{ID: "cat-id", Key: "cat-key", Name: domain.LocalizedString{"en": "Cat"}}}
 	req := searchRequest{}
 	req.Query.Filter = []filterClause{
 		{Range: &rangeFilter{Field: "variants.prices.centAmount", GTE: int64Ptr(10), LTE: int64Ptr(100)}},
@@ -26,12 +26,12 @@ func TestBuildSearchResponse_FiltersByPriceAndCategory(t *testing.T) {
 
 func TestSortProducts_DefaultsToNameAsc(t *testing.T) {
 	products := []domain.Product{
-		{Name: "Zeta"},
-		{Name: "Alpha"},
+		testProduct("z", "", "Zeta", "", 0, ""),
+		testProduct("a", "", "Alpha", "", 0, ""),
 	}
 	req := searchRequest{}
 	sortProducts(products, req)
-	if products[0].Name != "Alpha" {
+	if products[0].Current.Name["en"] != "Alpha" {
 		t.Fatalf("expected Alpha first, got %+v", products)
 	}
 }
@@ -39,3 +39,19 @@ func TestSortProducts_DefaultsToNameAsc(t *testing.T) {
 func int64Ptr(v int64) *int64 {
 	return &v
 }
+
+// testProduct builds a published product whose current and staged data match.
+func testProduct(id, key, name, sku string, cents int64, currency string, categoryIDs ...string) domain.Product {
+	data := domain.ProductData{
+		Name:        domain.LocalizedString{"en": name},
+		CategoryIDs: categoryIDs,
+		MasterVariant: domain.ProductVariant{
+			ID:  1,
+			SKU: sku,
+		},
+	}
+	if currency != "" {
+		data.MasterVariant.Prices = []domain.Price{{ID: "price-" + id, Value: domain.Money{CurrencyCode: currency, CentAmount: cents}}}
+	}
+	return domain.Product{ID: id, Key: key, Version: 1, Published: true, Current: data, Staged: data}
+}
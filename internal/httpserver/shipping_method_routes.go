@@ -0,0 +1,63 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"commercetools-replica/internal/domain"
+	shippingmethodsvc "commercetools-replica/internal/service/shippingmethod"
+
+	"github.com/gin-gonic/gin"
+)
+
+type shippingMethodService interface {
+	ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.ShippingMethod, int, error)
+	Get(ctx context.Context, projectID, id string) (*domain.ShippingMethod, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.ShippingMethod, error)
+	Create(ctx context.Context, projectID string, draft shippingmethodsvc.ShippingMethodDraft) (*domain.ShippingMethod, error)
+	Update(ctx context.Context, projectID, id string, in shippingmethodsvc.UpdateInput) (*domain.ShippingMethod, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.ShippingMethod, error)
+}
+
+// registerShippingMethodRoutes registers the /shipping-methods routes and
+// matching-cart, which lists the shipping methods a cart can be shipped with.
+func registerShippingMethodRoutes(read, write *gin.RouterGroup, logger *log.Logger, svc shippingMethodService, carts cartService) {
+	routes := crudRoutes[domain.ShippingMethod, shippingmethodsvc.ShippingMethodDraft, shippingmethodsvc.UpdateInput]{
+		path:       "/shipping-methods",
+		singular:   "shipping method",
+		plural:     "shipping methods",
+		svc:        svc,
+		id:         func(s *domain.ShippingMethod) string { return s.ID },
+		draftField: func(d shippingmethodsvc.ShippingMethodDraft) string { return "key=" + d.Key },
+		conflict:   "shipping method with this key or another default shipping method already exists",
+		render:     func(_ *gin.Context, s domain.ShippingMethod) any { return toCTShippingMethod(s) },
+		list: func(_ *gin.Context, items []domain.ShippingMethod, total, limit, offset int) any {
+			return buildShippingMethodList(items, total, limit, offset)
+		},
+	}
+	if read != nil {
+		read.GET("/shipping-methods/matching-cart", func(c *gin.Context) {
+			project := mustProject(c)
+			cartID := strings.TrimSpace(c.Query("cartId"))
+			if cartID == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "cartId query parameter required"})
+				return
+			}
+			methods, err := carts.MatchingShippingMethods(c.Request.Context(), project.ID, cartID)
+			if err != nil {
+				if errors.Is(err, domain.ErrNotFound) {
+					c.JSON(http.StatusNotFound, gin.H{"error": "cart not found"})
+					return
+				}
+				logger.Printf("matching shipping methods error project_id=%s cart_id=%s error=%v", project.ID, cartID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "list shipping methods failed"})
+				return
+			}
+			c.JSON(http.StatusOK, buildShippingMethodList(methods, len(methods), 0, 0))
+		})
+	}
+	routes.register(read, write, logger)
+}
@@ -0,0 +1,41 @@
+package httpserver
+
+import (
+	"context"
+	"log"
+
+	"commercetools-replica/internal/domain"
+	storesvc "commercetools-replica/internal/service/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+type storeService interface {
+	ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.Store, int, error)
+	Get(ctx context.Context, projectID, id string) (*domain.Store, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.Store, error)
+	Create(ctx context.Context, projectID string, draft storesvc.StoreDraft) (*domain.Store, error)
+	Update(ctx context.Context, projectID, id string, in storesvc.UpdateInput) (*domain.Store, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.Store, error)
+	ProductIDs(ctx context.Context, projectID, storeID string) (map[string]bool, error)
+}
+
+// registerStoreRoutes registers the /stores routes.
+func registerStoreRoutes(read, write *gin.RouterGroup, logger *log.Logger, svc storeService) {
+	routes := crudRoutes[domain.Store, storesvc.StoreDraft, storesvc.UpdateInput]{
+		path:       "/stores",
+		singular:   "store",
+		plural:     "stores",
+		svc:        svc,
+		id:         func(s *domain.Store) string { return s.ID },
+		draftField: func(d storesvc.StoreDraft) string { return "key=" + d.Key },
+		conflict:   "store with this key already exists",
+		render: func(c *gin.Context, s domain.Store) any {
+			return toCTStore(s, localeFromRequest(c))
+		},
+		list: func(c *gin.Context, items []domain.Store, total, limit, offset int) any {
+			return buildStoreList(items, total, limit, offset, localeFromRequest(c))
+		},
+	}
+	routes.register(read, write, logger)
+}
@@ -0,0 +1,38 @@
+package httpserver
+
+import (
+	"context"
+	"log"
+
+	"commercetools-replica/internal/domain"
+	taxcategorysvc "commercetools-replica/internal/service/taxcategory"
+
+	"github.com/gin-gonic/gin"
+)
+
+type taxCategoryService interface {
+	ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.TaxCategory, int, error)
+	Get(ctx context.Context, projectID, id string) (*domain.TaxCategory, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.TaxCategory, error)
+	Create(ctx context.Context, projectID string, draft taxcategorysvc.TaxCategoryDraft) (*domain.TaxCategory, error)
+	Update(ctx context.Context, projectID, id string, in taxcategorysvc.UpdateInput) (*domain.TaxCategory, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.TaxCategory, error)
+}
+
+// registerTaxCategoryRoutes registers the /tax-categories routes.
+func registerTaxCategoryRoutes(read, write *gin.RouterGroup, logger *log.Logger, svc taxCategoryService) {
+	routes := crudRoutes[domain.TaxCategory, taxcategorysvc.TaxCategoryDraft, taxcategorysvc.UpdateInput]{
+		path:       "/tax-categories",
+		singular:   "tax category",
+		plural:     "tax categories",
+		svc:        svc,
+		id:         func(t *domain.TaxCategory) string { return t.ID },
+		draftField: func(d taxcategorysvc.TaxCategoryDraft) string { return "key=" + d.Key },
+		conflict:   "tax category with this key already exists",
+		render:     func(_ *gin.Context, t domain.TaxCategory) any { return toCTTaxCategory(t) },
+		list: func(_ *gin.Context, items []domain.TaxCategory, total, limit, offset int) any {
+			return buildTaxCategoryList(items, total, limit, offset)
+		},
+	}
+	routes.register(read, write, logger)
+}
@@ -0,0 +1,60 @@
+package httpserver
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// lookupPredicate is the subset of commercetools query predicates accepted by the
+// lookup endpoints: slug(<locale>="..."), key="..." and id="...".
+type lookupPredicate struct {
+	Field  string
+	Locale string
+	Value  string
+}
+
+var (
+	slugPredicatePattern  = regexp.MustCompile(`^slug\s*\(\s*([A-Za-z]{2,3}(?:[-_][A-Za-z0-9]+)*)\s*=\s*("(?:[^"\\]|\\.)*")\s*\)$`)
+	fieldPredicatePattern = regexp.MustCompile(`^(key|id)\s*=\s*("(?:[^"\\]|\\.)*")$`)
+	emailPredicatePattern = regexp.MustCompile(`^email\s*=\s*("(?:[^"\\]|\\.)*")$`)
+)
+
+func parseLookupPredicate(where string) (lookupPredicate, error) {
+	where = strings.TrimSpace(where)
+	if m := slugPredicatePattern.FindStringSubmatch(where); m != nil {
+		value, err := strconv.Unquote(m[2])
+		if err != nil {
+			return lookupPredicate{}, fmt.Errorf("invalid where predicate %q", where)
+		}
+		return lookupPredicate{Field: "slug", Locale: m[1], Value: value}, nil
+	}
+	if m := fieldPredicatePattern.FindStringSubmatch(where); m != nil {
+		value, err := strconv.Unquote(m[2])
+		if err != nil {
+			return lookupPredicate{}, fmt.Errorf("invalid where predicate %q", where)
+		}
+		return lookupPredicate{Field: m[1], Value: value}, nil
+	}
+	return lookupPredicate{}, fmt.Errorf("unsupported where predicate %q", where)
+}
+
+// parseEmailPredicate reads the email="..." predicate of the customer query.
+func parseEmailPredicate(where string) (string, error) {
+	where = strings.TrimSpace(where)
+	if m := emailPredicatePattern.FindStringSubmatch(where); m != nil {
+		if value, err := strconv.Unquote(m[1]); err == nil {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported where predicate %q", where)
+}
+
+// keyFromPathParam returns the key of a "key=<key>" path segment.
+func keyFromPathParam(param string) (string, bool) {
+	if !strings.HasPrefix(param, "key=") {
+		return "", false
+	}
+	return strings.TrimPrefix(param, "key="), true
+}
@@ -0,0 +1,47 @@
+package httpserver
+
+import "testing"
+
+func TestParseLookupPredicate(t *testing.T) {
+	cases := []struct {
+		where   string
+		want    lookupPredicate
+		wantErr bool
+	}{
+		{where: `slug(en="red-shirt")`, want: lookupPredicate{Field: "slug", Locale: "en", Value: "red-shirt"}},
+		{where: ` slug( de-DE = "rotes-hemd" ) `, want: lookupPredicate{Field: "slug", Locale: "de-DE", Value: "rotes-hemd"}},
+		{where: `slug(en="say \"hi\"")`, want: lookupPredicate{Field: "slug", Locale: "en", Value: `say "hi"`}},
+		{where: `key="shirt-1"`, want: lookupPredicate{Field: "key", Value: "shirt-1"}},
+		{where: `id = "abc"`, want: lookupPredicate{Field: "id", Value: "abc"}},
+		{where: `slug(en=red-shirt)`, wantErr: true},
+		{where: `name(en="Shirt")`, wantErr: true},
+		{where: `key="a" and id="b"`, wantErr: true},
+		{where: ``, wantErr: true},
+	}
+	for _, tc := range cases {
+		got, err := parseLookupPredicate(tc.where)
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("%q: expected error, got %+v", tc.where, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tc.where, err)
+		}
+		if got != tc.want {
+			t.Fatalf("%q: expected %+v, got %+v", tc.where, tc.want, got)
+		}
+	}
+}
+
+func TestParseEmailPredicate(t *testing.T) {
+	if got, err := parseEmailPredicate(` email = "me@example.com" `); err != nil || got != "me@example.com" {
+		t.Fatalf("expected me@example.com, got %q (%v)", got, err)
+	}
+	for _, where := range []string{`email=me@example.com`, `key="me"`, `email="a" and key="b"`} {
+		if _, err := parseEmailPredicate(where); err == nil {
+			t.Fatalf("%q: expected error", where)
+		}
+	}
+}
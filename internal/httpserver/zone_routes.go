@@ -0,0 +1,38 @@
+package httpserver
+
+import (
+	"context"
+	"log"
+
+	"commercetools-replica/internal/domain"
+	zonesvc "commercetools-replica/internal/service/zone"
+
+	"github.com/gin-gonic/gin"
+)
+
+type zoneService interface {
+	ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.Zone, int, error)
+	Get(ctx context.Context, projectID, id string) (*domain.Zone, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.Zone, error)
+	Create(ctx context.Context, projectID string, draft zonesvc.ZoneDraft) (*domain.Zone, error)
+	Update(ctx context.Context, projectID, id string, in zonesvc.UpdateInput) (*domain.Zone, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.Zone, error)
+}
+
+// registerZoneRoutes registers the /zones routes.
+func registerZoneRoutes(read, write *gin.RouterGroup, logger *log.Logger, svc zoneService) {
+	routes := crudRoutes[domain.Zone, zonesvc.ZoneDraft, zonesvc.UpdateInput]{
+		path:       "/zones",
+		singular:   "zone",
+		plural:     "zones",
+		svc:        svc,
+		id:         func(z *domain.Zone) string { return z.ID },
+		draftField: func(d zonesvc.ZoneDraft) string { return "key=" + d.Key },
+		conflict:   "zone with this key already exists",
+		render:     func(_ *gin.Context, z domain.Zone) any { return toCTZone(z) },
+		list: func(_ *gin.Context, items []domain.Zone, total, limit, offset int) any {
+			return buildZoneList(items, total, limit, offset)
+		},
+	}
+	routes.register(read, write, logger)
+}
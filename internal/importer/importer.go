@@ -13,6 +13,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -34,19 +35,38 @@ type CategoryWriter interface {
 	Upsert(ctx context.Context, c domain.Category) (*domain.Category, error)
 }
 
+// ProductTypeStore resolves the product type named by productType.key; missing types
+// are created from the attribute columns of the file.
+type ProductTypeStore interface {
+	GetByKey(ctx context.Context, projectID, key string) (*domain.ProductType, error)
+	Create(ctx context.Context, t domain.ProductType) (*domain.ProductType, error)
+}
+
+// TaxCategoryStore resolves the tax category named by taxCategory.key; missing
+// categories are created without rates.
+type TaxCategoryStore interface {
+	GetByKey(ctx context.Context, projectID, key string) (*domain.TaxCategory, error)
+	Create(ctx context.Context, c domain.TaxCategory) (*domain.TaxCategory, error)
+}
+
 // CSVImporter reads commercetools-like CSV exports and inserts/updates products.
 type CSVImporter struct {
-	reader          *csv.Reader
-	productRepo     ProductWriter
-	categoryRepo    CategoryWriter
-	categorySeen    map[string]struct{}
-	categoryIDByKey map[string]string
-	projectID       string
-	projectKey      string
-	mediaRoot       string
-	mediaBaseURL    string
-	downloader      imageDownloader
-	lastKind        string
+	reader           *csv.Reader
+	productRepo      ProductWriter
+	categoryRepo     CategoryWriter
+	categorySeen     map[string]struct{}
+	categoryIDByKey  map[string]string
+	productTypes     ProductTypeStore
+	productTypeByKey map[string]*domain.ProductType
+	taxCategories    TaxCategoryStore
+	taxCategoryByKey map[string]string
+	attributeColumns []attributeColumn
+	projectID        string
+	projectKey       string
+	mediaRoot        string
+	mediaBaseURL     string
+	downloader       imageDownloader
+	lastKind         string
 }
 
 type Option func(*CSVImporter)
@@ -58,6 +78,21 @@ func WithMedia(root, baseURL string) Option {
 	}
 }
 
+// WithProductTypes links imported products to their product type and validates
+// variant attributes against its definitions.
+func WithProductTypes(store ProductTypeStore) Option {
+	return func(i *CSVImporter) {
+		i.productTypes = store
+	}
+}
+
+// WithTaxCategories links imported products to their tax category.
+func WithTaxCategories(store TaxCategoryStore) Option {
+	return func(i *CSVImporter) {
+		i.taxCategories = store
+	}
+}
+
 func WithDownloader(d imageDownloader) Option {
 	return func(i *CSVImporter) {
 		i.downloader = d
@@ -68,16 +103,18 @@ func NewCSVImporter(r io.Reader, repo ProductWriter, catRepo CategoryWriter, pro
 	csvr := csv.NewReader(r)
 	csvr.FieldsPerRecord = -1 // rows may have trailing commas
 	imp := &CSVImporter{
-		reader:          csvr,
-		productRepo:     repo,
-		categoryRepo:    catRepo,
-		categorySeen:    make(map[string]struct{}),
-		categoryIDByKey: make(map[string]string),
-		projectID:       projectID,
-		projectKey:      projectKey,
-		mediaBaseURL:    "/media",
-		downloader:      newHTTPImageDownloader(),
-		lastKind:        KindProducts,
+		reader:           csvr,
+		productRepo:      repo,
+		categoryRepo:     catRepo,
+		categorySeen:     make(map[string]struct{}),
+		categoryIDByKey:  make(map[string]string),
+		productTypeByKey: make(map[string]*domain.ProductType),
+		taxCategoryByKey: make(map[string]string),
+		projectID:        projectID,
+		projectKey:       projectKey,
+		mediaBaseURL:     "/media",
+		downloader:       newHTTPImageDownloader(),
+		lastKind:         KindProducts,
 	}
 	for _, opt := range opts {
 		opt(imp)
@@ -86,27 +123,41 @@ func NewCSVImporter(r io.Reader, repo ProductWriter, catRepo CategoryWriter, pro
 }
 
 type csvRow struct {
-	ID          string
-	Key         string
-	Name        string
-	Desc        string
-	SKU         string
-	Cents       int64
-	Currency    string
-	ImageURLs   []string
-	Categories  []string
-	ProductType string
+	ID              string
+	Key             string
+	Name            domain.LocalizedString
+	Slug            domain.LocalizedString
+	Desc            domain.LocalizedString
+	MetaTitle       domain.LocalizedString
+	MetaDescription domain.LocalizedString
+	SearchKeywords  domain.LocalizedKeywords
+	SKU             string
+	Cents           int64
+	Currency        string
+	ImageURLs       []string
+	Categories      []string
+	ProductType     string
+	TaxCategory     string
+	Attributes      map[string]domain.LocalizedString
+}
+
+// attributeColumn is a "variants.attributes.<name>[.<locale>]" column; values without
+// a locale are read into DefaultLocale and marked plain.
+type attributeColumn struct {
+	Name   string
+	Locale string
+	Pos    int
 }
 
 type categoryRow struct {
 	Key             string
-	Name            string
-	Slug            string
+	Name            domain.LocalizedString
+	Slug            domain.LocalizedString
 	ParentKey       string
 	OrderHint       string
-	Description     string
-	MetaTitle       string
-	MetaDescription string
+	Description     domain.LocalizedString
+	MetaTitle       domain.LocalizedString
+	MetaDescription domain.LocalizedString
 }
 
 // Run parses CSV rows and upserts products grouped by product key.
@@ -126,6 +177,7 @@ func (i *CSVImporter) Run(ctx context.Context) (int, error) {
 		return i.runCategories(ctx, index)
 	}
 	i.lastKind = KindProducts
+	i.attributeColumns = attributeColumns(index)
 
 	var (
 		current  *csvRow
@@ -145,6 +197,7 @@ func (i *CSVImporter) Run(ctx context.Context) (int, error) {
 		if row == nil {
 			continue
 		}
+		row.Attributes = pickAttributes(record, i.attributeColumns)
 
 		if row.Key != "" {
 			if current != nil {
@@ -194,16 +247,16 @@ func DetectKind(r io.Reader) (string, error) {
 }
 
 func (i *CSVImporter) save(ctx context.Context, row *csvRow) error {
-	if row.Key == "" || row.Name == "" || row.SKU == "" || row.Cents == 0 || row.Currency == "" {
+	if row.Key == "" || row.Name.IsEmpty() || row.SKU == "" || row.Cents == 0 || row.Currency == "" {
 		return fmt.Errorf("invalid product row (missing required fields) for key %q", row.Key)
 	}
 	if row.ID != "" && len(row.ID) != 36 {
 		return fmt.Errorf("invalid id for key %q: %s", row.Key, row.ID)
 	}
 
-	attrs := map[string]interface{}{}
+	var images []string
 	if len(row.ImageURLs) > 0 {
-		images := row.ImageURLs
+		images = row.ImageURLs
 		if i.mediaRoot != "" {
 			local, err := i.downloadImages(ctx, row.ImageURLs)
 			if err != nil {
@@ -211,33 +264,46 @@ func (i *CSVImporter) save(ctx context.Context, row *csvRow) error {
 			}
 			images = local
 		}
-		attrs["images"] = images
 	}
 	catKeys := pickCategoryKeys(row)
-	if len(catKeys) > 0 {
-		attrs["categoryKeys"] = catKeys
-	}
 	catIDs, err := i.ensureCategoryIDs(ctx, catKeys)
 	if err != nil {
 		return err
 	}
-	if len(catIDs) > 0 {
-		attrs["categories"] = catIDs
-	} else if len(catKeys) > 0 {
+	if len(catIDs) == 0 && len(catKeys) > 0 {
 		// Fallback for cases where categoryRepo isn't configured.
-		attrs["categories"] = catKeys
+		catIDs = catKeys
 	}
 
 	p := domain.Product{
-		ID:          row.ID,
-		ProjectID:   i.projectID,
-		Key:         row.Key,
-		SKU:         row.SKU,
-		Name:        row.Name,
-		Description: row.Desc,
-		PriceCents:  row.Cents,
-		Currency:    row.Currency,
-		Attributes:  attrs,
+		ID:        row.ID,
+		ProjectID: i.projectID,
+		Key:       row.Key,
+		Current: domain.ProductData{
+			Name:            row.Name,
+			Slug:            row.Slug,
+			Description:     row.Desc,
+			MetaTitle:       row.MetaTitle,
+			MetaDescription: row.MetaDescription,
+			SearchKeywords:  row.SearchKeywords,
+			CategoryIDs:     catIDs,
+			MasterVariant: domain.ProductVariant{
+				ID:     1,
+				SKU:    row.SKU,
+				Prices: []domain.Price{{Value: domain.Money{CurrencyCode: row.Currency, CentAmount: row.Cents}}},
+				Images: images,
+			},
+		},
+	}
+	if err := i.applyProductType(ctx, row, &p); err != nil {
+		return fmt.Errorf("product %q: %w", row.Key, err)
+	}
+	if row.TaxCategory != "" && i.taxCategories != nil {
+		id, err := i.ensureTaxCategory(ctx, row.TaxCategory)
+		if err != nil {
+			return fmt.Errorf("product %q: %w", row.Key, err)
+		}
+		p.TaxCategoryID = id
 	}
 
 	_, err = i.productRepo.Upsert(ctx, p)
@@ -462,8 +528,8 @@ func (i *CSVImporter) ensureCategoryIDs(ctx context.Context, catKeys []string) (
 		out, err := i.categoryRepo.Upsert(ctx, domain.Category{
 			ProjectID: i.projectID,
 			Key:       key,
-			Name:      displayNameFromKey(key),
-			Slug:      key,
+			Name:      domain.Localized(domain.DefaultLocale, displayNameFromKey(key)),
+			Slug:      domain.Localized(domain.DefaultLocale, key),
 		})
 		if err != nil {
 			return nil, fmt.Errorf("upsert category %q: %w", key, err)
@@ -487,7 +553,7 @@ func headerIndex(headers []string) map[string]int {
 
 func isCategoryFile(idx map[string]int) bool {
 	_, hasParent := idx["parent.key"]
-	_, hasSlug := idx["slug.en"]
+	hasSlug := len(localizedColumns(idx, "slug")) > 0
 	_, hasProductSKU := idx["variants.sku"]
 	return (hasParent || hasSlug) && !hasProductSKU
 }
@@ -495,8 +561,8 @@ func isCategoryFile(idx map[string]int) bool {
 func parseRow(record []string, index map[string]int) *csvRow {
 	id := pick(record, index, "id")
 	key := pick(record, index, "key")
-	name := pick(record, index, "name.en")
-	desc := pick(record, index, "description.en")
+	name := pickLocalized(record, index, "name")
+	desc := pickLocalized(record, index, "description")
 	sku := pick(record, index, "variants.sku")
 	currency := pick(record, index, "variants.prices.value.currencyCode")
 	centStr := pick(record, index, "variants.prices.value.centAmount")
@@ -516,15 +582,24 @@ func parseRow(record []string, index map[string]int) *csvRow {
 	}
 
 	row := &csvRow{
-		Key:         key,
-		Name:        name,
-		Desc:        desc,
-		SKU:         sku,
-		Cents:       cents,
-		Currency:    currency,
-		ID:          id,
-		Categories:  categories,
-		ProductType: ptype,
+		Key:             key,
+		Name:            name,
+		Slug:            pickLocalized(record, index, "slug"),
+		Desc:            desc,
+		MetaTitle:       pickLocalized(record, index, "metaTitle"),
+		MetaDescription: pickLocalized(record, index, "metaDescription"),
+		SearchKeywords:  pickKeywords(record, index, "searchKeywords"),
+		SKU:             sku,
+		Cents:           cents,
+		Currency:        currency,
+		ID:              id,
+		Categories:      categories,
+		ProductType:     ptype,
+		TaxCategory:     pick(record, index, "taxCategory.key"),
+	}
+	if row.Slug.IsEmpty() && key != "" {
+		// Slugs are unique per locale and looked up directly, so persist one instead of deriving it later.
+		row.Slug = domain.Localized(domain.DefaultLocale, strings.ReplaceAll(strings.ToLower(key), " ", "-"))
 	}
 	if imageURL != "" {
 		row.ImageURLs = []string{strings.TrimSpace(imageURL)}
@@ -540,6 +615,53 @@ func pick(record []string, index map[string]int, key string) string {
 	return strings.TrimSpace(record[pos])
 }
 
+// localizedColumns returns the column index per locale for "<field>.<locale>" headers.
+func localizedColumns(index map[string]int, field string) map[string]int {
+	prefix := field + "."
+	out := make(map[string]int)
+	for header, pos := range index {
+		if !strings.HasPrefix(header, prefix) {
+			continue
+		}
+		locale := strings.TrimPrefix(header, prefix)
+		if locale == "" || strings.Contains(locale, ".") {
+			continue
+		}
+		out[locale] = pos
+	}
+	return out
+}
+
+// pickLocalized reads every "<field>.<locale>" column into a LocalizedString.
+func pickLocalized(record []string, index map[string]int, field string) domain.LocalizedString {
+	out := domain.LocalizedString{}
+	for locale, pos := range localizedColumns(index, field) {
+		if pos >= len(record) {
+			continue
+		}
+		if v := strings.TrimSpace(record[pos]); v != "" {
+			out[locale] = v
+		}
+	}
+	return out
+}
+
+// pickKeywords reads "<field>.<locale>" columns holding ';' or '|' separated keywords.
+func pickKeywords(record []string, index map[string]int, field string) domain.LocalizedKeywords {
+	out := domain.LocalizedKeywords{}
+	for locale, raw := range pickLocalized(record, index, field) {
+		parts := strings.FieldsFunc(raw, func(r rune) bool {
+			return r == ';' || r == '|'
+		})
+		for _, p := range parts {
+			if p = strings.TrimSpace(p); p != "" {
+				out[locale] = append(out[locale], p)
+			}
+		}
+	}
+	return out
+}
+
 func pickCategories(record []string, index map[string]int, key string) []string {
 	val := pick(record, index, key)
 	if val == "" {
@@ -626,6 +748,7 @@ func (i *CSVImporter) runCategories(ctx context.Context, index map[string]int) (
 	}
 
 	rows = inferCategoryParents(rows)
+	rows = orderCategoriesParentFirst(rows)
 
 	imported := 0
 	for _, row := range rows {
@@ -639,25 +762,25 @@ func (i *CSVImporter) runCategories(ctx context.Context, index map[string]int) (
 
 func parseCategoryRow(record []string, index map[string]int) *categoryRow {
 	key := pick(record, index, "key")
-	name := pick(record, index, "name.en")
-	slug := pick(record, index, "slug.en")
+	name := pickLocalized(record, index, "name")
+	slug := pickLocalized(record, index, "slug")
 	parent := pick(record, index, "parent.key")
 	order := pick(record, index, "orderHint")
-	desc := pick(record, index, "description.en")
-	metaTitle := pick(record, index, "metaTitle.en")
-	metaDesc := pick(record, index, "metaDescription.en")
+	desc := pickLocalized(record, index, "description")
+	metaTitle := pickLocalized(record, index, "metaTitle")
+	metaDesc := pickLocalized(record, index, "metaDescription")
 
 	if key == "" {
-		key = slug
+		key = slug.Get(domain.DefaultLocale)
 	}
 	if key == "" {
 		return nil
 	}
-	if slug == "" {
-		slug = key
+	if slug.IsEmpty() {
+		slug = domain.Localized(domain.DefaultLocale, key)
 	}
-	if name == "" {
-		name = displayNameFromKey(key)
+	if name.IsEmpty() {
+		name = domain.Localized(domain.DefaultLocale, displayNameFromKey(key))
 	}
 
 	return &categoryRow{
@@ -699,6 +822,37 @@ func inferCategoryParents(rows []*categoryRow) []*categoryRow {
 	return rows
 }
 
+// orderCategoriesParentFirst sorts rows by their depth within the file (stable), so
+// every parent is stored before its children and can be referenced by id.
+func orderCategoriesParentFirst(rows []*categoryRow) []*categoryRow {
+	byKey := make(map[string]*categoryRow, len(rows))
+	for _, r := range rows {
+		if _, exists := byKey[r.Key]; !exists {
+			byKey[r.Key] = r
+		}
+	}
+	depth := func(r *categoryRow) int {
+		d := 0
+		seen := map[string]struct{}{r.Key: {}}
+		for parent := byKey[r.ParentKey]; parent != nil; parent = byKey[parent.ParentKey] {
+			if _, loop := seen[parent.Key]; loop {
+				break
+			}
+			seen[parent.Key] = struct{}{}
+			d++
+		}
+		return d
+	}
+	depths := make(map[*categoryRow]int, len(rows))
+	for _, r := range rows {
+		depths[r] = depth(r)
+	}
+	sort.SliceStable(rows, func(a, b int) bool {
+		return depths[rows[a]] < depths[rows[b]]
+	})
+	return rows
+}
+
 func primaryOrderHint(orderHint string) string {
 	orderHint = strings.TrimSpace(orderHint)
 	if orderHint == "" {
@@ -728,13 +882,23 @@ func (i *CSVImporter) saveCategory(ctx context.Context, row *categoryRow) error
 	if _, ok := i.categorySeen[key]; ok {
 		return nil
 	}
+	var parentID string
+	if parentKey := normalizeCategoryKey(row.ParentKey); parentKey != "" && parentKey != key {
+		ids, err := i.ensureCategoryIDs(ctx, []string{parentKey})
+		if err != nil {
+			return err
+		}
+		if len(ids) > 0 {
+			parentID = ids[0]
+		}
+	}
 	out, err := i.categoryRepo.Upsert(ctx, domain.Category{
 		ProjectID:       i.projectID,
 		Key:             key,
 		Name:            row.Name,
 		Slug:            row.Slug,
 		OrderHint:       row.OrderHint,
-		ParentKey:       normalizeCategoryKey(row.ParentKey),
+		ParentID:        parentID,
 		Description:     row.Description,
 		MetaTitle:       row.MetaTitle,
 		MetaDescription: row.MetaDescription,
@@ -748,3 +912,225 @@ func (i *CSVImporter) saveCategory(ctx context.Context, row *categoryRow) error
 	i.categorySeen[key] = struct{}{}
 	return nil
 }
+
+func attributeColumns(index map[string]int) []attributeColumn {
+	const prefix = "variants.attributes."
+	var cols []attributeColumn
+	for header, pos := range index {
+		if !strings.HasPrefix(header, prefix) {
+			continue
+		}
+		name, locale, _ := strings.Cut(strings.TrimPrefix(header, prefix), ".")
+		if name == "" {
+			continue
+		}
+		cols = append(cols, attributeColumn{Name: name, Locale: locale, Pos: pos})
+	}
+	sort.Slice(cols, func(a, b int) bool {
+		if cols[a].Name != cols[b].Name {
+			return cols[a].Name < cols[b].Name
+		}
+		return cols[a].Locale < cols[b].Locale
+	})
+	return cols
+}
+
+func pickAttributes(record []string, cols []attributeColumn) map[string]domain.LocalizedString {
+	out := map[string]domain.LocalizedString{}
+	for _, col := range cols {
+		if col.Pos >= len(record) {
+			continue
+		}
+		v := strings.TrimSpace(record[col.Pos])
+		if v == "" {
+			continue
+		}
+		if out[col.Name] == nil {
+			out[col.Name] = domain.LocalizedString{}
+		}
+		out[col.Name][col.Locale] = v
+	}
+	return out
+}
+
+// applyProductType links p to the row's product type and stores the row attributes
+// on the master variant, converted to the type of their definitions and validated.
+// Without a product type store the attributes are kept as plain strings.
+func (i *CSVImporter) applyProductType(ctx context.Context, row *csvRow, p *domain.Product) error {
+	var t *domain.ProductType
+	if row.ProductType != "" && i.productTypes != nil {
+		var err error
+		if t, err = i.ensureProductType(ctx, row.ProductType); err != nil {
+			return err
+		}
+		p.ProductTypeID = t.ID
+	}
+
+	attrs := map[string]interface{}{}
+	for name, values := range row.Attributes {
+		if t == nil {
+			attrs[name] = rawAttributeValue(values)
+			continue
+		}
+		def, ok := t.Attribute(name)
+		if !ok {
+			return fmt.Errorf("attribute %q is not defined on product type %s", name, t.Key)
+		}
+		v, err := coerceAttribute(def.Type, values)
+		if err != nil {
+			return fmt.Errorf("attribute %q: %w", name, err)
+		}
+		attrs[name] = v
+	}
+	if len(attrs) > 0 {
+		p.Current.MasterVariant.Attributes = attrs
+	}
+	if t == nil {
+		return nil
+	}
+	return t.ValidateAttributes(&p.Current)
+}
+
+func (i *CSVImporter) ensureProductType(ctx context.Context, key string) (*domain.ProductType, error) {
+	if t, ok := i.productTypeByKey[key]; ok {
+		return t, nil
+	}
+	t, err := i.productTypes.GetByKey(ctx, i.projectID, key)
+	if errors.Is(err, domain.ErrNotFound) {
+		t, err = i.productTypes.Create(ctx, i.inferProductType(key))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("product type %q: %w", key, err)
+	}
+	i.productTypeByKey[key] = t
+	return t, nil
+}
+
+func (i *CSVImporter) ensureTaxCategory(ctx context.Context, key string) (string, error) {
+	if id, ok := i.taxCategoryByKey[key]; ok {
+		return id, nil
+	}
+	c, err := i.taxCategories.GetByKey(ctx, i.projectID, key)
+	if errors.Is(err, domain.ErrNotFound) {
+		c, err = i.taxCategories.Create(ctx, domain.TaxCategory{
+			ProjectID: i.projectID,
+			Key:       key,
+			Name:      displayNameFromKey(normalizeCategoryKey(key)),
+		})
+	}
+	if err != nil {
+		return "", fmt.Errorf("tax category %q: %w", key, err)
+	}
+	i.taxCategoryByKey[key] = c.ID
+	return c.ID, nil
+}
+
+// inferProductType builds a product type for a key that does not exist yet: every
+// attribute column becomes an optional text attribute, or ltext when it has locales.
+func (i *CSVImporter) inferProductType(key string) domain.ProductType {
+	t := domain.ProductType{
+		ProjectID:  i.projectID,
+		Key:        key,
+		Name:       displayNameFromKey(normalizeCategoryKey(key)),
+		Attributes: []domain.AttributeDefinition{},
+	}
+	localized := map[string]bool{}
+	var names []string
+	for _, col := range i.attributeColumns {
+		if _, seen := localized[col.Name]; !seen {
+			names = append(names, col.Name)
+		}
+		localized[col.Name] = localized[col.Name] || col.Locale != ""
+	}
+	for _, name := range names {
+		typeName := domain.AttributeTypeText
+		if localized[name] {
+			typeName = domain.AttributeTypeLText
+		}
+		t.Attributes = append(t.Attributes, domain.AttributeDefinition{
+			Name:                name,
+			Label:               domain.Localized(domain.DefaultLocale, displayNameFromKey(name)),
+			Type:                domain.AttributeType{Name: typeName},
+			AttributeConstraint: domain.AttributeConstraintNone,
+			IsSearchable:        true,
+			InputHint:           "SingleLine",
+		})
+	}
+	return t
+}
+
+func rawAttributeValue(values domain.LocalizedString) interface{} {
+	if plain, ok := values[""]; ok && len(values) == 1 {
+		return plain
+	}
+	return localizedAttribute(values)
+}
+
+func localizedAttribute(values domain.LocalizedString) map[string]interface{} {
+	out := map[string]interface{}{}
+	for locale, v := range values {
+		if locale == "" {
+			locale = domain.DefaultLocale
+		}
+		out[locale] = v
+	}
+	return out
+}
+
+// coerceAttribute converts CSV cell values to the JSON shape of the attribute type.
+// Sets are ';' separated and money is written as "<currency> <centAmount>".
+func coerceAttribute(t domain.AttributeType, values domain.LocalizedString) (interface{}, error) {
+	if t.Name == domain.AttributeTypeLText {
+		return localizedAttribute(values), nil
+	}
+	raw, ok := values[""]
+	if !ok {
+		raw = values.Get(domain.DefaultLocale)
+	}
+	return coerceScalar(t, raw)
+}
+
+func coerceScalar(t domain.AttributeType, raw string) (interface{}, error) {
+	switch t.Name {
+	case domain.AttributeTypeBoolean:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean %q", raw)
+		}
+		return b, nil
+	case domain.AttributeTypeNumber:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", raw)
+		}
+		return n, nil
+	case domain.AttributeTypeLText:
+		return map[string]interface{}{domain.DefaultLocale: raw}, nil
+	case domain.AttributeTypeMoney:
+		currency, amount, _ := strings.Cut(raw, " ")
+		cents, err := strconv.ParseInt(strings.TrimSpace(amount), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid money %q", raw)
+		}
+		return map[string]interface{}{"currencyCode": currency, "centAmount": cents}, nil
+	case domain.AttributeTypeReference:
+		return map[string]interface{}{"typeId": t.ReferenceTypeID, "id": raw}, nil
+	case domain.AttributeTypeSet:
+		if t.ElementType == nil {
+			return nil, errors.New("set type without element type")
+		}
+		var out []interface{}
+		for _, part := range strings.Split(raw, ";") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			v, err := coerceScalar(*t.ElementType, part)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	}
+	return raw, nil
+}
@@ -57,20 +57,22 @@ func TestCSVImporter_Run(t *testing.T) {
 		t.Fatalf("expected 2 products saved, got %d", len(repo.items))
 	}
 
-	if len(repo.items[0].Attributes["images"].([]string)) != 2 {
+	first := repo.items[0]
+	master := first.Current.MasterVariant
+	if len(master.Images) != 2 {
 		t.Fatalf("expected 2 images on first product")
 	}
-	if repo.items[0].Key != "prod-1" || repo.items[0].SKU != "SKU-1" || repo.items[0].PriceCents != 100 || repo.items[0].Currency != "EUR" {
-		t.Fatalf("unexpected product data: %+v", repo.items[0])
+	if first.Key != "prod-1" || master.SKU != "SKU-1" || len(master.Prices) != 1 || master.Prices[0].Value.CentAmount != 100 || master.Prices[0].Value.CurrencyCode != "EUR" {
+		t.Fatalf("unexpected product data: %+v", first)
 	}
-	if repo.items[0].ID != "00000000-0000-0000-0000-000000000001" {
-		t.Fatalf("expected id to be preserved, got %s", repo.items[0].ID)
+	if first.ID != "00000000-0000-0000-0000-000000000001" {
+		t.Fatalf("expected id to be preserved, got %s", first.ID)
 	}
-	if cats, ok := repo.items[0].Attributes["categories"].([]string); !ok || len(cats) != 2 || cats[0] != "id-cat-1" || cats[1] != "id-cat-2" {
-		t.Fatalf("expected category IDs on first product, got %+v", repo.items[0].Attributes["categories"])
+	if slug := first.Current.Slug; len(slug) != 1 || slug["en"] != "prod-1" {
+		t.Fatalf("expected slug derived from key, got %+v", slug)
 	}
-	if keys, ok := repo.items[0].Attributes["categoryKeys"].([]string); !ok || len(keys) != 2 || keys[0] != "cat-1" || keys[1] != "cat-2" {
-		t.Fatalf("expected category keys preserved on first product, got %+v", repo.items[0].Attributes["categoryKeys"])
+	if cats := first.Current.CategoryIDs; len(cats) != 2 || cats[0] != "id-cat-1" || cats[1] != "id-cat-2" {
+		t.Fatalf("expected category IDs on first product, got %+v", cats)
 	}
 	if len(catRepo.items) != 3 { // cat-1, cat-2, productType fallback (succulents)
 		t.Fatalf("expected 3 category upserts, got %d", len(catRepo.items))
@@ -95,17 +97,203 @@ succulents,Succulents,,,,"",,Meta desc succ
 	if count != 5 {
 		t.Fatalf("expected 5 categories imported, got %d", count)
 	}
-	if catRepo.items[0].Key != "indoor-pots" || catRepo.items[0].OrderHint != "4.1" || catRepo.items[0].ParentKey != "pots" || catRepo.items[0].Description != "Desc indoor" || catRepo.items[0].MetaTitle != "Meta indoor" || catRepo.items[0].MetaDescription != "Meta desc indoor" {
-		t.Fatalf("unexpected first category %+v", catRepo.items[0])
+	// Roots are stored before children so parents can be referenced by id.
+	if catRepo.items[0].Key != "succulents" || catRepo.items[1].Key != "indoor-plants" || catRepo.items[2].Key != "pots" {
+		t.Fatalf("expected root categories first, got %v, %v, %v", catRepo.items[0].Key, catRepo.items[1].Key, catRepo.items[2].Key)
 	}
-	if catRepo.items[1].Key != "foliage-plants" || catRepo.items[1].Slug != "foliage-plants" || catRepo.items[1].ParentKey != "indoor-plants" {
-		t.Fatalf("expected slug fallback and inferred parent on second: %+v", catRepo.items[1])
+	indoorPots := catRepo.byKey["indoor-pots"]
+	if indoorPots.OrderHint != "4.1" || indoorPots.ParentID != "id-pots" || indoorPots.Description["en"] != "Desc indoor" || indoorPots.MetaTitle["en"] != "Meta indoor" || indoorPots.MetaDescription["en"] != "Meta desc indoor" {
+		t.Fatalf("unexpected indoor-pots category %+v", indoorPots)
 	}
-	if catRepo.items[2].Name != "Succulents" || catRepo.items[2].ParentKey != "" {
-		t.Fatalf("expected title-cased root name, got %+v", catRepo.items[2])
+	foliage := catRepo.byKey["foliage-plants"]
+	if foliage.Slug["en"] != "foliage-plants" || foliage.ParentID != "id-indoor-plants" {
+		t.Fatalf("expected slug fallback and inferred parent on foliage-plants: %+v", foliage)
 	}
-	if catRepo.items[3].Key != "indoor-plants" || catRepo.items[4].Key != "pots" {
-		t.Fatalf("expected root categories to be imported, got %v and %v", catRepo.items[3].Key, catRepo.items[4].Key)
+	if succ := catRepo.byKey["succulents"]; succ.Name["en"] != "Succulents" || succ.ParentID != "" {
+		t.Fatalf("expected title-cased root name, got %+v", succ)
+	}
+}
+
+func TestCSVImporter_RunCategoriesFileCreatesMissingParent(t *testing.T) {
+	csvData := `key,name.en,slug.en,parent.key,orderHint
+echeveria,Echeveria,echeveria,succulent-types,1.1
+`
+	catRepo := &stubCategoryRepo{}
+	imp := NewCSVImporter(strings.NewReader(csvData), nil, catRepo, "project-123", "project-123", WithMedia("", ""))
+
+	if _, err := imp.Run(context.Background()); err != nil {
+		t.Fatalf("import run: %v", err)
+	}
+	if len(catRepo.items) != 2 || catRepo.items[0].Key != "succulent" {
+		t.Fatalf("expected placeholder parent to be created first, got %+v", catRepo.items)
+	}
+	if child := catRepo.byKey["echeveria"]; child.ParentID != "id-succulent" {
+		t.Fatalf("expected parent id on child, got %+v", child)
+	}
+}
+
+func TestCSVImporter_RunImportsAllLocales(t *testing.T) {
+	csvData := `key,name.en,name.de-DE,slug.en,slug.de-DE,description.de-DE,metaTitle.en,searchKeywords.en,searchKeywords.de-DE,variants.sku,variants.prices.value.centAmount,variants.prices.value.currencyCode
+aloe,Aloe,Aloe DE,aloe,aloe-de,Beschreibung,Aloe meta,plant;green,pflanze|grün,SKU-A,100,EUR`
+
+	repo := &stubProductRepo{}
+	imp := NewCSVImporter(strings.NewReader(csvData), repo, &stubCategoryRepo{}, "project-123", "project-123", WithMedia("", ""))
+	if _, err := imp.Run(context.Background()); err != nil {
+		t.Fatalf("import run: %v", err)
+	}
+	if len(repo.items) != 1 {
+		t.Fatalf("expected 1 product, got %d", len(repo.items))
+	}
+	p := repo.items[0].Current
+	if p.Name["en"] != "Aloe" || p.Name["de-DE"] != "Aloe DE" {
+		t.Fatalf("unexpected names %+v", p.Name)
+	}
+	if p.Slug["en"] != "aloe" || p.Slug["de-DE"] != "aloe-de" {
+		t.Fatalf("unexpected slugs %+v", p.Slug)
+	}
+	if _, ok := p.Description["en"]; ok || p.Description["de-DE"] != "Beschreibung" {
+		t.Fatalf("unexpected description %+v", p.Description)
+	}
+	if p.MetaTitle["en"] != "Aloe meta" {
+		t.Fatalf("unexpected meta title %+v", p.MetaTitle)
+	}
+	if kw := p.SearchKeywords["en"]; len(kw) != 2 || kw[0] != "plant" || kw[1] != "green" {
+		t.Fatalf("unexpected en keywords %+v", p.SearchKeywords)
+	}
+	if kw := p.SearchKeywords["de-DE"]; len(kw) != 2 || kw[1] != "grün" {
+		t.Fatalf("unexpected de keywords %+v", p.SearchKeywords)
+	}
+}
+
+type stubProductTypeStore struct {
+	byKey   map[string]domain.ProductType
+	created []domain.ProductType
+}
+
+func (s *stubProductTypeStore) GetByKey(_ context.Context, _ string, key string) (*domain.ProductType, error) {
+	t, ok := s.byKey[key]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &t, nil
+}
+
+func (s *stubProductTypeStore) Create(_ context.Context, t domain.ProductType) (*domain.ProductType, error) {
+	if s.byKey == nil {
+		s.byKey = make(map[string]domain.ProductType)
+	}
+	t.ID = "pt-" + t.Key
+	s.byKey[t.Key] = t
+	s.created = append(s.created, t)
+	return &t, nil
+}
+
+func TestCSVImporter_RunLinksProductTypes(t *testing.T) {
+	csvData := `key,name.en,productType.key,variants.sku,variants.prices.value.centAmount,variants.prices.value.currencyCode,variants.attributes.height,variants.attributes.care.en,variants.attributes.care.de
+aloe,Aloe,succulent-types,SKU-A,100,EUR,12.5,Water weekly,Wöchentlich gießen`
+
+	store := &stubProductTypeStore{}
+	repo := &stubProductRepo{}
+	imp := NewCSVImporter(strings.NewReader(csvData), repo, nil, "project-123", "project-123", WithMedia("", ""), WithProductTypes(store))
+	if _, err := imp.Run(context.Background()); err != nil {
+		t.Fatalf("import run: %v", err)
+	}
+	if len(store.created) != 1 || store.created[0].Key != "succulent-types" {
+		t.Fatalf("expected inferred product type, got %+v", store.created)
+	}
+	if def, ok := store.created[0].Attribute("care"); !ok || def.Type.Name != domain.AttributeTypeLText {
+		t.Fatalf("expected ltext care attribute, got %+v", store.created[0].Attributes)
+	}
+	p := repo.items[0]
+	if p.ProductTypeID != "pt-succulent-types" {
+		t.Fatalf("expected product type link, got %q", p.ProductTypeID)
+	}
+	attrs := p.Current.MasterVariant.Attributes
+	if attrs["height"] != "12.5" {
+		t.Fatalf("expected text height, got %#v", attrs["height"])
+	}
+	if care, ok := attrs["care"].(map[string]string); !ok || care["de"] != "Wöchentlich gießen" {
+		t.Fatalf("expected localized care, got %#v", attrs["care"])
+	}
+}
+
+func TestCSVImporter_RunValidatesAttributes(t *testing.T) {
+	store := &stubProductTypeStore{byKey: map[string]domain.ProductType{
+		"plant": {ID: "pt-plant", Key: "plant", Attributes: []domain.AttributeDefinition{
+			{Name: "height", Type: domain.AttributeType{Name: domain.AttributeTypeNumber}, IsRequired: true},
+		}},
+	}}
+
+	valid := `key,name.en,productType.key,variants.sku,variants.prices.value.centAmount,variants.prices.value.currencyCode,variants.attributes.height
+aloe,Aloe,plant,SKU-A,100,EUR,12.5`
+	repo := &stubProductRepo{}
+	imp := NewCSVImporter(strings.NewReader(valid), repo, nil, "project-123", "project-123", WithMedia("", ""), WithProductTypes(store))
+	if _, err := imp.Run(context.Background()); err != nil {
+		t.Fatalf("import run: %v", err)
+	}
+	if h := repo.items[0].Current.MasterVariant.Attributes["height"]; h != 12.5 {
+		t.Fatalf("expected numeric height, got %#v", h)
+	}
+
+	cases := map[string]string{
+		"not a number": `key,name.en,productType.key,variants.sku,variants.prices.value.centAmount,variants.prices.value.currencyCode,variants.attributes.height
+aloe,Aloe,plant,SKU-A,100,EUR,tall`,
+		"missing required": `key,name.en,productType.key,variants.sku,variants.prices.value.centAmount,variants.prices.value.currencyCode
+aloe,Aloe,plant,SKU-A,100,EUR`,
+		"undefined attribute": `key,name.en,productType.key,variants.sku,variants.prices.value.centAmount,variants.prices.value.currencyCode,variants.attributes.height,variants.attributes.color
+aloe,Aloe,plant,SKU-A,100,EUR,12,red`,
+	}
+	for name, csvData := range cases {
+		imp := NewCSVImporter(strings.NewReader(csvData), &stubProductRepo{}, nil, "project-123", "project-123", WithMedia("", ""), WithProductTypes(store))
+		if _, err := imp.Run(context.Background()); err == nil {
+			t.Fatalf("%s: expected validation error", name)
+		}
+	}
+}
+
+type stubTaxCategoryStore struct {
+	byKey   map[string]domain.TaxCategory
+	created []domain.TaxCategory
+}
+
+func (s *stubTaxCategoryStore) GetByKey(_ context.Context, _ string, key string) (*domain.TaxCategory, error) {
+	c, ok := s.byKey[key]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &c, nil
+}
+
+func (s *stubTaxCategoryStore) Create(_ context.Context, c domain.TaxCategory) (*domain.TaxCategory, error) {
+	if s.byKey == nil {
+		s.byKey = make(map[string]domain.TaxCategory)
+	}
+	c.ID = "tc-" + c.Key
+	s.byKey[c.Key] = c
+	s.created = append(s.created, c)
+	return &c, nil
+}
+
+func TestCSVImporter_RunLinksTaxCategories(t *testing.T) {
+	csvData := `key,name.en,taxCategory.key,variants.sku,variants.prices.value.centAmount,variants.prices.value.currencyCode
+aloe,Aloe,standard-rate,SKU-A,100,EUR
+fern,Fern,standard-rate,SKU-F,200,EUR
+cactus,Cactus,,SKU-C,300,EUR`
+
+	store := &stubTaxCategoryStore{}
+	repo := &stubProductRepo{}
+	imp := NewCSVImporter(strings.NewReader(csvData), repo, nil, "project-123", "project-123", WithMedia("", ""), WithTaxCategories(store))
+	if _, err := imp.Run(context.Background()); err != nil {
+		t.Fatalf("import run: %v", err)
+	}
+	if len(store.created) != 1 || store.created[0].Key != "standard-rate" || store.created[0].Name != "Standard Rate" {
+		t.Fatalf("expected one inferred tax category, got %+v", store.created)
+	}
+	if repo.items[0].TaxCategoryID != "tc-standard-rate" || repo.items[1].TaxCategoryID != "tc-standard-rate" {
+		t.Fatalf("expected tax category links, got %q and %q", repo.items[0].TaxCategoryID, repo.items[1].TaxCategoryID)
+	}
+	if repo.items[2].TaxCategoryID != "" {
+		t.Fatalf("expected no tax category, got %q", repo.items[2].TaxCategoryID)
 	}
 }
 
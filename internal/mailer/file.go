@@ -0,0 +1,83 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// File writes each message as a JSON file into a maildir-style directory:
+// files are written to tmp/ and renamed into new/, so readers never see a
+// partial message.
+type File struct {
+	dir string
+}
+
+// NewFile creates the tmp/ and new/ directories below dir.
+func NewFile(dir string) (*File, error) {
+	for _, sub := range []string{"tmp", "new"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &File{dir: dir}, nil
+}
+
+func (f *File) Send(_ context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+	if msg.SentAt.IsZero() {
+		msg.SentAt = time.Now().UTC()
+	}
+	data, err := json.MarshalIndent(msg, "", "  ")
+	if err != nil {
+		return err
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	// The zero-padded timestamp keeps names in send order.
+	name := fmt.Sprintf("%020d-%s.json", msg.SentAt.UnixNano(), hex.EncodeToString(suffix))
+	tmp := filepath.Join(f.dir, "tmp", name)
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(f.dir, "new", name))
+}
+
+// Messages reads the delivered messages, oldest first.
+func (f *File) Messages() ([]Message, error) {
+	entries, err := os.ReadDir(filepath.Join(f.dir, "new"))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	out := make([]Message, 0, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(f.dir, "new", name))
+		if err != nil {
+			return nil, err
+		}
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return nil, fmt.Errorf("decode %s: %w", name, err)
+		}
+		out = append(out, msg)
+	}
+	return out, nil
+}
@@ -0,0 +1,50 @@
+// Package mailer delivers outbound customer email such as password reset and
+// email verification tokens.
+package mailer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Message kinds rendered by Templates; they match the token kinds of the
+// customer service.
+const (
+	KindPasswordReset     = "password-reset"
+	KindEmailVerification = "email-verification"
+)
+
+// Message is one outbound email. Kind, ProjectKey and Token are not sent over
+// SMTP; the file and memory sinks keep them so tests can find the token
+// without parsing the body.
+type Message struct {
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	Subject    string    `json:"subject"`
+	Body       string    `json:"body"`
+	Kind       string    `json:"kind"`
+	ProjectKey string    `json:"projectKey"`
+	Locale     string    `json:"locale,omitempty"`
+	Token      string    `json:"token,omitempty"`
+	SentAt     time.Time `json:"sentAt"`
+}
+
+// Mailer hands a message to a sink.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// validate rejects messages that cannot be delivered or would inject headers.
+func (m Message) validate() error {
+	if strings.TrimSpace(m.To) == "" {
+		return errors.New("recipient required")
+	}
+	for _, v := range []string{m.From, m.To, m.Subject} {
+		if strings.ContainsAny(v, "\r\n") {
+			return errors.New("line break in mail header")
+		}
+	}
+	return nil
+}
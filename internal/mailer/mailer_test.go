@@ -0,0 +1,129 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplatesRender(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"shop/de/password-reset.tmpl":               "Subject: Neues Passwort\n\nHallo {{.FirstName}}, {{.Token}}\n",
+		"_default/de-at/password-reset.tmpl":        "Subject: Servus\n\n{{.Token}}\n",
+		"_default/_default/email-verification.tmpl": "Subject: Verify {{.Email}}\n\nToken {{.Token}}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	templates := NewTemplates()
+	if err := templates.LoadDir(dir); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	data := TemplateData{Email: "me@example.com", FirstName: "Ada", Token: "tok", TTLMinutes: 15}
+
+	cases := []struct {
+		project, locale, kind string
+		subject, body         string
+	}{
+		{project: "shop", locale: "de-DE", kind: KindPasswordReset, subject: "Neues Passwort", body: "Hallo Ada, tok"},
+		{project: "shop", locale: "de-AT", kind: KindPasswordReset, subject: "Neues Passwort"},
+		{project: "other", locale: "de-AT", kind: KindPasswordReset, subject: "Servus"},
+		{project: "other", locale: "de-DE", kind: KindPasswordReset, subject: "Reset your password", body: "within 15 minutes"},
+		{project: "shop", locale: "en", kind: KindEmailVerification, subject: "Verify me@example.com", body: "Token tok"},
+	}
+	for _, tc := range cases {
+		subject, body, err := templates.Render(tc.project, tc.locale, tc.kind, data)
+		if err != nil {
+			t.Fatalf("%s/%s/%s: %v", tc.project, tc.locale, tc.kind, err)
+		}
+		if subject != tc.subject || !strings.Contains(body, tc.body) {
+			t.Fatalf("%s/%s/%s: got %q / %q", tc.project, tc.locale, tc.kind, subject, body)
+		}
+	}
+	if _, _, err := templates.Render("shop", "en", "unknown", data); err == nil {
+		t.Fatalf("expected an error for an unknown kind")
+	}
+
+	bad := t.TempDir()
+	path := filepath.Join(bad, "shop", "en", "password-reset.tmpl")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("no subject"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewTemplates().LoadDir(bad); err == nil {
+		t.Fatalf("expected an error for a template without subject")
+	}
+}
+
+func TestSinks(t *testing.T) {
+	ctx := context.Background()
+	file, err := NewFile(t.TempDir())
+	if err != nil {
+		t.Fatalf("file sink: %v", err)
+	}
+	memory := NewMemory()
+	for _, sink := range []Mailer{file, memory} {
+		outbox := NewOutbox(sink, nil, "shop@example.com")
+		for _, token := range []string{"first", "second"} {
+			if err := outbox.SendToken(ctx, KindPasswordReset, TemplateData{ProjectKey: "shop", Email: "me@example.com", Token: token}); err != nil {
+				t.Fatalf("send: %v", err)
+			}
+		}
+		if err := sink.Send(ctx, Message{To: "me@example.com\r\nBcc: x@example.com"}); err == nil {
+			t.Fatalf("expected header injection to be rejected")
+		}
+	}
+
+	stored, err := file.Messages()
+	if err != nil {
+		t.Fatalf("read file sink: %v", err)
+	}
+	if len(stored) != 2 || stored[1].Token != "second" || stored[1].From != "shop@example.com" || stored[1].Kind != KindPasswordReset {
+		t.Fatalf("unexpected file messages: %+v", stored)
+	}
+	last, ok := memory.Last("me@example.com", KindPasswordReset)
+	if !ok || last.Token != "second" || !strings.Contains(last.Body, "second") {
+		t.Fatalf("unexpected memory message: %+v", last)
+	}
+	if _, ok := memory.Last("me@example.com", KindEmailVerification); ok {
+		t.Fatalf("expected no verification message")
+	}
+
+	var dropped *Outbox
+	if err := dropped.SendToken(ctx, KindPasswordReset, TemplateData{Email: "me@example.com"}); err != nil {
+		t.Fatalf("nil outbox: %v", err)
+	}
+}
+
+func TestBuildMessage(t *testing.T) {
+	msg := buildMessage(Message{
+		From:    "shop@example.com",
+		To:      "me@example.com",
+		Subject: "Passwort zurücksetzen",
+		Body:    "line one\nline two\n",
+		SentAt:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	got := string(msg)
+	for _, want := range []string{
+		"To: me@example.com\r\n",
+		"Subject: =?utf-8?q?Passwort_zur=C3=BCcksetzen?=\r\n",
+		"Date: Tue, 02 Jan 2024 03:04:05 +0000\r\n",
+		"\r\n\r\nline one\r\nline two\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in %q", want, got)
+		}
+	}
+}
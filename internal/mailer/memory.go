@@ -0,0 +1,50 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory keeps sent messages in memory for tests.
+type Memory struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemory returns an empty in-memory sink.
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Send(_ context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+	if msg.SentAt.IsZero() {
+		msg.SentAt = time.Now().UTC()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns the sent messages, oldest first.
+func (m *Memory) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
+
+// Last returns the latest message of the kind sent to the address.
+func (m *Memory) Last(to, kind string) (Message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To == to && m.messages[i].Kind == kind {
+			return m.messages[i], true
+		}
+	}
+	return Message{}, false
+}
@@ -0,0 +1,45 @@
+package mailer
+
+import (
+	"context"
+	"time"
+)
+
+// Outbox renders messages from Templates and hands them to a Mailer. A nil
+// Outbox, or one without a Mailer, drops every message.
+type Outbox struct {
+	mailer    Mailer
+	templates *Templates
+	from      string
+}
+
+// NewOutbox returns an Outbox sending as from; nil templates mean the
+// defaults.
+func NewOutbox(m Mailer, templates *Templates, from string) *Outbox {
+	if templates == nil {
+		templates = NewTemplates()
+	}
+	return &Outbox{mailer: m, templates: templates, from: from}
+}
+
+// SendToken renders the kind template for data and sends it to data.Email.
+func (o *Outbox) SendToken(ctx context.Context, kind string, data TemplateData) error {
+	if o == nil || o.mailer == nil {
+		return nil
+	}
+	subject, body, err := o.templates.Render(data.ProjectKey, data.Locale, kind, data)
+	if err != nil {
+		return err
+	}
+	return o.mailer.Send(ctx, Message{
+		From:       o.from,
+		To:         data.Email,
+		Subject:    subject,
+		Body:       body,
+		Kind:       kind,
+		ProjectKey: data.ProjectKey,
+		Locale:     data.Locale,
+		Token:      data.Token,
+		SentAt:     time.Now().UTC(),
+	})
+}
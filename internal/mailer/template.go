@@ -0,0 +1,160 @@
+package mailer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultDir names the project or locale directory of LoadDir that applies to
+// every project or locale.
+const DefaultDir = "_default"
+
+// TemplateData is what message templates can use.
+type TemplateData struct {
+	ProjectKey string
+	Locale     string
+	Email      string
+	FirstName  string
+	LastName   string
+	Token      string
+	ExpiresAt  time.Time
+	TTLMinutes int
+}
+
+type templateKey struct {
+	project string
+	locale  string
+	kind    string
+}
+
+type messageTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+// Templates holds the subject and body templates per project, locale and
+// message kind.
+type Templates struct {
+	byKey map[templateKey]messageTemplate
+}
+
+// NewTemplates returns Templates with English defaults for every kind.
+func NewTemplates() *Templates {
+	t := &Templates{byKey: make(map[templateKey]messageTemplate)}
+	for kind, def := range defaultTemplates {
+		if err := t.Add("", "", kind, def[0], def[1]); err != nil {
+			panic(err)
+		}
+	}
+	return t
+}
+
+var defaultTemplates = map[string][2]string{
+	KindPasswordReset: {
+		"Reset your password",
+		"Hello {{if .FirstName}}{{.FirstName}}{{else}}{{.Email}}{{end}},\n\n" +
+			"use this token to set a new password within {{.TTLMinutes}} minutes:\n\n{{.Token}}\n\n" +
+			"If you did not ask for a new password, you can ignore this message.\n",
+	},
+	KindEmailVerification: {
+		"Confirm your email address",
+		"Hello {{if .FirstName}}{{.FirstName}}{{else}}{{.Email}}{{end}},\n\n" +
+			"use this token to confirm {{.Email}} within {{.TTLMinutes}} minutes:\n\n{{.Token}}\n",
+	},
+}
+
+// Add registers the templates of kind for a project key and locale; empty
+// projectKey or locale apply to all projects or locales.
+func (t *Templates) Add(projectKey, locale, kind, subject, body string) error {
+	if strings.TrimSpace(kind) == "" {
+		return errors.New("template kind required")
+	}
+	name := strings.Join([]string{projectKey, locale, kind}, "/")
+	subj, err := template.New(name + " subject").Option("missingkey=error").Parse(subject)
+	if err != nil {
+		return err
+	}
+	b, err := template.New(name).Option("missingkey=error").Parse(body)
+	if err != nil {
+		return err
+	}
+	t.byKey[templateKey{project: projectKey, locale: strings.ToLower(locale), kind: kind}] = messageTemplate{subject: subj, body: b}
+	return nil
+}
+
+// LoadDir adds the templates found as <dir>/<projectKey>/<locale>/<kind>.tmpl,
+// using DefaultDir for all projects or locales. A file starts with a
+// "Subject: " line and a blank line, followed by the body.
+func (t *Templates) LoadDir(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*", "*", "*.tmpl"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		project, locale := parts[0], parts[1]
+		if project == DefaultDir {
+			project = ""
+		}
+		if locale == DefaultDir {
+			locale = ""
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		subject, body, ok := strings.Cut(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n\n")
+		if !ok || !strings.HasPrefix(subject, "Subject: ") || strings.Contains(subject, "\n") {
+			return fmt.Errorf("%s: expected a Subject line and a blank line", rel)
+		}
+		kind := strings.TrimSuffix(parts[2], ".tmpl")
+		if err := t.Add(project, locale, kind, strings.TrimPrefix(subject, "Subject: "), body); err != nil {
+			return fmt.Errorf("%s: %w", rel, err)
+		}
+	}
+	return nil
+}
+
+// Render picks the most specific template of kind: the project before all
+// projects, then the full locale, its language and finally any locale.
+func (t *Templates) Render(projectKey, locale, kind string, data TemplateData) (string, string, error) {
+	tmpl, ok := t.lookup(projectKey, locale, kind)
+	if !ok {
+		return "", "", fmt.Errorf("no %s template", kind)
+	}
+	var subject, body bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return "", "", err
+	}
+	if err := tmpl.body.Execute(&body, data); err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(subject.String()), body.String(), nil
+}
+
+func (t *Templates) lookup(projectKey, locale, kind string) (messageTemplate, bool) {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	locales := []string{locale}
+	if lang, _, ok := strings.Cut(locale, "-"); ok {
+		locales = append(locales, lang)
+	}
+	locales = append(locales, "")
+	for _, project := range []string{projectKey, ""} {
+		for _, l := range locales {
+			if tmpl, ok := t.byKey[templateKey{project: project, locale: l, kind: kind}]; ok {
+				return tmpl, true
+			}
+		}
+	}
+	return messageTemplate{}, false
+}
@@ -0,0 +1,14 @@
+ALTER TABLE categories
+    ADD COLUMN IF NOT EXISTS parent_key TEXT;
+
+UPDATE categories c
+SET parent_key = p.key
+FROM categories p
+WHERE c.parent_id = p.id;
+
+DROP INDEX IF EXISTS idx_categories_ancestors;
+DROP INDEX IF EXISTS idx_categories_parent;
+
+ALTER TABLE categories
+    DROP COLUMN IF EXISTS ancestor_ids,
+    DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,31 @@
+ALTER TABLE categories
+    ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES categories(id),
+    ADD COLUMN IF NOT EXISTS ancestor_ids UUID[] NOT NULL DEFAULT '{}';
+
+UPDATE categories c
+SET parent_id = p.id
+FROM categories p
+WHERE c.parent_key IS NOT NULL
+  AND p.project_id = c.project_id
+  AND p.key = c.parent_key
+  AND p.id <> c.id;
+
+WITH RECURSIVE tree AS (
+    SELECT id, ARRAY[]::uuid[] AS path
+    FROM categories
+    WHERE parent_id IS NULL
+    UNION ALL
+    SELECT c.id, t.path || c.parent_id
+    FROM categories c
+    JOIN tree t ON c.parent_id = t.id
+)
+UPDATE categories c
+SET ancestor_ids = tree.path
+FROM tree
+WHERE tree.id = c.id;
+
+ALTER TABLE categories
+    DROP COLUMN IF EXISTS parent_key;
+
+CREATE INDEX IF NOT EXISTS idx_categories_parent ON categories(parent_id);
+CREATE INDEX IF NOT EXISTS idx_categories_ancestors ON categories USING GIN (ancestor_ids);
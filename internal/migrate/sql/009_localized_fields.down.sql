@@ -0,0 +1,28 @@
+ALTER TABLE categories
+    ALTER COLUMN slug DROP NOT NULL,
+    ALTER COLUMN slug DROP DEFAULT,
+    ALTER COLUMN description DROP NOT NULL,
+    ALTER COLUMN description DROP DEFAULT,
+    ALTER COLUMN meta_title DROP NOT NULL,
+    ALTER COLUMN meta_title DROP DEFAULT,
+    ALTER COLUMN meta_description DROP NOT NULL,
+    ALTER COLUMN meta_description DROP DEFAULT;
+
+ALTER TABLE categories
+    ALTER COLUMN name TYPE TEXT USING COALESCE(name->>'en', ''),
+    ALTER COLUMN slug TYPE TEXT USING NULLIF(slug->>'en', ''),
+    ALTER COLUMN description TYPE TEXT USING NULLIF(description->>'en', ''),
+    ALTER COLUMN meta_title TYPE TEXT USING NULLIF(meta_title->>'en', ''),
+    ALTER COLUMN meta_description TYPE TEXT USING NULLIF(meta_description->>'en', '');
+
+ALTER TABLE products
+    ALTER COLUMN description DROP NOT NULL,
+    ALTER COLUMN description DROP DEFAULT;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS search_keywords,
+    DROP COLUMN IF EXISTS meta_description,
+    DROP COLUMN IF EXISTS meta_title,
+    DROP COLUMN IF EXISTS slug,
+    ALTER COLUMN name TYPE TEXT USING COALESCE(name->>'en', ''),
+    ALTER COLUMN description TYPE TEXT USING NULLIF(description->>'en', '');
@@ -0,0 +1,43 @@
+ALTER TABLE products
+    ALTER COLUMN name TYPE JSONB USING jsonb_build_object('en', name),
+    ALTER COLUMN description TYPE JSONB USING (
+        CASE WHEN COALESCE(description, '') = '' THEN '{}'::jsonb ELSE jsonb_build_object('en', description) END
+    ),
+    ADD COLUMN IF NOT EXISTS slug JSONB NOT NULL DEFAULT '{}'::jsonb,
+    ADD COLUMN IF NOT EXISTS meta_title JSONB NOT NULL DEFAULT '{}'::jsonb,
+    ADD COLUMN IF NOT EXISTS meta_description JSONB NOT NULL DEFAULT '{}'::jsonb,
+    ADD COLUMN IF NOT EXISTS search_keywords JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+ALTER TABLE products
+    ALTER COLUMN description SET DEFAULT '{}'::jsonb,
+    ALTER COLUMN description SET NOT NULL;
+
+-- Products used to expose a slug derived from their key.
+UPDATE products
+SET slug = jsonb_build_object('en', replace(lower(key), ' ', '-'))
+WHERE slug = '{}'::jsonb;
+
+ALTER TABLE categories
+    ALTER COLUMN name TYPE JSONB USING jsonb_build_object('en', name),
+    ALTER COLUMN slug TYPE JSONB USING (
+        CASE WHEN COALESCE(slug, '') = '' THEN '{}'::jsonb ELSE jsonb_build_object('en', slug) END
+    ),
+    ALTER COLUMN description TYPE JSONB USING (
+        CASE WHEN COALESCE(description, '') = '' THEN '{}'::jsonb ELSE jsonb_build_object('en', description) END
+    ),
+    ALTER COLUMN meta_title TYPE JSONB USING (
+        CASE WHEN COALESCE(meta_title, '') = '' THEN '{}'::jsonb ELSE jsonb_build_object('en', meta_title) END
+    ),
+    ALTER COLUMN meta_description TYPE JSONB USING (
+        CASE WHEN COALESCE(meta_description, '') = '' THEN '{}'::jsonb ELSE jsonb_build_object('en', meta_description) END
+    );
+
+ALTER TABLE categories
+    ALTER COLUMN slug SET DEFAULT '{}'::jsonb,
+    ALTER COLUMN slug SET NOT NULL,
+    ALTER COLUMN description SET DEFAULT '{}'::jsonb,
+    ALTER COLUMN description SET NOT NULL,
+    ALTER COLUMN meta_title SET DEFAULT '{}'::jsonb,
+    ALTER COLUMN meta_title SET NOT NULL,
+    ALTER COLUMN meta_description SET DEFAULT '{}'::jsonb,
+    ALTER COLUMN meta_description SET NOT NULL;
@@ -0,0 +1,51 @@
+ALTER TABLE cart_lines
+    DROP COLUMN IF EXISTS variant_id;
+
+UPDATE products SET key = id::text WHERE key IS NULL;
+
+ALTER TABLE products
+    ALTER COLUMN key SET NOT NULL;
+
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS sku TEXT,
+    ADD COLUMN IF NOT EXISTS name JSONB,
+    ADD COLUMN IF NOT EXISTS slug JSONB NOT NULL DEFAULT '{}'::jsonb,
+    ADD COLUMN IF NOT EXISTS description JSONB NOT NULL DEFAULT '{}'::jsonb,
+    ADD COLUMN IF NOT EXISTS meta_title JSONB NOT NULL DEFAULT '{}'::jsonb,
+    ADD COLUMN IF NOT EXISTS meta_description JSONB NOT NULL DEFAULT '{}'::jsonb,
+    ADD COLUMN IF NOT EXISTS search_keywords JSONB NOT NULL DEFAULT '{}'::jsonb,
+    ADD COLUMN IF NOT EXISTS price_cents BIGINT,
+    ADD COLUMN IF NOT EXISTS currency CHAR(3),
+    ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+UPDATE products
+SET sku = COALESCE(current_data->'masterVariant'->>'sku', id::text),
+    name = COALESCE(current_data->'name', '{}'::jsonb),
+    slug = COALESCE(current_data->'slug', '{}'::jsonb),
+    description = COALESCE(current_data->'description', '{}'::jsonb),
+    meta_title = COALESCE(current_data->'metaTitle', '{}'::jsonb),
+    meta_description = COALESCE(current_data->'metaDescription', '{}'::jsonb),
+    search_keywords = COALESCE(current_data->'searchKeywords', '{}'::jsonb),
+    price_cents = COALESCE((current_data->'masterVariant'->'prices'->0->'value'->>'centAmount')::bigint, 0),
+    currency = COALESCE(current_data->'masterVariant'->'prices'->0->'value'->>'currencyCode', 'EUR'),
+    attributes = jsonb_strip_nulls(jsonb_build_object(
+        'images', current_data->'masterVariant'->'images',
+        'categories', current_data->'categoryIds'
+    ));
+
+ALTER TABLE products
+    ALTER COLUMN sku SET NOT NULL,
+    ALTER COLUMN name SET NOT NULL,
+    ALTER COLUMN price_cents SET NOT NULL,
+    ALTER COLUMN currency SET NOT NULL,
+    ADD CONSTRAINT products_project_id_sku_key UNIQUE (project_id, sku);
+
+DROP TABLE IF EXISTS product_skus;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS last_modified_at,
+    DROP COLUMN IF EXISTS staged_data,
+    DROP COLUMN IF EXISTS current_data,
+    DROP COLUMN IF EXISTS has_staged_changes,
+    DROP COLUMN IF EXISTS published,
+    DROP COLUMN IF EXISTS version;
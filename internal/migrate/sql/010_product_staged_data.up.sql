@@ -0,0 +1,64 @@
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS published BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS has_staged_changes BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS current_data JSONB NOT NULL DEFAULT '{}'::jsonb,
+    ADD COLUMN IF NOT EXISTS staged_data JSONB NOT NULL DEFAULT '{}'::jsonb,
+    ADD COLUMN IF NOT EXISTS last_modified_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- Existing rows came from the importer and were always served as published.
+UPDATE products
+SET current_data = jsonb_build_object(
+        'name', name,
+        'slug', slug,
+        'description', description,
+        'metaTitle', meta_title,
+        'metaDescription', meta_description,
+        'searchKeywords', search_keywords,
+        'categoryIds', CASE WHEN jsonb_typeof(attributes->'categories') = 'array' THEN attributes->'categories' ELSE '[]'::jsonb END,
+        'masterVariant', jsonb_build_object(
+            'id', 1,
+            'sku', sku,
+            'prices', jsonb_build_array(jsonb_build_object(
+                'id', gen_random_uuid()::text,
+                'value', jsonb_build_object('currencyCode', currency, 'centAmount', price_cents)
+            )),
+            'images', CASE WHEN jsonb_typeof(attributes->'images') = 'array' THEN attributes->'images' ELSE '[]'::jsonb END
+        )
+    ),
+    published = true,
+    last_modified_at = created_at;
+
+UPDATE products SET staged_data = current_data;
+
+-- SKUs live inside the JSONB variants; this table keeps them unique per project.
+CREATE TABLE IF NOT EXISTS product_skus (
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    sku TEXT NOT NULL,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    PRIMARY KEY (project_id, sku)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_skus_product ON product_skus(product_id);
+
+INSERT INTO product_skus (project_id, sku, product_id)
+SELECT project_id, sku, id FROM products;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS sku,
+    DROP COLUMN IF EXISTS name,
+    DROP COLUMN IF EXISTS slug,
+    DROP COLUMN IF EXISTS description,
+    DROP COLUMN IF EXISTS meta_title,
+    DROP COLUMN IF EXISTS meta_description,
+    DROP COLUMN IF EXISTS search_keywords,
+    DROP COLUMN IF EXISTS price_cents,
+    DROP COLUMN IF EXISTS currency,
+    DROP COLUMN IF EXISTS attributes;
+
+-- Keys are optional for products created through the API; NULLs never collide.
+ALTER TABLE products
+    ALTER COLUMN key DROP NOT NULL;
+
+ALTER TABLE cart_lines
+    ADD COLUMN IF NOT EXISTS variant_id INT NOT NULL DEFAULT 1;
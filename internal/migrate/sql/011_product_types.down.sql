@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_products_product_type;
+ALTER TABLE products DROP COLUMN IF EXISTS product_type_id;
+
+DROP TABLE IF EXISTS product_types;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS product_types (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    key TEXT,
+    version INT NOT NULL DEFAULT 1,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    attributes JSONB NOT NULL DEFAULT '[]'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_modified_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (project_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_types_project ON product_types(project_id);
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS product_type_id UUID REFERENCES product_types(id);
+
+CREATE INDEX IF NOT EXISTS idx_products_product_type ON products(product_type_id);
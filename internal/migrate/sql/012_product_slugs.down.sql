@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_categories_slug;
+
+DROP TABLE IF EXISTS product_slugs;
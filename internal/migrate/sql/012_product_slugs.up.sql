@@ -0,0 +1,33 @@
+-- Products without a slug used to get one synthesized from their key in responses.
+UPDATE products
+SET current_data = jsonb_set(current_data, '{slug}', jsonb_build_object('en', replace(lower(key), ' ', '-')))
+WHERE key IS NOT NULL AND COALESCE(current_data->'slug', '{}'::jsonb) = '{}'::jsonb;
+
+UPDATE products
+SET staged_data = jsonb_set(staged_data, '{slug}', jsonb_build_object('en', replace(lower(key), ' ', '-')))
+WHERE key IS NOT NULL AND COALESCE(staged_data->'slug', '{}'::jsonb) = '{}'::jsonb;
+
+CREATE TABLE IF NOT EXISTS product_slugs (
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    locale TEXT NOT NULL,
+    slug TEXT NOT NULL,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    PRIMARY KEY (project_id, locale, slug)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_slugs_product ON product_slugs(product_id);
+
+-- Existing duplicates keep the slug on the oldest product only.
+INSERT INTO product_slugs (project_id, locale, slug, product_id)
+SELECT p.project_id, s.key, s.value, p.id
+FROM products p
+CROSS JOIN LATERAL (
+    SELECT key, value FROM jsonb_each_text(p.current_data->'slug')
+    UNION
+    SELECT key, value FROM jsonb_each_text(p.staged_data->'slug')
+) s
+WHERE s.value <> ''
+ORDER BY p.created_at
+ON CONFLICT DO NOTHING;
+
+CREATE INDEX IF NOT EXISTS idx_categories_slug ON categories USING gin (slug jsonb_path_ops);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS product_discounts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    key TEXT,
+    version INT NOT NULL DEFAULT 1,
+    name JSONB NOT NULL DEFAULT '{}'::jsonb,
+    description JSONB NOT NULL DEFAULT '{}'::jsonb,
+    value JSONB NOT NULL,
+    predicate TEXT NOT NULL,
+    sort_order TEXT NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    valid_from TIMESTAMPTZ,
+    valid_until TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_modified_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (project_id, key),
+    UNIQUE (project_id, sort_order)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_discounts_project ON product_discounts(project_id);
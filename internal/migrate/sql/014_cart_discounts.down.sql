@@ -0,0 +1,12 @@
+ALTER TABLE cart_lines
+    DROP COLUMN IF EXISTS discounted_price_per_quantity,
+    DROP COLUMN IF EXISTS line_item_mode;
+
+ALTER TABLE carts
+    DROP COLUMN IF EXISTS refused_gifts,
+    DROP COLUMN IF EXISTS discount_on_total,
+    DROP COLUMN IF EXISTS direct_discounts;
+
+DROP TABLE IF EXISTS cart_discount_codes;
+DROP TABLE IF EXISTS discount_codes;
+DROP TABLE IF EXISTS cart_discounts;
@@ -0,0 +1,65 @@
+CREATE TABLE IF NOT EXISTS cart_discounts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    key TEXT,
+    version INT NOT NULL DEFAULT 1,
+    name JSONB NOT NULL DEFAULT '{}'::jsonb,
+    description JSONB NOT NULL DEFAULT '{}'::jsonb,
+    value JSONB NOT NULL,
+    cart_predicate TEXT NOT NULL,
+    target JSONB,
+    sort_order TEXT NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    valid_from TIMESTAMPTZ,
+    valid_until TIMESTAMPTZ,
+    requires_discount_code BOOLEAN NOT NULL DEFAULT FALSE,
+    stacking_mode TEXT NOT NULL DEFAULT 'Stacking',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_modified_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (project_id, key),
+    UNIQUE (project_id, sort_order)
+);
+
+CREATE INDEX IF NOT EXISTS idx_cart_discounts_project ON cart_discounts(project_id);
+
+CREATE TABLE IF NOT EXISTS discount_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    key TEXT,
+    code TEXT NOT NULL,
+    version INT NOT NULL DEFAULT 1,
+    name JSONB NOT NULL DEFAULT '{}'::jsonb,
+    description JSONB NOT NULL DEFAULT '{}'::jsonb,
+    cart_discount_ids TEXT[] NOT NULL DEFAULT '{}',
+    cart_predicate TEXT NOT NULL DEFAULT '',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    max_applications INT,
+    max_applications_per_customer INT,
+    valid_from TIMESTAMPTZ,
+    valid_until TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_modified_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (project_id, key),
+    UNIQUE (project_id, code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_discount_codes_project ON discount_codes(project_id);
+
+CREATE TABLE IF NOT EXISTS cart_discount_codes (
+    cart_id UUID NOT NULL REFERENCES carts(id) ON DELETE CASCADE,
+    discount_code_id UUID NOT NULL REFERENCES discount_codes(id) ON DELETE CASCADE,
+    state TEXT NOT NULL,
+    added_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (cart_id, discount_code_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_cart_discount_codes_code ON cart_discount_codes(discount_code_id);
+
+ALTER TABLE carts
+    ADD COLUMN IF NOT EXISTS direct_discounts JSONB NOT NULL DEFAULT '[]'::jsonb,
+    ADD COLUMN IF NOT EXISTS discount_on_total JSONB,
+    ADD COLUMN IF NOT EXISTS refused_gifts TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE cart_lines
+    ADD COLUMN IF NOT EXISTS line_item_mode TEXT NOT NULL DEFAULT 'Standard',
+    ADD COLUMN IF NOT EXISTS discounted_price_per_quantity JSONB NOT NULL DEFAULT '[]'::jsonb;
@@ -0,0 +1,15 @@
+ALTER TABLE cart_lines
+    DROP COLUMN IF EXISTS taxed_price,
+    DROP COLUMN IF EXISTS tax_rate;
+
+ALTER TABLE carts
+    DROP COLUMN IF EXISTS taxed_price,
+    DROP COLUMN IF EXISTS tax_calculation_mode,
+    DROP COLUMN IF EXISTS tax_rounding_mode,
+    DROP COLUMN IF EXISTS tax_mode,
+    DROP COLUMN IF EXISTS shipping_address,
+    DROP COLUMN IF EXISTS country;
+
+ALTER TABLE products DROP COLUMN IF EXISTS tax_category_id;
+
+DROP TABLE IF EXISTS tax_categories;
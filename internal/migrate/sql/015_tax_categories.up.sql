@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS tax_categories (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    key TEXT,
+    version INT NOT NULL DEFAULT 1,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    rates JSONB NOT NULL DEFAULT '[]'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_modified_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (project_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tax_categories_project ON tax_categories(project_id);
+
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS tax_category_id UUID REFERENCES tax_categories(id) ON DELETE SET NULL;
+
+ALTER TABLE carts
+    ADD COLUMN IF NOT EXISTS country TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS shipping_address JSONB,
+    ADD COLUMN IF NOT EXISTS tax_mode TEXT NOT NULL DEFAULT 'Platform',
+    ADD COLUMN IF NOT EXISTS tax_rounding_mode TEXT NOT NULL DEFAULT 'HalfEven',
+    ADD COLUMN IF NOT EXISTS tax_calculation_mode TEXT NOT NULL DEFAULT 'LineItemLevel',
+    ADD COLUMN IF NOT EXISTS taxed_price JSONB;
+
+ALTER TABLE cart_lines
+    ADD COLUMN IF NOT EXISTS tax_rate JSONB,
+    ADD COLUMN IF NOT EXISTS taxed_price JSONB;
@@ -0,0 +1,4 @@
+ALTER TABLE carts DROP COLUMN IF EXISTS shipping_info;
+
+DROP TABLE IF EXISTS shipping_methods;
+DROP TABLE IF EXISTS zones;
@@ -0,0 +1,6 @@
+ALTER TABLE cart_lines DROP COLUMN IF EXISTS shipping_details;
+
+ALTER TABLE carts
+    DROP COLUMN IF EXISTS item_shipping_addresses,
+    DROP COLUMN IF EXISTS shipping,
+    DROP COLUMN IF EXISTS shipping_mode;
@@ -0,0 +1,7 @@
+ALTER TABLE carts
+    ADD COLUMN IF NOT EXISTS shipping_mode TEXT NOT NULL DEFAULT 'Single',
+    ADD COLUMN IF NOT EXISTS shipping JSONB NOT NULL DEFAULT '[]'::jsonb,
+    ADD COLUMN IF NOT EXISTS item_shipping_addresses JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+ALTER TABLE cart_lines
+    ADD COLUMN IF NOT EXISTS shipping_details JSONB;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS orders;
+
+ALTER TABLE carts DROP COLUMN IF EXISTS inventory_mode;
+
+DROP TABLE IF EXISTS inventory_entries;
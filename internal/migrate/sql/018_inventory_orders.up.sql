@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS inventory_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    key TEXT,
+    version INT NOT NULL DEFAULT 1,
+    sku TEXT NOT NULL,
+    supply_channel_id UUID,
+    quantity_on_stock BIGINT NOT NULL DEFAULT 0,
+    available_quantity BIGINT NOT NULL DEFAULT 0,
+    restockable_in_days INT,
+    expected_delivery TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_modified_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (project_id, key),
+    UNIQUE NULLS NOT DISTINCT (project_id, sku, supply_channel_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_entries_project ON inventory_entries(project_id);
+
+ALTER TABLE carts
+    ADD COLUMN IF NOT EXISTS inventory_mode TEXT NOT NULL DEFAULT 'None';
+
+CREATE TABLE IF NOT EXISTS orders (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    version INT NOT NULL DEFAULT 1,
+    order_number TEXT,
+    order_state TEXT NOT NULL DEFAULT 'Open',
+    cart_id UUID NOT NULL UNIQUE REFERENCES carts(id),
+    customer_id UUID REFERENCES customers(id) ON DELETE SET NULL,
+    anonymous_id UUID,
+    cart JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_modified_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (project_id, order_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_orders_project_customer ON orders(project_id, customer_id);
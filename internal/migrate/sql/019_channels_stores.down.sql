@@ -0,0 +1,14 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS store_key;
+
+DROP INDEX IF EXISTS idx_carts_store;
+ALTER TABLE carts DROP COLUMN IF EXISTS store_key;
+
+DROP TABLE IF EXISTS store_product_selections;
+DROP TABLE IF EXISTS store_channels;
+DROP TABLE IF EXISTS stores;
+DROP TABLE IF EXISTS product_selection_products;
+DROP TABLE IF EXISTS product_selections;
+
+ALTER TABLE inventory_entries DROP CONSTRAINT IF EXISTS inventory_entries_supply_channel_fkey;
+
+DROP TABLE IF EXISTS channels;
@@ -0,0 +1,83 @@
+CREATE TABLE IF NOT EXISTS channels (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    version INT NOT NULL DEFAULT 1,
+    roles TEXT[] NOT NULL DEFAULT '{}',
+    name JSONB NOT NULL DEFAULT '{}'::jsonb,
+    description JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_modified_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (project_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_channels_project ON channels(project_id);
+
+-- Entries written before channels existed may point nowhere; only new ones are checked.
+ALTER TABLE inventory_entries
+    ADD CONSTRAINT inventory_entries_supply_channel_fkey
+    FOREIGN KEY (supply_channel_id) REFERENCES channels(id) NOT VALID;
+
+CREATE TABLE IF NOT EXISTS product_selections (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    key TEXT,
+    version INT NOT NULL DEFAULT 1,
+    name JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_modified_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (project_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_selections_project ON product_selections(project_id);
+
+CREATE TABLE IF NOT EXISTS product_selection_products (
+    product_selection_id UUID NOT NULL REFERENCES product_selections(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (product_selection_id, product_id)
+);
+
+CREATE TABLE IF NOT EXISTS stores (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    version INT NOT NULL DEFAULT 1,
+    name JSONB NOT NULL DEFAULT '{}'::jsonb,
+    languages TEXT[] NOT NULL DEFAULT '{}',
+    countries TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_modified_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (project_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stores_project ON stores(project_id);
+
+-- Channels and product selections cannot be deleted while a store uses them.
+CREATE TABLE IF NOT EXISTS store_channels (
+    store_id UUID NOT NULL REFERENCES stores(id) ON DELETE CASCADE,
+    channel_id UUID NOT NULL REFERENCES channels(id),
+    role TEXT NOT NULL,
+    position INT NOT NULL,
+    PRIMARY KEY (store_id, role, channel_id)
+);
+
+CREATE TABLE IF NOT EXISTS store_product_selections (
+    store_id UUID NOT NULL REFERENCES stores(id) ON DELETE CASCADE,
+    product_selection_id UUID NOT NULL REFERENCES product_selections(id),
+    active BOOLEAN NOT NULL DEFAULT true,
+    position INT NOT NULL,
+    PRIMARY KEY (store_id, product_selection_id)
+);
+
+ALTER TABLE carts
+    ADD COLUMN IF NOT EXISTS store_key TEXT,
+    ADD CONSTRAINT carts_store_fkey FOREIGN KEY (project_id, store_key)
+        REFERENCES stores(project_id, key) ON DELETE SET NULL (store_key);
+
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS store_key TEXT,
+    ADD CONSTRAINT orders_store_fkey FOREIGN KEY (project_id, store_key)
+        REFERENCES stores(project_id, key) ON DELETE SET NULL (store_key);
+
+CREATE INDEX IF NOT EXISTS idx_carts_store ON carts(project_id, store_key) WHERE store_key IS NOT NULL;
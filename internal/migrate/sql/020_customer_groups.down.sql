@@ -0,0 +1,18 @@
+DELETE FROM tokens WHERE kind = 'admin';
+
+ALTER TABLE tokens DROP CONSTRAINT IF EXISTS tokens_owner_check;
+
+ALTER TABLE tokens
+    ADD CONSTRAINT tokens_check CHECK (
+        (customer_id IS NOT NULL AND anonymous_id IS NULL)
+        OR (customer_id IS NULL AND anonymous_id IS NOT NULL)
+    );
+
+DROP INDEX IF EXISTS idx_customers_customer_group;
+
+ALTER TABLE customers
+    DROP COLUMN IF EXISTS customer_group_id,
+    DROP COLUMN IF EXISTS last_modified_at,
+    DROP COLUMN IF EXISTS version;
+
+DROP TABLE IF EXISTS customer_groups;
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS customer_groups (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    key TEXT,
+    version INT NOT NULL DEFAULT 1,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_modified_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (project_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_groups_project ON customer_groups(project_id);
+
+-- Customers now carry a version for update actions. Deleting a group that
+-- customers still belong to is refused by the foreign key.
+ALTER TABLE customers
+    ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS last_modified_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN IF NOT EXISTS customer_group_id UUID REFERENCES customer_groups(id);
+
+CREATE INDEX IF NOT EXISTS idx_customers_customer_group ON customers(customer_group_id);
+
+-- Admin tokens belong to the project only, not to a customer or an
+-- anonymous session.
+ALTER TABLE tokens DROP CONSTRAINT IF EXISTS tokens_check;
+
+ALTER TABLE tokens
+    ADD CONSTRAINT tokens_owner_check CHECK (
+        (customer_id IS NOT NULL AND anonymous_id IS NULL)
+        OR (customer_id IS NULL AND anonymous_id IS NOT NULL)
+        OR (kind = 'admin' AND customer_id IS NULL AND anonymous_id IS NULL)
+    );
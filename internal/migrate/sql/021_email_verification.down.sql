@@ -0,0 +1,5 @@
+ALTER TABLE projects
+    DROP COLUMN IF EXISTS require_email_verification;
+
+ALTER TABLE customers
+    DROP COLUMN IF EXISTS is_email_verified;
@@ -0,0 +1,7 @@
+-- Customers confirm their email with a single-use token (kind
+-- email-verification in tokens). Projects may refuse logins until they did.
+ALTER TABLE customers
+    ADD COLUMN IF NOT EXISTS is_email_verified BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE projects
+    ADD COLUMN IF NOT EXISTS require_email_verification BOOLEAN NOT NULL DEFAULT false;
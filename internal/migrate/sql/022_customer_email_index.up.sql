@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_customers_project_email ON customers(project_id, lower(email));
@@ -0,0 +1,11 @@
+-- Failed logins per project, counted per email and per client IP. A row is
+-- blocked until blocked_until; API replicas share the counters through it.
+CREATE TABLE IF NOT EXISTS login_attempts (
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('email', 'ip')),
+    subject TEXT NOT NULL,
+    failures INT NOT NULL,
+    last_failure_at TIMESTAMPTZ NOT NULL,
+    blocked_until TIMESTAMPTZ,
+    PRIMARY KEY (project_id, kind, subject)
+);
@@ -0,0 +1,7 @@
+ALTER TABLE projects
+    DROP COLUMN IF EXISTS password_min_length,
+    DROP COLUMN IF EXISTS password_require_upper,
+    DROP COLUMN IF EXISTS password_require_lower,
+    DROP COLUMN IF EXISTS password_require_digit,
+    DROP COLUMN IF EXISTS password_require_symbol,
+    DROP COLUMN IF EXISTS password_denylist_file;
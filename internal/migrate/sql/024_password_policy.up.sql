@@ -0,0 +1,9 @@
+-- Password rules per project (no API yet). The denylist file lists breached
+-- passwords one per line; empty means the server default.
+ALTER TABLE projects
+    ADD COLUMN IF NOT EXISTS password_min_length INT NOT NULL DEFAULT 8 CHECK (password_min_length BETWEEN 1 AND 128),
+    ADD COLUMN IF NOT EXISTS password_require_upper BOOLEAN NOT NULL DEFAULT true,
+    ADD COLUMN IF NOT EXISTS password_require_lower BOOLEAN NOT NULL DEFAULT true,
+    ADD COLUMN IF NOT EXISTS password_require_digit BOOLEAN NOT NULL DEFAULT true,
+    ADD COLUMN IF NOT EXISTS password_require_symbol BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS password_denylist_file TEXT NOT NULL DEFAULT '';
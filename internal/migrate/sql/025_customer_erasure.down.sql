@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS customer_erasures;
+
+DELETE FROM customers WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE customers
+    DROP COLUMN IF EXISTS deleted_at;
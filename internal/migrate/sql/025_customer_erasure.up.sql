@@ -0,0 +1,18 @@
+-- Erased customers stay as anonymised rows with deleted_at set; the API no
+-- longer finds them. customer_erasures records who asked and what changed,
+-- without personal data, and outlives the customer row.
+ALTER TABLE customers
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS customer_erasures (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    customer_id UUID NOT NULL,
+    requested_by TEXT NOT NULL CHECK (requested_by IN ('customer', 'admin')),
+    tokens INT NOT NULL,
+    carts INT NOT NULL,
+    orders INT NOT NULL,
+    erased_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_erasures_customer ON customer_erasures(project_id, customer_id);
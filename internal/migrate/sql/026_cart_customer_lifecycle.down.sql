@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_carts_project_customer;
+
+UPDATE carts SET customer_id = NULL
+WHERE customer_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM customers WHERE customers.id = carts.customer_id);
+
+ALTER TABLE carts
+    ADD CONSTRAINT carts_customer_id_fkey FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE SET NULL;
@@ -0,0 +1,5 @@
+-- Carts keep the customer_id of a deleted customer; the customer repository
+-- freezes its active carts instead of the foreign key clearing the owner.
+ALTER TABLE carts DROP CONSTRAINT IF EXISTS carts_customer_id_fkey;
+
+CREATE INDEX IF NOT EXISTS idx_carts_project_customer ON carts(project_id, customer_id) WHERE customer_id IS NOT NULL;
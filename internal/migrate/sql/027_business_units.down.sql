@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_carts_business_unit;
+ALTER TABLE carts DROP COLUMN IF EXISTS business_unit_key;
+
+DROP TABLE IF EXISTS business_unit_associates;
+DROP TABLE IF EXISTS business_units;
+DROP TABLE IF EXISTS associate_roles;
@@ -0,0 +1,57 @@
+CREATE TABLE IF NOT EXISTS associate_roles (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    version INT NOT NULL DEFAULT 1,
+    name TEXT NOT NULL DEFAULT '',
+    buyer_assignable BOOLEAN NOT NULL DEFAULT true,
+    permissions TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_modified_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (project_id, key)
+);
+
+-- Companies have no parent; divisions point at their parent and at the
+-- company on top of their tree. Units with divisions cannot be deleted.
+CREATE TABLE IF NOT EXISTS business_units (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    version INT NOT NULL DEFAULT 1,
+    unit_type TEXT NOT NULL CHECK (unit_type IN ('Company', 'Division')),
+    name TEXT NOT NULL,
+    contact_email TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'Active',
+    associate_mode TEXT NOT NULL DEFAULT 'Explicit',
+    addresses JSONB NOT NULL DEFAULT '[]'::jsonb,
+    default_shipping_address_id TEXT NOT NULL DEFAULT '',
+    default_billing_address_id TEXT NOT NULL DEFAULT '',
+    parent_unit_id UUID REFERENCES business_units(id),
+    top_level_unit_id UUID REFERENCES business_units(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_modified_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (project_id, key),
+    CHECK ((unit_type = 'Company') = (parent_unit_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_business_units_project ON business_units(project_id);
+CREATE INDEX IF NOT EXISTS idx_business_units_parent ON business_units(parent_unit_id) WHERE parent_unit_id IS NOT NULL;
+
+-- One row per role of an associate; roles cannot be deleted while assigned.
+CREATE TABLE IF NOT EXISTS business_unit_associates (
+    business_unit_id UUID NOT NULL REFERENCES business_units(id) ON DELETE CASCADE,
+    customer_id UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    associate_role_id UUID NOT NULL REFERENCES associate_roles(id),
+    inheritance TEXT NOT NULL DEFAULT 'Enabled',
+    position INT NOT NULL,
+    PRIMARY KEY (business_unit_id, customer_id, associate_role_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_business_unit_associates_customer ON business_unit_associates(customer_id);
+
+ALTER TABLE carts
+    ADD COLUMN IF NOT EXISTS business_unit_key TEXT,
+    ADD CONSTRAINT carts_business_unit_fkey FOREIGN KEY (project_id, business_unit_key)
+        REFERENCES business_units(project_id, key) ON DELETE SET NULL (business_unit_key);
+
+CREATE INDEX IF NOT EXISTS idx_carts_business_unit ON carts(project_id, business_unit_key) WHERE business_unit_key IS NOT NULL;
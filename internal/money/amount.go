@@ -0,0 +1,108 @@
+package money
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Amount is an exact amount of money. Arithmetic on amounts never rounds;
+// CentAmount and PreciseAmount round once, when a result is needed in a given
+// precision. The zero value is zero in no currency.
+type Amount struct {
+	currency string
+	value    *big.Rat // in major units
+}
+
+// Cents returns the amount of cents (minor units) in currency.
+func Cents(currency string, cents int64) Amount {
+	return Precise(currency, cents, FractionDigits(currency))
+}
+
+// Precise returns a high-precision amount: amount divided by 10^fractionDigits
+// major units, e.g. Precise("EUR", 12345, 3) is 12.345 EUR.
+func Precise(currency string, amount int64, fractionDigits int) Amount {
+	return Amount{
+		currency: strings.ToUpper(currency),
+		value:    new(big.Rat).SetFrac(big.NewInt(amount), pow10(fractionDigits)),
+	}
+}
+
+// Currency returns the ISO 4217 code of the amount.
+func (a Amount) Currency() string {
+	return a.currency
+}
+
+// Add returns a+b; b is assumed to be in the same currency.
+func (a Amount) Add(b Amount) Amount {
+	return a.with(new(big.Rat).Add(a.rat(), b.rat()))
+}
+
+// Sub returns a-b; b is assumed to be in the same currency.
+func (a Amount) Sub(b Amount) Amount {
+	return a.with(new(big.Rat).Sub(a.rat(), b.rat()))
+}
+
+// Mul returns a times n, e.g. a unit price times a quantity.
+func (a Amount) Mul(n int64) Amount {
+	return a.MulRat(new(big.Rat).SetInt64(n))
+}
+
+// MulRat returns a times r, e.g. a price times a tax rate.
+func (a Amount) MulRat(r *big.Rat) Amount {
+	return a.with(new(big.Rat).Mul(a.rat(), r))
+}
+
+// QuoRat returns a divided by r; r must not be zero.
+func (a Amount) QuoRat(r *big.Rat) Amount {
+	return a.with(new(big.Rat).Quo(a.rat(), r))
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of a.
+func (a Amount) Sign() int {
+	return a.rat().Sign()
+}
+
+// CentAmount rounds a to the minor unit of its currency.
+func (a Amount) CentAmount(mode RoundingMode) int64 {
+	return a.PreciseAmount(FractionDigits(a.currency), mode)
+}
+
+// PreciseAmount rounds a to fractionDigits decimals and returns it as a whole
+// number of 10^-fractionDigits units.
+func (a Amount) PreciseAmount(fractionDigits int, mode RoundingMode) int64 {
+	return Round(new(big.Rat).Mul(a.rat(), new(big.Rat).SetInt(pow10(fractionDigits))), mode)
+}
+
+func (a Amount) rat() *big.Rat {
+	if a.value == nil {
+		return new(big.Rat)
+	}
+	return a.value
+}
+
+func (a Amount) with(v *big.Rat) Amount {
+	return Amount{currency: a.currency, value: v}
+}
+
+// Decimal converts f to the decimal fraction it is written as, so a rate such
+// as 0.19 becomes exactly 19/100 rather than its binary approximation.
+func Decimal(f float64) *big.Rat {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, 64))
+	if !ok {
+		return new(big.Rat)
+	}
+	return r
+}
+
+// Permyriad returns p/10000, the factor of a relative discount.
+func Permyriad(p int) *big.Rat {
+	return big.NewRat(int64(p), 10000)
+}
+
+func pow10(n int) *big.Int {
+	if n <= 0 {
+		return big.NewInt(1)
+	}
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
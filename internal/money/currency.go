@@ -0,0 +1,46 @@
+package money
+
+import "strings"
+
+// DefaultFractionDigits is used for currencies not listed in fractionDigits.
+const DefaultFractionDigits = 2
+
+// fractionDigits lists the ISO 4217 currencies whose minor unit is not a
+// hundredth of the major unit.
+var fractionDigits = map[string]int{
+	"BHD": 3,
+	"BIF": 0,
+	"CLF": 4,
+	"CLP": 0,
+	"DJF": 0,
+	"GNF": 0,
+	"IQD": 3,
+	"ISK": 0,
+	"JOD": 3,
+	"JPY": 0,
+	"KMF": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"LYD": 3,
+	"OMR": 3,
+	"PYG": 0,
+	"RWF": 0,
+	"TND": 3,
+	"UGX": 0,
+	"UYI": 0,
+	"UYW": 4,
+	"VND": 0,
+	"VUV": 0,
+	"XAF": 0,
+	"XOF": 0,
+	"XPF": 0,
+}
+
+// FractionDigits returns the number of decimals of the currency's minor unit,
+// e.g. 2 for EUR, 0 for JPY and 3 for KWD.
+func FractionDigits(currency string) int {
+	if digits, ok := fractionDigits[strings.ToUpper(currency)]; ok {
+		return digits
+	}
+	return DefaultFractionDigits
+}
@@ -0,0 +1,71 @@
+package money
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestFractionDigits(t *testing.T) {
+	tests := map[string]int{"EUR": 2, "usd": 2, "JPY": 0, "KRW": 0, "KWD": 3, "BHD": 3, "CLF": 4, "XYZ": 2}
+	for currency, want := range tests {
+		if got := FractionDigits(currency); got != want {
+			t.Fatalf("%s: expected %d, got %d", currency, want, got)
+		}
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		num, den int64
+		mode     RoundingMode
+		want     int64
+	}{
+		{5, 2, HalfEven, 2},
+		{7, 2, HalfEven, 4},
+		{5, 2, HalfUp, 3},
+		{5, 2, HalfDown, 2},
+		{-5, 2, HalfUp, -3},
+		{-5, 2, HalfEven, -2},
+		{26, 10, HalfDown, 3},
+		{24, 10, HalfUp, 2},
+		{5, 2, "", 2},
+	}
+	for _, tc := range tests {
+		if got := Round(big.NewRat(tc.num, tc.den), tc.mode); got != tc.want {
+			t.Fatalf("%d/%d %s: expected %d, got %d", tc.num, tc.den, tc.mode, tc.want, got)
+		}
+	}
+}
+
+func TestAmount(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount Amount
+		mode   RoundingMode
+		cents  int64
+	}{
+		{"cents", Cents("EUR", 1999), HalfEven, 1999},
+		{"yen have no decimals", Cents("JPY", 1500).Mul(3), HalfEven, 4500},
+		{"precise rounds half even", Precise("EUR", 12345, 3), HalfEven, 1234},
+		{"precise rounds half up", Precise("EUR", 12345, 3), HalfUp, 1235},
+		{"precise times quantity rounds once", Precise("EUR", 3333, 3).Mul(3), HalfEven, 1000},
+		{"precise in dinar", Precise("KWD", 12345, 4), HalfEven, 1234},
+		{"precise yen", Precise("JPY", 1005, 1), HalfEven, 100},
+		{"tax rate", Cents("EUR", 1000).MulRat(Decimal(0.19)), HalfEven, 190},
+		{"included tax", Cents("EUR", 1190).QuoRat(big.NewRat(119, 100)), HalfEven, 1000},
+		{"relative discount", Cents("EUR", 35).MulRat(Permyriad(5000)), HalfEven, 18},
+		{"sum", Cents("EUR", 100).Add(Precise("EUR", 505, 3)).Sub(Cents("EUR", 1)), HalfEven, 150},
+		{"zero value", Amount{}, HalfEven, 0},
+	}
+	for _, tc := range tests {
+		if got := tc.amount.CentAmount(tc.mode); got != tc.cents {
+			t.Fatalf("%s: expected %d cents, got %d", tc.name, tc.cents, got)
+		}
+	}
+	if got := Precise("EUR", 12345, 3).PreciseAmount(4, HalfEven); got != 123450 {
+		t.Fatalf("expected 123450, got %d", got)
+	}
+	if got := Decimal(0.07); got.Cmp(big.NewRat(7, 100)) != 0 {
+		t.Fatalf("expected exactly 7/100, got %s", got)
+	}
+}
@@ -0,0 +1,50 @@
+package money
+
+import "math/big"
+
+// RoundingMode decides where amounts exactly halfway between two minor units go.
+type RoundingMode string
+
+const (
+	// HalfEven rounds ties to the even neighbour (banker's rounding).
+	HalfEven RoundingMode = "HalfEven"
+	// HalfUp rounds ties away from zero.
+	HalfUp RoundingMode = "HalfUp"
+	// HalfDown rounds ties towards zero.
+	HalfDown RoundingMode = "HalfDown"
+)
+
+// Valid reports whether m is one of the supported rounding modes.
+func (m RoundingMode) Valid() bool {
+	switch m {
+	case HalfEven, HalfUp, HalfDown:
+		return true
+	}
+	return false
+}
+
+// Round rounds r to a whole number; unknown modes round like HalfEven.
+func Round(r *big.Rat, mode RoundingMode) int64 {
+	num, den := new(big.Int).Set(r.Num()), r.Denom()
+	neg := num.Sign() < 0
+	num.Abs(num)
+	q, m := new(big.Int).QuoRem(num, den, new(big.Int))
+	switch new(big.Int).Mul(m, big.NewInt(2)).Cmp(den) {
+	case 1:
+		q.Add(q, big.NewInt(1))
+	case 0:
+		switch mode {
+		case HalfUp:
+			q.Add(q, big.NewInt(1))
+		case HalfDown:
+		default:
+			if q.Bit(0) == 1 {
+				q.Add(q, big.NewInt(1))
+			}
+		}
+	}
+	if neg {
+		q.Neg(q)
+	}
+	return q.Int64()
+}
@@ -0,0 +1,165 @@
+package predicate
+
+import "commercetools-replica/internal/domain"
+
+// CartSchema lists the fields of cart predicates (cart discounts, discount codes).
+var CartSchema = &Schema{
+	Fields: map[string]Type{
+		"id":                      TypeString,
+		"currency":                TypeString,
+		"totalPrice":              TypeMoney,
+		"totalPrice.centAmount":   TypeNumber,
+		"totalPrice.currencyCode": TypeString,
+		"lineItemCount":           TypeNumber,
+		"totalLineItemQuantity":   TypeNumber,
+		"lineItems.sku":           TypeStringSet,
+		"lineItems.productId":     TypeStringSet,
+		"customerId":              TypeString,
+		"customerEmail":           TypeString,
+		"anonymousId":             TypeString,
+		"country":                 TypeString,
+		"shippingAddress.country": TypeString,
+		"billingAddress.country":  TypeString,
+		"customer.id":             TypeString,
+		"customer.email":          TypeString,
+		"customer.firstName":      TypeString,
+		"customer.lastName":       TypeString,
+		"customer.customerGroup":  TypeAny,
+		"custom":                  TypeAny,
+	},
+	LineItems: LineItemSchema,
+}
+
+// LineItemSchema lists the fields of line item predicates: cart discount
+// lineItems targets and the arguments of the lineItem* functions.
+var LineItemSchema = &Schema{
+	Fields: map[string]Type{
+		"id":                 TypeString,
+		"productId":          TypeString,
+		"product.id":         TypeString,
+		"product.key":        TypeString,
+		"productType.id":     TypeString,
+		"variant.id":         TypeNumber,
+		"variantId":          TypeNumber,
+		"sku":                TypeString,
+		"quantity":           TypeNumber,
+		"price":              TypeMoney,
+		"price.centAmount":   TypeNumber,
+		"price.currencyCode": TypeString,
+		"totalPrice":         TypeMoney,
+		"categories.id":      TypeStringSet,
+		"name":               TypeLocalized,
+		"lineItemMode":       TypeString,
+		"attributes":         TypeAny,
+		"custom":             TypeAny,
+	},
+}
+
+// CartEnv is the Env of cart predicates. Lines holds the Env of every line
+// item that is not a gift, for the lineItem* functions.
+type CartEnv struct {
+	Fields
+	Lines []Fields
+}
+
+func (e *CartEnv) LineItems() []Env {
+	out := make([]Env, len(e.Lines))
+	for i, line := range e.Lines {
+		out[i] = line
+	}
+	return out
+}
+
+// CartFields exposes a cart and its customer, if known, to cart predicates.
+// totalPrice is the sum of the line item totals before cart discounts; gift
+// line items are left out.
+func CartFields(cart domain.Cart, customer *domain.Customer) *CartEnv {
+	var total int64
+	quantity := 0
+	skus := make([]interface{}, 0, len(cart.Lines))
+	productIDs := make([]interface{}, 0, len(cart.Lines))
+	var lines []Fields
+	for _, line := range cart.Lines {
+		if line.IsGift() {
+			continue
+		}
+		total += line.PriceTotal()
+		quantity += line.Quantity
+		if sku, ok := line.Snapshot["sku"].(string); ok && sku != "" {
+			skus = append(skus, sku)
+		}
+		productIDs = append(productIDs, line.ProductID)
+		lines = append(lines, LineItemFields(line))
+	}
+	f := Fields{
+		"id":                      cart.ID,
+		"currency":                cart.Currency,
+		"totalPrice":              domain.Money{CurrencyCode: cart.Currency, CentAmount: total},
+		"totalPrice.centAmount":   total,
+		"totalPrice.currencyCode": cart.Currency,
+		"lineItemCount":           len(productIDs),
+		"totalLineItemQuantity":   quantity,
+		"lineItems.sku":           skus,
+		"lineItems.productId":     productIDs,
+	}
+	if cart.CustomerID != nil && *cart.CustomerID != "" {
+		f["customerId"] = *cart.CustomerID
+		f["customer.id"] = *cart.CustomerID
+	}
+	if cart.AnonymousID != nil && *cart.AnonymousID != "" {
+		f["anonymousId"] = *cart.AnonymousID
+	}
+	if customer != nil {
+		for key, value := range map[string]string{
+			"customer.id":        customer.ID,
+			"customer.email":     customer.Email,
+			"customer.firstName": customer.FirstName,
+			"customer.lastName":  customer.LastName,
+		} {
+			if value != "" {
+				f[key] = value
+			}
+		}
+	}
+	return &CartEnv{Fields: f, Lines: lines}
+}
+
+// LineItemFields exposes one cart line to line item predicates, using the
+// product data the line snapshotted when it was added.
+func LineItemFields(line domain.CartLine) Fields {
+	snap := line.Snapshot
+	currency, _ := snap["currency"].(string)
+	f := Fields{
+		"id":                 line.ID,
+		"productId":          line.ProductID,
+		"product.id":         line.ProductID,
+		"variant.id":         line.VariantID,
+		"variantId":          line.VariantID,
+		"quantity":           line.Quantity,
+		"price.centAmount":   line.UnitPriceCents,
+		"price.currencyCode": currency,
+		"sku":                snap["sku"],
+		"product.key":        snap["productKey"],
+		"productType.id":     snap["productTypeId"],
+		"categories.id":      snap["categoryIds"],
+		"name":               snap["productName"],
+		"lineItemMode":       line.LineItemMode,
+	}
+	if currency != "" {
+		f["price"] = domain.Money{CurrencyCode: currency, CentAmount: line.UnitPriceCents}
+		f["totalPrice"] = domain.Money{CurrencyCode: currency, CentAmount: line.PriceTotal()}
+	}
+	for key, value := range f {
+		if value == nil {
+			delete(f, key)
+		} else if s, ok := value.(string); ok && s == "" {
+			delete(f, key)
+		}
+	}
+	if attrs, ok := snap["attributes"].(map[string]interface{}); ok {
+		for name, value := range attrs {
+			f["attributes."+name] = attributeValue(value)
+		}
+	}
+	return f
+}
@@ -0,0 +1,235 @@
+package predicate
+
+import "strings"
+
+// Type is the type of a field in a Schema.
+type Type int
+
+const (
+	// TypeAny accepts every value; custom attributes are untyped.
+	TypeAny Type = iota
+	TypeString
+	TypeNumber
+	TypeBoolean
+	TypeMoney
+	TypeStringSet
+	// TypeLocalized fields are only compared per locale, e.g. name.en.
+	TypeLocalized
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeString:
+		return "string"
+	case TypeNumber:
+		return "number"
+	case TypeBoolean:
+		return "boolean"
+	case TypeMoney:
+		return "money"
+	case TypeStringSet:
+		return "set of strings"
+	case TypeLocalized:
+		return "localized string"
+	}
+	return "any"
+}
+
+// Schema lists the fields a kind of predicate may refer to. A TypeAny field
+// also covers every path below it, so "attributes" allows "attributes.size".
+type Schema struct {
+	Fields map[string]Type
+	// LineItems is the schema of the arguments of the lineItem* functions;
+	// nil when the functions are not available.
+	LineItems *Schema
+}
+
+func (s *Schema) lookup(path string) (Type, bool) {
+	if t, ok := s.Fields[path]; ok {
+		return t, true
+	}
+	parts := strings.Split(path, ".")
+	for i := len(parts) - 1; i > 0; i-- {
+		t, ok := s.Fields[strings.Join(parts[:i], ".")]
+		if !ok {
+			continue
+		}
+		switch {
+		case t == TypeAny:
+			return TypeAny, true
+		case t == TypeLocalized && i == len(parts)-1:
+			return TypeString, true
+		}
+		return 0, false
+	}
+	return 0, false
+}
+
+// Check reports the first reference to an unknown field or function and the
+// first comparison between incompatible types.
+func (p *Predicate) Check(schema *Schema) error {
+	return p.root.check(schema)
+}
+
+func (n andNode) check(s *Schema) error {
+	if err := n.left.check(s); err != nil {
+		return err
+	}
+	return n.right.check(s)
+}
+
+func (n orNode) check(s *Schema) error {
+	if err := n.left.check(s); err != nil {
+		return err
+	}
+	return n.right.check(s)
+}
+
+func (n notNode) check(s *Schema) error {
+	return n.inner.check(s)
+}
+
+func (o operand) typeOf(s *Schema) (Type, error) {
+	switch {
+	case o.call != nil:
+		if s.LineItems == nil {
+			return 0, errorf(o.pos, "%s is only available in cart predicates", o.call.fn.name)
+		}
+		if err := o.call.arg.check(s.LineItems); err != nil {
+			return 0, err
+		}
+		return o.call.fn.result, nil
+	case o.isPath():
+		t, ok := s.lookup(o.path)
+		if !ok {
+			return 0, errorf(o.pos, "unknown field %q", o.path)
+		}
+		return t, nil
+	}
+	return literalType(o.literal), nil
+}
+
+func literalType(v interface{}) Type {
+	switch v.(type) {
+	case string:
+		return TypeString
+	case float64:
+		return TypeNumber
+	case bool:
+		return TypeBoolean
+	}
+	return TypeAny
+}
+
+func (n compareNode) check(s *Schema) error {
+	lt, err := n.left.typeOf(s)
+	if err != nil {
+		return err
+	}
+	rt, err := n.right.typeOf(s)
+	if err != nil {
+		return err
+	}
+	if rt == TypeMoney && lt != TypeMoney {
+		return compatible(n.right, rt, n.left, lt, n.op, n.pos)
+	}
+	return compatible(n.left, lt, n.right, rt, n.op, n.pos)
+}
+
+// compatible checks that a value of type lt can be compared with one of type
+// rt using op. Money is compared with money or with a literal like "10.00 EUR".
+func compatible(left operand, lt Type, right operand, rt Type, op string, pos int) error {
+	if lt == TypeStringSet {
+		lt = TypeString
+	}
+	if rt == TypeStringSet {
+		rt = TypeString
+	}
+	switch {
+	case lt == TypeLocalized || rt == TypeLocalized:
+		field := left
+		if rt == TypeLocalized {
+			field = right
+		}
+		return errorf(field.pos, "%q is localized, compare %s.<locale> instead", field.path, field.path)
+	case lt == TypeMoney && rt == TypeString && !right.isPath() && right.call == nil:
+		if _, err := parseMoney(right.literal.(string)); err != nil {
+			return at(right.pos, err)
+		}
+		return nil
+	case lt == TypeAny || rt == TypeAny:
+		return nil
+	case lt != rt:
+		return errorf(pos, "cannot compare %s with %s", lt, rt)
+	case lt == TypeBoolean && op != "=" && op != "!=":
+		return errorf(pos, "operator %s is not defined for booleans", op)
+	}
+	return nil
+}
+
+func (n callNode) check(s *Schema) error {
+	_, err := operand{call: n.call, pos: n.call.pos}.typeOf(s)
+	return err
+}
+
+func (n containsNode) check(s *Schema) error {
+	t, err := n.field.typeOf(s)
+	if err != nil {
+		return err
+	}
+	if t != TypeStringSet && t != TypeAny {
+		return errorf(n.field.pos, "contains needs a set but %q is a %s", n.field.path, t)
+	}
+	return checkValues(n.values, t)
+}
+
+func (n inNode) check(s *Schema) error {
+	t, err := n.field.typeOf(s)
+	if err != nil {
+		return err
+	}
+	switch t {
+	case TypeString, TypeNumber, TypeStringSet, TypeAny:
+	default:
+		return errorf(n.field.pos, "in is not defined for %s field %q", t, n.field.path)
+	}
+	return checkValues(n.values, t)
+}
+
+// checkValues checks the literals of a list against the element type of a field.
+func checkValues(values []operand, t Type) error {
+	if t == TypeStringSet {
+		t = TypeString
+	}
+	if t == TypeAny {
+		return nil
+	}
+	for _, v := range values {
+		if vt := literalType(v.literal); vt != t {
+			return errorf(v.pos, "expected a %s but found a %s", t, vt)
+		}
+	}
+	return nil
+}
+
+func (n definedNode) check(s *Schema) error {
+	_, err := n.field.typeOf(s)
+	return err
+}
+
+func (n emptyNode) check(s *Schema) error {
+	_, err := n.field.typeOf(s)
+	return err
+}
+
+// schemaWithPrefix returns fields with every name also available under prefix.
+func schemaWithPrefix(prefix string, fields map[string]Type) map[string]Type {
+	out := make(map[string]Type, len(fields)*2)
+	for name, t := range fields {
+		out[name] = t
+		if !strings.HasPrefix(name, prefix) {
+			out[prefix+name] = t
+		}
+	}
+	return out
+}
@@ -0,0 +1,417 @@
+package predicate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"commercetools-replica/internal/domain"
+)
+
+// Env resolves the dotted field paths a predicate refers to. Multi-valued fields
+// such as categories.id are returned as []interface{} and money as domain.Money.
+type Env interface {
+	Lookup(path string) (interface{}, bool)
+}
+
+// Fields is an Env backed by a map. Paths not found verbatim are resolved by
+// descending into nested maps, so "attributes.name.en" finds the "en" entry of
+// a localized "attributes.name" value.
+type Fields map[string]interface{}
+
+func (f Fields) Lookup(path string) (interface{}, bool) {
+	if v, ok := f[path]; ok {
+		return v, true
+	}
+	parts := strings.Split(path, ".")
+	for i := len(parts) - 1; i > 0; i-- {
+		v, ok := f[strings.Join(parts[:i], ".")]
+		if !ok {
+			continue
+		}
+		for _, part := range parts[i:] {
+			switch m := v.(type) {
+			case map[string]interface{}:
+				v, ok = m[part]
+			case map[string]string:
+				v, ok = m[part]
+			default:
+				ok = false
+			}
+			if !ok {
+				return nil, false
+			}
+		}
+		return v, true
+	}
+	return nil, false
+}
+
+// Eval evaluates the predicate against env. Conditions on undefined fields are
+// false; comparing incompatible values is an error.
+func (p *Predicate) Eval(env Env) (bool, error) {
+	return p.root.eval(env)
+}
+
+type node interface {
+	eval(env Env) (bool, error)
+	check(s *Schema) error
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(env Env) (bool, error) {
+	ok, err := n.left.eval(env)
+	if err != nil || !ok {
+		return false, err
+	}
+	return n.right.eval(env)
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(env Env) (bool, error) {
+	ok, err := n.left.eval(env)
+	if err != nil || ok {
+		return ok, err
+	}
+	return n.right.eval(env)
+}
+
+type notNode struct{ inner node }
+
+func (n notNode) eval(env Env) (bool, error) {
+	ok, err := n.inner.eval(env)
+	return !ok, err
+}
+
+// operand is a field path, a literal or a function call.
+type operand struct {
+	path    string
+	literal interface{}
+	call    *call
+	pos     int
+}
+
+func (o operand) isPath() bool {
+	return o.path != ""
+}
+
+func (o operand) resolve(env Env) (interface{}, bool, error) {
+	switch {
+	case o.call != nil:
+		v, err := o.call.eval(env)
+		return v, err == nil, err
+	case !o.isPath():
+		return o.literal, true, nil
+	}
+	v, ok := env.Lookup(o.path)
+	if !ok || v == nil {
+		return nil, false, nil
+	}
+	return normalize(v), true, nil
+}
+
+type compareNode struct {
+	left  operand
+	op    string
+	right operand
+	pos   int
+}
+
+func (n compareNode) eval(env Env) (bool, error) {
+	left, ok, err := n.left.resolve(env)
+	if err != nil || !ok {
+		return false, err
+	}
+	right, ok, err := n.right.resolve(env)
+	if err != nil || !ok {
+		return false, err
+	}
+	if list, isList := left.([]interface{}); isList {
+		// A multi-valued field matches if any value does; != means none is equal.
+		if n.op == "!=" {
+			found, err := containsValue(list, right)
+			return !found, at(n.pos, err)
+		}
+		for _, item := range list {
+			ok, err := compare(item, n.op, right)
+			if err != nil {
+				return false, at(n.pos, err)
+			}
+			if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	ok, err = compare(left, n.op, right)
+	return ok, at(n.pos, err)
+}
+
+// callNode is a boolean function used as a condition, e.g. `lineItemExists(sku = "a")`.
+type callNode struct {
+	call *call
+}
+
+func (n callNode) eval(env Env) (bool, error) {
+	v, err := n.call.eval(env)
+	if err != nil {
+		return false, err
+	}
+	ok, _ := v.(bool)
+	return ok, nil
+}
+
+type containsMode int
+
+const (
+	containsOne containsMode = iota
+	containsAny
+	containsAll
+)
+
+type containsNode struct {
+	field  operand
+	mode   containsMode
+	values []operand
+}
+
+func (n containsNode) eval(env Env) (bool, error) {
+	v, ok, err := n.field.resolve(env)
+	if err != nil || !ok {
+		return false, err
+	}
+	list := asList(v)
+	for _, want := range n.values {
+		found, err := containsValue(list, want.literal)
+		if err != nil {
+			return false, at(want.pos, err)
+		}
+		if found && n.mode != containsAll {
+			return true, nil
+		}
+		if !found && n.mode == containsAll {
+			return false, nil
+		}
+	}
+	return n.mode == containsAll, nil
+}
+
+type inNode struct {
+	field  operand
+	values []operand
+	negate bool
+}
+
+func (n inNode) eval(env Env) (bool, error) {
+	v, ok, err := n.field.resolve(env)
+	if err != nil || !ok {
+		return false, err
+	}
+	values := make([]interface{}, len(n.values))
+	for i, o := range n.values {
+		values[i] = o.literal
+	}
+	for _, item := range asList(v) {
+		found, err := containsValue(values, item)
+		if err != nil {
+			return false, at(n.field.pos, err)
+		}
+		if found {
+			return !n.negate, nil
+		}
+	}
+	return n.negate, nil
+}
+
+type definedNode struct {
+	field  operand
+	negate bool
+}
+
+func (n definedNode) eval(env Env) (bool, error) {
+	_, ok, err := n.field.resolve(env)
+	return ok != n.negate, err
+}
+
+type emptyNode struct {
+	field  operand
+	negate bool
+}
+
+func (n emptyNode) eval(env Env) (bool, error) {
+	v, ok, err := n.field.resolve(env)
+	if err != nil {
+		return false, err
+	}
+	empty := !ok || len(asList(v)) == 0
+	return empty != n.negate, nil
+}
+
+// at attaches a position to an evaluation error.
+func at(pos int, err error) error {
+	if err == nil {
+		return nil
+	}
+	var perr *Error
+	if errors.As(err, &perr) {
+		return err
+	}
+	return &Error{Pos: pos, Msg: err.Error()}
+}
+
+// normalize maps the numeric and list types callers put into an Env onto
+// float64 and []interface{}.
+func normalize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case int:
+		return float64(t)
+	case int32:
+		return float64(t)
+	case int64:
+		return float64(t)
+	case float32:
+		return float64(t)
+	case []string:
+		out := make([]interface{}, len(t))
+		for i, s := range t {
+			out[i] = s
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, item := range t {
+			out[i] = normalize(item)
+		}
+		return out
+	case *domain.Money:
+		return *t
+	}
+	return v
+}
+
+func asList(v interface{}) []interface{} {
+	if list, ok := v.([]interface{}); ok {
+		return list
+	}
+	return []interface{}{v}
+}
+
+func containsValue(list []interface{}, want interface{}) (bool, error) {
+	for _, item := range list {
+		ok, err := compare(item, "=", want)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func compare(left interface{}, op string, right interface{}) (bool, error) {
+	if _, ok := right.(domain.Money); ok {
+		if _, ok := left.(domain.Money); !ok {
+			return compare(right, flip(op), left)
+		}
+	}
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return false, fmt.Errorf("cannot compare number with %s", typeName(right))
+		}
+		return compareOrdered(l, op, r), nil
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot compare string with %s", typeName(right))
+		}
+		return compareOrdered(l, op, r), nil
+	case bool:
+		r, ok := right.(bool)
+		if !ok {
+			return false, fmt.Errorf("cannot compare boolean with %s", typeName(right))
+		}
+		switch op {
+		case "=":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		}
+		return false, fmt.Errorf("operator %s is not defined for booleans", op)
+	case domain.Money:
+		var r domain.Money
+		switch t := right.(type) {
+		case domain.Money:
+			r = t
+		case string:
+			m, err := parseMoney(t)
+			if err != nil {
+				return false, err
+			}
+			r = m
+		default:
+			return false, fmt.Errorf("cannot compare money with %s", typeName(right))
+		}
+		if !strings.EqualFold(l.CurrencyCode, r.CurrencyCode) {
+			// Amounts in different currencies are never equal nor ordered.
+			return op == "!=", nil
+		}
+		return compareOrdered(l.CentAmount, op, r.CentAmount), nil
+	}
+	return false, fmt.Errorf("cannot compare %s values", typeName(left))
+}
+
+func compareOrdered[T float64 | int64 | string](l T, op string, r T) bool {
+	switch op {
+	case "=":
+		return l == r
+	case "!=":
+		return l != r
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	case ">=":
+		return l >= r
+	}
+	return false
+}
+
+// flip returns the operator that compares the operands the other way round.
+func flip(op string) string {
+	switch op {
+	case "<":
+		return ">"
+	case "<=":
+		return ">="
+	case ">":
+		return "<"
+	case ">=":
+		return "<="
+	}
+	return op
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case domain.Money:
+		return "money"
+	case []interface{}:
+		return "set"
+	case map[string]interface{}, map[string]string:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
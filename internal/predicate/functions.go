@@ -0,0 +1,75 @@
+package predicate
+
+import "commercetools-replica/internal/domain"
+
+// LineItemsEnv is an Env that also exposes line items to the lineItem*
+// functions of cart predicates; see CartFields.
+type LineItemsEnv interface {
+	Env
+	LineItems() []Env
+}
+
+type function struct {
+	name   string
+	result Type
+}
+
+// functions are the cart predicate functions; each takes a line item predicate.
+var functions = map[string]function{
+	"lineItemTotal":   {name: "lineItemTotal", result: TypeMoney},
+	"lineItemCount":   {name: "lineItemCount", result: TypeNumber},
+	"lineItemExists":  {name: "lineItemExists", result: TypeBoolean},
+	"forAllLineItems": {name: "forAllLineItems", result: TypeBoolean},
+}
+
+type call struct {
+	fn  function
+	arg node
+	pos int
+}
+
+// eval runs the line item predicate over the line items of env:
+// lineItemTotal sums the totalPrice of the matching lines, lineItemCount
+// their quantities.
+func (c *call) eval(env Env) (interface{}, error) {
+	lines, ok := env.(LineItemsEnv)
+	if !ok {
+		return nil, errorf(c.pos, "%s is only available in cart predicates", c.fn.name)
+	}
+	currency, _ := env.Lookup("currency")
+	total := domain.Money{}
+	total.CurrencyCode, _ = currency.(string)
+	var count float64
+	all, exists := true, false
+	for _, line := range lines.LineItems() {
+		ok, err := c.arg.eval(line)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			all = false
+			continue
+		}
+		exists = true
+		if q, found := line.Lookup("quantity"); found {
+			if n, isNumber := normalize(q).(float64); isNumber {
+				count += n
+			}
+		}
+		if v, found := line.Lookup("totalPrice"); found {
+			if m, isMoney := normalize(v).(domain.Money); isMoney && (total.CurrencyCode == "" || m.CurrencyCode == total.CurrencyCode) {
+				total.CurrencyCode = m.CurrencyCode
+				total.CentAmount += m.CentAmount
+			}
+		}
+	}
+	switch c.fn.name {
+	case "lineItemTotal":
+		return total, nil
+	case "lineItemCount":
+		return count, nil
+	case "lineItemExists":
+		return exists, nil
+	}
+	return all, nil
+}
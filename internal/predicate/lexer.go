@@ -0,0 +1,123 @@
+package predicate
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokLParen
+	tokRParen
+	tokComma
+	tokDot
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of predicate"
+	case tokString:
+		return fmt.Sprintf("string %q", t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// is reports whether t is the given keyword or symbol; keywords are case-insensitive.
+func (t token) is(text string) bool {
+	switch t.kind {
+	case tokIdent:
+		return strings.EqualFold(t.text, text)
+	case tokOp, tokLParen, tokRParen, tokComma, tokDot:
+		return t.text == text
+	}
+	return false
+}
+
+func lex(src string) ([]token, error) {
+	var out []token
+	runes := []rune(src)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			out = append(out, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			out = append(out, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case r == ',':
+			out = append(out, token{kind: tokComma, text: ",", pos: i})
+			i++
+		case r == '.':
+			out = append(out, token{kind: tokDot, text: ".", pos: i})
+			i++
+		case r == '"':
+			start := i
+			var b strings.Builder
+			i++
+			closed := false
+			for i < len(runes) {
+				c := runes[i]
+				if c == '\\' && i+1 < len(runes) {
+					b.WriteRune(runes[i+1])
+					i += 2
+					continue
+				}
+				if c == '"' {
+					closed = true
+					i++
+					break
+				}
+				b.WriteRune(c)
+				i++
+			}
+			if !closed {
+				return nil, errorf(start, "unterminated string")
+			}
+			out = append(out, token{kind: tokString, text: b.String(), pos: start})
+		case r == '=' || r == '!' || r == '<' || r == '>':
+			start := i
+			op := string(r)
+			if i+1 < len(runes) && (runes[i+1] == '=' || (r == '<' && runes[i+1] == '>')) {
+				op += string(runes[i+1])
+			}
+			if op == "!" {
+				return nil, errorf(start, "unexpected %q", op)
+			}
+			i += len([]rune(op))
+			out = append(out, token{kind: tokOp, text: op, pos: start})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			out = append(out, token{kind: tokNumber, text: string(runes[start:i]), pos: start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '-') {
+				i++
+			}
+			out = append(out, token{kind: tokIdent, text: string(runes[start:i]), pos: start})
+		default:
+			return nil, errorf(i, "unexpected %q", r)
+		}
+	}
+	return append(out, token{kind: tokEOF, pos: len(runes)}), nil
+}
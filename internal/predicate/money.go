@@ -0,0 +1,36 @@
+package predicate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"commercetools-replica/internal/domain"
+	"commercetools-replica/internal/money"
+)
+
+// parseMoney parses a money literal such as "10.00 EUR" or "10 EUR" into cents
+// of the currency, which may have as many decimals as the currency's minor unit.
+func parseMoney(s string) (domain.Money, error) {
+	parts := strings.Fields(s)
+	if len(parts) != 2 || len(parts[1]) != 3 {
+		return domain.Money{}, fmt.Errorf("invalid money value %q, expected an amount and a currency like \"10.00 EUR\"", s)
+	}
+	digits := money.FractionDigits(parts[1])
+	amount := parts[0]
+	negative := strings.HasPrefix(amount, "-")
+	amount = strings.TrimPrefix(amount, "-")
+	whole, frac, _ := strings.Cut(amount, ".")
+	if len(frac) > digits {
+		return domain.Money{}, fmt.Errorf("invalid money value %q, at most %d decimals are allowed", s, digits)
+	}
+	frac += strings.Repeat("0", digits-len(frac))
+	cents, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil || whole == "" {
+		return domain.Money{}, fmt.Errorf("invalid money value %q", s)
+	}
+	if negative {
+		cents = -cents
+	}
+	return domain.Money{CurrencyCode: strings.ToUpper(parts[1]), CentAmount: cents}, nil
+}
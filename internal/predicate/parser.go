@@ -0,0 +1,338 @@
+package predicate
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Predicate is a parsed commercetools predicate such as
+// `product.categories.id containsAny ("a", "b") and price.centAmount > 1000`.
+type Predicate struct {
+	src  string
+	root node
+}
+
+// Error is a syntax, type or evaluation error; Pos is the rune offset in the
+// predicate the error refers to.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+func errorf(pos int, format string, args ...interface{}) error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Parse parses a predicate; an empty string is rejected. Field names are not
+// checked, see Compile.
+func Parse(src string) (*Predicate, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return nil, errors.New("empty predicate")
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, errorf(tok.pos, "unexpected %s", tok)
+	}
+	return &Predicate{src: src, root: root}, nil
+}
+
+// Compile parses a predicate and type checks it against schema.
+func Compile(src string, schema *Schema) (*Predicate, error) {
+	p, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Check(schema); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// MustParse is like Parse but panics on invalid predicates; meant for tests and constants.
+func MustParse(src string) *Predicate {
+	p, err := Parse(src)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func (p *Predicate) String() string {
+	return p.src
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+// peekNext returns the token after the next one.
+func (p *parser) peekNext() token {
+	if p.pos+1 < len(p.tokens) {
+		return p.tokens[p.pos+1]
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) expect(text string) error {
+	tok := p.next()
+	if !tok.is(text) {
+		return errorf(tok.pos, "expected %q but found %s", text, tok)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	tok := p.peek()
+	switch {
+	case tok.is("not"):
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner: inner}, nil
+	case tok.kind == tokLParen:
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	}
+	return p.parseCondition()
+}
+
+func (p *parser) parseCondition() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	tok := p.peek()
+	if tok.kind == tokOp {
+		p.next()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		op := tok.text
+		if op == "<>" {
+			op = "!="
+		}
+		return compareNode{left: left, op: op, right: right, pos: tok.pos}, nil
+	}
+	if left.call != nil && left.call.fn.result == TypeBoolean {
+		return callNode{call: left.call}, nil
+	}
+	if !left.isPath() {
+		return nil, errorf(tok.pos, "expected operator but found %s", tok)
+	}
+
+	switch {
+	case tok.is("contains"):
+		p.next()
+		mode := containsOne
+		switch {
+		case p.peek().is("any"):
+			p.next()
+			mode = containsAny
+		case p.peek().is("all"):
+			p.next()
+			mode = containsAll
+		}
+		if mode == containsOne {
+			v, err := p.parseLiteral()
+			if err != nil {
+				return nil, err
+			}
+			return containsNode{field: left, mode: mode, values: []operand{v}}, nil
+		}
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return containsNode{field: left, mode: mode, values: values}, nil
+	case tok.is("containsAny"), tok.is("containsAll"):
+		p.next()
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		mode := containsAny
+		if tok.is("containsAll") {
+			mode = containsAll
+		}
+		return containsNode{field: left, mode: mode, values: values}, nil
+	case tok.is("in"), tok.is("not"):
+		p.next()
+		negate := tok.is("not")
+		if negate {
+			if err := p.expect("in"); err != nil {
+				return nil, err
+			}
+		}
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return inNode{field: left, values: values, negate: negate}, nil
+	case tok.is("is"):
+		p.next()
+		negate := false
+		if p.peek().is("not") {
+			p.next()
+			negate = true
+		}
+		check := p.next()
+		switch {
+		case check.is("defined"):
+			return definedNode{field: left, negate: negate}, nil
+		case check.is("empty"):
+			return emptyNode{field: left, negate: negate}, nil
+		}
+		return nil, errorf(check.pos, "expected \"defined\" or \"empty\" but found %s", check)
+	}
+	return nil, errorf(tok.pos, "expected operator but found %s", tok)
+}
+
+// parseOperand reads a field path, a function call or a literal.
+func (p *parser) parseOperand() (operand, error) {
+	tok := p.peek()
+	if tok.kind == tokIdent && !tok.is("true") && !tok.is("false") {
+		if p.peekNext().kind == tokLParen {
+			return p.parseCall()
+		}
+		parts := []string{p.next().text}
+		for p.peek().kind == tokDot {
+			p.next()
+			part := p.next()
+			if part.kind != tokIdent && part.kind != tokNumber {
+				return operand{}, errorf(part.pos, "expected field name but found %s", part)
+			}
+			parts = append(parts, part.text)
+		}
+		return operand{path: strings.Join(parts, "."), pos: tok.pos}, nil
+	}
+	return p.parseLiteral()
+}
+
+// parseCall reads a function call such as `lineItemTotal(sku = "a")`; every
+// function takes one line item predicate.
+func (p *parser) parseCall() (operand, error) {
+	name := p.next()
+	fn, ok := functions[name.text]
+	if !ok {
+		return operand{}, errorf(name.pos, "unknown function %q", name.text)
+	}
+	p.next()
+	arg, err := p.parseOr()
+	if err != nil {
+		return operand{}, err
+	}
+	if err := p.expect(")"); err != nil {
+		return operand{}, err
+	}
+	return operand{call: &call{fn: fn, arg: arg, pos: name.pos}, pos: name.pos}, nil
+}
+
+func (p *parser) parseLiteral() (operand, error) {
+	tok := p.next()
+	switch {
+	case tok.kind == tokString:
+		return operand{literal: tok.text, pos: tok.pos}, nil
+	case tok.kind == tokNumber:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return operand{}, errorf(tok.pos, "invalid number %s", tok.text)
+		}
+		return operand{literal: f, pos: tok.pos}, nil
+	case tok.is("true"):
+		return operand{literal: true, pos: tok.pos}, nil
+	case tok.is("false"):
+		return operand{literal: false, pos: tok.pos}, nil
+	}
+	return operand{}, errorf(tok.pos, "expected value but found %s", tok)
+}
+
+func (p *parser) parseList() ([]operand, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var out []operand
+	for {
+		v, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+		if p.peek().kind != tokComma {
+			break
+		}
+		p.next()
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,409 @@
+package predicate
+
+import (
+	"errors"
+	"testing"
+
+	"commercetools-replica/internal/domain"
+)
+
+func TestParseAndEval(t *testing.T) {
+	env := Fields{
+		"sku":              "SKU-1",
+		"categories.id":    []string{"cat-1", "cat-2"},
+		"price.centAmount": int64(1500),
+		"price":            domain.Money{CurrencyCode: "EUR", CentAmount: 1500},
+		"published":        true,
+		"attributes.color": map[string]interface{}{"en": "red"},
+	}
+	cases := []struct {
+		predicate string
+		want      bool
+	}{
+		{`1 = 1`, true},
+		{`1 = 2`, false},
+		{`sku = "SKU-1"`, true},
+		{`sku != "SKU-1"`, false},
+		{`sku <> "SKU-2"`, true},
+		{`sku > "SKU-0"`, true},
+		{`price.centAmount > 1000 and price.centAmount <= 1500`, true},
+		{`price.centAmount >= 1500.5`, false},
+		{`price.centAmount < 1000 or published = true`, true},
+		{`published != false`, true},
+		{`not (published = true)`, false},
+		{`not published = false`, true},
+		{`(sku = "a" or sku = "SKU-1") and (published = true)`, true},
+		{`sku = "a" or sku = "b" and published = true`, false},
+		{`categories.id contains "cat-2"`, true},
+		{`categories.id = "cat-1"`, true},
+		{`categories.id != "cat-3"`, true},
+		{`categories.id != "cat-1"`, false},
+		{`categories.id containsAny ("cat-3", "cat-1")`, true},
+		{`categories.id contains any ("cat-3")`, false},
+		{`categories.id containsAll ("cat-1", "cat-2")`, true},
+		{`categories.id contains all ("cat-1", "cat-3")`, false},
+		{`sku in ("SKU-0", "SKU-1")`, true},
+		{`sku not in ("SKU-0", "SKU-1")`, false},
+		{`categories.id in ("cat-2")`, true},
+		{`attributes.color.en = "red"`, true},
+		{`attributes.size = "XL"`, false},
+		{`attributes.size != "XL"`, false},
+		{`attributes.size is not defined`, true},
+		{`sku is defined AND categories.id is not empty`, true},
+		{`attributes.size is empty`, true},
+		{`price = "15.00 EUR"`, true},
+		{`price > "14.99 EUR"`, true},
+		{`price >= "15 EUR"`, true},
+		{`price < "15.00 EUR"`, false},
+		{`"20.00 EUR" > price`, true},
+		{`price = "15.00 USD"`, false},
+		{`price != "15.00 USD"`, true},
+		{`price > "1.00 USD"`, false},
+		{`sku = "SKU\"1"`, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.predicate, func(t *testing.T) {
+			p, err := Parse(tc.predicate)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			got, err := p.Eval(env)
+			if err != nil {
+				t.Fatalf("eval: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		predicate string
+		want      string
+		pos       int
+	}{
+		{``, "empty predicate", -1},
+		{`   `, "empty predicate", -1},
+		{`sku =`, "expected value but found end of predicate at position 5", 5},
+		{`sku = "a" and`, "expected value but found end of predicate at position 13", 13},
+		{`sku "a"`, `expected operator but found string "a" at position 4`, 4},
+		{`(sku = "a"`, `expected ")" but found end of predicate at position 10`, 10},
+		{`sku = "a`, "unterminated string at position 6", 6},
+		{`sku ! "a"`, `unexpected "!" at position 4`, 4},
+		{`sku = 'a'`, `unexpected '\'' at position 6`, 6},
+		{`categories.id containsAny "a"`, `expected "(" but found string "a" at position 26`, 26},
+		{`categories.id containsAny ("a",)`, `expected value but found ")" at position 31`, 31},
+		{`sku in ("a" "b")`, `expected ")" but found string "b" at position 12`, 12},
+		{`sku not ("a")`, `expected "in" but found "(" at position 8`, 8},
+		{`sku is something`, `expected "defined" or "empty" but found "something" at position 7`, 7},
+		{`sku = "a" sku = "b"`, `unexpected "sku" at position 10`, 10},
+		{`attributes. = "a"`, `expected field name but found "=" at position 12`, 12},
+		{`"a" contains "b"`, `expected operator but found "contains" at position 4`, 4},
+		{`lineItemSum(sku = "a") > 1`, `unknown function "lineItemSum" at position 0`, 0},
+		{`lineItemTotal(sku = "a" > "1.00 EUR"`, `expected ")" but found ">" at position 24`, 24},
+		{`lineItemTotal(sku = "a")`, `expected operator but found end of predicate at position 24`, 24},
+		{`1 = 1 and ü = "x" and ?`, `unexpected '?' at position 22`, 22},
+	}
+	for _, tc := range cases {
+		t.Run(tc.predicate, func(t *testing.T) {
+			_, err := Parse(tc.predicate)
+			if err == nil {
+				t.Fatal("expected parse error")
+			}
+			if err.Error() != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, err.Error())
+			}
+			var perr *Error
+			if tc.pos >= 0 && (!errors.As(err, &perr) || perr.Pos != tc.pos) {
+				t.Fatalf("expected error position %d, got %v", tc.pos, err)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	cases := []struct {
+		schema    *Schema
+		predicate string
+		want      string
+	}{
+		{ProductSchema, `product.categories.id containsAny ("a", "b")`, ""},
+		{ProductSchema, `categories.id contains "a" and sku = "x"`, ""},
+		{ProductSchema, `attributes.size = "XL" and attributes.weight > 10`, ""},
+		{ProductSchema, `name.en = "Shirt"`, ""},
+		{ProductSchema, `price > "10.00 EUR" and price.centAmount < 5000`, ""},
+		{ProductSchema, `published = true`, ""},
+		{ProductSchema, `1 = 1`, ""},
+		{ProductSchema, `variant.id in (1, 2)`, ""},
+		{ProductSchema, `color = "red"`, `unknown field "color" at position 0`},
+		{ProductSchema, `sku = 1`, "cannot compare string with number at position 4"},
+		{ProductSchema, `price.centAmount = "a"`, "cannot compare number with string at position 17"},
+		{ProductSchema, `published > true`, "operator > is not defined for booleans at position 10"},
+		{ProductSchema, `name = "Shirt"`, `"name" is localized, compare name.<locale> instead at position 0`},
+		{ProductSchema, `name.en.x = "Shirt"`, `unknown field "name.en.x" at position 0`},
+		{ProductSchema, `price > "ten EUR"`, `invalid money value "ten EUR" at position 8`},
+		{ProductSchema, `price > "10.001 EUR"`, `invalid money value "10.001 EUR", at most 2 decimals are allowed at position 8`},
+		{ProductSchema, `price > "1.5 JPY"`, `invalid money value "1.5 JPY", at most 0 decimals are allowed at position 8`},
+		{ProductSchema, `price > "10.001 KWD"`, ""},
+		{ProductSchema, `price > "10.00"`, `invalid money value "10.00", expected an amount and a currency like "10.00 EUR" at position 8`},
+		{ProductSchema, `price > 10`, "cannot compare money with number at position 6"},
+		{ProductSchema, `sku contains "a"`, `contains needs a set but "sku" is a string at position 0`},
+		{ProductSchema, `categories.id containsAny ("a", 2)`, "expected a string but found a number at position 32"},
+		{ProductSchema, `published in (true)`, `in is not defined for boolean field "published" at position 0`},
+		{ProductSchema, `lineItemCount(1 = 1) > 1`, "lineItemCount is only available in cart predicates at position 0"},
+		{ProductSchema, `colour is defined`, `unknown field "colour" at position 0`},
+
+		{CartSchema, `lineItemTotal(sku = "X") > "10.00 EUR"`, ""},
+		{CartSchema, `lineItemCount(categories.id contains "shoes") >= 2`, ""},
+		{CartSchema, `lineItemExists(attributes.color = "red")`, ""},
+		{CartSchema, `forAllLineItems(price.centAmount < 1000) and totalPrice > "5.00 EUR"`, ""},
+		{CartSchema, `customer.email is defined`, ""},
+		{CartSchema, `customer.customerGroup.key = "b2b"`, ""},
+		{CartSchema, `country = "DE" or shippingAddress.country = "AT"`, ""},
+		{CartSchema, `lineItems.sku contains "a" and totalLineItemQuantity > 3`, ""},
+		{CartSchema, `custom.loyalty = true`, ""},
+		{CartSchema, `sku = "X"`, `unknown field "sku" at position 0`},
+		{CartSchema, `lineItemTotal(sku = "X") > 10`, "cannot compare money with number at position 25"},
+		{CartSchema, `lineItemCount(sku = "X") = "2"`, "cannot compare number with string at position 25"},
+		{CartSchema, `lineItemTotal(country = "DE") > "1.00 EUR"`, `unknown field "country" at position 14`},
+		{CartSchema, `lineItemExists(sku = 1)`, "cannot compare string with number at position 19"},
+		{CartSchema, `totalPrice > "10 EURO"`, `invalid money value "10 EURO", expected an amount and a currency like "10.00 EUR" at position 13`},
+
+		{LineItemSchema, `sku = "X" and quantity > 1`, ""},
+		{LineItemSchema, `price > "1.00 EUR" and productType.id = "pt"`, ""},
+		{LineItemSchema, `name.de = "Hemd"`, ""},
+		{LineItemSchema, `lineItemExists(1 = 1)`, "lineItemExists is only available in cart predicates at position 0"},
+		{LineItemSchema, `totalPrice.centAmount > 1`, `unknown field "totalPrice.centAmount" at position 0`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.predicate, func(t *testing.T) {
+			_, err := Compile(tc.predicate, tc.schema)
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestEvalTypeMismatch(t *testing.T) {
+	cases := []struct {
+		predicate string
+		env       Fields
+		want      string
+	}{
+		{`sku > 10`, Fields{"sku": "a"}, "cannot compare string with number at position 4"},
+		{`count = "a"`, Fields{"count": 1}, "cannot compare number with string at position 6"},
+		{`flag > true`, Fields{"flag": true}, "operator > is not defined for booleans at position 5"},
+		{`price > "x"`, Fields{"price": domain.Money{CurrencyCode: "EUR"}}, `invalid money value "x", expected an amount and a currency like "10.00 EUR" at position 6`},
+		{`tags contains 1`, Fields{"tags": []string{"a"}}, "cannot compare string with number at position 14"},
+		{`lineItemExists(1 = 1)`, Fields{}, "lineItemExists is only available in cart predicates at position 0"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.predicate, func(t *testing.T) {
+			_, err := MustParse(tc.predicate).Eval(tc.env)
+			if err == nil || err.Error() != tc.want {
+				t.Fatalf("expected %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestProductFields(t *testing.T) {
+	p := domain.Product{ID: "p1", Key: "shirt", ProductTypeID: "pt-1", Published: true}
+	data := domain.ProductData{CategoryIDs: []string{"cat-1"}, Name: domain.LocalizedString{"en": "Shirt", "de": "Hemd"}}
+	v := domain.ProductVariant{ID: 2, SKU: "SKU-2", Attributes: map[string]interface{}{
+		"size":   map[string]interface{}{"key": "l", "label": "Large"},
+		"colors": []interface{}{"red", "blue"},
+		"weight": 1.5,
+	}}
+	price := domain.Price{ID: "price-1", Value: domain.Money{CurrencyCode: "EUR", CentAmount: 999}}
+	env := ProductFields(p, data, v, price)
+
+	cases := []struct {
+		predicate string
+		want      bool
+	}{
+		{`product.id = "p1"`, true},
+		{`product.key = "shirt"`, true},
+		{`productType.id = "pt-1"`, true},
+		{`product.productType.id = "pt-1"`, true},
+		{`product.categories.id containsAny ("cat-1")`, true},
+		{`categories.id contains "cat-2"`, false},
+		{`variant.id = 2 and sku = "SKU-2"`, true},
+		{`product.sku = "SKU-2"`, true},
+		{`published = true`, true},
+		{`attributes.size = "l"`, true},
+		{`attributes.colors contains "blue"`, true},
+		{`attributes.weight > 1`, true},
+		{`name.de = "Hemd"`, true},
+		{`name.fr is defined`, false},
+		{`price.currencyCode = "EUR" and centAmount < 1000`, true},
+		{`price < "10.00 EUR"`, true},
+		{`price = "9.99 EUR"`, true},
+		{`variant.key is defined`, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.predicate, func(t *testing.T) {
+			pred, err := Compile(tc.predicate, ProductSchema)
+			if err != nil {
+				t.Fatalf("compile: %v", err)
+			}
+			got, err := pred.Eval(env)
+			if err != nil {
+				t.Fatalf("eval: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func testCart() domain.Cart {
+	customerID := "cust-1"
+	return domain.Cart{
+		ID:         "cart-1",
+		CustomerID: &customerID,
+		Currency:   "EUR",
+		Lines: []domain.CartLine{
+			{ID: "l1", ProductID: "p1", VariantID: 1, Quantity: 2, UnitPriceCents: 1000, Snapshot: map[string]interface{}{
+				"sku": "X", "currency": "EUR", "productKey": "shirt", "categoryIds": []interface{}{"shirts"},
+				"productName": map[string]interface{}{"en": "Shirt"},
+				"attributes":  map[string]interface{}{"color": map[string]interface{}{"key": "red", "label": "Red"}},
+			}},
+			{ID: "l2", ProductID: "p2", VariantID: 3, Quantity: 1, UnitPriceCents: 2500, Snapshot: map[string]interface{}{
+				"sku": "Y", "currency": "EUR", "categoryIds": []interface{}{"shoes"},
+			}},
+			{ID: "g1", ProductID: "p9", VariantID: 1, Quantity: 1, UnitPriceCents: 700, LineItemMode: domain.LineItemModeGiftLineItem,
+				Snapshot: map[string]interface{}{"sku": "GIFT", "currency": "EUR"}},
+		},
+	}
+}
+
+func TestCartFields(t *testing.T) {
+	customer := &domain.Customer{ID: "cust-1", Email: "jane@example.com", FirstName: "Jane"}
+	env := CartFields(testCart(), customer)
+
+	cases := []struct {
+		predicate string
+		want      bool
+	}{
+		{`totalPrice = "45.00 EUR"`, true},
+		{`totalPrice.centAmount = 4500 and currency = "EUR"`, true},
+		{`lineItemCount = 2 and totalLineItemQuantity = 3`, true},
+		{`lineItems.sku contains "X"`, true},
+		{`lineItems.sku contains "GIFT"`, false},
+		{`customerId = "cust-1" and customer.id = "cust-1"`, true},
+		{`customer.email is defined`, true},
+		{`customer.email = "jane@example.com" and customer.firstName = "Jane"`, true},
+		{`customer.lastName is defined`, false},
+		{`anonymousId is defined`, false},
+		{`country = "DE"`, false},
+		{`lineItemTotal(sku = "X") > "10.00 EUR"`, true},
+		{`lineItemTotal(sku = "X") = "20.00 EUR"`, true},
+		{`lineItemTotal(sku = "Z") = "0.00 EUR"`, true},
+		{`lineItemTotal(1 = 1) = totalPrice`, true},
+		{`lineItemTotal(categories.id contains "shoes") >= "25 EUR"`, true},
+		{`lineItemTotal(sku = "X") > "10.00 USD"`, false},
+		{`lineItemCount(1 = 1) = 3`, true},
+		{`lineItemCount(sku in ("X", "Y")) >= 3`, true},
+		{`lineItemCount(quantity > 1) = 2`, true},
+		{`lineItemExists(attributes.color = "red")`, true},
+		{`lineItemExists(name.en = "Shirt")`, true},
+		{`lineItemExists(sku = "GIFT")`, false},
+		{`not lineItemExists(sku = "Z")`, true},
+		{`lineItemExists(sku = "Z") = false`, true},
+		{`forAllLineItems(price < "30.00 EUR")`, true},
+		{`forAllLineItems(sku = "X")`, false},
+		{`lineItemExists(sku = "X") and lineItemTotal(sku = "Y") < "30.00 EUR"`, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.predicate, func(t *testing.T) {
+			pred, err := Compile(tc.predicate, CartSchema)
+			if err != nil {
+				t.Fatalf("compile: %v", err)
+			}
+			got, err := pred.Eval(env)
+			if err != nil {
+				t.Fatalf("eval: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+
+	if ok, err := MustParse(`customer.email is defined`).Eval(CartFields(testCart(), nil)); err != nil || ok {
+		t.Fatalf("expected customer.email to be undefined without a customer, got %v %v", ok, err)
+	}
+}
+
+func TestLineItemFields(t *testing.T) {
+	lines := testCart().Lines
+	cases := []struct {
+		line      domain.CartLine
+		predicate string
+		want      bool
+	}{
+		{lines[0], `sku = "X" and quantity = 2`, true},
+		{lines[0], `product.id = "p1" and productId = "p1" and product.key = "shirt"`, true},
+		{lines[0], `variant.id = 1 and variantId = 1`, true},
+		{lines[0], `price = "10.00 EUR" and totalPrice = "20.00 EUR"`, true},
+		{lines[0], `price.centAmount = 1000 and price.currencyCode = "EUR"`, true},
+		{lines[0], `categories.id contains "shirts"`, true},
+		{lines[0], `attributes.color = "red"`, true},
+		{lines[1], `attributes.color = "red"`, false},
+		{lines[1], `product.key is defined`, false},
+		{lines[1], `categories.id containsAny ("shirts", "shoes")`, true},
+		{lines[2], `lineItemMode = "GiftLineItem"`, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.predicate, func(t *testing.T) {
+			pred, err := Compile(tc.predicate, LineItemSchema)
+			if err != nil {
+				t.Fatalf("compile: %v", err)
+			}
+			got, err := pred.Eval(LineItemFields(tc.line))
+			if err != nil {
+				t.Fatalf("eval: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	cases := []struct {
+		in   string
+		want domain.Money
+		err  bool
+	}{
+		{in: "10.00 EUR", want: domain.Money{CurrencyCode: "EUR", CentAmount: 1000}},
+		{in: "10 eur", want: domain.Money{CurrencyCode: "EUR", CentAmount: 1000}},
+		{in: "0.5 USD", want: domain.Money{CurrencyCode: "USD", CentAmount: 50}},
+		{in: "-1.25 EUR", want: domain.Money{CurrencyCode: "EUR", CentAmount: -125}},
+		{in: " 3.10  GBP ", want: domain.Money{CurrencyCode: "GBP", CentAmount: 310}},
+		{in: "EUR 10.00", err: true},
+		{in: ".50 EUR", err: true},
+		{in: "1.234 EUR", err: true},
+		{in: "1,00 EUR", err: true},
+		{in: "10", err: true},
+	}
+	for _, tc := range cases {
+		got, err := parseMoney(tc.in)
+		if (err != nil) != tc.err {
+			t.Fatalf("%q: unexpected error %v", tc.in, err)
+		}
+		if !tc.err && got != tc.want {
+			t.Fatalf("%q: expected %+v, got %+v", tc.in, tc.want, got)
+		}
+	}
+}
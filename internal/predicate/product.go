@@ -0,0 +1,90 @@
+package predicate
+
+import "commercetools-replica/internal/domain"
+
+// ProductSchema lists the fields of product discount predicates; every field
+// is also available with the "product." prefix.
+var ProductSchema = &Schema{Fields: schemaWithPrefix("product.", map[string]Type{
+	"product.id":         TypeString,
+	"product.key":        TypeString,
+	"productType.id":     TypeString,
+	"published":          TypeBoolean,
+	"categories.id":      TypeStringSet,
+	"variant.id":         TypeNumber,
+	"variantId":          TypeNumber,
+	"variant.key":        TypeString,
+	"sku":                TypeString,
+	"price":              TypeMoney,
+	"price.id":           TypeString,
+	"price.centAmount":   TypeNumber,
+	"price.currencyCode": TypeString,
+	"centAmount":         TypeNumber,
+	"currencyCode":       TypeString,
+	"name":               TypeLocalized,
+	"slug":               TypeLocalized,
+	"attributes":         TypeAny,
+})}
+
+// ProductFields exposes one price of a product variant to product discount
+// predicates. Fields are available with and without the "product." prefix.
+func ProductFields(p domain.Product, data domain.ProductData, v domain.ProductVariant, price domain.Price) Fields {
+	categories := make([]interface{}, 0, len(data.CategoryIDs))
+	for _, id := range data.CategoryIDs {
+		categories = append(categories, id)
+	}
+	f := Fields{
+		"product.id":         p.ID,
+		"product.key":        p.Key,
+		"productType.id":     p.ProductTypeID,
+		"published":          p.Published,
+		"categories.id":      categories,
+		"variant.id":         v.ID,
+		"variantId":          v.ID,
+		"variant.key":        v.Key,
+		"sku":                v.SKU,
+		"price":              price.Value,
+		"price.id":           price.ID,
+		"price.centAmount":   price.Value.CentAmount,
+		"price.currencyCode": price.Value.CurrencyCode,
+		"centAmount":         price.Value.CentAmount,
+		"currencyCode":       price.Value.CurrencyCode,
+		"name":               map[string]string(data.Name),
+		"slug":               map[string]string(data.Slug),
+	}
+	for key, value := range f {
+		if s, ok := value.(string); ok && s == "" {
+			delete(f, key)
+		}
+	}
+	for name, value := range v.Attributes {
+		f["attributes."+name] = attributeValue(value)
+	}
+	aliased := make(Fields, len(f)*2)
+	for key, value := range f {
+		aliased[key] = value
+		if key != "product.id" && key != "product.key" {
+			aliased["product."+key] = value
+		}
+	}
+	return aliased
+}
+
+// attributeValue reduces enum values ({key, label}) to their key and sets to lists.
+func attributeValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if key, ok := t["key"].(string); ok {
+			if _, hasLabel := t["label"]; hasLabel {
+				return key
+			}
+		}
+		return t
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, item := range t {
+			out[i] = attributeValue(item)
+		}
+		return out
+	}
+	return v
+}
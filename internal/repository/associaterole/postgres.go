@@ -0,0 +1,142 @@
+package associaterole
+
+import (
+	"context"
+	"errors"
+
+	"commercetools-replica/internal/db"
+	"commercetools-replica/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const existsQuery = `SELECT EXISTS (SELECT 1 FROM associate_roles WHERE project_id = $1 AND id = $2)`
+
+type postgresRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgres(pool *pgxpool.Pool) Repository {
+	return &postgresRepo{pool: pool}
+}
+
+const roleColumns = `id::text, project_id::text, key, version, name, buyer_assignable, permissions, created_at, last_modified_at`
+
+func (r *postgresRepo) List(ctx context.Context, projectID string, limit, offset int) ([]domain.AssociateRole, int, error) {
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM associate_roles WHERE project_id = $1`, projectID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	const q = `
+SELECT ` + roleColumns + `
+FROM associate_roles
+WHERE project_id = $1
+ORDER BY created_at ASC, id ASC
+LIMIT NULLIF($2::int, 0) OFFSET $3
+`
+	rows, err := r.pool.Query(ctx, q, projectID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var out []domain.AssociateRole
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, *role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+func (r *postgresRepo) GetByID(ctx context.Context, projectID, id string) (*domain.AssociateRole, error) {
+	const q = `
+SELECT ` + roleColumns + `
+FROM associate_roles
+WHERE project_id = $1 AND id = $2
+`
+	return scanRole(r.pool.QueryRow(ctx, q, projectID, id))
+}
+
+func (r *postgresRepo) GetByKey(ctx context.Context, projectID, key string) (*domain.AssociateRole, error) {
+	const q = `
+SELECT ` + roleColumns + `
+FROM associate_roles
+WHERE project_id = $1 AND key = $2
+`
+	return scanRole(r.pool.QueryRow(ctx, q, projectID, key))
+}
+
+func (r *postgresRepo) Create(ctx context.Context, role domain.AssociateRole) (*domain.AssociateRole, error) {
+	const q = `
+INSERT INTO associate_roles (project_id, key, name, buyer_assignable, permissions)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING ` + roleColumns + `
+`
+	out, err := scanRole(r.pool.QueryRow(ctx, q, role.ProjectID, role.Key, role.Name, role.BuyerAssignable, nonNil(role.Permissions)))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Update(ctx context.Context, role domain.AssociateRole) (*domain.AssociateRole, error) {
+	const q = `
+UPDATE associate_roles
+SET version = version + 1,
+    name = $4,
+    buyer_assignable = $5,
+    permissions = $6,
+    last_modified_at = now()
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + roleColumns + `
+`
+	out, err := scanRole(r.pool.QueryRow(ctx, q, role.ProjectID, role.ID, role.Version, role.Name, role.BuyerAssignable, nonNil(role.Permissions)))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, db.MissingOrStale(ctx, r.pool, existsQuery, role.ProjectID, role.ID)
+	}
+	return out, err
+}
+
+func (r *postgresRepo) Delete(ctx context.Context, projectID, id string, version int) (*domain.AssociateRole, error) {
+	const q = `
+DELETE FROM associate_roles
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + roleColumns + `
+`
+	out, err := scanRole(r.pool.QueryRow(ctx, q, projectID, id, version))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, db.MissingOrStale(ctx, r.pool, existsQuery, projectID, id)
+	}
+	if db.IsForeignKeyViolation(err) {
+		return nil, domain.ErrReferenceExists
+	}
+	return out, err
+}
+
+func scanRole(row pgx.Row) (*domain.AssociateRole, error) {
+	var role domain.AssociateRole
+	err := row.Scan(&role.ID, &role.ProjectID, &role.Key, &role.Version, &role.Name, &role.BuyerAssignable, &role.Permissions, &role.CreatedAt, &role.LastModifiedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,18 @@
+package associaterole
+
+import (
+	"context"
+
+	"commercetools-replica/internal/domain"
+)
+
+type Repository interface {
+	List(ctx context.Context, projectID string, limit, offset int) ([]domain.AssociateRole, int, error)
+	GetByID(ctx context.Context, projectID, id string) (*domain.AssociateRole, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.AssociateRole, error)
+	Create(ctx context.Context, r domain.AssociateRole) (*domain.AssociateRole, error)
+	// Update writes r if r.Version is still the stored version and bumps the version.
+	Update(ctx context.Context, r domain.AssociateRole) (*domain.AssociateRole, error)
+	// Delete fails with domain.ErrReferenceExists while an associate has the role.
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.AssociateRole, error)
+}
@@ -0,0 +1,255 @@
+package businessunit
+
+import (
+	"context"
+	"errors"
+
+	"commercetools-replica/internal/db"
+	"commercetools-replica/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const existsQuery = `SELECT EXISTS (SELECT 1 FROM business_units WHERE project_id = $1 AND id = $2)`
+
+type postgresRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgres(pool *pgxpool.Pool) Repository {
+	return &postgresRepo{pool: pool}
+}
+
+// The associates are aggregated from business_unit_associates, one row per
+// role, in the order they were written.
+const unitColumns = `id::text, project_id::text, key, version, unit_type, name, contact_email, status, associate_mode,
+    addresses, default_shipping_address_id, default_billing_address_id,
+    COALESCE(parent_unit_id::text, ''),
+    COALESCE((SELECT p.key FROM business_units p WHERE p.id = business_units.parent_unit_id), ''),
+    COALESCE(top_level_unit_id::text, ''),
+    COALESCE((SELECT t.key FROM business_units t WHERE t.id = business_units.top_level_unit_id), business_units.key),
+    COALESCE((SELECT jsonb_agg(jsonb_build_object('customerId', a.customer_id, 'associateRoleAssignments', a.roles) ORDER BY a.position)
+        FROM (
+            SELECT x.customer_id::text AS customer_id, min(x.position) AS position,
+                jsonb_agg(jsonb_build_object('associateRoleKey', r.key, 'inheritance', x.inheritance) ORDER BY x.position) AS roles
+            FROM business_unit_associates x
+            JOIN associate_roles r ON r.id = x.associate_role_id
+            WHERE x.business_unit_id = business_units.id
+            GROUP BY x.customer_id
+        ) a), '[]'::jsonb),
+    created_at, last_modified_at`
+
+func (r *postgresRepo) List(ctx context.Context, projectID string, limit, offset int) ([]domain.BusinessUnit, int, error) {
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM business_units WHERE project_id = $1`, projectID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	const q = `
+SELECT ` + unitColumns + `
+FROM business_units
+WHERE project_id = $1
+ORDER BY created_at ASC, id ASC
+LIMIT NULLIF($2::int, 0) OFFSET $3
+`
+	rows, err := r.pool.Query(ctx, q, projectID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var out []domain.BusinessUnit
+	for rows.Next() {
+		u, err := scanUnit(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, *u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+func (r *postgresRepo) GetByID(ctx context.Context, projectID, id string) (*domain.BusinessUnit, error) {
+	const q = `
+SELECT ` + unitColumns + `
+FROM business_units
+WHERE project_id = $1 AND id = $2
+`
+	return scanUnit(r.pool.QueryRow(ctx, q, projectID, id))
+}
+
+func (r *postgresRepo) GetByKey(ctx context.Context, projectID, key string) (*domain.BusinessUnit, error) {
+	const q = `
+SELECT ` + unitColumns + `
+FROM business_units
+WHERE project_id = $1 AND key = $2
+`
+	return scanUnit(r.pool.QueryRow(ctx, q, projectID, key))
+}
+
+func (r *postgresRepo) Create(ctx context.Context, u domain.BusinessUnit) (*domain.BusinessUnit, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := checkParent(ctx, tx, u.ProjectID, u.ParentUnitID); err != nil {
+		return nil, err
+	}
+	var id string
+	err = tx.QueryRow(ctx, `
+INSERT INTO business_units (project_id, key, unit_type, name, contact_email, status, associate_mode, addresses,
+    default_shipping_address_id, default_billing_address_id, parent_unit_id, top_level_unit_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, '')::uuid,
+    (SELECT COALESCE(p.top_level_unit_id, p.id) FROM business_units p WHERE p.project_id = $1 AND p.id = NULLIF($11, '')::uuid))
+RETURNING id::text
+`, u.ProjectID, u.Key, u.UnitType, u.Name, u.ContactEmail, u.Status, u.AssociateMode, nonNilAddresses(u.Addresses),
+		u.DefaultShippingAddressID, u.DefaultBillingAddressID, u.ParentUnitID).Scan(&id)
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		return nil, err
+	}
+	return r.finish(ctx, tx, id, u)
+}
+
+func (r *postgresRepo) Update(ctx context.Context, u domain.BusinessUnit) (*domain.BusinessUnit, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := checkParent(ctx, tx, u.ProjectID, u.ParentUnitID); err != nil {
+		return nil, err
+	}
+	tag, err := tx.Exec(ctx, `
+UPDATE business_units
+SET version = version + 1,
+    name = $4,
+    contact_email = $5,
+    status = $6,
+    associate_mode = $7,
+    addresses = $8,
+    default_shipping_address_id = $9,
+    default_billing_address_id = $10,
+    parent_unit_id = NULLIF($11, '')::uuid,
+    top_level_unit_id = (SELECT COALESCE(p.top_level_unit_id, p.id) FROM business_units p WHERE p.project_id = $1 AND p.id = NULLIF($11, '')::uuid),
+    last_modified_at = now()
+WHERE project_id = $1 AND id = $2 AND version = $3
+`, u.ProjectID, u.ID, u.Version, u.Name, u.ContactEmail, u.Status, u.AssociateMode, nonNilAddresses(u.Addresses),
+		u.DefaultShippingAddressID, u.DefaultBillingAddressID, u.ParentUnitID)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, db.MissingOrStale(ctx, r.pool, existsQuery, u.ProjectID, u.ID)
+	}
+	return r.finish(ctx, tx, u.ID, u)
+}
+
+// finish replaces the associates of the unit, commits and returns the stored
+// unit. Customers and roles of other projects are ErrNotFound, as are
+// deleted customers.
+func (r *postgresRepo) finish(ctx context.Context, tx pgx.Tx, id string, u domain.BusinessUnit) (*domain.BusinessUnit, error) {
+	if _, err := tx.Exec(ctx, `DELETE FROM business_unit_associates WHERE business_unit_id = $1`, id); err != nil {
+		return nil, err
+	}
+	var customerIDs, roleKeys, inheritances []string
+	for _, a := range u.Associates {
+		for _, ra := range a.RoleAssignments {
+			customerIDs = append(customerIDs, a.CustomerID)
+			roleKeys = append(roleKeys, ra.RoleKey)
+			inheritances = append(inheritances, ra.Inheritance)
+		}
+	}
+	tag, err := tx.Exec(ctx, `
+INSERT INTO business_unit_associates (business_unit_id, customer_id, associate_role_id, inheritance, position)
+SELECT $1, c.id, r.id, t.inheritance, t.position
+FROM unnest($2::text[], $3::text[], $4::text[]) WITH ORDINALITY AS t(customer_id, role_key, inheritance, position)
+JOIN customers c ON c.id = t.customer_id::uuid AND c.project_id = $5 AND c.deleted_at IS NULL
+JOIN associate_roles r ON r.key = t.role_key AND r.project_id = $5
+`, id, nonNil(customerIDs), nonNil(roleKeys), nonNil(inheritances), u.ProjectID)
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		return nil, err
+	}
+	if tag.RowsAffected() != int64(len(customerIDs)) {
+		return nil, domain.ErrNotFound
+	}
+	out, err := scanUnit(tx.QueryRow(ctx, `
+SELECT `+unitColumns+`
+FROM business_units
+WHERE project_id = $1 AND id = $2
+`, u.ProjectID, id))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Delete(ctx context.Context, projectID, id string, version int) (*domain.BusinessUnit, error) {
+	const q = `
+DELETE FROM business_units
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + unitColumns + `
+`
+	out, err := scanUnit(r.pool.QueryRow(ctx, q, projectID, id, version))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, db.MissingOrStale(ctx, r.pool, existsQuery, projectID, id)
+	}
+	if db.IsForeignKeyViolation(err) {
+		return nil, domain.ErrReferenceExists
+	}
+	return out, err
+}
+
+// checkParent locks the parent unit, which has to be one of the project's.
+func checkParent(ctx context.Context, tx pgx.Tx, projectID, parentID string) error {
+	if parentID == "" {
+		return nil
+	}
+	var id string
+	err := tx.QueryRow(ctx, `SELECT id::text FROM business_units WHERE project_id = $1 AND id = $2 FOR SHARE`, projectID, parentID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	return err
+}
+
+func scanUnit(row pgx.Row) (*domain.BusinessUnit, error) {
+	var u domain.BusinessUnit
+	err := row.Scan(&u.ID, &u.ProjectID, &u.Key, &u.Version, &u.UnitType, &u.Name, &u.ContactEmail, &u.Status, &u.AssociateMode,
+		&u.Addresses, &u.DefaultShippingAddressID, &u.DefaultBillingAddressID,
+		&u.ParentUnitID, &u.ParentUnitKey, &u.TopLevelUnitID, &u.TopLevelUnitKey, &u.Associates, &u.CreatedAt, &u.LastModifiedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func nonNilAddresses(addresses []domain.CustomerAddress) []domain.CustomerAddress {
+	if addresses == nil {
+		return []domain.CustomerAddress{}
+	}
+	return addresses
+}
@@ -0,0 +1,22 @@
+package businessunit
+
+import (
+	"context"
+
+	"commercetools-replica/internal/domain"
+)
+
+type Repository interface {
+	List(ctx context.Context, projectID string, limit, offset int) ([]domain.BusinessUnit, int, error)
+	GetByID(ctx context.Context, projectID, id string) (*domain.BusinessUnit, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.BusinessUnit, error)
+	// Create and Update store the associates with the unit and derive the
+	// top-level unit from the parent. The parent, the associates' customers
+	// and their roles have to exist in the unit's project; otherwise they
+	// fail with domain.ErrNotFound.
+	Create(ctx context.Context, u domain.BusinessUnit) (*domain.BusinessUnit, error)
+	// Update writes u if u.Version is still the stored version and bumps the version.
+	Update(ctx context.Context, u domain.BusinessUnit) (*domain.BusinessUnit, error)
+	// Delete fails with domain.ErrReferenceExists while the unit has divisions.
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.BusinessUnit, error)
+}
@@ -2,9 +2,11 @@ package cart
 
 import (
 	"context"
+	"errors"
 	"os"
 	"testing"
 
+	"commercetools-replica/internal/domain"
 	"commercetools-replica/internal/migrate"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -46,6 +48,67 @@ func TestPostgres_CreateAndGet(t *testing.T) {
 	}
 }
 
+func TestPostgres_DiscountCodes(t *testing.T) {
+	ctx := context.Background()
+	pool := testPool(ctx, t)
+	defer pool.Close()
+
+	if err := migrate.Apply(ctx, pool); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+	resetTables(ctx, t, pool)
+
+	var projectID, codeID string
+	err := pool.QueryRow(ctx, `INSERT INTO projects (key, name) VALUES (gen_random_uuid()::text, 'Proj') RETURNING id::text`).Scan(&projectID)
+	if err != nil {
+		t.Fatalf("insert project: %v", err)
+	}
+	err = pool.QueryRow(ctx, `INSERT INTO discount_codes (project_id, code) VALUES ($1, 'SPRING') RETURNING id::text`, projectID).Scan(&codeID)
+	if err != nil {
+		t.Fatalf("insert discount code: %v", err)
+	}
+
+	repo := NewPostgres(pool)
+	created, err := repo.Create(ctx, CreateCartInput{ProjectID: projectID, Currency: "EUR"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := repo.AddDiscountCode(ctx, projectID, created.ID, codeID, domain.DiscountCodeMatchesCart); err != nil {
+		t.Fatalf("AddDiscountCode: %v", err)
+	}
+	if err := repo.AddDiscountCode(ctx, projectID, created.ID, codeID, domain.DiscountCodeMatchesCart); !errors.Is(err, domain.ErrAlreadyExists) {
+		t.Fatalf("expected duplicate code to fail, got %v", err)
+	}
+	total, _, err := repo.CountDiscountCodeUses(ctx, projectID, codeID, "", "")
+	if err != nil || total != 1 {
+		t.Fatalf("CountDiscountCodeUses: %d, %v", total, err)
+	}
+
+	err = repo.SaveTotals(ctx, projectID, created.ID, SaveTotalsInput{
+		TotalCents:         900,
+		DiscountOnTotal:    &domain.DiscountOnTotal{DiscountedAmount: domain.Money{CurrencyCode: "EUR", CentAmount: 100}},
+		DiscountCodeStates: map[string]string{codeID: domain.DiscountCodeDoesNotMatchCart},
+	})
+	if err != nil {
+		t.Fatalf("SaveTotals: %v", err)
+	}
+	fetched, err := repo.GetByID(ctx, projectID, created.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if fetched.TotalCents != 900 || fetched.DiscountOnTotal == nil || len(fetched.DiscountCodes) != 1 ||
+		fetched.DiscountCodes[0].State != domain.DiscountCodeDoesNotMatchCart {
+		t.Fatalf("unexpected cart %+v", fetched)
+	}
+
+	if err := repo.RemoveDiscountCode(ctx, projectID, created.ID, codeID); err != nil {
+		t.Fatalf("RemoveDiscountCode: %v", err)
+	}
+	if err := repo.RemoveDiscountCode(ctx, projectID, created.ID, codeID); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected missing code, got %v", err)
+	}
+}
+
 func testPool(ctx context.Context, t *testing.T) *pgxpool.Pool {
 	t.Helper()
 	candidates := []string{
@@ -77,7 +140,7 @@ func testPool(ctx context.Context, t *testing.T) *pgxpool.Pool {
 
 func resetTables(ctx context.Context, t *testing.T, pool *pgxpool.Pool) {
 	t.Helper()
-	if _, err := pool.Exec(ctx, `TRUNCATE cart_lines, carts, products, customers, projects RESTART IDENTITY CASCADE`); err != nil {
+	if _, err := pool.Exec(ctx, `TRUNCATE cart_discount_codes, discount_codes, cart_lines, carts, products, customers, projects RESTART IDENTITY CASCADE`); err != nil {
 		t.Fatalf("truncate tables: %v", err)
 	}
 }
@@ -2,26 +2,62 @@ package cart
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
+	"fmt"
 
+	"commercetools-replica/internal/db"
 	"commercetools-replica/internal/domain"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// conn is the part of a pool or transaction the repository uses. Begin on a
+// transaction starts a savepoint, so the methods that run their own
+// transaction also work inside InTx.
+type conn interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 type postgresRepo struct {
-	pool *pgxpool.Pool
+	conn conn
 }
 
 func NewPostgres(pool *pgxpool.Pool) Repository {
-	return &postgresRepo{pool: pool}
+	return &postgresRepo{conn: pool}
+}
+
+func (r *postgresRepo) InTx(ctx context.Context, fn func(Repository) error) error {
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&postgresRepo{conn: tx}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
+const cartColumns = `id::text, project_id::text, customer_id::text, anonymous_id::text, currency, total_cents, state, created_at, direct_discounts, discount_on_total, refused_gifts,
+    country, shipping_address, tax_mode, tax_rounding_mode, tax_calculation_mode, taxed_price, shipping_info,
+    shipping_mode, shipping, item_shipping_addresses, inventory_mode, COALESCE(store_key, ''), COALESCE(business_unit_key, '')`
+
 func (r *postgresRepo) Create(ctx context.Context, in CreateCartInput) (*domain.Cart, error) {
 	const q = `
-INSERT INTO carts (project_id, customer_id, anonymous_id, currency, total_cents, state)
-VALUES ($1, $2, $3, $4, 0, 'active')
-RETURNING id::text, project_id::text, customer_id::text, anonymous_id::text, currency, total_cents, state, created_at
+INSERT INTO carts (project_id, customer_id, anonymous_id, currency, total_cents, state, country, shipping_address, tax_mode, tax_rounding_mode, tax_calculation_mode, shipping_mode, inventory_mode, store_key, business_unit_key)
+SELECT $1, $2, $3, $4, 0, 'active', $5, $6,
+    COALESCE(NULLIF($7, ''), 'Platform'), COALESCE(NULLIF($8, ''), 'HalfEven'), COALESCE(NULLIF($9, ''), 'LineItemLevel'), COALESCE(NULLIF($10, ''), 'Single'),
+    COALESCE(NULLIF($11, ''), 'None'), NULLIF($12, ''), NULLIF($13, '')
+WHERE $2::uuid IS NULL OR EXISTS (SELECT 1 FROM customers WHERE project_id = $1 AND id = $2 AND deleted_at IS NULL)
+RETURNING id::text, project_id::text, customer_id::text, anonymous_id::text, currency, total_cents, state, created_at,
+    country, shipping_address, tax_mode, tax_rounding_mode, tax_calculation_mode, shipping_mode, inventory_mode, COALESCE(store_key, ''),
+    COALESCE(business_unit_key, '')
 `
 	var cart domain.Cart
 	var customerID *string
@@ -32,7 +68,8 @@ RETURNING id::text, project_id::text, customer_id::text, anonymous_id::text, cur
 	if in.AnonymousID != nil {
 		anonymousID = in.AnonymousID
 	}
-	if err := r.pool.QueryRow(ctx, q, in.ProjectID, customerID, anonymousID, in.Currency).Scan(
+	if err := r.conn.QueryRow(ctx, q, in.ProjectID, customerID, anonymousID, in.Currency,
+		in.Country, in.ShippingAddress, in.TaxMode, in.TaxRoundingMode, in.TaxCalculationMode, in.ShippingMode, in.InventoryMode, in.StoreKey, in.BusinessUnitKey).Scan(
 		&cart.ID,
 		&cart.ProjectID,
 		&customerID,
@@ -41,7 +78,20 @@ RETURNING id::text, project_id::text, customer_id::text, anonymous_id::text, cur
 		&cart.TotalCents,
 		&cart.State,
 		&cart.CreatedAt,
+		&cart.Country,
+		&cart.ShippingAddress,
+		&cart.TaxMode,
+		&cart.TaxRoundingMode,
+		&cart.TaxCalculationMode,
+		&cart.ShippingMode,
+		&cart.InventoryMode,
+		&cart.StoreKey,
+		&cart.BusinessUnitKey,
 	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The customer is not one of the project's.
+			return nil, domain.ErrNotFound
+		}
 		return nil, err
 	}
 	cart.CustomerID = customerID
@@ -51,33 +101,33 @@ RETURNING id::text, project_id::text, customer_id::text, anonymous_id::text, cur
 
 func (r *postgresRepo) GetByID(ctx context.Context, projectID, id string) (*domain.Cart, error) {
 	const cartQuery = `
-SELECT id::text, project_id::text, customer_id::text, anonymous_id::text, currency, total_cents, state, created_at
+SELECT ` + cartColumns + `
 FROM carts
 WHERE project_id = $1 AND id = $2
 `
 	return r.fetchCart(ctx, cartQuery, projectID, id)
 }
 
-func (r *postgresRepo) GetActiveByCustomer(ctx context.Context, projectID, customerID string) (*domain.Cart, error) {
+func (r *postgresRepo) GetActiveByCustomer(ctx context.Context, projectID, customerID, storeKey string) (*domain.Cart, error) {
 	const cartQuery = `
-SELECT id::text, project_id::text, customer_id::text, anonymous_id::text, currency, total_cents, state, created_at
+SELECT ` + cartColumns + `
 FROM carts
-WHERE project_id = $1 AND customer_id = $2 AND state = 'active'
+WHERE project_id = $1 AND customer_id = $2 AND state = 'active' AND ($3 = '' OR store_key = $3)
 ORDER BY created_at DESC
 LIMIT 1
 `
-	return r.fetchCart(ctx, cartQuery, projectID, customerID)
+	return r.fetchCart(ctx, cartQuery, projectID, customerID, storeKey)
 }
 
-func (r *postgresRepo) GetActiveByAnonymous(ctx context.Context, projectID, anonymousID string) (*domain.Cart, error) {
+func (r *postgresRepo) GetActiveByAnonymous(ctx context.Context, projectID, anonymousID, storeKey string) (*domain.Cart, error) {
 	const cartQuery = `
-SELECT id::text, project_id::text, customer_id::text, anonymous_id::text, currency, total_cents, state, created_at
+SELECT ` + cartColumns + `
 FROM carts
-WHERE project_id = $1 AND anonymous_id = $2 AND state = 'active'
+WHERE project_id = $1 AND anonymous_id = $2 AND state = 'active' AND ($3 = '' OR store_key = $3)
 ORDER BY created_at DESC
 LIMIT 1
 `
-	return r.fetchCart(ctx, cartQuery, projectID, anonymousID)
+	return r.fetchCart(ctx, cartQuery, projectID, anonymousID, storeKey)
 }
 
 func (r *postgresRepo) AssignCustomerToAnonymous(ctx context.Context, projectID, anonymousID, customerID string) (*domain.Cart, error) {
@@ -89,116 +139,128 @@ WHERE project_id = $2 AND anonymous_id = $3 AND state = 'active'
 RETURNING id::text
 `
 	var cartID string
-	if err := r.pool.QueryRow(ctx, q, customerID, projectID, anonymousID).Scan(&cartID); err != nil {
+	if err := r.conn.QueryRow(ctx, q, customerID, projectID, anonymousID).Scan(&cartID); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, domain.ErrNotFound
 		}
 		return nil, err
 	}
-	return r.fetchCart(ctx, `
-SELECT id::text, project_id::text, customer_id::text, anonymous_id::text, currency, total_cents, state, created_at
+	return r.GetByID(ctx, projectID, cartID)
+}
+
+func (r *postgresRepo) ListByCustomer(ctx context.Context, projectID, customerID string) ([]domain.Cart, error) {
+	rows, err := r.conn.Query(ctx, `
+SELECT id::text
 FROM carts
-WHERE id = $1
-`, cartID)
+WHERE project_id = $1 AND customer_id = $2
+ORDER BY created_at DESC, id DESC
+`, projectID, customerID)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	carts := make([]domain.Cart, 0, len(ids))
+	for _, id := range ids {
+		cart, err := r.GetByID(ctx, projectID, id)
+		if err != nil {
+			return nil, err
+		}
+		carts = append(carts, *cart)
+	}
+	return carts, nil
 }
 
-func (r *postgresRepo) AddLineItem(ctx context.Context, cartID string, product domain.Product, quantity int, snapshot map[string]interface{}) error {
-	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+func (r *postgresRepo) AddLineItem(ctx context.Context, projectID, cartID string, in AddLineItemInput) error {
+	tx, err := r.conn.Begin(ctx)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback(ctx)
 
+	if err := lockCart(ctx, tx, projectID, cartID); err != nil {
+		return err
+	}
+	mode := in.LineItemMode
+	if mode == "" {
+		mode = domain.LineItemModeStandard
+	}
 	var lineID string
 	var existingQty int
-	var unitPrice int64
-	err = tx.QueryRow(ctx, `
-SELECT id::text, quantity, unit_price_cents
+	err = pgx.ErrNoRows
+	if mode == domain.LineItemModeStandard {
+		err = tx.QueryRow(ctx, `
+SELECT id::text, quantity
 FROM cart_lines
-WHERE cart_id = $1 AND product_id = $2
-`, cartID, product.ID).Scan(&lineID, &existingQty, &unitPrice)
-	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-		return err
+WHERE cart_id = $1 AND product_id = $2 AND variant_id = $3 AND line_item_mode = 'Standard'
+`, cartID, in.ProductID, in.VariantID).Scan(&lineID, &existingQty)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
 	}
 
+	// Totals are left to SaveTotals, which the cart service calls after every update.
 	if err == nil {
-		newQty := existingQty + quantity
-		newTotal := unitPrice * int64(newQty)
 		if _, err := tx.Exec(ctx, `
 UPDATE cart_lines
-SET quantity = $1, total_cents = $2
-WHERE id = $3
-`, newQty, newTotal, lineID); err != nil {
+SET quantity = $1
+WHERE id = $2
+`, existingQty+in.Quantity, lineID); err != nil {
 			return err
 		}
 	} else {
-		unitPrice = product.PriceCents
-		total := unitPrice * int64(quantity)
-		if _, err := tx.Exec(ctx, `
-INSERT INTO cart_lines (cart_id, product_id, quantity, unit_price_cents, total_cents, snapshot)
-VALUES ($1, $2, $3, $4, $5, $6)
-`, cartID, product.ID, quantity, unitPrice, total, snapshot); err != nil {
+		cmd, err := tx.Exec(ctx, `
+INSERT INTO cart_lines (cart_id, product_id, variant_id, quantity, unit_price_cents, total_cents, snapshot, line_item_mode)
+SELECT $1, id, $3, $4, $5, 0, $6, $7
+FROM products
+WHERE project_id = $8 AND id = $2
+`, cartID, in.ProductID, in.VariantID, in.Quantity, in.UnitPriceCents, in.Snapshot, mode, projectID)
+		if err != nil {
 			return err
 		}
-	}
-
-	if err := updateCartTotal(ctx, tx, cartID); err != nil {
-		return err
+		if cmd.RowsAffected() == 0 {
+			return domain.ErrNotFound
+		}
 	}
 
 	return tx.Commit(ctx)
 }
 
-func (r *postgresRepo) ChangeLineItemQuantity(ctx context.Context, cartID, lineItemID string, quantity int) error {
-	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback(ctx)
-
+func (r *postgresRepo) ChangeLineItemQuantity(ctx context.Context, projectID, cartID, lineItemID string, quantity int) error {
+	q := `
+UPDATE cart_lines
+SET quantity = $4
+WHERE id = $3 AND ` + inCart
+	args := []interface{}{projectID, cartID, lineItemID, quantity}
 	if quantity <= 0 {
-		cmd, err := tx.Exec(ctx, `
+		q = `
 DELETE FROM cart_lines
-WHERE id = $1 AND cart_id = $2
-`, lineItemID, cartID)
-		if err != nil {
-			return err
-		}
-		if cmd.RowsAffected() == 0 {
-			return domain.ErrNotFound
-		}
-	} else {
-		var unitPrice int64
-		err := tx.QueryRow(ctx, `
-SELECT unit_price_cents
-FROM cart_lines
-WHERE id = $1 AND cart_id = $2
-`, lineItemID, cartID).Scan(&unitPrice)
-		if err != nil {
-			if errors.Is(err, pgx.ErrNoRows) {
-				return domain.ErrNotFound
-			}
-			return err
-		}
-		total := unitPrice * int64(quantity)
-		if _, err := tx.Exec(ctx, `
-UPDATE cart_lines
-SET quantity = $1, total_cents = $2
-WHERE id = $3 AND cart_id = $4
-`, quantity, total, lineItemID, cartID); err != nil {
-			return err
-		}
+WHERE id = $3 AND ` + inCart
+		args = args[:3]
 	}
-
-	if err := updateCartTotal(ctx, tx, cartID); err != nil {
+	cmd, err := r.conn.Exec(ctx, q, args...)
+	if err != nil {
 		return err
 	}
-
-	return tx.Commit(ctx)
+	if cmd.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
 }
 
 func (r *postgresRepo) SetState(ctx context.Context, projectID, cartID, state string) error {
-	cmd, err := r.pool.Exec(ctx, `
+	cmd, err := r.conn.Exec(ctx, `
 UPDATE carts
 SET state = $1
 WHERE project_id = $2 AND id = $3
@@ -216,7 +278,7 @@ func (r *postgresRepo) fetchCart(ctx context.Context, cartQuery string, args ...
 	var cart domain.Cart
 	var customerID *string
 	var anonymousID *string
-	err := r.pool.QueryRow(ctx, cartQuery, args...).Scan(
+	err := r.conn.QueryRow(ctx, cartQuery, args...).Scan(
 		&cart.ID,
 		&cart.ProjectID,
 		&customerID,
@@ -225,6 +287,22 @@ func (r *postgresRepo) fetchCart(ctx context.Context, cartQuery string, args ...
 		&cart.TotalCents,
 		&cart.State,
 		&cart.CreatedAt,
+		&cart.DirectDiscounts,
+		&cart.DiscountOnTotal,
+		&cart.RefusedGifts,
+		&cart.Country,
+		&cart.ShippingAddress,
+		&cart.TaxMode,
+		&cart.TaxRoundingMode,
+		&cart.TaxCalculationMode,
+		&cart.TaxedPrice,
+		&cart.ShippingInfo,
+		&cart.ShippingMode,
+		&cart.Shipping,
+		&cart.ItemShippingAddresses,
+		&cart.InventoryMode,
+		&cart.StoreKey,
+		&cart.BusinessUnitKey,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -236,12 +314,13 @@ func (r *postgresRepo) fetchCart(ctx context.Context, cartQuery string, args ...
 	cart.AnonymousID = anonymousID
 
 	const linesQuery = `
-SELECT id::text, cart_id::text, product_id::text, quantity, unit_price_cents, total_cents, snapshot, created_at
+SELECT id::text, cart_id::text, product_id::text, variant_id, quantity, unit_price_cents, total_cents, snapshot, created_at, line_item_mode, discounted_price_per_quantity,
+    tax_rate, taxed_price, shipping_details
 FROM cart_lines
 WHERE cart_id = $1
 ORDER BY created_at ASC
 `
-	rows, err := r.pool.Query(ctx, linesQuery, cart.ID)
+	rows, err := r.conn.Query(ctx, linesQuery, cart.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -253,11 +332,17 @@ ORDER BY created_at ASC
 			&line.ID,
 			&line.CartID,
 			&line.ProductID,
+			&line.VariantID,
 			&line.Quantity,
 			&line.UnitPriceCents,
 			&line.TotalCents,
 			&line.Snapshot,
 			&line.CreatedAt,
+			&line.LineItemMode,
+			&line.DiscountedPricePerQuantity,
+			&line.TaxRate,
+			&line.TaxedPrice,
+			&line.ShippingDetails,
 		); err != nil {
 			return nil, err
 		}
@@ -267,18 +352,226 @@ ORDER BY created_at ASC
 		return nil, err
 	}
 
+	codeRows, err := r.conn.Query(ctx, `
+SELECT discount_code_id::text, state
+FROM cart_discount_codes
+WHERE cart_id = $1
+ORDER BY added_at ASC
+`, cart.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer codeRows.Close()
+	for codeRows.Next() {
+		var code domain.CartDiscountCode
+		if err := codeRows.Scan(&code.DiscountCodeID, &code.State); err != nil {
+			return nil, err
+		}
+		cart.DiscountCodes = append(cart.DiscountCodes, code)
+	}
+	if err := codeRows.Err(); err != nil {
+		return nil, err
+	}
+
 	return &cart, nil
 }
 
-func updateCartTotal(ctx context.Context, tx pgx.Tx, cartID string) error {
-	_, err := tx.Exec(ctx, `
+func (r *postgresRepo) AddDiscountCode(ctx context.Context, projectID, cartID, discountCodeID, state string) error {
+	cmd, err := r.conn.Exec(ctx, `
+INSERT INTO cart_discount_codes (cart_id, discount_code_id, state)
+SELECT c.id, dc.id, $4
+FROM carts c
+JOIN discount_codes dc ON dc.project_id = c.project_id AND dc.id = $3
+WHERE c.project_id = $1 AND c.id = $2
+`, projectID, cartID, discountCodeID, state)
+	if db.IsUniqueViolation(err) {
+		return domain.ErrAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *postgresRepo) RemoveDiscountCode(ctx context.Context, projectID, cartID, discountCodeID string) error {
+	cmd, err := r.conn.Exec(ctx, `
+DELETE FROM cart_discount_codes
+WHERE discount_code_id = $3 AND `+inCart, projectID, cartID, discountCodeID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *postgresRepo) CountDiscountCodeUses(ctx context.Context, projectID, discountCodeID, customerID, anonymousID string) (int, int, error) {
+	if _, err := r.conn.Exec(ctx, `
+SELECT 1 FROM discount_codes WHERE project_id = $1 AND id = $2 FOR UPDATE
+`, projectID, discountCodeID); err != nil {
+		return 0, 0, err
+	}
+	var total, byOwner int
+	err := r.conn.QueryRow(ctx, `
+SELECT COUNT(*), COUNT(*) FILTER (WHERE c.customer_id::text = NULLIF($3, '') OR c.anonymous_id::text = NULLIF($4, ''))
+FROM cart_discount_codes dc
+JOIN carts c ON c.id = dc.cart_id
+WHERE c.project_id = $1 AND dc.discount_code_id = $2 AND c.state <> 'deleted'
+`, projectID, discountCodeID, customerID, anonymousID).Scan(&total, &byOwner)
+	return total, byOwner, err
+}
+
+func (r *postgresRepo) SetDirectDiscounts(ctx context.Context, projectID, cartID string, discounts []domain.DirectDiscount) error {
+	if discounts == nil {
+		discounts = []domain.DirectDiscount{}
+	}
+	for i := range discounts {
+		if discounts[i].ID != "" {
+			continue
+		}
+		id, err := newUUID()
+		if err != nil {
+			return err
+		}
+		discounts[i].ID = id
+	}
+	return r.updateCart(ctx, projectID, cartID, `direct_discounts = $3`, discounts)
+}
+
+func (r *postgresRepo) RefuseGift(ctx context.Context, projectID, cartID, discountID string) error {
+	return r.updateCart(ctx, projectID, cartID, `
+    refused_gifts = CASE WHEN $3 = ANY(refused_gifts) THEN refused_gifts ELSE array_append(refused_gifts, $3) END`, discountID)
+}
+
+func (r *postgresRepo) SetTaxSettings(ctx context.Context, projectID, cartID string, in TaxSettings) error {
+	return r.updateCart(ctx, projectID, cartID, `
+    country = $3,
+    shipping_address = $4,
+    tax_mode = $5,
+    tax_rounding_mode = $6,
+    tax_calculation_mode = $7`, in.Country, in.ShippingAddress, in.TaxMode, in.TaxRoundingMode, in.TaxCalculationMode)
+}
+
+func (r *postgresRepo) SetShippingInfo(ctx context.Context, projectID, cartID string, info *domain.ShippingInfo) error {
+	return r.updateCart(ctx, projectID, cartID, `shipping_info = $3`, info)
+}
+
+func (r *postgresRepo) SetItemShippingAddresses(ctx context.Context, projectID, cartID string, addresses []domain.CustomerAddress) error {
+	if addresses == nil {
+		addresses = []domain.CustomerAddress{}
+	}
+	return r.updateCart(ctx, projectID, cartID, `item_shipping_addresses = $3`, addresses)
+}
+
+func (r *postgresRepo) SetLineItemShippingDetails(ctx context.Context, projectID, cartID, lineItemID string, details *domain.ItemShippingDetails) error {
+	cmd, err := r.conn.Exec(ctx, `
+UPDATE cart_lines
+SET shipping_details = $4
+WHERE id = $3 AND `+inCart, projectID, cartID, lineItemID, details)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *postgresRepo) SetShipping(ctx context.Context, projectID, cartID string, shipping []domain.Shipping) error {
+	if shipping == nil {
+		shipping = []domain.Shipping{}
+	}
+	return r.updateCart(ctx, projectID, cartID, `shipping = $3`, shipping)
+}
+
+func (r *postgresRepo) SaveTotals(ctx context.Context, projectID, cartID string, in SaveTotalsInput) error {
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockCart(ctx, tx, projectID, cartID); err != nil {
+		return err
+	}
+	for _, line := range in.Lines {
+		discounted := line.DiscountedPricePerQuantity
+		if discounted == nil {
+			discounted = []domain.DiscountedQuantity{}
+		}
+		if _, err := tx.Exec(ctx, `
+UPDATE cart_lines
+SET total_cents = $3, discounted_price_per_quantity = $4, tax_rate = $5, taxed_price = $6
+WHERE id = $1 AND cart_id = $2
+`, line.LineID, cartID, line.TotalCents, discounted, line.TaxRate, line.TaxedPrice); err != nil {
+			return err
+		}
+	}
+	for codeID, state := range in.DiscountCodeStates {
+		if _, err := tx.Exec(ctx, `
+UPDATE cart_discount_codes
+SET state = $3
+WHERE cart_id = $1 AND discount_code_id = $2
+`, cartID, codeID, state); err != nil {
+			return err
+		}
+	}
+	shipping := in.Shipping
+	if shipping == nil {
+		shipping = []domain.Shipping{}
+	}
+	if _, err := tx.Exec(ctx, `
 UPDATE carts
-SET total_cents = COALESCE((
-	SELECT SUM(total_cents)
-	FROM cart_lines
-	WHERE cart_id = $1
-), 0)
-WHERE id = $1
-`, cartID)
+SET total_cents = $3, discount_on_total = $4, taxed_price = $5, shipping_info = $6, shipping = $7
+WHERE project_id = $1 AND id = $2
+`, projectID, cartID, in.TotalCents, in.DiscountOnTotal, in.TaxedPrice, in.ShippingInfo, shipping); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// inCart restricts cart_lines and cart_discount_codes to the cart $2 of the
+// project $1.
+const inCart = `cart_id IN (SELECT id FROM carts WHERE project_id = $1 AND id = $2)`
+
+// updateCart sets columns of the cart $2 of the project $1; set numbers its
+// args from $3.
+func (r *postgresRepo) updateCart(ctx context.Context, projectID, cartID, set string, args ...interface{}) error {
+	cmd, err := r.conn.Exec(ctx, `
+UPDATE carts
+SET `+set+`
+WHERE project_id = $1 AND id = $2
+`, append([]interface{}{projectID, cartID}, args...)...)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// lockCart locks the cart row for the transaction, so line and total updates
+// of one cart do not interleave, and checks that it belongs to the project.
+func lockCart(ctx context.Context, tx pgx.Tx, projectID, cartID string) error {
+	var id string
+	err := tx.QueryRow(ctx, `SELECT id::text FROM carts WHERE project_id = $1 AND id = $2 FOR UPDATE`, projectID, cartID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ErrNotFound
+	}
 	return err
 }
+
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -11,15 +11,101 @@ type CreateCartInput struct {
 	CustomerID  *string
 	AnonymousID *string
 	Currency    string
+	Country     string
+	// ShippingAddress and the modes are optional; empty modes take the column
+	// defaults (Platform, HalfEven, LineItemLevel, Single, None).
+	ShippingAddress    *domain.CustomerAddress
+	TaxMode            string
+	TaxRoundingMode    string
+	TaxCalculationMode string
+	ShippingMode       string
+	InventoryMode      string
+	// StoreKey is the store the cart belongs to; empty for none.
+	StoreKey string
+	// BusinessUnitKey is the business unit the cart belongs to; empty for none.
+	BusinessUnitKey string
+}
+
+// AddLineItemInput describes one product variant added to a cart at a fixed unit price.
+type AddLineItemInput struct {
+	ProductID      string
+	VariantID      int
+	Quantity       int
+	UnitPriceCents int64
+	Snapshot       map[string]interface{}
+	// LineItemMode defaults to Standard; gift lines are never merged with others.
+	LineItemMode string
+}
+
+// SaveTotalsInput holds the outcome of a discount and tax recalculation.
+type SaveTotalsInput struct {
+	Lines              []LineTotals
+	TotalCents         int64
+	DiscountOnTotal    *domain.DiscountOnTotal
+	DiscountCodeStates map[string]string
+	TaxedPrice         *domain.TaxedPrice
+	// ShippingInfo replaces the stored shipping info with its recalculated price,
+	// discounts and taxes; nil removes it.
+	ShippingInfo *domain.ShippingInfo
+	// Shipping replaces the shippings of a cart in Multiple shipping mode.
+	Shipping []domain.Shipping
+}
+
+type LineTotals struct {
+	LineID                     string
+	TotalCents                 int64
+	DiscountedPricePerQuantity []domain.DiscountedQuantity
+	TaxRate                    *domain.TaxRate
+	TaxedPrice                 *domain.TaxedPrice
+}
+
+// TaxSettings are the cart fields that decide how line items are taxed.
+type TaxSettings struct {
+	Country            string
+	ShippingAddress    *domain.CustomerAddress
+	TaxMode            string
+	TaxRoundingMode    string
+	TaxCalculationMode string
 }
 
 type Repository interface {
 	Create(ctx context.Context, in CreateCartInput) (*domain.Cart, error)
 	GetByID(ctx context.Context, projectID, id string) (*domain.Cart, error)
-	GetActiveByCustomer(ctx context.Context, projectID, customerID string) (*domain.Cart, error)
-	GetActiveByAnonymous(ctx context.Context, projectID, anonymousID string) (*domain.Cart, error)
+	// GetActiveByCustomer and GetActiveByAnonymous return the newest active
+	// cart, in the store with storeKey unless it is empty.
+	GetActiveByCustomer(ctx context.Context, projectID, customerID, storeKey string) (*domain.Cart, error)
+	GetActiveByAnonymous(ctx context.Context, projectID, anonymousID, storeKey string) (*domain.Cart, error)
 	AssignCustomerToAnonymous(ctx context.Context, projectID, anonymousID, customerID string) (*domain.Cart, error)
-	AddLineItem(ctx context.Context, cartID string, product domain.Product, quantity int, snapshot map[string]interface{}) error
-	ChangeLineItemQuantity(ctx context.Context, cartID, lineItemID string, quantity int) error
+	// ListByCustomer returns every cart of the customer in any state, newest
+	// first.
+	ListByCustomer(ctx context.Context, projectID, customerID string) ([]domain.Cart, error)
+	// AddLineItem and ChangeLineItemQuantity only change the lines; SaveTotals
+	// stores the line and cart totals computed by the cart service.
+	AddLineItem(ctx context.Context, projectID, cartID string, in AddLineItemInput) error
+	ChangeLineItemQuantity(ctx context.Context, projectID, cartID, lineItemID string, quantity int) error
 	SetState(ctx context.Context, projectID, cartID, state string) error
+	AddDiscountCode(ctx context.Context, projectID, cartID, discountCodeID, state string) error
+	RemoveDiscountCode(ctx context.Context, projectID, cartID, discountCodeID string) error
+	// CountDiscountCodeUses counts the carts that are not deleted holding the code,
+	// in total and for customerID or anonymousID. It locks the code until the
+	// transaction ends, so inside InTx no other cart can add it between the
+	// count and AddDiscountCode.
+	CountDiscountCodeUses(ctx context.Context, projectID, discountCodeID, customerID, anonymousID string) (int, int, error)
+	// SetDirectDiscounts replaces the direct discounts, assigning ids to new ones.
+	SetDirectDiscounts(ctx context.Context, projectID, cartID string, discounts []domain.DirectDiscount) error
+	// RefuseGift records that the customer removed the gift line of a discount.
+	RefuseGift(ctx context.Context, projectID, cartID, discountID string) error
+	// SetTaxSettings replaces the country, shipping address and tax modes.
+	SetTaxSettings(ctx context.Context, projectID, cartID string, in TaxSettings) error
+	// SetShippingInfo sets the shipping method of the cart; nil removes it.
+	SetShippingInfo(ctx context.Context, projectID, cartID string, info *domain.ShippingInfo) error
+	// SetShipping replaces the shippings of a cart in Multiple shipping mode.
+	SetShipping(ctx context.Context, projectID, cartID string, shipping []domain.Shipping) error
+	SetItemShippingAddresses(ctx context.Context, projectID, cartID string, addresses []domain.CustomerAddress) error
+	// SetLineItemShippingDetails replaces the shipping details of a line; nil removes them.
+	SetLineItemShippingDetails(ctx context.Context, projectID, cartID, lineItemID string, details *domain.ItemShippingDetails) error
+	SaveTotals(ctx context.Context, projectID, cartID string, in SaveTotalsInput) error
+	// InTx runs fn with a repository bound to one transaction, committed when
+	// fn returns nil and rolled back otherwise.
+	InTx(ctx context.Context, fn func(Repository) error) error
 }
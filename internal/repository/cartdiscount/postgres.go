@@ -0,0 +1,154 @@
+package cartdiscount
+
+import (
+	"context"
+	"errors"
+
+	"commercetools-replica/internal/db"
+	"commercetools-replica/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const existsQuery = `SELECT EXISTS (SELECT 1 FROM cart_discounts WHERE project_id = $1 AND id = $2)`
+
+type postgresRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgres(pool *pgxpool.Pool) Repository {
+	return &postgresRepo{pool: pool}
+}
+
+const cartDiscountColumns = `id::text, project_id::text, COALESCE(key, ''), version, name, description, value, cart_predicate, target, sort_order, is_active, valid_from, valid_until, requires_discount_code, stacking_mode, created_at, last_modified_at`
+
+func (r *postgresRepo) List(ctx context.Context, projectID string, limit, offset int) ([]domain.CartDiscount, int, error) {
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM cart_discounts WHERE project_id = $1`, projectID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	const q = `
+SELECT ` + cartDiscountColumns + `
+FROM cart_discounts
+WHERE project_id = $1
+ORDER BY sort_order::numeric DESC, id ASC
+LIMIT NULLIF($2::int, 0) OFFSET $3
+`
+	rows, err := r.pool.Query(ctx, q, projectID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var out []domain.CartDiscount
+	for rows.Next() {
+		d, err := scanCartDiscount(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+func (r *postgresRepo) GetByID(ctx context.Context, projectID, id string) (*domain.CartDiscount, error) {
+	const q = `
+SELECT ` + cartDiscountColumns + `
+FROM cart_discounts
+WHERE project_id = $1 AND id = $2
+`
+	return scanCartDiscount(r.pool.QueryRow(ctx, q, projectID, id))
+}
+
+func (r *postgresRepo) GetByKey(ctx context.Context, projectID, key string) (*domain.CartDiscount, error) {
+	const q = `
+SELECT ` + cartDiscountColumns + `
+FROM cart_discounts
+WHERE project_id = $1 AND key = $2
+`
+	return scanCartDiscount(r.pool.QueryRow(ctx, q, projectID, key))
+}
+
+func (r *postgresRepo) Create(ctx context.Context, d domain.CartDiscount) (*domain.CartDiscount, error) {
+	const q = `
+INSERT INTO cart_discounts (project_id, key, name, description, value, cart_predicate, target, sort_order, is_active, valid_from, valid_until, requires_discount_code, stacking_mode)
+VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+RETURNING ` + cartDiscountColumns + `
+`
+	out, err := scanCartDiscount(r.pool.QueryRow(ctx, q, d.ProjectID, d.Key, nonNilLocalized(d.Name), nonNilLocalized(d.Description), d.Value, d.CartPredicate, d.Target, d.SortOrder, d.IsActive, d.ValidFrom, d.ValidUntil, d.RequiresDiscountCode, d.StackingMode))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Update(ctx context.Context, d domain.CartDiscount) (*domain.CartDiscount, error) {
+	const q = `
+UPDATE cart_discounts
+SET version = version + 1,
+    key = NULLIF($4, ''),
+    name = $5,
+    description = $6,
+    value = $7,
+    cart_predicate = $8,
+    target = $9,
+    sort_order = $10,
+    is_active = $11,
+    valid_from = $12,
+    valid_until = $13,
+    requires_discount_code = $14,
+    stacking_mode = $15,
+    last_modified_at = now()
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + cartDiscountColumns + `
+`
+	out, err := scanCartDiscount(r.pool.QueryRow(ctx, q, d.ProjectID, d.ID, d.Version, d.Key, nonNilLocalized(d.Name), nonNilLocalized(d.Description), d.Value, d.CartPredicate, d.Target, d.SortOrder, d.IsActive, d.ValidFrom, d.ValidUntil, d.RequiresDiscountCode, d.StackingMode))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, db.MissingOrStale(ctx, r.pool, existsQuery, d.ProjectID, d.ID)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Delete(ctx context.Context, projectID, id string, version int) (*domain.CartDiscount, error) {
+	const q = `
+DELETE FROM cart_discounts
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + cartDiscountColumns + `
+`
+	out, err := scanCartDiscount(r.pool.QueryRow(ctx, q, projectID, id, version))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, db.MissingOrStale(ctx, r.pool, existsQuery, projectID, id)
+	}
+	return out, err
+}
+
+func scanCartDiscount(row pgx.Row) (*domain.CartDiscount, error) {
+	var d domain.CartDiscount
+	err := row.Scan(&d.ID, &d.ProjectID, &d.Key, &d.Version, &d.Name, &d.Description, &d.Value, &d.CartPredicate, &d.Target, &d.SortOrder, &d.IsActive, &d.ValidFrom, &d.ValidUntil, &d.RequiresDiscountCode, &d.StackingMode, &d.CreatedAt, &d.LastModifiedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &d, nil
+}
+
+func nonNilLocalized(s domain.LocalizedString) domain.LocalizedString {
+	if s == nil {
+		return domain.LocalizedString{}
+	}
+	return s
+}
@@ -0,0 +1,17 @@
+package cartdiscount
+
+import (
+	"context"
+
+	"commercetools-replica/internal/domain"
+)
+
+type Repository interface {
+	List(ctx context.Context, projectID string, limit, offset int) ([]domain.CartDiscount, int, error)
+	GetByID(ctx context.Context, projectID, id string) (*domain.CartDiscount, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.CartDiscount, error)
+	Create(ctx context.Context, d domain.CartDiscount) (*domain.CartDiscount, error)
+	// Update writes d if d.Version is still the stored version and bumps the version.
+	Update(ctx context.Context, d domain.CartDiscount) (*domain.CartDiscount, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.CartDiscount, error)
+}
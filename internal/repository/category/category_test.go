@@ -2,6 +2,7 @@ package category
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"commercetools-replica/internal/domain"
@@ -28,9 +29,8 @@ func TestPostgres_UpsertAndList(t *testing.T) {
 	cat, err := repo.Upsert(ctx, domain.Category{
 		ProjectID: projectID,
 		Key:       "cat-1",
-		Name:      "Cat 1",
-		Slug:      "cat-1",
-		ParentKey: "",
+		Name:      domain.LocalizedString{"en": "Cat 1"},
+		Slug:      domain.LocalizedString{"en": "cat-1"},
 	})
 	if err != nil {
 		t.Fatalf("upsert: %v", err)
@@ -67,8 +67,8 @@ func TestPostgres_UpsertUpdatesExisting(t *testing.T) {
 	first, err := repo.Upsert(ctx, domain.Category{
 		ProjectID: projectID,
 		Key:       "cat-1",
-		Name:      "Cat 1",
-		Slug:      "cat-1",
+		Name:      domain.LocalizedString{"en": "Cat 1"},
+		Slug:      domain.LocalizedString{"en": "cat-1"},
 	})
 	if err != nil {
 		t.Fatalf("upsert: %v", err)
@@ -77,8 +77,8 @@ func TestPostgres_UpsertUpdatesExisting(t *testing.T) {
 	second, err := repo.Upsert(ctx, domain.Category{
 		ProjectID: projectID,
 		Key:       "cat-1",
-		Name:      "Cat 1 Updated",
-		Slug:      "cat-1",
+		Name:      domain.LocalizedString{"en": "Cat 1 Updated"},
+		Slug:      domain.LocalizedString{"en": "cat-1"},
 	})
 	if err != nil {
 		t.Fatalf("upsert update: %v", err)
@@ -86,11 +86,72 @@ func TestPostgres_UpsertUpdatesExisting(t *testing.T) {
 	if second.ID != first.ID {
 		t.Fatalf("expected same ID after update")
 	}
-	if second.Name != "Cat 1 Updated" {
+	if second.Name["en"] != "Cat 1 Updated" {
 		t.Fatalf("expected updated name, got %+v", second)
 	}
 }
 
+func TestPostgres_UpsertMaintainsAncestors(t *testing.T) {
+	ctx := context.Background()
+	pool := testPool(ctx, t)
+	defer pool.Close()
+
+	if err := migrate.Apply(ctx, pool); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+	resetTables(ctx, t, pool)
+
+	var projectID string
+	if err := pool.QueryRow(ctx, `INSERT INTO projects (key, name) VALUES ('proj-key', 'Proj') RETURNING id::text`).Scan(&projectID); err != nil {
+		t.Fatalf("insert project: %v", err)
+	}
+
+	repo := NewPostgres(pool)
+	upsert := func(key, parentID string) *domain.Category {
+		t.Helper()
+		c, err := repo.Upsert(ctx, domain.Category{ProjectID: projectID, Key: key, Name: domain.Localized(domain.DefaultLocale, key), ParentID: parentID})
+		if err != nil {
+			t.Fatalf("upsert %s: %v", key, err)
+		}
+		return c
+	}
+	rootA := upsert("root-a", "")
+	rootB := upsert("root-b", "")
+	mid := upsert("mid", rootA.ID)
+	leaf := upsert("leaf", mid.ID)
+	if len(leaf.AncestorIDs) != 2 || leaf.AncestorIDs[0] != rootA.ID || leaf.AncestorIDs[1] != mid.ID {
+		t.Fatalf("unexpected leaf ancestors %+v", leaf.AncestorIDs)
+	}
+
+	// Moving the middle node rewrites the path of its whole subtree.
+	upsert("mid", rootB.ID)
+	got, err := repo.GetByID(ctx, projectID, leaf.ID)
+	if err != nil {
+		t.Fatalf("get leaf: %v", err)
+	}
+	if len(got.AncestorIDs) != 2 || got.AncestorIDs[0] != rootB.ID || got.AncestorIDs[1] != mid.ID || got.ParentID != mid.ID {
+		t.Fatalf("expected leaf path to follow moved parent, got %+v", got)
+	}
+
+	// An empty parent id keeps the existing parent.
+	kept := upsert("leaf", "")
+	if kept.ParentID != mid.ID {
+		t.Fatalf("expected parent to be kept, got %+v", kept)
+	}
+
+	if _, err := repo.Upsert(ctx, domain.Category{ProjectID: projectID, Key: "root-b", Name: domain.LocalizedString{"en": "root-b"}, ParentID: leaf.ID}); !errors.Is(err, ErrInvalidParent) {
+		t.Fatalf("expected cycle to be rejected, got %v", err)
+	}
+
+	page, total, err := repo.List(ctx, projectID, 2, 1)
+	if err != nil {
+		t.Fatalf("list page: %v", err)
+	}
+	if total != 4 || len(page) != 2 {
+		t.Fatalf("unexpected page total=%d len=%d", total, len(page))
+	}
+}
+
 func testPool(ctx context.Context, t *testing.T) *pgxpool.Pool {
 	t.Helper()
 	candidates := []string{
@@ -2,8 +2,10 @@ package category
 
 import (
 	"context"
+	"errors"
 
 	"commercetools-replica/internal/domain"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -15,14 +17,159 @@ func NewPostgres(pool *pgxpool.Pool) Repository {
 	return &postgresRepo{pool: pool}
 }
 
+const categoryColumns = `id::text, project_id::text, key, name, slug, COALESCE(order_hint, ''), COALESCE(parent_id::text, ''), ancestor_ids::text[], description, meta_title, meta_description, created_at`
+
 func (r *postgresRepo) ListByProject(ctx context.Context, projectID string) ([]domain.Category, error) {
 	const q = `
-SELECT id::text, project_id::text, key, name, COALESCE(slug, ''), COALESCE(order_hint, ''), COALESCE(parent_key, ''), COALESCE(description, ''), COALESCE(meta_title, ''), COALESCE(meta_description, ''), created_at
+SELECT ` + categoryColumns + `
+FROM categories
+WHERE project_id = $1
+ORDER BY COALESCE(name->>'en', name::text) ASC
+`
+	return r.queryCategories(ctx, q, projectID)
+}
+
+func (r *postgresRepo) List(ctx context.Context, projectID string, limit, offset int) ([]domain.Category, int, error) {
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM categories WHERE project_id = $1`, projectID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	// A zero limit means "no limit" to match the previous in-memory paging.
+	const q = `
+SELECT ` + categoryColumns + `
 FROM categories
 WHERE project_id = $1
-ORDER BY name ASC
+ORDER BY COALESCE(name->>'en', name::text) ASC, id ASC
+LIMIT NULLIF($2::int, 0) OFFSET $3
+`
+	cats, err := r.queryCategories(ctx, q, projectID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	return cats, total, nil
+}
+
+func (r *postgresRepo) GetByID(ctx context.Context, projectID, id string) (*domain.Category, error) {
+	const q = `
+SELECT ` + categoryColumns + `
+FROM categories
+WHERE project_id = $1 AND id = $2
+`
+	return scanCategory(r.pool.QueryRow(ctx, q, projectID, id))
+}
+
+func (r *postgresRepo) GetByKey(ctx context.Context, projectID, key string) (*domain.Category, error) {
+	const q = `
+SELECT ` + categoryColumns + `
+FROM categories
+WHERE project_id = $1 AND key = $2
 `
-	rows, err := r.pool.Query(ctx, q, projectID)
+	return scanCategory(r.pool.QueryRow(ctx, q, projectID, key))
+}
+
+func (r *postgresRepo) GetBySlug(ctx context.Context, projectID, locale, slug string) (*domain.Category, error) {
+	const q = `
+SELECT ` + categoryColumns + `
+FROM categories
+WHERE project_id = $1 AND slug @> jsonb_build_object($2::text, $3::text)
+ORDER BY created_at ASC, id ASC
+LIMIT 1
+`
+	return scanCategory(r.pool.QueryRow(ctx, q, projectID, locale, slug))
+}
+
+// Upsert inserts or updates a category by key. The parent is referenced by id and
+// the ancestors path is recomputed on write, including for every descendant when
+// the category moves to another parent. An empty ParentID keeps the current parent;
+// localized fields other than the name are merged per locale with the stored values.
+func (r *postgresRepo) Upsert(ctx context.Context, c domain.Category) (*domain.Category, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		existingID        string
+		existingParentID  string
+		existingAncestors []string
+		exists            = true
+	)
+	err = tx.QueryRow(ctx, `
+SELECT id::text, COALESCE(parent_id::text, ''), ancestor_ids::text[]
+FROM categories
+WHERE project_id = $1 AND key = $2
+FOR UPDATE
+`, c.ProjectID, c.Key).Scan(&existingID, &existingParentID, &existingAncestors)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		exists = false
+	}
+
+	parentID := c.ParentID
+	if parentID == "" {
+		parentID = existingParentID
+	}
+	ancestors := []string{}
+	if parentID != "" {
+		var parentAncestors []string
+		err := tx.QueryRow(ctx, `
+SELECT ancestor_ids::text[]
+FROM categories
+WHERE project_id = $1 AND id = $2
+`, c.ProjectID, parentID).Scan(&parentAncestors)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, ErrInvalidParent
+			}
+			return nil, err
+		}
+		if exists && (parentID == existingID || containsID(parentAncestors, existingID)) {
+			return nil, ErrInvalidParent
+		}
+		ancestors = append(parentAncestors, parentID)
+	}
+
+	const q = `
+INSERT INTO categories (project_id, key, name, slug, order_hint, parent_id, ancestor_ids, description, meta_title, meta_description)
+VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, '')::uuid, $7::uuid[], $8, $9, $10)
+ON CONFLICT (project_id, key) DO UPDATE
+SET name = EXCLUDED.name,
+    slug = categories.slug || EXCLUDED.slug,
+    order_hint = COALESCE(NULLIF(EXCLUDED.order_hint, ''), categories.order_hint),
+    parent_id = EXCLUDED.parent_id,
+    ancestor_ids = EXCLUDED.ancestor_ids,
+    description = categories.description || EXCLUDED.description,
+    meta_title = categories.meta_title || EXCLUDED.meta_title,
+    meta_description = categories.meta_description || EXCLUDED.meta_description
+RETURNING ` + categoryColumns + `
+`
+	out, err := scanCategory(tx.QueryRow(ctx, q, c.ProjectID, c.Key, c.Name.Clone(), c.Slug.Clone(), c.OrderHint, parentID, ancestors, c.Description.Clone(), c.MetaTitle.Clone(), c.MetaDescription.Clone()))
+	if err != nil {
+		return nil, err
+	}
+
+	if exists && !equalIDs(existingAncestors, ancestors) {
+		// Descendants carry this category in their path; swap the prefix up to it.
+		if _, err := tx.Exec(ctx, `
+UPDATE categories
+SET ancestor_ids = $3::uuid[] || ancestor_ids[array_position(ancestor_ids, $2::uuid):]
+WHERE project_id = $1 AND $2::uuid = ANY(ancestor_ids)
+`, c.ProjectID, out.ID, ancestors); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) queryCategories(ctx context.Context, q string, args ...interface{}) ([]domain.Category, error) {
+	rows, err := r.pool.Query(ctx, q, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -30,11 +177,11 @@ ORDER BY name ASC
 
 	var result []domain.Category
 	for rows.Next() {
-		var c domain.Category
-		if err := rows.Scan(&c.ID, &c.ProjectID, &c.Key, &c.Name, &c.Slug, &c.OrderHint, &c.ParentKey, &c.Description, &c.MetaTitle, &c.MetaDescription, &c.CreatedAt); err != nil {
+		c, err := scanCategory(rows)
+		if err != nil {
 			return nil, err
 		}
-		result = append(result, c)
+		result = append(result, *c)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -42,28 +189,34 @@ ORDER BY name ASC
 	return result, nil
 }
 
-func (r *postgresRepo) Upsert(ctx context.Context, c domain.Category) (*domain.Category, error) {
-	const q = `
-INSERT INTO categories (project_id, key, name, slug, order_hint, parent_key, description, meta_title, meta_description)
-VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, $8, $9)
-ON CONFLICT (project_id, key) DO UPDATE
-SET name = EXCLUDED.name,
-    slug = COALESCE(NULLIF(EXCLUDED.slug, ''), categories.slug),
-    order_hint = COALESCE(NULLIF(EXCLUDED.order_hint, ''), categories.order_hint),
-    parent_key = COALESCE(NULLIF(EXCLUDED.parent_key, ''), categories.parent_key),
-    description = COALESCE(NULLIF(EXCLUDED.description, ''), categories.description),
-    meta_title = COALESCE(NULLIF(EXCLUDED.meta_title, ''), categories.meta_title),
-    meta_description = COALESCE(NULLIF(EXCLUDED.meta_description, ''), categories.meta_description)
-RETURNING id::text, created_at, COALESCE(slug, ''), COALESCE(order_hint, ''), COALESCE(parent_key, ''), COALESCE(description, ''), COALESCE(meta_title, ''), COALESCE(meta_description, '')
-`
-	var out domain.Category
-	err := r.pool.QueryRow(ctx, q, c.ProjectID, c.Key, c.Name, c.Slug, c.OrderHint, c.ParentKey, c.Description, c.MetaTitle, c.MetaDescription).
-		Scan(&out.ID, &out.CreatedAt, &out.Slug, &out.OrderHint, &out.ParentKey, &out.Description, &out.MetaTitle, &out.MetaDescription)
-	if err != nil {
+func scanCategory(row pgx.Row) (*domain.Category, error) {
+	var c domain.Category
+	if err := row.Scan(&c.ID, &c.ProjectID, &c.Key, &c.Name, &c.Slug, &c.OrderHint, &c.ParentID, &c.AncestorIDs, &c.Description, &c.MetaTitle, &c.MetaDescription, &c.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
 		return nil, err
 	}
-	out.ProjectID = c.ProjectID
-	out.Key = c.Key
-	out.Name = c.Name
-	return &out, nil
+	return &c, nil
+}
+
+func containsID(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func equalIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
 }
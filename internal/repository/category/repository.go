@@ -2,11 +2,20 @@ package category
 
 import (
 	"context"
+	"errors"
 
 	"commercetools-replica/internal/domain"
 )
 
+// ErrInvalidParent is returned when a parent does not exist in the project or would create a cycle.
+var ErrInvalidParent = errors.New("invalid parent category")
+
 type Repository interface {
 	ListByProject(ctx context.Context, projectID string) ([]domain.Category, error)
+	List(ctx context.Context, projectID string, limit, offset int) ([]domain.Category, int, error)
+	GetByID(ctx context.Context, projectID, id string) (*domain.Category, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.Category, error)
+	// GetBySlug returns the oldest category using slug for locale.
+	GetBySlug(ctx context.Context, projectID, locale, slug string) (*domain.Category, error)
 	Upsert(ctx context.Context, c domain.Category) (*domain.Category, error)
 }
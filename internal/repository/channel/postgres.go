@@ -0,0 +1,156 @@
+package channel
+
+import (
+	"context"
+	"errors"
+
+	"commercetools-replica/internal/db"
+	"commercetools-replica/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const existsQuery = `SELECT EXISTS (SELECT 1 FROM channels WHERE project_id = $1 AND id = $2)`
+
+type postgresRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgres(pool *pgxpool.Pool) Repository {
+	return &postgresRepo{pool: pool}
+}
+
+const channelColumns = `id::text, project_id::text, key, version, roles, name, description, created_at, last_modified_at`
+
+func (r *postgresRepo) List(ctx context.Context, projectID string, limit, offset int) ([]domain.Channel, int, error) {
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM channels WHERE project_id = $1`, projectID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	const q = `
+SELECT ` + channelColumns + `
+FROM channels
+WHERE project_id = $1
+ORDER BY created_at ASC, id ASC
+LIMIT NULLIF($2::int, 0) OFFSET $3
+`
+	rows, err := r.pool.Query(ctx, q, projectID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var out []domain.Channel
+	for rows.Next() {
+		c, err := scanChannel(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+func (r *postgresRepo) GetByID(ctx context.Context, projectID, id string) (*domain.Channel, error) {
+	const q = `
+SELECT ` + channelColumns + `
+FROM channels
+WHERE project_id = $1 AND id = $2
+`
+	return scanChannel(r.pool.QueryRow(ctx, q, projectID, id))
+}
+
+func (r *postgresRepo) GetByKey(ctx context.Context, projectID, key string) (*domain.Channel, error) {
+	const q = `
+SELECT ` + channelColumns + `
+FROM channels
+WHERE project_id = $1 AND key = $2
+`
+	return scanChannel(r.pool.QueryRow(ctx, q, projectID, key))
+}
+
+func (r *postgresRepo) Create(ctx context.Context, c domain.Channel) (*domain.Channel, error) {
+	const q = `
+INSERT INTO channels (project_id, key, roles, name, description)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING ` + channelColumns + `
+`
+	out, err := scanChannel(r.pool.QueryRow(ctx, q, c.ProjectID, c.Key, nonNilRoles(c.Roles), nonNilLocalized(c.Name), nonNilLocalized(c.Description)))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Update(ctx context.Context, c domain.Channel) (*domain.Channel, error) {
+	const q = `
+UPDATE channels
+SET version = version + 1,
+    key = $4,
+    roles = $5,
+    name = $6,
+    description = $7,
+    last_modified_at = now()
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + channelColumns + `
+`
+	out, err := scanChannel(r.pool.QueryRow(ctx, q, c.ProjectID, c.ID, c.Version, c.Key, nonNilRoles(c.Roles), nonNilLocalized(c.Name), nonNilLocalized(c.Description)))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, db.MissingOrStale(ctx, r.pool, existsQuery, c.ProjectID, c.ID)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Delete(ctx context.Context, projectID, id string, version int) (*domain.Channel, error) {
+	const q = `
+DELETE FROM channels
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + channelColumns + `
+`
+	out, err := scanChannel(r.pool.QueryRow(ctx, q, projectID, id, version))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, db.MissingOrStale(ctx, r.pool, existsQuery, projectID, id)
+	}
+	if db.IsForeignKeyViolation(err) {
+		return nil, domain.ErrReferenceExists
+	}
+	return out, err
+}
+
+func scanChannel(row pgx.Row) (*domain.Channel, error) {
+	var c domain.Channel
+	err := row.Scan(&c.ID, &c.ProjectID, &c.Key, &c.Version, &c.Roles, &c.Name, &c.Description, &c.CreatedAt, &c.LastModifiedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func nonNilRoles(roles []string) []string {
+	if roles == nil {
+		return []string{}
+	}
+	return roles
+}
+
+func nonNilLocalized(s domain.LocalizedString) domain.LocalizedString {
+	if s == nil {
+		return domain.LocalizedString{}
+	}
+	return s
+}
@@ -0,0 +1,19 @@
+package channel
+
+import (
+	"context"
+
+	"commercetools-replica/internal/domain"
+)
+
+type Repository interface {
+	List(ctx context.Context, projectID string, limit, offset int) ([]domain.Channel, int, error)
+	GetByID(ctx context.Context, projectID, id string) (*domain.Channel, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.Channel, error)
+	Create(ctx context.Context, c domain.Channel) (*domain.Channel, error)
+	// Update writes c if c.Version is still the stored version and bumps the version.
+	Update(ctx context.Context, c domain.Channel) (*domain.Channel, error)
+	// Delete fails with domain.ErrReferenceExists while stores or inventory
+	// entries use the channel.
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.Channel, error)
+}
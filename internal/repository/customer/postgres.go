@@ -8,12 +8,14 @@ import (
 	"log"
 	"strings"
 
+	"commercetools-replica/internal/db"
 	"commercetools-replica/internal/domain"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const existsQuery = `SELECT EXISTS (SELECT 1 FROM customers WHERE project_id = $1 AND id = $2 AND deleted_at IS NULL)`
+
 type postgresRepo struct {
 	pool   *pgxpool.Pool
 	logger *log.Logger
@@ -27,6 +29,10 @@ func NewPostgres(pool *pgxpool.Pool, logger *log.Logger) Repository {
 	return &postgresRepo{pool: pool, logger: logger}
 }
 
+const customerColumns = `id::text, project_id::text, version, email, is_email_verified, password_hash, first_name, last_name, date_of_birth, addresses,
+       default_shipping_address_id, default_billing_address_id, shipping_address_ids, billing_address_ids,
+       COALESCE(customer_group_id::text, ''), created_at, last_modified_at`
+
 func (r *postgresRepo) Create(ctx context.Context, c domain.Customer) (*domain.Customer, error) {
 	addrJSON, err := json.Marshal(c.Addresses)
 	if err != nil {
@@ -44,10 +50,10 @@ func (r *postgresRepo) Create(ctx context.Context, c domain.Customer) (*domain.C
 	const q = `
 INSERT INTO customers (
     project_id, email, password_hash, first_name, last_name, date_of_birth, addresses,
-    default_shipping_address_id, default_billing_address_id, shipping_address_ids, billing_address_ids
-) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-RETURNING id::text, project_id::text, email, password_hash, first_name, last_name, date_of_birth, addresses,
-          default_shipping_address_id, default_billing_address_id, shipping_address_ids, billing_address_ids, created_at
+    default_shipping_address_id, default_billing_address_id, shipping_address_ids, billing_address_ids,
+    customer_group_id, is_email_verified
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, '')::uuid, $13)
+RETURNING ` + customerColumns + `
 `
 	return r.scanCustomer(r.pool.QueryRow(
 		ctx,
@@ -63,15 +69,16 @@ RETURNING id::text, project_id::text, email, password_hash, first_name, last_nam
 		c.DefaultBillingAddressID,
 		shipJSON,
 		billJSON,
+		c.CustomerGroupID,
+		c.IsEmailVerified,
 	))
 }
 
 func (r *postgresRepo) GetByEmail(ctx context.Context, projectID, email string) (*domain.Customer, error) {
 	const q = `
-SELECT id::text, project_id::text, email, password_hash, first_name, last_name, date_of_birth, addresses,
-       default_shipping_address_id, default_billing_address_id, shipping_address_ids, billing_address_ids, created_at
+SELECT ` + customerColumns + `
 FROM customers
-WHERE project_id = $1 AND lower(email) = lower($2)
+WHERE project_id = $1 AND lower(email) = lower($2) AND deleted_at IS NULL
 LIMIT 1
 `
 	return r.scanCustomer(r.pool.QueryRow(ctx, q, projectID, email))
@@ -79,22 +86,327 @@ LIMIT 1
 
 func (r *postgresRepo) GetByID(ctx context.Context, projectID, id string) (*domain.Customer, error) {
 	const q = `
-SELECT id::text, project_id::text, email, password_hash, first_name, last_name, date_of_birth, addresses,
-       default_shipping_address_id, default_billing_address_id, shipping_address_ids, billing_address_ids, created_at
+SELECT ` + customerColumns + `
 FROM customers
-WHERE project_id = $1 AND id = $2
+WHERE project_id = $1 AND id = $2 AND deleted_at IS NULL
 LIMIT 1
 `
 	return r.scanCustomer(r.pool.QueryRow(ctx, q, projectID, id))
 }
 
+func (r *postgresRepo) List(ctx context.Context, projectID, email string, limit, offset int) ([]domain.Customer, int, error) {
+	const filter = `WHERE project_id = $1 AND deleted_at IS NULL AND ($2 = '' OR lower(email) = lower($2))`
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM customers `+filter, projectID, email).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	q := `
+SELECT ` + customerColumns + `
+FROM customers
+` + filter + `
+ORDER BY created_at ASC, id ASC
+LIMIT NULLIF($3::int, 0) OFFSET $4
+`
+	rows, err := r.pool.Query(ctx, q, projectID, email, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var out []domain.Customer
+	for rows.Next() {
+		c, err := r.scanCustomer(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+func (r *postgresRepo) SetPasswordHash(ctx context.Context, projectID, id, oldHash, newHash string) error {
+	const q = `
+UPDATE customers
+SET password_hash = $4
+WHERE project_id = $1 AND id = $2 AND password_hash = $3
+`
+	_, err := r.pool.Exec(ctx, q, projectID, id, oldHash, newHash)
+	return err
+}
+
+func (r *postgresRepo) Update(ctx context.Context, c domain.Customer) (*domain.Customer, error) {
+	addrJSON, err := json.Marshal(c.Addresses)
+	if err != nil {
+		return nil, err
+	}
+	shipJSON, err := json.Marshal(c.ShippingAddressIDs)
+	if err != nil {
+		return nil, err
+	}
+	billJSON, err := json.Marshal(c.BillingAddressIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	const q = `
+UPDATE customers
+SET version = version + 1,
+    email = $4,
+    password_hash = $5,
+    first_name = $6,
+    last_name = $7,
+    date_of_birth = $8,
+    addresses = $9,
+    default_shipping_address_id = $10,
+    default_billing_address_id = $11,
+    shipping_address_ids = $12,
+    billing_address_ids = $13,
+    customer_group_id = NULLIF($14, '')::uuid,
+    is_email_verified = $15,
+    last_modified_at = now()
+WHERE project_id = $1 AND id = $2 AND version = $3 AND deleted_at IS NULL
+RETURNING ` + customerColumns + `
+`
+	out, err := r.scanCustomer(r.pool.QueryRow(
+		ctx,
+		q,
+		c.ProjectID,
+		c.ID,
+		c.Version,
+		strings.ToLower(c.Email),
+		c.PasswordHash,
+		c.FirstName,
+		c.LastName,
+		c.DateOfBirth,
+		addrJSON,
+		c.DefaultShippingAddressID,
+		c.DefaultBillingAddressID,
+		shipJSON,
+		billJSON,
+		c.CustomerGroupID,
+		c.IsEmailVerified,
+	))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, db.MissingOrStale(ctx, r.pool, existsQuery, c.ProjectID, c.ID)
+	}
+	return out, err
+}
+
+// Delete freezes the customer's active carts, which keep their customer_id;
+// tokens go with the customer and orders keep their data without a
+// customer_id.
+func (r *postgresRepo) Delete(ctx context.Context, projectID, id string, version int) (*domain.Customer, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	const q = `
+DELETE FROM customers
+WHERE project_id = $1 AND id = $2 AND version = $3 AND deleted_at IS NULL
+RETURNING ` + customerColumns + `
+`
+	out, err := r.scanCustomer(tx.QueryRow(ctx, q, projectID, id, version))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, db.MissingOrStale(ctx, r.pool, existsQuery, projectID, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := freezeCarts(ctx, tx, projectID, id); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Erase anonymises the customer and soft-deletes it in one transaction: its
+// tokens and the failed logins of its email are deleted, its active carts frozen, the addresses on its carts and
+// orders are anonymised and an audit row is written. Order totals stay for accounting.
+func (r *postgresRepo) Erase(ctx context.Context, projectID, id string, version int, requestedBy string) (*domain.Customer, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// The email goes away with the update, so its failed logins go first.
+	if _, err := tx.Exec(ctx, `
+DELETE FROM login_attempts
+WHERE project_id = $1 AND kind = 'email'
+  AND subject = (SELECT lower(email) FROM customers WHERE project_id = $1 AND id = $2 AND deleted_at IS NULL)
+`, projectID, id); err != nil {
+		return nil, err
+	}
+
+	const q = `
+UPDATE customers
+SET version = version + 1,
+    email = 'erased-' || id::text || '@invalid',
+    password_hash = '',
+    first_name = '',
+    last_name = '',
+    date_of_birth = '',
+    addresses = '[]',
+    default_shipping_address_id = '',
+    default_billing_address_id = '',
+    shipping_address_ids = '[]',
+    billing_address_ids = '[]',
+    is_email_verified = false,
+    deleted_at = now(),
+    last_modified_at = now()
+WHERE project_id = $1 AND id = $2 AND version = $3 AND deleted_at IS NULL
+RETURNING ` + customerColumns + `
+`
+	erased, err := r.scanCustomer(tx.QueryRow(ctx, q, projectID, id, version))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, db.MissingOrStale(ctx, r.pool, existsQuery, projectID, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := tx.Exec(ctx, `DELETE FROM tokens WHERE project_id = $1 AND customer_id = $2`, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	// The row stays, so the business units drop the customer here instead
+	// of by cascade.
+	if _, err := tx.Exec(ctx, `DELETE FROM business_unit_associates WHERE customer_id = $1`, id); err != nil {
+		return nil, err
+	}
+	if err := freezeCarts(ctx, tx, projectID, id); err != nil {
+		return nil, err
+	}
+	carts, err := eraseCarts(ctx, tx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := eraseOrders(ctx, tx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `
+INSERT INTO customer_erasures (project_id, customer_id, requested_by, tokens, carts, orders)
+VALUES ($1, $2, $3, $4, $5, $6)
+`, projectID, id, requestedBy, tag.RowsAffected(), carts, orders); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	r.logger.Printf("customer repo: erased id=%s by=%s carts=%d orders=%d", id, requestedBy, carts, orders)
+	return erased, nil
+}
+
+// freezeCarts moves the active carts of a customer that is going away to
+// domain.CartStateFrozen.
+func freezeCarts(ctx context.Context, tx pgx.Tx, projectID, customerID string) error {
+	_, err := tx.Exec(ctx, `
+UPDATE carts
+SET state = $3
+WHERE project_id = $1 AND customer_id = $2 AND state = 'active'
+`, projectID, customerID, domain.CartStateFrozen)
+	return err
+}
+
+func eraseCarts(ctx context.Context, tx pgx.Tx, projectID, customerID string) (int, error) {
+	rows, err := tx.Query(ctx, `
+SELECT id::text, shipping_address, shipping, item_shipping_addresses
+FROM carts
+WHERE project_id = $1 AND customer_id = $2
+FOR UPDATE
+`, projectID, customerID)
+	if err != nil {
+		return 0, err
+	}
+	var carts []domain.Cart
+	for rows.Next() {
+		var c domain.Cart
+		if err := rows.Scan(&c.ID, &c.ShippingAddress, &c.Shipping, &c.ItemShippingAddresses); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		carts = append(carts, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, c := range carts {
+		c.ErasePersonalData()
+		if c.Shipping == nil {
+			c.Shipping = []domain.Shipping{}
+		}
+		if c.ItemShippingAddresses == nil {
+			c.ItemShippingAddresses = []domain.CustomerAddress{}
+		}
+		if _, err := tx.Exec(ctx, `
+UPDATE carts
+SET shipping_address = $3, shipping = $4, item_shipping_addresses = $5
+WHERE project_id = $1 AND id = $2
+`, projectID, c.ID, c.ShippingAddress, c.Shipping, c.ItemShippingAddresses); err != nil {
+			return 0, err
+		}
+	}
+	return len(carts), nil
+}
+
+func eraseOrders(ctx context.Context, tx pgx.Tx, projectID, customerID string) (int, error) {
+	rows, err := tx.Query(ctx, `
+SELECT id::text, cart
+FROM orders
+WHERE project_id = $1 AND customer_id = $2
+FOR UPDATE
+`, projectID, customerID)
+	if err != nil {
+		return 0, err
+	}
+	type orderCart struct {
+		id   string
+		cart domain.Cart
+	}
+	var orders []orderCart
+	for rows.Next() {
+		var o orderCart
+		if err := rows.Scan(&o.id, &o.cart); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		orders = append(orders, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, o := range orders {
+		o.cart.ErasePersonalData()
+		if _, err := tx.Exec(ctx, `
+UPDATE orders
+SET cart = $3, version = version + 1, last_modified_at = now()
+WHERE project_id = $1 AND id = $2
+`, projectID, o.id, o.cart); err != nil {
+			return 0, err
+		}
+	}
+	return len(orders), nil
+}
+
 func (r *postgresRepo) scanCustomer(row pgx.Row) (*domain.Customer, error) {
 	var c domain.Customer
 	var addrJSON, shipJSON, billJSON []byte
 	err := row.Scan(
 		&c.ID,
 		&c.ProjectID,
+		&c.Version,
 		&c.Email,
+		&c.IsEmailVerified,
 		&c.PasswordHash,
 		&c.FirstName,
 		&c.LastName,
@@ -104,14 +416,15 @@ func (r *postgresRepo) scanCustomer(row pgx.Row) (*domain.Customer, error) {
 		&c.DefaultBillingAddressID,
 		&shipJSON,
 		&billJSON,
+		&c.CustomerGroupID,
 		&c.CreatedAt,
+		&c.LastModifiedAt,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, domain.ErrNotFound
 		}
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		if db.IsUniqueViolation(err) {
 			return nil, domain.ErrAlreadyExists
 		}
 		r.logger.Printf("customer repo: scan error=%v", err)
@@ -11,4 +11,19 @@ type Repository interface {
 	Create(ctx context.Context, c domain.Customer) (*domain.Customer, error)
 	GetByEmail(ctx context.Context, projectID, email string) (*domain.Customer, error)
 	GetByID(ctx context.Context, projectID, id string) (*domain.Customer, error)
+	// List pages the customers of a project, oldest first; a non-empty email
+	// only matches that address. It also returns the total count.
+	List(ctx context.Context, projectID, email string, limit, offset int) ([]domain.Customer, int, error)
+	// Update writes c if c.Version is still the stored version and bumps the version.
+	Update(ctx context.Context, c domain.Customer) (*domain.Customer, error)
+	// SetPasswordHash replaces the hash if it is still oldHash, without
+	// bumping the version; a changed hash is left alone.
+	SetPasswordHash(ctx context.Context, projectID, id, oldHash, newHash string) error
+	// Delete removes the customer at version along with its tokens.
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.Customer, error)
+	// Erase anonymises the customer at version and its carts and orders,
+	// deletes its tokens and business unit roles and records requestedBy
+	// (domain.ErasureBy*). The customer is soft-deleted: no other method
+	// finds it afterwards.
+	Erase(ctx context.Context, projectID, id string, version int, requestedBy string) (*domain.Customer, error)
 }
@@ -0,0 +1,140 @@
+package customergroup
+
+import (
+	"context"
+	"errors"
+
+	"commercetools-replica/internal/db"
+	"commercetools-replica/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const existsQuery = `SELECT EXISTS (SELECT 1 FROM customer_groups WHERE project_id = $1 AND id = $2)`
+
+type postgresRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgres(pool *pgxpool.Pool) Repository {
+	return &postgresRepo{pool: pool}
+}
+
+const customerGroupColumns = `id::text, project_id::text, COALESCE(key, ''), version, name, created_at, last_modified_at`
+
+func (r *postgresRepo) List(ctx context.Context, projectID string, limit, offset int) ([]domain.CustomerGroup, int, error) {
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM customer_groups WHERE project_id = $1`, projectID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	const q = `
+SELECT ` + customerGroupColumns + `
+FROM customer_groups
+WHERE project_id = $1
+ORDER BY created_at ASC, id ASC
+LIMIT NULLIF($2::int, 0) OFFSET $3
+`
+	rows, err := r.pool.Query(ctx, q, projectID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var out []domain.CustomerGroup
+	for rows.Next() {
+		g, err := scanCustomerGroup(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, *g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+func (r *postgresRepo) GetByID(ctx context.Context, projectID, id string) (*domain.CustomerGroup, error) {
+	const q = `
+SELECT ` + customerGroupColumns + `
+FROM customer_groups
+WHERE project_id = $1 AND id = $2
+`
+	return scanCustomerGroup(r.pool.QueryRow(ctx, q, projectID, id))
+}
+
+func (r *postgresRepo) GetByKey(ctx context.Context, projectID, key string) (*domain.CustomerGroup, error) {
+	const q = `
+SELECT ` + customerGroupColumns + `
+FROM customer_groups
+WHERE project_id = $1 AND key = $2
+`
+	return scanCustomerGroup(r.pool.QueryRow(ctx, q, projectID, key))
+}
+
+func (r *postgresRepo) Create(ctx context.Context, g domain.CustomerGroup) (*domain.CustomerGroup, error) {
+	const q = `
+INSERT INTO customer_groups (project_id, key, name)
+VALUES ($1, NULLIF($2, ''), $3)
+RETURNING ` + customerGroupColumns + `
+`
+	out, err := scanCustomerGroup(r.pool.QueryRow(ctx, q, g.ProjectID, g.Key, g.Name))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Update(ctx context.Context, g domain.CustomerGroup) (*domain.CustomerGroup, error) {
+	const q = `
+UPDATE customer_groups
+SET version = version + 1,
+    key = NULLIF($4, ''),
+    name = $5,
+    last_modified_at = now()
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + customerGroupColumns + `
+`
+	out, err := scanCustomerGroup(r.pool.QueryRow(ctx, q, g.ProjectID, g.ID, g.Version, g.Key, g.Name))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, db.MissingOrStale(ctx, r.pool, existsQuery, g.ProjectID, g.ID)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Delete(ctx context.Context, projectID, id string, version int) (*domain.CustomerGroup, error) {
+	const q = `
+DELETE FROM customer_groups
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + customerGroupColumns + `
+`
+	out, err := scanCustomerGroup(r.pool.QueryRow(ctx, q, projectID, id, version))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, db.MissingOrStale(ctx, r.pool, existsQuery, projectID, id)
+	}
+	if db.IsForeignKeyViolation(err) {
+		return nil, domain.ErrReferenceExists
+	}
+	return out, err
+}
+
+func scanCustomerGroup(row pgx.Row) (*domain.CustomerGroup, error) {
+	var g domain.CustomerGroup
+	err := row.Scan(&g.ID, &g.ProjectID, &g.Key, &g.Version, &g.Name, &g.CreatedAt, &g.LastModifiedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &g, nil
+}
@@ -0,0 +1,18 @@
+package customergroup
+
+import (
+	"context"
+
+	"commercetools-replica/internal/domain"
+)
+
+type Repository interface {
+	List(ctx context.Context, projectID string, limit, offset int) ([]domain.CustomerGroup, int, error)
+	GetByID(ctx context.Context, projectID, id string) (*domain.CustomerGroup, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.CustomerGroup, error)
+	Create(ctx context.Context, g domain.CustomerGroup) (*domain.CustomerGroup, error)
+	// Update writes g if g.Version is still the stored version and bumps the version.
+	Update(ctx context.Context, g domain.CustomerGroup) (*domain.CustomerGroup, error)
+	// Delete fails with domain.ErrReferenceExists while customers belong to the group.
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.CustomerGroup, error)
+}
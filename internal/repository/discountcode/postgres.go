@@ -0,0 +1,168 @@
+package discountcode
+
+import (
+	"context"
+	"errors"
+
+	"commercetools-replica/internal/db"
+	"commercetools-replica/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const existsQuery = `SELECT EXISTS (SELECT 1 FROM discount_codes WHERE project_id = $1 AND id = $2)`
+
+type postgresRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgres(pool *pgxpool.Pool) Repository {
+	return &postgresRepo{pool: pool}
+}
+
+const discountCodeColumns = `id::text, project_id::text, COALESCE(key, ''), code, version, name, description, cart_discount_ids, cart_predicate, is_active, max_applications, max_applications_per_customer, valid_from, valid_until, created_at, last_modified_at`
+
+func (r *postgresRepo) List(ctx context.Context, projectID string, limit, offset int) ([]domain.DiscountCode, int, error) {
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM discount_codes WHERE project_id = $1`, projectID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	const q = `
+SELECT ` + discountCodeColumns + `
+FROM discount_codes
+WHERE project_id = $1
+ORDER BY created_at ASC, id ASC
+LIMIT NULLIF($2::int, 0) OFFSET $3
+`
+	rows, err := r.pool.Query(ctx, q, projectID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var out []domain.DiscountCode
+	for rows.Next() {
+		c, err := scanDiscountCode(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+func (r *postgresRepo) GetByID(ctx context.Context, projectID, id string) (*domain.DiscountCode, error) {
+	const q = `
+SELECT ` + discountCodeColumns + `
+FROM discount_codes
+WHERE project_id = $1 AND id = $2
+`
+	return scanDiscountCode(r.pool.QueryRow(ctx, q, projectID, id))
+}
+
+func (r *postgresRepo) GetByKey(ctx context.Context, projectID, key string) (*domain.DiscountCode, error) {
+	const q = `
+SELECT ` + discountCodeColumns + `
+FROM discount_codes
+WHERE project_id = $1 AND key = $2
+`
+	return scanDiscountCode(r.pool.QueryRow(ctx, q, projectID, key))
+}
+
+func (r *postgresRepo) GetByCode(ctx context.Context, projectID, code string) (*domain.DiscountCode, error) {
+	const q = `
+SELECT ` + discountCodeColumns + `
+FROM discount_codes
+WHERE project_id = $1 AND code = $2
+`
+	return scanDiscountCode(r.pool.QueryRow(ctx, q, projectID, code))
+}
+
+func (r *postgresRepo) Create(ctx context.Context, c domain.DiscountCode) (*domain.DiscountCode, error) {
+	const q = `
+INSERT INTO discount_codes (project_id, key, code, name, description, cart_discount_ids, cart_predicate, is_active, max_applications, max_applications_per_customer, valid_from, valid_until)
+VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+RETURNING ` + discountCodeColumns + `
+`
+	out, err := scanDiscountCode(r.pool.QueryRow(ctx, q, c.ProjectID, c.Key, c.Code, nonNilLocalized(c.Name), nonNilLocalized(c.Description), nonNilIDs(c.CartDiscountIDs), c.CartPredicate, c.IsActive, c.MaxApplications, c.MaxApplicationsPerCustomer, c.ValidFrom, c.ValidUntil))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Update(ctx context.Context, c domain.DiscountCode) (*domain.DiscountCode, error) {
+	const q = `
+UPDATE discount_codes
+SET version = version + 1,
+    key = NULLIF($4, ''),
+    name = $5,
+    description = $6,
+    cart_discount_ids = $7,
+    cart_predicate = $8,
+    is_active = $9,
+    max_applications = $10,
+    max_applications_per_customer = $11,
+    valid_from = $12,
+    valid_until = $13,
+    last_modified_at = now()
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + discountCodeColumns + `
+`
+	out, err := scanDiscountCode(r.pool.QueryRow(ctx, q, c.ProjectID, c.ID, c.Version, c.Key, nonNilLocalized(c.Name), nonNilLocalized(c.Description), nonNilIDs(c.CartDiscountIDs), c.CartPredicate, c.IsActive, c.MaxApplications, c.MaxApplicationsPerCustomer, c.ValidFrom, c.ValidUntil))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, db.MissingOrStale(ctx, r.pool, existsQuery, c.ProjectID, c.ID)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Delete(ctx context.Context, projectID, id string, version int) (*domain.DiscountCode, error) {
+	const q = `
+DELETE FROM discount_codes
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + discountCodeColumns + `
+`
+	out, err := scanDiscountCode(r.pool.QueryRow(ctx, q, projectID, id, version))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, db.MissingOrStale(ctx, r.pool, existsQuery, projectID, id)
+	}
+	return out, err
+}
+
+func scanDiscountCode(row pgx.Row) (*domain.DiscountCode, error) {
+	var c domain.DiscountCode
+	err := row.Scan(&c.ID, &c.ProjectID, &c.Key, &c.Code, &c.Version, &c.Name, &c.Description, &c.CartDiscountIDs, &c.CartPredicate, &c.IsActive, &c.MaxApplications, &c.MaxApplicationsPerCustomer, &c.ValidFrom, &c.ValidUntil, &c.CreatedAt, &c.LastModifiedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func nonNilLocalized(s domain.LocalizedString) domain.LocalizedString {
+	if s == nil {
+		return domain.LocalizedString{}
+	}
+	return s
+}
+
+func nonNilIDs(ids []string) []string {
+	if ids == nil {
+		return []string{}
+	}
+	return ids
+}
@@ -0,0 +1,18 @@
+package discountcode
+
+import (
+	"context"
+
+	"commercetools-replica/internal/domain"
+)
+
+type Repository interface {
+	List(ctx context.Context, projectID string, limit, offset int) ([]domain.DiscountCode, int, error)
+	GetByID(ctx context.Context, projectID, id string) (*domain.DiscountCode, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.DiscountCode, error)
+	GetByCode(ctx context.Context, projectID, code string) (*domain.DiscountCode, error)
+	Create(ctx context.Context, c domain.DiscountCode) (*domain.DiscountCode, error)
+	// Update writes c if c.Version is still the stored version and bumps the version.
+	Update(ctx context.Context, c domain.DiscountCode) (*domain.DiscountCode, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.DiscountCode, error)
+}
@@ -0,0 +1,163 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+
+	"commercetools-replica/internal/db"
+	"commercetools-replica/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const existsQuery = `SELECT EXISTS (SELECT 1 FROM inventory_entries WHERE project_id = $1 AND id = $2)`
+
+type postgresRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgres(pool *pgxpool.Pool) Repository {
+	return &postgresRepo{pool: pool}
+}
+
+const entryColumns = `id::text, project_id::text, COALESCE(key, ''), version, sku, COALESCE(supply_channel_id::text, ''),
+    quantity_on_stock, available_quantity, restockable_in_days, expected_delivery, created_at, last_modified_at`
+
+func (r *postgresRepo) List(ctx context.Context, projectID string, limit, offset int) ([]domain.InventoryEntry, int, error) {
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM inventory_entries WHERE project_id = $1`, projectID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	const q = `
+SELECT ` + entryColumns + `
+FROM inventory_entries
+WHERE project_id = $1
+ORDER BY created_at ASC, id ASC
+LIMIT NULLIF($2::int, 0) OFFSET $3
+`
+	out, err := r.query(ctx, q, projectID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+func (r *postgresRepo) GetByID(ctx context.Context, projectID, id string) (*domain.InventoryEntry, error) {
+	const q = `
+SELECT ` + entryColumns + `
+FROM inventory_entries
+WHERE project_id = $1 AND id = $2
+`
+	return scanEntry(r.pool.QueryRow(ctx, q, projectID, id))
+}
+
+func (r *postgresRepo) GetByKey(ctx context.Context, projectID, key string) (*domain.InventoryEntry, error) {
+	const q = `
+SELECT ` + entryColumns + `
+FROM inventory_entries
+WHERE project_id = $1 AND key = $2
+`
+	return scanEntry(r.pool.QueryRow(ctx, q, projectID, key))
+}
+
+func (r *postgresRepo) ListBySKUs(ctx context.Context, projectID string, skus []string) ([]domain.InventoryEntry, error) {
+	if len(skus) == 0 {
+		return nil, nil
+	}
+	const q = `
+SELECT ` + entryColumns + `
+FROM inventory_entries
+WHERE project_id = $1 AND sku = ANY($2)
+ORDER BY sku ASC, created_at ASC
+`
+	return r.query(ctx, q, projectID, skus)
+}
+
+func (r *postgresRepo) Create(ctx context.Context, e domain.InventoryEntry) (*domain.InventoryEntry, error) {
+	const q = `
+INSERT INTO inventory_entries (project_id, key, sku, supply_channel_id, quantity_on_stock, available_quantity, restockable_in_days, expected_delivery)
+VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, '')::uuid, $5, $6, $7, $8)
+RETURNING ` + entryColumns + `
+`
+	out, err := scanEntry(r.pool.QueryRow(ctx, q, e.ProjectID, e.Key, e.SKU, e.SupplyChannelID,
+		e.QuantityOnStock, e.AvailableQuantity, e.RestockableInDays, e.ExpectedDelivery))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Update(ctx context.Context, e domain.InventoryEntry) (*domain.InventoryEntry, error) {
+	const q = `
+UPDATE inventory_entries
+SET version = version + 1,
+    key = NULLIF($4, ''),
+    supply_channel_id = NULLIF($5, '')::uuid,
+    quantity_on_stock = $6,
+    available_quantity = $7,
+    restockable_in_days = $8,
+    expected_delivery = $9,
+    last_modified_at = now()
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + entryColumns + `
+`
+	out, err := scanEntry(r.pool.QueryRow(ctx, q, e.ProjectID, e.ID, e.Version, e.Key, e.SupplyChannelID,
+		e.QuantityOnStock, e.AvailableQuantity, e.RestockableInDays, e.ExpectedDelivery))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, db.MissingOrStale(ctx, r.pool, existsQuery, e.ProjectID, e.ID)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Delete(ctx context.Context, projectID, id string, version int) (*domain.InventoryEntry, error) {
+	const q = `
+DELETE FROM inventory_entries
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + entryColumns + `
+`
+	out, err := scanEntry(r.pool.QueryRow(ctx, q, projectID, id, version))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, db.MissingOrStale(ctx, r.pool, existsQuery, projectID, id)
+	}
+	return out, err
+}
+
+func (r *postgresRepo) query(ctx context.Context, q string, args ...interface{}) ([]domain.InventoryEntry, error) {
+	rows, err := r.pool.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []domain.InventoryEntry
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *e)
+	}
+	return out, rows.Err()
+}
+
+func scanEntry(row pgx.Row) (*domain.InventoryEntry, error) {
+	var e domain.InventoryEntry
+	err := row.Scan(&e.ID, &e.ProjectID, &e.Key, &e.Version, &e.SKU, &e.SupplyChannelID,
+		&e.QuantityOnStock, &e.AvailableQuantity, &e.RestockableInDays, &e.ExpectedDelivery, &e.CreatedAt, &e.LastModifiedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &e, nil
+}
@@ -0,0 +1,19 @@
+package inventory
+
+import (
+	"context"
+
+	"commercetools-replica/internal/domain"
+)
+
+type Repository interface {
+	List(ctx context.Context, projectID string, limit, offset int) ([]domain.InventoryEntry, int, error)
+	GetByID(ctx context.Context, projectID, id string) (*domain.InventoryEntry, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.InventoryEntry, error)
+	// ListBySKUs returns the entries of the SKUs in every supply channel.
+	ListBySKUs(ctx context.Context, projectID string, skus []string) ([]domain.InventoryEntry, error)
+	Create(ctx context.Context, e domain.InventoryEntry) (*domain.InventoryEntry, error)
+	// Update writes e if e.Version is still the stored version and bumps the version.
+	Update(ctx context.Context, e domain.InventoryEntry) (*domain.InventoryEntry, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.InventoryEntry, error)
+}
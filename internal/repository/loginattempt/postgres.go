@@ -0,0 +1,77 @@
+package loginattempt
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type postgresRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgres(pool *pgxpool.Pool) Repository {
+	return &postgresRepo{pool: pool}
+}
+
+func (r *postgresRepo) Attempt(ctx context.Context, projectID string, key Key, now time.Time, limit Limit) (time.Time, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	// The upsert creates or locks the row until commit, so concurrent
+	// attempts of the key wait for the count and block written below.
+	const lock = `
+INSERT INTO login_attempts (project_id, kind, subject, failures, last_failure_at)
+VALUES ($1, $2, $3, 0, $4)
+ON CONFLICT (project_id, kind, subject) DO UPDATE SET failures = login_attempts.failures
+RETURNING failures, last_failure_at, blocked_until
+`
+	var failures int
+	var lastFailure time.Time
+	var blockedUntil *time.Time
+	if err := tx.QueryRow(ctx, lock, projectID, key.Kind, key.Subject, now).Scan(&failures, &lastFailure, &blockedUntil); err != nil {
+		return time.Time{}, err
+	}
+	if blockedUntil != nil && blockedUntil.After(now) {
+		return *blockedUntil, tx.Commit(ctx)
+	}
+	if limit.Window > 0 && lastFailure.Before(now.Add(-limit.Window)) {
+		failures = 0
+	}
+	failures++
+	var until *time.Time
+	if block := limit.BlockFor(failures); block > 0 {
+		t := now.Add(block)
+		until = &t
+	}
+	const count = `
+UPDATE login_attempts
+SET failures = $4, last_failure_at = $5, blocked_until = COALESCE($6, blocked_until)
+WHERE project_id = $1 AND kind = $2 AND subject = $3
+`
+	if _, err := tx.Exec(ctx, count, projectID, key.Kind, key.Subject, failures, now, until); err != nil {
+		return time.Time{}, err
+	}
+	return time.Time{}, tx.Commit(ctx)
+}
+
+func (r *postgresRepo) Forgive(ctx context.Context, projectID string, key Key) error {
+	const q = `
+UPDATE login_attempts
+SET failures = GREATEST(failures - 1, 0)
+WHERE project_id = $1 AND kind = $2 AND subject = $3
+`
+	_, err := r.pool.Exec(ctx, q, projectID, key.Kind, key.Subject)
+	return err
+}
+
+func (r *postgresRepo) Clear(ctx context.Context, projectID string, key Key) error {
+	const q = `DELETE FROM login_attempts WHERE project_id = $1 AND kind = $2 AND subject = $3`
+	_, err := r.pool.Exec(ctx, q, projectID, key.Kind, key.Subject)
+	return err
+}
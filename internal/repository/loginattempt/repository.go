@@ -0,0 +1,64 @@
+package loginattempt
+
+import (
+	"context"
+	"time"
+)
+
+// Key kinds.
+const (
+	KindEmail = "email"
+	KindIP    = "ip"
+)
+
+// Key names one failed-login counter of a project.
+type Key struct {
+	Kind    string
+	Subject string
+}
+
+// Limit is how failures block a key; zero fields turn their rule off.
+type Limit struct {
+	// Window is how long a failure counts after the last one.
+	Window time.Duration
+	// Free failures go unpunished. Each further one blocks the key for
+	// BackoffBase, doubled per failure up to BackoffMax.
+	Free        int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// Max failures block the key for Lockout.
+	Max     int
+	Lockout time.Duration
+}
+
+// BlockFor returns how long a key with failures failed logins is blocked.
+func (l Limit) BlockFor(failures int) time.Duration {
+	if l.Max > 0 && failures >= l.Max {
+		return l.Lockout
+	}
+	if l.BackoffBase <= 0 || failures <= l.Free {
+		return 0
+	}
+	delay := l.BackoffBase
+	for i := l.Free + 1; i < failures && delay < l.BackoffMax; i++ {
+		delay *= 2
+	}
+	if l.BackoffMax > 0 && delay > l.BackoffMax {
+		delay = l.BackoffMax
+	}
+	return delay
+}
+
+type Repository interface {
+	// Attempt counts a login attempt as a failure of the key at now, before
+	// the password is checked, and blocks the key as limit says for the new
+	// count. Reading the block, counting and blocking hold the row, so
+	// concurrent attempts cannot get past a limit. A key that is blocked at
+	// now is not counted; Attempt returns its blocked_until, otherwise the
+	// zero time.
+	Attempt(ctx context.Context, projectID string, key Key, now time.Time, limit Limit) (time.Time, error)
+	// Forgive takes back the failure Attempt counted for the key.
+	Forgive(ctx context.Context, projectID string, key Key) error
+	// Clear forgets the failures of the key.
+	Clear(ctx context.Context, projectID string, key Key) error
+}
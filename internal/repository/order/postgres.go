@@ -0,0 +1,218 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"commercetools-replica/internal/db"
+	"commercetools-replica/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type postgresRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgres(pool *pgxpool.Pool) Repository {
+	return &postgresRepo{pool: pool}
+}
+
+const orderColumns = `id::text, project_id::text, version, COALESCE(order_number, ''), order_state,
+    anonymous_id::text, COALESCE(store_key, ''), cart, created_at, last_modified_at`
+
+func (r *postgresRepo) Create(ctx context.Context, in CreateOrderInput) (*domain.Order, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	cmd, err := tx.Exec(ctx, `
+UPDATE carts
+SET state = 'ordered'
+WHERE project_id = $1 AND id = $2 AND state = 'active'
+`, in.ProjectID, in.Cart.ID)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.RowsAffected() == 0 {
+		// Another request ordered or deleted the cart first.
+		return nil, domain.ErrConcurrentModification
+	}
+	if in.Cart.InventoryMode == domain.InventoryModeTrackOnly || in.Cart.InventoryMode == domain.InventoryModeReserveOnOrder {
+		if err := reserveStock(ctx, tx, in.ProjectID, in.Cart); err != nil {
+			return nil, err
+		}
+	}
+
+	cart := in.Cart
+	cart.State = "ordered"
+	out, err := scanOrder(tx.QueryRow(ctx, `
+INSERT INTO orders (project_id, order_number, order_state, cart_id, customer_id, anonymous_id, store_key, cart)
+VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, NULLIF($7, ''), $8)
+RETURNING `+orderColumns+`
+`, in.ProjectID, in.OrderNumber, domain.OrderStateOpen, cart.ID, cart.CustomerID, cart.AnonymousID, cart.StoreKey, cart))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// reserveStock takes the ordered quantities off the available quantity of the
+// SKUs' entries without a supply channel. The entries are locked in SKU order
+// so concurrent orders wait for each other instead of deadlocking. SKUs
+// without an entry are only tracked in ReserveOnOrder mode, where they count
+// as out of stock.
+func reserveStock(ctx context.Context, tx pgx.Tx, projectID string, cart domain.Cart) error {
+	wanted := map[string]int64{}
+	for _, line := range cart.Lines {
+		sku, _ := line.Snapshot["sku"].(string)
+		if sku != "" {
+			wanted[sku] += int64(line.Quantity)
+		}
+	}
+	if len(wanted) == 0 {
+		return nil
+	}
+	skus := make([]string, 0, len(wanted))
+	for sku := range wanted {
+		skus = append(skus, sku)
+	}
+	sort.Strings(skus)
+
+	rows, err := tx.Query(ctx, `
+SELECT id::text, sku, available_quantity
+FROM inventory_entries
+WHERE project_id = $1 AND sku = ANY($2) AND supply_channel_id IS NULL
+ORDER BY sku
+FOR UPDATE
+`, projectID, skus)
+	if err != nil {
+		return err
+	}
+	type entry struct {
+		id        string
+		available int64
+	}
+	entries := map[string]entry{}
+	for rows.Next() {
+		var (
+			e   entry
+			sku string
+		)
+		if err := rows.Scan(&e.id, &sku, &e.available); err != nil {
+			rows.Close()
+			return err
+		}
+		entries[sku] = e
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if cart.InventoryMode == domain.InventoryModeReserveOnOrder {
+		var missing []string
+		for _, sku := range skus {
+			if e, ok := entries[sku]; !ok || e.available < wanted[sku] {
+				missing = append(missing, sku)
+			}
+		}
+		if len(missing) > 0 {
+			return &domain.OutOfStockError{SKUs: missing}
+		}
+	}
+	for _, sku := range skus {
+		e, ok := entries[sku]
+		if !ok {
+			continue
+		}
+		if _, err := tx.Exec(ctx, `
+UPDATE inventory_entries
+SET available_quantity = available_quantity - $2, version = version + 1, last_modified_at = now()
+WHERE project_id = $3 AND id = $1
+`, e.id, wanted[sku], projectID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepo) GetByID(ctx context.Context, projectID, id string) (*domain.Order, error) {
+	const q = `
+SELECT ` + orderColumns + `
+FROM orders
+WHERE project_id = $1 AND id = $2
+`
+	return scanOrder(r.pool.QueryRow(ctx, q, projectID, id))
+}
+
+func (r *postgresRepo) ListByCustomer(ctx context.Context, projectID, customerID, storeKey string, limit, offset int) ([]domain.Order, int, error) {
+	return r.list(ctx, "customer_id", projectID, customerID, storeKey, limit, offset)
+}
+
+func (r *postgresRepo) ListByAnonymous(ctx context.Context, projectID, anonymousID, storeKey string, limit, offset int) ([]domain.Order, int, error) {
+	return r.list(ctx, "anonymous_id", projectID, anonymousID, storeKey, limit, offset)
+}
+
+// list returns the orders whose owner column matches owner, newest first,
+// only those of the store with storeKey unless it is empty.
+func (r *postgresRepo) list(ctx context.Context, column, projectID, owner, storeKey string, limit, offset int) ([]domain.Order, int, error) {
+	where := `project_id = $1 AND ` + column + ` = $2 AND ($3 = '' OR store_key = $3)`
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM orders WHERE `+where, projectID, owner, storeKey).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := r.pool.Query(ctx, `
+SELECT `+orderColumns+`
+FROM orders
+WHERE `+where+`
+ORDER BY created_at DESC, id DESC
+LIMIT NULLIF($4::int, 0) OFFSET $5
+`, projectID, owner, storeKey, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var out []domain.Order
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, *o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+func scanOrder(row pgx.Row) (*domain.Order, error) {
+	var (
+		o           domain.Order
+		anonymousID *string
+		storeKey    string
+	)
+	err := row.Scan(&o.ID, &o.ProjectID, &o.Version, &o.OrderNumber, &o.OrderState,
+		&anonymousID, &storeKey, &o.Cart, &o.CreatedAt, &o.LastModifiedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	// The cart JSON leaves out its project and anonymous id, and the store
+	// may have been deleted since.
+	o.Cart.ProjectID, o.Cart.AnonymousID, o.Cart.StoreKey = o.ProjectID, anonymousID, storeKey
+	return &o, nil
+}
@@ -0,0 +1,28 @@
+package order
+
+import (
+	"context"
+
+	"commercetools-replica/internal/domain"
+)
+
+type CreateOrderInput struct {
+	ProjectID   string
+	OrderNumber string
+	// Cart is stored with the order and marked ordered; it has to be active.
+	Cart domain.Cart
+}
+
+type Repository interface {
+	// Create orders the cart in one transaction: it marks the cart ordered,
+	// takes the ordered quantities off the available stock of carts that track
+	// inventory and stores the order. Carts in ReserveOnOrder mode fail with a
+	// *domain.OutOfStockError if a SKU lacks stock; the rows are locked, so
+	// concurrent orders cannot oversell.
+	Create(ctx context.Context, in CreateOrderInput) (*domain.Order, error)
+	GetByID(ctx context.Context, projectID, id string) (*domain.Order, error)
+	// ListByCustomer and ListByAnonymous only return the orders of the store
+	// with storeKey unless it is empty.
+	ListByCustomer(ctx context.Context, projectID, customerID, storeKey string, limit, offset int) ([]domain.Order, int, error)
+	ListByAnonymous(ctx context.Context, projectID, anonymousID, storeKey string, limit, offset int) ([]domain.Order, int, error)
+}
@@ -2,11 +2,13 @@ package product
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 
+	"commercetools-replica/internal/db"
 	"commercetools-replica/internal/domain"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -24,9 +26,15 @@ func NewPostgres(pool *pgxpool.Pool, logger *log.Logger) Repository {
 	return &postgresRepo{pool: pool, logger: logger}
 }
 
+const productColumns = `id::text, project_id::text, COALESCE(key, ''), COALESCE(product_type_id::text, ''), COALESCE(tax_category_id::text, ''), version, published, has_staged_changes, current_data, staged_data, created_at, last_modified_at`
+
+func productScanTargets(p *domain.Product) []interface{} {
+	return []interface{}{&p.ID, &p.ProjectID, &p.Key, &p.ProductTypeID, &p.TaxCategoryID, &p.Version, &p.Published, &p.HasStagedChanges, &p.Current, &p.Staged, &p.CreatedAt, &p.LastModifiedAt}
+}
+
 func (r *postgresRepo) ListByProject(ctx context.Context, projectID string) ([]domain.Product, error) {
 	const q = `
-SELECT id::text, project_id::text, key, sku, name, COALESCE(description, ''), price_cents, currency, attributes, created_at
+SELECT ` + productColumns + `
 FROM products
 WHERE project_id = $1
 ORDER BY created_at DESC
@@ -41,7 +49,7 @@ ORDER BY created_at DESC
 	var result []domain.Product
 	for rows.Next() {
 		var p domain.Product
-		if err := rows.Scan(&p.ID, &p.ProjectID, &p.Key, &p.SKU, &p.Name, &p.Description, &p.PriceCents, &p.Currency, &p.Attributes, &p.CreatedAt); err != nil {
+		if err := rows.Scan(productScanTargets(&p)...); err != nil {
 			return nil, err
 		}
 		result = append(result, p)
@@ -56,12 +64,12 @@ ORDER BY created_at DESC
 
 func (r *postgresRepo) GetByID(ctx context.Context, projectID, id string) (*domain.Product, error) {
 	const q = `
-SELECT id::text, project_id::text, key, sku, name, COALESCE(description, ''), price_cents, currency, attributes, created_at
+SELECT ` + productColumns + `
 FROM products
 WHERE project_id = $1 AND id = $2
 `
 	var p domain.Product
-	err := r.pool.QueryRow(ctx, q, projectID, id).Scan(&p.ID, &p.ProjectID, &p.Key, &p.SKU, &p.Name, &p.Description, &p.PriceCents, &p.Currency, &p.Attributes, &p.CreatedAt)
+	err := r.pool.QueryRow(ctx, q, projectID, id).Scan(productScanTargets(&p)...)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			r.logger.Printf("product repo: get project_id=%s id=%s not found", projectID, id)
@@ -76,12 +84,12 @@ WHERE project_id = $1 AND id = $2
 
 func (r *postgresRepo) GetBySKU(ctx context.Context, projectID, sku string) (*domain.Product, error) {
 	const q = `
-SELECT id::text, project_id::text, key, sku, name, COALESCE(description, ''), price_cents, currency, attributes, created_at
+SELECT ` + productColumns + `
 FROM products
-WHERE project_id = $1 AND sku = $2
+WHERE id = (SELECT product_id FROM product_skus WHERE project_id = $1 AND sku = $2)
 `
 	var p domain.Product
-	err := r.pool.QueryRow(ctx, q, projectID, sku).Scan(&p.ID, &p.ProjectID, &p.Key, &p.SKU, &p.Name, &p.Description, &p.PriceCents, &p.Currency, &p.Attributes, &p.CreatedAt)
+	err := r.pool.QueryRow(ctx, q, projectID, sku).Scan(productScanTargets(&p)...)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			r.logger.Printf("product repo: get by sku project_id=%s sku=%s not found", projectID, sku)
@@ -94,31 +102,72 @@ WHERE project_id = $1 AND sku = $2
 	return &p, nil
 }
 
+func (r *postgresRepo) GetByKey(ctx context.Context, projectID, key string) (*domain.Product, error) {
+	const q = `
+SELECT ` + productColumns + `
+FROM products
+WHERE project_id = $1 AND key = $2
+`
+	var p domain.Product
+	err := r.pool.QueryRow(ctx, q, projectID, key).Scan(productScanTargets(&p)...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("product repo: get by key project_id=%s key=%s not found", projectID, key)
+			return nil, domain.ErrNotFound
+		}
+		r.logger.Printf("product repo: get by key project_id=%s key=%s error=%v", projectID, key, err)
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *postgresRepo) GetBySlug(ctx context.Context, projectID, locale, slug string) (*domain.Product, error) {
+	const q = `
+SELECT ` + productColumns + `
+FROM products
+WHERE id = (SELECT product_id FROM product_slugs WHERE project_id = $1 AND locale = $2 AND slug = $3)
+`
+	var p domain.Product
+	err := r.pool.QueryRow(ctx, q, projectID, locale, slug).Scan(productScanTargets(&p)...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("product repo: get by slug project_id=%s locale=%s slug=%s not found", projectID, locale, slug)
+			return nil, domain.ErrNotFound
+		}
+		r.logger.Printf("product repo: get by slug project_id=%s locale=%s slug=%s error=%v", projectID, locale, slug, err)
+		return nil, err
+	}
+	return &p, nil
+}
+
 func (r *postgresRepo) Upsert(ctx context.Context, product domain.Product) (*domain.Product, error) {
+	if err := assignPriceIDs(&product.Current); err != nil {
+		return nil, err
+	}
+	product.Staged = product.Current
+
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
 	const q = `
-INSERT INTO products (id, project_id, key, sku, name, description, price_cents, currency, attributes)
-VALUES (COALESCE(NULLIF($1, '')::uuid, gen_random_uuid()), $2, $3, $4, NULLIF($5, ''), $6, $7, $8, COALESCE($9, '{}'::jsonb))
+INSERT INTO products (id, project_id, key, product_type_id, tax_category_id, version, published, has_staged_changes, current_data, staged_data)
+VALUES (COALESCE(NULLIF($1, '')::uuid, gen_random_uuid()), $2, $3, NULLIF($5, '')::uuid, NULLIF($6, '')::uuid, 1, true, false, $4, $4)
 ON CONFLICT (project_id, key) DO UPDATE SET
-    sku = EXCLUDED.sku,
-    name = EXCLUDED.name,
-    description = EXCLUDED.description,
-    price_cents = EXCLUDED.price_cents,
-    currency = EXCLUDED.currency,
-    attributes = EXCLUDED.attributes
-RETURNING id::text, created_at
+    version = products.version + 1,
+    product_type_id = COALESCE(EXCLUDED.product_type_id, products.product_type_id),
+    tax_category_id = COALESCE(EXCLUDED.tax_category_id, products.tax_category_id),
+    published = true,
+    has_staged_changes = false,
+    current_data = EXCLUDED.current_data,
+    staged_data = EXCLUDED.staged_data,
+    last_modified_at = now()
+RETURNING ` + productColumns + `
 `
 	var res domain.Product
-	err := r.pool.QueryRow(ctx, q,
-		product.ID,
-		product.ProjectID,
-		product.Key,
-		product.SKU,
-		product.Name,
-		product.Description,
-		product.PriceCents,
-		product.Currency,
-		product.Attributes,
-	).Scan(&res.ID, &res.CreatedAt)
+	err = tx.QueryRow(ctx, q, product.ID, product.ProjectID, product.Key, product.Current, product.ProductTypeID, product.TaxCategoryID).Scan(productScanTargets(&res)...)
 	if err != nil {
 		r.logger.Printf("product repo: upsert key=%s project_id=%s error=%v", product.Key, product.ProjectID, err)
 		return nil, err
@@ -126,14 +175,198 @@ RETURNING id::text, created_at
 	if product.ID != "" && res.ID != product.ID {
 		return nil, fmt.Errorf("product repo: id mismatch for key=%s project_id=%s existing_id=%s import_id=%s", product.Key, product.ProjectID, res.ID, product.ID)
 	}
-	res.ProjectID = product.ProjectID
-	res.Key = product.Key
-	res.SKU = product.SKU
-	res.Name = product.Name
-	res.Description = product.Description
-	res.PriceCents = product.PriceCents
-	res.Currency = product.Currency
-	res.Attributes = product.Attributes
-	r.logger.Printf("product repo: upserted key=%s project_id=%s id=%s", res.Key, res.ProjectID, res.ID)
+	if err := syncSKUs(ctx, tx, res); err != nil {
+		r.logger.Printf("product repo: upsert skus key=%s project_id=%s error=%v", product.Key, product.ProjectID, err)
+		return nil, err
+	}
+	if err := syncSlugs(ctx, tx, res); err != nil {
+		r.logger.Printf("product repo: upsert slugs key=%s project_id=%s error=%v", product.Key, product.ProjectID, err)
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	r.logger.Printf("product repo: upserted key=%s project_id=%s id=%s version=%d", res.Key, res.ProjectID, res.ID, res.Version)
 	return &res, nil
 }
+
+func (r *postgresRepo) Create(ctx context.Context, product domain.Product) (*domain.Product, error) {
+	if err := assignPriceIDs(&product.Current); err != nil {
+		return nil, err
+	}
+	if err := assignPriceIDs(&product.Staged); err != nil {
+		return nil, err
+	}
+
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	const q = `
+INSERT INTO products (project_id, key, product_type_id, tax_category_id, version, published, has_staged_changes, current_data, staged_data)
+VALUES ($1, NULLIF($2, ''), NULLIF($7, '')::uuid, NULLIF($8, '')::uuid, 1, $3, $4, $5, $6)
+RETURNING ` + productColumns + `
+`
+	var res domain.Product
+	err = tx.QueryRow(ctx, q, product.ProjectID, product.Key, product.Published, product.HasStagedChanges, product.Current, product.Staged, product.ProductTypeID, product.TaxCategoryID).Scan(productScanTargets(&res)...)
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		r.logger.Printf("product repo: create key=%s project_id=%s error=%v", product.Key, product.ProjectID, err)
+		return nil, err
+	}
+	if err := syncSKUs(ctx, tx, res); err != nil {
+		return nil, err
+	}
+	if err := syncSlugs(ctx, tx, res); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	r.logger.Printf("product repo: created key=%s project_id=%s id=%s", res.Key, res.ProjectID, res.ID)
+	return &res, nil
+}
+
+func (r *postgresRepo) Update(ctx context.Context, product domain.Product) (*domain.Product, error) {
+	if err := assignPriceIDs(&product.Current); err != nil {
+		return nil, err
+	}
+	if err := assignPriceIDs(&product.Staged); err != nil {
+		return nil, err
+	}
+
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	const q = `
+UPDATE products
+SET version = version + 1,
+    published = $4,
+    has_staged_changes = $5,
+    current_data = $6,
+    staged_data = $7,
+    tax_category_id = NULLIF($8, '')::uuid,
+    last_modified_at = now()
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + productColumns + `
+`
+	var res domain.Product
+	err = tx.QueryRow(ctx, q, product.ProjectID, product.ID, product.Version, product.Published, product.HasStagedChanges, product.Current, product.Staged, product.TaxCategoryID).Scan(productScanTargets(&res)...)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Printf("product repo: update project_id=%s id=%s error=%v", product.ProjectID, product.ID, err)
+			return nil, err
+		}
+		var exists bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM products WHERE project_id = $1 AND id = $2)`, product.ProjectID, product.ID).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, domain.ErrConcurrentModification
+		}
+		return nil, domain.ErrNotFound
+	}
+	if err := syncSKUs(ctx, tx, res); err != nil {
+		return nil, err
+	}
+	if err := syncSlugs(ctx, tx, res); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	r.logger.Printf("product repo: updated project_id=%s id=%s version=%d", res.ProjectID, res.ID, res.Version)
+	return &res, nil
+}
+
+// syncSKUs replaces the sku index rows of a product; a sku taken by another product
+// surfaces as domain.ErrAlreadyExists.
+func syncSKUs(ctx context.Context, tx pgx.Tx, p domain.Product) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM product_skus WHERE product_id = $1`, p.ID); err != nil {
+		return err
+	}
+	for _, sku := range p.SKUs() {
+		if _, err := tx.Exec(ctx, `
+INSERT INTO product_skus (project_id, sku, product_id)
+VALUES ($1, $2, $3)
+`, p.ProjectID, sku, p.ID); err != nil {
+			if db.IsUniqueViolation(err) {
+				return fmt.Errorf("%w: sku %s", domain.ErrAlreadyExists, sku)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// syncSlugs replaces the slug index rows of a product, one per locale and slug of
+// either projection; a slug taken by another product surfaces as domain.ErrAlreadyExists.
+func syncSlugs(ctx context.Context, tx pgx.Tx, p domain.Product) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM product_slugs WHERE product_id = $1`, p.ID); err != nil {
+		return err
+	}
+	seen := map[[2]string]struct{}{}
+	for _, data := range []domain.ProductData{p.Current, p.Staged} {
+		for locale, slug := range data.Slug {
+			if slug == "" {
+				continue
+			}
+			if _, ok := seen[[2]string{locale, slug}]; ok {
+				continue
+			}
+			seen[[2]string{locale, slug}] = struct{}{}
+			if _, err := tx.Exec(ctx, `
+INSERT INTO product_slugs (project_id, locale, slug, product_id)
+VALUES ($1, $2, $3, $4)
+`, p.ProjectID, locale, slug, p.ID); err != nil {
+				if db.IsUniqueViolation(err) {
+					return fmt.Errorf("%w: slug %s (%s)", domain.ErrAlreadyExists, slug, locale)
+				}
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func assignPriceIDs(data *domain.ProductData) error {
+	assign := func(v *domain.ProductVariant) error {
+		for i := range v.Prices {
+			if v.Prices[i].ID != "" {
+				continue
+			}
+			id, err := newUUID()
+			if err != nil {
+				return err
+			}
+			v.Prices[i].ID = id
+		}
+		return nil
+	}
+	if err := assign(&data.MasterVariant); err != nil {
+		return err
+	}
+	for i := range data.Variants {
+		if err := assign(&data.Variants[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
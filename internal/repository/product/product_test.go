@@ -2,6 +2,7 @@ package product
 
 import (
 	"context"
+	"errors"
 	"os"
 	"testing"
 
@@ -28,8 +29,8 @@ func TestPostgres_ListAndGet(t *testing.T) {
 
 	var pid string
 	err = pool.QueryRow(ctx, `
-		INSERT INTO products (project_id, key, sku, name, description, price_cents, currency, attributes)
-		VALUES ($1, 'p1', 'SKU1', 'Prod 1', 'desc', 100, 'USD', '{}'::jsonb)
+		INSERT INTO products (project_id, key, published, current_data, staged_data)
+		VALUES ($1, 'p1', true, '{"name": {"en": "Prod 1"}, "masterVariant": {"id": 1, "sku": "SKU1"}}'::jsonb, '{"name": {"en": "Prod 1"}, "masterVariant": {"id": 1, "sku": "SKU1"}}'::jsonb)
 		RETURNING id::text
 	`, projectID).Scan(&pid)
 	if err != nil {
@@ -50,7 +51,7 @@ func TestPostgres_ListAndGet(t *testing.T) {
 	if err != nil {
 		t.Fatalf("GetByID: %v", err)
 	}
-	if got.ID != pid || got.ProjectID != projectID {
+	if got.ID != pid || got.ProjectID != projectID || got.Current.Name["en"] != "Prod 1" || got.Current.MasterVariant.SKU != "SKU1" {
 		t.Fatalf("unexpected product %+v", got)
 	}
 }
@@ -74,12 +75,16 @@ func TestPostgres_Upsert(t *testing.T) {
 	repo := NewPostgres(pool, nil)
 
 	p, err := repo.Upsert(ctx, domain.Product{
-		ProjectID:  projectID,
-		Key:        "p1",
-		SKU:        "SKU1",
-		Name:       "Prod 1",
-		PriceCents: 100,
-		Currency:   "USD",
+		ProjectID: projectID,
+		Key:       "p1",
+		Current: domain.ProductData{
+			Name: domain.LocalizedString{"en": "Prod 1"},
+			MasterVariant: domain.ProductVariant{
+				ID:     1,
+				SKU:    "SKU1",
+				Prices: []domain.Price{{Value: domain.Money{CurrencyCode: "USD", CentAmount: 100}}},
+			},
+		},
 	})
 	if err != nil {
 		t.Fatalf("Upsert insert: %v", err)
@@ -89,14 +94,18 @@ func TestPostgres_Upsert(t *testing.T) {
 	}
 
 	updated, err := repo.Upsert(ctx, domain.Product{
-		ProjectID:   projectID,
-		Key:         "p1",
-		SKU:         "SKU-NEW",
-		Name:        "Prod 1 updated",
-		Description: "new desc",
-		PriceCents:  200,
-		Currency:    "USD",
-		Attributes:  map[string]interface{}{"images": []string{"https://example.com/1.jpg"}},
+		ProjectID: projectID,
+		Key:       "p1",
+		Current: domain.ProductData{
+			Name:        domain.LocalizedString{"en": "Prod 1 updated"},
+			Description: domain.LocalizedString{"en": "new desc"},
+			MasterVariant: domain.ProductVariant{
+				ID:     1,
+				SKU:    "SKU-NEW",
+				Prices: []domain.Price{{Value: domain.Money{CurrencyCode: "USD", CentAmount: 200}}},
+				Images: []string{"https://example.com/1.jpg"},
+			},
+		},
 	})
 	if err != nil {
 		t.Fatalf("Upsert update: %v", err)
@@ -104,7 +113,7 @@ func TestPostgres_Upsert(t *testing.T) {
 	if updated.ID != p.ID {
 		t.Fatalf("expected same ID after update")
 	}
-	if updated.SKU != "SKU-NEW" || updated.Description != "new desc" || updated.PriceCents != 200 {
+	if updated.Current.MasterVariant.SKU != "SKU-NEW" || updated.Staged.Description["en"] != "new desc" || updated.Current.MasterVariant.Prices[0].Value.CentAmount != 200 || updated.Version != 2 {
 		t.Fatalf("unexpected updated product %+v", updated)
 	}
 }
@@ -127,13 +136,17 @@ func TestPostgres_UpsertWithProvidedID(t *testing.T) {
 	repo := NewPostgres(pool, nil)
 	customID := "00000000-0000-0000-0000-000000000123"
 	p, err := repo.Upsert(ctx, domain.Product{
-		ID:         customID,
-		ProjectID:  projectID,
-		Key:        "p1",
-		SKU:        "SKU1",
-		Name:       "Prod 1",
-		PriceCents: 100,
-		Currency:   "USD",
+		ID:        customID,
+		ProjectID: projectID,
+		Key:       "p1",
+		Current: domain.ProductData{
+			Name: domain.LocalizedString{"en": "Prod 1"},
+			MasterVariant: domain.ProductVariant{
+				ID:     1,
+				SKU:    "SKU1",
+				Prices: []domain.Price{{Value: domain.Money{CurrencyCode: "USD", CentAmount: 100}}},
+			},
+		},
 	})
 	if err != nil {
 		t.Fatalf("Upsert insert with id: %v", err)
@@ -161,30 +174,158 @@ func TestPostgres_UpsertIDMismatch(t *testing.T) {
 	repo := NewPostgres(pool, nil)
 	// First insert without provided ID.
 	if _, err := repo.Upsert(ctx, domain.Product{
-		ProjectID:  projectID,
-		Key:        "p1",
-		SKU:        "SKU1",
-		Name:       "Prod 1",
-		PriceCents: 100,
-		Currency:   "USD",
+		ProjectID: projectID,
+		Key:       "p1",
+		Current: domain.ProductData{
+			Name: domain.LocalizedString{"en": "Prod 1"},
+			MasterVariant: domain.ProductVariant{
+				ID:     1,
+				SKU:    "SKU1",
+				Prices: []domain.Price{{Value: domain.Money{CurrencyCode: "USD", CentAmount: 100}}},
+			},
+		},
 	}); err != nil {
 		t.Fatalf("Upsert insert: %v", err)
 	}
 	// Second insert with conflicting ID should error.
 	_, err := repo.Upsert(ctx, domain.Product{
-		ID:         "00000000-0000-0000-0000-000000000321",
-		ProjectID:  projectID,
-		Key:        "p1",
-		SKU:        "SKU1",
-		Name:       "Prod 1",
-		PriceCents: 100,
-		Currency:   "USD",
+		ID:        "00000000-0000-0000-0000-000000000321",
+		ProjectID: projectID,
+		Key:       "p1",
+		Current: domain.ProductData{
+			Name: domain.LocalizedString{"en": "Prod 1"},
+			MasterVariant: domain.ProductVariant{
+				ID:     1,
+				SKU:    "SKU1",
+				Prices: []domain.Price{{Value: domain.Money{CurrencyCode: "USD", CentAmount: 100}}},
+			},
+		},
 	})
 	if err == nil {
 		t.Fatalf("expected id mismatch error, got nil")
 	}
 }
 
+func TestPostgres_CreateUpdateStagedData(t *testing.T) {
+	ctx := context.Background()
+	pool := testPool(ctx, t)
+	defer pool.Close()
+
+	if err := migrate.Apply(ctx, pool); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+	resetTables(ctx, t, pool)
+
+	var projectID string
+	if err := pool.QueryRow(ctx, `INSERT INTO projects (key, name) VALUES ('proj-key', 'Proj') RETURNING id::text`).Scan(&projectID); err != nil {
+		t.Fatalf("insert project: %v", err)
+	}
+
+	repo := NewPostgres(pool, nil)
+	data := domain.ProductData{
+		Name: domain.LocalizedString{"en": "Draft"},
+		MasterVariant: domain.ProductVariant{
+			ID:     1,
+			SKU:    "SKU-A",
+			Prices: []domain.Price{{Value: domain.Money{CurrencyCode: "EUR", CentAmount: 100}}},
+		},
+	}
+	created, err := repo.Create(ctx, domain.Product{ProjectID: projectID, Current: data, Staged: data})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.Version != 1 || created.Published || created.Key != "" || created.Current.MasterVariant.Prices[0].ID == "" {
+		t.Fatalf("unexpected created product %+v", created)
+	}
+
+	staged := *created
+	staged.Staged.Name = domain.LocalizedString{"en": "Staged"}
+	staged.Staged.Variants = []domain.ProductVariant{{ID: 2, SKU: "SKU-B"}}
+	staged.HasStagedChanges = true
+	updated, err := repo.Update(ctx, staged)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if updated.Version != 2 || updated.Current.Name["en"] != "Draft" || updated.Staged.Name["en"] != "Staged" || !updated.HasStagedChanges {
+		t.Fatalf("unexpected updated product %+v", updated)
+	}
+	if bySKU, err := repo.GetBySKU(ctx, projectID, "SKU-B"); err != nil || bySKU.ID != created.ID {
+		t.Fatalf("expected staged sku lookup, got %+v err=%v", bySKU, err)
+	}
+
+	// The stale version is rejected.
+	if _, err := repo.Update(ctx, staged); !errors.Is(err, domain.ErrConcurrentModification) {
+		t.Fatalf("expected concurrent modification, got %v", err)
+	}
+
+	// Another product cannot claim a used sku.
+	other := domain.ProductData{Name: domain.LocalizedString{"en": "Other"}, MasterVariant: domain.ProductVariant{ID: 1, SKU: "SKU-B"}}
+	if _, err := repo.Create(ctx, domain.Product{ProjectID: projectID, Current: other, Staged: other}); !errors.Is(err, domain.ErrAlreadyExists) {
+		t.Fatalf("expected duplicate sku error, got %v", err)
+	}
+}
+
+func TestPostgres_SlugLookupAndUniqueness(t *testing.T) {
+	ctx := context.Background()
+	pool := testPool(ctx, t)
+	defer pool.Close()
+
+	if err := migrate.Apply(ctx, pool); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+	resetTables(ctx, t, pool)
+
+	var projectID string
+	if err := pool.QueryRow(ctx, `INSERT INTO projects (key, name) VALUES ('proj-key', 'Proj') RETURNING id::text`).Scan(&projectID); err != nil {
+		t.Fatalf("insert project: %v", err)
+	}
+
+	repo := NewPostgres(pool, nil)
+	data := domain.ProductData{
+		Name:          domain.LocalizedString{"en": "Shirt"},
+		Slug:          domain.LocalizedString{"en": "shirt", "de-DE": "hemd"},
+		MasterVariant: domain.ProductVariant{ID: 1, SKU: "SKU-A"},
+	}
+	created, err := repo.Create(ctx, domain.Product{ProjectID: projectID, Key: "shirt", Current: data, Staged: data})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if got, err := repo.GetBySlug(ctx, projectID, "de-DE", "hemd"); err != nil || got.ID != created.ID {
+		t.Fatalf("expected slug lookup, got %+v err=%v", got, err)
+	}
+	if got, err := repo.GetByKey(ctx, projectID, "shirt"); err != nil || got.ID != created.ID {
+		t.Fatalf("expected key lookup, got %+v err=%v", got, err)
+	}
+
+	// The same slug in another locale is fine, the same locale is not.
+	other := domain.ProductData{
+		Name:          domain.LocalizedString{"en": "Other"},
+		Slug:          domain.LocalizedString{"en": "hemd"},
+		MasterVariant: domain.ProductVariant{ID: 1, SKU: "SKU-B"},
+	}
+	otherProduct, err := repo.Create(ctx, domain.Product{ProjectID: projectID, Current: other, Staged: other})
+	if err != nil {
+		t.Fatalf("Create other: %v", err)
+	}
+	otherProduct.Staged.Slug = domain.LocalizedString{"en": "shirt"}
+	if _, err := repo.Update(ctx, *otherProduct); !errors.Is(err, domain.ErrAlreadyExists) {
+		t.Fatalf("expected duplicate slug error, got %v", err)
+	}
+
+	// A renamed slug is released for other products.
+	created.Staged.Slug = domain.LocalizedString{"en": "shirt-new"}
+	created.Current.Slug = created.Staged.Slug
+	if _, err := repo.Update(ctx, *created); err != nil {
+		t.Fatalf("Update slug: %v", err)
+	}
+	if _, err := repo.GetBySlug(ctx, projectID, "en", "shirt"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected old slug to be released, got %v", err)
+	}
+	if _, err := repo.Update(ctx, *otherProduct); err != nil {
+		t.Fatalf("expected released slug to be claimable: %v", err)
+	}
+}
+
 func testPool(ctx context.Context, t *testing.T) *pgxpool.Pool {
 	t.Helper()
 	candidates := []string{
@@ -10,5 +10,12 @@ type Repository interface {
 	ListByProject(ctx context.Context, projectID string) ([]domain.Product, error)
 	GetByID(ctx context.Context, projectID, id string) (*domain.Product, error)
 	GetBySKU(ctx context.Context, projectID, sku string) (*domain.Product, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.Product, error)
+	// GetBySlug finds the product using slug for locale in its current or staged data.
+	GetBySlug(ctx context.Context, projectID, locale, slug string) (*domain.Product, error)
+	// Upsert writes imported data to both projections and publishes the product.
 	Upsert(ctx context.Context, product domain.Product) (*domain.Product, error)
+	Create(ctx context.Context, product domain.Product) (*domain.Product, error)
+	// Update stores product if product.Version still matches the stored version.
+	Update(ctx context.Context, product domain.Product) (*domain.Product, error)
 }
@@ -0,0 +1,151 @@
+package productdiscount
+
+import (
+	"context"
+	"errors"
+
+	"commercetools-replica/internal/db"
+	"commercetools-replica/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const existsQuery = `SELECT EXISTS (SELECT 1 FROM product_discounts WHERE project_id = $1 AND id = $2)`
+
+type postgresRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgres(pool *pgxpool.Pool) Repository {
+	return &postgresRepo{pool: pool}
+}
+
+const productDiscountColumns = `id::text, project_id::text, COALESCE(key, ''), version, name, description, value, predicate, sort_order, is_active, valid_from, valid_until, created_at, last_modified_at`
+
+func (r *postgresRepo) List(ctx context.Context, projectID string, limit, offset int) ([]domain.ProductDiscount, int, error) {
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM product_discounts WHERE project_id = $1`, projectID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	const q = `
+SELECT ` + productDiscountColumns + `
+FROM product_discounts
+WHERE project_id = $1
+ORDER BY sort_order::numeric DESC, id ASC
+LIMIT NULLIF($2::int, 0) OFFSET $3
+`
+	rows, err := r.pool.Query(ctx, q, projectID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var out []domain.ProductDiscount
+	for rows.Next() {
+		d, err := scanProductDiscount(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+func (r *postgresRepo) GetByID(ctx context.Context, projectID, id string) (*domain.ProductDiscount, error) {
+	const q = `
+SELECT ` + productDiscountColumns + `
+FROM product_discounts
+WHERE project_id = $1 AND id = $2
+`
+	return scanProductDiscount(r.pool.QueryRow(ctx, q, projectID, id))
+}
+
+func (r *postgresRepo) GetByKey(ctx context.Context, projectID, key string) (*domain.ProductDiscount, error) {
+	const q = `
+SELECT ` + productDiscountColumns + `
+FROM product_discounts
+WHERE project_id = $1 AND key = $2
+`
+	return scanProductDiscount(r.pool.QueryRow(ctx, q, projectID, key))
+}
+
+func (r *postgresRepo) Create(ctx context.Context, d domain.ProductDiscount) (*domain.ProductDiscount, error) {
+	const q = `
+INSERT INTO product_discounts (project_id, key, name, description, value, predicate, sort_order, is_active, valid_from, valid_until)
+VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING ` + productDiscountColumns + `
+`
+	out, err := scanProductDiscount(r.pool.QueryRow(ctx, q, d.ProjectID, d.Key, nonNilLocalized(d.Name), nonNilLocalized(d.Description), d.Value, d.Predicate, d.SortOrder, d.IsActive, d.ValidFrom, d.ValidUntil))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Update(ctx context.Context, d domain.ProductDiscount) (*domain.ProductDiscount, error) {
+	const q = `
+UPDATE product_discounts
+SET version = version + 1,
+    key = NULLIF($4, ''),
+    name = $5,
+    description = $6,
+    value = $7,
+    predicate = $8,
+    sort_order = $9,
+    is_active = $10,
+    valid_from = $11,
+    valid_until = $12,
+    last_modified_at = now()
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + productDiscountColumns + `
+`
+	out, err := scanProductDiscount(r.pool.QueryRow(ctx, q, d.ProjectID, d.ID, d.Version, d.Key, nonNilLocalized(d.Name), nonNilLocalized(d.Description), d.Value, d.Predicate, d.SortOrder, d.IsActive, d.ValidFrom, d.ValidUntil))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, db.MissingOrStale(ctx, r.pool, existsQuery, d.ProjectID, d.ID)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Delete(ctx context.Context, projectID, id string, version int) (*domain.ProductDiscount, error) {
+	const q = `
+DELETE FROM product_discounts
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + productDiscountColumns + `
+`
+	out, err := scanProductDiscount(r.pool.QueryRow(ctx, q, projectID, id, version))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, db.MissingOrStale(ctx, r.pool, existsQuery, projectID, id)
+	}
+	return out, err
+}
+
+func scanProductDiscount(row pgx.Row) (*domain.ProductDiscount, error) {
+	var d domain.ProductDiscount
+	err := row.Scan(&d.ID, &d.ProjectID, &d.Key, &d.Version, &d.Name, &d.Description, &d.Value, &d.Predicate, &d.SortOrder, &d.IsActive, &d.ValidFrom, &d.ValidUntil, &d.CreatedAt, &d.LastModifiedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &d, nil
+}
+
+func nonNilLocalized(s domain.LocalizedString) domain.LocalizedString {
+	if s == nil {
+		return domain.LocalizedString{}
+	}
+	return s
+}
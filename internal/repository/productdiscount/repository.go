@@ -0,0 +1,17 @@
+package productdiscount
+
+import (
+	"context"
+
+	"commercetools-replica/internal/domain"
+)
+
+type Repository interface {
+	List(ctx context.Context, projectID string, limit, offset int) ([]domain.ProductDiscount, int, error)
+	GetByID(ctx context.Context, projectID, id string) (*domain.ProductDiscount, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.ProductDiscount, error)
+	Create(ctx context.Context, d domain.ProductDiscount) (*domain.ProductDiscount, error)
+	// Update writes d if d.Version is still the stored version and bumps the version.
+	Update(ctx context.Context, d domain.ProductDiscount) (*domain.ProductDiscount, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.ProductDiscount, error)
+}
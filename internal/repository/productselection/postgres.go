@@ -0,0 +1,218 @@
+package productselection
+
+import (
+	"context"
+	"errors"
+
+	"commercetools-replica/internal/db"
+	"commercetools-replica/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const existsQuery = `SELECT EXISTS (SELECT 1 FROM product_selections WHERE project_id = $1 AND id = $2)`
+
+type postgresRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgres(pool *pgxpool.Pool) Repository {
+	return &postgresRepo{pool: pool}
+}
+
+const selectionColumns = `id::text, project_id::text, COALESCE(key, ''), version, name,
+    (SELECT COUNT(*) FROM product_selection_products p WHERE p.product_selection_id = product_selections.id),
+    created_at, last_modified_at`
+
+func (r *postgresRepo) List(ctx context.Context, projectID string, limit, offset int) ([]domain.ProductSelection, int, error) {
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM product_selections WHERE project_id = $1`, projectID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	const q = `
+SELECT ` + selectionColumns + `
+FROM product_selections
+WHERE project_id = $1
+ORDER BY created_at ASC, id ASC
+LIMIT NULLIF($2::int, 0) OFFSET $3
+`
+	rows, err := r.pool.Query(ctx, q, projectID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var out []domain.ProductSelection
+	for rows.Next() {
+		s, err := scanSelection(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+func (r *postgresRepo) GetByID(ctx context.Context, projectID, id string) (*domain.ProductSelection, error) {
+	const q = `
+SELECT ` + selectionColumns + `
+FROM product_selections
+WHERE project_id = $1 AND id = $2
+`
+	return scanSelection(r.pool.QueryRow(ctx, q, projectID, id))
+}
+
+func (r *postgresRepo) GetByKey(ctx context.Context, projectID, key string) (*domain.ProductSelection, error) {
+	const q = `
+SELECT ` + selectionColumns + `
+FROM product_selections
+WHERE project_id = $1 AND key = $2
+`
+	return scanSelection(r.pool.QueryRow(ctx, q, projectID, key))
+}
+
+func (r *postgresRepo) Create(ctx context.Context, s domain.ProductSelection) (*domain.ProductSelection, error) {
+	const q = `
+INSERT INTO product_selections (project_id, key, name)
+VALUES ($1, NULLIF($2, ''), $3)
+RETURNING ` + selectionColumns + `
+`
+	out, err := scanSelection(r.pool.QueryRow(ctx, q, s.ProjectID, s.Key, nonNilLocalized(s.Name)))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Update(ctx context.Context, s domain.ProductSelection, add, remove []string) (*domain.ProductSelection, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+UPDATE product_selections
+SET version = version + 1,
+    key = NULLIF($4, ''),
+    name = $5,
+    last_modified_at = now()
+WHERE project_id = $1 AND id = $2 AND version = $3
+`, s.ProjectID, s.ID, s.Version, s.Key, nonNilLocalized(s.Name))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, db.MissingOrStale(ctx, r.pool, existsQuery, s.ProjectID, s.ID)
+	}
+	if len(add) > 0 {
+		if _, err := tx.Exec(ctx, `
+INSERT INTO product_selection_products (product_selection_id, product_id)
+SELECT $1, id FROM products WHERE project_id = $2 AND id = ANY($3::uuid[])
+ON CONFLICT DO NOTHING
+`, s.ID, s.ProjectID, add); err != nil {
+			return nil, err
+		}
+	}
+	if len(remove) > 0 {
+		if _, err := tx.Exec(ctx, `
+DELETE FROM product_selection_products
+WHERE product_selection_id = $1 AND product_id = ANY($2::uuid[])
+`, s.ID, remove); err != nil {
+			return nil, err
+		}
+	}
+	out, err := scanSelection(tx.QueryRow(ctx, `
+SELECT `+selectionColumns+`
+FROM product_selections
+WHERE project_id = $1 AND id = $2
+`, s.ProjectID, s.ID))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Delete(ctx context.Context, projectID, id string, version int) (*domain.ProductSelection, error) {
+	const q = `
+DELETE FROM product_selections
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + selectionColumns + `
+`
+	out, err := scanSelection(r.pool.QueryRow(ctx, q, projectID, id, version))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, db.MissingOrStale(ctx, r.pool, existsQuery, projectID, id)
+	}
+	if db.IsForeignKeyViolation(err) {
+		return nil, domain.ErrReferenceExists
+	}
+	return out, err
+}
+
+func (r *postgresRepo) ListProducts(ctx context.Context, projectID, id string, limit, offset int) ([]string, int, error) {
+	var total int
+	if err := r.pool.QueryRow(ctx, `
+SELECT COUNT(*)
+FROM product_selection_products p
+JOIN product_selections s ON s.id = p.product_selection_id
+WHERE s.project_id = $1 AND s.id = $2
+`, projectID, id).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := r.pool.Query(ctx, `
+SELECT p.product_id::text
+FROM product_selection_products p
+JOIN product_selections s ON s.id = p.product_selection_id
+WHERE s.project_id = $1 AND s.id = $2
+ORDER BY p.created_at ASC, p.product_id ASC
+LIMIT NULLIF($3::int, 0) OFFSET $4
+`, projectID, id, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var productID string
+		if err := rows.Scan(&productID); err != nil {
+			return nil, 0, err
+		}
+		out = append(out, productID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+func scanSelection(row pgx.Row) (*domain.ProductSelection, error) {
+	var s domain.ProductSelection
+	err := row.Scan(&s.ID, &s.ProjectID, &s.Key, &s.Version, &s.Name, &s.ProductCount, &s.CreatedAt, &s.LastModifiedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func nonNilLocalized(s domain.LocalizedString) domain.LocalizedString {
+	if s == nil {
+		return domain.LocalizedString{}
+	}
+	return s
+}
@@ -0,0 +1,22 @@
+package productselection
+
+import (
+	"context"
+
+	"commercetools-replica/internal/domain"
+)
+
+type Repository interface {
+	List(ctx context.Context, projectID string, limit, offset int) ([]domain.ProductSelection, int, error)
+	GetByID(ctx context.Context, projectID, id string) (*domain.ProductSelection, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.ProductSelection, error)
+	Create(ctx context.Context, s domain.ProductSelection) (*domain.ProductSelection, error)
+	// Update writes s if s.Version is still the stored version, bumps the
+	// version and adds and removes the products by id in the same transaction.
+	Update(ctx context.Context, s domain.ProductSelection, add, remove []string) (*domain.ProductSelection, error)
+	// Delete fails with domain.ErrReferenceExists while a store uses the selection.
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.ProductSelection, error)
+	// ListProducts returns one page of the product ids of a selection in the
+	// order they were added.
+	ListProducts(ctx context.Context, projectID, id string, limit, offset int) ([]string, int, error)
+}
@@ -0,0 +1,102 @@
+package producttype
+
+import (
+	"context"
+	"errors"
+
+	"commercetools-replica/internal/db"
+	"commercetools-replica/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type postgresRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgres(pool *pgxpool.Pool) Repository {
+	return &postgresRepo{pool: pool}
+}
+
+const productTypeColumns = `id::text, project_id::text, COALESCE(key, ''), version, name, description, attributes, created_at, last_modified_at`
+
+func (r *postgresRepo) List(ctx context.Context, projectID string, limit, offset int) ([]domain.ProductType, int, error) {
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM product_types WHERE project_id = $1`, projectID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	const q = `
+SELECT ` + productTypeColumns + `
+FROM product_types
+WHERE project_id = $1
+ORDER BY name ASC, id ASC
+LIMIT NULLIF($2::int, 0) OFFSET $3
+`
+	rows, err := r.pool.Query(ctx, q, projectID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var out []domain.ProductType
+	for rows.Next() {
+		t, err := scanProductType(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+func (r *postgresRepo) GetByID(ctx context.Context, projectID, id string) (*domain.ProductType, error) {
+	const q = `
+SELECT ` + productTypeColumns + `
+FROM product_types
+WHERE project_id = $1 AND id = $2
+`
+	return scanProductType(r.pool.QueryRow(ctx, q, projectID, id))
+}
+
+func (r *postgresRepo) GetByKey(ctx context.Context, projectID, key string) (*domain.ProductType, error) {
+	const q = `
+SELECT ` + productTypeColumns + `
+FROM product_types
+WHERE project_id = $1 AND key = $2
+`
+	return scanProductType(r.pool.QueryRow(ctx, q, projectID, key))
+}
+
+func (r *postgresRepo) Create(ctx context.Context, t domain.ProductType) (*domain.ProductType, error) {
+	if t.Attributes == nil {
+		t.Attributes = []domain.AttributeDefinition{}
+	}
+	const q = `
+INSERT INTO product_types (project_id, key, name, description, attributes)
+VALUES ($1, NULLIF($2, ''), $3, $4, $5)
+RETURNING ` + productTypeColumns + `
+`
+	out, err := scanProductType(r.pool.QueryRow(ctx, q, t.ProjectID, t.Key, t.Name, t.Description, t.Attributes))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func scanProductType(row pgx.Row) (*domain.ProductType, error) {
+	var t domain.ProductType
+	err := row.Scan(&t.ID, &t.ProjectID, &t.Key, &t.Version, &t.Name, &t.Description, &t.Attributes, &t.CreatedAt, &t.LastModifiedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
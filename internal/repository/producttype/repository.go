@@ -0,0 +1,14 @@
+package producttype
+
+import (
+	"context"
+
+	"commercetools-replica/internal/domain"
+)
+
+type Repository interface {
+	List(ctx context.Context, projectID string, limit, offset int) ([]domain.ProductType, int, error)
+	GetByID(ctx context.Context, projectID, id string) (*domain.ProductType, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.ProductType, error)
+	Create(ctx context.Context, t domain.ProductType) (*domain.ProductType, error)
+}
@@ -23,14 +23,34 @@ func NewPostgres(pool *pgxpool.Pool, logger *log.Logger) Repository {
 	return &postgresRepo{pool: pool, logger: logger}
 }
 
-func (r *postgresRepo) GetByKey(ctx context.Context, key string) (*domain.Project, error) {
-	const q = `
-SELECT id::text, key, name, created_at
-FROM projects
-WHERE key = $1
-`
+const projectColumns = `id::text, key, name, require_email_verification,
+password_min_length, password_require_upper, password_require_lower,
+password_require_digit, password_require_symbol, password_denylist_file, created_at`
+
+func scanProject(row pgx.Row) (*domain.Project, error) {
 	var p domain.Project
-	err := r.pool.QueryRow(ctx, q, key).Scan(&p.ID, &p.Key, &p.Name, &p.CreatedAt)
+	err := row.Scan(
+		&p.ID,
+		&p.Key,
+		&p.Name,
+		&p.RequireEmailVerification,
+		&p.PasswordPolicy.MinLength,
+		&p.PasswordPolicy.RequireUpper,
+		&p.PasswordPolicy.RequireLower,
+		&p.PasswordPolicy.RequireDigit,
+		&p.PasswordPolicy.RequireSymbol,
+		&p.PasswordPolicy.DenylistFile,
+		&p.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *postgresRepo) GetByKey(ctx context.Context, key string) (*domain.Project, error) {
+	q := `SELECT ` + projectColumns + ` FROM projects WHERE key = $1`
+	p, err := scanProject(r.pool.QueryRow(ctx, q, key))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			r.logger.Printf("project repo: key=%s not found", key)
@@ -40,23 +60,33 @@ WHERE key = $1
 		return nil, err
 	}
 	r.logger.Printf("project repo: key=%s id=%s name=%s", p.Key, p.ID, p.Name)
-	return &p, nil
+	return p, nil
+}
+
+func (r *postgresRepo) GetByID(ctx context.Context, id string) (*domain.Project, error) {
+	q := `SELECT ` + projectColumns + ` FROM projects WHERE id = $1`
+	p, err := scanProject(r.pool.QueryRow(ctx, q, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		r.logger.Printf("project repo: get id=%s error=%v", id, err)
+		return nil, err
+	}
+	return p, nil
 }
 
 func (r *postgresRepo) Create(ctx context.Context, project *domain.Project) (*domain.Project, error) {
-	const q = `
-INSERT INTO projects (key, name)
-VALUES ($1, $2)
-RETURNING id::text, created_at
+	q := `
+INSERT INTO projects (key, name, require_email_verification)
+VALUES ($1, $2, $3)
+RETURNING ` + projectColumns + `
 `
-	var out domain.Project
-	err := r.pool.QueryRow(ctx, q, project.Key, project.Name).Scan(&out.ID, &out.CreatedAt)
+	out, err := scanProject(r.pool.QueryRow(ctx, q, project.Key, project.Name, project.RequireEmailVerification))
 	if err != nil {
 		r.logger.Printf("project repo: create key=%s error=%v", project.Key, err)
 		return nil, err
 	}
-	out.Key = project.Key
-	out.Name = project.Name
 	r.logger.Printf("project repo: created key=%s id=%s", out.Key, out.ID)
-	return &out, nil
+	return out, nil
 }
@@ -8,5 +8,6 @@ import (
 
 type Repository interface {
 	GetByKey(ctx context.Context, key string) (*domain.Project, error)
+	GetByID(ctx context.Context, id string) (*domain.Project, error)
 	Create(ctx context.Context, project *domain.Project) (*domain.Project, error)
 }
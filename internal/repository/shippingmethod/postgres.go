@@ -0,0 +1,151 @@
+package shippingmethod
+
+import (
+	"context"
+	"errors"
+
+	"commercetools-replica/internal/db"
+	"commercetools-replica/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const existsQuery = `SELECT EXISTS (SELECT 1 FROM shipping_methods WHERE project_id = $1 AND id = $2)`
+
+type postgresRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgres(pool *pgxpool.Pool) Repository {
+	return &postgresRepo{pool: pool}
+}
+
+const shippingMethodColumns = `id::text, project_id::text, COALESCE(key, ''), version, name, description, tax_category_id::text, zone_rates, is_default, predicate,
+    created_at, last_modified_at`
+
+func (r *postgresRepo) List(ctx context.Context, projectID string, limit, offset int) ([]domain.ShippingMethod, int, error) {
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM shipping_methods WHERE project_id = $1`, projectID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	const q = `
+SELECT ` + shippingMethodColumns + `
+FROM shipping_methods
+WHERE project_id = $1
+ORDER BY created_at ASC, id ASC
+LIMIT NULLIF($2::int, 0) OFFSET $3
+`
+	rows, err := r.pool.Query(ctx, q, projectID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var out []domain.ShippingMethod
+	for rows.Next() {
+		m, err := scanShippingMethod(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+func (r *postgresRepo) GetByID(ctx context.Context, projectID, id string) (*domain.ShippingMethod, error) {
+	const q = `
+SELECT ` + shippingMethodColumns + `
+FROM shipping_methods
+WHERE project_id = $1 AND id = $2
+`
+	return scanShippingMethod(r.pool.QueryRow(ctx, q, projectID, id))
+}
+
+func (r *postgresRepo) GetByKey(ctx context.Context, projectID, key string) (*domain.ShippingMethod, error) {
+	const q = `
+SELECT ` + shippingMethodColumns + `
+FROM shipping_methods
+WHERE project_id = $1 AND key = $2
+`
+	return scanShippingMethod(r.pool.QueryRow(ctx, q, projectID, key))
+}
+
+func (r *postgresRepo) Create(ctx context.Context, m domain.ShippingMethod) (*domain.ShippingMethod, error) {
+	const q = `
+INSERT INTO shipping_methods (project_id, key, name, description, tax_category_id, zone_rates, is_default, predicate)
+VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8)
+RETURNING ` + shippingMethodColumns + `
+`
+	out, err := scanShippingMethod(r.pool.QueryRow(ctx, q, m.ProjectID, m.Key, m.Name, m.Description, m.TaxCategoryID, nonNilZoneRates(m.ZoneRates), m.IsDefault, m.Predicate))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Update(ctx context.Context, m domain.ShippingMethod) (*domain.ShippingMethod, error) {
+	const q = `
+UPDATE shipping_methods
+SET version = version + 1,
+    key = NULLIF($4, ''),
+    name = $5,
+    description = $6,
+    tax_category_id = $7,
+    zone_rates = $8,
+    is_default = $9,
+    predicate = $10,
+    last_modified_at = now()
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + shippingMethodColumns + `
+`
+	out, err := scanShippingMethod(r.pool.QueryRow(ctx, q, m.ProjectID, m.ID, m.Version, m.Key, m.Name, m.Description, m.TaxCategoryID, nonNilZoneRates(m.ZoneRates), m.IsDefault, m.Predicate))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, db.MissingOrStale(ctx, r.pool, existsQuery, m.ProjectID, m.ID)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Delete(ctx context.Context, projectID, id string, version int) (*domain.ShippingMethod, error) {
+	const q = `
+DELETE FROM shipping_methods
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + shippingMethodColumns + `
+`
+	out, err := scanShippingMethod(r.pool.QueryRow(ctx, q, projectID, id, version))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, db.MissingOrStale(ctx, r.pool, existsQuery, projectID, id)
+	}
+	return out, err
+}
+
+func scanShippingMethod(row pgx.Row) (*domain.ShippingMethod, error) {
+	var m domain.ShippingMethod
+	err := row.Scan(&m.ID, &m.ProjectID, &m.Key, &m.Version, &m.Name, &m.Description, &m.TaxCategoryID, &m.ZoneRates, &m.IsDefault, &m.Predicate,
+		&m.CreatedAt, &m.LastModifiedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+func nonNilZoneRates(rates []domain.ZoneRate) []domain.ZoneRate {
+	if rates == nil {
+		return []domain.ZoneRate{}
+	}
+	return rates
+}
@@ -0,0 +1,19 @@
+package shippingmethod
+
+import (
+	"context"
+
+	"commercetools-replica/internal/domain"
+)
+
+type Repository interface {
+	List(ctx context.Context, projectID string, limit, offset int) ([]domain.ShippingMethod, int, error)
+	GetByID(ctx context.Context, projectID, id string) (*domain.ShippingMethod, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.ShippingMethod, error)
+	// Create and Update return domain.ErrAlreadyExists for a taken key or a
+	// second default shipping method.
+	Create(ctx context.Context, m domain.ShippingMethod) (*domain.ShippingMethod, error)
+	// Update writes m if m.Version is still the stored version and bumps the version.
+	Update(ctx context.Context, m domain.ShippingMethod) (*domain.ShippingMethod, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.ShippingMethod, error)
+}
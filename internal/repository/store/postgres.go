@@ -0,0 +1,263 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"commercetools-replica/internal/db"
+	"commercetools-replica/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const existsQuery = `SELECT EXISTS (SELECT 1 FROM stores WHERE project_id = $1 AND id = $2)`
+
+type postgresRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgres(pool *pgxpool.Pool) Repository {
+	return &postgresRepo{pool: pool}
+}
+
+// Channel links are told apart by role in store_channels.
+const (
+	roleDistribution = "distribution"
+	roleSupply       = "supply"
+)
+
+const storeColumns = `id::text, project_id::text, key, version, name, languages, countries,
+    ARRAY(SELECT c.channel_id::text FROM store_channels c WHERE c.store_id = stores.id AND c.role = 'distribution' ORDER BY c.position),
+    ARRAY(SELECT c.channel_id::text FROM store_channels c WHERE c.store_id = stores.id AND c.role = 'supply' ORDER BY c.position),
+    COALESCE((SELECT jsonb_agg(jsonb_build_object('productSelectionId', p.product_selection_id::text, 'active', p.active) ORDER BY p.position)
+        FROM store_product_selections p WHERE p.store_id = stores.id), '[]'::jsonb),
+    created_at, last_modified_at`
+
+func (r *postgresRepo) List(ctx context.Context, projectID string, limit, offset int) ([]domain.Store, int, error) {
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM stores WHERE project_id = $1`, projectID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	const q = `
+SELECT ` + storeColumns + `
+FROM stores
+WHERE project_id = $1
+ORDER BY created_at ASC, id ASC
+LIMIT NULLIF($2::int, 0) OFFSET $3
+`
+	rows, err := r.pool.Query(ctx, q, projectID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var out []domain.Store
+	for rows.Next() {
+		s, err := scanStore(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+func (r *postgresRepo) GetByID(ctx context.Context, projectID, id string) (*domain.Store, error) {
+	const q = `
+SELECT ` + storeColumns + `
+FROM stores
+WHERE project_id = $1 AND id = $2
+`
+	return scanStore(r.pool.QueryRow(ctx, q, projectID, id))
+}
+
+func (r *postgresRepo) GetByKey(ctx context.Context, projectID, key string) (*domain.Store, error) {
+	const q = `
+SELECT ` + storeColumns + `
+FROM stores
+WHERE project_id = $1 AND key = $2
+`
+	return scanStore(r.pool.QueryRow(ctx, q, projectID, key))
+}
+
+func (r *postgresRepo) Create(ctx context.Context, s domain.Store) (*domain.Store, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id string
+	err = tx.QueryRow(ctx, `
+INSERT INTO stores (project_id, key, name, languages, countries)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id::text
+`, s.ProjectID, s.Key, nonNilLocalized(s.Name), nonNil(s.Languages), nonNil(s.Countries)).Scan(&id)
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		return nil, err
+	}
+	return r.finish(ctx, tx, id, s)
+}
+
+func (r *postgresRepo) Update(ctx context.Context, s domain.Store) (*domain.Store, error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+UPDATE stores
+SET version = version + 1,
+    name = $4,
+    languages = $5,
+    countries = $6,
+    last_modified_at = now()
+WHERE project_id = $1 AND id = $2 AND version = $3
+`, s.ProjectID, s.ID, s.Version, nonNilLocalized(s.Name), nonNil(s.Languages), nonNil(s.Countries))
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, db.MissingOrStale(ctx, r.pool, existsQuery, s.ProjectID, s.ID)
+	}
+	return r.finish(ctx, tx, s.ID, s)
+}
+
+// finish replaces the channel and product selection links of the store,
+// commits and returns the stored store. Channels and product selections of
+// other projects are ErrNotFound.
+func (r *postgresRepo) finish(ctx context.Context, tx pgx.Tx, id string, s domain.Store) (*domain.Store, error) {
+	if _, err := tx.Exec(ctx, `DELETE FROM store_channels WHERE store_id = $1`, id); err != nil {
+		return nil, err
+	}
+	for role, ids := range map[string][]string{roleDistribution: s.DistributionChannelIDs, roleSupply: s.SupplyChannelIDs} {
+		tag, err := tx.Exec(ctx, `
+INSERT INTO store_channels (store_id, channel_id, role, position)
+SELECT $1, c.id, $2, t.position
+FROM unnest($3::text[]) WITH ORDINALITY AS t(channel_id, position)
+JOIN channels c ON c.id = t.channel_id::uuid AND c.project_id = $4
+`, id, role, nonNil(ids), s.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		if tag.RowsAffected() != int64(len(ids)) {
+			return nil, domain.ErrNotFound
+		}
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM store_product_selections WHERE store_id = $1`, id); err != nil {
+		return nil, err
+	}
+	selectionIDs := make([]string, 0, len(s.ProductSelections))
+	active := make([]bool, 0, len(s.ProductSelections))
+	for _, ps := range s.ProductSelections {
+		selectionIDs = append(selectionIDs, ps.ProductSelectionID)
+		active = append(active, ps.Active)
+	}
+	tag, err := tx.Exec(ctx, `
+INSERT INTO store_product_selections (store_id, product_selection_id, active, position)
+SELECT $1, ps.id, t.active, t.position
+FROM unnest($2::text[], $3::boolean[]) WITH ORDINALITY AS t(selection_id, active, position)
+JOIN product_selections ps ON ps.id = t.selection_id::uuid AND ps.project_id = $4
+`, id, selectionIDs, active, s.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() != int64(len(selectionIDs)) {
+		return nil, domain.ErrNotFound
+	}
+	out, err := scanStore(tx.QueryRow(ctx, `
+SELECT `+storeColumns+`
+FROM stores
+WHERE project_id = $1 AND id = $2
+`, s.ProjectID, id))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Delete(ctx context.Context, projectID, id string, version int) (*domain.Store, error) {
+	const q = `
+DELETE FROM stores
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + storeColumns + `
+`
+	out, err := scanStore(r.pool.QueryRow(ctx, q, projectID, id, version))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, db.MissingOrStale(ctx, r.pool, existsQuery, projectID, id)
+	}
+	return out, err
+}
+
+func (r *postgresRepo) ProductIDs(ctx context.Context, projectID, storeID string) (map[string]bool, error) {
+	var restricted bool
+	if err := r.pool.QueryRow(ctx, `
+SELECT EXISTS (
+    SELECT 1 FROM store_product_selections p JOIN stores s ON s.id = p.store_id
+    WHERE s.project_id = $1 AND s.id = $2
+)
+`, projectID, storeID).Scan(&restricted); err != nil {
+		return nil, err
+	}
+	if !restricted {
+		return nil, nil
+	}
+	rows, err := r.pool.Query(ctx, `
+SELECT DISTINCT pp.product_id::text
+FROM store_product_selections sp
+JOIN stores s ON s.id = sp.store_id
+JOIN product_selection_products pp ON pp.product_selection_id = sp.product_selection_id
+WHERE s.project_id = $1 AND sp.store_id = $2 AND sp.active
+`, projectID, storeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out[id] = true
+	}
+	return out, rows.Err()
+}
+
+func scanStore(row pgx.Row) (*domain.Store, error) {
+	var s domain.Store
+	err := row.Scan(&s.ID, &s.ProjectID, &s.Key, &s.Version, &s.Name, &s.Languages, &s.Countries,
+		&s.DistributionChannelIDs, &s.SupplyChannelIDs, &s.ProductSelections, &s.CreatedAt, &s.LastModifiedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func nonNilLocalized(s domain.LocalizedString) domain.LocalizedString {
+	if s == nil {
+		return domain.LocalizedString{}
+	}
+	return s
+}
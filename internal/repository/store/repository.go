@@ -0,0 +1,22 @@
+package store
+
+import (
+	"context"
+
+	"commercetools-replica/internal/domain"
+)
+
+type Repository interface {
+	List(ctx context.Context, projectID string, limit, offset int) ([]domain.Store, int, error)
+	GetByID(ctx context.Context, projectID, id string) (*domain.Store, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.Store, error)
+	// Create and Update store the channels and product selections with the
+	// store; they have to exist.
+	Create(ctx context.Context, s domain.Store) (*domain.Store, error)
+	// Update writes s if s.Version is still the stored version and bumps the version.
+	Update(ctx context.Context, s domain.Store) (*domain.Store, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.Store, error)
+	// ProductIDs returns the products of the store's active product
+	// selections, or nil if the store has no product selections at all.
+	ProductIDs(ctx context.Context, projectID, storeID string) (map[string]bool, error)
+}
@@ -0,0 +1,182 @@
+package taxcategory
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"commercetools-replica/internal/db"
+	"commercetools-replica/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const existsQuery = `SELECT EXISTS (SELECT 1 FROM tax_categories WHERE project_id = $1 AND id = $2)`
+
+type postgresRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgres(pool *pgxpool.Pool) Repository {
+	return &postgresRepo{pool: pool}
+}
+
+const taxCategoryColumns = `id::text, project_id::text, COALESCE(key, ''), version, name, description, rates, created_at, last_modified_at`
+
+func (r *postgresRepo) List(ctx context.Context, projectID string, limit, offset int) ([]domain.TaxCategory, int, error) {
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM tax_categories WHERE project_id = $1`, projectID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	const q = `
+SELECT ` + taxCategoryColumns + `
+FROM tax_categories
+WHERE project_id = $1
+ORDER BY created_at ASC, id ASC
+LIMIT NULLIF($2::int, 0) OFFSET $3
+`
+	rows, err := r.pool.Query(ctx, q, projectID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var out []domain.TaxCategory
+	for rows.Next() {
+		c, err := scanTaxCategory(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+func (r *postgresRepo) GetByID(ctx context.Context, projectID, id string) (*domain.TaxCategory, error) {
+	const q = `
+SELECT ` + taxCategoryColumns + `
+FROM tax_categories
+WHERE project_id = $1 AND id = $2
+`
+	return scanTaxCategory(r.pool.QueryRow(ctx, q, projectID, id))
+}
+
+func (r *postgresRepo) GetByKey(ctx context.Context, projectID, key string) (*domain.TaxCategory, error) {
+	const q = `
+SELECT ` + taxCategoryColumns + `
+FROM tax_categories
+WHERE project_id = $1 AND key = $2
+`
+	return scanTaxCategory(r.pool.QueryRow(ctx, q, projectID, key))
+}
+
+func (r *postgresRepo) Create(ctx context.Context, c domain.TaxCategory) (*domain.TaxCategory, error) {
+	if err := assignRateIDs(c.Rates); err != nil {
+		return nil, err
+	}
+	const q = `
+INSERT INTO tax_categories (project_id, key, name, description, rates)
+VALUES ($1, NULLIF($2, ''), $3, $4, $5)
+RETURNING ` + taxCategoryColumns + `
+`
+	out, err := scanTaxCategory(r.pool.QueryRow(ctx, q, c.ProjectID, c.Key, c.Name, c.Description, nonNilRates(c.Rates)))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Update(ctx context.Context, c domain.TaxCategory) (*domain.TaxCategory, error) {
+	if err := assignRateIDs(c.Rates); err != nil {
+		return nil, err
+	}
+	const q = `
+UPDATE tax_categories
+SET version = version + 1,
+    key = NULLIF($4, ''),
+    name = $5,
+    description = $6,
+    rates = $7,
+    last_modified_at = now()
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + taxCategoryColumns + `
+`
+	out, err := scanTaxCategory(r.pool.QueryRow(ctx, q, c.ProjectID, c.ID, c.Version, c.Key, c.Name, c.Description, nonNilRates(c.Rates)))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, db.MissingOrStale(ctx, r.pool, existsQuery, c.ProjectID, c.ID)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Delete(ctx context.Context, projectID, id string, version int) (*domain.TaxCategory, error) {
+	const q = `
+DELETE FROM tax_categories
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + taxCategoryColumns + `
+`
+	out, err := scanTaxCategory(r.pool.QueryRow(ctx, q, projectID, id, version))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, db.MissingOrStale(ctx, r.pool, existsQuery, projectID, id)
+	}
+	if db.IsForeignKeyViolation(err) {
+		// Shipping methods cannot lose their tax category.
+		return nil, domain.ErrReferenceExists
+	}
+	return out, err
+}
+
+func scanTaxCategory(row pgx.Row) (*domain.TaxCategory, error) {
+	var c domain.TaxCategory
+	err := row.Scan(&c.ID, &c.ProjectID, &c.Key, &c.Version, &c.Name, &c.Description, &c.Rates, &c.CreatedAt, &c.LastModifiedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func assignRateIDs(rates []domain.TaxRate) error {
+	for i := range rates {
+		if rates[i].ID != "" {
+			continue
+		}
+		id, err := newUUID()
+		if err != nil {
+			return err
+		}
+		rates[i].ID = id
+	}
+	return nil
+}
+
+func nonNilRates(rates []domain.TaxRate) []domain.TaxRate {
+	if rates == nil {
+		return []domain.TaxRate{}
+	}
+	return rates
+}
+
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
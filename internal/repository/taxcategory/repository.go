@@ -0,0 +1,18 @@
+package taxcategory
+
+import (
+	"context"
+
+	"commercetools-replica/internal/domain"
+)
+
+type Repository interface {
+	List(ctx context.Context, projectID string, limit, offset int) ([]domain.TaxCategory, int, error)
+	GetByID(ctx context.Context, projectID, id string) (*domain.TaxCategory, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.TaxCategory, error)
+	// Create and Update assign ids to rates that have none.
+	Create(ctx context.Context, c domain.TaxCategory) (*domain.TaxCategory, error)
+	// Update writes c if c.Version is still the stored version and bumps the version.
+	Update(ctx context.Context, c domain.TaxCategory) (*domain.TaxCategory, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.TaxCategory, error)
+}
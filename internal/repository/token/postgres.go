@@ -4,9 +4,9 @@ import (
 	"context"
 	"errors"
 
+	"commercetools-replica/internal/db"
 	"commercetools-replica/internal/domain"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -25,8 +25,7 @@ VALUES ($1, $2, $3, $4, $5, $6)
 `
 	_, err := r.pool.Exec(ctx, q, token.Token, token.ProjectID, token.CustomerID, token.AnonymousID, token.Kind, token.ExpiresAt)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		if db.IsUniqueViolation(err) {
 			return domain.ErrAlreadyExists
 		}
 		return err
@@ -34,17 +33,17 @@ VALUES ($1, $2, $3, $4, $5, $6)
 	return nil
 }
 
-func (r *postgresRepo) Get(ctx context.Context, token string) (*Token, error) {
+func (r *postgresRepo) Get(ctx context.Context, projectID, token string) (*Token, error) {
 	const q = `
 SELECT token, project_id::text, customer_id::text, anonymous_id, kind, expires_at, created_at
 FROM tokens
-WHERE token = $1
+WHERE project_id = $1 AND token = $2
 LIMIT 1
 `
 	var out Token
 	var customerID *string
 	var anonymousID *string
-	if err := r.pool.QueryRow(ctx, q, token).Scan(
+	if err := r.pool.QueryRow(ctx, q, projectID, token).Scan(
 		&out.Token,
 		&out.ProjectID,
 		&customerID,
@@ -63,8 +62,8 @@ LIMIT 1
 	return &out, nil
 }
 
-func (r *postgresRepo) Delete(ctx context.Context, token string) error {
-	cmd, err := r.pool.Exec(ctx, `DELETE FROM tokens WHERE token = $1`, token)
+func (r *postgresRepo) Delete(ctx context.Context, projectID, token string) error {
+	cmd, err := r.pool.Exec(ctx, `DELETE FROM tokens WHERE project_id = $1 AND token = $2`, projectID, token)
 	if err != nil {
 		return err
 	}
@@ -73,3 +72,36 @@ func (r *postgresRepo) Delete(ctx context.Context, token string) error {
 	}
 	return nil
 }
+
+func (r *postgresRepo) DeleteByCustomer(ctx context.Context, projectID, customerID, keep string) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM tokens WHERE project_id = $1 AND customer_id = $2 AND token <> $3`, projectID, customerID, keep)
+	return err
+}
+
+func (r *postgresRepo) DeleteByKind(ctx context.Context, projectID, customerID, kind string) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM tokens WHERE project_id = $1 AND customer_id = $2 AND kind = $3`, projectID, customerID, kind)
+	return err
+}
+
+func (r *postgresRepo) ListByCustomer(ctx context.Context, projectID, customerID string) ([]Token, error) {
+	const q = `
+SELECT token, project_id::text, customer_id::text, anonymous_id, kind, expires_at, created_at
+FROM tokens
+WHERE project_id = $1 AND customer_id = $2
+ORDER BY created_at, token
+`
+	rows, err := r.pool.Query(ctx, q, projectID, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Token
+	for rows.Next() {
+		var t Token
+		if err := rows.Scan(&t.Token, &t.ProjectID, &t.CustomerID, &t.AnonymousID, &t.Kind, &t.ExpiresAt, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
@@ -15,8 +15,16 @@ type Token struct {
 	CreatedAt   time.Time
 }
 
+// Repository only finds the tokens of projectID; a token of another project
+// is ErrNotFound.
 type Repository interface {
 	Create(ctx context.Context, token Token) error
-	Get(ctx context.Context, token string) (*Token, error)
-	Delete(ctx context.Context, token string) error
+	Get(ctx context.Context, projectID, token string) (*Token, error)
+	Delete(ctx context.Context, projectID, token string) error
+	// DeleteByCustomer revokes every token of the customer except keep.
+	DeleteByCustomer(ctx context.Context, projectID, customerID, keep string) error
+	// DeleteByKind revokes the tokens of one kind of the customer.
+	DeleteByKind(ctx context.Context, projectID, customerID, kind string) error
+	// ListByCustomer returns the tokens of the customer, oldest first.
+	ListByCustomer(ctx context.Context, projectID, customerID string) ([]Token, error)
 }
@@ -0,0 +1,146 @@
+package zone
+
+import (
+	"context"
+	"errors"
+
+	"commercetools-replica/internal/db"
+	"commercetools-replica/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const existsQuery = `SELECT EXISTS (SELECT 1 FROM zones WHERE project_id = $1 AND id = $2)`
+
+type postgresRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgres(pool *pgxpool.Pool) Repository {
+	return &postgresRepo{pool: pool}
+}
+
+const zoneColumns = `id::text, project_id::text, COALESCE(key, ''), version, name, description, locations, created_at, last_modified_at`
+
+func (r *postgresRepo) List(ctx context.Context, projectID string, limit, offset int) ([]domain.Zone, int, error) {
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM zones WHERE project_id = $1`, projectID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	const q = `
+SELECT ` + zoneColumns + `
+FROM zones
+WHERE project_id = $1
+ORDER BY created_at ASC, id ASC
+LIMIT NULLIF($2::int, 0) OFFSET $3
+`
+	rows, err := r.pool.Query(ctx, q, projectID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var out []domain.Zone
+	for rows.Next() {
+		z, err := scanZone(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, *z)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+func (r *postgresRepo) GetByID(ctx context.Context, projectID, id string) (*domain.Zone, error) {
+	const q = `
+SELECT ` + zoneColumns + `
+FROM zones
+WHERE project_id = $1 AND id = $2
+`
+	return scanZone(r.pool.QueryRow(ctx, q, projectID, id))
+}
+
+func (r *postgresRepo) GetByKey(ctx context.Context, projectID, key string) (*domain.Zone, error) {
+	const q = `
+SELECT ` + zoneColumns + `
+FROM zones
+WHERE project_id = $1 AND key = $2
+`
+	return scanZone(r.pool.QueryRow(ctx, q, projectID, key))
+}
+
+func (r *postgresRepo) Create(ctx context.Context, z domain.Zone) (*domain.Zone, error) {
+	const q = `
+INSERT INTO zones (project_id, key, name, description, locations)
+VALUES ($1, NULLIF($2, ''), $3, $4, $5)
+RETURNING ` + zoneColumns + `
+`
+	out, err := scanZone(r.pool.QueryRow(ctx, q, z.ProjectID, z.Key, z.Name, z.Description, nonNilLocations(z.Locations)))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Update(ctx context.Context, z domain.Zone) (*domain.Zone, error) {
+	const q = `
+UPDATE zones
+SET version = version + 1,
+    key = NULLIF($4, ''),
+    name = $5,
+    description = $6,
+    locations = $7,
+    last_modified_at = now()
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + zoneColumns + `
+`
+	out, err := scanZone(r.pool.QueryRow(ctx, q, z.ProjectID, z.ID, z.Version, z.Key, z.Name, z.Description, nonNilLocations(z.Locations)))
+	if err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, domain.ErrAlreadyExists
+		}
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, db.MissingOrStale(ctx, r.pool, existsQuery, z.ProjectID, z.ID)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *postgresRepo) Delete(ctx context.Context, projectID, id string, version int) (*domain.Zone, error) {
+	const q = `
+DELETE FROM zones
+WHERE project_id = $1 AND id = $2 AND version = $3
+RETURNING ` + zoneColumns + `
+`
+	out, err := scanZone(r.pool.QueryRow(ctx, q, projectID, id, version))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, db.MissingOrStale(ctx, r.pool, existsQuery, projectID, id)
+	}
+	return out, err
+}
+
+func scanZone(row pgx.Row) (*domain.Zone, error) {
+	var z domain.Zone
+	err := row.Scan(&z.ID, &z.ProjectID, &z.Key, &z.Version, &z.Name, &z.Description, &z.Locations, &z.CreatedAt, &z.LastModifiedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &z, nil
+}
+
+func nonNilLocations(locations []domain.Location) []domain.Location {
+	if locations == nil {
+		return []domain.Location{}
+	}
+	return locations
+}
@@ -0,0 +1,17 @@
+package zone
+
+import (
+	"context"
+
+	"commercetools-replica/internal/domain"
+)
+
+type Repository interface {
+	List(ctx context.Context, projectID string, limit, offset int) ([]domain.Zone, int, error)
+	GetByID(ctx context.Context, projectID, id string) (*domain.Zone, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.Zone, error)
+	Create(ctx context.Context, z domain.Zone) (*domain.Zone, error)
+	// Update writes z if z.Version is still the stored version and bumps the version.
+	Update(ctx context.Context, z domain.Zone) (*domain.Zone, error)
+	Delete(ctx context.Context, projectID, id string, version int) (*domain.Zone, error)
+}
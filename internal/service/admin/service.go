@@ -0,0 +1,110 @@
+package admin
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"commercetools-replica/internal/domain"
+	tokenrepo "commercetools-replica/internal/repository/token"
+)
+
+var (
+	// ErrInvalidClient is returned when the client credentials do not match.
+	ErrInvalidClient = errors.New("invalid client credentials")
+	// ErrInvalidToken is returned for missing, expired or non-admin tokens and
+	// tokens of another project.
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+// Scope is the scope of admin tokens, followed by ":<projectKey>".
+const Scope = "manage_customers"
+
+const tokenKind = "admin"
+
+// Service issues project-bound admin tokens to the one configured API client.
+type Service struct {
+	tokens       tokenrepo.Repository
+	clientID     string
+	clientSecret string
+	accessTTL    time.Duration
+}
+
+// New creates a Service. Without a client id or secret no token is ever
+// issued.
+func New(tokens tokenrepo.Repository, clientID, clientSecret string) *Service {
+	return &Service{
+		tokens:       tokens,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		accessTTL:    48 * time.Hour,
+	}
+}
+
+// Issue checks the client credentials and returns an admin token for the
+// project.
+func (s *Service) Issue(ctx context.Context, projectID, clientID, clientSecret string) (string, error) {
+	if s.clientID == "" || s.clientSecret == "" {
+		return "", ErrInvalidClient
+	}
+	idOK := subtle.ConstantTimeCompare([]byte(clientID), []byte(s.clientID)) == 1
+	secretOK := subtle.ConstantTimeCompare([]byte(clientSecret), []byte(s.clientSecret)) == 1
+	if !idOK || !secretOK {
+		return "", ErrInvalidClient
+	}
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		token, err := randomToken()
+		if err != nil {
+			return "", err
+		}
+		err = s.tokens.Create(ctx, tokenrepo.Token{
+			Token:     token,
+			ProjectID: projectID,
+			Kind:      tokenKind,
+			ExpiresAt: now.Add(s.accessTTL),
+			CreatedAt: now,
+		})
+		if err == nil {
+			return token, nil
+		}
+		if !errors.Is(err, domain.ErrAlreadyExists) {
+			return "", err
+		}
+	}
+	return "", errors.New("token collision")
+}
+
+// Authorize accepts valid admin tokens of the project.
+func (s *Service) Authorize(ctx context.Context, projectID, token string) error {
+	meta, err := s.tokens.Get(ctx, projectID, token)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+	if meta.Kind != tokenKind {
+		return ErrInvalidToken
+	}
+	if time.Now().After(meta.ExpiresAt) {
+		_ = s.tokens.Delete(ctx, projectID, token)
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+func (s *Service) AccessTTLSeconds() int {
+	return int(s.accessTTL.Seconds())
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
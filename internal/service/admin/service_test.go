@@ -0,0 +1,87 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"commercetools-replica/internal/domain"
+	tokenrepo "commercetools-replica/internal/repository/token"
+)
+
+type memoryTokenRepo struct {
+	tokens map[string]tokenrepo.Token
+}
+
+func (r *memoryTokenRepo) Create(_ context.Context, token tokenrepo.Token) error {
+	if _, exists := r.tokens[token.Token]; exists {
+		return domain.ErrAlreadyExists
+	}
+	r.tokens[token.Token] = token
+	return nil
+}
+
+func (r *memoryTokenRepo) Get(_ context.Context, projectID, token string) (*tokenrepo.Token, error) {
+	t, ok := r.tokens[token]
+	if !ok || t.ProjectID != projectID {
+		return nil, domain.ErrNotFound
+	}
+	return &t, nil
+}
+
+func (r *memoryTokenRepo) Delete(_ context.Context, _, token string) error {
+	delete(r.tokens, token)
+	return nil
+}
+
+func (r *memoryTokenRepo) DeleteByCustomer(context.Context, string, string, string) error { return nil }
+
+func (r *memoryTokenRepo) DeleteByKind(context.Context, string, string, string) error { return nil }
+
+func (r *memoryTokenRepo) ListByCustomer(context.Context, string, string) ([]tokenrepo.Token, error) {
+	return nil, nil
+}
+
+func TestIssueAndAuthorize(t *testing.T) {
+	ctx := context.Background()
+	tokens := &memoryTokenRepo{tokens: make(map[string]tokenrepo.Token)}
+	svc := New(tokens, "client", "secret")
+
+	if _, err := svc.Issue(ctx, "proj", "client", "wrong"); !errors.Is(err, ErrInvalidClient) {
+		t.Fatalf("expected ErrInvalidClient, got %v", err)
+	}
+	if _, err := New(tokens, "", "").Issue(ctx, "proj", "", ""); !errors.Is(err, ErrInvalidClient) {
+		t.Fatalf("expected an unconfigured client to be rejected, got %v", err)
+	}
+
+	token, err := svc.Issue(ctx, "proj", "client", "secret")
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	if err := svc.Authorize(ctx, "proj", token); err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	if err := svc.Authorize(ctx, "other", token); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected another project to be rejected, got %v", err)
+	}
+	if err := svc.Authorize(ctx, "proj", "missing"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a missing token to be rejected, got %v", err)
+	}
+
+	customerID := "cust"
+	tokens.tokens["customer-token"] = tokenrepo.Token{Token: "customer-token", ProjectID: "proj", CustomerID: &customerID, Kind: "access", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := svc.Authorize(ctx, "proj", "customer-token"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a customer token to be rejected, got %v", err)
+	}
+
+	expired := tokens.tokens[token]
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	tokens.tokens[token] = expired
+	if err := svc.Authorize(ctx, "proj", token); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected an expired token to be rejected, got %v", err)
+	}
+	if _, ok := tokens.tokens[token]; ok {
+		t.Fatalf("expected the expired token to be deleted")
+	}
+}
@@ -41,8 +41,8 @@ func (s *Service) Issue(ctx context.Context, projectID string) (accessToken, ref
 }
 
 func (s *Service) LookupByToken(ctx context.Context, projectID, token string) (string, error) {
-	meta, ok := s.tokens.Validate(ctx, token)
-	if !ok || meta.ProjectID != projectID {
+	meta, ok := s.tokens.Validate(ctx, projectID, token)
+	if !ok {
 		return "", ErrInvalidToken
 	}
 	return meta.AnonymousID, nil
@@ -54,8 +54,9 @@ func (m *tokenManager) Issue(ctx context.Context, projectID, anonymousID, kind s
 	return "", errors.New("token collision")
 }
 
-func (m *tokenManager) Validate(ctx context.Context, token string) (tokenMeta, bool) {
-	meta, err := m.repo.Get(ctx, token)
+// Validate accepts unexpired access tokens of the project.
+func (m *tokenManager) Validate(ctx context.Context, projectID, token string) (tokenMeta, bool) {
+	meta, err := m.repo.Get(ctx, projectID, token)
 	if err != nil {
 		return tokenMeta{}, false
 	}
@@ -63,7 +64,7 @@ func (m *tokenManager) Validate(ctx context.Context, token string) (tokenMeta, b
 		return tokenMeta{}, false
 	}
 	if time.Now().After(meta.ExpiresAt) {
-		_ = m.repo.Delete(ctx, token)
+		_ = m.repo.Delete(ctx, projectID, token)
 		return tokenMeta{}, false
 	}
 	return tokenMeta{
@@ -0,0 +1,181 @@
+package associaterole
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"commercetools-replica/internal/domain"
+	associaterolerepo "commercetools-replica/internal/repository/associaterole"
+	"commercetools-replica/internal/service/updateaction"
+)
+
+type Service struct {
+	repo associaterolerepo.Repository
+}
+
+func New(repo associaterolerepo.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// ListPage returns one page of associate roles, oldest first; limit 0 means no limit.
+func (s *Service) ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.AssociateRole, int, error) {
+	return s.repo.List(ctx, projectID, limit, offset)
+}
+
+func (s *Service) Get(ctx context.Context, projectID, id string) (*domain.AssociateRole, error) {
+	return s.repo.GetByID(ctx, projectID, id)
+}
+
+func (s *Service) GetByKey(ctx context.Context, projectID, key string) (*domain.AssociateRole, error) {
+	return s.repo.GetByKey(ctx, projectID, key)
+}
+
+type AssociateRoleDraft struct {
+	Key             string   `json:"key"`
+	Name            string   `json:"name,omitempty"`
+	BuyerAssignable *bool    `json:"buyerAssignable,omitempty"`
+	Permissions     []string `json:"permissions,omitempty"`
+}
+
+// Create stores a new role; roles are buyer assignable unless the draft says
+// otherwise.
+func (s *Service) Create(ctx context.Context, projectID string, draft AssociateRoleDraft) (*domain.AssociateRole, error) {
+	r := domain.AssociateRole{
+		ProjectID:       projectID,
+		Key:             strings.TrimSpace(draft.Key),
+		Name:            draft.Name,
+		BuyerAssignable: draft.BuyerAssignable == nil || *draft.BuyerAssignable,
+		Permissions:     draft.Permissions,
+	}
+	if err := validate(&r); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, r)
+}
+
+type UpdateInput struct {
+	Version int            `json:"version"`
+	Actions []UpdateAction `json:"actions"`
+}
+
+type UpdateAction = updateaction.Action
+
+// Update applies the actions to the stored role if in.Version is still current.
+func (s *Service) Update(ctx context.Context, projectID, id string, in UpdateInput) (*domain.AssociateRole, error) {
+	if len(in.Actions) == 0 {
+		return nil, errors.New("actions required")
+	}
+	if in.Version <= 0 {
+		return nil, errors.New("version required")
+	}
+	r, err := s.repo.GetByID(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if r.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	for _, action := range in.Actions {
+		if err := apply(r, action); err != nil {
+			return nil, err
+		}
+	}
+	if err := validate(r); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, *r)
+}
+
+// Delete removes the role; it fails with domain.ErrReferenceExists while an
+// associate has it.
+func (s *Service) Delete(ctx context.Context, projectID, id string, version int) (*domain.AssociateRole, error) {
+	if version <= 0 {
+		return nil, errors.New("version required")
+	}
+	return s.repo.Delete(ctx, projectID, id, version)
+}
+
+func apply(r *domain.AssociateRole, action UpdateAction) error {
+	switch strings.ToLower(strings.TrimSpace(action.Action)) {
+	case "setname":
+		var a struct {
+			Name string `json:"name"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		r.Name = a.Name
+	case "changebuyerassignable":
+		var a struct {
+			BuyerAssignable bool `json:"buyerAssignable"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		r.BuyerAssignable = a.BuyerAssignable
+	case "setpermissions":
+		var a struct {
+			Permissions []string `json:"permissions"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		r.Permissions = a.Permissions
+	case "addpermission", "removepermission":
+		var a struct {
+			Permission string `json:"permission"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		if strings.EqualFold(action.Action, "addPermission") {
+			r.Permissions = append(r.Permissions, a.Permission)
+			return nil
+		}
+		if !r.HasPermission(a.Permission) {
+			return fmt.Errorf("role %s lacks permission %s", r.Key, a.Permission)
+		}
+		kept := r.Permissions[:0:0]
+		for _, p := range r.Permissions {
+			if p != a.Permission {
+				kept = append(kept, p)
+			}
+		}
+		r.Permissions = kept
+	default:
+		return fmt.Errorf("unsupported action %q", action.Action)
+	}
+	return nil
+}
+
+var keyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{2,256}$`)
+
+// validate checks the role and drops duplicate permissions.
+func validate(r *domain.AssociateRole) error {
+	if !keyPattern.MatchString(r.Key) {
+		return errors.New("key must be 2 to 256 letters, digits, '-' or '_'")
+	}
+	permissions := []string{}
+	for _, p := range r.Permissions {
+		if !contains(domain.Permissions, p) {
+			return fmt.Errorf("unsupported permission %q", p)
+		}
+		if !contains(permissions, p) {
+			permissions = append(permissions, p)
+		}
+	}
+	r.Permissions = permissions
+	return nil
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package associaterole
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"commercetools-replica/internal/domain"
+	associaterolerepo "commercetools-replica/internal/repository/associaterole"
+)
+
+// roleRepo keeps roles by key, which the tests use as their id, and refuses
+// to delete the roles in assigned like the postgres repository.
+type roleRepo struct {
+	associaterolerepo.Repository
+	byKey    map[string]domain.AssociateRole
+	assigned map[string]bool
+}
+
+func newRoleRepo() *roleRepo {
+	return &roleRepo{byKey: map[string]domain.AssociateRole{}, assigned: map[string]bool{}}
+}
+
+func (r *roleRepo) GetByID(_ context.Context, _, id string) (*domain.AssociateRole, error) {
+	role, ok := r.byKey[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	role.Permissions = append([]string(nil), role.Permissions...)
+	return &role, nil
+}
+
+func (r *roleRepo) Create(_ context.Context, role domain.AssociateRole) (*domain.AssociateRole, error) {
+	role.ID, role.Version = role.Key, 1
+	r.byKey[role.Key] = role
+	return &role, nil
+}
+
+func (r *roleRepo) Update(_ context.Context, role domain.AssociateRole) (*domain.AssociateRole, error) {
+	role.Version++
+	r.byKey[role.ID] = role
+	return &role, nil
+}
+
+func (r *roleRepo) Delete(_ context.Context, _, id string, _ int) (*domain.AssociateRole, error) {
+	if r.assigned[id] {
+		return nil, domain.ErrReferenceExists
+	}
+	role, ok := r.byKey[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	delete(r.byKey, id)
+	return &role, nil
+}
+
+func TestServiceCreateBuyerAssignableByDefault(t *testing.T) {
+	svc := New(newRoleRepo())
+	ctx := context.Background()
+
+	buyer, err := svc.Create(ctx, "proj", AssociateRoleDraft{Key: " buyer ", Permissions: []string{"CreateMyCarts", "ViewMyCarts", "CreateMyCarts"}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if buyer.Key != "buyer" || !buyer.BuyerAssignable || !reflect.DeepEqual(buyer.Permissions, []string{domain.PermissionCreateMyCarts, domain.PermissionViewMyCarts}) {
+		t.Fatalf("expected a buyer assignable role without duplicate permissions, got %+v", buyer)
+	}
+	admin, err := svc.Create(ctx, "proj", AssociateRoleDraft{Key: "admin", BuyerAssignable: new(bool)})
+	if err != nil || admin.BuyerAssignable || len(admin.Permissions) != 0 {
+		t.Fatalf("expected an admin role only sellers assign, got %+v, %v", admin, err)
+	}
+
+	for _, tc := range []struct {
+		draft AssociateRoleDraft
+		want  string
+	}{
+		{AssociateRoleDraft{Key: "x"}, "key must be 2 to 256 letters, digits, '-' or '_'"},
+		{AssociateRoleDraft{Key: "buyer role"}, "key must be 2 to 256 letters, digits, '-' or '_'"},
+		// Permissions are commercetools names, case included.
+		{AssociateRoleDraft{Key: "viewer", Permissions: []string{"viewMyCarts"}}, `unsupported permission "viewMyCarts"`},
+	} {
+		if _, err := svc.Create(ctx, "proj", tc.draft); err == nil || err.Error() != tc.want {
+			t.Fatalf("expected %q, got %v", tc.want, err)
+		}
+	}
+}
+
+func TestServicePermissionActions(t *testing.T) {
+	repo := newRoleRepo()
+	svc := New(repo)
+	ctx := context.Background()
+	role, err := svc.Create(ctx, "proj", AssociateRoleDraft{Key: "buyer", Permissions: []string{domain.PermissionViewMyCarts}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	update := func(actions string) (*domain.AssociateRole, error) {
+		in := UpdateInput{Version: role.Version}
+		if err := json.Unmarshal([]byte(actions), &in.Actions); err != nil {
+			t.Fatalf("unmarshal %s: %v", actions, err)
+		}
+		return svc.Update(ctx, "proj", role.ID, in)
+	}
+
+	role, err = update(`[
+		{"action":"addPermission","permission":"CreateMyCarts"},
+		{"action":"addPermission","permission":"CreateMyCarts"},
+		{"action":"removePermission","permission":"ViewMyCarts"},
+		{"action":"changeBuyerAssignable","buyerAssignable":false}
+	]`)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if !reflect.DeepEqual(role.Permissions, []string{domain.PermissionCreateMyCarts}) || role.BuyerAssignable {
+		t.Fatalf("expected only CreateMyCarts on a role buyers cannot assign, got %+v", role)
+	}
+
+	for _, tc := range []struct {
+		actions string
+		want    string
+	}{
+		{`[{"action":"removePermission","permission":"ViewMyCarts"}]`, "role buyer lacks permission ViewMyCarts"},
+		{`[{"action":"setPermissions","permissions":["ViewMyOrders","DeleteEverything"]}]`, `unsupported permission "DeleteEverything"`},
+	} {
+		if _, err := update(tc.actions); err == nil || err.Error() != tc.want {
+			t.Fatalf("%s: expected %q, got %v", tc.actions, tc.want, err)
+		}
+	}
+	if stored := repo.byKey["buyer"]; stored.Version != 2 || len(stored.Permissions) != 1 {
+		t.Fatalf("expected failed updates not to be stored, got %+v", stored)
+	}
+
+	role, err = update(`[{"action":"setPermissions","permissions":["ViewMyOrders","ViewOthersOrders","ViewMyOrders"]}]`)
+	if err != nil || !reflect.DeepEqual(role.Permissions, []string{domain.PermissionViewMyOrders, domain.PermissionViewOthersOrders}) {
+		t.Fatalf("expected the order permissions once each, got %+v, %v", role, err)
+	}
+}
+
+func TestServiceDeleteAssignedRole(t *testing.T) {
+	repo := newRoleRepo()
+	svc := New(repo)
+	ctx := context.Background()
+	role, err := svc.Create(ctx, "proj", AssociateRoleDraft{Key: "buyer"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	repo.assigned["buyer"] = true
+	if _, err := svc.Delete(ctx, "proj", role.ID, role.Version); !errors.Is(err, domain.ErrReferenceExists) {
+		t.Fatalf("expected an assigned role to be kept, got %v", err)
+	}
+	repo.assigned["buyer"] = false
+	if _, err := svc.Delete(ctx, "proj", role.ID, role.Version); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := svc.Get(ctx, "proj", role.ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected the role to be gone, got %v", err)
+	}
+}
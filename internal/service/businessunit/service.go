@@ -0,0 +1,601 @@
+package businessunit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"commercetools-replica/internal/domain"
+	businessunitrepo "commercetools-replica/internal/repository/businessunit"
+	"commercetools-replica/internal/service/updateaction"
+)
+
+var (
+	// ErrNotAssociate is returned when a customer has no role in a business
+	// unit, neither explicit nor inherited.
+	ErrNotAssociate = errors.New("customer is not an associate of the business unit")
+	// ErrPermissionDenied is returned when none of an associate's roles grants
+	// the permission.
+	ErrPermissionDenied = errors.New("associate lacks the permission")
+)
+
+type Service struct {
+	repo      businessunitrepo.Repository
+	roles     roleLookup
+	customers customerLookup
+}
+
+// roleLookup resolves the associate roles of the associates; see the
+// associaterole service.
+type roleLookup interface {
+	GetByID(ctx context.Context, projectID, id string) (*domain.AssociateRole, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.AssociateRole, error)
+}
+
+// customerLookup checks the customers made associates.
+type customerLookup interface {
+	GetByID(ctx context.Context, projectID, id string) (*domain.Customer, error)
+}
+
+func New(repo businessunitrepo.Repository, roles roleLookup, customers customerLookup) *Service {
+	return &Service{repo: repo, roles: roles, customers: customers}
+}
+
+// ListPage returns one page of business units, oldest first; limit 0 means no limit.
+func (s *Service) ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.BusinessUnit, int, error) {
+	return s.repo.List(ctx, projectID, limit, offset)
+}
+
+func (s *Service) Get(ctx context.Context, projectID, id string) (*domain.BusinessUnit, error) {
+	return s.repo.GetByID(ctx, projectID, id)
+}
+
+func (s *Service) GetByKey(ctx context.Context, projectID, key string) (*domain.BusinessUnit, error) {
+	return s.repo.GetByKey(ctx, projectID, key)
+}
+
+type ResourceIdentifier struct {
+	TypeID string `json:"typeId,omitempty"`
+	ID     string `json:"id,omitempty"`
+	Key    string `json:"key,omitempty"`
+}
+
+type AssociateRoleAssignmentDraft struct {
+	AssociateRole ResourceIdentifier `json:"associateRole"`
+	// Inheritance is Enabled (the default) or Disabled.
+	Inheritance string `json:"inheritance,omitempty"`
+}
+
+type AssociateDraft struct {
+	Customer                 ResourceIdentifier             `json:"customer"`
+	AssociateRoleAssignments []AssociateRoleAssignmentDraft `json:"associateRoleAssignments"`
+}
+
+type BusinessUnitDraft struct {
+	Key          string `json:"key"`
+	UnitType     string `json:"unitType"`
+	Name         string `json:"name"`
+	ContactEmail string `json:"contactEmail,omitempty"`
+	Status       string `json:"status,omitempty"`
+	// AssociateMode defaults to Explicit for companies and to
+	// ExplicitAndFromParent for divisions.
+	AssociateMode          string                   `json:"associateMode,omitempty"`
+	Addresses              []domain.CustomerAddress `json:"addresses,omitempty"`
+	DefaultShippingAddress *int                     `json:"defaultShippingAddress,omitempty"`
+	DefaultBillingAddress  *int                     `json:"defaultBillingAddress,omitempty"`
+	// ParentUnit is required for divisions and not allowed for companies.
+	ParentUnit *ResourceIdentifier `json:"parentUnit,omitempty"`
+	Associates []AssociateDraft    `json:"associates,omitempty"`
+}
+
+func (s *Service) Create(ctx context.Context, projectID string, draft BusinessUnitDraft) (*domain.BusinessUnit, error) {
+	u := domain.BusinessUnit{
+		ProjectID:     projectID,
+		Key:           strings.TrimSpace(draft.Key),
+		UnitType:      draft.UnitType,
+		Name:          draft.Name,
+		ContactEmail:  strings.TrimSpace(draft.ContactEmail),
+		Status:        defaultString(draft.Status, domain.BusinessUnitActive),
+		AssociateMode: draft.AssociateMode,
+	}
+	if u.AssociateMode == "" {
+		u.AssociateMode = domain.AssociateModeExplicit
+		if u.UnitType == domain.BusinessUnitDivision {
+			u.AssociateMode = domain.AssociateModeExplicitAndFromParent
+		}
+	}
+	for _, a := range draft.Addresses {
+		a.ID = randomAddressID()
+		u.Addresses = append(u.Addresses, a)
+	}
+	u.DefaultShippingAddressID = addressIDFromIndex(u.Addresses, draft.DefaultShippingAddress)
+	u.DefaultBillingAddressID = addressIDFromIndex(u.Addresses, draft.DefaultBillingAddress)
+	if draft.ParentUnit != nil {
+		parent, err := s.resolveUnit(ctx, projectID, *draft.ParentUnit)
+		if err != nil {
+			return nil, err
+		}
+		u.ParentUnitID = parent.ID
+	}
+	for _, a := range draft.Associates {
+		associate, err := s.resolveAssociate(ctx, projectID, a)
+		if err != nil {
+			return nil, err
+		}
+		u.Associates = append(u.Associates, associate)
+	}
+	if err := validate(&u); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, u)
+}
+
+type UpdateInput struct {
+	Version int            `json:"version"`
+	Actions []UpdateAction `json:"actions"`
+}
+
+type UpdateAction = updateaction.Action
+
+// Update applies the actions to the stored unit if in.Version is still current.
+func (s *Service) Update(ctx context.Context, projectID, id string, in UpdateInput) (*domain.BusinessUnit, error) {
+	if len(in.Actions) == 0 {
+		return nil, errors.New("actions required")
+	}
+	if in.Version <= 0 {
+		return nil, errors.New("version required")
+	}
+	u, err := s.repo.GetByID(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if u.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	for _, action := range in.Actions {
+		if err := s.apply(ctx, u, action); err != nil {
+			return nil, err
+		}
+	}
+	if err := validate(u); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, *u)
+}
+
+// Delete removes the unit; it fails with domain.ErrReferenceExists while the
+// unit has divisions. Its carts stay without a business unit.
+func (s *Service) Delete(ctx context.Context, projectID, id string, version int) (*domain.BusinessUnit, error) {
+	if version <= 0 {
+		return nil, errors.New("version required")
+	}
+	return s.repo.Delete(ctx, projectID, id, version)
+}
+
+// addressSelector picks an address of the unit by id or key.
+type addressSelector struct {
+	AddressID  string `json:"addressId"`
+	AddressKey string `json:"addressKey"`
+}
+
+func (a addressSelector) set() bool {
+	return a.AddressID != "" || a.AddressKey != ""
+}
+
+func (a addressSelector) find(u *domain.BusinessUnit) (int, error) {
+	if !a.set() {
+		return 0, errors.New("addressId or addressKey required")
+	}
+	for i, addr := range u.Addresses {
+		if (a.AddressID != "" && addr.ID == a.AddressID) || (a.AddressID == "" && addr.Key == a.AddressKey) {
+			return i, nil
+		}
+	}
+	return 0, errors.New("address not found")
+}
+
+func (s *Service) apply(ctx context.Context, u *domain.BusinessUnit, action UpdateAction) error {
+	name := strings.ToLower(strings.TrimSpace(action.Action))
+	switch name {
+	case "changename":
+		var a struct {
+			Name string `json:"name"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		u.Name = a.Name
+	case "setcontactemail":
+		var a struct {
+			ContactEmail string `json:"contactEmail"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		u.ContactEmail = strings.TrimSpace(a.ContactEmail)
+	case "changestatus":
+		var a struct {
+			Status string `json:"status"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		u.Status = a.Status
+	case "changeassociatemode":
+		var a struct {
+			AssociateMode string `json:"associateMode"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		u.AssociateMode = a.AssociateMode
+	case "addaddress":
+		var a struct {
+			Address domain.CustomerAddress `json:"address"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		a.Address.ID = randomAddressID()
+		u.Addresses = append(u.Addresses, a.Address)
+	case "changeaddress":
+		var a struct {
+			addressSelector
+			Address domain.CustomerAddress `json:"address"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		i, err := a.find(u)
+		if err != nil {
+			return err
+		}
+		a.Address.ID = u.Addresses[i].ID
+		u.Addresses[i] = a.Address
+	case "removeaddress":
+		var a addressSelector
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		i, err := a.find(u)
+		if err != nil {
+			return err
+		}
+		id := u.Addresses[i].ID
+		u.Addresses = append(u.Addresses[:i:i], u.Addresses[i+1:]...)
+		if u.DefaultShippingAddressID == id {
+			u.DefaultShippingAddressID = ""
+		}
+		if u.DefaultBillingAddressID == id {
+			u.DefaultBillingAddressID = ""
+		}
+	case "setdefaultshippingaddress", "setdefaultbillingaddress":
+		var a addressSelector
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		// Without an address the default is unset.
+		id := ""
+		if a.set() {
+			i, err := a.find(u)
+			if err != nil {
+				return err
+			}
+			id = u.Addresses[i].ID
+		}
+		if name == "setdefaultshippingaddress" {
+			u.DefaultShippingAddressID = id
+		} else {
+			u.DefaultBillingAddressID = id
+		}
+	case "addassociate", "changeassociate":
+		var a struct {
+			Associate AssociateDraft `json:"associate"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		associate, err := s.resolveAssociate(ctx, u.ProjectID, a.Associate)
+		if err != nil {
+			return err
+		}
+		i := associateIndex(u.Associates, associate.CustomerID)
+		switch {
+		case name == "addassociate":
+			u.Associates = append(u.Associates, associate)
+		case i < 0:
+			return fmt.Errorf("customer %s is not an associate", associate.CustomerID)
+		default:
+			u.Associates[i] = associate
+		}
+	case "removeassociate":
+		var a struct {
+			Customer ResourceIdentifier `json:"customer"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		i := associateIndex(u.Associates, strings.TrimSpace(a.Customer.ID))
+		if i < 0 {
+			return fmt.Errorf("customer %s is not an associate", a.Customer.ID)
+		}
+		u.Associates = append(u.Associates[:i:i], u.Associates[i+1:]...)
+	case "setassociates":
+		var a struct {
+			Associates []AssociateDraft `json:"associates"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		u.Associates = nil
+		for _, draft := range a.Associates {
+			associate, err := s.resolveAssociate(ctx, u.ProjectID, draft)
+			if err != nil {
+				return err
+			}
+			u.Associates = append(u.Associates, associate)
+		}
+	case "changeparentunit":
+		var a struct {
+			ParentUnit ResourceIdentifier `json:"parentUnit"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		return s.changeParent(ctx, u, a.ParentUnit)
+	default:
+		return fmt.Errorf("unsupported action %q", action.Action)
+	}
+	return nil
+}
+
+// changeParent moves a division to another unit of the same company.
+func (s *Service) changeParent(ctx context.Context, u *domain.BusinessUnit, ref ResourceIdentifier) error {
+	if u.UnitType != domain.BusinessUnitDivision {
+		return errors.New("only divisions have a parent unit")
+	}
+	parent, err := s.resolveUnit(ctx, u.ProjectID, ref)
+	if err != nil {
+		return err
+	}
+	if parent.TopLevelUnitKey != u.TopLevelUnitKey {
+		return fmt.Errorf("business unit %s belongs to another company", parent.Key)
+	}
+	for p := parent; ; {
+		if p.ID == u.ID {
+			return fmt.Errorf("business unit %s is a division of %s", parent.Key, u.Key)
+		}
+		if p.ParentUnitID == "" {
+			break
+		}
+		if p, err = s.repo.GetByID(ctx, u.ProjectID, p.ParentUnitID); err != nil {
+			return err
+		}
+	}
+	u.ParentUnitID = parent.ID
+	u.ParentUnitKey = parent.Key
+	return nil
+}
+
+// Permissions returns the permissions the customer has in the unit: those
+// of its roles in the unit and, while units take associates from their
+// parent, those of its inherited roles in the parent units.
+func (s *Service) Permissions(ctx context.Context, u *domain.BusinessUnit, customerID string) (map[string]bool, error) {
+	if s.roles == nil {
+		return nil, errors.New("associate role lookup unavailable")
+	}
+	permissions := map[string]bool{}
+	associate := false
+	for unit, inherited := u, false; ; inherited = true {
+		if a, ok := unit.Associate(customerID); ok {
+			for _, ra := range a.RoleAssignments {
+				if inherited && ra.Inheritance != domain.InheritanceEnabled {
+					continue
+				}
+				role, err := s.roles.GetByKey(ctx, unit.ProjectID, ra.RoleKey)
+				if err != nil {
+					return nil, err
+				}
+				associate = true
+				for _, p := range role.Permissions {
+					permissions[p] = true
+				}
+			}
+		}
+		if unit.AssociateMode != domain.AssociateModeExplicitAndFromParent || unit.ParentUnitID == "" {
+			break
+		}
+		parent, err := s.repo.GetByID(ctx, unit.ProjectID, unit.ParentUnitID)
+		if err != nil {
+			return nil, err
+		}
+		unit = parent
+	}
+	if !associate {
+		return nil, ErrNotAssociate
+	}
+	return permissions, nil
+}
+
+// Authorize fails with ErrNotAssociate or ErrPermissionDenied unless the
+// customer has permission in the unit.
+func (s *Service) Authorize(ctx context.Context, u *domain.BusinessUnit, customerID, permission string) error {
+	permissions, err := s.Permissions(ctx, u, customerID)
+	if err != nil {
+		return err
+	}
+	if !permissions[permission] {
+		return fmt.Errorf("%w %s", ErrPermissionDenied, permission)
+	}
+	return nil
+}
+
+func (s *Service) resolveUnit(ctx context.Context, projectID string, ref ResourceIdentifier) (*domain.BusinessUnit, error) {
+	id := strings.TrimSpace(ref.ID)
+	key := strings.TrimSpace(ref.Key)
+	if id == "" && key == "" {
+		return nil, errors.New("parent unit id or key required")
+	}
+	var (
+		u   *domain.BusinessUnit
+		err error
+	)
+	if id != "" {
+		u, err = s.repo.GetByID(ctx, projectID, id)
+	} else {
+		u, err = s.repo.GetByKey(ctx, projectID, key)
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, errors.New("parent unit not found")
+		}
+		return nil, err
+	}
+	return u, nil
+}
+
+// resolveAssociate checks the customer and turns the role references into
+// role keys.
+func (s *Service) resolveAssociate(ctx context.Context, projectID string, draft AssociateDraft) (domain.Associate, error) {
+	a := domain.Associate{CustomerID: strings.TrimSpace(draft.Customer.ID)}
+	if a.CustomerID == "" {
+		return a, errors.New("associate customer id required")
+	}
+	if s.customers != nil {
+		if _, err := s.customers.GetByID(ctx, projectID, a.CustomerID); err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				return a, fmt.Errorf("customer %s not found", a.CustomerID)
+			}
+			return a, err
+		}
+	}
+	for _, ra := range draft.AssociateRoleAssignments {
+		role, err := s.resolveRole(ctx, projectID, ra.AssociateRole)
+		if err != nil {
+			return a, err
+		}
+		a.RoleAssignments = append(a.RoleAssignments, domain.AssociateRoleAssignment{
+			RoleKey:     role.Key,
+			Inheritance: defaultString(ra.Inheritance, domain.InheritanceEnabled),
+		})
+	}
+	return a, nil
+}
+
+func (s *Service) resolveRole(ctx context.Context, projectID string, ref ResourceIdentifier) (*domain.AssociateRole, error) {
+	id := strings.TrimSpace(ref.ID)
+	key := strings.TrimSpace(ref.Key)
+	if id == "" && key == "" {
+		return nil, errors.New("associate role id or key required")
+	}
+	if s.roles == nil {
+		return nil, errors.New("associate role lookup unavailable")
+	}
+	var (
+		r   *domain.AssociateRole
+		err error
+	)
+	if id != "" {
+		r, err = s.roles.GetByID(ctx, projectID, id)
+	} else {
+		r, err = s.roles.GetByKey(ctx, projectID, key)
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, errors.New("associate role not found")
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+var keyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{2,256}$`)
+
+// validate checks the unit, its tree position and its associates.
+func validate(u *domain.BusinessUnit) error {
+	if !keyPattern.MatchString(u.Key) {
+		return errors.New("key must be 2 to 256 letters, digits, '-' or '_'")
+	}
+	if strings.TrimSpace(u.Name) == "" {
+		return errors.New("name required")
+	}
+	switch u.UnitType {
+	case domain.BusinessUnitCompany:
+		if u.ParentUnitID != "" {
+			return errors.New("a company has no parent unit")
+		}
+		if u.AssociateMode != domain.AssociateModeExplicit {
+			return errors.New("a company's associateMode must be Explicit")
+		}
+	case domain.BusinessUnitDivision:
+		if u.ParentUnitID == "" {
+			return errors.New("a division requires a parent unit")
+		}
+	default:
+		return fmt.Errorf("unsupported unitType %q", u.UnitType)
+	}
+	switch u.Status {
+	case domain.BusinessUnitActive, domain.BusinessUnitInactive:
+	default:
+		return fmt.Errorf("unsupported status %q", u.Status)
+	}
+	switch u.AssociateMode {
+	case domain.AssociateModeExplicit, domain.AssociateModeExplicitAndFromParent:
+	default:
+		return fmt.Errorf("unsupported associateMode %q", u.AssociateMode)
+	}
+	for i, a := range u.Associates {
+		if associateIndex(u.Associates[:i], a.CustomerID) >= 0 {
+			return fmt.Errorf("duplicate associate %s", a.CustomerID)
+		}
+		if len(a.RoleAssignments) == 0 {
+			return fmt.Errorf("associate %s requires a role", a.CustomerID)
+		}
+		for j, ra := range a.RoleAssignments {
+			switch ra.Inheritance {
+			case domain.InheritanceEnabled, domain.InheritanceDisabled:
+			default:
+				return fmt.Errorf("unsupported inheritance %q", ra.Inheritance)
+			}
+			for _, prev := range a.RoleAssignments[:j] {
+				if prev.RoleKey == ra.RoleKey {
+					return fmt.Errorf("duplicate role %s of associate %s", ra.RoleKey, a.CustomerID)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func associateIndex(associates []domain.Associate, customerID string) int {
+	for i, a := range associates {
+		if a.CustomerID == customerID {
+			return i
+		}
+	}
+	return -1
+}
+
+func addressIDFromIndex(addresses []domain.CustomerAddress, idx *int) string {
+	if idx == nil || *idx < 0 || *idx >= len(addresses) {
+		return ""
+	}
+	return addresses[*idx].ID
+}
+
+func randomAddressID() string {
+	var buf [6]byte
+	_, _ = rand.Read(buf[:])
+	return base64.RawURLEncoding.EncodeToString(buf[:])
+}
+
+func defaultString(v, def string) string {
+	if strings.TrimSpace(v) == "" {
+		return def
+	}
+	return v
+}
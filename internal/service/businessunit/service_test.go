@@ -0,0 +1,287 @@
+package businessunit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"commercetools-replica/internal/domain"
+)
+
+// stubRepo returns the stored business units and records the ones the
+// service writes.
+type stubRepo struct {
+	stored  []domain.BusinessUnit
+	created *domain.BusinessUnit
+	updated *domain.BusinessUnit
+	deleted int
+}
+
+func (r *stubRepo) List(_ context.Context, _ string, _, _ int) ([]domain.BusinessUnit, int, error) {
+	return r.stored, len(r.stored), nil
+}
+
+func (r *stubRepo) GetByID(_ context.Context, _, id string) (*domain.BusinessUnit, error) {
+	for _, u := range r.stored {
+		if u.ID == id {
+			u.Addresses = slices.Clone(u.Addresses)
+			u.Associates = slices.Clone(u.Associates)
+			return &u, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (r *stubRepo) GetByKey(ctx context.Context, projectID, key string) (*domain.BusinessUnit, error) {
+	for _, u := range r.stored {
+		if u.Key == key {
+			return r.GetByID(ctx, projectID, u.ID)
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (r *stubRepo) Create(_ context.Context, u domain.BusinessUnit) (*domain.BusinessUnit, error) {
+	u.ID, u.Version = "unit-1", 1
+	r.created = &u
+	return &u, nil
+}
+
+func (r *stubRepo) Update(_ context.Context, u domain.BusinessUnit) (*domain.BusinessUnit, error) {
+	r.updated = &u
+	out := u
+	out.Version++
+	return &out, nil
+}
+
+func (r *stubRepo) Delete(_ context.Context, _, id string, version int) (*domain.BusinessUnit, error) {
+	r.deleted++
+	return &domain.BusinessUnit{ID: id, Version: version}, nil
+}
+
+// units returns the acme company with its east division, east's north
+// division, and the globex company, as the repository resolves them.
+func units() []domain.BusinessUnit {
+	return []domain.BusinessUnit{
+		{ID: "unit-acme", ProjectID: "proj", Version: 1, Key: "acme", UnitType: domain.BusinessUnitCompany, Name: "ACME",
+			Status: domain.BusinessUnitActive, AssociateMode: domain.AssociateModeExplicit, TopLevelUnitKey: "acme"},
+		{ID: "unit-east", ProjectID: "proj", Version: 1, Key: "east", UnitType: domain.BusinessUnitDivision, Name: "East",
+			Status: domain.BusinessUnitActive, AssociateMode: domain.AssociateModeExplicitAndFromParent,
+			ParentUnitID: "unit-acme", ParentUnitKey: "acme", TopLevelUnitID: "unit-acme", TopLevelUnitKey: "acme"},
+		{ID: "unit-north", ProjectID: "proj", Version: 1, Key: "north", UnitType: domain.BusinessUnitDivision, Name: "North",
+			Status: domain.BusinessUnitActive, AssociateMode: domain.AssociateModeExplicitAndFromParent,
+			ParentUnitID: "unit-east", ParentUnitKey: "east", TopLevelUnitID: "unit-acme", TopLevelUnitKey: "acme"},
+		{ID: "unit-globex", ProjectID: "proj", Version: 1, Key: "globex", UnitType: domain.BusinessUnitCompany, Name: "Globex",
+			Status: domain.BusinessUnitActive, AssociateMode: domain.AssociateModeExplicit, TopLevelUnitKey: "globex"},
+	}
+}
+
+type stubRoles []domain.AssociateRole
+
+func (s stubRoles) GetByID(_ context.Context, _, id string) (*domain.AssociateRole, error) {
+	for i := range s {
+		if s[i].ID == id {
+			return &s[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s stubRoles) GetByKey(_ context.Context, _, key string) (*domain.AssociateRole, error) {
+	for i := range s {
+		if s[i].Key == key {
+			return &s[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+type stubCustomers map[string]bool
+
+func (s stubCustomers) GetByID(_ context.Context, _, id string) (*domain.Customer, error) {
+	if !s[id] {
+		return nil, domain.ErrNotFound
+	}
+	return &domain.Customer{ID: id}, nil
+}
+
+var roles = stubRoles{
+	{ID: "r-admin", Key: "admin", Permissions: []string{domain.PermissionCreateMyCarts, domain.PermissionCreateOthersCarts}},
+	{ID: "r-buyer", Key: "buyer", Permissions: []string{domain.PermissionCreateMyCarts}},
+}
+
+func associate(customerID, roleKey, inheritance string) AssociateDraft {
+	return AssociateDraft{
+		Customer:                 ResourceIdentifier{ID: customerID},
+		AssociateRoleAssignments: []AssociateRoleAssignmentDraft{{AssociateRole: ResourceIdentifier{Key: roleKey}, Inheritance: inheritance}},
+	}
+}
+
+func TestServiceCreate(t *testing.T) {
+	repo := &stubRepo{stored: units()}
+	svc := New(repo, roles, stubCustomers{"c1": true})
+	ctx := context.Background()
+
+	shipping := 1
+	if _, err := svc.Create(ctx, "proj", BusinessUnitDraft{
+		Key:                    " initech ",
+		UnitType:               domain.BusinessUnitCompany,
+		Name:                   "Initech",
+		Addresses:              []domain.CustomerAddress{{Key: "hq", Country: "DE"}, {Key: "dock", Country: "DE"}},
+		DefaultShippingAddress: &shipping,
+		Associates:             []AssociateDraft{associate("c1", "admin", "")},
+	}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	company := repo.created
+	if company == nil || company.ProjectID != "proj" || company.Key != "initech" || company.Status != domain.BusinessUnitActive ||
+		company.AssociateMode != domain.AssociateModeExplicit || company.Addresses[0].ID == "" ||
+		company.DefaultShippingAddressID != company.Addresses[1].ID {
+		t.Fatalf("unexpected company %+v", company)
+	}
+	want := []domain.Associate{{CustomerID: "c1", RoleAssignments: []domain.AssociateRoleAssignment{{RoleKey: "admin", Inheritance: domain.InheritanceEnabled}}}}
+	if !reflect.DeepEqual(company.Associates, want) {
+		t.Fatalf("unexpected associates %+v", company.Associates)
+	}
+	if _, err := svc.Create(ctx, "proj", BusinessUnitDraft{Key: "west", UnitType: domain.BusinessUnitDivision, Name: "West", ParentUnit: &ResourceIdentifier{Key: "acme"}}); err != nil {
+		t.Fatalf("create division: %v", err)
+	}
+	if division := repo.created; division.AssociateMode != domain.AssociateModeExplicitAndFromParent || division.ParentUnitID != "unit-acme" {
+		t.Fatalf("expected west to be created under acme, got %+v", division)
+	}
+
+	repo.created = nil
+	cases := []struct {
+		draft BusinessUnitDraft
+		want  string
+	}{
+		{BusinessUnitDraft{Key: "x", UnitType: domain.BusinessUnitCompany, Name: "X"}, "key must be 2 to 256 letters, digits, '-' or '_'"},
+		{BusinessUnitDraft{Key: "west", UnitType: domain.BusinessUnitDivision, Name: "West"}, "a division requires a parent unit"},
+		{BusinessUnitDraft{Key: "sub", UnitType: domain.BusinessUnitCompany, Name: "Sub", ParentUnit: &ResourceIdentifier{Key: "acme"}}, "a company has no parent unit"},
+		{BusinessUnitDraft{Key: "west", UnitType: domain.BusinessUnitDivision, Name: "West", ParentUnit: &ResourceIdentifier{Key: "nope"}}, "parent unit not found"},
+		{BusinessUnitDraft{Key: "shop", UnitType: "Shop", Name: "Shop"}, `unsupported unitType "Shop"`},
+		{BusinessUnitDraft{Key: "other", UnitType: domain.BusinessUnitCompany, Name: "Other", Associates: []AssociateDraft{associate("c2", "admin", "")}}, "customer c2 not found"},
+		{BusinessUnitDraft{Key: "other", UnitType: domain.BusinessUnitCompany, Name: "Other", Associates: []AssociateDraft{associate("c1", "owner", "")}}, "associate role not found"},
+		{BusinessUnitDraft{Key: "other", UnitType: domain.BusinessUnitCompany, Name: "Other", Associates: []AssociateDraft{associate("c1", "admin", ""), associate("c1", "buyer", "")}}, "duplicate associate c1"},
+	}
+	for _, tc := range cases {
+		if _, err := svc.Create(ctx, "proj", tc.draft); err == nil || err.Error() != tc.want {
+			t.Fatalf("%s: expected %q, got %v", tc.draft.Key, tc.want, err)
+		}
+	}
+	if repo.created != nil {
+		t.Fatalf("expected invalid drafts not to be stored, got %+v", repo.created)
+	}
+}
+
+func TestServiceUpdateActions(t *testing.T) {
+	repo := &stubRepo{stored: units()}
+	svc := New(repo, roles, stubCustomers{"c1": true, "c2": true})
+	ctx := context.Background()
+
+	var in UpdateInput
+	body := `{"version":1,"actions":[
+		{"action":"changeName","name":"ACME Corp"},
+		{"action":"setContactEmail","contactEmail":"buy@acme.test"},
+		{"action":"addAddress","address":{"key":"hq","country":"DE"}},
+		{"action":"setDefaultBillingAddress","addressKey":"hq"},
+		{"action":"addAssociate","associate":{"customer":{"id":"c1"},"associateRoleAssignments":[{"associateRole":{"key":"buyer"}}]}},
+		{"action":"addAssociate","associate":{"customer":{"id":"c2"},"associateRoleAssignments":[{"associateRole":{"key":"buyer"}}]}},
+		{"action":"changeAssociate","associate":{"customer":{"id":"c1"},"associateRoleAssignments":[{"associateRole":{"id":"r-admin"},"inheritance":"Disabled"}]}},
+		{"action":"removeAssociate","customer":{"id":"c2"}}
+	]}`
+	if err := json.Unmarshal([]byte(body), &in); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if _, err := svc.Update(ctx, "proj", "unit-acme", in); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	want := []domain.Associate{{CustomerID: "c1", RoleAssignments: []domain.AssociateRoleAssignment{{RoleKey: "admin", Inheritance: domain.InheritanceDisabled}}}}
+	if u := repo.updated; u == nil || u.Version != 1 || u.Name != "ACME Corp" || u.ContactEmail != "buy@acme.test" ||
+		u.DefaultBillingAddressID != u.Addresses[0].ID || !reflect.DeepEqual(u.Associates, want) {
+		t.Fatalf("unexpected update %+v", u)
+	}
+
+	repo.updated = nil
+	cases := []struct {
+		id   string
+		body string
+		want string
+	}{
+		{"unit-acme", `{"version":2,"actions":[{"action":"changeName","name":"ACME"}]}`, domain.ErrConcurrentModification.Error()},
+		{"unit-acme", `{"version":1,"actions":[{"action":"changeParentUnit","parentUnit":{"key":"east"}}]}`, "only divisions have a parent unit"},
+		{"unit-east", `{"version":1,"actions":[{"action":"changeParentUnit","parentUnit":{"key":"north"}}]}`, "business unit north is a division of east"},
+		{"unit-east", `{"version":1,"actions":[{"action":"changeParentUnit","parentUnit":{"key":"globex"}}]}`, "business unit globex belongs to another company"},
+		{"unit-acme", `{"version":1,"actions":[{"action":"changeAssociateMode","associateMode":"ExplicitAndFromParent"}]}`, "a company's associateMode must be Explicit"},
+		{"unit-east", `{"version":1,"actions":[{"action":"changeStatus","status":"Closed"}]}`, `unsupported status "Closed"`},
+		{"unit-east", `{"version":1,"actions":[{"action":"removeAssociate","customer":{"id":"c1"}}]}`, "customer c1 is not an associate"},
+		{"unit-east", `{"version":1,"actions":[{"action":"removeAddress","addressId":"missing"}]}`, "address not found"},
+	}
+	for _, tc := range cases {
+		var in UpdateInput
+		if err := json.Unmarshal([]byte(tc.body), &in); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if _, err := svc.Update(ctx, "proj", tc.id, in); err == nil || err.Error() != tc.want {
+			t.Fatalf("%s: expected %q, got %v", tc.body, tc.want, err)
+		}
+	}
+	if repo.updated != nil {
+		t.Fatalf("expected failed updates not to be stored, got %+v", repo.updated)
+	}
+
+	if err := json.Unmarshal([]byte(`{"version":1,"actions":[{"action":"changeParentUnit","parentUnit":{"key":"acme"}}]}`), &in); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if _, err := svc.Update(ctx, "proj", "unit-north", in); err != nil {
+		t.Fatalf("change parent: %v", err)
+	}
+	if moved := repo.updated; moved.ParentUnitID != "unit-acme" || moved.ParentUnitKey != "acme" {
+		t.Fatalf("expected north to move to acme, got %+v", moved)
+	}
+	if _, err := svc.Update(ctx, "proj", "missing", in); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if _, err := svc.Delete(ctx, "proj", "unit-north", 0); err == nil || repo.deleted != 0 {
+		t.Fatalf("expected delete without a version to fail, got %v", err)
+	}
+}
+
+func TestServicePermissions(t *testing.T) {
+	us := units()
+	acme, east, north := &us[0], &us[1], &us[2]
+	acme.Associates = []domain.Associate{
+		{CustomerID: "admin", RoleAssignments: []domain.AssociateRoleAssignment{{RoleKey: "admin", Inheritance: domain.InheritanceEnabled}}},
+		{CustomerID: "local", RoleAssignments: []domain.AssociateRoleAssignment{{RoleKey: "admin", Inheritance: domain.InheritanceDisabled}}},
+	}
+	east.Associates = []domain.Associate{
+		{CustomerID: "buyer", RoleAssignments: []domain.AssociateRoleAssignment{{RoleKey: "buyer", Inheritance: domain.InheritanceEnabled}}},
+	}
+	north.AssociateMode = domain.AssociateModeExplicit
+	svc := New(&stubRepo{stored: us}, roles, nil)
+	ctx := context.Background()
+
+	cases := []struct {
+		unit       *domain.BusinessUnit
+		customer   string
+		permission string
+		want       error
+	}{
+		{acme, "admin", domain.PermissionCreateOthersCarts, nil},
+		{acme, "buyer", domain.PermissionCreateMyCarts, ErrNotAssociate},
+		{east, "admin", domain.PermissionCreateOthersCarts, nil},
+		{east, "local", domain.PermissionCreateMyCarts, ErrNotAssociate},
+		{east, "buyer", domain.PermissionCreateMyCarts, nil},
+		{east, "buyer", domain.PermissionCreateOthersCarts, ErrPermissionDenied},
+		{north, "admin", domain.PermissionCreateMyCarts, ErrNotAssociate},
+	}
+	for _, tc := range cases {
+		err := svc.Authorize(ctx, tc.unit, tc.customer, tc.permission)
+		if (tc.want == nil && err != nil) || (tc.want != nil && !errors.Is(err, tc.want)) {
+			t.Fatalf("%s in %s with %s: expected %v, got %v", tc.customer, tc.unit.Key, tc.permission, tc.want, err)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"commercetools-replica/internal/domain"
+)
+
+// businessUnitLookup loads the business unit a cart is created for; see the
+// businessunit service.
+type businessUnitLookup interface {
+	GetByKey(ctx context.Context, projectID, key string) (*domain.BusinessUnit, error)
+}
+
+// WithBusinessUnits lets carts be created for business units; without it no
+// cart can reference one.
+func (s *Service) WithBusinessUnits(units businessUnitLookup) *Service {
+	s.businessUnits = units
+	return s
+}
+
+// businessUnit loads the active business unit a cart is created for.
+func (s *Service) businessUnit(ctx context.Context, projectID, key string) (*domain.BusinessUnit, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil, errors.New("business unit key required")
+	}
+	if s.businessUnits == nil {
+		return nil, errors.New("business unit lookup unavailable")
+	}
+	bu, err := s.businessUnits.GetByKey(ctx, projectID, key)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, fmt.Errorf("business unit %s not found", key)
+		}
+		return nil, err
+	}
+	if bu.Status != domain.BusinessUnitActive {
+		return nil, fmt.Errorf("business unit %s is not active", key)
+	}
+	return bu, nil
+}
@@ -0,0 +1,54 @@
+package cart
+
+import (
+	"context"
+	"testing"
+
+	"commercetools-replica/internal/domain"
+)
+
+type stubBusinessUnits []domain.BusinessUnit
+
+func (s stubBusinessUnits) GetByKey(_ context.Context, _, key string) (*domain.BusinessUnit, error) {
+	for i := range s {
+		if s[i].Key == key {
+			return &s[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func TestServiceBusinessUnitCarts(t *testing.T) {
+	repo := &stubRepo{createCart: &domain.Cart{ID: "cart"}}
+	svc := New(repo, &stubProductRepo{}, nil, nil, nil, nil, nil, nil, nil, nil)
+	ctx := context.Background()
+	in := CreateInput{Currency: "EUR", BusinessUnit: &BusinessUnitReference{Key: "acme"}}
+
+	if _, err := svc.Create(ctx, "proj", in); err == nil || err.Error() != "business unit lookup unavailable" {
+		t.Fatalf("expected lookup error, got %v", err)
+	}
+	svc.WithBusinessUnits(stubBusinessUnits{
+		{Key: "acme", Status: domain.BusinessUnitActive},
+		{Key: "old", Status: domain.BusinessUnitInactive},
+	})
+	if _, err := svc.Create(ctx, "proj", in); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if repo.lastCreate.BusinessUnitKey != "acme" {
+		t.Fatalf("expected the business unit key to be stored, got %+v", repo.lastCreate)
+	}
+	cases := []struct {
+		key  string
+		want string
+	}{
+		{"old", "business unit old is not active"},
+		{"missing", "business unit missing not found"},
+		{" ", "business unit key required"},
+	}
+	for _, tc := range cases {
+		in := CreateInput{Currency: "EUR", BusinessUnit: &BusinessUnitReference{Key: tc.key}}
+		if _, err := svc.Create(ctx, "proj", in); err == nil || err.Error() != tc.want {
+			t.Fatalf("expected %q, got %v", tc.want, err)
+		}
+	}
+}
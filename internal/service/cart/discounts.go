@@ -0,0 +1,354 @@
+package cart
+
+import (
+	"sort"
+	"time"
+
+	"commercetools-replica/internal/domain"
+	"commercetools-replica/internal/money"
+	"commercetools-replica/internal/predicate"
+	cartrepo "commercetools-replica/internal/repository/cart"
+)
+
+// candidate is a cart or direct discount that may apply to the cart.
+type candidate struct {
+	id            string
+	direct        bool
+	value         domain.CartDiscountValue
+	target        *domain.CartDiscountTarget
+	cartPredicate *predicate.Predicate
+	linePredicate *predicate.Predicate
+	stop          bool
+	sortOrder     float64
+	// codeID is the discount code that unlocked the discount, if any.
+	codeID string
+}
+
+// gift is a gift line item a giftLineItem discount wants in the cart.
+type gift struct {
+	discountID string
+	productID  string
+	variantID  int
+}
+
+type lineState struct {
+	unit int64
+	// precise is the high-precision unit price of lines without a product
+	// discount; it is only used while no cart discount changed the unit price.
+	precise    *money.Amount
+	portions   []domain.DiscountPortion
+	taxRate    *domain.TaxRate
+	taxedPrice *domain.TaxedPrice
+}
+
+// total returns the line total in cents. High-precision unit prices are
+// multiplied by the quantity before the result is rounded half even.
+func (st *lineState) total(quantity int) int64 {
+	if st.precise != nil && len(st.portions) == 0 {
+		return st.precise.Mul(int64(quantity)).CentAmount(money.HalfEven)
+	}
+	return st.unit * int64(quantity)
+}
+
+// calculation is the outcome of applying the cart discounts to a cart.
+type calculation struct {
+	lines           map[string]*lineState
+	totalCents      int64
+	discountOnTotal *domain.DiscountOnTotal
+	codeStates      map[string]string
+	gifts           []gift
+	taxedPrice      *domain.TaxedPrice
+	// shipping is the cart's shipping info with its discounted price and taxes;
+	// multiShipping holds the same for each shipping in Multiple shipping mode.
+	shipping      *domain.ShippingInfo
+	multiShipping []domain.Shipping
+}
+
+// calculate applies discounts to cart in descending sortOrder. Line item targets
+// lower the unit prices of the matching lines, totalPrice targets what is left
+// of the cart total, and gift values discount their gift line to zero. Carts
+// with direct discounts apply those, in order, instead. Shipping targets lower
+// the price of every shipping method that matches the cart. The shipping costs
+// are part of the total price.
+func calculate(cart domain.Cart, customer *domain.Customer, discounts []domain.CartDiscount, codes []domain.DiscountCode, now time.Time) calculation {
+	env := predicate.CartFields(cart, customer)
+	calc := calculation{lines: map[string]*lineState{}, codeStates: map[string]string{}}
+
+	var candidates []candidate
+	if len(cart.DirectDiscounts) > 0 {
+		for _, d := range cart.DirectDiscounts {
+			c := candidate{id: d.ID, direct: true, value: d.Value, target: d.Target}
+			if !c.compileTarget() {
+				continue
+			}
+			candidates = append(candidates, c)
+		}
+	} else {
+		unlocked := map[string]string{}
+		for _, code := range codes {
+			switch {
+			case !code.IsActive:
+				calc.codeStates[code.ID] = domain.DiscountCodeNotActive
+				continue
+			case !code.ValidAt(now):
+				calc.codeStates[code.ID] = domain.DiscountCodeNotValid
+				continue
+			case !matches(code.CartPredicate, env):
+				calc.codeStates[code.ID] = domain.DiscountCodeDoesNotMatchCart
+				continue
+			}
+			calc.codeStates[code.ID] = domain.DiscountCodeDoesNotMatchCart
+			for _, id := range code.CartDiscountIDs {
+				if _, ok := unlocked[id]; !ok {
+					unlocked[id] = code.ID
+				}
+			}
+		}
+		for _, d := range discounts {
+			if !d.ValidAt(now) {
+				continue
+			}
+			codeID, isUnlocked := unlocked[d.ID]
+			if d.RequiresDiscountCode && !isUnlocked {
+				continue
+			}
+			pred, err := predicate.Parse(d.CartPredicate)
+			if err != nil {
+				continue
+			}
+			c := candidate{
+				id:            d.ID,
+				value:         d.Value,
+				target:        d.Target,
+				cartPredicate: pred,
+				stop:          d.StackingMode == domain.StackingModeStopAfterThisDiscount,
+				sortOrder:     d.SortOrderValue(),
+				codeID:        codeID,
+			}
+			if !c.compileTarget() {
+				continue
+			}
+			candidates = append(candidates, c)
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].sortOrder > candidates[j].sortOrder
+		})
+	}
+
+	for _, line := range cart.Lines {
+		calc.lines[line.ID] = &lineState{unit: line.UnitPriceCents, precise: line.PreciseUnitPrice()}
+	}
+	refused := map[string]struct{}{}
+	for _, id := range cart.RefusedGifts {
+		refused[id] = struct{}{}
+	}
+	lineEnvs := map[string]predicate.Fields{}
+
+	var totalOff int64
+	var totalPortions []domain.DiscountPortion
+	shipped := chargedShipping(cart)
+	shippingOff := make([]int64, len(shipped))
+	shippingPortions := make([][]domain.DiscountPortion, len(shipped))
+	for i, c := range candidates {
+		if c.cartPredicate != nil {
+			if ok, err := c.cartPredicate.Eval(env); err != nil || !ok {
+				continue
+			}
+		}
+		applied := false
+		switch {
+		case c.value.Type == domain.CartDiscountGiftLineItem:
+			if _, ok := refused[c.id]; ok {
+				continue
+			}
+			calc.gifts = append(calc.gifts, gift{discountID: c.id, productID: c.value.ProductID, variantID: c.value.VariantID})
+			applied = true
+			for _, line := range cart.Lines {
+				if !line.IsGift() || giftDiscountID(line) != c.id {
+					continue
+				}
+				st := calc.lines[line.ID]
+				if st.unit > 0 {
+					st.portions = append(st.portions, c.portion(st.unit, currencyOf(cart, line)))
+					st.unit = 0
+				}
+			}
+		case c.target.Type == domain.CartDiscountTargetLineItems:
+			for _, line := range cart.Lines {
+				if line.IsGift() {
+					continue
+				}
+				lineEnv, ok := lineEnvs[line.ID]
+				if !ok {
+					lineEnv = predicate.LineItemFields(line)
+					lineEnvs[line.ID] = lineEnv
+				}
+				if ok, err := c.linePredicate.Eval(lineEnv); err != nil || !ok {
+					continue
+				}
+				st := calc.lines[line.ID]
+				currency := currencyOf(cart, line)
+				off := c.value.Off(st.unit, currency)
+				if off <= 0 {
+					continue
+				}
+				st.unit -= off
+				st.portions = append(st.portions, c.portion(off, currency))
+				applied = true
+			}
+		case c.target.Type == domain.CartDiscountTargetTotalPrice:
+			var base int64
+			for _, line := range cart.Lines {
+				base += calc.lines[line.ID].total(line.Quantity)
+			}
+			base -= totalOff
+			off := c.value.Off(base, cart.Currency)
+			if off > 0 {
+				totalOff += off
+				totalPortions = append(totalPortions, c.portion(off, cart.Currency))
+				applied = true
+			}
+		case c.target.Type == domain.CartDiscountTargetShipping:
+			for j, info := range shipped {
+				if info.ShippingMethodState != domain.ShippingMethodMatchesCart {
+					continue
+				}
+				off := c.value.Off(info.Price.CentAmount-shippingOff[j], cart.Currency)
+				if off > 0 {
+					shippingOff[j] += off
+					shippingPortions[j] = append(shippingPortions[j], c.portion(off, cart.Currency))
+					applied = true
+				}
+			}
+		}
+		if !applied {
+			continue
+		}
+		if c.codeID != "" {
+			calc.codeStates[c.codeID] = domain.DiscountCodeMatchesCart
+		}
+		if c.stop {
+			for _, rest := range candidates[i+1:] {
+				if rest.codeID != "" && calc.codeStates[rest.codeID] != domain.DiscountCodeMatchesCart {
+					calc.codeStates[rest.codeID] = domain.DiscountCodeApplicationStoppedByPreviousDiscount
+				}
+			}
+			break
+		}
+	}
+
+	for _, line := range cart.Lines {
+		calc.totalCents += calc.lines[line.ID].total(line.Quantity)
+	}
+	calc.totalCents -= totalOff
+	if totalOff > 0 {
+		calc.discountOnTotal = &domain.DiscountOnTotal{
+			DiscountedAmount:  domain.Money{CurrencyCode: cart.Currency, CentAmount: totalOff},
+			IncludedDiscounts: totalPortions,
+		}
+	}
+	for i := range shipped {
+		info := &shipped[i]
+		if shippingOff[i] > 0 {
+			info.DiscountedPrice = &domain.DiscountedShippingPrice{
+				Value:             domain.Money{CurrencyCode: cart.Currency, CentAmount: info.Price.CentAmount - shippingOff[i]},
+				IncludedDiscounts: shippingPortions[i],
+			}
+		}
+		calc.totalCents += info.Cost().CentAmount
+	}
+	if cart.ShippingInfo != nil {
+		calc.shipping = &shipped[0]
+	}
+	offset := len(shipped) - len(cart.Shipping)
+	for i, sh := range cart.Shipping {
+		sh.ShippingInfo = shipped[offset+i]
+		calc.multiShipping = append(calc.multiShipping, sh)
+	}
+	return calc
+}
+
+// chargedShipping copies the shipping infos the cart pays for: its shipping
+// info followed by those of its shippings in Multiple shipping mode.
+func chargedShipping(cart domain.Cart) []domain.ShippingInfo {
+	var out []domain.ShippingInfo
+	if cart.ShippingInfo != nil {
+		out = append(out, *cart.ShippingInfo)
+	}
+	for _, sh := range cart.Shipping {
+		out = append(out, sh.ShippingInfo)
+	}
+	return out
+}
+
+// compileTarget parses the line item predicate; discounts with an invalid one are skipped.
+func (c *candidate) compileTarget() bool {
+	if c.value.Type == domain.CartDiscountGiftLineItem {
+		return true
+	}
+	if c.target == nil {
+		return false
+	}
+	if c.target.Type == domain.CartDiscountTargetLineItems {
+		pred, err := predicate.Parse(c.target.Predicate)
+		if err != nil {
+			return false
+		}
+		c.linePredicate = pred
+	}
+	return true
+}
+
+func (c candidate) portion(cents int64, currency string) domain.DiscountPortion {
+	return domain.DiscountPortion{DiscountID: c.id, Direct: c.direct, Amount: domain.Money{CurrencyCode: currency, CentAmount: cents}}
+}
+
+// saveInput turns the calculation into the per-line totals, discounted prices
+// and taxes to store.
+func (calc calculation) saveInput(cart domain.Cart) cartrepo.SaveTotalsInput {
+	in := cartrepo.SaveTotalsInput{
+		TotalCents:         calc.totalCents,
+		DiscountOnTotal:    calc.discountOnTotal,
+		DiscountCodeStates: calc.codeStates,
+		TaxedPrice:         calc.taxedPrice,
+		ShippingInfo:       calc.shipping,
+		Shipping:           calc.multiShipping,
+	}
+	for _, line := range cart.Lines {
+		st := calc.lines[line.ID]
+		ld := cartrepo.LineTotals{LineID: line.ID, TotalCents: st.total(line.Quantity), TaxRate: st.taxRate, TaxedPrice: st.taxedPrice}
+		if len(st.portions) > 0 {
+			ld.DiscountedPricePerQuantity = []domain.DiscountedQuantity{{
+				Quantity:          line.Quantity,
+				Value:             domain.Money{CurrencyCode: currencyOf(cart, line), CentAmount: st.unit},
+				IncludedDiscounts: st.portions,
+			}}
+		}
+		in.Lines = append(in.Lines, ld)
+	}
+	return in
+}
+
+func matches(src string, env predicate.Env) bool {
+	if src == "" {
+		return true
+	}
+	pred, err := predicate.Parse(src)
+	if err != nil {
+		return false
+	}
+	ok, err := pred.Eval(env)
+	return err == nil && ok
+}
+
+func currencyOf(cart domain.Cart, line domain.CartLine) string {
+	if currency, ok := line.Snapshot["currency"].(string); ok && currency != "" {
+		return currency
+	}
+	return cart.Currency
+}
+
+func giftDiscountID(line domain.CartLine) string {
+	id, _ := line.Snapshot["giftDiscountId"].(string)
+	return id
+}
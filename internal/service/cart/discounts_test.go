@@ -0,0 +1,286 @@
+package cart
+
+import (
+	"testing"
+	"time"
+
+	"commercetools-replica/internal/domain"
+)
+
+func discountCart() domain.Cart {
+	return domain.Cart{
+		ID:         "cart",
+		CustomerID: strPtr("cust"),
+		Currency:   "EUR",
+		Lines: []domain.CartLine{
+			{ID: "l1", ProductID: "p1", VariantID: 1, Quantity: 2, UnitPriceCents: 1000, Snapshot: map[string]interface{}{"sku": "shirt", "currency": "EUR"}},
+			{ID: "l2", ProductID: "p2", VariantID: 1, Quantity: 1, UnitPriceCents: 3000, Snapshot: map[string]interface{}{"sku": "shoes", "currency": "EUR"}},
+		},
+	}
+}
+
+func cartDiscount(id, sortOrder string, value domain.CartDiscountValue, target *domain.CartDiscountTarget) domain.CartDiscount {
+	return domain.CartDiscount{
+		ID:            id,
+		Value:         value,
+		CartPredicate: "1 = 1",
+		Target:        target,
+		SortOrder:     sortOrder,
+		IsActive:      true,
+		StackingMode:  domain.StackingModeStacking,
+	}
+}
+
+var (
+	shirts     = &domain.CartDiscountTarget{Type: domain.CartDiscountTargetLineItems, Predicate: `sku = "shirt"`}
+	allLines   = &domain.CartDiscountTarget{Type: domain.CartDiscountTargetLineItems, Predicate: "1 = 1"}
+	totalPrice = &domain.CartDiscountTarget{Type: domain.CartDiscountTargetTotalPrice}
+	tenPercent = domain.CartDiscountValue{Type: domain.CartDiscountRelative, Permyriad: 1000}
+)
+
+func TestCalculateLineItemAndTotalDiscounts(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		discounts []domain.CartDiscount
+		units     map[string]int64
+		total     int64
+		onTotal   int64
+	}{
+		{
+			name:  "no discounts",
+			units: map[string]int64{"l1": 1000, "l2": 3000},
+			total: 5000,
+		},
+		{
+			name:      "relative on matching lines",
+			discounts: []domain.CartDiscount{cartDiscount("d1", "0.5", tenPercent, shirts)},
+			units:     map[string]int64{"l1": 900, "l2": 3000},
+			total:     4800,
+		},
+		{
+			name: "absolute per unit is capped at the price",
+			discounts: []domain.CartDiscount{cartDiscount("d1", "0.5", domain.CartDiscountValue{
+				Type: domain.CartDiscountAbsolute, Money: []domain.Money{{CurrencyCode: "EUR", CentAmount: 1500}},
+			}, allLines)},
+			units: map[string]int64{"l1": 0, "l2": 1500},
+			total: 1500,
+		},
+		{
+			name: "fixed only lowers prices above the amount",
+			discounts: []domain.CartDiscount{cartDiscount("d1", "0.5", domain.CartDiscountValue{
+				Type: domain.CartDiscountFixed, Money: []domain.Money{{CurrencyCode: "EUR", CentAmount: 2000}},
+			}, allLines)},
+			units: map[string]int64{"l1": 1000, "l2": 2000},
+			total: 4000,
+		},
+		{
+			name: "absolute in another currency does nothing",
+			discounts: []domain.CartDiscount{cartDiscount("d1", "0.5", domain.CartDiscountValue{
+				Type: domain.CartDiscountAbsolute, Money: []domain.Money{{CurrencyCode: "USD", CentAmount: 100}},
+			}, allLines)},
+			units: map[string]int64{"l1": 1000, "l2": 3000},
+			total: 5000,
+		},
+		{
+			name: "total price after line discounts",
+			discounts: []domain.CartDiscount{
+				cartDiscount("d1", "0.5", tenPercent, shirts),
+				cartDiscount("d2", "0.4", tenPercent, totalPrice),
+			},
+			units:   map[string]int64{"l1": 900, "l2": 3000},
+			total:   4320,
+			onTotal: 480,
+		},
+		{
+			name: "higher sortOrder applies first and can stop the chain",
+			discounts: []domain.CartDiscount{
+				cartDiscount("d1", "0.5", tenPercent, shirts),
+				func() domain.CartDiscount {
+					d := cartDiscount("d2", "0.9", domain.CartDiscountValue{Type: domain.CartDiscountRelative, Permyriad: 5000}, allLines)
+					d.StackingMode = domain.StackingModeStopAfterThisDiscount
+					return d
+				}(),
+			},
+			units: map[string]int64{"l1": 500, "l2": 1500},
+			total: 2500,
+		},
+		{
+			name: "cart predicate must match",
+			discounts: []domain.CartDiscount{func() domain.CartDiscount {
+				d := cartDiscount("d1", "0.5", tenPercent, allLines)
+				d.CartPredicate = "totalPrice.centAmount > 10000"
+				return d
+			}()},
+			units: map[string]int64{"l1": 1000, "l2": 3000},
+			total: 5000,
+		},
+		{
+			name: "inactive and code-only discounts are skipped",
+			discounts: []domain.CartDiscount{
+				func() domain.CartDiscount {
+					d := cartDiscount("d1", "0.5", tenPercent, allLines)
+					d.IsActive = false
+					return d
+				}(),
+				func() domain.CartDiscount {
+					d := cartDiscount("d2", "0.4", tenPercent, allLines)
+					d.RequiresDiscountCode = true
+					return d
+				}(),
+			},
+			units: map[string]int64{"l1": 1000, "l2": 3000},
+			total: 5000,
+		},
+		{
+			name:      "shipping targets have nothing to discount",
+			discounts: []domain.CartDiscount{cartDiscount("d1", "0.5", tenPercent, &domain.CartDiscountTarget{Type: domain.CartDiscountTargetShipping})},
+			units:     map[string]int64{"l1": 1000, "l2": 3000},
+			total:     5000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := discountCart()
+			calc := calculate(cart, nil, tt.discounts, nil, now)
+			for id, want := range tt.units {
+				if got := calc.lines[id].unit; got != want {
+					t.Fatalf("line %s: expected unit price %d, got %d", id, want, got)
+				}
+			}
+			if calc.totalCents != tt.total {
+				t.Fatalf("expected total %d, got %d", tt.total, calc.totalCents)
+			}
+			var onTotal int64
+			if calc.discountOnTotal != nil {
+				onTotal = calc.discountOnTotal.DiscountedAmount.CentAmount
+			}
+			if onTotal != tt.onTotal {
+				t.Fatalf("expected %d off the total, got %d", tt.onTotal, onTotal)
+			}
+		})
+	}
+}
+
+func TestCalculateHighPrecisionLines(t *testing.T) {
+	precise := map[string]interface{}{"sku": "screw", "currency": "EUR", "preciseAmount": float64(3333), "fractionDigits": float64(3)}
+	cart := domain.Cart{
+		ID:       "cart",
+		Currency: "EUR",
+		Lines: []domain.CartLine{
+			{ID: "l1", ProductID: "p1", VariantID: 1, Quantity: 3, UnitPriceCents: 333, Snapshot: precise},
+			{ID: "l2", ProductID: "p2", VariantID: 1, Quantity: 2, UnitPriceCents: 1500, Snapshot: map[string]interface{}{"sku": "shoes", "currency": "EUR"}},
+		},
+	}
+	calc := calculate(cart, nil, nil, nil, time.Now())
+	if got := calc.lines["l1"].total(3); got != 1000 {
+		t.Fatalf("expected the precise price to be rounded once, got %d", got)
+	}
+	if calc.totalCents != 4000 {
+		t.Fatalf("expected total 4000, got %d", calc.totalCents)
+	}
+
+	calc = calculate(cart, nil, []domain.CartDiscount{cartDiscount("d1", "0.5", tenPercent, allLines)}, nil, time.Now())
+	if got := calc.lines["l1"].unit; got != 300 {
+		t.Fatalf("expected discounts to work on the cent price, got %d", got)
+	}
+	if calc.totalCents != 900+2700 {
+		t.Fatalf("expected total 3600, got %d", calc.totalCents)
+	}
+
+	cart.Currency = "JPY"
+	cart.Lines = []domain.CartLine{{ID: "l1", ProductID: "p1", VariantID: 1, Quantity: 1, UnitPriceCents: 1005, Snapshot: map[string]interface{}{"currency": "JPY"}}}
+	calc = calculate(cart, nil, []domain.CartDiscount{cartDiscount("d1", "0.5", domain.CartDiscountValue{Type: domain.CartDiscountRelative, Permyriad: 5000}, allLines)}, nil, time.Now())
+	if got := calc.lines["l1"].unit; got != 503 {
+		t.Fatalf("expected 502 yen off (502.5 rounded half even), got unit price %d", got)
+	}
+}
+
+func TestCalculateDiscountCodeStates(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	coded := cartDiscount("d1", "0.5", tenPercent, allLines)
+	coded.RequiresDiscountCode = true
+	stopper := cartDiscount("d0", "0.9", tenPercent, allLines)
+	stopper.StackingMode = domain.StackingModeStopAfterThisDiscount
+	stopper.RequiresDiscountCode = true
+	unmatched := cartDiscount("d2", "0.4", tenPercent, &domain.CartDiscountTarget{Type: domain.CartDiscountTargetLineItems, Predicate: `sku = "hat"`})
+
+	tests := []struct {
+		name      string
+		discounts []domain.CartDiscount
+		code      domain.DiscountCode
+		// other codes added next to code
+		others []domain.DiscountCode
+		state  string
+		total  int64
+	}{
+		{name: "matches", discounts: []domain.CartDiscount{coded}, code: domain.DiscountCode{ID: "c", IsActive: true, CartDiscountIDs: []string{"d1"}}, state: domain.DiscountCodeMatchesCart, total: 4500},
+		{name: "inactive", discounts: []domain.CartDiscount{coded}, code: domain.DiscountCode{ID: "c", CartDiscountIDs: []string{"d1"}}, state: domain.DiscountCodeNotActive, total: 5000},
+		{name: "expired", discounts: []domain.CartDiscount{coded}, code: domain.DiscountCode{ID: "c", IsActive: true, ValidUntil: &past, CartDiscountIDs: []string{"d1"}}, state: domain.DiscountCodeNotValid, total: 5000},
+		{name: "code predicate", discounts: []domain.CartDiscount{coded}, code: domain.DiscountCode{ID: "c", IsActive: true, CartPredicate: `currency = "USD"`, CartDiscountIDs: []string{"d1"}}, state: domain.DiscountCodeDoesNotMatchCart, total: 5000},
+		{name: "no line matches", discounts: []domain.CartDiscount{unmatched}, code: domain.DiscountCode{ID: "c", IsActive: true, CartDiscountIDs: []string{"d2"}}, state: domain.DiscountCodeDoesNotMatchCart, total: 5000},
+		{name: "stopped", discounts: []domain.CartDiscount{stopper, coded}, code: domain.DiscountCode{ID: "c", IsActive: true, CartDiscountIDs: []string{"d1"}},
+			others: []domain.DiscountCode{{ID: "other", IsActive: true, CartDiscountIDs: []string{"d0"}}}, state: domain.DiscountCodeApplicationStoppedByPreviousDiscount, total: 4500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codes := append([]domain.DiscountCode{tt.code}, tt.others...)
+			calc := calculate(discountCart(), nil, tt.discounts, codes, now)
+			if got := calc.codeStates["c"]; got != tt.state {
+				t.Fatalf("expected state %s, got %s", tt.state, got)
+			}
+			if calc.totalCents != tt.total {
+				t.Fatalf("expected total %d, got %d", tt.total, calc.totalCents)
+			}
+		})
+	}
+}
+
+func TestCalculateGiftLineItems(t *testing.T) {
+	now := time.Now()
+	giftValue := domain.CartDiscountValue{Type: domain.CartDiscountGiftLineItem, ProductID: "p9", VariantID: 1}
+	giftDiscount := cartDiscount("gift", "0.5", giftValue, nil)
+
+	calc := calculate(discountCart(), nil, []domain.CartDiscount{giftDiscount}, nil, now)
+	if len(calc.gifts) != 1 || calc.gifts[0].productID != "p9" {
+		t.Fatalf("expected the gift to be wanted, got %+v", calc.gifts)
+	}
+
+	cart := discountCart()
+	cart.Lines = append(cart.Lines, domain.CartLine{
+		ID: "g1", ProductID: "p9", VariantID: 1, Quantity: 1, UnitPriceCents: 700,
+		LineItemMode: domain.LineItemModeGiftLineItem,
+		Snapshot:     map[string]interface{}{"giftDiscountId": "gift", "currency": "EUR"},
+	})
+	calc = calculate(cart, nil, []domain.CartDiscount{giftDiscount, cartDiscount("d1", "0.4", tenPercent, allLines)}, nil, now)
+	if calc.lines["g1"].unit != 0 || calc.totalCents != 4500 {
+		t.Fatalf("expected a free gift line untouched by line discounts, got unit %d total %d", calc.lines["g1"].unit, calc.totalCents)
+	}
+	in := calc.saveInput(cart)
+	giftLine := in.Lines[2]
+	if giftLine.TotalCents != 0 || len(giftLine.DiscountedPricePerQuantity) != 1 || giftLine.DiscountedPricePerQuantity[0].IncludedDiscounts[0].Amount.CentAmount != 700 {
+		t.Fatalf("unexpected gift line discounts %+v", giftLine)
+	}
+
+	cart.RefusedGifts = []string{"gift"}
+	calc = calculate(cart, nil, []domain.CartDiscount{giftDiscount}, nil, now)
+	if len(calc.gifts) != 0 || calc.lines["g1"].unit != 700 {
+		t.Fatalf("expected refused gift to be ignored, got %+v", calc.gifts)
+	}
+}
+
+func TestCalculateDirectDiscountsReplaceCartDiscounts(t *testing.T) {
+	cart := discountCart()
+	cart.DirectDiscounts = []domain.DirectDiscount{{ID: "dd1", Value: domain.CartDiscountValue{
+		Type: domain.CartDiscountAbsolute, Money: []domain.Money{{CurrencyCode: "EUR", CentAmount: 1000}},
+	}, Target: totalPrice}}
+	calc := calculate(cart, nil, []domain.CartDiscount{cartDiscount("d1", "0.5", tenPercent, allLines)}, nil, time.Now())
+	if calc.totalCents != 4000 || calc.lines["l1"].unit != 1000 {
+		t.Fatalf("expected only the direct discount, got total %d", calc.totalCents)
+	}
+	portion := calc.discountOnTotal.IncludedDiscounts[0]
+	if portion.DiscountID != "dd1" || !portion.Direct {
+		t.Fatalf("unexpected portion %+v", portion)
+	}
+}
@@ -3,40 +3,141 @@ package cart
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
+	"time"
 
 	"commercetools-replica/internal/domain"
+	"commercetools-replica/internal/money"
 	cartrepo "commercetools-replica/internal/repository/cart"
+	cartdiscountsvc "commercetools-replica/internal/service/cartdiscount"
 )
 
 type Service struct {
-	repo        cartRepo
-	productRepo productRepo
+	repo          cartRepo
+	productRepo   productRepo
+	discounts     productDiscounter
+	cartDiscounts cartDiscountLister
+	discountCodes discountCodeGetter
+	customers     customerGetter
+	taxCategories taxCategoryGetter
+	shipping      shippingMethodGetter
+	zones         zoneLister
+	stores        storeLookup
+	businessUnits businessUnitLookup
+	now           func() time.Time
 }
 
 type cartRepo interface {
 	Create(ctx context.Context, in cartrepo.CreateCartInput) (*domain.Cart, error)
 	GetByID(ctx context.Context, projectID, id string) (*domain.Cart, error)
-	GetActiveByCustomer(ctx context.Context, projectID, customerID string) (*domain.Cart, error)
-	GetActiveByAnonymous(ctx context.Context, projectID, anonymousID string) (*domain.Cart, error)
+	GetActiveByCustomer(ctx context.Context, projectID, customerID, storeKey string) (*domain.Cart, error)
+	GetActiveByAnonymous(ctx context.Context, projectID, anonymousID, storeKey string) (*domain.Cart, error)
 	AssignCustomerToAnonymous(ctx context.Context, projectID, anonymousID, customerID string) (*domain.Cart, error)
-	AddLineItem(ctx context.Context, cartID string, product domain.Product, quantity int, snapshot map[string]interface{}) error
-	ChangeLineItemQuantity(ctx context.Context, cartID, lineItemID string, quantity int) error
+	AddLineItem(ctx context.Context, projectID, cartID string, in cartrepo.AddLineItemInput) error
+	ChangeLineItemQuantity(ctx context.Context, projectID, cartID, lineItemID string, quantity int) error
 	SetState(ctx context.Context, projectID, cartID, state string) error
+	AddDiscountCode(ctx context.Context, projectID, cartID, discountCodeID, state string) error
+	RemoveDiscountCode(ctx context.Context, projectID, cartID, discountCodeID string) error
+	CountDiscountCodeUses(ctx context.Context, projectID, discountCodeID, customerID, anonymousID string) (int, int, error)
+	SetDirectDiscounts(ctx context.Context, projectID, cartID string, discounts []domain.DirectDiscount) error
+	RefuseGift(ctx context.Context, projectID, cartID, discountID string) error
+	SetTaxSettings(ctx context.Context, projectID, cartID string, in cartrepo.TaxSettings) error
+	SetShippingInfo(ctx context.Context, projectID, cartID string, info *domain.ShippingInfo) error
+	SetShipping(ctx context.Context, projectID, cartID string, shipping []domain.Shipping) error
+	SetItemShippingAddresses(ctx context.Context, projectID, cartID string, addresses []domain.CustomerAddress) error
+	SetLineItemShippingDetails(ctx context.Context, projectID, cartID, lineItemID string, details *domain.ItemShippingDetails) error
+	SaveTotals(ctx context.Context, projectID, cartID string, in cartrepo.SaveTotalsInput) error
+	InTx(ctx context.Context, fn func(cartrepo.Repository) error) error
 }
 
 type productRepo interface {
+	GetByID(ctx context.Context, projectID, id string) (*domain.Product, error)
 	GetBySKU(ctx context.Context, projectID, sku string) (*domain.Product, error)
 }
 
-func New(repo cartrepo.Repository, productRepo productRepo) *Service {
-	return &Service{repo: repo, productRepo: productRepo}
+// productDiscounter sets the discounted prices on products; see the productdiscount service.
+type productDiscounter interface {
+	ApplyToProducts(ctx context.Context, projectID string, products []domain.Product) error
+}
+
+// cartDiscountLister lists the project's cart discounts; see the cartdiscount service.
+type cartDiscountLister interface {
+	ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.CartDiscount, int, error)
+}
+
+type discountCodeGetter interface {
+	Get(ctx context.Context, projectID, id string) (*domain.DiscountCode, error)
+	GetByCode(ctx context.Context, projectID, code string) (*domain.DiscountCode, error)
+}
+
+// customerGetter loads the cart's customer for customer.* cart predicates.
+type customerGetter interface {
+	GetByID(ctx context.Context, projectID, id string) (*domain.Customer, error)
+}
+
+// taxCategoryGetter loads the tax categories of the line items' products.
+type taxCategoryGetter interface {
+	GetByID(ctx context.Context, projectID, id string) (*domain.TaxCategory, error)
+}
+
+// shippingMethodGetter loads the shipping methods carts are shipped with.
+type shippingMethodGetter interface {
+	ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.ShippingMethod, int, error)
+	Get(ctx context.Context, projectID, id string) (*domain.ShippingMethod, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.ShippingMethod, error)
+}
+
+// zoneLister lists the zones matched against the shipping address.
+type zoneLister interface {
+	ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.Zone, int, error)
+}
+
+// storeLookup loads the store of a cart and its assortment; see the store service.
+type storeLookup interface {
+	GetByKey(ctx context.Context, projectID, key string) (*domain.Store, error)
+	ProductIDs(ctx context.Context, projectID, storeID string) (map[string]bool, error)
+}
+
+// New creates the cart service; discounts may be nil, in which case line items
+// are added at their undiscounted price. Without cartDiscounts carts are priced
+// without cart discounts, and without discountCodes no codes can be added.
+// Without customers, customer.* fields are undefined in cart predicates, and
+// without taxCategories carts are not taxed. Without shippingMethods and zones
+// no shipping method can be set, and without stores no cart can be created in
+// a store.
+func New(repo cartrepo.Repository, productRepo productRepo, discounts productDiscounter, cartDiscounts cartDiscountLister, discountCodes discountCodeGetter, customers customerGetter, taxCategories taxCategoryGetter, shippingMethods shippingMethodGetter, zones zoneLister, stores storeLookup) *Service {
+	return &Service{repo: repo, productRepo: productRepo, discounts: discounts, cartDiscounts: cartDiscounts, discountCodes: discountCodes, customers: customers, taxCategories: taxCategories, shipping: shippingMethods, zones: zones, stores: stores, now: time.Now}
 }
 
 type CreateInput struct {
 	CustomerID  *string `json:"customerId,omitempty"`
 	AnonymousID *string `json:"anonymousId,omitempty"`
 	Currency    string  `json:"currency"`
+	Country     string  `json:"country,omitempty"`
+	// ShippingAddress decides the tax rates of the line items.
+	ShippingAddress    *domain.CustomerAddress `json:"shippingAddress,omitempty"`
+	TaxMode            string                  `json:"taxMode,omitempty"`
+	TaxRoundingMode    string                  `json:"taxRoundingMode,omitempty"`
+	TaxCalculationMode string                  `json:"taxCalculationMode,omitempty"`
+	// ShippingMode is Single (the default) or Multiple.
+	ShippingMode string `json:"shippingMode,omitempty"`
+	// InventoryMode is None (the default), TrackOnly or ReserveOnOrder.
+	InventoryMode string `json:"inventoryMode,omitempty"`
+	// Store restricts the cart to the store's countries and assortment.
+	Store *StoreReference `json:"store,omitempty"`
+	// BusinessUnit makes the cart a cart of the business unit.
+	BusinessUnit *BusinessUnitReference `json:"businessUnit,omitempty"`
+}
+
+type StoreReference struct {
+	TypeID string `json:"typeId,omitempty"`
+	Key    string `json:"key"`
+}
+
+type BusinessUnitReference struct {
+	TypeID string `json:"typeId,omitempty"`
+	Key    string `json:"key"`
 }
 
 type UpdateInput struct {
@@ -49,17 +150,104 @@ type UpdateAction struct {
 	SKU        string `json:"sku,omitempty"`
 	LineItemID string `json:"lineItemId,omitempty"`
 	Quantity   int    `json:"quantity,omitempty"`
+	// Code is the code of addDiscountCode; DiscountCode references the code removed
+	// by removeDiscountCode.
+	Code         string                 `json:"code,omitempty"`
+	DiscountCode *DiscountCodeReference `json:"discountCode,omitempty"`
+	// Discounts replaces the direct discounts of the cart (setDirectDiscounts).
+	Discounts []DirectDiscountDraft `json:"discounts,omitempty"`
+	// Address is the address of setShippingAddress; a missing one removes it.
+	Address            *domain.CustomerAddress `json:"address,omitempty"`
+	Country            string                  `json:"country,omitempty"`
+	TaxMode            string                  `json:"taxMode,omitempty"`
+	TaxRoundingMode    string                  `json:"taxRoundingMode,omitempty"`
+	TaxCalculationMode string                  `json:"taxCalculationMode,omitempty"`
+	// ShippingMethod is the method of setShippingMethod; a missing one removes
+	// the shipping info.
+	ShippingMethod *ShippingMethodReference `json:"shippingMethod,omitempty"`
+	// AddressKey references the item shipping address removed by
+	// removeItemShippingAddress; addItemShippingAddress takes Address.
+	AddressKey      string                      `json:"addressKey,omitempty"`
+	ShippingDetails *domain.ItemShippingDetails `json:"shippingDetails,omitempty"`
+	// ShippingKey identifies the shipping of addShippingMethod and
+	// removeShippingMethod in Multiple shipping mode.
+	ShippingKey     string                  `json:"shippingKey,omitempty"`
+	ShippingAddress *domain.CustomerAddress `json:"shippingAddress,omitempty"`
+}
+
+type ShippingMethodReference struct {
+	TypeID string `json:"typeId,omitempty"`
+	ID     string `json:"id,omitempty"`
+	Key    string `json:"key,omitempty"`
+}
+
+type DiscountCodeReference struct {
+	TypeID string `json:"typeId,omitempty"`
+	ID     string `json:"id"`
+}
+
+type DirectDiscountDraft struct {
+	Value  cartdiscountsvc.ValueDraft `json:"value"`
+	Target *domain.CartDiscountTarget `json:"target,omitempty"`
 }
 
 func (s *Service) Create(ctx context.Context, projectID string, in CreateInput) (*domain.Cart, error) {
 	if strings.TrimSpace(in.Currency) == "" {
 		return nil, errors.New("currency required")
 	}
+	settings := cartrepo.TaxSettings{
+		Country:            strings.ToUpper(strings.TrimSpace(in.Country)),
+		ShippingAddress:    normalizeAddress(in.ShippingAddress),
+		TaxMode:            defaultString(in.TaxMode, domain.TaxModePlatform),
+		TaxRoundingMode:    defaultString(in.TaxRoundingMode, domain.RoundingHalfEven),
+		TaxCalculationMode: defaultString(in.TaxCalculationMode, domain.TaxCalculationLineItemLevel),
+	}
+	if err := validateTaxSettings(settings); err != nil {
+		return nil, err
+	}
+	shippingMode := defaultString(in.ShippingMode, domain.ShippingModeSingle)
+	if shippingMode != domain.ShippingModeSingle && shippingMode != domain.ShippingModeMultiple {
+		return nil, fmt.Errorf("unsupported shippingMode %q", shippingMode)
+	}
+	inventoryMode := defaultString(in.InventoryMode, domain.InventoryModeNone)
+	switch inventoryMode {
+	case domain.InventoryModeNone, domain.InventoryModeTrackOnly, domain.InventoryModeReserveOnOrder:
+	default:
+		return nil, fmt.Errorf("unsupported inventoryMode %q", inventoryMode)
+	}
+	var storeKey string
+	if in.Store != nil {
+		st, err := s.store(ctx, projectID, in.Store.Key)
+		if err != nil {
+			return nil, err
+		}
+		if settings.Country != "" && !st.SellsInCountry(settings.Country) {
+			return nil, fmt.Errorf("country %s is not a country of store %s", settings.Country, st.Key)
+		}
+		storeKey = st.Key
+	}
+	var businessUnitKey string
+	if in.BusinessUnit != nil {
+		bu, err := s.businessUnit(ctx, projectID, in.BusinessUnit.Key)
+		if err != nil {
+			return nil, err
+		}
+		businessUnitKey = bu.Key
+	}
 	return s.repo.Create(ctx, cartrepo.CreateCartInput{
-		ProjectID:   projectID,
-		CustomerID:  in.CustomerID,
-		AnonymousID: in.AnonymousID,
-		Currency:    in.Currency,
+		ProjectID:          projectID,
+		CustomerID:         in.CustomerID,
+		AnonymousID:        in.AnonymousID,
+		Currency:           in.Currency,
+		Country:            settings.Country,
+		ShippingAddress:    settings.ShippingAddress,
+		TaxMode:            settings.TaxMode,
+		TaxRoundingMode:    settings.TaxRoundingMode,
+		TaxCalculationMode: settings.TaxCalculationMode,
+		ShippingMode:       shippingMode,
+		InventoryMode:      inventoryMode,
+		StoreKey:           storeKey,
+		BusinessUnitKey:    businessUnitKey,
 	})
 }
 
@@ -67,12 +255,22 @@ func (s *Service) Get(ctx context.Context, projectID, id string) (*domain.Cart,
 	return s.repo.GetByID(ctx, projectID, id)
 }
 
+// GetActive returns the customer's newest active cart in any store.
 func (s *Service) GetActive(ctx context.Context, projectID, customerID string) (*domain.Cart, error) {
-	return s.repo.GetActiveByCustomer(ctx, projectID, customerID)
+	return s.repo.GetActiveByCustomer(ctx, projectID, customerID, "")
 }
 
 func (s *Service) GetActiveAnonymous(ctx context.Context, projectID, anonymousID string) (*domain.Cart, error) {
-	return s.repo.GetActiveByAnonymous(ctx, projectID, anonymousID)
+	return s.repo.GetActiveByAnonymous(ctx, projectID, anonymousID, "")
+}
+
+// GetActiveInStore returns the customer's newest active cart of the store.
+func (s *Service) GetActiveInStore(ctx context.Context, projectID, storeKey, customerID string) (*domain.Cart, error) {
+	return s.repo.GetActiveByCustomer(ctx, projectID, customerID, storeKey)
+}
+
+func (s *Service) GetActiveAnonymousInStore(ctx context.Context, projectID, storeKey, anonymousID string) (*domain.Cart, error) {
+	return s.repo.GetActiveByAnonymous(ctx, projectID, anonymousID, storeKey)
 }
 
 func (s *Service) AssignCustomerFromAnonymous(ctx context.Context, projectID, anonymousID, customerID string) (*domain.Cart, error) {
@@ -95,10 +293,27 @@ func (s *Service) DeleteAnonymous(ctx context.Context, projectID, anonymousID, c
 	return s.deleteWithOwner(ctx, projectID, cartID, nil, &anonymousID)
 }
 
+// updateWithOwner applies the actions and saves the recalculated totals in one
+// transaction, so a failing action leaves the cart as it was.
 func (s *Service) updateWithOwner(ctx context.Context, projectID, cartID string, customerID, anonymousID *string, in UpdateInput) (*domain.Cart, error) {
 	if len(in.Actions) == 0 {
 		return nil, errors.New("actions required")
 	}
+	var updated *domain.Cart
+	err := s.repo.InTx(ctx, func(repo cartrepo.Repository) error {
+		tx := *s
+		tx.repo = repo
+		var err error
+		updated, err = tx.applyActions(ctx, projectID, cartID, customerID, anonymousID, in)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (s *Service) applyActions(ctx context.Context, projectID, cartID string, customerID, anonymousID *string, in UpdateInput) (*domain.Cart, error) {
 	cart, err := s.repo.GetByID(ctx, projectID, cartID)
 	if err != nil {
 		return nil, err
@@ -115,6 +330,12 @@ func (s *Service) updateWithOwner(ctx context.Context, projectID, cartID string,
 	default:
 		return nil, domain.ErrNotFound
 	}
+	if strings.EqualFold(cart.State, "ordered") {
+		return nil, errors.New("ordered carts cannot be updated")
+	}
+	if cart.State == domain.CartStateFrozen {
+		return nil, errors.New("frozen carts cannot be updated")
+	}
 
 	for _, action := range in.Actions {
 		switch strings.ToLower(strings.TrimSpace(action.Action)) {
@@ -136,8 +357,39 @@ func (s *Service) updateWithOwner(ctx context.Context, projectID, cartID string,
 				}
 				return nil, err
 			}
-			snapshot := snapshotFromProduct(*product)
-			if err := s.repo.AddLineItem(ctx, cartID, *product, action.Quantity, snapshot); err != nil {
+			if !product.Published {
+				return nil, errors.New("product not published")
+			}
+			if err := s.checkAssortment(ctx, projectID, cart.StoreKey, product.ID); err != nil {
+				return nil, err
+			}
+			variant := product.Current.VariantBySKU(sku)
+			if variant == nil {
+				return nil, errors.New("product not found")
+			}
+			groupID, err := s.customerGroupID(ctx, projectID, cart)
+			if err != nil {
+				return nil, err
+			}
+			price, ok := selectPrice(*variant, cart.Currency, groupID)
+			if !ok {
+				return nil, fmt.Errorf("no price for currency %s", cart.Currency)
+			}
+			price, err = s.discountedPrice(ctx, *product, variant.ID, price)
+			if err != nil {
+				return nil, err
+			}
+			unitPrice := price.Value.CentAmount
+			if price.Discounted != nil {
+				unitPrice = price.Discounted.Value.CentAmount
+			}
+			if err := s.repo.AddLineItem(ctx, projectID, cartID, cartrepo.AddLineItemInput{
+				ProductID:      product.ID,
+				VariantID:      variant.ID,
+				Quantity:       action.Quantity,
+				UnitPriceCents: unitPrice,
+				Snapshot:       snapshotFromProduct(*product, *variant, price),
+			}); err != nil {
 				return nil, err
 			}
 		case "changelineitemquantity":
@@ -148,7 +400,101 @@ func (s *Service) updateWithOwner(ctx context.Context, projectID, cartID string,
 			if action.Quantity <= 0 {
 				return nil, errors.New("quantity must be positive")
 			}
-			if err := s.repo.ChangeLineItemQuantity(ctx, cartID, lineID, action.Quantity); err != nil {
+			if line := findLine(cart, lineID); line != nil && line.IsGift() {
+				return nil, errors.New("quantity of gift line items cannot be changed")
+			}
+			if err := s.repo.ChangeLineItemQuantity(ctx, projectID, cartID, lineID, action.Quantity); err != nil {
+				return nil, err
+			}
+		case "removelineitem":
+			lineID := strings.TrimSpace(action.LineItemID)
+			if lineID == "" {
+				return nil, errors.New("lineItemId required")
+			}
+			if err := s.repo.ChangeLineItemQuantity(ctx, projectID, cartID, lineID, 0); err != nil {
+				return nil, err
+			}
+			if line := findLine(cart, lineID); line != nil && line.IsGift() {
+				if err := s.repo.RefuseGift(ctx, projectID, cartID, giftDiscountID(*line)); err != nil {
+					return nil, err
+				}
+			}
+		case "adddiscountcode":
+			if err := s.addDiscountCode(ctx, projectID, cart, strings.TrimSpace(action.Code)); err != nil {
+				return nil, err
+			}
+		case "removediscountcode":
+			if action.DiscountCode == nil || strings.TrimSpace(action.DiscountCode.ID) == "" {
+				return nil, errors.New("discountCode required")
+			}
+			if err := s.repo.RemoveDiscountCode(ctx, projectID, cartID, strings.TrimSpace(action.DiscountCode.ID)); err != nil {
+				if errors.Is(err, domain.ErrNotFound) {
+					return nil, errors.New("discount code is not on the cart")
+				}
+				return nil, err
+			}
+		case "setdirectdiscounts":
+			if len(cart.DiscountCodes) > 0 {
+				return nil, errors.New("direct discounts cannot be set on carts with discount codes")
+			}
+			discounts := make([]domain.DirectDiscount, 0, len(action.Discounts))
+			for _, draft := range action.Discounts {
+				d := domain.DirectDiscount{Value: draft.Value.ToValue(), Target: draft.Target}
+				if err := cartdiscountsvc.ValidateValue(d.Value); err != nil {
+					return nil, err
+				}
+				if err := cartdiscountsvc.ValidateTarget(d.Value, d.Target); err != nil {
+					return nil, err
+				}
+				discounts = append(discounts, d)
+			}
+			if err := s.repo.SetDirectDiscounts(ctx, projectID, cartID, discounts); err != nil {
+				return nil, err
+			}
+		case "setshippingaddress", "setcountry", "changetaxmode", "changetaxroundingmode", "changetaxcalculationmode":
+			settings := taxSettingsOf(*cart)
+			switch strings.ToLower(strings.TrimSpace(action.Action)) {
+			case "setshippingaddress":
+				settings.ShippingAddress = normalizeAddress(action.Address)
+			case "setcountry":
+				settings.Country = strings.ToUpper(strings.TrimSpace(action.Country))
+			case "changetaxmode":
+				settings.TaxMode = strings.TrimSpace(action.TaxMode)
+			case "changetaxroundingmode":
+				settings.TaxRoundingMode = strings.TrimSpace(action.TaxRoundingMode)
+			case "changetaxcalculationmode":
+				settings.TaxCalculationMode = strings.TrimSpace(action.TaxCalculationMode)
+			}
+			if err := validateTaxSettings(settings); err != nil {
+				return nil, err
+			}
+			if err := s.repo.SetTaxSettings(ctx, projectID, cartID, settings); err != nil {
+				return nil, err
+			}
+			cart.Country, cart.ShippingAddress = settings.Country, settings.ShippingAddress
+			cart.TaxMode, cart.TaxRoundingMode, cart.TaxCalculationMode = settings.TaxMode, settings.TaxRoundingMode, settings.TaxCalculationMode
+		case "setshippingmethod":
+			if err := s.setShippingMethod(ctx, projectID, cart, action.ShippingMethod); err != nil {
+				return nil, err
+			}
+		case "additemshippingaddress":
+			if err := s.addItemShippingAddress(ctx, cart, action.Address); err != nil {
+				return nil, err
+			}
+		case "removeitemshippingaddress":
+			if err := s.removeItemShippingAddress(ctx, cart, strings.TrimSpace(action.AddressKey)); err != nil {
+				return nil, err
+			}
+		case "setlineitemshippingdetails":
+			if err := s.setLineItemShippingDetails(ctx, cart, strings.TrimSpace(action.LineItemID), action.ShippingDetails); err != nil {
+				return nil, err
+			}
+		case "addshippingmethod":
+			if err := s.addShippingMethod(ctx, projectID, cart, strings.TrimSpace(action.ShippingKey), action.ShippingMethod, action.ShippingAddress); err != nil {
+				return nil, err
+			}
+		case "removeshippingmethod":
+			if err := s.removeShippingMethod(ctx, cart, strings.TrimSpace(action.ShippingKey)); err != nil {
 				return nil, err
 			}
 		default:
@@ -156,7 +502,262 @@ func (s *Service) updateWithOwner(ctx context.Context, projectID, cartID string,
 		}
 	}
 
-	return s.repo.GetByID(ctx, projectID, cartID)
+	cart, err = s.repo.GetByID(ctx, projectID, cartID)
+	if err != nil {
+		return nil, err
+	}
+	return s.recalculate(ctx, projectID, cart)
+}
+
+// addDiscountCode adds the code to the cart if it is active, valid and has
+// applications left; whether it matches the cart is settled by recalculate.
+func (s *Service) addDiscountCode(ctx context.Context, projectID string, cart *domain.Cart, code string) error {
+	if code == "" {
+		return errors.New("code required")
+	}
+	if s.discountCodes == nil {
+		return errors.New("discount codes unavailable")
+	}
+	if len(cart.DirectDiscounts) > 0 {
+		return errors.New("discount codes cannot be added to carts with direct discounts")
+	}
+	dc, err := s.discountCodes.GetByCode(ctx, projectID, code)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return fmt.Errorf("discount code %q not found", code)
+		}
+		return err
+	}
+	if !dc.IsActive {
+		return fmt.Errorf("discount code %q is not active", code)
+	}
+	if !dc.ValidAt(s.now()) {
+		return fmt.Errorf("discount code %q is not valid", code)
+	}
+	for _, existing := range cart.DiscountCodes {
+		if existing.DiscountCodeID == dc.ID {
+			return fmt.Errorf("discount code %q already added", code)
+		}
+	}
+	if dc.MaxApplications != nil || dc.MaxApplicationsPerCustomer != nil {
+		// Anonymous carts count against the per-customer limit by anonymous id.
+		customerID, anonymousID := "", ""
+		if cart.CustomerID != nil {
+			customerID = *cart.CustomerID
+		}
+		if cart.AnonymousID != nil {
+			anonymousID = *cart.AnonymousID
+		}
+		total, byCustomer, err := s.repo.CountDiscountCodeUses(ctx, projectID, dc.ID, customerID, anonymousID)
+		if err != nil {
+			return err
+		}
+		if dc.MaxApplications != nil && total >= *dc.MaxApplications {
+			return fmt.Errorf("discount code %q reached its maximum number of applications", code)
+		}
+		if dc.MaxApplicationsPerCustomer != nil && (customerID != "" || anonymousID != "") && byCustomer >= *dc.MaxApplicationsPerCustomer {
+			return fmt.Errorf("discount code %q reached its maximum number of applications for this customer", code)
+		}
+	}
+	if err := s.repo.AddDiscountCode(ctx, cart.ProjectID, cart.ID, dc.ID, domain.DiscountCodeDoesNotMatchCart); err != nil {
+		if errors.Is(err, domain.ErrAlreadyExists) {
+			return fmt.Errorf("discount code %q already added", code)
+		}
+		return err
+	}
+	return nil
+}
+
+// recalculate computes the line and cart totals, prices the shipping, applies
+// the cart discounts, adds or removes gift line items, taxes the line items and
+// shipping and stores the discounted prices, totals and taxes.
+func (s *Service) recalculate(ctx context.Context, projectID string, cart *domain.Cart) (*domain.Cart, error) {
+	var discounts []domain.CartDiscount
+	if s.cartDiscounts != nil {
+		var err error
+		if discounts, _, err = s.cartDiscounts.ListPage(ctx, projectID, 0, 0); err != nil {
+			return nil, err
+		}
+	}
+	var codes []domain.DiscountCode
+	if s.discountCodes != nil {
+		for _, c := range cart.DiscountCodes {
+			dc, err := s.discountCodes.Get(ctx, projectID, c.DiscountCodeID)
+			if err != nil {
+				if errors.Is(err, domain.ErrNotFound) {
+					continue
+				}
+				return nil, err
+			}
+			codes = append(codes, *dc)
+		}
+	}
+	customer, err := s.customer(ctx, projectID, cart)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if s.now != nil {
+		now = s.now()
+	}
+	if err := s.refreshShipping(ctx, projectID, cart, customer); err != nil {
+		return nil, err
+	}
+	calc := calculate(*cart, customer, discounts, codes, now)
+	changed, err := s.syncGifts(ctx, projectID, cart, calc.gifts)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		if cart, err = s.repo.GetByID(ctx, projectID, cart.ID); err != nil {
+			return nil, err
+		}
+		if err := s.refreshShipping(ctx, projectID, cart, customer); err != nil {
+			return nil, err
+		}
+		calc = calculate(*cart, customer, discounts, codes, now)
+	}
+	categories, err := s.taxCategoriesOf(ctx, projectID, cart)
+	if err != nil {
+		return nil, err
+	}
+	calc.applyTaxes(*cart, categories)
+	if err := s.repo.SaveTotals(ctx, cart.ProjectID, cart.ID, calc.saveInput(*cart)); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, projectID, cart.ID)
+}
+
+// taxCategoriesOf loads the tax categories the line items snapshotted and the
+// ones of the shipping methods, leaving out deleted ones.
+func (s *Service) taxCategoriesOf(ctx context.Context, projectID string, cart *domain.Cart) (map[string]domain.TaxCategory, error) {
+	out := map[string]domain.TaxCategory{}
+	if s.taxCategories == nil {
+		return out, nil
+	}
+	var ids []string
+	for _, line := range cart.Lines {
+		id, _ := line.Snapshot["taxCategoryId"].(string)
+		ids = append(ids, id)
+	}
+	if cart.ShippingInfo != nil {
+		ids = append(ids, cart.ShippingInfo.TaxCategoryID)
+	}
+	for _, sh := range cart.Shipping {
+		ids = append(ids, sh.ShippingInfo.TaxCategoryID)
+	}
+	for _, id := range ids {
+		if id == "" {
+			continue
+		}
+		if _, ok := out[id]; ok {
+			continue
+		}
+		tc, err := s.taxCategories.GetByID(ctx, projectID, id)
+		if err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		out[id] = *tc
+	}
+	return out, nil
+}
+
+// customer returns the customer of cart, or nil for anonymous carts and
+// customers that no longer exist.
+func (s *Service) customer(ctx context.Context, projectID string, cart *domain.Cart) (*domain.Customer, error) {
+	if s.customers == nil || cart.CustomerID == nil {
+		return nil, nil
+	}
+	customer, err := s.customers.GetByID(ctx, projectID, *cart.CustomerID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, nil
+	}
+	return customer, err
+}
+
+// customerGroupID returns the customer group whose prices apply to cart, or ""
+// for anonymous carts and customers without a group.
+func (s *Service) customerGroupID(ctx context.Context, projectID string, cart *domain.Cart) (string, error) {
+	customer, err := s.customer(ctx, projectID, cart)
+	if err != nil || customer == nil {
+		return "", err
+	}
+	return customer.CustomerGroupID, nil
+}
+
+// syncGifts adds a gift line for every wanted gift missing from the cart and
+// removes gift lines whose discount no longer applies. Gifts whose product has
+// no price in the cart currency are skipped.
+func (s *Service) syncGifts(ctx context.Context, projectID string, cart *domain.Cart, gifts []gift) (bool, error) {
+	wanted := map[string]gift{}
+	for _, g := range gifts {
+		wanted[g.discountID] = g
+	}
+	changed := false
+	for _, line := range cart.Lines {
+		if !line.IsGift() {
+			continue
+		}
+		id := giftDiscountID(line)
+		if _, ok := wanted[id]; ok {
+			delete(wanted, id)
+			continue
+		}
+		if err := s.repo.ChangeLineItemQuantity(ctx, cart.ProjectID, cart.ID, line.ID, 0); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+	for _, g := range gifts {
+		if _, ok := wanted[g.discountID]; !ok || s.productRepo == nil {
+			continue
+		}
+		product, err := s.productRepo.GetByID(ctx, projectID, g.productID)
+		if err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				continue
+			}
+			return false, err
+		}
+		variant := product.Current.Variant(g.variantID)
+		if !product.Published || variant == nil {
+			continue
+		}
+		groupID, err := s.customerGroupID(ctx, projectID, cart)
+		if err != nil {
+			return false, err
+		}
+		price, ok := selectPrice(*variant, cart.Currency, groupID)
+		if !ok {
+			continue
+		}
+		snap := snapshotFromProduct(*product, *variant, price)
+		snap["giftDiscountId"] = g.discountID
+		if err := s.repo.AddLineItem(ctx, cart.ProjectID, cart.ID, cartrepo.AddLineItemInput{
+			ProductID:      product.ID,
+			VariantID:      variant.ID,
+			Quantity:       1,
+			UnitPriceCents: price.Value.CentAmount,
+			Snapshot:       snap,
+			LineItemMode:   domain.LineItemModeGiftLineItem,
+		}); err != nil {
+			return false, err
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+func findLine(cart *domain.Cart, lineID string) *domain.CartLine {
+	for i := range cart.Lines {
+		if cart.Lines[i].ID == lineID {
+			return &cart.Lines[i]
+		}
+	}
+	return nil
 }
 
 func (s *Service) deleteWithOwner(ctx context.Context, projectID, cartID string, customerID, anonymousID *string) (*domain.Cart, error) {
@@ -183,23 +784,131 @@ func (s *Service) deleteWithOwner(ctx context.Context, projectID, cartID string,
 	return s.repo.GetByID(ctx, projectID, cartID)
 }
 
-func snapshotFromProduct(p domain.Product) map[string]interface{} {
-	slug := strings.TrimSpace(p.Key)
-	if slug == "" {
-		slug = strings.ReplaceAll(strings.ToLower(p.Name), " ", "-")
+// discountedPrice returns price with the product discount that applies to it now, if any.
+func (s *Service) discountedPrice(ctx context.Context, product domain.Product, variantID int, price domain.Price) (domain.Price, error) {
+	if s.discounts == nil {
+		return price, nil
+	}
+	products := []domain.Product{product}
+	if err := s.discounts.ApplyToProducts(ctx, product.ProjectID, products); err != nil {
+		return domain.Price{}, err
+	}
+	if v := products[0].Current.Variant(variantID); v != nil {
+		for _, p := range v.Prices {
+			if p.ID == price.ID {
+				return p, nil
+			}
+		}
+	}
+	return price, nil
+}
+
+// selectPrice picks the variant price in the cart currency, preferring the
+// price of the customer's group; carts without a currency take the first price.
+func selectPrice(v domain.ProductVariant, currency, customerGroupID string) (domain.Price, bool) {
+	if strings.TrimSpace(currency) == "" {
+		if len(v.Prices) == 0 {
+			return domain.Price{}, false
+		}
+		return v.Prices[0], true
+	}
+	return v.PriceForGroup(currency, customerGroupID)
+}
+
+func snapshotFromProduct(p domain.Product, v domain.ProductVariant, price domain.Price) map[string]interface{} {
+	data := p.Current
+	slug := data.Slug.Clone()
+	if slug.IsEmpty() {
+		key := strings.TrimSpace(p.Key)
+		if key == "" {
+			key = strings.ReplaceAll(strings.ToLower(data.Name.Get(domain.DefaultLocale)), " ", "-")
+		}
+		slug = domain.Localized(domain.DefaultLocale, key)
 	}
 	snap := map[string]interface{}{
 		"productKey":  p.Key,
-		"productName": p.Name,
-		"sku":         p.SKU,
+		"productName": data.Name.Clone(),
+		"sku":         v.SKU,
+		"variantId":   v.ID,
 		"productSlug": slug,
-		"priceCents":  p.PriceCents,
-		"currency":    p.Currency,
+		"priceId":     price.ID,
+		"priceCents":  price.Value.CentAmount,
+		"currency":    price.Value.CurrencyCode,
 	}
-	if len(p.Attributes) > 0 {
-		if images, ok := p.Attributes["images"]; ok {
-			snap["images"] = images
-		}
+	if price.Value.HighPrecision() {
+		snap["preciseAmount"] = price.Value.PreciseAmount
+		snap["fractionDigits"] = price.Value.FractionDigits
+	}
+	if price.Discounted != nil {
+		snap["discountedCents"] = price.Discounted.Value.CentAmount
+		snap["productDiscountId"] = price.Discounted.DiscountID
+	}
+	if len(v.Images) > 0 {
+		snap["images"] = v.Images
+	}
+	if len(v.Attributes) > 0 {
+		snap["attributes"] = v.Attributes
+	}
+	if p.ProductTypeID != "" {
+		snap["productTypeId"] = p.ProductTypeID
+	}
+	if p.TaxCategoryID != "" {
+		snap["taxCategoryId"] = p.TaxCategoryID
+	}
+	if len(data.CategoryIDs) > 0 {
+		snap["categoryIds"] = data.CategoryIDs
 	}
 	return snap
 }
+
+func taxSettingsOf(cart domain.Cart) cartrepo.TaxSettings {
+	return cartrepo.TaxSettings{
+		Country:            cart.Country,
+		ShippingAddress:    cart.ShippingAddress,
+		TaxMode:            defaultString(cart.TaxMode, domain.TaxModePlatform),
+		TaxRoundingMode:    defaultString(cart.TaxRoundingMode, domain.RoundingHalfEven),
+		TaxCalculationMode: defaultString(cart.TaxCalculationMode, domain.TaxCalculationLineItemLevel),
+	}
+}
+
+// validateTaxSettings accepts the Platform and Disabled tax modes; external
+// tax rates and amounts are not supported.
+func validateTaxSettings(in cartrepo.TaxSettings) error {
+	if in.Country != "" && len(in.Country) != 2 {
+		return fmt.Errorf("invalid country %q", in.Country)
+	}
+	if in.ShippingAddress != nil && len(in.ShippingAddress.Country) != 2 {
+		return errors.New("shipping address country must be a two-letter code")
+	}
+	switch in.TaxMode {
+	case domain.TaxModePlatform, domain.TaxModeDisabled:
+	default:
+		return fmt.Errorf("unsupported taxMode %q", in.TaxMode)
+	}
+	if !money.RoundingMode(in.TaxRoundingMode).Valid() {
+		return fmt.Errorf("unsupported taxRoundingMode %q", in.TaxRoundingMode)
+	}
+	switch in.TaxCalculationMode {
+	case domain.TaxCalculationLineItemLevel, domain.TaxCalculationUnitPriceLevel:
+	default:
+		return fmt.Errorf("unsupported taxCalculationMode %q", in.TaxCalculationMode)
+	}
+	return nil
+}
+
+func normalizeAddress(a *domain.CustomerAddress) *domain.CustomerAddress {
+	if a == nil {
+		return nil
+	}
+	copied := *a
+	copied.Country = strings.ToUpper(strings.TrimSpace(copied.Country))
+	copied.State = strings.TrimSpace(copied.State)
+	return &copied
+}
+
+func defaultString(s, def string) string {
+	if s = strings.TrimSpace(s); s != "" {
+		return s
+	}
+	return def
+}
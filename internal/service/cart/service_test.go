@@ -7,11 +7,13 @@ import (
 
 	"commercetools-replica/internal/domain"
 	cartrepo "commercetools-replica/internal/repository/cart"
+	cartdiscountsvc "commercetools-replica/internal/service/cartdiscount"
 )
 
 type stubRepo struct {
 	createCart        *domain.Cart
 	createErr         error
+	lastCreate        cartrepo.CreateCartInput
 	getByIDResults    []*domain.Cart
 	getByIDErr        error
 	getByIDCalls      int
@@ -20,9 +22,7 @@ type stubRepo struct {
 	addLineItemErr    error
 	changeLineItemErr error
 	lastAddCartID     string
-	lastAddProduct    domain.Product
-	lastAddQty        int
-	lastAddSnapshot   map[string]interface{}
+	lastAddInput      cartrepo.AddLineItemInput
 	lastChangeCartID  string
 	lastChangeLineID  string
 	lastChangeQty     int
@@ -30,9 +30,28 @@ type stubRepo struct {
 	lastStateCartID   string
 	lastStateValue    string
 	setStateErr       error
+	addedCodes        []string
+	addCodeErr        error
+	removedCodes      []string
+	removeCodeErr     error
+	codeUsesTotal     int
+	codeUsesCustomer  int
+	codeUsesOwner     string
+	directDiscounts   []domain.DirectDiscount
+	refusedGifts      []string
+	saved             []cartrepo.SaveTotalsInput
+	taxSettings       []cartrepo.TaxSettings
+	shippingInfos     []*domain.ShippingInfo
+	shipping          [][]domain.Shipping
+	itemAddresses     [][]domain.CustomerAddress
+	lineDetails       map[string]*domain.ItemShippingDetails
+	addedLines        []cartrepo.AddLineItemInput
+	committed         int
+	rolledBack        int
 }
 
-func (s *stubRepo) Create(_ context.Context, _ cartrepo.CreateCartInput) (*domain.Cart, error) {
+func (s *stubRepo) Create(_ context.Context, in cartrepo.CreateCartInput) (*domain.Cart, error) {
+	s.lastCreate = in
 	return s.createCart, s.createErr
 }
 
@@ -52,11 +71,11 @@ func (s *stubRepo) GetByID(_ context.Context, _, _ string) (*domain.Cart, error)
 	return res, nil
 }
 
-func (s *stubRepo) GetActiveByCustomer(_ context.Context, _, _ string) (*domain.Cart, error) {
+func (s *stubRepo) GetActiveByCustomer(_ context.Context, _, _, _ string) (*domain.Cart, error) {
 	return s.activeCart, s.activeErr
 }
 
-func (s *stubRepo) GetActiveByAnonymous(_ context.Context, _, _ string) (*domain.Cart, error) {
+func (s *stubRepo) GetActiveByAnonymous(_ context.Context, _, _, _ string) (*domain.Cart, error) {
 	return s.activeCart, s.activeErr
 }
 
@@ -64,15 +83,18 @@ func (s *stubRepo) AssignCustomerToAnonymous(_ context.Context, _, _, _ string)
 	return nil, nil
 }
 
-func (s *stubRepo) AddLineItem(_ context.Context, cartID string, product domain.Product, quantity int, snapshot map[string]interface{}) error {
+func (s *stubRepo) ListByCustomer(_ context.Context, _, _ string) ([]domain.Cart, error) {
+	return nil, nil
+}
+
+func (s *stubRepo) AddLineItem(_ context.Context, _, cartID string, in cartrepo.AddLineItemInput) error {
 	s.lastAddCartID = cartID
-	s.lastAddProduct = product
-	s.lastAddQty = quantity
-	s.lastAddSnapshot = snapshot
+	s.lastAddInput = in
+	s.addedLines = append(s.addedLines, in)
 	return s.addLineItemErr
 }
 
-func (s *stubRepo) ChangeLineItemQuantity(_ context.Context, cartID, lineItemID string, quantity int) error {
+func (s *stubRepo) ChangeLineItemQuantity(_ context.Context, _, cartID, lineItemID string, quantity int) error {
 	s.lastChangeCartID = cartID
 	s.lastChangeLineID = lineItemID
 	s.lastChangeQty = quantity
@@ -86,6 +108,73 @@ func (s *stubRepo) SetState(_ context.Context, projectID, cartID, state string)
 	return s.setStateErr
 }
 
+func (s *stubRepo) AddDiscountCode(_ context.Context, _, _, discountCodeID, _ string) error {
+	s.addedCodes = append(s.addedCodes, discountCodeID)
+	return s.addCodeErr
+}
+
+func (s *stubRepo) RemoveDiscountCode(_ context.Context, _, _, discountCodeID string) error {
+	s.removedCodes = append(s.removedCodes, discountCodeID)
+	return s.removeCodeErr
+}
+
+func (s *stubRepo) CountDiscountCodeUses(_ context.Context, _, _, customerID, anonymousID string) (int, int, error) {
+	s.codeUsesOwner = customerID + anonymousID
+	return s.codeUsesTotal, s.codeUsesCustomer, nil
+}
+
+func (s *stubRepo) SetDirectDiscounts(_ context.Context, _, _ string, discounts []domain.DirectDiscount) error {
+	s.directDiscounts = discounts
+	return nil
+}
+
+func (s *stubRepo) RefuseGift(_ context.Context, _, _, discountID string) error {
+	s.refusedGifts = append(s.refusedGifts, discountID)
+	return nil
+}
+
+func (s *stubRepo) SetTaxSettings(_ context.Context, _, _ string, in cartrepo.TaxSettings) error {
+	s.taxSettings = append(s.taxSettings, in)
+	return nil
+}
+
+func (s *stubRepo) SetShippingInfo(_ context.Context, _, _ string, info *domain.ShippingInfo) error {
+	s.shippingInfos = append(s.shippingInfos, info)
+	return nil
+}
+
+func (s *stubRepo) SetShipping(_ context.Context, _, _ string, shipping []domain.Shipping) error {
+	s.shipping = append(s.shipping, shipping)
+	return nil
+}
+
+func (s *stubRepo) SetItemShippingAddresses(_ context.Context, _, _ string, addresses []domain.CustomerAddress) error {
+	s.itemAddresses = append(s.itemAddresses, addresses)
+	return nil
+}
+
+func (s *stubRepo) SetLineItemShippingDetails(_ context.Context, _, _, lineItemID string, details *domain.ItemShippingDetails) error {
+	if s.lineDetails == nil {
+		s.lineDetails = map[string]*domain.ItemShippingDetails{}
+	}
+	s.lineDetails[lineItemID] = details
+	return nil
+}
+
+func (s *stubRepo) SaveTotals(_ context.Context, _, _ string, in cartrepo.SaveTotalsInput) error {
+	s.saved = append(s.saved, in)
+	return nil
+}
+
+func (s *stubRepo) InTx(_ context.Context, fn func(cartrepo.Repository) error) error {
+	if err := fn(s); err != nil {
+		s.rolledBack++
+		return err
+	}
+	s.committed++
+	return nil
+}
+
 type stubProductRepo struct {
 	product     *domain.Product
 	err         error
@@ -93,12 +182,36 @@ type stubProductRepo struct {
 	lastSKU     string
 }
 
+func (s *stubProductRepo) GetByID(_ context.Context, projectID, _ string) (*domain.Product, error) {
+	s.lastProject = projectID
+	return s.product, s.err
+}
+
 func (s *stubProductRepo) GetBySKU(_ context.Context, projectID, sku string) (*domain.Product, error) {
 	s.lastProject = projectID
 	s.lastSKU = sku
 	return s.product, s.err
 }
 
+// stubDiscounts discounts every price by a fixed amount.
+type stubDiscounts struct {
+	off int64
+	err error
+}
+
+func (s *stubDiscounts) ApplyToProducts(_ context.Context, _ string, products []domain.Product) error {
+	for i := range products {
+		for j := range products[i].Current.MasterVariant.Prices {
+			price := &products[i].Current.MasterVariant.Prices[j]
+			price.Discounted = &domain.DiscountedPrice{
+				Value:      domain.Money{CurrencyCode: price.Value.CurrencyCode, CentAmount: price.Value.CentAmount - s.off},
+				DiscountID: "disc-1",
+			}
+		}
+	}
+	return s.err
+}
+
 func strPtr(v string) *string {
 	return &v
 }
@@ -206,7 +319,7 @@ func TestServiceUpdateAddLineItemRepoError(t *testing.T) {
 		getByIDResults: []*domain.Cart{{ID: "cart", CustomerID: strPtr("cust")}},
 		addLineItemErr: errors.New("add failed"),
 	}
-	product := &domain.Product{ID: "p1", SKU: "sku", Name: "Prod", PriceCents: 100, Currency: "USD"}
+	product := publishedProduct("p1", "sku", 100, "USD")
 	svc := &Service{repo: repo, productRepo: &stubProductRepo{product: product}}
 	_, err := svc.Update(context.Background(), "proj", "cust", "cart", UpdateInput{
 		Actions: []UpdateAction{{Action: "addLineItem", SKU: "sku", Quantity: 2}},
@@ -220,7 +333,7 @@ func TestServiceUpdateAddLineItemSuccess(t *testing.T) {
 	initial := &domain.Cart{ID: "cart", CustomerID: strPtr("cust")}
 	updated := &domain.Cart{ID: "cart", CustomerID: strPtr("cust")}
 	repo := &stubRepo{getByIDResults: []*domain.Cart{initial, updated}}
-	product := &domain.Product{ID: "p1", SKU: "sku", Name: "Prod", PriceCents: 100, Currency: "USD"}
+	product := publishedProduct("p1", "sku", 100, "USD")
 	svc := &Service{repo: repo, productRepo: &stubProductRepo{product: product}}
 	got, err := svc.Update(context.Background(), "proj", "cust", "cart", UpdateInput{
 		Actions: []UpdateAction{{Action: "addLineItem", SKU: "sku", Quantity: 2}},
@@ -231,8 +344,72 @@ func TestServiceUpdateAddLineItemSuccess(t *testing.T) {
 	if got != updated {
 		t.Fatalf("unexpected cart: %+v", got)
 	}
-	if repo.lastAddCartID != "cart" || repo.lastAddQty != 2 || repo.lastAddProduct.ID != "p1" {
-		t.Fatalf("add line item not called as expected")
+	if repo.lastAddCartID != "cart" || repo.lastAddInput.Quantity != 2 || repo.lastAddInput.ProductID != "p1" || repo.lastAddInput.VariantID != 1 || repo.lastAddInput.UnitPriceCents != 100 {
+		t.Fatalf("add line item not called as expected: %+v", repo.lastAddInput)
+	}
+}
+
+type stubCustomers map[string]domain.Customer
+
+func (s stubCustomers) GetByID(_ context.Context, _, id string) (*domain.Customer, error) {
+	c, ok := s[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &c, nil
+}
+
+func TestServiceUpdateAddLineItemUsesCustomerGroupPrice(t *testing.T) {
+	product := publishedProduct("p1", "sku", 100, "EUR")
+	product.Current.MasterVariant.Prices = append(product.Current.MasterVariant.Prices,
+		domain.Price{ID: "price-2", Value: domain.Money{CurrencyCode: "EUR", CentAmount: 80}, CustomerGroupID: "wholesale"})
+	customers := stubCustomers{
+		"buyer":  {ID: "buyer", CustomerGroupID: "wholesale"},
+		"retail": {ID: "retail"},
+		"other":  {ID: "other", CustomerGroupID: "staff"},
+	}
+	cases := []struct {
+		customer string
+		want     int64
+	}{
+		{"buyer", 80},
+		{"retail", 100},
+		{"other", 100},
+	}
+	for _, tc := range cases {
+		cart := &domain.Cart{ID: "cart", CustomerID: strPtr(tc.customer), Currency: "EUR"}
+		repo := &stubRepo{getByIDResults: []*domain.Cart{cart, cart}}
+		svc := &Service{repo: repo, productRepo: &stubProductRepo{product: product}, customers: customers}
+		if _, err := svc.Update(context.Background(), "proj", tc.customer, "cart", UpdateInput{
+			Actions: []UpdateAction{{Action: "addLineItem", SKU: "sku", Quantity: 1}},
+		}); err != nil {
+			t.Fatalf("%s: %v", tc.customer, err)
+		}
+		if repo.lastAddInput.UnitPriceCents != tc.want {
+			t.Fatalf("%s: expected unit price %d, got %d", tc.customer, tc.want, repo.lastAddInput.UnitPriceCents)
+		}
+	}
+}
+
+func TestServiceUpdateAddLineItemAppliesProductDiscount(t *testing.T) {
+	repo := &stubRepo{getByIDResults: []*domain.Cart{{ID: "cart", CustomerID: strPtr("cust"), Currency: "USD"}}}
+	product := publishedProduct("p1", "sku", 100, "USD")
+	svc := New(repo, &stubProductRepo{product: product}, &stubDiscounts{off: 30}, nil, nil, nil, nil, nil, nil, nil)
+	if _, err := svc.Update(context.Background(), "proj", "cust", "cart", UpdateInput{
+		Actions: []UpdateAction{{Action: "addLineItem", SKU: "sku", Quantity: 2}},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	in := repo.lastAddInput
+	if in.UnitPriceCents != 70 || in.Snapshot["priceCents"] != int64(100) || in.Snapshot["discountedCents"] != int64(70) || in.Snapshot["productDiscountId"] != "disc-1" {
+		t.Fatalf("expected discounted unit price with original price in snapshot, got %+v", in)
+	}
+
+	svc = New(repo, &stubProductRepo{product: product}, &stubDiscounts{err: errors.New("boom")}, nil, nil, nil, nil, nil, nil, nil)
+	if _, err := svc.Update(context.Background(), "proj", "cust", "cart", UpdateInput{
+		Actions: []UpdateAction{{Action: "addLineItem", SKU: "sku", Quantity: 1}},
+	}); err == nil || err.Error() != "boom" {
+		t.Fatalf("expected discount error, got %v", err)
 	}
 }
 
@@ -287,6 +464,40 @@ func TestServiceUpdateChangeLineItemSuccess(t *testing.T) {
 	}
 }
 
+func TestServiceUpdateRollsBackWhenALaterActionFails(t *testing.T) {
+	repo := &stubRepo{
+		getByIDResults:    []*domain.Cart{{ID: "cart", CustomerID: strPtr("cust")}},
+		changeLineItemErr: errors.New("change failed"),
+	}
+	svc := &Service{repo: repo}
+	_, err := svc.Update(context.Background(), "proj", "cust", "cart", UpdateInput{
+		Actions: []UpdateAction{
+			{Action: "setDirectDiscounts"},
+			{Action: "changeLineItemQuantity", LineItemID: "line", Quantity: 2},
+		},
+	})
+	if err == nil || err.Error() != "change failed" {
+		t.Fatalf("expected repo error, got %v", err)
+	}
+	if repo.rolledBack != 1 || repo.committed != 0 {
+		t.Fatalf("expected the update to roll back, committed=%d rolled back=%d", repo.committed, repo.rolledBack)
+	}
+}
+
+func TestServiceUpdateCommitsActionsWithTotals(t *testing.T) {
+	initial := &domain.Cart{ID: "cart", CustomerID: strPtr("cust")}
+	repo := &stubRepo{getByIDResults: []*domain.Cart{initial, initial}}
+	svc := &Service{repo: repo}
+	if _, err := svc.Update(context.Background(), "proj", "cust", "cart", UpdateInput{
+		Actions: []UpdateAction{{Action: "changeLineItemQuantity", LineItemID: "line", Quantity: 3}},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.committed != 1 || len(repo.saved) != 1 {
+		t.Fatalf("expected totals saved in one committed update, committed=%d saved=%d", repo.committed, len(repo.saved))
+	}
+}
+
 func TestServiceDeleteCustomerOwnership(t *testing.T) {
 	repo := &stubRepo{getByIDResults: []*domain.Cart{{ID: "cart", CustomerID: strPtr("other")}}}
 	svc := &Service{repo: repo}
@@ -312,3 +523,268 @@ func TestServiceDeleteCustomerHappyPath(t *testing.T) {
 		t.Fatalf("unexpected SetState args: %s %s %s", repo.lastStateProject, repo.lastStateCartID, repo.lastStateValue)
 	}
 }
+
+func TestServiceUpdateAddLineItemRequiresPublishedPriceInCurrency(t *testing.T) {
+	repo := &stubRepo{getByIDResults: []*domain.Cart{{ID: "cart", CustomerID: strPtr("cust"), Currency: "EUR"}}}
+	product := publishedProduct("p1", "sku", 100, "USD")
+	svc := &Service{repo: repo, productRepo: &stubProductRepo{product: product}}
+	_, err := svc.Update(context.Background(), "proj", "cust", "cart", UpdateInput{
+		Actions: []UpdateAction{{Action: "addLineItem", SKU: "sku", Quantity: 1}},
+	})
+	if err == nil || err.Error() != "no price for currency EUR" {
+		t.Fatalf("expected currency error, got %v", err)
+	}
+
+	product.Published = false
+	_, err = svc.Update(context.Background(), "proj", "cust", "cart", UpdateInput{
+		Actions: []UpdateAction{{Action: "addLineItem", SKU: "sku", Quantity: 1}},
+	})
+	if err == nil || err.Error() != "product not published" {
+		t.Fatalf("expected unpublished error, got %v", err)
+	}
+}
+
+func publishedProduct(id, sku string, cents int64, currency string) *domain.Product {
+	data := domain.ProductData{
+		Name: domain.LocalizedString{"en": "Prod"},
+		MasterVariant: domain.ProductVariant{
+			ID:     1,
+			SKU:    sku,
+			Prices: []domain.Price{{ID: "price-1", Value: domain.Money{CurrencyCode: currency, CentAmount: cents}}},
+		},
+	}
+	return &domain.Product{ID: id, Version: 1, Published: true, Current: data, Staged: data}
+}
+
+type stubCartDiscounts struct {
+	discounts []domain.CartDiscount
+}
+
+func (s *stubCartDiscounts) ListPage(_ context.Context, _ string, _, _ int) ([]domain.CartDiscount, int, error) {
+	return s.discounts, len(s.discounts), nil
+}
+
+type stubDiscountCodes struct {
+	codes []domain.DiscountCode
+}
+
+func (s *stubDiscountCodes) Get(_ context.Context, _, id string) (*domain.DiscountCode, error) {
+	for i := range s.codes {
+		if s.codes[i].ID == id {
+			return &s.codes[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *stubDiscountCodes) GetByCode(_ context.Context, _, code string) (*domain.DiscountCode, error) {
+	for i := range s.codes {
+		if s.codes[i].Code == code {
+			return &s.codes[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func TestServiceUpdateAddDiscountCode(t *testing.T) {
+	one := 1
+	codes := &stubDiscountCodes{codes: []domain.DiscountCode{
+		{ID: "code-1", Code: "SAVE10", IsActive: true, CartDiscountIDs: []string{"d1"}, MaxApplicationsPerCustomer: &one},
+		{ID: "code-2", Code: "OFF", CartDiscountIDs: []string{"d1"}},
+	}}
+	discount := cartDiscount("d1", "0.5", tenPercent, allLines)
+	discount.RequiresDiscountCode = true
+	discounts := &stubCartDiscounts{discounts: []domain.CartDiscount{discount}}
+	add := func(repo *stubRepo, code string) error {
+		svc := New(repo, &stubProductRepo{}, nil, discounts, codes, nil, nil, nil, nil, nil)
+		_, err := svc.Update(context.Background(), "proj", "cust", "cart", UpdateInput{
+			Actions: []UpdateAction{{Action: "addDiscountCode", Code: code}},
+		})
+		return err
+	}
+
+	for code, want := range map[string]string{
+		"NOPE": `discount code "NOPE" not found`,
+		"OFF":  `discount code "OFF" is not active`,
+	} {
+		repo := &stubRepo{getByIDResults: []*domain.Cart{{ID: "cart", CustomerID: strPtr("cust")}}}
+		if err := add(repo, code); err == nil || err.Error() != want {
+			t.Fatalf("code %s: expected %q, got %v", code, want, err)
+		}
+	}
+
+	repo := &stubRepo{getByIDResults: []*domain.Cart{{ID: "cart", CustomerID: strPtr("cust")}}, codeUsesCustomer: 1}
+	if err := add(repo, "SAVE10"); err == nil || err.Error() != `discount code "SAVE10" reached its maximum number of applications for this customer` {
+		t.Fatalf("expected per-customer limit error, got %v", err)
+	}
+
+	repo = &stubRepo{getByIDResults: []*domain.Cart{{ID: "cart", AnonymousID: strPtr("anon")}}, codeUsesCustomer: 1}
+	svc := New(repo, &stubProductRepo{}, nil, discounts, codes, nil, nil, nil, nil, nil)
+	_, err := svc.UpdateAnonymous(context.Background(), "proj", "anon", "cart", UpdateInput{
+		Actions: []UpdateAction{{Action: "addDiscountCode", Code: "SAVE10"}},
+	})
+	if err == nil || err.Error() != `discount code "SAVE10" reached its maximum number of applications for this customer` {
+		t.Fatalf("expected per-customer limit error for an anonymous cart, got %v", err)
+	}
+	if repo.codeUsesOwner != "anon" {
+		t.Fatalf("expected uses counted for the anonymous id, got %q", repo.codeUsesOwner)
+	}
+
+	withCode := discountCart()
+	withCode.DiscountCodes = []domain.CartDiscountCode{{DiscountCodeID: "code-1", State: domain.DiscountCodeDoesNotMatchCart}}
+	initial := discountCart()
+	repo = &stubRepo{getByIDResults: []*domain.Cart{&initial, &withCode}}
+	if err := add(repo, "SAVE10"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.addedCodes) != 1 || repo.addedCodes[0] != "code-1" {
+		t.Fatalf("expected the code to be added, got %v", repo.addedCodes)
+	}
+	if len(repo.saved) != 1 {
+		t.Fatalf("expected one recalculation, got %d", len(repo.saved))
+	}
+	saved := repo.saved[0]
+	if saved.TotalCents != 4500 || saved.DiscountCodeStates["code-1"] != domain.DiscountCodeMatchesCart {
+		t.Fatalf("unexpected recalculation %+v", saved)
+	}
+	if got := saved.Lines[0].DiscountedPricePerQuantity; len(got) != 1 || got[0].Value.CentAmount != 900 || got[0].Quantity != 2 {
+		t.Fatalf("unexpected discounted price per quantity %+v", got)
+	}
+
+	repo = &stubRepo{getByIDResults: []*domain.Cart{&withCode}}
+	if err := add(repo, "SAVE10"); err == nil || err.Error() != `discount code "SAVE10" already added` {
+		t.Fatalf("expected duplicate error, got %v", err)
+	}
+}
+
+func TestServiceUpdateRemoveDiscountCodeAndDirectDiscounts(t *testing.T) {
+	withCode := discountCart()
+	withCode.DiscountCodes = []domain.CartDiscountCode{{DiscountCodeID: "code-1"}}
+	repo := &stubRepo{getByIDResults: []*domain.Cart{&withCode}}
+	svc := New(repo, &stubProductRepo{}, nil, &stubCartDiscounts{}, &stubDiscountCodes{}, nil, nil, nil, nil, nil)
+
+	_, err := svc.Update(context.Background(), "proj", "cust", "cart", UpdateInput{
+		Actions: []UpdateAction{{Action: "setDirectDiscounts", Discounts: []DirectDiscountDraft{{Value: cartdiscountsvc.ValueDraft{Type: "relative", Permyriad: 1000}, Target: totalPrice}}}},
+	})
+	if err == nil || err.Error() != "direct discounts cannot be set on carts with discount codes" {
+		t.Fatalf("expected conflict with discount codes, got %v", err)
+	}
+
+	if _, err := svc.Update(context.Background(), "proj", "cust", "cart", UpdateInput{
+		Actions: []UpdateAction{{Action: "removeDiscountCode", DiscountCode: &DiscountCodeReference{TypeID: "discount-code", ID: "code-1"}}},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.removedCodes) != 1 || repo.removedCodes[0] != "code-1" {
+		t.Fatalf("expected the code to be removed, got %v", repo.removedCodes)
+	}
+
+	plain := discountCart()
+	repo = &stubRepo{getByIDResults: []*domain.Cart{&plain}}
+	svc = New(repo, &stubProductRepo{}, nil, &stubCartDiscounts{}, &stubDiscountCodes{}, nil, nil, nil, nil, nil)
+	_, err = svc.Update(context.Background(), "proj", "cust", "cart", UpdateInput{
+		Actions: []UpdateAction{{Action: "setDirectDiscounts", Discounts: []DirectDiscountDraft{{Value: cartdiscountsvc.ValueDraft{Type: "relative", Permyriad: 1000}}}}},
+	})
+	if err == nil || err.Error() != "target required" {
+		t.Fatalf("expected target error, got %v", err)
+	}
+	if _, err := svc.Update(context.Background(), "proj", "cust", "cart", UpdateInput{
+		Actions: []UpdateAction{{Action: "setDirectDiscounts", Discounts: []DirectDiscountDraft{{Value: cartdiscountsvc.ValueDraft{Type: "relative", Permyriad: 1000}, Target: totalPrice}}}},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.directDiscounts) != 1 || repo.directDiscounts[0].Value.Permyriad != 1000 {
+		t.Fatalf("unexpected direct discounts %+v", repo.directDiscounts)
+	}
+}
+
+func TestServiceRecalculateManagesGiftLineItems(t *testing.T) {
+	giftDiscount := cartDiscount("gift", "0.5", domain.CartDiscountValue{Type: domain.CartDiscountGiftLineItem, ProductID: "p9", VariantID: 1}, nil)
+	discounts := &stubCartDiscounts{discounts: []domain.CartDiscount{giftDiscount}}
+	product := publishedProduct("p9", "mug", 700, "EUR")
+
+	plain := discountCart()
+	withGift := discountCart()
+	withGift.Lines = append(withGift.Lines, domain.CartLine{
+		ID: "g1", ProductID: "p9", VariantID: 1, Quantity: 1, UnitPriceCents: 700,
+		LineItemMode: domain.LineItemModeGiftLineItem,
+		Snapshot:     map[string]interface{}{"giftDiscountId": "gift", "currency": "EUR"},
+	})
+	repo := &stubRepo{getByIDResults: []*domain.Cart{&plain, &plain, &withGift}}
+	svc := New(repo, &stubProductRepo{product: product}, nil, discounts, nil, nil, nil, nil, nil, nil)
+	if _, err := svc.Update(context.Background(), "proj", "cust", "cart", UpdateInput{
+		Actions: []UpdateAction{{Action: "changeLineItemQuantity", LineItemID: "l1", Quantity: 2}},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.addedLines) != 1 || repo.addedLines[0].LineItemMode != domain.LineItemModeGiftLineItem || repo.addedLines[0].Snapshot["giftDiscountId"] != "gift" {
+		t.Fatalf("expected a gift line to be added, got %+v", repo.addedLines)
+	}
+	if saved := repo.saved[0]; saved.TotalCents != 5000 || saved.Lines[2].TotalCents != 0 {
+		t.Fatalf("expected the gift to be free, got %+v", saved)
+	}
+
+	repo = &stubRepo{getByIDResults: []*domain.Cart{&withGift}}
+	svc = New(repo, &stubProductRepo{product: product}, nil, discounts, nil, nil, nil, nil, nil, nil)
+	_, err := svc.Update(context.Background(), "proj", "cust", "cart", UpdateInput{
+		Actions: []UpdateAction{{Action: "changeLineItemQuantity", LineItemID: "g1", Quantity: 2}},
+	})
+	if err == nil || err.Error() != "quantity of gift line items cannot be changed" {
+		t.Fatalf("expected gift quantity error, got %v", err)
+	}
+	if _, err := svc.Update(context.Background(), "proj", "cust", "cart", UpdateInput{
+		Actions: []UpdateAction{{Action: "removeLineItem", LineItemID: "g1"}},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.refusedGifts) != 1 || repo.refusedGifts[0] != "gift" || repo.lastChangeQty != 0 {
+		t.Fatalf("expected the gift to be refused, got %v", repo.refusedGifts)
+	}
+}
+
+type stubTaxCategories map[string]domain.TaxCategory
+
+func (s stubTaxCategories) GetByID(_ context.Context, _, id string) (*domain.TaxCategory, error) {
+	tc, ok := s[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &tc, nil
+}
+
+func TestServiceUpdateTaxSettings(t *testing.T) {
+	cart := taxCart("DE", "")
+	cart.ShippingAddress = nil
+	repo := &stubRepo{getByIDResults: []*domain.Cart{&cart}}
+	svc := New(repo, &stubProductRepo{}, nil, nil, nil, nil, stubTaxCategories(taxCategories), nil, nil, nil)
+
+	_, err := svc.Update(context.Background(), "proj", "cust", "cart", UpdateInput{
+		Actions: []UpdateAction{{Action: "changeTaxMode", TaxMode: "External"}},
+	})
+	if err == nil || err.Error() != `unsupported taxMode "External"` {
+		t.Fatalf("expected unsupported tax mode, got %v", err)
+	}
+	_, err = svc.Update(context.Background(), "proj", "cust", "cart", UpdateInput{
+		Actions: []UpdateAction{{Action: "setShippingAddress", Address: &domain.CustomerAddress{Country: "Germany"}}},
+	})
+	if err == nil || err.Error() != "shipping address country must be a two-letter code" {
+		t.Fatalf("expected invalid country, got %v", err)
+	}
+
+	if _, err := svc.Update(context.Background(), "proj", "cust", "cart", UpdateInput{
+		Actions: []UpdateAction{
+			{Action: "setShippingAddress", Address: &domain.CustomerAddress{Country: "de", City: "Berlin"}},
+			{Action: "changeTaxRoundingMode", TaxRoundingMode: domain.RoundingHalfUp},
+		},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	last := repo.taxSettings[len(repo.taxSettings)-1]
+	if len(repo.taxSettings) != 2 || last.ShippingAddress.Country != "DE" || last.TaxRoundingMode != domain.RoundingHalfUp || last.TaxMode != domain.TaxModePlatform {
+		t.Fatalf("unexpected tax settings %+v", repo.taxSettings)
+	}
+	saved := repo.saved[len(repo.saved)-1]
+	if saved.TaxedPrice == nil || saved.TaxedPrice.TotalTax.CentAmount != 798 || saved.Lines[0].TaxRate == nil {
+		t.Fatalf("expected the cart to be taxed, got %+v", saved)
+	}
+}
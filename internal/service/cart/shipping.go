@@ -0,0 +1,412 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"commercetools-replica/internal/domain"
+	"commercetools-replica/internal/predicate"
+)
+
+// MatchingShippingMethods returns the shipping methods that can ship the cart:
+// those whose predicate matches it and that have a rate in the cart currency
+// for a zone of its shipping address. Each method only keeps the matching zone
+// rates and shipping rates.
+func (s *Service) MatchingShippingMethods(ctx context.Context, projectID, cartID string) ([]domain.ShippingMethod, error) {
+	cart, err := s.repo.GetByID(ctx, projectID, cartID)
+	if err != nil {
+		return nil, err
+	}
+	if s.shipping == nil {
+		return nil, nil
+	}
+	customer, err := s.customer(ctx, projectID, cart)
+	if err != nil {
+		return nil, err
+	}
+	zoneIDs, err := s.shippingZones(ctx, projectID, cart.ShippingAddress)
+	if err != nil {
+		return nil, err
+	}
+	methods, _, err := s.shipping.ListPage(ctx, projectID, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	env := predicate.CartFields(*cart, customer)
+	out := []domain.ShippingMethod{}
+	for _, m := range methods {
+		if shippingRate(m, *cart, zoneIDs, env) == nil {
+			continue
+		}
+		var rates []domain.ZoneRate
+		for _, zr := range m.ZoneRates {
+			if !containsID(zoneIDs, zr.ZoneID) {
+				continue
+			}
+			for _, r := range zr.ShippingRates {
+				if r.Price.CurrencyCode == cart.Currency {
+					rates = append(rates, domain.ZoneRate{ZoneID: zr.ZoneID, ShippingRates: []domain.ShippingRate{r}})
+				}
+			}
+		}
+		m.ZoneRates = rates
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// setShippingMethod sets the shipping info of the cart to the referenced
+// method, which has to match the cart; a nil reference removes it. The price is
+// filled in by recalculate.
+func (s *Service) setShippingMethod(ctx context.Context, projectID string, cart *domain.Cart, ref *ShippingMethodReference) error {
+	if ref == nil {
+		cart.ShippingInfo = nil
+		return s.repo.SetShippingInfo(ctx, cart.ProjectID, cart.ID, nil)
+	}
+	if cart.ShippingMode == domain.ShippingModeMultiple {
+		return errors.New("setShippingMethod is not supported in Multiple shipping mode; use addShippingMethod")
+	}
+	m, err := s.resolveShippingMethod(ctx, projectID, ref)
+	if err != nil {
+		return err
+	}
+	if cart.ShippingAddress == nil || cart.ShippingAddress.Country == "" {
+		return errors.New("shipping address required to set a shipping method")
+	}
+	info, err := s.matchingShippingInfo(ctx, projectID, cart, *m, cart.ShippingAddress)
+	if err != nil {
+		return err
+	}
+	cart.ShippingInfo = info
+	return s.repo.SetShippingInfo(ctx, cart.ProjectID, cart.ID, info)
+}
+
+// addShippingMethod adds a shipping to a cart in Multiple shipping mode. The
+// method has to match the cart and ship to the given address; line items are
+// assigned to the shipping by the shippingMethodKey of their targets.
+func (s *Service) addShippingMethod(ctx context.Context, projectID string, cart *domain.Cart, key string, ref *ShippingMethodReference, address *domain.CustomerAddress) error {
+	if cart.ShippingMode != domain.ShippingModeMultiple {
+		return errors.New("addShippingMethod requires the Multiple shipping mode")
+	}
+	if key == "" {
+		return errors.New("shippingKey required")
+	}
+	if cart.ShippingByKey(key) != nil {
+		return fmt.Errorf("shipping %q already exists", key)
+	}
+	if ref == nil {
+		return errors.New("shippingMethod required")
+	}
+	m, err := s.resolveShippingMethod(ctx, projectID, ref)
+	if err != nil {
+		return err
+	}
+	address = normalizeAddress(address)
+	if address == nil || len(address.Country) != 2 {
+		return errors.New("shipping address country must be a two-letter code")
+	}
+	info, err := s.matchingShippingInfo(ctx, projectID, cart, *m, address)
+	if err != nil {
+		return err
+	}
+	shipping := append(append([]domain.Shipping(nil), cart.Shipping...), domain.Shipping{ShippingKey: key, ShippingInfo: *info, ShippingAddress: *address})
+	if err := s.repo.SetShipping(ctx, cart.ProjectID, cart.ID, shipping); err != nil {
+		return err
+	}
+	cart.Shipping = shipping
+	return nil
+}
+
+// removeShippingMethod removes the shipping key from a cart in Multiple
+// shipping mode; line items still shipped with it have to be reassigned first.
+func (s *Service) removeShippingMethod(ctx context.Context, cart *domain.Cart, key string) error {
+	if key == "" {
+		return errors.New("shippingKey required")
+	}
+	if cart.ShippingByKey(key) == nil {
+		return fmt.Errorf("shipping %q not found", key)
+	}
+	for _, line := range cart.Lines {
+		if line.ShippingDetails != nil && line.ShippingDetails.QuantityFor(key) > 0 {
+			return fmt.Errorf("shipping %q is used by line item %s", key, line.ID)
+		}
+	}
+	var shipping []domain.Shipping
+	for _, sh := range cart.Shipping {
+		if sh.ShippingKey != key {
+			shipping = append(shipping, sh)
+		}
+	}
+	if err := s.repo.SetShipping(ctx, cart.ProjectID, cart.ID, shipping); err != nil {
+		return err
+	}
+	cart.Shipping = shipping
+	return nil
+}
+
+// addItemShippingAddress adds an address line items can be shipped to; it is
+// referenced by its key, which has to be unique within the cart.
+func (s *Service) addItemShippingAddress(ctx context.Context, cart *domain.Cart, address *domain.CustomerAddress) error {
+	address = normalizeAddress(address)
+	if address == nil {
+		return errors.New("address required")
+	}
+	address.Key = strings.TrimSpace(address.Key)
+	if address.Key == "" {
+		return errors.New("address key required")
+	}
+	if len(address.Country) != 2 {
+		return errors.New("address country must be a two-letter code")
+	}
+	if cart.ItemShippingAddress(address.Key) != nil {
+		return fmt.Errorf("item shipping address %q already exists", address.Key)
+	}
+	addresses := append(append([]domain.CustomerAddress(nil), cart.ItemShippingAddresses...), *address)
+	if err := s.repo.SetItemShippingAddresses(ctx, cart.ProjectID, cart.ID, addresses); err != nil {
+		return err
+	}
+	cart.ItemShippingAddresses = addresses
+	return nil
+}
+
+// removeItemShippingAddress removes the item shipping address key unless a
+// line item still ships to it.
+func (s *Service) removeItemShippingAddress(ctx context.Context, cart *domain.Cart, key string) error {
+	if key == "" {
+		return errors.New("addressKey required")
+	}
+	if cart.ItemShippingAddress(key) == nil {
+		return fmt.Errorf("item shipping address %q not found", key)
+	}
+	for _, line := range cart.Lines {
+		if line.ShippingDetails == nil {
+			continue
+		}
+		for _, t := range line.ShippingDetails.Targets {
+			if t.AddressKey == key {
+				return fmt.Errorf("item shipping address %q is used by line item %s", key, line.ID)
+			}
+		}
+	}
+	var addresses []domain.CustomerAddress
+	for _, a := range cart.ItemShippingAddresses {
+		if a.Key != key {
+			addresses = append(addresses, a)
+		}
+	}
+	if err := s.repo.SetItemShippingAddresses(ctx, cart.ProjectID, cart.ID, addresses); err != nil {
+		return err
+	}
+	cart.ItemShippingAddresses = addresses
+	return nil
+}
+
+// setLineItemShippingDetails splits a line item over item shipping addresses.
+// The target quantities have to add up to the line quantity, and in Multiple
+// shipping mode every target names the shipping it is sent with. Nil or empty
+// details remove the split.
+func (s *Service) setLineItemShippingDetails(ctx context.Context, cart *domain.Cart, lineID string, details *domain.ItemShippingDetails) error {
+	if lineID == "" {
+		return errors.New("lineItemId required")
+	}
+	line := findLine(cart, lineID)
+	if line == nil {
+		return domain.ErrNotFound
+	}
+	if details != nil && len(details.Targets) == 0 {
+		details = nil
+	}
+	if details != nil {
+		multiple := cart.ShippingMode == domain.ShippingModeMultiple
+		seen := map[domain.ItemShippingTarget]bool{}
+		for i, t := range details.Targets {
+			t.AddressKey = strings.TrimSpace(t.AddressKey)
+			t.ShippingMethodKey = strings.TrimSpace(t.ShippingMethodKey)
+			if cart.ItemShippingAddress(t.AddressKey) == nil {
+				return fmt.Errorf("item shipping address %q not found", t.AddressKey)
+			}
+			if t.Quantity <= 0 {
+				return errors.New("target quantity must be positive")
+			}
+			switch {
+			case multiple && t.ShippingMethodKey == "":
+				return errors.New("shippingMethodKey required in Multiple shipping mode")
+			case multiple && cart.ShippingByKey(t.ShippingMethodKey) == nil:
+				return fmt.Errorf("shipping %q not found", t.ShippingMethodKey)
+			case !multiple && t.ShippingMethodKey != "":
+				return errors.New("shippingMethodKey requires the Multiple shipping mode")
+			}
+			key := domain.ItemShippingTarget{AddressKey: t.AddressKey, ShippingMethodKey: t.ShippingMethodKey}
+			if seen[key] {
+				return fmt.Errorf("duplicate target for address %q", t.AddressKey)
+			}
+			seen[key] = true
+			details.Targets[i] = t
+		}
+		if n := details.Quantity(); n != line.Quantity {
+			return fmt.Errorf("target quantities add up to %d but the line item quantity is %d", n, line.Quantity)
+		}
+	}
+	if err := s.repo.SetLineItemShippingDetails(ctx, cart.ProjectID, cart.ID, lineID, details); err != nil {
+		return err
+	}
+	line.ShippingDetails = details
+	return nil
+}
+
+// resolveShippingMethod loads the shipping method ref points to by id or key.
+func (s *Service) resolveShippingMethod(ctx context.Context, projectID string, ref *ShippingMethodReference) (*domain.ShippingMethod, error) {
+	if s.shipping == nil {
+		return nil, errors.New("shipping methods unavailable")
+	}
+	id, key := strings.TrimSpace(ref.ID), strings.TrimSpace(ref.Key)
+	if id == "" && key == "" {
+		return nil, errors.New("shipping method id or key required")
+	}
+	var (
+		m   *domain.ShippingMethod
+		err error
+	)
+	if id != "" {
+		m, err = s.shipping.Get(ctx, projectID, id)
+	} else {
+		m, err = s.shipping.GetByKey(ctx, projectID, key)
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, errors.New("shipping method not found")
+		}
+		return nil, err
+	}
+	return m, nil
+}
+
+// matchingShippingInfo returns the shipping info for shipping cart to address
+// with m, or an error if m does not match the cart there.
+func (s *Service) matchingShippingInfo(ctx context.Context, projectID string, cart *domain.Cart, m domain.ShippingMethod, address *domain.CustomerAddress) (*domain.ShippingInfo, error) {
+	customer, err := s.customer(ctx, projectID, cart)
+	if err != nil {
+		return nil, err
+	}
+	zoneIDs, err := s.shippingZones(ctx, projectID, address)
+	if err != nil {
+		return nil, err
+	}
+	rate := shippingRate(m, *cart, zoneIDs, predicate.CartFields(*cart, customer))
+	if rate == nil {
+		return nil, fmt.Errorf("shipping method %q does not match the cart", m.Name)
+	}
+	return &domain.ShippingInfo{
+		ShippingMethodID:    m.ID,
+		ShippingMethodName:  m.Name,
+		Price:               rate.Price,
+		ShippingRate:        *rate,
+		TaxCategoryID:       m.TaxCategoryID,
+		ShippingMethodState: domain.ShippingMethodMatchesCart,
+	}, nil
+}
+
+// refreshShipping prices the cart's shipping info, or in Multiple shipping
+// mode each of its shippings, with the current rate of the shipping method.
+// Shipping methods that no longer match the cart keep their last price and are
+// marked DoesNotMatchCart; deleted ones are removed. Cart discounts and taxes
+// are left to calculate and applyTaxes.
+func (s *Service) refreshShipping(ctx context.Context, projectID string, cart *domain.Cart, customer *domain.Customer) error {
+	if s.shipping == nil {
+		return nil
+	}
+	if cart.ShippingInfo != nil {
+		var lineTotal int64
+		for _, line := range cart.Lines {
+			lineTotal += line.PriceTotal()
+		}
+		info, err := s.refreshShippingInfo(ctx, projectID, cart, customer, *cart.ShippingInfo, cart.ShippingAddress, lineTotal)
+		if err != nil {
+			return err
+		}
+		cart.ShippingInfo = info
+	}
+	var shipping []domain.Shipping
+	for _, sh := range cart.Shipping {
+		// Free shipping thresholds only count the units shipped with sh.
+		var lineTotal int64
+		for _, line := range cart.Lines {
+			if line.ShippingDetails == nil {
+				continue
+			}
+			line.Quantity = line.ShippingDetails.QuantityFor(sh.ShippingKey)
+			lineTotal += line.PriceTotal()
+		}
+		address := sh.ShippingAddress
+		info, err := s.refreshShippingInfo(ctx, projectID, cart, customer, sh.ShippingInfo, &address, lineTotal)
+		if err != nil {
+			return err
+		}
+		if info != nil {
+			sh.ShippingInfo = *info
+			shipping = append(shipping, sh)
+		}
+	}
+	cart.Shipping = shipping
+	return nil
+}
+
+// refreshShippingInfo reprices info for shipping lineTotal to address; it
+// returns nil if the shipping method was deleted.
+func (s *Service) refreshShippingInfo(ctx context.Context, projectID string, cart *domain.Cart, customer *domain.Customer, info domain.ShippingInfo, address *domain.CustomerAddress, lineTotal int64) (*domain.ShippingInfo, error) {
+	m, err := s.shipping.Get(ctx, projectID, info.ShippingMethodID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	zoneIDs, err := s.shippingZones(ctx, projectID, address)
+	if err != nil {
+		return nil, err
+	}
+	info.ShippingMethodName = m.Name
+	info.TaxCategoryID = m.TaxCategoryID
+	info.DiscountedPrice, info.TaxRate, info.TaxedPrice = nil, nil, nil
+	rate := shippingRate(*m, *cart, zoneIDs, predicate.CartFields(*cart, customer))
+	if rate == nil {
+		info.ShippingMethodState = domain.ShippingMethodDoesNotMatchCart
+		return &info, nil
+	}
+	info.ShippingRate = *rate
+	info.Price = rate.PriceFor(lineTotal)
+	info.ShippingMethodState = domain.ShippingMethodMatchesCart
+	return &info, nil
+}
+
+// shippingZones returns the ids of the zones covering the shipping address.
+func (s *Service) shippingZones(ctx context.Context, projectID string, address *domain.CustomerAddress) ([]string, error) {
+	if s.zones == nil || address == nil || address.Country == "" {
+		return nil, nil
+	}
+	zones, _, err := s.zones.ListPage(ctx, projectID, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return domain.ZonesContaining(zones, address.Country, address.State), nil
+}
+
+// shippingRate returns the rate m charges for cart, or nil if m's predicate
+// does not match the cart or m has no rate in the cart currency for zoneIDs.
+func shippingRate(m domain.ShippingMethod, cart domain.Cart, zoneIDs []string, env predicate.Env) *domain.ShippingRate {
+	if !matches(m.Predicate, env) {
+		return nil
+	}
+	return m.RateFor(zoneIDs, cart.Currency)
+}
+
+func containsID(ids []string, id string) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+package cart
+
+import (
+	"context"
+	"testing"
+
+	"commercetools-replica/internal/domain"
+)
+
+type stubShippingMethods []domain.ShippingMethod
+
+func (s stubShippingMethods) ListPage(_ context.Context, _ string, _, _ int) ([]domain.ShippingMethod, int, error) {
+	return s, len(s), nil
+}
+
+func (s stubShippingMethods) Get(_ context.Context, _, id string) (*domain.ShippingMethod, error) {
+	for _, m := range s {
+		if m.ID == id {
+			return &m, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s stubShippingMethods) GetByKey(_ context.Context, _, key string) (*domain.ShippingMethod, error) {
+	for _, m := range s {
+		if m.Key == key {
+			return &m, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+type stubZones []domain.Zone
+
+func (s stubZones) ListPage(_ context.Context, _ string, _, _ int) ([]domain.Zone, int, error) {
+	return s, len(s), nil
+}
+
+var shippingZones = stubZones{
+	{ID: "europe", Locations: []domain.Location{{Country: "DE"}, {Country: "AT"}}},
+	{ID: "usa", Locations: []domain.Location{{Country: "US"}}},
+}
+
+func shippingMethod(id string, freeAbove int64) domain.ShippingMethod {
+	rate := domain.ShippingRate{Price: domain.Money{CurrencyCode: "EUR", CentAmount: 500}}
+	if freeAbove > 0 {
+		rate.FreeAbove = &domain.Money{CurrencyCode: "EUR", CentAmount: freeAbove}
+	}
+	return domain.ShippingMethod{
+		ID:            id,
+		Key:           id,
+		Name:          id,
+		TaxCategoryID: "standard",
+		ZoneRates: []domain.ZoneRate{
+			{ZoneID: "europe", ShippingRates: []domain.ShippingRate{rate, {Price: domain.Money{CurrencyCode: "USD", CentAmount: 600}}}},
+			{ZoneID: "usa", ShippingRates: []domain.ShippingRate{{Price: domain.Money{CurrencyCode: "USD", CentAmount: 900}}}},
+		},
+	}
+}
+
+func TestCalculateShipping(t *testing.T) {
+	shippingTarget := &domain.CartDiscountTarget{Type: domain.CartDiscountTargetShipping}
+	halfOff := domain.CartDiscountValue{Type: domain.CartDiscountRelative, Permyriad: 5000}
+
+	cart := taxCart("DE", "")
+	cart.ShippingInfo = &domain.ShippingInfo{
+		ShippingMethodID:    "standard",
+		Price:               domain.Money{CurrencyCode: "EUR", CentAmount: 500},
+		TaxCategoryID:       "standard",
+		ShippingMethodState: domain.ShippingMethodMatchesCart,
+	}
+	calc := taxedCalculation(cart, cartDiscount("d1", "0.5", halfOff, shippingTarget))
+	if calc.totalCents != 5250 {
+		t.Fatalf("expected the discounted shipping in the total, got %d", calc.totalCents)
+	}
+	shipping := calc.shipping
+	if shipping.DiscountedPrice == nil || shipping.DiscountedPrice.Value.CentAmount != 250 || shipping.DiscountedPrice.IncludedDiscounts[0].DiscountID != "d1" {
+		t.Fatalf("unexpected discounted shipping price %+v", shipping.DiscountedPrice)
+	}
+	if shipping.TaxedPrice == nil || shipping.TaxedPrice.TotalNet.CentAmount != 210 || shipping.TaxedPrice.TotalGross.CentAmount != 250 {
+		t.Fatalf("unexpected shipping taxes %+v", shipping.TaxedPrice)
+	}
+	if calc.taxedPrice == nil || calc.taxedPrice.TotalGross.CentAmount != 5250 || calc.taxedPrice.TotalTax.CentAmount != 838 {
+		t.Fatalf("expected the cart taxes to include shipping, got %+v", calc.taxedPrice)
+	}
+
+	cart.ShippingInfo.ShippingMethodState = domain.ShippingMethodDoesNotMatchCart
+	calc = taxedCalculation(cart, cartDiscount("d1", "0.5", halfOff, shippingTarget))
+	if calc.totalCents != 5500 || calc.shipping.DiscountedPrice != nil || calc.taxedPrice != nil {
+		t.Fatalf("expected an untaxed, undiscounted shipping price, got %d %+v", calc.totalCents, calc.taxedPrice)
+	}
+}
+
+func TestServiceSetShippingMethod(t *testing.T) {
+	methods := stubShippingMethods{shippingMethod("standard", 0), shippingMethod("free", 5000)}
+	setShipping := func(cart *domain.Cart, ref *ShippingMethodReference) (*stubRepo, error) {
+		repo := &stubRepo{getByIDResults: []*domain.Cart{cart}}
+		svc := New(repo, &stubProductRepo{}, nil, nil, nil, nil, stubTaxCategories(taxCategories), methods, shippingZones, nil)
+		_, err := svc.Update(context.Background(), "proj", "cust", "cart", UpdateInput{
+			Actions: []UpdateAction{{Action: "setShippingMethod", ShippingMethod: ref}},
+		})
+		return repo, err
+	}
+
+	noAddress := taxCart("DE", "")
+	noAddress.ShippingAddress = nil
+	if _, err := setShipping(&noAddress, &ShippingMethodReference{Key: "standard"}); err == nil || err.Error() != "shipping address required to set a shipping method" {
+		t.Fatalf("expected a missing address error, got %v", err)
+	}
+	france := taxCart("FR", "")
+	if _, err := setShipping(&france, &ShippingMethodReference{Key: "standard"}); err == nil || err.Error() != `shipping method "standard" does not match the cart` {
+		t.Fatalf("expected no match, got %v", err)
+	}
+	if _, err := setShipping(&france, &ShippingMethodReference{ID: "express"}); err == nil || err.Error() != "shipping method not found" {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	germany := taxCart("DE", "")
+	repo, err := setShipping(&germany, &ShippingMethodReference{TypeID: "shipping-method", ID: "standard"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	saved := repo.saved[len(repo.saved)-1]
+	if len(repo.shippingInfos) != 1 || saved.ShippingInfo == nil || saved.ShippingInfo.Price.CentAmount != 500 || saved.TotalCents != 5500 {
+		t.Fatalf("expected shipping to be added to the total, got %+v", saved)
+	}
+	if saved.TaxedPrice == nil || saved.TaxedPrice.TotalGross.CentAmount != 5500 || saved.ShippingInfo.TaxRate == nil {
+		t.Fatalf("expected shipping to be taxed, got %+v", saved.TaxedPrice)
+	}
+
+	germany = taxCart("DE", "")
+	repo, err = setShipping(&germany, &ShippingMethodReference{Key: "free"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if saved := repo.saved[len(repo.saved)-1]; saved.ShippingInfo.Price.CentAmount != 0 || saved.TotalCents != 5000 {
+		t.Fatalf("expected free shipping above 50 EUR, got %+v", saved.ShippingInfo)
+	}
+
+	repo, err = setShipping(&germany, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if saved := repo.saved[len(repo.saved)-1]; repo.shippingInfos[0] != nil || saved.ShippingInfo != nil || saved.TotalCents != 5000 {
+		t.Fatalf("expected the shipping info to be removed, got %+v", saved.ShippingInfo)
+	}
+}
+
+func TestServiceMatchingShippingMethods(t *testing.T) {
+	premium := shippingMethod("premium", 0)
+	premium.Predicate = `totalPrice.centAmount > 10000`
+	usOnly := shippingMethod("us-only", 0)
+	usOnly.ZoneRates = usOnly.ZoneRates[1:]
+	cart := taxCart("AT", "")
+	repo := &stubRepo{getByIDResults: []*domain.Cart{&cart}}
+	svc := New(repo, &stubProductRepo{}, nil, nil, nil, nil, nil, stubShippingMethods{shippingMethod("standard", 0), premium, usOnly}, shippingZones, nil)
+
+	methods, err := svc.MatchingShippingMethods(context.Background(), "proj", "cart")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(methods) != 1 || methods[0].ID != "standard" {
+		t.Fatalf("expected only the standard method, got %+v", methods)
+	}
+	if rates := methods[0].ZoneRates; len(rates) != 1 || rates[0].ZoneID != "europe" || len(rates[0].ShippingRates) != 1 || rates[0].ShippingRates[0].Price.CurrencyCode != "EUR" {
+		t.Fatalf("expected only the matching EUR rate, got %+v", rates)
+	}
+}
+
+func TestServiceMultipleShipping(t *testing.T) {
+	methods := stubShippingMethods{shippingMethod("standard", 0), shippingMethod("free", 2500)}
+	update := func(cart domain.Cart, actions ...UpdateAction) (*stubRepo, error) {
+		repo := &stubRepo{getByIDResults: []*domain.Cart{&cart}}
+		svc := New(repo, &stubProductRepo{}, nil, nil, nil, nil, stubTaxCategories(taxCategories), methods, shippingZones, nil)
+		_, err := svc.Update(context.Background(), "proj", "cust", "cart", UpdateInput{Actions: actions})
+		return repo, err
+	}
+	multiple := func() domain.Cart {
+		cart := taxCart("DE", "")
+		cart.ShippingMode = domain.ShippingModeMultiple
+		return cart
+	}
+	addresses := []UpdateAction{
+		{Action: "addItemShippingAddress", Address: &domain.CustomerAddress{Key: "home", Country: "de"}},
+		{Action: "addItemShippingAddress", Address: &domain.CustomerAddress{Key: "friend", Country: "DE"}},
+		{Action: "addShippingMethod", ShippingKey: "to-home", ShippingMethod: &ShippingMethodReference{Key: "standard"}, ShippingAddress: &domain.CustomerAddress{Country: "DE"}},
+		{Action: "addShippingMethod", ShippingKey: "gift", ShippingMethod: &ShippingMethodReference{Key: "free"}, ShippingAddress: &domain.CustomerAddress{Country: "DE"}},
+	}
+	details := func(lineID string, targets ...domain.ItemShippingTarget) UpdateAction {
+		return UpdateAction{Action: "setLineItemShippingDetails", LineItemID: lineID, ShippingDetails: &domain.ItemShippingDetails{Targets: targets}}
+	}
+
+	repo, err := update(multiple(), append(addresses,
+		details("l1", domain.ItemShippingTarget{AddressKey: "home", Quantity: 2, ShippingMethodKey: "to-home"}),
+		details("l2", domain.ItemShippingTarget{AddressKey: "friend", Quantity: 1, ShippingMethodKey: "gift"}),
+	)...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	saved := repo.saved[len(repo.saved)-1]
+	if len(saved.Shipping) != 2 || saved.Shipping[0].ShippingInfo.Price.CentAmount != 500 || saved.Shipping[1].ShippingInfo.Price.CentAmount != 0 {
+		t.Fatalf("expected free shipping for the gift only, got %+v", saved.Shipping)
+	}
+	if saved.TotalCents != 5500 || saved.TaxedPrice == nil || saved.TaxedPrice.TotalGross.CentAmount != 5500 || saved.Shipping[0].ShippingInfo.TaxedPrice == nil {
+		t.Fatalf("expected both shippings in the taxed total, got %d %+v", saved.TotalCents, saved.TaxedPrice)
+	}
+	if d := repo.lineDetails["l1"]; d == nil || d.Quantity() != 2 || len(repo.itemAddresses[len(repo.itemAddresses)-1]) != 2 {
+		t.Fatalf("expected the line details and addresses to be stored, got %+v", d)
+	}
+
+	cases := []struct {
+		cart    domain.Cart
+		actions []UpdateAction
+		want    string
+	}{
+		{taxCart("DE", ""), addresses[2:3], "addShippingMethod requires the Multiple shipping mode"},
+		{multiple(), []UpdateAction{{Action: "setShippingMethod", ShippingMethod: &ShippingMethodReference{Key: "standard"}}}, "setShippingMethod is not supported in Multiple shipping mode; use addShippingMethod"},
+		{multiple(), []UpdateAction{addresses[0], addresses[0]}, `item shipping address "home" already exists`},
+		{multiple(), []UpdateAction{{Action: "addShippingMethod", ShippingKey: "fr", ShippingMethod: &ShippingMethodReference{Key: "standard"}, ShippingAddress: &domain.CustomerAddress{Country: "FR"}}}, `shipping method "standard" does not match the cart`},
+		{multiple(), append(addresses, details("l1", domain.ItemShippingTarget{AddressKey: "home", Quantity: 1, ShippingMethodKey: "to-home"})), "target quantities add up to 1 but the line item quantity is 2"},
+		{multiple(), append(addresses, details("l1", domain.ItemShippingTarget{AddressKey: "office", Quantity: 2, ShippingMethodKey: "to-home"})), `item shipping address "office" not found`},
+		{multiple(), append(addresses, details("l1", domain.ItemShippingTarget{AddressKey: "home", Quantity: 2})), "shippingMethodKey required in Multiple shipping mode"},
+		{multiple(), append(addresses,
+			details("l1", domain.ItemShippingTarget{AddressKey: "home", Quantity: 1, ShippingMethodKey: "gift"}, domain.ItemShippingTarget{AddressKey: "home", Quantity: 1, ShippingMethodKey: "gift"})), `duplicate target for address "home"`},
+		{multiple(), append(addresses,
+			details("l1", domain.ItemShippingTarget{AddressKey: "home", Quantity: 2, ShippingMethodKey: "to-home"}),
+			UpdateAction{Action: "removeItemShippingAddress", AddressKey: "home"}), `item shipping address "home" is used by line item l1`},
+	}
+	for _, tc := range cases {
+		if _, err := update(tc.cart, tc.actions...); err == nil || err.Error() != tc.want {
+			t.Fatalf("expected %q, got %v", tc.want, err)
+		}
+	}
+}
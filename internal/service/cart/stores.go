@@ -0,0 +1,52 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"commercetools-replica/internal/domain"
+)
+
+// store loads the store a cart is created in.
+func (s *Service) store(ctx context.Context, projectID, key string) (*domain.Store, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil, errors.New("store key required")
+	}
+	if s.stores == nil {
+		return nil, errors.New("store lookup unavailable")
+	}
+	st, err := s.stores.GetByKey(ctx, projectID, key)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, fmt.Errorf("store %s not found", key)
+		}
+		return nil, err
+	}
+	return st, nil
+}
+
+// checkAssortment rejects products the cart's store does not sell. Carts
+// without a store, or whose store was deleted, take every product.
+func (s *Service) checkAssortment(ctx context.Context, projectID, storeKey, productID string) error {
+	if storeKey == "" || s.stores == nil {
+		return nil
+	}
+	st, err := s.stores.GetByKey(ctx, projectID, storeKey)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	ids, err := s.stores.ProductIDs(ctx, projectID, st.ID)
+	if err != nil {
+		return err
+	}
+	if ids != nil && !ids[productID] {
+		return fmt.Errorf("product %s is not in the assortment of store %s", productID, st.Key)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package cart
+
+import (
+	"context"
+	"testing"
+
+	"commercetools-replica/internal/domain"
+)
+
+type stubStores struct {
+	stores   []domain.Store
+	products map[string]map[string]bool
+}
+
+func (s stubStores) GetByKey(_ context.Context, _, key string) (*domain.Store, error) {
+	for i := range s.stores {
+		if s.stores[i].Key == key {
+			return &s.stores[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s stubStores) ProductIDs(_ context.Context, _, storeID string) (map[string]bool, error) {
+	return s.products[storeID], nil
+}
+
+func TestServiceStoreCarts(t *testing.T) {
+	stores := stubStores{
+		stores: []domain.Store{
+			{ID: "st-eu", Key: "eu", Countries: []string{"DE", "AT"}},
+			{ID: "st-uk", Key: "uk", Countries: []string{"GB"}},
+		},
+		products: map[string]map[string]bool{"st-uk": {"p2": true}},
+	}
+	repo := &stubRepo{createCart: &domain.Cart{ID: "cart"}}
+	svc := New(repo, &stubProductRepo{product: publishedProduct("p1", "sku", 100, "EUR")}, nil, nil, nil, nil, nil, nil, nil, stores)
+	ctx := context.Background()
+
+	if _, err := svc.Create(ctx, "proj", CreateInput{Currency: "EUR", Country: "de", Store: &StoreReference{Key: "eu"}}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if repo.lastCreate.StoreKey != "eu" {
+		t.Fatalf("expected the store key to be stored, got %+v", repo.lastCreate)
+	}
+	cases := []struct {
+		in   CreateInput
+		want string
+	}{
+		{CreateInput{Currency: "EUR", Country: "FR", Store: &StoreReference{Key: "eu"}}, "country FR is not a country of store eu"},
+		{CreateInput{Currency: "EUR", Store: &StoreReference{Key: "us"}}, "store us not found"},
+		{CreateInput{Currency: "EUR", Store: &StoreReference{}}, "store key required"},
+	}
+	for _, tc := range cases {
+		if _, err := svc.Create(ctx, "proj", tc.in); err == nil || err.Error() != tc.want {
+			t.Fatalf("expected %q, got %v", tc.want, err)
+		}
+	}
+
+	add := UpdateInput{Actions: []UpdateAction{{Action: "addLineItem", SKU: "sku", Quantity: 1}}}
+	repo.getByIDResults = []*domain.Cart{{ID: "cart", CustomerID: strPtr("cust"), Currency: "EUR", State: "active", StoreKey: "uk"}}
+	if _, err := svc.Update(ctx, "proj", "cust", "cart", add); err == nil || err.Error() != "product p1 is not in the assortment of store uk" {
+		t.Fatalf("expected assortment error, got %v", err)
+	}
+	repo.getByIDResults = []*domain.Cart{{ID: "cart", CustomerID: strPtr("cust"), Currency: "EUR", State: "active", StoreKey: "eu"}}
+	if _, err := svc.Update(ctx, "proj", "cust", "cart", add); err != nil {
+		t.Fatalf("expected a store without product selections to sell every product, got %v", err)
+	}
+}
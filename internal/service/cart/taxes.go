@@ -0,0 +1,232 @@
+package cart
+
+import (
+	"math/big"
+
+	"commercetools-replica/internal/domain"
+	"commercetools-replica/internal/money"
+)
+
+// applyTaxes sets the tax rate and taxed price of every line item, of the
+// shipping and of the cart. Lines are taxed with the rate their product's tax
+// category has for the shipping address country (and state), shipping with the
+// rate of its shipping method's tax category; shippings in Multiple shipping
+// mode use their own address. The cart only gets a taxed price once every line
+// and shipping has a rate; the discount on the total price is spread over the
+// lines in proportion to their totals first.
+func (calc *calculation) applyTaxes(cart domain.Cart, categories map[string]domain.TaxCategory) {
+	if cart.TaxMode == domain.TaxModeDisabled || cart.ShippingAddress == nil || cart.ShippingAddress.Country == "" {
+		return
+	}
+	rounding := money.RoundingMode(cart.TaxRoundingMode)
+	unitLevel := cart.TaxCalculationMode == domain.TaxCalculationUnitPriceLevel
+
+	shares := calc.totalDiscountShares(cart)
+	cartTaxed := &domain.TaxedPrice{
+		TotalNet:   domain.Money{CurrencyCode: cart.Currency},
+		TotalGross: domain.Money{CurrencyCode: cart.Currency},
+		TotalTax:   domain.Money{CurrencyCode: cart.Currency},
+	}
+	complete := true
+	for _, line := range cart.Lines {
+		st := calc.lines[line.ID]
+		categoryID, _ := line.Snapshot["taxCategoryId"].(string)
+		category, ok := categories[categoryID]
+		if !ok {
+			complete = false
+			continue
+		}
+		rate := category.RateFor(cart.ShippingAddress.Country, cart.ShippingAddress.State)
+		if rate == nil {
+			complete = false
+			continue
+		}
+		copied := *rate
+		st.taxRate = &copied
+		currency := currencyOf(cart, line)
+
+		var lineTaxed domain.TaxedPrice
+		if unitLevel {
+			unit := taxAmounts(money.Cents(currency, st.unit), *rate, rounding)
+			lineTaxed = unit.times(int64(line.Quantity))
+		} else {
+			lineTaxed = taxAmounts(money.Cents(currency, st.total(line.Quantity)), *rate, rounding).taxedPrice()
+		}
+		st.taxedPrice = withCurrency(lineTaxed, currency)
+
+		// The share of the discount on the total price is always taxed as a
+		// whole and taken off the line's amounts.
+		cartLine := lineTaxed
+		if share := shares[line.ID]; share > 0 {
+			discount := taxAmounts(money.Cents(currency, share), *rate, rounding).taxedPrice()
+			cartLine = subtractTaxed(lineTaxed, discount)
+		}
+		cartTaxed.TotalNet.CentAmount += cartLine.TotalNet.CentAmount
+		cartTaxed.TotalGross.CentAmount += cartLine.TotalGross.CentAmount
+		cartTaxed.TotalTax.CentAmount += cartLine.TotalTax.CentAmount
+		cartTaxed.TaxPortions = addPortions(cartTaxed.TaxPortions, cartLine.TaxPortions)
+	}
+	taxShipping := func(shipping *domain.ShippingInfo, address domain.CustomerAddress) {
+		var rate *domain.TaxRate
+		if category, ok := categories[shipping.TaxCategoryID]; ok && shipping.ShippingMethodState == domain.ShippingMethodMatchesCart {
+			rate = category.RateFor(address.Country, address.State)
+		}
+		if rate == nil {
+			complete = false
+			return
+		}
+		copied := *rate
+		shipping.TaxRate = &copied
+		taxed := taxAmounts(money.Cents(cart.Currency, shipping.Cost().CentAmount), *rate, rounding).taxedPrice()
+		shipping.TaxedPrice = withCurrency(taxed, cart.Currency)
+		cartTaxed.TotalNet.CentAmount += taxed.TotalNet.CentAmount
+		cartTaxed.TotalGross.CentAmount += taxed.TotalGross.CentAmount
+		cartTaxed.TotalTax.CentAmount += taxed.TotalTax.CentAmount
+		cartTaxed.TaxPortions = addPortions(cartTaxed.TaxPortions, taxed.TaxPortions)
+	}
+	if calc.shipping != nil {
+		taxShipping(calc.shipping, *cart.ShippingAddress)
+	}
+	for i := range calc.multiShipping {
+		taxShipping(&calc.multiShipping[i].ShippingInfo, calc.multiShipping[i].ShippingAddress)
+	}
+	if !complete {
+		return
+	}
+	calc.taxedPrice = withCurrency(*cartTaxed, cart.Currency)
+}
+
+// totalDiscountShares splits the discount on the total price over the lines in
+// proportion to their totals; cents left over by rounding go to the first lines.
+func (calc *calculation) totalDiscountShares(cart domain.Cart) map[string]int64 {
+	shares := map[string]int64{}
+	if calc.discountOnTotal == nil {
+		return shares
+	}
+	off := calc.discountOnTotal.DiscountedAmount.CentAmount
+	var base int64
+	for _, line := range cart.Lines {
+		base += calc.lines[line.ID].total(line.Quantity)
+	}
+	if base <= 0 {
+		return shares
+	}
+	rest := off
+	for _, line := range cart.Lines {
+		total := calc.lines[line.ID].total(line.Quantity)
+		share := new(big.Int).Div(new(big.Int).Mul(big.NewInt(total), big.NewInt(off)), big.NewInt(base)).Int64()
+		shares[line.ID] = share
+		rest -= share
+	}
+	for _, line := range cart.Lines {
+		if rest <= 0 {
+			break
+		}
+		if calc.lines[line.ID].unit > 0 {
+			shares[line.ID]++
+			rest--
+		}
+	}
+	return shares
+}
+
+// taxed holds the net, gross and tax portions of one amount, in cents.
+type taxed struct {
+	net, gross int64
+	portions   []domain.TaxPortion
+}
+
+// taxAmounts taxes amount with rate: prices that include the tax are gross
+// amounts, others net amounts. Rates with sub rates get one portion per sub
+// rate, the last one taking the cents lost to rounding.
+func taxAmounts(amount money.Amount, rate domain.TaxRate, rounding money.RoundingMode) taxed {
+	var out taxed
+	factor := money.Decimal(rate.Amount)
+	if rate.IncludedInPrice {
+		out.gross = amount.CentAmount(rounding)
+		out.net = amount.QuoRat(new(big.Rat).Add(big.NewRat(1, 1), factor)).CentAmount(rounding)
+	} else {
+		out.net = amount.CentAmount(rounding)
+		out.gross = out.net + amount.MulRat(factor).CentAmount(rounding)
+	}
+	tax := out.gross - out.net
+	if len(rate.SubRates) == 0 {
+		out.portions = []domain.TaxPortion{{Name: rate.Name, Rate: rate.Amount, Amount: domain.Money{CentAmount: tax}}}
+		return out
+	}
+	rest := tax
+	for i, sub := range rate.SubRates {
+		cents := rest
+		if i < len(rate.SubRates)-1 && rate.Amount > 0 {
+			share := new(big.Rat).Quo(money.Decimal(sub.Amount), factor)
+			cents = money.Cents(amount.Currency(), tax).MulRat(share).CentAmount(rounding)
+		}
+		rest -= cents
+		out.portions = append(out.portions, domain.TaxPortion{Name: sub.Name, Rate: sub.Amount, Amount: domain.Money{CentAmount: cents}})
+	}
+	return out
+}
+
+func (t taxed) taxedPrice() domain.TaxedPrice {
+	return t.times(1)
+}
+
+// times multiplies the amounts of one unit by quantity.
+func (t taxed) times(quantity int64) domain.TaxedPrice {
+	out := domain.TaxedPrice{
+		TotalNet:   domain.Money{CentAmount: t.net * quantity},
+		TotalGross: domain.Money{CentAmount: t.gross * quantity},
+		TotalTax:   domain.Money{CentAmount: (t.gross - t.net) * quantity},
+	}
+	for _, p := range t.portions {
+		p.Amount.CentAmount *= quantity
+		out.TaxPortions = append(out.TaxPortions, p)
+	}
+	return out
+}
+
+func subtractTaxed(a, b domain.TaxedPrice) domain.TaxedPrice {
+	out := domain.TaxedPrice{
+		TotalNet:   domain.Money{CentAmount: a.TotalNet.CentAmount - b.TotalNet.CentAmount},
+		TotalGross: domain.Money{CentAmount: a.TotalGross.CentAmount - b.TotalGross.CentAmount},
+		TotalTax:   domain.Money{CentAmount: a.TotalTax.CentAmount - b.TotalTax.CentAmount},
+	}
+	out.TaxPortions = append(out.TaxPortions, a.TaxPortions...)
+	for i := range out.TaxPortions {
+		if i < len(b.TaxPortions) {
+			out.TaxPortions[i].Amount.CentAmount -= b.TaxPortions[i].Amount.CentAmount
+		}
+	}
+	return out
+}
+
+// addPortions adds more to portions, merging portions of the same name and rate.
+func addPortions(portions, more []domain.TaxPortion) []domain.TaxPortion {
+	for _, p := range more {
+		merged := false
+		for i := range portions {
+			if portions[i].Name == p.Name && portions[i].Rate == p.Rate {
+				portions[i].Amount.CentAmount += p.Amount.CentAmount
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			portions = append(portions, p)
+		}
+	}
+	return portions
+}
+
+func withCurrency(t domain.TaxedPrice, currency string) *domain.TaxedPrice {
+	t.TotalNet.CurrencyCode = currency
+	t.TotalGross.CurrencyCode = currency
+	t.TotalTax.CurrencyCode = currency
+	portions := make([]domain.TaxPortion, len(t.TaxPortions))
+	for i, p := range t.TaxPortions {
+		p.Amount.CurrencyCode = currency
+		portions[i] = p
+	}
+	t.TaxPortions = portions
+	return &t
+}
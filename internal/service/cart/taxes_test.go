@@ -0,0 +1,168 @@
+package cart
+
+import (
+	"testing"
+	"time"
+
+	"commercetools-replica/internal/domain"
+)
+
+var taxCategories = map[string]domain.TaxCategory{
+	"standard": {ID: "standard", Rates: []domain.TaxRate{
+		{Name: "19% MwSt", Amount: 0.19, IncludedInPrice: true, Country: "DE"},
+		{Name: "CA", Amount: 0.0725, Country: "US", State: "CA", SubRates: []domain.TaxSubRate{
+			{Name: "state", Amount: 0.06},
+			{Name: "county", Amount: 0.0125},
+		}},
+		{Name: "US", Amount: 0.0725, Country: "US"},
+	}},
+}
+
+// taxCart is discountCart with both lines in the standard tax category.
+func taxCart(country, state string) domain.Cart {
+	cart := discountCart()
+	for _, line := range cart.Lines {
+		line.Snapshot["taxCategoryId"] = "standard"
+	}
+	cart.ShippingAddress = &domain.CustomerAddress{Country: country, State: state}
+	cart.TaxMode = domain.TaxModePlatform
+	cart.TaxRoundingMode = domain.RoundingHalfEven
+	cart.TaxCalculationMode = domain.TaxCalculationLineItemLevel
+	return cart
+}
+
+func taxedCalculation(cart domain.Cart, discounts ...domain.CartDiscount) calculation {
+	calc := calculate(cart, nil, discounts, nil, time.Now())
+	calc.applyTaxes(cart, taxCategories)
+	return calc
+}
+
+func TestApplyTaxes(t *testing.T) {
+	type amounts struct{ net, gross, tax int64 }
+	tests := []struct {
+		name      string
+		cart      func() domain.Cart
+		discounts []domain.CartDiscount
+		lines     map[string]amounts
+		total     *amounts
+	}{
+		{
+			name:  "included in price",
+			cart:  func() domain.Cart { return taxCart("DE", "") },
+			lines: map[string]amounts{"l1": {1681, 2000, 319}, "l2": {2521, 3000, 479}},
+			total: &amounts{4202, 5000, 798},
+		},
+		{
+			name:  "added to the price, ties to even",
+			cart:  func() domain.Cart { return taxCart("US", "NY") },
+			lines: map[string]amounts{"l1": {2000, 2145, 145}, "l2": {3000, 3218, 218}},
+			total: &amounts{5000, 5363, 363},
+		},
+		{
+			name: "half down",
+			cart: func() domain.Cart {
+				cart := taxCart("US", "NY")
+				cart.TaxRoundingMode = domain.RoundingHalfDown
+				return cart
+			},
+			lines: map[string]amounts{"l1": {2000, 2145, 145}, "l2": {3000, 3217, 217}},
+			total: &amounts{5000, 5362, 362},
+		},
+		{
+			name: "unit price level",
+			cart: func() domain.Cart {
+				cart := taxCart("DE", "")
+				cart.TaxCalculationMode = domain.TaxCalculationUnitPriceLevel
+				return cart
+			},
+			lines: map[string]amounts{"l1": {1680, 2000, 320}, "l2": {2521, 3000, 479}},
+			total: &amounts{4201, 5000, 799},
+		},
+		{
+			name:      "discount on the total price is spread over the lines",
+			cart:      func() domain.Cart { return taxCart("DE", "") },
+			discounts: []domain.CartDiscount{cartDiscount("d1", "0.5", tenPercent, totalPrice)},
+			lines:     map[string]amounts{"l1": {1681, 2000, 319}, "l2": {2521, 3000, 479}},
+			total:     &amounts{3782, 4500, 718},
+		},
+		{
+			name: "line without tax category",
+			cart: func() domain.Cart {
+				cart := taxCart("DE", "")
+				delete(cart.Lines[1].Snapshot, "taxCategoryId")
+				return cart
+			},
+			lines: map[string]amounts{"l1": {1681, 2000, 319}},
+		},
+		{
+			name:  "country without rate",
+			cart:  func() domain.Cart { return taxCart("FR", "") },
+			lines: map[string]amounts{},
+		},
+		{
+			name: "tax mode disabled",
+			cart: func() domain.Cart {
+				cart := taxCart("DE", "")
+				cart.TaxMode = domain.TaxModeDisabled
+				return cart
+			},
+			lines: map[string]amounts{},
+		},
+		{
+			name: "no shipping address",
+			cart: func() domain.Cart {
+				cart := taxCart("DE", "")
+				cart.ShippingAddress = nil
+				return cart
+			},
+			lines: map[string]amounts{},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			calc := taxedCalculation(tc.cart(), tc.discounts...)
+			for id, st := range calc.lines {
+				want, ok := tc.lines[id]
+				if !ok {
+					if st.taxRate != nil || st.taxedPrice != nil {
+						t.Fatalf("line %s: expected no taxes, got %+v", id, st.taxedPrice)
+					}
+					continue
+				}
+				got := st.taxedPrice
+				if st.taxRate == nil || got == nil || got.TotalNet.CentAmount != want.net || got.TotalGross.CentAmount != want.gross || got.TotalTax.CentAmount != want.tax {
+					t.Fatalf("line %s: expected %+v, got %+v", id, want, got)
+				}
+			}
+			if tc.total == nil {
+				if calc.taxedPrice != nil {
+					t.Fatalf("expected no cart taxed price, got %+v", calc.taxedPrice)
+				}
+				return
+			}
+			got := calc.taxedPrice
+			if got == nil || got.TotalNet.CentAmount != tc.total.net || got.TotalGross.CentAmount != tc.total.gross || got.TotalTax.CentAmount != tc.total.tax {
+				t.Fatalf("expected cart %+v, got %+v", *tc.total, got)
+			}
+			if got.TotalGross.CurrencyCode != "EUR" {
+				t.Fatalf("expected EUR amounts, got %+v", got)
+			}
+		})
+	}
+}
+
+func TestApplyTaxesStateRateWithSubRates(t *testing.T) {
+	calc := taxedCalculation(taxCart("US", "CA"))
+	st := calc.lines["l2"]
+	if st.taxRate == nil || st.taxRate.Name != "CA" {
+		t.Fatalf("expected the CA rate, got %+v", st.taxRate)
+	}
+	portions := st.taxedPrice.TaxPortions
+	if len(portions) != 2 || portions[0].Name != "state" || portions[0].Amount.CentAmount != 180 || portions[1].Amount.CentAmount != 38 {
+		t.Fatalf("unexpected portions %+v", portions)
+	}
+	cartPortions := calc.taxedPrice.TaxPortions
+	if len(cartPortions) != 2 || cartPortions[0].Amount.CentAmount+cartPortions[1].Amount.CentAmount != calc.taxedPrice.TotalTax.CentAmount {
+		t.Fatalf("expected cart portions to add up to the tax, got %+v", calc.taxedPrice)
+	}
+}
@@ -0,0 +1,374 @@
+package cartdiscount
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"commercetools-replica/internal/domain"
+	"commercetools-replica/internal/predicate"
+	cartdiscountrepo "commercetools-replica/internal/repository/cartdiscount"
+	"commercetools-replica/internal/service/updateaction"
+)
+
+type Service struct {
+	repo cartdiscountrepo.Repository
+}
+
+func New(repo cartdiscountrepo.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// ListPage returns one page of cart discounts, highest sortOrder first; limit 0 means no limit.
+func (s *Service) ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.CartDiscount, int, error) {
+	return s.repo.List(ctx, projectID, limit, offset)
+}
+
+func (s *Service) Get(ctx context.Context, projectID, id string) (*domain.CartDiscount, error) {
+	return s.repo.GetByID(ctx, projectID, id)
+}
+
+func (s *Service) GetByKey(ctx context.Context, projectID, key string) (*domain.CartDiscount, error) {
+	return s.repo.GetByKey(ctx, projectID, key)
+}
+
+// ValueDraft is a cart discount value as sent by clients; gift values reference
+// their product as {"typeId": "product", "id": ...}.
+type ValueDraft struct {
+	Type      string         `json:"type"`
+	Permyriad int            `json:"permyriad,omitempty"`
+	Money     []domain.Money `json:"money,omitempty"`
+	Product   *struct {
+		ID string `json:"id"`
+	} `json:"product,omitempty"`
+	VariantID int `json:"variantId,omitempty"`
+}
+
+// ToValue converts the draft, dropping the fields that do not belong to its type.
+func (v ValueDraft) ToValue() domain.CartDiscountValue {
+	out := domain.CartDiscountValue{Type: strings.TrimSpace(v.Type)}
+	switch out.Type {
+	case domain.CartDiscountRelative:
+		out.Permyriad = v.Permyriad
+	case domain.CartDiscountAbsolute, domain.CartDiscountFixed:
+		for _, m := range v.Money {
+			out.Money = append(out.Money, domain.Money{CurrencyCode: strings.ToUpper(strings.TrimSpace(m.CurrencyCode)), CentAmount: m.CentAmount})
+		}
+	case domain.CartDiscountGiftLineItem:
+		if v.Product != nil {
+			out.ProductID = strings.TrimSpace(v.Product.ID)
+		}
+		out.VariantID = v.VariantID
+	}
+	return out
+}
+
+type CartDiscountDraft struct {
+	Key           string                     `json:"key,omitempty"`
+	Name          domain.LocalizedString     `json:"name"`
+	Description   domain.LocalizedString     `json:"description,omitempty"`
+	Value         ValueDraft                 `json:"value"`
+	CartPredicate string                     `json:"cartPredicate"`
+	Target        *domain.CartDiscountTarget `json:"target,omitempty"`
+	SortOrder     string                     `json:"sortOrder"`
+	// IsActive defaults to true like in commercetools.
+	IsActive             *bool      `json:"isActive,omitempty"`
+	ValidFrom            *time.Time `json:"validFrom,omitempty"`
+	ValidUntil           *time.Time `json:"validUntil,omitempty"`
+	RequiresDiscountCode bool       `json:"requiresDiscountCode,omitempty"`
+	StackingMode         string     `json:"stackingMode,omitempty"`
+}
+
+func (s *Service) Create(ctx context.Context, projectID string, draft CartDiscountDraft) (*domain.CartDiscount, error) {
+	active := true
+	if draft.IsActive != nil {
+		active = *draft.IsActive
+	}
+	d := domain.CartDiscount{
+		ProjectID:            projectID,
+		Key:                  strings.TrimSpace(draft.Key),
+		Name:                 draft.Name.Clone(),
+		Description:          draft.Description.Clone(),
+		Value:                draft.Value.ToValue(),
+		CartPredicate:        strings.TrimSpace(draft.CartPredicate),
+		Target:               normalizeTarget(draft.Target),
+		SortOrder:            strings.TrimSpace(draft.SortOrder),
+		IsActive:             active,
+		ValidFrom:            draft.ValidFrom,
+		ValidUntil:           draft.ValidUntil,
+		RequiresDiscountCode: draft.RequiresDiscountCode,
+		StackingMode:         strings.TrimSpace(draft.StackingMode),
+	}
+	if d.StackingMode == "" {
+		d.StackingMode = domain.StackingModeStacking
+	}
+	if err := validate(d); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, d)
+}
+
+type UpdateInput struct {
+	Version int            `json:"version"`
+	Actions []UpdateAction `json:"actions"`
+}
+
+type UpdateAction = updateaction.Action
+
+// Update applies the actions to the stored discount if in.Version is still current.
+func (s *Service) Update(ctx context.Context, projectID, id string, in UpdateInput) (*domain.CartDiscount, error) {
+	if len(in.Actions) == 0 {
+		return nil, errors.New("actions required")
+	}
+	if in.Version <= 0 {
+		return nil, errors.New("version required")
+	}
+	d, err := s.repo.GetByID(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	for _, action := range in.Actions {
+		if err := apply(d, action); err != nil {
+			return nil, err
+		}
+	}
+	if err := validate(*d); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, *d)
+}
+
+func (s *Service) Delete(ctx context.Context, projectID, id string, version int) (*domain.CartDiscount, error) {
+	if version <= 0 {
+		return nil, errors.New("version required")
+	}
+	return s.repo.Delete(ctx, projectID, id, version)
+}
+
+func apply(d *domain.CartDiscount, action UpdateAction) error {
+	switch strings.ToLower(strings.TrimSpace(action.Action)) {
+	case "changename":
+		var a struct {
+			Name domain.LocalizedString `json:"name"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.Name = a.Name.Clone()
+	case "setdescription":
+		var a struct {
+			Description domain.LocalizedString `json:"description"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.Description = a.Description.Clone()
+	case "setkey":
+		var a struct {
+			Key string `json:"key"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.Key = strings.TrimSpace(a.Key)
+	case "changevalue":
+		var a struct {
+			Value ValueDraft `json:"value"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.Value = a.Value.ToValue()
+	case "changecartpredicate":
+		var a struct {
+			CartPredicate string `json:"cartPredicate"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.CartPredicate = strings.TrimSpace(a.CartPredicate)
+	case "changetarget":
+		var a struct {
+			Target *domain.CartDiscountTarget `json:"target"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.Target = normalizeTarget(a.Target)
+	case "changesortorder":
+		var a struct {
+			SortOrder string `json:"sortOrder"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.SortOrder = strings.TrimSpace(a.SortOrder)
+	case "changeisactive":
+		var a struct {
+			IsActive bool `json:"isActive"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.IsActive = a.IsActive
+	case "changerequiresdiscountcode":
+		var a struct {
+			RequiresDiscountCode bool `json:"requiresDiscountCode"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.RequiresDiscountCode = a.RequiresDiscountCode
+	case "changestackingmode":
+		var a struct {
+			StackingMode string `json:"stackingMode"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.StackingMode = strings.TrimSpace(a.StackingMode)
+	case "setvalidfrom":
+		var a struct {
+			ValidFrom *time.Time `json:"validFrom"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.ValidFrom = a.ValidFrom
+	case "setvaliduntil":
+		var a struct {
+			ValidUntil *time.Time `json:"validUntil"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.ValidUntil = a.ValidUntil
+	case "setvalidfromanduntil":
+		var a struct {
+			ValidFrom  *time.Time `json:"validFrom"`
+			ValidUntil *time.Time `json:"validUntil"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.ValidFrom, d.ValidUntil = a.ValidFrom, a.ValidUntil
+	default:
+		return fmt.Errorf("unsupported action %q", action.Action)
+	}
+	return nil
+}
+
+// sortOrderPattern matches the commercetools sortOrder format: a decimal between
+// 0 and 1 that does not end in zero.
+var sortOrderPattern = regexp.MustCompile(`^0\.\d*[1-9]$`)
+
+func validate(d domain.CartDiscount) error {
+	if d.Name.IsEmpty() {
+		return errors.New("name required")
+	}
+	if d.CartPredicate == "" {
+		return errors.New("cartPredicate required")
+	}
+	if _, err := predicate.Compile(d.CartPredicate, predicate.CartSchema); err != nil {
+		return fmt.Errorf("invalid cartPredicate: %w", err)
+	}
+	if !sortOrderPattern.MatchString(d.SortOrder) {
+		return fmt.Errorf("invalid sortOrder %q", d.SortOrder)
+	}
+	if d.ValidFrom != nil && d.ValidUntil != nil && !d.ValidFrom.Before(*d.ValidUntil) {
+		return errors.New("validFrom must be before validUntil")
+	}
+	switch d.StackingMode {
+	case domain.StackingModeStacking, domain.StackingModeStopAfterThisDiscount:
+	default:
+		return fmt.Errorf("unsupported stackingMode %q", d.StackingMode)
+	}
+	if err := ValidateValue(d.Value); err != nil {
+		return err
+	}
+	return ValidateTarget(d.Value, d.Target)
+}
+
+// ValidateValue checks a cart discount value; direct discounts share it.
+func ValidateValue(v domain.CartDiscountValue) error {
+	switch v.Type {
+	case domain.CartDiscountRelative:
+		if v.Permyriad <= 0 || v.Permyriad > 10000 {
+			return errors.New("permyriad must be between 1 and 10000")
+		}
+	case domain.CartDiscountAbsolute, domain.CartDiscountFixed:
+		if len(v.Money) == 0 {
+			return fmt.Errorf("money required for %s discounts", v.Type)
+		}
+		seen := map[string]struct{}{}
+		for _, m := range v.Money {
+			if len(m.CurrencyCode) != 3 {
+				return fmt.Errorf("invalid currency code %q", m.CurrencyCode)
+			}
+			if m.CentAmount < 0 || (m.CentAmount == 0 && v.Type == domain.CartDiscountAbsolute) {
+				return errors.New("centAmount must be positive")
+			}
+			if _, dup := seen[m.CurrencyCode]; dup {
+				return fmt.Errorf("duplicate currency %s", m.CurrencyCode)
+			}
+			seen[m.CurrencyCode] = struct{}{}
+		}
+	case domain.CartDiscountGiftLineItem:
+		if v.ProductID == "" {
+			return errors.New("product required for giftLineItem discounts")
+		}
+		if v.VariantID <= 0 {
+			return errors.New("variantId required for giftLineItem discounts")
+		}
+	case "":
+		return errors.New("value type required")
+	default:
+		return fmt.Errorf("unsupported value type %q", v.Type)
+	}
+	return nil
+}
+
+// ValidateTarget checks that gift values come without a target and all other
+// values with a supported one.
+func ValidateTarget(v domain.CartDiscountValue, t *domain.CartDiscountTarget) error {
+	if v.Type == domain.CartDiscountGiftLineItem {
+		if t != nil {
+			return errors.New("giftLineItem discounts take no target")
+		}
+		return nil
+	}
+	if t == nil {
+		return errors.New("target required")
+	}
+	switch t.Type {
+	case domain.CartDiscountTargetLineItems:
+		if t.Predicate == "" {
+			return errors.New("target predicate required")
+		}
+		if _, err := predicate.Compile(t.Predicate, predicate.LineItemSchema); err != nil {
+			return fmt.Errorf("invalid target predicate: %w", err)
+		}
+	case domain.CartDiscountTargetTotalPrice, domain.CartDiscountTargetShipping:
+		if t.Predicate != "" {
+			return fmt.Errorf("%s targets take no predicate", t.Type)
+		}
+	case "":
+		return errors.New("target type required")
+	default:
+		return fmt.Errorf("unsupported target type %q", t.Type)
+	}
+	return nil
+}
+
+func normalizeTarget(t *domain.CartDiscountTarget) *domain.CartDiscountTarget {
+	if t == nil {
+		return nil
+	}
+	return &domain.CartDiscountTarget{Type: strings.TrimSpace(t.Type), Predicate: strings.TrimSpace(t.Predicate)}
+}
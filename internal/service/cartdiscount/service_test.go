@@ -0,0 +1,206 @@
+package cartdiscount
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"commercetools-replica/internal/domain"
+	cartdiscountrepo "commercetools-replica/internal/repository/cartdiscount"
+)
+
+// lastRepo keeps the last cart discount the service wrote and serves it
+// back by id.
+type lastRepo struct {
+	cartdiscountrepo.Repository
+	last *domain.CartDiscount
+}
+
+func (r *lastRepo) GetByID(_ context.Context, _, id string) (*domain.CartDiscount, error) {
+	if r.last == nil || r.last.ID != id {
+		return nil, domain.ErrNotFound
+	}
+	d := *r.last
+	return &d, nil
+}
+
+func (r *lastRepo) Create(_ context.Context, d domain.CartDiscount) (*domain.CartDiscount, error) {
+	d.ID, d.Version = "disc-1", 1
+	r.last = &d
+	return &d, nil
+}
+
+func (r *lastRepo) Update(_ context.Context, d domain.CartDiscount) (*domain.CartDiscount, error) {
+	d.Version++
+	r.last = &d
+	return &d, nil
+}
+
+func relativeDraft(key, sortOrder string, permyriad int) CartDiscountDraft {
+	return CartDiscountDraft{
+		Key:           key,
+		Name:          domain.Localized("en", key),
+		Value:         ValueDraft{Type: domain.CartDiscountRelative, Permyriad: permyriad},
+		CartPredicate: "1 = 1",
+		Target:        &domain.CartDiscountTarget{Type: domain.CartDiscountTargetLineItems, Predicate: `sku = "a"`},
+		SortOrder:     sortOrder,
+	}
+}
+
+func TestValidateValue(t *testing.T) {
+	eur := func(cents int64) domain.Money { return domain.Money{CurrencyCode: "EUR", CentAmount: cents} }
+	for _, tc := range []struct {
+		value domain.CartDiscountValue
+		want  string
+	}{
+		{domain.CartDiscountValue{Type: domain.CartDiscountRelative, Permyriad: 1}, ""},
+		{domain.CartDiscountValue{Type: domain.CartDiscountRelative, Permyriad: 10000}, ""},
+		{domain.CartDiscountValue{Type: domain.CartDiscountRelative, Permyriad: 10001}, "permyriad must be between 1 and 10000"},
+		{domain.CartDiscountValue{Type: domain.CartDiscountAbsolute}, "money required for absolute discounts"},
+		{domain.CartDiscountValue{Type: domain.CartDiscountAbsolute, Money: []domain.Money{eur(0)}}, "centAmount must be positive"},
+		// A fixed price of zero makes the items free.
+		{domain.CartDiscountValue{Type: domain.CartDiscountFixed, Money: []domain.Money{eur(0)}}, ""},
+		{domain.CartDiscountValue{Type: domain.CartDiscountFixed, Money: []domain.Money{eur(-1)}}, "centAmount must be positive"},
+		{domain.CartDiscountValue{Type: domain.CartDiscountFixed, Money: []domain.Money{eur(100), eur(200)}}, "duplicate currency EUR"},
+		{domain.CartDiscountValue{Type: domain.CartDiscountAbsolute, Money: []domain.Money{{CurrencyCode: "EURO", CentAmount: 1}}}, `invalid currency code "EURO"`},
+		{domain.CartDiscountValue{Type: domain.CartDiscountGiftLineItem, ProductID: "p1"}, "variantId required for giftLineItem discounts"},
+		{domain.CartDiscountValue{}, "value type required"},
+		{domain.CartDiscountValue{Type: "percent"}, `unsupported value type "percent"`},
+	} {
+		err := ValidateValue(tc.value)
+		if (tc.want == "" && err != nil) || (tc.want != "" && (err == nil || err.Error() != tc.want)) {
+			t.Fatalf("%+v: expected %q, got %v", tc.value, tc.want, err)
+		}
+	}
+}
+
+func TestValidateTarget(t *testing.T) {
+	relative := domain.CartDiscountValue{Type: domain.CartDiscountRelative, Permyriad: 1000}
+	gift := domain.CartDiscountValue{Type: domain.CartDiscountGiftLineItem, ProductID: "p1", VariantID: 1}
+	for _, tc := range []struct {
+		value  domain.CartDiscountValue
+		target *domain.CartDiscountTarget
+		want   string
+	}{
+		{gift, nil, ""},
+		{gift, &domain.CartDiscountTarget{Type: domain.CartDiscountTargetTotalPrice}, "giftLineItem discounts take no target"},
+		{relative, nil, "target required"},
+		{relative, &domain.CartDiscountTarget{Type: domain.CartDiscountTargetShipping}, ""},
+		{relative, &domain.CartDiscountTarget{Type: domain.CartDiscountTargetLineItems}, "target predicate required"},
+		{relative, &domain.CartDiscountTarget{Type: domain.CartDiscountTargetShipping, Predicate: "1 = 1"}, "shipping targets take no predicate"},
+		{relative, &domain.CartDiscountTarget{Type: "customLineItems"}, `unsupported target type "customLineItems"`},
+	} {
+		err := ValidateTarget(tc.value, tc.target)
+		if (tc.want == "" && err != nil) || (tc.want != "" && (err == nil || err.Error() != tc.want)) {
+			t.Fatalf("%+v %+v: expected %q, got %v", tc.value, tc.target, tc.want, err)
+		}
+	}
+	// Line item predicates are checked against the line item schema.
+	err := ValidateTarget(relative, &domain.CartDiscountTarget{Type: domain.CartDiscountTargetLineItems, Predicate: "totalPrice.centAmount > 1"})
+	if err == nil {
+		t.Fatal("expected a cart field in a line item predicate to be rejected")
+	}
+}
+
+func TestServiceCreateDefaultsAndValues(t *testing.T) {
+	svc := New(&lastRepo{})
+	ctx := context.Background()
+
+	d, err := svc.Create(ctx, "proj", relativeDraft("spring", "0.5", 1000))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !d.IsActive || d.StackingMode != domain.StackingModeStacking {
+		t.Fatalf("expected an active stacking discount by default, got %+v", d)
+	}
+	inactive := relativeDraft("later", "0.6", 1000)
+	inactive.IsActive = new(bool)
+	if d, err := svc.Create(ctx, "proj", inactive); err != nil || d.IsActive {
+		t.Fatalf("expected isActive false to be kept, got %+v, %v", d, err)
+	}
+
+	// Values keep only the fields of their type.
+	var value ValueDraft
+	if err := json.Unmarshal([]byte(`{"type":"absolute","permyriad":500,"money":[{"currencyCode":" eur","centAmount":300}],"variantId":2}`), &value); err != nil {
+		t.Fatalf("unmarshal value: %v", err)
+	}
+	if v := value.ToValue(); v.Permyriad != 0 || v.VariantID != 0 || len(v.Money) != 1 || v.Money[0].CurrencyCode != "EUR" {
+		t.Fatalf("unexpected absolute value %+v", v)
+	}
+	if err := json.Unmarshal([]byte(`{"type":"giftLineItem","product":{"typeId":"product","id":"p1"},"variantId":2,"permyriad":500}`), &value); err != nil {
+		t.Fatalf("unmarshal gift: %v", err)
+	}
+	giftDraft := relativeDraft("gift", "0.7", 0)
+	giftDraft.Value, giftDraft.Target = value, nil
+	g, err := svc.Create(ctx, "proj", giftDraft)
+	if err != nil {
+		t.Fatalf("create gift: %v", err)
+	}
+	if g.Value.ProductID != "p1" || g.Value.VariantID != 2 || g.Value.Permyriad != 0 || g.Target != nil {
+		t.Fatalf("unexpected gift discount %+v", g)
+	}
+}
+
+func TestServiceSortOrderAndValidity(t *testing.T) {
+	svc := New(&lastRepo{})
+	ctx := context.Background()
+
+	for sortOrder, ok := range map[string]bool{
+		"0.5": true, "0.0001": true, " 0.25 ": true,
+		"0.10": false, "0.0": false, "1": false, "1.5": false, ".5": false, "": false,
+	} {
+		_, err := svc.Create(ctx, "proj", relativeDraft("x", sortOrder, 1000))
+		if ok != (err == nil) {
+			t.Fatalf("sortOrder %q: expected ok=%v, got %v", sortOrder, ok, err)
+		}
+	}
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	draft := relativeDraft("spring", "0.5", 1000)
+	draft.ValidFrom, draft.ValidUntil = &from, &from
+	if _, err := svc.Create(ctx, "proj", draft); err == nil || err.Error() != "validFrom must be before validUntil" {
+		t.Fatalf("expected an empty validity window to be rejected, got %v", err)
+	}
+	draft.ValidUntil = nil
+	d, err := svc.Create(ctx, "proj", draft)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	for _, tc := range []struct {
+		actions string
+		want    string
+	}{
+		{`[{"action":"setValidUntil","validUntil":"2026-02-01T00:00:00Z"}]`, "validFrom must be before validUntil"},
+		{`[{"action":"setValidFromAndUntil","validFrom":"2026-04-01T00:00:00Z","validUntil":"2026-04-01T00:00:00Z"}]`, "validFrom must be before validUntil"},
+		{`[{"action":"changeSortOrder","sortOrder":"0.50"}]`, `invalid sortOrder "0.50"`},
+		{`[{"action":"changeValue","value":{"type":"giftLineItem","product":{"id":"p1"},"variantId":1}}]`, "giftLineItem discounts take no target"},
+		{`[{"action":"changeStackingMode","stackingMode":"Sometimes"}]`, `unsupported stackingMode "Sometimes"`},
+	} {
+		in := UpdateInput{Version: d.Version}
+		if err := json.Unmarshal([]byte(tc.actions), &in.Actions); err != nil {
+			t.Fatalf("unmarshal %s: %v", tc.actions, err)
+		}
+		if _, err := svc.Update(ctx, "proj", d.ID, in); err == nil || err.Error() != tc.want {
+			t.Fatalf("%s: expected %q, got %v", tc.actions, tc.want, err)
+		}
+	}
+
+	in := UpdateInput{Version: d.Version}
+	if err := json.Unmarshal([]byte(`[
+		{"action":"setValidFrom","validFrom":null},
+		{"action":"setValidUntil","validUntil":"2026-02-01T00:00:00Z"},
+		{"action":"changeValue","value":{"type":"giftLineItem","product":{"id":"p1"},"variantId":1}},
+		{"action":"changeTarget","target":null}
+	]`), &in.Actions); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	d, err = svc.Update(ctx, "proj", d.ID, in)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if d.ValidFrom != nil || d.ValidUntil == nil || d.Value.Type != domain.CartDiscountGiftLineItem || d.Target != nil {
+		t.Fatalf("expected an open-ended gift discount, got %+v", d)
+	}
+}
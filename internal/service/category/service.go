@@ -19,6 +19,23 @@ func (s *Service) List(ctx context.Context, projectID string) ([]domain.Category
 	return s.repo.ListByProject(ctx, projectID)
 }
 
+// ListPage returns one page of categories plus the project total; limit 0 means no limit.
+func (s *Service) ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.Category, int, error) {
+	return s.repo.List(ctx, projectID, limit, offset)
+}
+
+func (s *Service) Get(ctx context.Context, projectID, id string) (*domain.Category, error) {
+	return s.repo.GetByID(ctx, projectID, id)
+}
+
+func (s *Service) GetByKey(ctx context.Context, projectID, key string) (*domain.Category, error) {
+	return s.repo.GetByKey(ctx, projectID, key)
+}
+
+func (s *Service) GetBySlug(ctx context.Context, projectID, locale, slug string) (*domain.Category, error) {
+	return s.repo.GetBySlug(ctx, projectID, locale, slug)
+}
+
 func (s *Service) Upsert(ctx context.Context, c domain.Category) (*domain.Category, error) {
 	return s.repo.Upsert(ctx, c)
 }
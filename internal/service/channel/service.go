@@ -0,0 +1,187 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"commercetools-replica/internal/domain"
+	channelrepo "commercetools-replica/internal/repository/channel"
+	"commercetools-replica/internal/service/updateaction"
+)
+
+type Service struct {
+	repo channelrepo.Repository
+}
+
+func New(repo channelrepo.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// ListPage returns one page of channels, oldest first; limit 0 means no limit.
+func (s *Service) ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.Channel, int, error) {
+	return s.repo.List(ctx, projectID, limit, offset)
+}
+
+func (s *Service) Get(ctx context.Context, projectID, id string) (*domain.Channel, error) {
+	return s.repo.GetByID(ctx, projectID, id)
+}
+
+func (s *Service) GetByKey(ctx context.Context, projectID, key string) (*domain.Channel, error) {
+	return s.repo.GetByKey(ctx, projectID, key)
+}
+
+type ChannelDraft struct {
+	Key         string                 `json:"key"`
+	Roles       []string               `json:"roles,omitempty"`
+	Name        domain.LocalizedString `json:"name,omitempty"`
+	Description domain.LocalizedString `json:"description,omitempty"`
+}
+
+// Create stores a new channel; without roles it is an InventorySupply channel.
+func (s *Service) Create(ctx context.Context, projectID string, draft ChannelDraft) (*domain.Channel, error) {
+	c := domain.Channel{
+		ProjectID:   projectID,
+		Key:         strings.TrimSpace(draft.Key),
+		Roles:       draft.Roles,
+		Name:        draft.Name,
+		Description: draft.Description,
+	}
+	if len(c.Roles) == 0 {
+		c.Roles = []string{domain.ChannelRoleInventorySupply}
+	}
+	if err := validate(&c); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, c)
+}
+
+type UpdateInput struct {
+	Version int            `json:"version"`
+	Actions []UpdateAction `json:"actions"`
+}
+
+type UpdateAction = updateaction.Action
+
+// Update applies the actions to the stored channel if in.Version is still current.
+func (s *Service) Update(ctx context.Context, projectID, id string, in UpdateInput) (*domain.Channel, error) {
+	if len(in.Actions) == 0 {
+		return nil, errors.New("actions required")
+	}
+	if in.Version <= 0 {
+		return nil, errors.New("version required")
+	}
+	c, err := s.repo.GetByID(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	for _, action := range in.Actions {
+		if err := apply(c, action); err != nil {
+			return nil, err
+		}
+	}
+	if err := validate(c); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, *c)
+}
+
+// Delete removes the channel; it fails with domain.ErrReferenceExists while a
+// store or an inventory entry uses it.
+func (s *Service) Delete(ctx context.Context, projectID, id string, version int) (*domain.Channel, error) {
+	if version <= 0 {
+		return nil, errors.New("version required")
+	}
+	return s.repo.Delete(ctx, projectID, id, version)
+}
+
+func apply(c *domain.Channel, action UpdateAction) error {
+	switch strings.ToLower(strings.TrimSpace(action.Action)) {
+	case "changekey":
+		var a struct {
+			Key string `json:"key"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		c.Key = strings.TrimSpace(a.Key)
+	case "changename":
+		var a struct {
+			Name domain.LocalizedString `json:"name"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		c.Name = a.Name
+	case "changedescription":
+		var a struct {
+			Description domain.LocalizedString `json:"description"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		c.Description = a.Description
+	case "setroles", "addroles", "removeroles":
+		var a struct {
+			Roles []string `json:"roles"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		switch strings.ToLower(strings.TrimSpace(action.Action)) {
+		case "setroles":
+			c.Roles = a.Roles
+		case "addroles":
+			for _, role := range a.Roles {
+				if !c.HasRole(role) {
+					c.Roles = append(c.Roles, role)
+				}
+			}
+		default:
+			kept := c.Roles[:0:0]
+			for _, role := range c.Roles {
+				if !contains(a.Roles, role) {
+					kept = append(kept, role)
+				}
+			}
+			c.Roles = kept
+		}
+	default:
+		return fmt.Errorf("unsupported action %q", action.Action)
+	}
+	return nil
+}
+
+// validate checks the channel and drops duplicate roles.
+func validate(c *domain.Channel) error {
+	if c.Key == "" {
+		return errors.New("key required")
+	}
+	var roles []string
+	for _, role := range c.Roles {
+		switch role {
+		case domain.ChannelRoleInventorySupply, domain.ChannelRoleProductDistribution,
+			domain.ChannelRoleOrderExport, domain.ChannelRoleOrderImport, domain.ChannelRolePrimary:
+		default:
+			return fmt.Errorf("unsupported role %q", role)
+		}
+		if !contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	c.Roles = roles
+	return nil
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
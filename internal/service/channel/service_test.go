@@ -0,0 +1,128 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"commercetools-replica/internal/domain"
+	channelrepo "commercetools-replica/internal/repository/channel"
+)
+
+// channelRepo keeps channels by key, which the tests use as their id.
+type channelRepo struct {
+	channelrepo.Repository
+	byKey map[string]domain.Channel
+}
+
+func (r *channelRepo) GetByID(_ context.Context, _, id string) (*domain.Channel, error) {
+	c, ok := r.byKey[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	c.Roles = append([]string(nil), c.Roles...)
+	return &c, nil
+}
+
+func (r *channelRepo) Create(_ context.Context, c domain.Channel) (*domain.Channel, error) {
+	c.ID, c.Version = c.Key, 1
+	r.byKey[c.Key] = c
+	return &c, nil
+}
+
+func (r *channelRepo) Update(_ context.Context, c domain.Channel) (*domain.Channel, error) {
+	c.Version++
+	r.byKey[c.ID] = c
+	return &c, nil
+}
+
+func changeRoles(svc *Service, c *domain.Channel, actions string) (*domain.Channel, error) {
+	in := UpdateInput{Version: c.Version}
+	if err := json.Unmarshal([]byte(actions), &in.Actions); err != nil {
+		return nil, err
+	}
+	return svc.Update(context.Background(), "proj", c.ID, in)
+}
+
+func TestServiceCreateRoles(t *testing.T) {
+	repo := &channelRepo{byKey: map[string]domain.Channel{}}
+	svc := New(repo)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		draft ChannelDraft
+		want  []string
+	}{
+		// Channels without roles supply inventory.
+		{ChannelDraft{Key: " warehouse "}, []string{domain.ChannelRoleInventorySupply}},
+		{ChannelDraft{Key: "web", Roles: []string{domain.ChannelRolePrimary, domain.ChannelRoleProductDistribution, domain.ChannelRolePrimary}},
+			[]string{domain.ChannelRolePrimary, domain.ChannelRoleProductDistribution}},
+		{ChannelDraft{Key: "erp", Roles: []string{domain.ChannelRoleOrderExport, domain.ChannelRoleOrderImport}},
+			[]string{domain.ChannelRoleOrderExport, domain.ChannelRoleOrderImport}},
+	} {
+		c, err := svc.Create(ctx, "proj", tc.draft)
+		if err != nil {
+			t.Fatalf("create %q: %v", tc.draft.Key, err)
+		}
+		if !reflect.DeepEqual(c.Roles, tc.want) {
+			t.Fatalf("%q: expected roles %v, got %v", c.Key, tc.want, c.Roles)
+		}
+	}
+	if _, ok := repo.byKey["warehouse"]; !ok {
+		t.Fatalf("expected a trimmed key, got %v", repo.byKey)
+	}
+
+	for _, tc := range []struct {
+		draft ChannelDraft
+		want  string
+	}{
+		{ChannelDraft{Key: " "}, "key required"},
+		// Roles are case-sensitive like in commercetools.
+		{ChannelDraft{Key: "shop", Roles: []string{"primary"}}, `unsupported role "primary"`},
+	} {
+		if _, err := svc.Create(ctx, "proj", tc.draft); err == nil || err.Error() != tc.want {
+			t.Fatalf("expected %q, got %v", tc.want, err)
+		}
+	}
+}
+
+func TestServiceRoleActions(t *testing.T) {
+	repo := &channelRepo{byKey: map[string]domain.Channel{}}
+	svc := New(repo)
+	c, err := svc.Create(context.Background(), "proj", ChannelDraft{Key: "web", Roles: []string{domain.ChannelRoleProductDistribution}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	c, err = changeRoles(svc, c, `[
+		{"action":"addRoles","roles":["InventorySupply","ProductDistribution"]},
+		{"action":"removeRoles","roles":["ProductDistribution","OrderExport"]},
+		{"action":"addRoles","roles":["Primary","Primary"]}
+	]`)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if want := []string{domain.ChannelRoleInventorySupply, domain.ChannelRolePrimary}; !reflect.DeepEqual(c.Roles, want) {
+		t.Fatalf("expected roles %v, got %v", want, c.Roles)
+	}
+
+	if _, err := changeRoles(svc, c, `[{"action":"setRoles","roles":["Primary","Unknown"]}]`); err == nil || err.Error() != `unsupported role "Unknown"` {
+		t.Fatalf("expected an unknown role to be rejected, got %v", err)
+	}
+	if _, err := changeRoles(svc, c, `[{"action":"changeKey","key":""}]`); err == nil || err.Error() != "key required" {
+		t.Fatalf("expected the key to be required, got %v", err)
+	}
+	if stored := repo.byKey["web"]; stored.Version != 2 || len(stored.Roles) != 2 {
+		t.Fatalf("expected failed updates not to be stored, got %+v", stored)
+	}
+
+	// Unlike on create, an update may leave a channel without roles.
+	c, err = changeRoles(svc, c, `[{"action":"setRoles","roles":[]}]`)
+	if err != nil || len(c.Roles) != 0 {
+		t.Fatalf("expected no roles, got %+v, %v", c, err)
+	}
+	if c.HasRole(domain.ChannelRoleInventorySupply) {
+		t.Fatal("expected the channel to no longer supply inventory")
+	}
+}
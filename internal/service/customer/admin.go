@@ -0,0 +1,56 @@
+package customer
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"commercetools-replica/internal/domain"
+)
+
+// CustomerDraft is the payload of POST /customers: the signup fields plus what
+// only the project API may set.
+type CustomerDraft struct {
+	SignupInput
+	IsEmailVerified bool                `json:"isEmailVerified"`
+	CustomerGroup   *ResourceIdentifier `json:"customerGroup"`
+}
+
+// ListPage returns a page of the project's customers and the total; a
+// non-empty email only matches that address.
+func (s *Service) ListPage(ctx context.Context, projectID, email string, limit, offset int) ([]domain.Customer, int, error) {
+	return s.repo.List(ctx, projectID, strings.TrimSpace(email), limit, offset)
+}
+
+func (s *Service) Get(ctx context.Context, projectID, id string) (*domain.Customer, error) {
+	return s.repo.GetByID(ctx, projectID, id)
+}
+
+// Create registers a customer on behalf of the customer.
+func (s *Service) Create(ctx context.Context, projectID string, draft CustomerDraft) (*domain.Customer, error) {
+	c, err := s.newCustomer(ctx, projectID, draft.SignupInput)
+	if err != nil {
+		return nil, err
+	}
+	c.IsEmailVerified = draft.IsEmailVerified
+	if draft.CustomerGroup != nil {
+		g, err := s.resolveCustomerGroup(ctx, projectID, *draft.CustomerGroup)
+		if err != nil {
+			return nil, err
+		}
+		c.CustomerGroupID = g.ID
+	}
+	if err := validate(*c); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, *c)
+}
+
+// Delete removes the customer and its tokens; carts and orders stay without a
+// customer.
+func (s *Service) Delete(ctx context.Context, projectID, id string, version int) (*domain.Customer, error) {
+	if version <= 0 {
+		return nil, errors.New("version required")
+	}
+	return s.repo.Delete(ctx, projectID, id, version)
+}
@@ -0,0 +1,66 @@
+package customer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"commercetools-replica/internal/domain"
+)
+
+func TestAdminCustomerLifecycle(t *testing.T) {
+	svc := New(newMemoryRepo(), newMemoryTokenRepo(), stubGroups{{ID: "group-1", Key: "wholesale", Name: "Wholesale"}}, nil)
+	ctx := context.Background()
+
+	created, err := svc.Create(ctx, "proj", CustomerDraft{
+		SignupInput:     SignupInput{Email: "b@example.com", Password: "Abcdefg1"},
+		IsEmailVerified: true,
+		CustomerGroup:   &ResourceIdentifier{TypeID: "customer-group", Key: "wholesale"},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !created.IsEmailVerified || created.CustomerGroupID != "group-1" {
+		t.Fatalf("expected a verified wholesale customer, got %+v", created)
+	}
+	if _, err := svc.Create(ctx, "proj", CustomerDraft{SignupInput: SignupInput{Email: "b@example.com", Password: "Abcdefg1"}}); !errors.Is(err, domain.ErrAlreadyExists) {
+		t.Fatalf("expected ErrAlreadyExists, got %v", err)
+	}
+	if _, err := svc.Create(ctx, "proj", CustomerDraft{
+		SignupInput:   SignupInput{Email: "c@example.com", Password: "Abcdefg1"},
+		CustomerGroup: &ResourceIdentifier{Key: "retail"},
+	}); err == nil || err.Error() != "customer group not found" {
+		t.Fatalf("expected an unknown group to be rejected, got %v", err)
+	}
+	if _, err := svc.Signup(ctx, "proj", SignupInput{Email: "a@example.com", Password: "Abcdefg1"}); err != nil {
+		t.Fatalf("signup: %v", err)
+	}
+
+	page, total, err := svc.ListPage(ctx, "proj", "", 1, 1)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if total != 2 || len(page) != 1 || page[0].Email != "b@example.com" {
+		t.Fatalf("unexpected page %+v (total %d)", page, total)
+	}
+	page, total, err = svc.ListPage(ctx, "proj", " A@example.com ", 0, 0)
+	if err != nil {
+		t.Fatalf("list by email: %v", err)
+	}
+	if total != 1 || len(page) != 1 || page[0].Email != "a@example.com" {
+		t.Fatalf("unexpected email match %+v (total %d)", page, total)
+	}
+
+	if _, err := svc.Delete(ctx, "proj", created.ID, 0); err == nil || err.Error() != "version required" {
+		t.Fatalf("expected version required, got %v", err)
+	}
+	if _, err := svc.Delete(ctx, "proj", created.ID, 2); !errors.Is(err, domain.ErrConcurrentModification) {
+		t.Fatalf("expected ErrConcurrentModification, got %v", err)
+	}
+	if _, err := svc.Delete(ctx, "proj", created.ID, 1); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := svc.Get(ctx, "proj", created.ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected the deleted customer to be gone, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package customer
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"commercetools-replica/internal/domain"
+	"commercetools-replica/internal/mailer"
+)
+
+// ErrEmailNotVerified is returned by Login when the project requires a
+// verified email and the customer has not confirmed theirs.
+var ErrEmailNotVerified = errors.New("email not verified")
+
+const emailVerificationKind = mailer.KindEmailVerification
+
+// CreateEmailToken issues a verification token for the customer and mails it.
+// Version is checked when set.
+func (s *Service) CreateEmailToken(ctx context.Context, projectID, customerID string, version int, opts TokenOptions) (*CustomerToken, error) {
+	ttl, err := tokenTTL(opts.TTL)
+	if err != nil {
+		return nil, err
+	}
+	customerID = strings.TrimSpace(customerID)
+	if customerID == "" {
+		return nil, errors.New("id required")
+	}
+	c, err := s.repo.GetByID(ctx, projectID, customerID)
+	if err != nil {
+		return nil, err
+	}
+	if version > 0 && c.Version != version {
+		return nil, domain.ErrConcurrentModification
+	}
+	return s.issueCustomerToken(ctx, c, emailVerificationKind, ttl, opts)
+}
+
+// ConfirmEmailInput mirrors the commercetools CustomerEmailVerify draft;
+// Version is optional.
+type ConfirmEmailInput struct {
+	TokenValue string `json:"tokenValue"`
+	Version    int    `json:"version,omitempty"`
+}
+
+// ConfirmEmail consumes a verification token and marks the email of its
+// customer as verified. A non-empty customerID (POST /me/email/confirm) only
+// accepts tokens of that customer.
+func (s *Service) ConfirmEmail(ctx context.Context, projectID, customerID string, in ConfirmEmailInput) (*domain.Customer, error) {
+	meta, ok := s.tokens.Consume(ctx, projectID, customerID, strings.TrimSpace(in.TokenValue), emailVerificationKind)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	c, err := s.repo.GetByID(ctx, projectID, meta.CustomerID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if in.Version > 0 && c.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	if c.IsEmailVerified {
+		return c, nil
+	}
+	c.IsEmailVerified = true
+	return s.repo.Update(ctx, *c)
+}
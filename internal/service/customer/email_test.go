@@ -0,0 +1,131 @@
+package customer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"commercetools-replica/internal/domain"
+	"commercetools-replica/internal/mailer"
+)
+
+func TestEmailVerification(t *testing.T) {
+	svc := New(newMemoryRepo(), newMemoryTokenRepo(), nil, nil)
+	ctx := context.Background()
+	c, err := svc.Signup(ctx, "proj", SignupInput{Email: "me@example.com", Password: "Abcdefg1"})
+	if err != nil {
+		t.Fatalf("signup: %v", err)
+	}
+	other, err := svc.Signup(ctx, "proj", SignupInput{Email: "other@example.com", Password: "Abcdefg1"})
+	if err != nil {
+		t.Fatalf("signup other: %v", err)
+	}
+
+	if _, _, _, err := svc.Login(ctx, "proj", "me@example.com", "Abcdefg1", "", true); !errors.Is(err, ErrEmailNotVerified) {
+		t.Fatalf("expected ErrEmailNotVerified, got %v", err)
+	}
+	if _, err := svc.CreateEmailToken(ctx, "proj", c.ID, 2, TokenOptions{}); !errors.Is(err, domain.ErrConcurrentModification) {
+		t.Fatalf("expected ErrConcurrentModification, got %v", err)
+	}
+	if _, err := svc.CreateEmailToken(ctx, "proj", "missing", 0, TokenOptions{}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	token, err := svc.CreateEmailToken(ctx, "proj", c.ID, 1, TokenOptions{})
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	if got := token.ExpiresAt.Sub(token.CreatedAt); got != defaultTokenTTL {
+		t.Fatalf("expected default ttl, got %s", got)
+	}
+	// Another customer cannot confirm it and does not use it up.
+	if _, err := svc.ConfirmEmail(ctx, "proj", other.ID, ConfirmEmailInput{TokenValue: token.Value}); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken for another customer, got %v", err)
+	}
+	verified, err := svc.ConfirmEmail(ctx, "proj", c.ID, ConfirmEmailInput{TokenValue: token.Value})
+	if err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+	if !verified.IsEmailVerified || verified.Version != 2 {
+		t.Fatalf("expected verified customer at version 2, got %+v", verified)
+	}
+	if _, err := svc.ConfirmEmail(ctx, "proj", "", ConfirmEmailInput{TokenValue: token.Value}); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a used token to be rejected, got %v", err)
+	}
+	if _, _, _, err := svc.Login(ctx, "proj", "me@example.com", "Abcdefg1", "", true); err != nil {
+		t.Fatalf("login after verification: %v", err)
+	}
+
+	// Changing the email drops the flag and tokens sent to the old address.
+	pending, err := svc.CreateEmailToken(ctx, "proj", c.ID, 0, TokenOptions{})
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	var in UpdateInput
+	if err := json.Unmarshal([]byte(`{"version":2,"actions":[{"action":"changeEmail","email":"new@example.com"}]}`), &in); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	changed, err := svc.UpdateMe(ctx, "proj", c.ID, in)
+	if err != nil {
+		t.Fatalf("change email: %v", err)
+	}
+	if changed.IsEmailVerified {
+		t.Fatalf("expected changeEmail to reset isEmailVerified")
+	}
+	if _, err := svc.ConfirmEmail(ctx, "proj", "", ConfirmEmailInput{TokenValue: pending.Value}); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected the pending token to be revoked, got %v", err)
+	}
+}
+
+type failingMailer struct{}
+
+func (failingMailer) Send(context.Context, mailer.Message) error {
+	return errors.New("relay down")
+}
+
+func TestTokensAreMailed(t *testing.T) {
+	sink := mailer.NewMemory()
+	svc := New(newMemoryRepo(), newMemoryTokenRepo(), nil, mailer.NewOutbox(sink, nil, "shop@example.com"))
+	ctx := context.Background()
+	c, err := svc.Signup(ctx, "proj", SignupInput{Email: "me@example.com", Password: "Abcdefg1", FirstName: "Ada"})
+	if err != nil {
+		t.Fatalf("signup: %v", err)
+	}
+	opts := TokenOptions{ProjectKey: "shop", Locale: "de-DE"}
+
+	if _, err := svc.CreateEmailToken(ctx, "proj", c.ID, 0, opts); err != nil {
+		t.Fatalf("create email token: %v", err)
+	}
+	msg, ok := sink.Last("me@example.com", mailer.KindEmailVerification)
+	if !ok || msg.ProjectKey != "shop" || msg.Locale != "de-DE" || !strings.Contains(msg.Body, "Ada") {
+		t.Fatalf("unexpected verification mail: %+v", msg)
+	}
+	if _, err := svc.ConfirmEmail(ctx, "proj", "", ConfirmEmailInput{TokenValue: msg.Token}); err != nil {
+		t.Fatalf("confirm with mailed token: %v", err)
+	}
+
+	if _, err := svc.CreatePasswordToken(ctx, "proj", "me@example.com", opts); err != nil {
+		t.Fatalf("create password token: %v", err)
+	}
+	msg, ok = sink.Last("me@example.com", mailer.KindPasswordReset)
+	if !ok || !strings.Contains(msg.Body, msg.Token) {
+		t.Fatalf("unexpected reset mail: %+v", msg)
+	}
+	if _, err := svc.ResetPassword(ctx, "proj", ResetPasswordInput{TokenValue: msg.Token, NewPassword: "Newpass12"}); err != nil {
+		t.Fatalf("reset with mailed token: %v", err)
+	}
+
+	tokens := newMemoryTokenRepo()
+	failing := New(newMemoryRepo(), tokens, nil, mailer.NewOutbox(failingMailer{}, nil, "shop@example.com"))
+	if _, err := failing.Signup(ctx, "proj", SignupInput{Email: "me@example.com", Password: "Abcdefg1"}); err != nil {
+		t.Fatalf("signup: %v", err)
+	}
+	if _, err := failing.CreatePasswordToken(ctx, "proj", "me@example.com", opts); !errors.Is(err, ErrMailFailed) {
+		t.Fatalf("expected ErrMailFailed, got %v", err)
+	}
+	if len(tokens.tokens) != 0 {
+		t.Fatalf("expected the unsent token to be revoked, got %d tokens", len(tokens.tokens))
+	}
+}
@@ -0,0 +1,149 @@
+package customer
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hash algorithms.
+const (
+	HashArgon2id = "argon2id"
+	HashBcrypt   = "bcrypt"
+)
+
+// PasswordHashing picks how new passwords are hashed. Hashes of the other
+// algorithm, or with other parameters, still verify and are replaced on the
+// next login.
+type PasswordHashing struct {
+	Algorithm  string
+	BcryptCost int
+	// Argon2 parameters: memory in KiB, passes and lanes.
+	Argon2Memory  uint32
+	Argon2Time    uint32
+	Argon2Threads uint8
+}
+
+// DefaultPasswordHashing is argon2id with the OWASP minimum of 19 MiB and
+// two passes.
+func DefaultPasswordHashing() PasswordHashing {
+	return PasswordHashing{
+		Algorithm:     HashArgon2id,
+		BcryptCost:    bcrypt.DefaultCost,
+		Argon2Memory:  19 * 1024,
+		Argon2Time:    2,
+		Argon2Threads: 1,
+	}
+}
+
+// Validate rejects unknown algorithms and parameters the hash functions
+// cannot use.
+func (h PasswordHashing) Validate() error {
+	switch h.Algorithm {
+	case HashBcrypt:
+		if h.BcryptCost < bcrypt.MinCost || h.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case HashArgon2id:
+		if h.Argon2Time < 1 || h.Argon2Threads < 1 || h.Argon2Memory < 8*uint32(h.Argon2Threads) {
+			return errors.New("argon2id needs at least 1 pass, 1 thread and 8 KiB memory per thread")
+		}
+	default:
+		return fmt.Errorf("unknown password hash %q", h.Algorithm)
+	}
+	return nil
+}
+
+const (
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+)
+
+func (h PasswordHashing) hash(password string) (string, error) {
+	switch h.Algorithm {
+	case HashBcrypt:
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hashed), nil
+	case HashArgon2id:
+		salt := make([]byte, argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, h.Argon2Time, h.Argon2Memory, h.Argon2Threads, argon2KeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, h.Argon2Memory, h.Argon2Time, h.Argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key)), nil
+	default:
+		return "", fmt.Errorf("unknown password hash %q", h.Algorithm)
+	}
+}
+
+// dummyHash hashes a random password with h. Login verifies against it when
+// the email is unknown, so that takes as long as a wrong password.
+func (h PasswordHashing) dummyHash() string {
+	hashed, err := h.hash(rand.Text())
+	if err != nil {
+		return ""
+	}
+	return hashed
+}
+
+// verify reports whether password matches hash and, if so, whether the hash
+// should be replaced with one of the configured algorithm and parameters.
+func (h PasswordHashing) verify(hash, password string) (ok, rehash bool) {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		p, salt, key, err := parseArgon2id(hash)
+		if err != nil {
+			return false, false
+		}
+		got := argon2.IDKey([]byte(password), salt, p.Argon2Time, p.Argon2Memory, p.Argon2Threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(got, key) != 1 {
+			return false, false
+		}
+		return true, h.Algorithm != HashArgon2id || p.Argon2Memory != h.Argon2Memory ||
+			p.Argon2Time != h.Argon2Time || p.Argon2Threads != h.Argon2Threads
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return false, false
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return true, h.Algorithm != HashBcrypt || err != nil || cost != h.BcryptCost
+}
+
+// parseArgon2id reads $argon2id$v=19$m=...,t=...,p=...$salt$key.
+func parseArgon2id(hash string) (PasswordHashing, []byte, []byte, error) {
+	var p PasswordHashing
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, errors.New("malformed argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, errors.New("unsupported argon2id version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Argon2Memory, &p.Argon2Time, &p.Argon2Threads); err != nil {
+		return p, nil, nil, fmt.Errorf("malformed argon2id parameters: %w", err)
+	}
+	if p.Argon2Time < 1 || p.Argon2Threads < 1 {
+		return p, nil, nil, errors.New("malformed argon2id parameters")
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, errors.New("malformed argon2id key")
+	}
+	return p, salt, key, nil
+}
@@ -0,0 +1,186 @@
+package customer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"commercetools-replica/internal/domain"
+	"commercetools-replica/internal/mailer"
+)
+
+var (
+	// ErrInvalidCurrentPassword is returned when a password change does not
+	// confirm the current password.
+	ErrInvalidCurrentPassword = errors.New("invalid current password")
+	// ErrMailFailed is returned when a token could not be mailed; the token is
+	// revoked again.
+	ErrMailFailed = errors.New("sending email failed")
+)
+
+const (
+	passwordResetKind = mailer.KindPasswordReset
+	// defaultTokenTTL and maxTokenTTL bound the lifetime of reset and email
+	// verification tokens.
+	defaultTokenTTL = 15 * time.Minute
+	maxTokenTTL     = 24 * time.Hour
+)
+
+// ChangePasswordInput mirrors the commercetools MyCustomerChangePassword draft.
+type ChangePasswordInput struct {
+	Version         int    `json:"version"`
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+// ChangePassword replaces the password of the customer after checking the
+// current one, and revokes every token but keepToken.
+func (s *Service) ChangePassword(ctx context.Context, projectID, customerID, keepToken string, in ChangePasswordInput) (*domain.Customer, error) {
+	if in.Version <= 0 {
+		return nil, errors.New("version required")
+	}
+	c, err := s.repo.GetByID(ctx, projectID, customerID)
+	if err != nil {
+		return nil, err
+	}
+	if c.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	if ok, _ := s.hashing.verify(c.PasswordHash, strings.TrimSpace(in.CurrentPassword)); !ok {
+		return nil, ErrInvalidCurrentPassword
+	}
+	return s.setPassword(ctx, c, in.NewPassword, keepToken)
+}
+
+// TokenOptions tunes reset and verification tokens. TTL 0 means the default
+// of 15 minutes; ProjectKey and Locale pick the mail template.
+type TokenOptions struct {
+	TTL        time.Duration
+	ProjectKey string
+	Locale     string
+}
+
+// CustomerToken is a single-use token that lets a customer set a new password
+// or confirm the email.
+type CustomerToken struct {
+	Value      string
+	CustomerID string
+	ExpiresAt  time.Time
+	CreatedAt  time.Time
+}
+
+// CreatePasswordToken issues a reset token for the customer with email and
+// mails it.
+func (s *Service) CreatePasswordToken(ctx context.Context, projectID, email string, opts TokenOptions) (*CustomerToken, error) {
+	ttl, err := tokenTTL(opts.TTL)
+	if err != nil {
+		return nil, err
+	}
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil, errors.New("email required")
+	}
+	c, err := s.repo.GetByEmail(ctx, projectID, email)
+	if err != nil {
+		return nil, err
+	}
+	return s.issueCustomerToken(ctx, c, passwordResetKind, ttl, opts)
+}
+
+func (s *Service) issueCustomerToken(ctx context.Context, c *domain.Customer, kind string, ttl time.Duration, opts TokenOptions) (*CustomerToken, error) {
+	t, err := s.tokens.issueToken(ctx, c.ProjectID, c.ID, kind, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if s.mail != nil {
+		err := s.mail.SendToken(ctx, kind, mailer.TemplateData{
+			ProjectKey: opts.ProjectKey,
+			Locale:     opts.Locale,
+			Email:      c.Email,
+			FirstName:  c.FirstName,
+			LastName:   c.LastName,
+			Token:      t.Token,
+			ExpiresAt:  t.ExpiresAt,
+			TTLMinutes: int(ttl / time.Minute),
+		})
+		if err != nil {
+			// A token nobody received is of no use.
+			_ = s.tokens.repo.Delete(ctx, t.ProjectID, t.Token)
+			return nil, fmt.Errorf("%w: %v", ErrMailFailed, err)
+		}
+	}
+	return &CustomerToken{Value: t.Token, CustomerID: c.ID, ExpiresAt: t.ExpiresAt, CreatedAt: t.CreatedAt}, nil
+}
+
+// tokenTTL applies the default to a zero ttl and checks the bounds.
+func tokenTTL(ttl time.Duration) (time.Duration, error) {
+	if ttl == 0 {
+		return defaultTokenTTL, nil
+	}
+	if ttl < time.Minute || ttl > maxTokenTTL {
+		return 0, errors.New("ttlMinutes must be between 1 and 1440")
+	}
+	return ttl, nil
+}
+
+// ResetPasswordInput mirrors the commercetools CustomerResetPassword draft;
+// Version is optional.
+type ResetPasswordInput struct {
+	TokenValue  string `json:"tokenValue"`
+	NewPassword string `json:"newPassword"`
+	Version     int    `json:"version,omitempty"`
+}
+
+// ResetPassword consumes a reset token and sets the new password. All tokens of
+// the customer are revoked, so sessions have to log in again.
+func (s *Service) ResetPassword(ctx context.Context, projectID string, in ResetPasswordInput) (*domain.Customer, error) {
+	// Check the password first so a typo does not burn the token.
+	if err := s.checkPassword(ctx, projectID, "newPassword", strings.TrimSpace(in.NewPassword)); err != nil {
+		return nil, err
+	}
+	meta, ok := s.tokens.Consume(ctx, projectID, "", strings.TrimSpace(in.TokenValue), passwordResetKind)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	c, err := s.repo.GetByID(ctx, projectID, meta.CustomerID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if in.Version > 0 && c.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	updated, err := s.setPassword(ctx, c, in.NewPassword, "")
+	if err != nil {
+		return nil, err
+	}
+	// The reset proves the customer owns the email, so lift a lockout.
+	if err := s.throttle.clear(ctx, projectID, c.Email); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (s *Service) setPassword(ctx context.Context, c *domain.Customer, password, keepToken string) (*domain.Customer, error) {
+	password = strings.TrimSpace(password)
+	if err := s.checkPassword(ctx, c.ProjectID, "newPassword", password); err != nil {
+		return nil, err
+	}
+	hashed, err := s.hashPassword("newPassword", password)
+	if err != nil {
+		return nil, err
+	}
+	c.PasswordHash = hashed
+	updated, err := s.repo.Update(ctx, *c)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tokens.RevokeOthers(ctx, c.ProjectID, c.ID, keepToken); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
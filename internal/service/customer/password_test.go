@@ -0,0 +1,124 @@
+package customer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"commercetools-replica/internal/domain"
+)
+
+func TestChangePassword(t *testing.T) {
+	tokens := newMemoryTokenRepo()
+	svc := New(newMemoryRepo(), tokens, nil, nil)
+	ctx := context.Background()
+	c, err := svc.Signup(ctx, "proj", SignupInput{Email: "me@example.com", Password: "Abcdefg1"})
+	if err != nil {
+		t.Fatalf("signup: %v", err)
+	}
+	_, current, _, err := svc.Login(ctx, "proj", "me@example.com", "Abcdefg1", "", false)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	_, other, _, err := svc.Login(ctx, "proj", "me@example.com", "Abcdefg1", "", false)
+	if err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+
+	cases := []struct {
+		in   ChangePasswordInput
+		want error
+		msg  string
+	}{
+		{in: ChangePasswordInput{Version: 1, CurrentPassword: "Wrong1234", NewPassword: "Newpass12"}, want: ErrInvalidCurrentPassword},
+		{in: ChangePasswordInput{Version: 2, CurrentPassword: "Abcdefg1", NewPassword: "Newpass12"}, want: domain.ErrConcurrentModification},
+		{in: ChangePasswordInput{Version: 1, CurrentPassword: "Abcdefg1", NewPassword: "Short1"}, msg: "password must be at least 8 characters"},
+	}
+	for _, tc := range cases {
+		_, err := svc.ChangePassword(ctx, "proj", c.ID, current, tc.in)
+		if tc.want != nil && !errors.Is(err, tc.want) {
+			t.Fatalf("expected %v, got %v", tc.want, err)
+		}
+		if tc.msg != "" && (err == nil || err.Error() != tc.msg) {
+			t.Fatalf("expected %q, got %v", tc.msg, err)
+		}
+	}
+
+	updated, err := svc.ChangePassword(ctx, "proj", c.ID, current, ChangePasswordInput{Version: 1, CurrentPassword: "Abcdefg1", NewPassword: "Newpass12"})
+	if err != nil {
+		t.Fatalf("change password: %v", err)
+	}
+	if updated.Version != 2 {
+		t.Fatalf("expected version 2, got %d", updated.Version)
+	}
+	if _, err := svc.LookupByToken(ctx, "proj", current); err != nil {
+		t.Fatalf("expected the current token to stay valid, got %v", err)
+	}
+	if _, err := svc.LookupByToken(ctx, "proj", other); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected other tokens to be revoked, got %v", err)
+	}
+	if _, _, _, err := svc.Login(ctx, "proj", "me@example.com", "Abcdefg1", "", false); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected the old password to fail, got %v", err)
+	}
+	if _, _, _, err := svc.Login(ctx, "proj", "me@example.com", "Newpass12", "", false); err != nil {
+		t.Fatalf("login with new password: %v", err)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	tokens := newMemoryTokenRepo()
+	svc := New(newMemoryRepo(), tokens, nil, nil)
+	ctx := context.Background()
+	if _, err := svc.Signup(ctx, "proj", SignupInput{Email: "me@example.com", Password: "Abcdefg1"}); err != nil {
+		t.Fatalf("signup: %v", err)
+	}
+	_, session, _, err := svc.Login(ctx, "proj", "me@example.com", "Abcdefg1", "", false)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	if _, err := svc.CreatePasswordToken(ctx, "proj", "nobody@example.com", TokenOptions{}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected unknown email to be not found, got %v", err)
+	}
+	if _, err := svc.CreatePasswordToken(ctx, "proj", "me@example.com", TokenOptions{TTL: 48 * time.Hour}); err == nil || err.Error() != "ttlMinutes must be between 1 and 1440" {
+		t.Fatalf("expected ttl error, got %v", err)
+	}
+	token, err := svc.CreatePasswordToken(ctx, "proj", "me@example.com", TokenOptions{})
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	if ttl := token.ExpiresAt.Sub(token.CreatedAt); ttl != 15*time.Minute {
+		t.Fatalf("expected the default ttl, got %v", ttl)
+	}
+
+	if _, err := svc.ResetPassword(ctx, "proj", ResetPasswordInput{TokenValue: token.Value, NewPassword: "weak"}); err == nil {
+		t.Fatalf("expected a weak password to be rejected")
+	}
+	if _, err := svc.ResetPassword(ctx, "other", ResetPasswordInput{TokenValue: token.Value, NewPassword: "Newpass12"}); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a token of another project to be invalid, got %v", err)
+	}
+	if _, err := svc.ResetPassword(ctx, "proj", ResetPasswordInput{TokenValue: token.Value, NewPassword: "Newpass12"}); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if _, err := svc.ResetPassword(ctx, "proj", ResetPasswordInput{TokenValue: token.Value, NewPassword: "Newpass34"}); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected the token to be single-use, got %v", err)
+	}
+	if _, err := svc.LookupByToken(ctx, "proj", session); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected sessions to be revoked, got %v", err)
+	}
+	if _, _, _, err := svc.Login(ctx, "proj", "me@example.com", "Newpass12", "", false); err != nil {
+		t.Fatalf("login with new password: %v", err)
+	}
+
+	expired, err := svc.CreatePasswordToken(ctx, "proj", "me@example.com", TokenOptions{TTL: time.Minute})
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	stored := tokens.tokens[expired.Value]
+	stored.ExpiresAt = time.Now().Add(-time.Second)
+	tokens.tokens[expired.Value] = stored
+	if _, err := svc.ResetPassword(ctx, "proj", ResetPasswordInput{TokenValue: expired.Value, NewPassword: "Newpass34"}); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected an expired token to be invalid, got %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+package customer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"commercetools-replica/internal/domain"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidField matches every ValidationError.
+var ErrInvalidField = errors.New("invalid field")
+
+// FieldError is one rule an input field breaks.
+type FieldError struct {
+	Field   string
+	Rule    string
+	Message string
+}
+
+// ValidationError lists every rule the input breaks.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, f := range e.Errors {
+		msgs[i] = f.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidField
+}
+
+// maxPasswordLength bounds the work of hashing a password.
+const maxPasswordLength = 128
+
+// projectLookup loads the password policy of a project.
+type projectLookup interface {
+	GetByID(ctx context.Context, id string) (*domain.Project, error)
+}
+
+// WithPasswordPolicies checks passwords against the policy of their project
+// instead of the default one; defaultDenylist applies to projects without a
+// denylist file.
+func (s *Service) WithPasswordPolicies(projects projectLookup, defaultDenylist string) *Service {
+	s.projects = projects
+	s.denylists.defaultFile = defaultDenylist
+	return s
+}
+
+// WithPasswordHashing hashes new passwords with h.
+func (s *Service) WithPasswordHashing(h PasswordHashing) *Service {
+	s.hashing = h
+	s.dummyHash = sync.OnceValue(h.dummyHash)
+	return s
+}
+
+func (s *Service) passwordPolicy(ctx context.Context, projectID string) (domain.PasswordPolicy, error) {
+	if s.projects == nil {
+		return domain.DefaultPasswordPolicy(), nil
+	}
+	p, err := s.projects.GetByID(ctx, projectID)
+	if err != nil {
+		return domain.PasswordPolicy{}, err
+	}
+	return p.PasswordPolicy, nil
+}
+
+// checkPassword validates password, reported as field, against the policy
+// of the project.
+func (s *Service) checkPassword(ctx context.Context, projectID, field, password string) error {
+	policy, err := s.passwordPolicy(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	denied, err := s.denylists.contains(policy.DenylistFile, password)
+	if err != nil {
+		return err
+	}
+	return validatePassword(field, password, policy, denied)
+}
+
+func (s *Service) hashPassword(field, password string) (string, error) {
+	hashed, err := s.hashing.hash(password)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return "", &ValidationError{Errors: []FieldError{{Field: field, Rule: "maxLength", Message: "password must be at most 72 bytes"}}}
+	}
+	return hashed, err
+}
+
+// validatePassword collects every rule of policy that p breaks; denied marks
+// a password found in the denylist.
+func validatePassword(field, p string, policy domain.PasswordPolicy, denied bool) error {
+	var errs []FieldError
+	add := func(rule, msg string) {
+		errs = append(errs, FieldError{Field: field, Rule: rule, Message: msg})
+	}
+	length := utf8.RuneCountInString(strings.TrimSpace(p))
+	if length < policy.MinLength {
+		add("minLength", fmt.Sprintf("password must be at least %d characters", policy.MinLength))
+	}
+	if length > maxPasswordLength {
+		add("maxLength", fmt.Sprintf("password must be at most %d characters", maxPasswordLength))
+	}
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range p {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+	if policy.RequireUpper && !hasUpper {
+		add("uppercase", "password must contain an uppercase letter")
+	}
+	if policy.RequireLower && !hasLower {
+		add("lowercase", "password must contain a lowercase letter")
+	}
+	if policy.RequireDigit && !hasDigit {
+		add("digit", "password must contain a number")
+	}
+	if policy.RequireSymbol && !hasSymbol {
+		add("symbol", "password must contain a symbol")
+	}
+	if denied {
+		add("denylist", "password is too common or known from a breach")
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// denylists reads each denylist file once; passwords are compared case
+// insensitively.
+type denylists struct {
+	defaultFile string
+	mu          sync.Mutex
+	byPath      map[string]map[string]struct{}
+}
+
+func (d *denylists) contains(path, password string) (bool, error) {
+	if path == "" {
+		path = d.defaultFile
+	}
+	if path == "" {
+		return false, nil
+	}
+	list, err := d.load(path)
+	if err != nil {
+		return false, err
+	}
+	_, ok := list[strings.ToLower(strings.TrimSpace(password))]
+	return ok, nil
+}
+
+func (d *denylists) load(path string) (map[string]struct{}, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if list, ok := d.byPath[path]; ok {
+		return list, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("password denylist: %w", err)
+	}
+	defer f.Close()
+	list := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("password denylist %s: %w", path, err)
+	}
+	if d.byPath == nil {
+		d.byPath = make(map[string]map[string]struct{})
+	}
+	d.byPath[path] = list
+	return list, nil
+}
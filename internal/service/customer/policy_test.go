@@ -0,0 +1,183 @@
+package customer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"commercetools-replica/internal/domain"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type stubProjects map[string]domain.PasswordPolicy
+
+func (p stubProjects) GetByID(_ context.Context, id string) (*domain.Project, error) {
+	policy, ok := p[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &domain.Project{ID: id, PasswordPolicy: policy}, nil
+}
+
+func TestPasswordPolicies(t *testing.T) {
+	dir := t.TempDir()
+	common := filepath.Join(dir, "common.txt")
+	strict := filepath.Join(dir, "strict.txt")
+	if err := os.WriteFile(common, []byte("# top passwords\nPassword1\n\nqwerty123\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(strict, []byte("Correct-Horse-9\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	svc := New(newMemoryRepo(), newMemoryTokenRepo(), nil, nil).WithPasswordPolicies(stubProjects{
+		"default": domain.DefaultPasswordPolicy(),
+		"strict":  {MinLength: 12, RequireLower: true, RequireSymbol: true, DenylistFile: strict},
+		"lax":     {MinLength: 4},
+	}, common)
+	ctx := context.Background()
+
+	cases := []struct {
+		project  string
+		password string
+		rules    []string
+	}{
+		{project: "default", password: "Abcdefg1"},
+		{project: "default", password: "PASSWORD1", rules: []string{"lowercase", "denylist"}},
+		{project: "default", password: "abc", rules: []string{"minLength", "uppercase", "digit"}},
+		{project: "strict", password: "correct horse battery", rules: []string{"symbol"}},
+		{project: "strict", password: "correct-horse-9", rules: []string{"denylist"}},
+		{project: "strict", password: "Password1", rules: []string{"minLength", "symbol"}},
+		{project: "strict", password: "ünïcödé-pässwörd"},
+		{project: "lax", password: "qwerty"},
+		{project: "lax", password: "QWERTY123", rules: []string{"denylist"}},
+		{project: "lax", password: strings.Repeat("a", maxPasswordLength+1), rules: []string{"maxLength"}},
+	}
+	for _, tc := range cases {
+		err := svc.checkPassword(ctx, tc.project, "password", tc.password)
+		if len(tc.rules) == 0 {
+			if err != nil {
+				t.Fatalf("%s/%s: %v", tc.project, tc.password, err)
+			}
+			continue
+		}
+		var invalid *ValidationError
+		if !errors.As(err, &invalid) || !errors.Is(err, ErrInvalidField) {
+			t.Fatalf("%s/%s: expected a ValidationError, got %v", tc.project, tc.password, err)
+		}
+		var rules []string
+		for _, f := range invalid.Errors {
+			if f.Field != "password" {
+				t.Fatalf("%s/%s: unexpected field %q", tc.project, tc.password, f.Field)
+			}
+			rules = append(rules, f.Rule)
+		}
+		if strings.Join(rules, ",") != strings.Join(tc.rules, ",") {
+			t.Fatalf("%s/%s: expected rules %v, got %v", tc.project, tc.password, tc.rules, rules)
+		}
+	}
+
+	if err := svc.checkPassword(ctx, "missing", "password", "Abcdefg1"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected the project lookup error, got %v", err)
+	}
+	broken := New(newMemoryRepo(), newMemoryTokenRepo(), nil, nil).WithPasswordPolicies(stubProjects{"p": domain.DefaultPasswordPolicy()}, filepath.Join(dir, "missing.txt"))
+	if err := broken.checkPassword(ctx, "p", "password", "Abcdefg1"); err == nil || errors.Is(err, ErrInvalidField) {
+		t.Fatalf("expected a missing denylist to fail loudly, got %v", err)
+	}
+
+	_, err := svc.Signup(ctx, "strict", SignupInput{Email: "me@example.com", Password: "short"})
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) || len(invalid.Errors) != 2 {
+		t.Fatalf("expected signup to report both rules, got %v", err)
+	}
+}
+
+func TestPasswordHashing(t *testing.T) {
+	argon := DefaultPasswordHashing()
+	legacy := PasswordHashing{Algorithm: HashBcrypt, BcryptCost: bcrypt.MinCost}
+
+	hash, err := argon.hash("Abcdefg1")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Fatalf("unexpected argon2id hash %q", hash)
+	}
+	if ok, rehash := argon.verify(hash, "Abcdefg1"); !ok || rehash {
+		t.Fatalf("expected a current argon2id hash to verify, got ok=%v rehash=%v", ok, rehash)
+	}
+	if ok, _ := argon.verify(hash, "Abcdefg2"); ok {
+		t.Fatalf("expected a wrong password to fail")
+	}
+	stronger := argon
+	stronger.Argon2Time = 3
+	if ok, rehash := stronger.verify(hash, "Abcdefg1"); !ok || !rehash {
+		t.Fatalf("expected changed parameters to ask for a rehash, got ok=%v rehash=%v", ok, rehash)
+	}
+
+	old, err := legacy.hash("Abcdefg1")
+	if err != nil {
+		t.Fatalf("bcrypt hash: %v", err)
+	}
+	if ok, rehash := legacy.verify(old, "Abcdefg1"); !ok || rehash {
+		t.Fatalf("expected a current bcrypt hash to verify, got ok=%v rehash=%v", ok, rehash)
+	}
+	if ok, rehash := argon.verify(old, "Abcdefg1"); !ok || !rehash {
+		t.Fatalf("expected bcrypt to verify and ask for argon2id, got ok=%v rehash=%v", ok, rehash)
+	}
+	for _, bad := range []string{"", "plain", "$argon2id$v=19$m=1,t=0,p=1$c2FsdA$a2V5", "$argon2id$v=18$m=8,t=1,p=1$c2FsdA$a2V5"} {
+		if ok, _ := argon.verify(bad, ""); ok {
+			t.Fatalf("expected %q not to verify", bad)
+		}
+	}
+
+	for _, invalid := range []PasswordHashing{{Algorithm: "md5"}, {Algorithm: HashBcrypt, BcryptCost: 99}, {Algorithm: HashArgon2id}} {
+		if err := invalid.Validate(); err == nil {
+			t.Fatalf("expected %+v to be rejected", invalid)
+		}
+	}
+	if err := argon.Validate(); err != nil {
+		t.Fatalf("default hashing: %v", err)
+	}
+
+	dummy := argon.dummyHash()
+	if p, _, _, err := parseArgon2id(dummy); err != nil || p.Argon2Memory != argon.Argon2Memory || p.Argon2Time != argon.Argon2Time {
+		t.Fatalf("expected the dummy hash to use the configured parameters, got %q: %v", dummy, err)
+	}
+	if ok, _ := argon.verify(dummy, ""); ok {
+		t.Fatal("expected the dummy hash not to verify an empty password")
+	}
+}
+
+func TestLoginRehashesPassword(t *testing.T) {
+	repo := newMemoryRepo()
+	ctx := context.Background()
+	legacy := New(repo, newMemoryTokenRepo(), nil, nil).WithPasswordHashing(PasswordHashing{Algorithm: HashBcrypt, BcryptCost: bcrypt.MinCost})
+	c, err := legacy.Signup(ctx, "proj", SignupInput{Email: "me@example.com", Password: "Abcdefg1"})
+	if err != nil {
+		t.Fatalf("signup: %v", err)
+	}
+	if !strings.HasPrefix(c.PasswordHash, "$2") {
+		t.Fatalf("expected a bcrypt hash, got %q", c.PasswordHash)
+	}
+
+	svc := New(repo, newMemoryTokenRepo(), nil, nil)
+	if _, _, _, err := svc.Login(ctx, "proj", "me@example.com", "Abcdefg1", "", false); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	stored, err := repo.GetByID(ctx, "proj", c.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if !strings.HasPrefix(stored.PasswordHash, "$argon2id$") || stored.Version != c.Version {
+		t.Fatalf("expected an argon2id hash at the same version, got %q at %d", stored.PasswordHash, stored.Version)
+	}
+	if _, _, _, err := svc.Login(ctx, "proj", "me@example.com", "Abcdefg1", "", false); err != nil {
+		t.Fatalf("login with the new hash: %v", err)
+	}
+	if _, _, _, err := legacy.Login(ctx, "proj", "me@example.com", "Abcdefg1", "", false); err != nil {
+		t.Fatalf("bcrypt service login with an argon2id hash: %v", err)
+	}
+}
@@ -4,15 +4,17 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"commercetools-replica/internal/domain"
+	"commercetools-replica/internal/mailer"
 	custrepo "commercetools-replica/internal/repository/customer"
 	tokenrepo "commercetools-replica/internal/repository/token"
-	"golang.org/x/crypto/bcrypt"
 )
 
 var (
@@ -24,32 +26,56 @@ var (
 
 // Service handles customer signup/login flows.
 type Service struct {
-	repo        custrepo.Repository
-	tokens      *tokenManager
-	accessTTL   time.Duration
-	refreshTTL  time.Duration
-	passwordMin int
+	repo       custrepo.Repository
+	groups     customerGroupLookup
+	tokens     *tokenManager
+	mail       tokenMailer
+	throttle   *loginThrottle
+	projects   projectLookup
+	denylists  *denylists
+	hashing    PasswordHashing
+	dummyHash  func() string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
 }
 
-// New creates a Service with sane defaults.
-func New(repo custrepo.Repository, tokens tokenrepo.Repository) *Service {
+// customerGroupLookup resolves the group reference of setCustomerGroup.
+type customerGroupLookup interface {
+	GetByID(ctx context.Context, projectID, id string) (*domain.CustomerGroup, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.CustomerGroup, error)
+}
+
+// tokenMailer delivers reset and verification tokens to the customer.
+type tokenMailer interface {
+	SendToken(ctx context.Context, kind string, data mailer.TemplateData) error
+}
+
+// New creates a Service with sane defaults. mail may be nil, in which case
+// tokens are only returned to the caller.
+func New(repo custrepo.Repository, tokens tokenrepo.Repository, groups customerGroupLookup, mail tokenMailer) *Service {
 	return &Service{
-		repo:        repo,
-		tokens:      newTokenManager(tokens),
-		accessTTL:   48 * time.Hour,
-		refreshTTL:  30 * 24 * time.Hour,
-		passwordMin: 8,
+		repo:       repo,
+		groups:     groups,
+		tokens:     newTokenManager(tokens),
+		mail:       mail,
+		denylists:  &denylists{},
+		hashing:    DefaultPasswordHashing(),
+		dummyHash:  sync.OnceValue(DefaultPasswordHashing().dummyHash),
+		accessTTL:  48 * time.Hour,
+		refreshTTL: 30 * 24 * time.Hour,
 	}
 }
 
 // AddressInput mirrors incoming address payloads.
 type AddressInput struct {
+	Key        string `json:"key"`
 	FirstName  string `json:"firstName"`
 	LastName   string `json:"lastName"`
 	Country    string `json:"country"`
 	StreetName string `json:"streetName"`
 	PostalCode string `json:"postalCode"`
 	City       string `json:"city"`
+	State      string `json:"state"`
 	Email      string `json:"email"`
 	Department string `json:"department"`
 }
@@ -68,32 +94,32 @@ type SignupInput struct {
 
 // Signup registers a new customer within the given project.
 func (s *Service) Signup(ctx context.Context, projectID string, in SignupInput) (*domain.Customer, error) {
+	customer, err := s.newCustomer(ctx, projectID, in)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, *customer)
+}
+
+// newCustomer builds a customer from the signup fields; the first address is
+// the default unless one is chosen.
+func (s *Service) newCustomer(ctx context.Context, projectID string, in SignupInput) (*domain.Customer, error) {
 	email := strings.TrimSpace(strings.ToLower(in.Email))
 	if email == "" {
 		return nil, errors.New("email required")
 	}
 	password := strings.TrimSpace(in.Password)
-	if err := validatePassword(password, s.passwordMin); err != nil {
+	if err := s.checkPassword(ctx, projectID, "password", password); err != nil {
 		return nil, err
 	}
-	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashed, err := s.hashPassword("password", password)
 	if err != nil {
 		return nil, err
 	}
 
 	addresses := make([]domain.CustomerAddress, 0, len(in.Addresses))
 	for _, a := range in.Addresses {
-		addresses = append(addresses, domain.CustomerAddress{
-			ID:         randomAddressID(),
-			FirstName:  a.FirstName,
-			LastName:   a.LastName,
-			Country:    a.Country,
-			StreetName: a.StreetName,
-			PostalCode: a.PostalCode,
-			City:       a.City,
-			Email:      a.Email,
-			Department: a.Department,
-		})
+		addresses = append(addresses, addressFromInput(randomAddressID(), a))
 	}
 
 	shippingID := addressIDFromIndex(addresses, in.DefaultShippingAddress)
@@ -122,23 +148,45 @@ func (s *Service) Signup(ctx context.Context, projectID string, in SignupInput)
 	if billingID != "" {
 		customer.BillingAddressIDs = []string{billingID}
 	}
-
-	return s.repo.Create(ctx, customer)
+	return &customer, nil
 }
 
 // Login validates credentials and returns issued tokens plus the customer.
-func (s *Service) Login(ctx context.Context, projectID, email, password string) (*domain.Customer, string, string, error) {
+// With requireVerifiedEmail set, customers that have not confirmed their email
+// get ErrEmailNotVerified.
+func (s *Service) Login(ctx context.Context, projectID, email, password, clientIP string, requireVerifiedEmail bool) (*domain.Customer, string, string, error) {
 	password = strings.TrimSpace(password)
+	// The attempt counts as a failure until the password checks out, unknown
+	// emails included.
+	keys := loginKeys(email, clientIP)
+	if err := s.throttle.admit(ctx, projectID, keys); err != nil {
+		return nil, "", "", err
+	}
 	c, err := s.repo.GetByEmail(ctx, projectID, email)
 	if err != nil {
 		if err == domain.ErrNotFound {
+			s.hashing.verify(s.dummyHash(), password)
 			return nil, "", "", ErrInvalidCredentials
 		}
 		return nil, "", "", err
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(c.PasswordHash), []byte(password)); err != nil {
+	ok, rehash := s.hashing.verify(c.PasswordHash, password)
+	if !ok {
 		return nil, "", "", ErrInvalidCredentials
 	}
+	if rehash {
+		// Best effort: a hash that could not be replaced is tried again on
+		// the next login.
+		if hashed, err := s.hashing.hash(password); err == nil {
+			_ = s.repo.SetPasswordHash(ctx, projectID, c.ID, c.PasswordHash, hashed)
+		}
+	}
+	if err := s.throttle.succeeded(ctx, projectID, keys); err != nil {
+		return nil, "", "", err
+	}
+	if requireVerifiedEmail && !c.IsEmailVerified {
+		return nil, "", "", ErrEmailNotVerified
+	}
 
 	access, err := s.tokens.Issue(ctx, c.ProjectID, c.ID, "access", s.accessTTL)
 	if err != nil {
@@ -153,8 +201,8 @@ func (s *Service) Login(ctx context.Context, projectID, email, password string)
 
 // LookupByToken returns the customer bound to a valid access token.
 func (s *Service) LookupByToken(ctx context.Context, projectID, token string) (*domain.Customer, error) {
-	meta, ok := s.tokens.Validate(ctx, token)
-	if !ok || meta.ProjectID != projectID {
+	meta, ok := s.tokens.Validate(ctx, projectID, token)
+	if !ok {
 		return nil, ErrInvalidToken
 	}
 	c, err := s.repo.GetByID(ctx, projectID, meta.CustomerID)
@@ -167,11 +215,376 @@ func (s *Service) LookupByToken(ctx context.Context, projectID, token string) (*
 	return c, nil
 }
 
+type ResourceIdentifier struct {
+	TypeID string `json:"typeId,omitempty"`
+	ID     string `json:"id,omitempty"`
+	Key    string `json:"key,omitempty"`
+}
+
+type UpdateInput struct {
+	Version int            `json:"version"`
+	Actions []UpdateAction `json:"actions"`
+}
+
+type UpdateAction struct {
+	Action string
+	raw    json.RawMessage
+}
+
+func (a *UpdateAction) UnmarshalJSON(b []byte) error {
+	var head struct {
+		Action string `json:"action"`
+	}
+	if err := json.Unmarshal(b, &head); err != nil {
+		return err
+	}
+	a.Action = head.Action
+	a.raw = append(json.RawMessage(nil), b...)
+	return nil
+}
+
+func (a UpdateAction) decode(v interface{}) error {
+	if len(a.raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(a.raw, v); err != nil {
+		return fmt.Errorf("invalid %s action: %w", a.Action, err)
+	}
+	return nil
+}
+
+// Update applies the actions to the stored customer if in.Version is still current.
+func (s *Service) Update(ctx context.Context, projectID, id string, in UpdateInput) (*domain.Customer, error) {
+	if len(in.Actions) == 0 {
+		return nil, errors.New("actions required")
+	}
+	if in.Version <= 0 {
+		return nil, errors.New("version required")
+	}
+	c, err := s.repo.GetByID(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	email := c.Email
+	for _, action := range in.Actions {
+		if err := s.apply(ctx, c, action); err != nil {
+			return nil, err
+		}
+	}
+	if err := validate(*c); err != nil {
+		return nil, err
+	}
+	updated, err := s.repo.Update(ctx, *c)
+	if err != nil {
+		return nil, err
+	}
+	// Verification tokens were sent to the old address.
+	if updated.Email != email {
+		if err := s.tokens.RevokeKind(ctx, c.ProjectID, c.ID, emailVerificationKind); err != nil {
+			return nil, err
+		}
+	}
+	return updated, nil
+}
+
+// myCustomerActions are the actions customers may apply to themselves.
+var myCustomerActions = map[string]bool{
+	"setfirstname":              true,
+	"setlastname":               true,
+	"setdateofbirth":            true,
+	"changeemail":               true,
+	"addaddress":                true,
+	"changeaddress":             true,
+	"removeaddress":             true,
+	"setdefaultshippingaddress": true,
+	"setdefaultbillingaddress":  true,
+	"addshippingaddressid":      true,
+	"addbillingaddressid":       true,
+}
+
+// UpdateMe applies the actions of POST /me; actions such as setCustomerGroup
+// are left to the project API.
+func (s *Service) UpdateMe(ctx context.Context, projectID, id string, in UpdateInput) (*domain.Customer, error) {
+	for _, action := range in.Actions {
+		if !myCustomerActions[strings.ToLower(strings.TrimSpace(action.Action))] {
+			return nil, fmt.Errorf("unsupported action %q", action.Action)
+		}
+	}
+	return s.Update(ctx, projectID, id, in)
+}
+
+// addressSelector picks an address of the customer by id or key.
+type addressSelector struct {
+	AddressID  string `json:"addressId"`
+	AddressKey string `json:"addressKey"`
+}
+
+func (a addressSelector) set() bool {
+	return strings.TrimSpace(a.AddressID) != "" || strings.TrimSpace(a.AddressKey) != ""
+}
+
+func (a addressSelector) find(c *domain.Customer) (int, error) {
+	id := strings.TrimSpace(a.AddressID)
+	key := strings.TrimSpace(a.AddressKey)
+	if id == "" && key == "" {
+		return -1, errors.New("addressId or addressKey required")
+	}
+	for i, addr := range c.Addresses {
+		if (id != "" && addr.ID == id) || (id == "" && addr.Key == key) {
+			return i, nil
+		}
+	}
+	if id == "" {
+		id = key
+	}
+	return -1, fmt.Errorf("address %s not found", id)
+}
+
+func (s *Service) apply(ctx context.Context, c *domain.Customer, action UpdateAction) error {
+	switch strings.ToLower(strings.TrimSpace(action.Action)) {
+	case "setcustomergroup":
+		var a struct {
+			CustomerGroup *ResourceIdentifier `json:"customerGroup"`
+		}
+		if err := action.decode(&a); err != nil {
+			return err
+		}
+		// Without a customerGroup the customer leaves its group.
+		c.CustomerGroupID = ""
+		if a.CustomerGroup != nil {
+			g, err := s.resolveCustomerGroup(ctx, c.ProjectID, *a.CustomerGroup)
+			if err != nil {
+				return err
+			}
+			c.CustomerGroupID = g.ID
+		}
+	case "setfirstname":
+		var a struct {
+			FirstName string `json:"firstName"`
+		}
+		if err := action.decode(&a); err != nil {
+			return err
+		}
+		c.FirstName = strings.TrimSpace(a.FirstName)
+	case "setlastname":
+		var a struct {
+			LastName string `json:"lastName"`
+		}
+		if err := action.decode(&a); err != nil {
+			return err
+		}
+		c.LastName = strings.TrimSpace(a.LastName)
+	case "setdateofbirth":
+		var a struct {
+			DateOfBirth string `json:"dateOfBirth"`
+		}
+		if err := action.decode(&a); err != nil {
+			return err
+		}
+		dob := strings.TrimSpace(a.DateOfBirth)
+		if dob != "" {
+			if _, err := time.Parse("2006-01-02", dob); err != nil {
+				return errors.New("dateOfBirth must be a date in YYYY-MM-DD format")
+			}
+		}
+		c.DateOfBirth = dob
+	case "changeemail":
+		var a struct {
+			Email string `json:"email"`
+		}
+		if err := action.decode(&a); err != nil {
+			return err
+		}
+		email := strings.ToLower(strings.TrimSpace(a.Email))
+		if email != c.Email {
+			c.Email = email
+			c.IsEmailVerified = false
+		}
+	case "addaddress":
+		var a struct {
+			Address AddressInput `json:"address"`
+		}
+		if err := action.decode(&a); err != nil {
+			return err
+		}
+		c.Addresses = append(c.Addresses, addressFromInput(randomAddressID(), a.Address))
+	case "changeaddress":
+		var a struct {
+			addressSelector
+			Address AddressInput `json:"address"`
+		}
+		if err := action.decode(&a); err != nil {
+			return err
+		}
+		i, err := a.find(c)
+		if err != nil {
+			return err
+		}
+		c.Addresses[i] = addressFromInput(c.Addresses[i].ID, a.Address)
+	case "removeaddress":
+		var a addressSelector
+		if err := action.decode(&a); err != nil {
+			return err
+		}
+		i, err := a.find(c)
+		if err != nil {
+			return err
+		}
+		removeAddress(c, c.Addresses[i].ID)
+	case "setdefaultshippingaddress", "setdefaultbillingaddress":
+		var a addressSelector
+		if err := action.decode(&a); err != nil {
+			return err
+		}
+		// Without an address the default is unset; the address stays a
+		// shipping or billing address.
+		id := ""
+		if a.set() {
+			i, err := a.find(c)
+			if err != nil {
+				return err
+			}
+			id = c.Addresses[i].ID
+		}
+		if strings.EqualFold(action.Action, "setDefaultShippingAddress") {
+			c.DefaultShippingAddressID = id
+			if id != "" {
+				c.ShippingAddressIDs = appendUnique(c.ShippingAddressIDs, id)
+			}
+		} else {
+			c.DefaultBillingAddressID = id
+			if id != "" {
+				c.BillingAddressIDs = appendUnique(c.BillingAddressIDs, id)
+			}
+		}
+	case "addshippingaddressid", "addbillingaddressid":
+		var a addressSelector
+		if err := action.decode(&a); err != nil {
+			return err
+		}
+		i, err := a.find(c)
+		if err != nil {
+			return err
+		}
+		if strings.EqualFold(action.Action, "addShippingAddressId") {
+			c.ShippingAddressIDs = appendUnique(c.ShippingAddressIDs, c.Addresses[i].ID)
+		} else {
+			c.BillingAddressIDs = appendUnique(c.BillingAddressIDs, c.Addresses[i].ID)
+		}
+	default:
+		return fmt.Errorf("unsupported action %q", action.Action)
+	}
+	return nil
+}
+
+func (s *Service) resolveCustomerGroup(ctx context.Context, projectID string, ref ResourceIdentifier) (*domain.CustomerGroup, error) {
+	id := strings.TrimSpace(ref.ID)
+	key := strings.TrimSpace(ref.Key)
+	if id == "" && key == "" {
+		return nil, errors.New("customer group id or key required")
+	}
+	if s.groups == nil {
+		return nil, errors.New("customer group lookup unavailable")
+	}
+	var (
+		g   *domain.CustomerGroup
+		err error
+	)
+	if id != "" {
+		g, err = s.groups.GetByID(ctx, projectID, id)
+	} else {
+		g, err = s.groups.GetByKey(ctx, projectID, key)
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, errors.New("customer group not found")
+		}
+		return nil, err
+	}
+	return g, nil
+}
+
 // AccessTTLSeconds exposes the access token lifetime in seconds.
 func (s *Service) AccessTTLSeconds() int {
 	return int(s.accessTTL.Seconds())
 }
 
+// validate checks a customer after update actions. Address ids always point
+// at existing addresses because the actions resolve them.
+func validate(c domain.Customer) error {
+	if c.Email == "" {
+		return errors.New("email required")
+	}
+	keys := map[string]struct{}{}
+	for _, a := range c.Addresses {
+		if a.Key == "" {
+			continue
+		}
+		if _, dup := keys[a.Key]; dup {
+			return fmt.Errorf("duplicate address key %s", a.Key)
+		}
+		keys[a.Key] = struct{}{}
+	}
+	return nil
+}
+
+func addressFromInput(id string, a AddressInput) domain.CustomerAddress {
+	return domain.CustomerAddress{
+		ID:         id,
+		Key:        strings.TrimSpace(a.Key),
+		FirstName:  a.FirstName,
+		LastName:   a.LastName,
+		Country:    a.Country,
+		StreetName: a.StreetName,
+		PostalCode: a.PostalCode,
+		City:       a.City,
+		State:      a.State,
+		Email:      a.Email,
+		Department: a.Department,
+	}
+}
+
+// removeAddress drops the address and every reference to it.
+func removeAddress(c *domain.Customer, id string) {
+	var kept []domain.CustomerAddress
+	for _, a := range c.Addresses {
+		if a.ID != id {
+			kept = append(kept, a)
+		}
+	}
+	c.Addresses = kept
+	c.ShippingAddressIDs = removeString(c.ShippingAddressIDs, id)
+	c.BillingAddressIDs = removeString(c.BillingAddressIDs, id)
+	if c.DefaultShippingAddressID == id {
+		c.DefaultShippingAddressID = ""
+	}
+	if c.DefaultBillingAddressID == id {
+		c.DefaultBillingAddressID = ""
+	}
+}
+
+func appendUnique(ids []string, id string) []string {
+	for _, existing := range ids {
+		if existing == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
+
+func removeString(ids []string, id string) []string {
+	var out []string
+	for _, existing := range ids {
+		if existing != id {
+			out = append(out, existing)
+		}
+	}
+	return out
+}
+
 func addressIDFromIndex(addresses []domain.CustomerAddress, idx *int) string {
 	if idx == nil {
 		return ""
@@ -189,27 +602,3 @@ func randomAddressID() string {
 	}
 	return base64.RawURLEncoding.EncodeToString(buf[:])
 }
-
-func validatePassword(p string, min int) error {
-	trimmed := strings.TrimSpace(p)
-	if len(trimmed) < min {
-		return fmt.Errorf("password must be at least %d characters", min)
-	}
-	hasUpper := false
-	hasLower := false
-	hasDigit := false
-	for _, r := range trimmed {
-		switch {
-		case r >= 'A' && r <= 'Z':
-			hasUpper = true
-		case r >= 'a' && r <= 'z':
-			hasLower = true
-		case r >= '0' && r <= '9':
-			hasDigit = true
-		}
-	}
-	if !hasUpper || !hasLower || !hasDigit {
-		return errors.New("password must contain at least 1 uppercase letter, 1 lowercase letter, and 1 number")
-	}
-	return nil
-}
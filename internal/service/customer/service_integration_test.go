@@ -29,7 +29,7 @@ func TestSignupAndLogin_Integration(t *testing.T) {
 
 	repo := customerrepo.NewPostgres(pool, log.New(os.Stdout, "[test] ", log.LstdFlags))
 	tokenRepo := tokenrepo.NewPostgres(pool)
-	svc := New(repo, tokenRepo)
+	svc := New(repo, tokenRepo, nil, nil)
 
 	password := "Abcdefg1"
 	cust, err := svc.Signup(ctx, projectID, SignupInput{
@@ -50,7 +50,7 @@ func TestSignupAndLogin_Integration(t *testing.T) {
 		t.Fatalf("expected created customer, got %+v", cust)
 	}
 
-	_, access, refresh, err := svc.Login(ctx, projectID, "integration@example.com", password)
+	_, access, refresh, err := svc.Login(ctx, projectID, "integration@example.com", password, "", false)
 	if err != nil {
 		t.Fatalf("login: %v", err)
 	}
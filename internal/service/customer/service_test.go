@@ -2,6 +2,10 @@ package customer
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
 	"testing"
 
 	"commercetools-replica/internal/domain"
@@ -33,23 +37,51 @@ func (r *memoryTokenRepo) Create(_ context.Context, token tokenrepo.Token) error
 	return nil
 }
 
-func (r *memoryTokenRepo) Get(_ context.Context, token string) (*tokenrepo.Token, error) {
+func (r *memoryTokenRepo) Get(_ context.Context, projectID, token string) (*tokenrepo.Token, error) {
 	t, ok := r.tokens[token]
-	if !ok {
+	if !ok || t.ProjectID != projectID {
 		return nil, domain.ErrNotFound
 	}
 	clone := t
 	return &clone, nil
 }
 
-func (r *memoryTokenRepo) Delete(_ context.Context, token string) error {
-	if _, ok := r.tokens[token]; !ok {
+func (r *memoryTokenRepo) Delete(_ context.Context, projectID, token string) error {
+	if t, ok := r.tokens[token]; !ok || t.ProjectID != projectID {
 		return domain.ErrNotFound
 	}
 	delete(r.tokens, token)
 	return nil
 }
 
+func (r *memoryTokenRepo) DeleteByCustomer(_ context.Context, projectID, customerID, keep string) error {
+	for token, t := range r.tokens {
+		if t.ProjectID == projectID && t.CustomerID != nil && *t.CustomerID == customerID && token != keep {
+			delete(r.tokens, token)
+		}
+	}
+	return nil
+}
+
+func (r *memoryTokenRepo) DeleteByKind(_ context.Context, projectID, customerID, kind string) error {
+	for token, t := range r.tokens {
+		if t.ProjectID == projectID && t.CustomerID != nil && *t.CustomerID == customerID && t.Kind == kind {
+			delete(r.tokens, token)
+		}
+	}
+	return nil
+}
+
+func (r *memoryTokenRepo) ListByCustomer(_ context.Context, projectID, customerID string) ([]tokenrepo.Token, error) {
+	var out []tokenrepo.Token
+	for _, t := range r.tokens {
+		if t.ProjectID == projectID && t.CustomerID != nil && *t.CustomerID == customerID {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
 func (r *memoryRepo) Create(_ context.Context, c domain.Customer) (*domain.Customer, error) {
 	if r.byProject[c.ProjectID] == nil {
 		r.byProject[c.ProjectID] = make(map[string]domain.Customer)
@@ -61,6 +93,7 @@ func (r *memoryRepo) Create(_ context.Context, c domain.Customer) (*domain.Custo
 	if clone.ID == "" {
 		clone.ID = "cust-" + c.Email
 	}
+	clone.Version = 1
 	r.byProject[c.ProjectID][clone.Email] = clone
 	return &clone, nil
 }
@@ -91,9 +124,273 @@ func (r *memoryRepo) GetByID(_ context.Context, projectID, id string) (*domain.C
 	return nil, domain.ErrNotFound
 }
 
+func (r *memoryRepo) Update(_ context.Context, c domain.Customer) (*domain.Customer, error) {
+	for email, stored := range r.byProject[c.ProjectID] {
+		if stored.ID != c.ID {
+			continue
+		}
+		if stored.Version != c.Version {
+			return nil, domain.ErrConcurrentModification
+		}
+		if _, taken := r.byProject[c.ProjectID][c.Email]; taken && c.Email != email {
+			return nil, domain.ErrAlreadyExists
+		}
+		delete(r.byProject[c.ProjectID], email)
+		c.Version++
+		r.byProject[c.ProjectID][c.Email] = c
+		return &c, nil
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (r *memoryRepo) SetPasswordHash(_ context.Context, projectID, id, oldHash, newHash string) error {
+	for email, stored := range r.byProject[projectID] {
+		if stored.ID == id && stored.PasswordHash == oldHash {
+			stored.PasswordHash = newHash
+			r.byProject[projectID][email] = stored
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepo) List(_ context.Context, projectID, email string, limit, offset int) ([]domain.Customer, int, error) {
+	var out []domain.Customer
+	for _, c := range r.byProject[projectID] {
+		if email == "" || strings.EqualFold(c.Email, email) {
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Email < out[j].Email })
+	total := len(out)
+	if offset > len(out) {
+		offset = len(out)
+	}
+	out = out[offset:]
+	if limit > 0 && limit < len(out) {
+		out = out[:limit]
+	}
+	return out, total, nil
+}
+
+func (r *memoryRepo) Delete(_ context.Context, projectID, id string, version int) (*domain.Customer, error) {
+	for email, c := range r.byProject[projectID] {
+		if c.ID != id {
+			continue
+		}
+		if c.Version != version {
+			return nil, domain.ErrConcurrentModification
+		}
+		delete(r.byProject[projectID], email)
+		return &c, nil
+	}
+	return nil, domain.ErrNotFound
+}
+
+// Erase drops the customer like Delete; the anonymised row of the Postgres
+// repository is invisible to every other method anyway.
+func (r *memoryRepo) Erase(ctx context.Context, projectID, id string, version int, _ string) (*domain.Customer, error) {
+	c, err := r.Delete(ctx, projectID, id, version)
+	if err != nil {
+		return nil, err
+	}
+	erased := domain.Customer{ID: c.ID, ProjectID: projectID, Version: c.Version + 1, Email: "erased-" + c.ID + "@invalid"}
+	return &erased, nil
+}
+
+type stubGroups []domain.CustomerGroup
+
+func (s stubGroups) GetByID(_ context.Context, _, id string) (*domain.CustomerGroup, error) {
+	for i := range s {
+		if s[i].ID == id {
+			return &s[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s stubGroups) GetByKey(_ context.Context, _, key string) (*domain.CustomerGroup, error) {
+	for i := range s {
+		if s[i].Key == key {
+			return &s[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func TestUpdateSetCustomerGroup(t *testing.T) {
+	repo := newMemoryRepo()
+	svc := New(repo, newMemoryTokenRepo(), stubGroups{{ID: "group-1", Key: "wholesale", Name: "Wholesale"}}, nil)
+	ctx := context.Background()
+	c, err := svc.Signup(ctx, "proj", SignupInput{Email: "buyer@example.com", Password: "Abcdefg1"})
+	if err != nil {
+		t.Fatalf("signup: %v", err)
+	}
+
+	update := func(body string) (*domain.Customer, error) {
+		var in UpdateInput
+		if err := json.Unmarshal([]byte(body), &in); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		return svc.Update(ctx, "proj", c.ID, in)
+	}
+
+	updated, err := update(`{"version":1,"actions":[{"action":"setCustomerGroup","customerGroup":{"typeId":"customer-group","key":"wholesale"}}]}`)
+	if err != nil {
+		t.Fatalf("set group: %v", err)
+	}
+	if updated.CustomerGroupID != "group-1" || updated.Version != 2 {
+		t.Fatalf("expected group-1 at version 2, got %+v", updated)
+	}
+
+	cases := []struct {
+		body string
+		want string
+	}{
+		{`{"version":2,"actions":[{"action":"setCustomerGroup","customerGroup":{"key":"retail"}}]}`, "customer group not found"},
+		{`{"version":2,"actions":[{"action":"setCustomerGroup","customerGroup":{"typeId":"customer-group"}}]}`, "customer group id or key required"},
+		{`{"version":2,"actions":[{"action":"setCompanyName"}]}`, `unsupported action "setCompanyName"`},
+		{`{"version":2,"actions":[]}`, "actions required"},
+	}
+	for _, tc := range cases {
+		if _, err := update(tc.body); err == nil || err.Error() != tc.want {
+			t.Fatalf("%s: expected %q, got %v", tc.body, tc.want, err)
+		}
+	}
+	if _, err := update(`{"version":1,"actions":[{"action":"setCustomerGroup"}]}`); !errors.Is(err, domain.ErrConcurrentModification) {
+		t.Fatalf("expected version conflict, got %v", err)
+	}
+
+	updated, err = update(`{"version":2,"actions":[{"action":"setCustomerGroup"}]}`)
+	if err != nil {
+		t.Fatalf("clear group: %v", err)
+	}
+	if updated.CustomerGroupID != "" {
+		t.Fatalf("expected the group to be cleared, got %q", updated.CustomerGroupID)
+	}
+}
+
+func TestUpdateMeProfileActions(t *testing.T) {
+	repo := newMemoryRepo()
+	svc := New(repo, newMemoryTokenRepo(), nil, nil)
+	ctx := context.Background()
+	c, err := svc.Signup(ctx, "proj", SignupInput{Email: "me@example.com", Password: "Abcdefg1"})
+	if err != nil {
+		t.Fatalf("signup: %v", err)
+	}
+	if _, err := svc.Signup(ctx, "proj", SignupInput{Email: "taken@example.com", Password: "Abcdefg1"}); err != nil {
+		t.Fatalf("signup: %v", err)
+	}
+
+	var in UpdateInput
+	body := `{"version":1,"actions":[
+		{"action":"setFirstName","firstName":" Ada "},
+		{"action":"setLastName","lastName":"Lovelace"},
+		{"action":"setDateOfBirth","dateOfBirth":"1815-12-10"},
+		{"action":"changeEmail","email":" Ada@Example.com "}
+	]}`
+	if err := json.Unmarshal([]byte(body), &in); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	updated, err := svc.UpdateMe(ctx, "proj", c.ID, in)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.FirstName != "Ada" || updated.LastName != "Lovelace" || updated.DateOfBirth != "1815-12-10" || updated.Email != "ada@example.com" || updated.Version != 2 {
+		t.Fatalf("unexpected update result %+v", updated)
+	}
+
+	cases := []struct {
+		body string
+		want string
+	}{
+		{`{"version":2,"actions":[{"action":"setDateOfBirth","dateOfBirth":"10.12.1815"}]}`, "dateOfBirth must be a date in YYYY-MM-DD format"},
+		{`{"version":2,"actions":[{"action":"changeEmail","email":" "}]}`, "email required"},
+		{`{"version":2,"actions":[{"action":"setCustomerGroup"}]}`, `unsupported action "setCustomerGroup"`},
+	}
+	for _, tc := range cases {
+		var in UpdateInput
+		if err := json.Unmarshal([]byte(tc.body), &in); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if _, err := svc.UpdateMe(ctx, "proj", c.ID, in); err == nil || err.Error() != tc.want {
+			t.Fatalf("expected %q, got %v", tc.want, err)
+		}
+	}
+	if err := json.Unmarshal([]byte(`{"version":2,"actions":[{"action":"changeEmail","email":"taken@example.com"}]}`), &in); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if _, err := svc.UpdateMe(ctx, "proj", c.ID, in); !errors.Is(err, domain.ErrAlreadyExists) {
+		t.Fatalf("expected email taken, got %v", err)
+	}
+	if _, err := svc.UpdateMe(ctx, "proj", c.ID, UpdateInput{Version: 1, Actions: in.Actions}); !errors.Is(err, domain.ErrConcurrentModification) {
+		t.Fatalf("expected version conflict, got %v", err)
+	}
+}
+
+func TestUpdateMeAddressActions(t *testing.T) {
+	repo := newMemoryRepo()
+	svc := New(repo, newMemoryTokenRepo(), nil, nil)
+	ctx := context.Background()
+	c, err := svc.Signup(ctx, "proj", SignupInput{Email: "me@example.com", Password: "Abcdefg1",
+		Addresses: []AddressInput{{Country: "DE", City: "Berlin"}}})
+	if err != nil {
+		t.Fatalf("signup: %v", err)
+	}
+	first := c.Addresses[0].ID
+
+	update := func(body string) (*domain.Customer, error) {
+		var in UpdateInput
+		if err := json.Unmarshal([]byte(body), &in); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		in.Version = c.Version
+		return svc.UpdateMe(ctx, "proj", c.ID, in)
+	}
+
+	c, err = update(`{"actions":[
+		{"action":"addAddress","address":{"key":"office","country":"GB","city":"London"}},
+		{"action":"setDefaultBillingAddress","addressKey":"office"},
+		{"action":"addShippingAddressId","addressKey":"office"},
+		{"action":"changeAddress","addressKey":"office","address":{"key":"office","country":"GB","city":"Leeds"}}
+	]}`)
+	if err != nil {
+		t.Fatalf("add address: %v", err)
+	}
+	if len(c.Addresses) != 2 || c.Addresses[1].City != "Leeds" {
+		t.Fatalf("expected the changed office address, got %+v", c.Addresses)
+	}
+	office := c.Addresses[1].ID
+	if c.DefaultBillingAddressID != office || len(c.BillingAddressIDs) != 2 || len(c.ShippingAddressIDs) != 2 {
+		t.Fatalf("unexpected address ids %+v", c)
+	}
+
+	cases := []struct {
+		body string
+		want string
+	}{
+		{`{"actions":[{"action":"removeAddress","addressId":"nope"}]}`, "address nope not found"},
+		{`{"actions":[{"action":"addBillingAddressId"}]}`, "addressId or addressKey required"},
+		{`{"actions":[{"action":"addAddress","address":{"key":"office"}}]}`, "duplicate address key office"},
+	}
+	for _, tc := range cases {
+		if _, err := update(tc.body); err == nil || err.Error() != tc.want {
+			t.Fatalf("expected %q, got %v", tc.want, err)
+		}
+	}
+
+	c, err = update(`{"actions":[{"action":"removeAddress","addressKey":"office"},{"action":"setDefaultShippingAddress"}]}`)
+	if err != nil {
+		t.Fatalf("remove address: %v", err)
+	}
+	if len(c.Addresses) != 1 || c.DefaultBillingAddressID != "" || c.DefaultShippingAddressID != "" ||
+		len(c.BillingAddressIDs) != 1 || c.BillingAddressIDs[0] != first || len(c.ShippingAddressIDs) != 1 {
+		t.Fatalf("expected every reference to the office address removed, got %+v", c)
+	}
+}
+
 func TestSignupAndLogin_SucceedsWithTrimmedPassword(t *testing.T) {
 	repo := newMemoryRepo()
-	svc := New(repo, newMemoryTokenRepo())
+	svc := New(repo, newMemoryTokenRepo(), nil, nil)
 
 	ctx := context.Background()
 	projectID := "proj-1"
@@ -112,7 +409,7 @@ func TestSignupAndLogin_SucceedsWithTrimmedPassword(t *testing.T) {
 		t.Fatalf("unexpected customer %+v", customer)
 	}
 
-	_, _, _, err = svc.Login(ctx, projectID, "user@example.com", "Abcdefg1")
+	_, _, _, err = svc.Login(ctx, projectID, "user@example.com", "Abcdefg1", "", false)
 	if err != nil {
 		t.Fatalf("login failed with trimmed password: %v", err)
 	}
@@ -129,7 +426,7 @@ func TestValidatePassword_FailsOnWeakValues(t *testing.T) {
 		{"no digit", "Abcdefgh"},
 	}
 	for _, tc := range cases {
-		if err := validatePassword(tc.pass, 8); err == nil {
+		if err := validatePassword("password", tc.pass, domain.DefaultPasswordPolicy(), false); err == nil {
 			t.Fatalf("expected error for case %s", tc.name)
 		}
 	}
@@ -137,7 +434,7 @@ func TestValidatePassword_FailsOnWeakValues(t *testing.T) {
 
 func TestLogin_InvalidCredentials(t *testing.T) {
 	repo := newMemoryRepo()
-	svc := New(repo, newMemoryTokenRepo())
+	svc := New(repo, newMemoryTokenRepo(), nil, nil)
 	ctx := context.Background()
 
 	if _, err := svc.Signup(ctx, "proj", SignupInput{
@@ -148,10 +445,10 @@ func TestLogin_InvalidCredentials(t *testing.T) {
 		t.Fatalf("signup: %v", err)
 	}
 
-	if _, _, _, err := svc.Login(ctx, "proj", "user@example.com", "wrongpass"); err != ErrInvalidCredentials {
+	if _, _, _, err := svc.Login(ctx, "proj", "user@example.com", "wrongpass", "", false); err != ErrInvalidCredentials {
 		t.Fatalf("expected ErrInvalidCredentials, got %v", err)
 	}
-	if _, _, _, err := svc.Login(ctx, "proj", "missing@example.com", "Abcdefg1"); err != ErrInvalidCredentials {
+	if _, _, _, err := svc.Login(ctx, "proj", "missing@example.com", "Abcdefg1", "", false); err != ErrInvalidCredentials {
 		t.Fatalf("expected ErrInvalidCredentials for missing user, got %v", err)
 	}
 }
@@ -0,0 +1,146 @@
+package customer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	attemptrepo "commercetools-replica/internal/repository/loginattempt"
+)
+
+// ErrTooManyAttempts is returned by Login while the email or the client IP is
+// blocked; see TooManyAttemptsError.
+var ErrTooManyAttempts = errors.New("too many login attempts")
+
+// TooManyAttemptsError tells the caller how long the login stays blocked.
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	return fmt.Sprintf("too many login attempts, retry in %d seconds", e.RetryAfterSeconds())
+}
+
+func (e *TooManyAttemptsError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+// RetryAfterSeconds rounds RetryAfter up to whole seconds.
+func (e *TooManyAttemptsError) RetryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+// LoginPolicy configures the login throttle; zero limits are off.
+type LoginPolicy struct {
+	// FreeAttempts failures of an email go unpunished. Each further one blocks
+	// the email for BackoffBase, doubled per failure up to BackoffMax.
+	FreeAttempts int
+	BackoffBase  time.Duration
+	BackoffMax   time.Duration
+	// MaxAttempts failures of an email, or IPMaxAttempts from one client IP,
+	// block it for Lockout.
+	MaxAttempts   int
+	IPMaxAttempts int
+	Lockout       time.Duration
+	// Window is how long a failure counts after the last one.
+	Window time.Duration
+}
+
+// limit returns the rules of a key kind. Client IPs are shared behind NAT, so
+// they only get the lockout.
+func (p LoginPolicy) limit(kind string) attemptrepo.Limit {
+	if kind == attemptrepo.KindIP {
+		return attemptrepo.Limit{Window: p.Window, Max: p.IPMaxAttempts, Lockout: p.Lockout}
+	}
+	return attemptrepo.Limit{
+		Window:      p.Window,
+		Free:        p.FreeAttempts,
+		BackoffBase: p.BackoffBase,
+		BackoffMax:  p.BackoffMax,
+		Max:         p.MaxAttempts,
+		Lockout:     p.Lockout,
+	}
+}
+
+// loginThrottle keeps the failed logins in the repository so every API
+// replica sees them. A nil loginThrottle lets every attempt through.
+type loginThrottle struct {
+	attempts attemptrepo.Repository
+	policy   LoginPolicy
+	now      func() time.Time
+}
+
+// WithLoginThrottle makes Login count failures per email and client IP and
+// refuse attempts while they are blocked.
+func (s *Service) WithLoginThrottle(attempts attemptrepo.Repository, policy LoginPolicy) *Service {
+	s.throttle = &loginThrottle{attempts: attempts, policy: policy, now: time.Now}
+	return s
+}
+
+func loginKeys(email, clientIP string) []attemptrepo.Key {
+	keys := []attemptrepo.Key{emailKey(email)}
+	if clientIP = strings.TrimSpace(clientIP); clientIP != "" {
+		keys = append(keys, attemptrepo.Key{Kind: attemptrepo.KindIP, Subject: clientIP})
+	}
+	return keys
+}
+
+func emailKey(email string) attemptrepo.Key {
+	return attemptrepo.Key{Kind: attemptrepo.KindEmail, Subject: strings.ToLower(strings.TrimSpace(email))}
+}
+
+// admit counts the attempt as a failure of every key before the password is
+// checked and refuses it while a key is blocked. Keys counted before a blocked
+// one are forgiven, so refused attempts count nowhere.
+func (t *loginThrottle) admit(ctx context.Context, projectID string, keys []attemptrepo.Key) error {
+	if t == nil {
+		return nil
+	}
+	now := t.now()
+	for i, key := range keys {
+		until, err := t.attempts.Attempt(ctx, projectID, key, now, t.policy.limit(key.Kind))
+		if err != nil {
+			return err
+		}
+		if wait := until.Sub(now); wait > 0 {
+			for _, counted := range keys[:i] {
+				if err := t.attempts.Forgive(ctx, projectID, counted); err != nil {
+					return err
+				}
+			}
+			return &TooManyAttemptsError{RetryAfter: wait}
+		}
+	}
+	return nil
+}
+
+// succeeded clears the email after a good password; the client IP only gets
+// back the failure admit counted.
+func (t *loginThrottle) succeeded(ctx context.Context, projectID string, keys []attemptrepo.Key) error {
+	if t == nil {
+		return nil
+	}
+	for _, key := range keys {
+		var err error
+		if key.Kind == attemptrepo.KindEmail {
+			err = t.attempts.Clear(ctx, projectID, key)
+		} else {
+			err = t.attempts.Forgive(ctx, projectID, key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clear forgets the failures of the email; the client IP keeps its count.
+func (t *loginThrottle) clear(ctx context.Context, projectID, email string) error {
+	if t == nil {
+		return nil
+	}
+	return t.attempts.Clear(ctx, projectID, emailKey(email))
+}
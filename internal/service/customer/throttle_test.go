@@ -0,0 +1,156 @@
+package customer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	attemptrepo "commercetools-replica/internal/repository/loginattempt"
+)
+
+type memoryAttempt struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+type memoryAttemptRepo struct {
+	rows map[string]*memoryAttempt
+}
+
+func attemptKey(projectID string, key attemptrepo.Key) string {
+	return projectID + "/" + key.Kind + "/" + key.Subject
+}
+
+func (r *memoryAttemptRepo) Attempt(_ context.Context, projectID string, key attemptrepo.Key, now time.Time, limit attemptrepo.Limit) (time.Time, error) {
+	row, ok := r.rows[attemptKey(projectID, key)]
+	if !ok {
+		row = &memoryAttempt{lastFailure: now}
+		r.rows[attemptKey(projectID, key)] = row
+	}
+	if row.blockedUntil.After(now) {
+		return row.blockedUntil, nil
+	}
+	if limit.Window > 0 && row.lastFailure.Before(now.Add(-limit.Window)) {
+		row.failures = 0
+	}
+	row.failures++
+	row.lastFailure = now
+	if block := limit.BlockFor(row.failures); block > 0 {
+		row.blockedUntil = now.Add(block)
+	}
+	return time.Time{}, nil
+}
+
+func (r *memoryAttemptRepo) Forgive(_ context.Context, projectID string, key attemptrepo.Key) error {
+	if row, ok := r.rows[attemptKey(projectID, key)]; ok && row.failures > 0 {
+		row.failures--
+	}
+	return nil
+}
+
+func (r *memoryAttemptRepo) Clear(_ context.Context, projectID string, key attemptrepo.Key) error {
+	delete(r.rows, attemptKey(projectID, key))
+	return nil
+}
+
+func TestLoginPolicyBlockFor(t *testing.T) {
+	policy := LoginPolicy{FreeAttempts: 2, BackoffBase: time.Second, BackoffMax: 5 * time.Second, MaxAttempts: 8, IPMaxAttempts: 20, Lockout: time.Hour}
+	cases := []struct {
+		kind     string
+		failures int
+		want     time.Duration
+	}{
+		{attemptrepo.KindEmail, 2, 0},
+		{attemptrepo.KindEmail, 3, time.Second},
+		{attemptrepo.KindEmail, 4, 2 * time.Second},
+		{attemptrepo.KindEmail, 5, 4 * time.Second},
+		{attemptrepo.KindEmail, 7, 5 * time.Second},
+		{attemptrepo.KindEmail, 8, time.Hour},
+		{attemptrepo.KindIP, 19, 0},
+		{attemptrepo.KindIP, 20, time.Hour},
+	}
+	for _, tc := range cases {
+		if got := policy.limit(tc.kind).BlockFor(tc.failures); got != tc.want {
+			t.Fatalf("%s after %d failures: expected %s, got %s", tc.kind, tc.failures, tc.want, got)
+		}
+	}
+	if got := (LoginPolicy{}).limit(attemptrepo.KindEmail).BlockFor(100); got != 0 {
+		t.Fatalf("expected a zero policy to never block, got %s", got)
+	}
+}
+
+func TestLoginThrottle(t *testing.T) {
+	attempts := &memoryAttemptRepo{rows: make(map[string]*memoryAttempt)}
+	svc := New(newMemoryRepo(), newMemoryTokenRepo(), nil, nil).WithLoginThrottle(attempts, LoginPolicy{
+		FreeAttempts:  1,
+		BackoffBase:   time.Second,
+		BackoffMax:    time.Minute,
+		MaxAttempts:   4,
+		IPMaxAttempts: 6,
+		Lockout:       15 * time.Minute,
+		Window:        time.Hour,
+	})
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc.throttle.now = func() time.Time { return now }
+	ctx := context.Background()
+	if _, err := svc.Signup(ctx, "proj", SignupInput{Email: "me@example.com", Password: "Abcdefg1"}); err != nil {
+		t.Fatalf("signup: %v", err)
+	}
+	login := func(email, password, ip string) error {
+		_, _, _, err := svc.Login(ctx, "proj", email, password, ip, false)
+		return err
+	}
+
+	if err := login("me@example.com", "wrong", "10.0.0.1"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected a free failure, got %v", err)
+	}
+	if err := login("ME@example.com", "wrong", "10.0.0.2"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected invalid credentials, got %v", err)
+	}
+	// The second failure backs the email off, whatever the IP.
+	err := login("me@example.com", "Abcdefg1", "10.0.0.3")
+	var throttled *TooManyAttemptsError
+	if !errors.As(err, &throttled) || throttled.RetryAfterSeconds() != 1 {
+		t.Fatalf("expected a 1s backoff, got %v", err)
+	}
+	now = now.Add(time.Second)
+	if err := login("me@example.com", "Abcdefg1", "10.0.0.3"); err != nil {
+		t.Fatalf("login after the backoff: %v", err)
+	}
+
+	// A successful login starts the email over; unknown emails count too.
+	for i := 0; i < 4; i++ {
+		now = now.Add(time.Minute)
+		if err := login("nobody@example.com", "wrong", "10.0.0.9"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("failure %d: expected invalid credentials, got %v", i+1, err)
+		}
+	}
+	if err := login("nobody@example.com", "wrong", "10.0.0.8"); !errors.As(err, &throttled) || throttled.RetryAfter != 15*time.Minute {
+		t.Fatalf("expected a lockout, got %v", err)
+	}
+
+	// The IP locks out after its own limit, for every email.
+	for _, email := range []string{"a@example.com", "b@example.com"} {
+		now = now.Add(time.Minute)
+		if err := login(email, "wrong", "10.0.0.9"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("%s: expected invalid credentials, got %v", email, err)
+		}
+	}
+	if err := login("me@example.com", "Abcdefg1", "10.0.0.9"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("expected the IP lockout to cover other emails, got %v", err)
+	}
+	if err := login("me@example.com", "Abcdefg1", "10.0.0.7"); err != nil {
+		t.Fatalf("login from another IP: %v", err)
+	}
+
+	// Old failures fall out of the window.
+	now = now.Add(2 * time.Hour)
+	if err := login("nobody@example.com", "wrong", "10.0.0.8"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected the count to start over, got %v", err)
+	}
+	if row := attempts.rows[attemptKey("proj", emailKey("nobody@example.com"))]; row.failures != 1 {
+		t.Fatalf("expected 1 failure in the window, got %d", row.failures)
+	}
+}
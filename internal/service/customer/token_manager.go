@@ -28,33 +28,78 @@ func newTokenManager(repo tokenrepo.Repository) *tokenManager {
 }
 
 func (m *tokenManager) Issue(ctx context.Context, projectID, customerID, kind string, ttl time.Duration) (string, error) {
-	expiresAt := time.Now().Add(ttl)
+	t, err := m.issueToken(ctx, projectID, customerID, kind, ttl)
+	if err != nil {
+		return "", err
+	}
+	return t.Token, nil
+}
+
+func (m *tokenManager) issueToken(ctx context.Context, projectID, customerID, kind string, ttl time.Duration) (*tokenrepo.Token, error) {
+	now := time.Now()
 	for i := 0; i < 5; i++ {
 		token, err := randomToken()
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		customer := customerID
-		err = m.repo.Create(ctx, tokenrepo.Token{
+		t := tokenrepo.Token{
 			Token:      token,
 			ProjectID:  projectID,
 			CustomerID: &customer,
 			Kind:       kind,
-			ExpiresAt:  expiresAt,
-		})
+			ExpiresAt:  now.Add(ttl),
+			CreatedAt:  now,
+		}
+		err = m.repo.Create(ctx, t)
 		if err == nil {
-			return token, nil
+			return &t, nil
 		}
 		if errors.Is(err, domain.ErrAlreadyExists) {
 			continue
 		}
-		return "", err
+		return nil, err
 	}
-	return "", errors.New("token collision")
+	return nil, errors.New("token collision")
+}
+
+// Consume validates a single-use token of the given kind and project and
+// deletes it. A non-empty customerID only accepts tokens of that customer.
+func (m *tokenManager) Consume(ctx context.Context, projectID, customerID, token, kind string) (tokenMeta, bool) {
+	meta, err := m.repo.Get(ctx, projectID, token)
+	if err != nil || meta.Kind != kind || meta.CustomerID == nil {
+		return tokenMeta{}, false
+	}
+	if customerID != "" && *meta.CustomerID != customerID {
+		return tokenMeta{}, false
+	}
+	// A token that cannot be deleted was consumed concurrently.
+	if err := m.repo.Delete(ctx, projectID, token); err != nil {
+		return tokenMeta{}, false
+	}
+	if time.Now().After(meta.ExpiresAt) {
+		return tokenMeta{}, false
+	}
+	return tokenMeta{
+		CustomerID: *meta.CustomerID,
+		ProjectID:  meta.ProjectID,
+		ExpiresAt:  meta.ExpiresAt,
+	}, true
+}
+
+// RevokeOthers deletes every token of the customer except keep.
+func (m *tokenManager) RevokeOthers(ctx context.Context, projectID, customerID, keep string) error {
+	return m.repo.DeleteByCustomer(ctx, projectID, customerID, keep)
+}
+
+// RevokeKind deletes the tokens of one kind of the customer.
+func (m *tokenManager) RevokeKind(ctx context.Context, projectID, customerID, kind string) error {
+	return m.repo.DeleteByKind(ctx, projectID, customerID, kind)
 }
 
-func (m *tokenManager) Validate(ctx context.Context, token string) (tokenMeta, bool) {
-	meta, err := m.repo.Get(ctx, token)
+// Validate accepts unexpired access tokens of the project.
+func (m *tokenManager) Validate(ctx context.Context, projectID, token string) (tokenMeta, bool) {
+	meta, err := m.repo.Get(ctx, projectID, token)
 	if err != nil {
 		return tokenMeta{}, false
 	}
@@ -62,7 +107,7 @@ func (m *tokenManager) Validate(ctx context.Context, token string) (tokenMeta, b
 		return tokenMeta{}, false
 	}
 	if time.Now().After(meta.ExpiresAt) {
-		_ = m.repo.Delete(ctx, token)
+		_ = m.repo.Delete(ctx, projectID, token)
 		return tokenMeta{}, false
 	}
 	return tokenMeta{
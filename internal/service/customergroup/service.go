@@ -0,0 +1,123 @@
+package customergroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"commercetools-replica/internal/domain"
+	customergrouprepo "commercetools-replica/internal/repository/customergroup"
+	"commercetools-replica/internal/service/updateaction"
+)
+
+type Service struct {
+	repo customergrouprepo.Repository
+}
+
+func New(repo customergrouprepo.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// ListPage returns one page of customer groups, oldest first; limit 0 means no limit.
+func (s *Service) ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.CustomerGroup, int, error) {
+	return s.repo.List(ctx, projectID, limit, offset)
+}
+
+func (s *Service) Get(ctx context.Context, projectID, id string) (*domain.CustomerGroup, error) {
+	return s.repo.GetByID(ctx, projectID, id)
+}
+
+func (s *Service) GetByKey(ctx context.Context, projectID, key string) (*domain.CustomerGroup, error) {
+	return s.repo.GetByKey(ctx, projectID, key)
+}
+
+// CustomerGroupDraft follows commercetools, which calls the name groupName on creation.
+type CustomerGroupDraft struct {
+	Key       string `json:"key,omitempty"`
+	GroupName string `json:"groupName"`
+}
+
+func (s *Service) Create(ctx context.Context, projectID string, draft CustomerGroupDraft) (*domain.CustomerGroup, error) {
+	g := domain.CustomerGroup{
+		ProjectID: projectID,
+		Key:       strings.TrimSpace(draft.Key),
+		Name:      strings.TrimSpace(draft.GroupName),
+	}
+	if err := validate(g); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, g)
+}
+
+type UpdateInput struct {
+	Version int            `json:"version"`
+	Actions []UpdateAction `json:"actions"`
+}
+
+type UpdateAction = updateaction.Action
+
+// Update applies the actions to the stored customer group if in.Version is still current.
+func (s *Service) Update(ctx context.Context, projectID, id string, in UpdateInput) (*domain.CustomerGroup, error) {
+	if len(in.Actions) == 0 {
+		return nil, errors.New("actions required")
+	}
+	if in.Version <= 0 {
+		return nil, errors.New("version required")
+	}
+	g, err := s.repo.GetByID(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if g.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	for _, action := range in.Actions {
+		if err := apply(g, action); err != nil {
+			return nil, err
+		}
+	}
+	if err := validate(*g); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, *g)
+}
+
+// Delete removes the group; it fails with domain.ErrReferenceExists while customers belong to it.
+func (s *Service) Delete(ctx context.Context, projectID, id string, version int) (*domain.CustomerGroup, error) {
+	if version <= 0 {
+		return nil, errors.New("version required")
+	}
+	return s.repo.Delete(ctx, projectID, id, version)
+}
+
+func apply(g *domain.CustomerGroup, action UpdateAction) error {
+	switch strings.ToLower(strings.TrimSpace(action.Action)) {
+	case "changename":
+		var a struct {
+			Name string `json:"name"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		g.Name = strings.TrimSpace(a.Name)
+	case "setkey":
+		var a struct {
+			Key string `json:"key"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		g.Key = strings.TrimSpace(a.Key)
+	default:
+		return fmt.Errorf("unsupported action %q", action.Action)
+	}
+	return nil
+}
+
+func validate(g domain.CustomerGroup) error {
+	if g.Name == "" {
+		return errors.New("name required")
+	}
+	return nil
+}
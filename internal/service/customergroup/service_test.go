@@ -0,0 +1,108 @@
+package customergroup
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"commercetools-replica/internal/domain"
+	customergrouprepo "commercetools-replica/internal/repository/customergroup"
+)
+
+// groupRepo holds the one customer group a test works on.
+type groupRepo struct {
+	customergrouprepo.Repository
+	group *domain.CustomerGroup
+}
+
+func (r *groupRepo) GetByID(_ context.Context, _, id string) (*domain.CustomerGroup, error) {
+	if r.group == nil || r.group.ID != id {
+		return nil, domain.ErrNotFound
+	}
+	g := *r.group
+	return &g, nil
+}
+
+func (r *groupRepo) Create(_ context.Context, g domain.CustomerGroup) (*domain.CustomerGroup, error) {
+	g.ID, g.Version = "group-1", 1
+	r.group = &g
+	return &g, nil
+}
+
+func (r *groupRepo) Update(_ context.Context, g domain.CustomerGroup) (*domain.CustomerGroup, error) {
+	g.Version++
+	r.group = &g
+	return &g, nil
+}
+
+func TestServiceCreateTakesGroupName(t *testing.T) {
+	repo := &groupRepo{}
+	svc := New(repo)
+	ctx := context.Background()
+
+	// Drafts name the group groupName; a name field is not read.
+	var draft CustomerGroupDraft
+	if err := json.Unmarshal([]byte(`{"key":" wholesale ","groupName":" Wholesale ","name":"Ignored"}`), &draft); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	g, err := svc.Create(ctx, "proj", draft)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if g.Key != "wholesale" || g.Name != "Wholesale" {
+		t.Fatalf("expected trimmed key and group name, got %+v", g)
+	}
+
+	var retail CustomerGroupDraft
+	if err := json.Unmarshal([]byte(`{"key":"retail","name":"Retail"}`), &retail); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if _, err := svc.Create(ctx, "proj", retail); err == nil || err.Error() != "name required" {
+		t.Fatalf("expected name required without groupName, got %v", err)
+	}
+	if repo.group.Key != "wholesale" {
+		t.Fatalf("expected only wholesale stored, got %+v", repo.group)
+	}
+}
+
+func TestServiceUpdateNameAndKey(t *testing.T) {
+	repo := &groupRepo{}
+	svc := New(repo)
+	ctx := context.Background()
+	g, err := svc.Create(ctx, "proj", CustomerGroupDraft{Key: "wholesale", GroupName: "Wholesale"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	update := func(actions string) (*domain.CustomerGroup, error) {
+		in := UpdateInput{Version: g.Version}
+		if err := json.Unmarshal([]byte(actions), &in.Actions); err != nil {
+			t.Fatalf("unmarshal %s: %v", actions, err)
+		}
+		return svc.Update(ctx, "proj", g.ID, in)
+	}
+
+	// Updates call the name name, and the key can be removed.
+	g, err = update(`[{"action":"changeName","name":" B2B "},{"action":"setKey"}]`)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if g.Name != "B2B" || g.Key != "" || g.Version != 2 {
+		t.Fatalf("expected B2B without a key at version 2, got %+v", g)
+	}
+
+	for _, tc := range []struct {
+		actions string
+		want    string
+	}{
+		{`[{"action":"changeName","groupName":"Retail"}]`, "name required"},
+		{`[{"action":"setDescription","description":"Resellers"}]`, `unsupported action "setDescription"`},
+	} {
+		if _, err := update(tc.actions); err == nil || err.Error() != tc.want {
+			t.Fatalf("%s: expected %q, got %v", tc.actions, tc.want, err)
+		}
+	}
+	if repo.group.Name != "B2B" || repo.group.Version != 2 {
+		t.Fatalf("expected failed updates not to be stored, got %+v", repo.group)
+	}
+}
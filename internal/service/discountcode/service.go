@@ -0,0 +1,277 @@
+package discountcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"commercetools-replica/internal/domain"
+	"commercetools-replica/internal/predicate"
+	discountcoderepo "commercetools-replica/internal/repository/discountcode"
+	"commercetools-replica/internal/service/updateaction"
+)
+
+type Service struct {
+	repo          discountcoderepo.Repository
+	cartDiscounts cartDiscountGetter
+}
+
+// cartDiscountGetter checks the cart discounts a code references.
+type cartDiscountGetter interface {
+	Get(ctx context.Context, projectID, id string) (*domain.CartDiscount, error)
+}
+
+func New(repo discountcoderepo.Repository, cartDiscounts cartDiscountGetter) *Service {
+	return &Service{repo: repo, cartDiscounts: cartDiscounts}
+}
+
+// ListPage returns one page of discount codes, oldest first; limit 0 means no limit.
+func (s *Service) ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.DiscountCode, int, error) {
+	return s.repo.List(ctx, projectID, limit, offset)
+}
+
+func (s *Service) Get(ctx context.Context, projectID, id string) (*domain.DiscountCode, error) {
+	return s.repo.GetByID(ctx, projectID, id)
+}
+
+func (s *Service) GetByKey(ctx context.Context, projectID, key string) (*domain.DiscountCode, error) {
+	return s.repo.GetByKey(ctx, projectID, key)
+}
+
+func (s *Service) GetByCode(ctx context.Context, projectID, code string) (*domain.DiscountCode, error) {
+	return s.repo.GetByCode(ctx, projectID, code)
+}
+
+type CartDiscountReference struct {
+	TypeID string `json:"typeId,omitempty"`
+	ID     string `json:"id"`
+}
+
+type DiscountCodeDraft struct {
+	Key           string                  `json:"key,omitempty"`
+	Code          string                  `json:"code"`
+	Name          domain.LocalizedString  `json:"name,omitempty"`
+	Description   domain.LocalizedString  `json:"description,omitempty"`
+	CartDiscounts []CartDiscountReference `json:"cartDiscounts"`
+	CartPredicate string                  `json:"cartPredicate,omitempty"`
+	// IsActive defaults to true like in commercetools.
+	IsActive                   *bool      `json:"isActive,omitempty"`
+	MaxApplications            *int       `json:"maxApplications,omitempty"`
+	MaxApplicationsPerCustomer *int       `json:"maxApplicationsPerCustomer,omitempty"`
+	ValidFrom                  *time.Time `json:"validFrom,omitempty"`
+	ValidUntil                 *time.Time `json:"validUntil,omitempty"`
+}
+
+func (s *Service) Create(ctx context.Context, projectID string, draft DiscountCodeDraft) (*domain.DiscountCode, error) {
+	active := true
+	if draft.IsActive != nil {
+		active = *draft.IsActive
+	}
+	c := domain.DiscountCode{
+		ProjectID:                  projectID,
+		Key:                        strings.TrimSpace(draft.Key),
+		Code:                       strings.TrimSpace(draft.Code),
+		Name:                       draft.Name.Clone(),
+		Description:                draft.Description.Clone(),
+		CartDiscountIDs:            referenceIDs(draft.CartDiscounts),
+		CartPredicate:              strings.TrimSpace(draft.CartPredicate),
+		IsActive:                   active,
+		MaxApplications:            draft.MaxApplications,
+		MaxApplicationsPerCustomer: draft.MaxApplicationsPerCustomer,
+		ValidFrom:                  draft.ValidFrom,
+		ValidUntil:                 draft.ValidUntil,
+	}
+	if c.Code == "" {
+		return nil, errors.New("code required")
+	}
+	if err := s.validate(ctx, c); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, c)
+}
+
+type UpdateInput struct {
+	Version int            `json:"version"`
+	Actions []UpdateAction `json:"actions"`
+}
+
+type UpdateAction = updateaction.Action
+
+// Update applies the actions to the stored code if in.Version is still current.
+// The code itself cannot be changed.
+func (s *Service) Update(ctx context.Context, projectID, id string, in UpdateInput) (*domain.DiscountCode, error) {
+	if len(in.Actions) == 0 {
+		return nil, errors.New("actions required")
+	}
+	if in.Version <= 0 {
+		return nil, errors.New("version required")
+	}
+	c, err := s.repo.GetByID(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	for _, action := range in.Actions {
+		if err := apply(c, action); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.validate(ctx, *c); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, *c)
+}
+
+func (s *Service) Delete(ctx context.Context, projectID, id string, version int) (*domain.DiscountCode, error) {
+	if version <= 0 {
+		return nil, errors.New("version required")
+	}
+	return s.repo.Delete(ctx, projectID, id, version)
+}
+
+func apply(c *domain.DiscountCode, action UpdateAction) error {
+	switch strings.ToLower(strings.TrimSpace(action.Action)) {
+	case "setname":
+		var a struct {
+			Name domain.LocalizedString `json:"name"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		c.Name = a.Name.Clone()
+	case "setdescription":
+		var a struct {
+			Description domain.LocalizedString `json:"description"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		c.Description = a.Description.Clone()
+	case "setkey":
+		var a struct {
+			Key string `json:"key"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		c.Key = strings.TrimSpace(a.Key)
+	case "changecartdiscounts":
+		var a struct {
+			CartDiscounts []CartDiscountReference `json:"cartDiscounts"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		c.CartDiscountIDs = referenceIDs(a.CartDiscounts)
+	case "setcartpredicate":
+		var a struct {
+			CartPredicate string `json:"cartPredicate"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		c.CartPredicate = strings.TrimSpace(a.CartPredicate)
+	case "changeisactive":
+		var a struct {
+			IsActive bool `json:"isActive"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		c.IsActive = a.IsActive
+	case "setmaxapplications":
+		var a struct {
+			MaxApplications *int `json:"maxApplications"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		c.MaxApplications = a.MaxApplications
+	case "setmaxapplicationspercustomer":
+		var a struct {
+			MaxApplicationsPerCustomer *int `json:"maxApplicationsPerCustomer"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		c.MaxApplicationsPerCustomer = a.MaxApplicationsPerCustomer
+	case "setvalidfrom":
+		var a struct {
+			ValidFrom *time.Time `json:"validFrom"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		c.ValidFrom = a.ValidFrom
+	case "setvaliduntil":
+		var a struct {
+			ValidUntil *time.Time `json:"validUntil"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		c.ValidUntil = a.ValidUntil
+	case "setvalidfromanduntil":
+		var a struct {
+			ValidFrom  *time.Time `json:"validFrom"`
+			ValidUntil *time.Time `json:"validUntil"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		c.ValidFrom, c.ValidUntil = a.ValidFrom, a.ValidUntil
+	default:
+		return fmt.Errorf("unsupported action %q", action.Action)
+	}
+	return nil
+}
+
+func (s *Service) validate(ctx context.Context, c domain.DiscountCode) error {
+	if len(c.CartDiscountIDs) == 0 {
+		return errors.New("cartDiscounts required")
+	}
+	for _, id := range c.CartDiscountIDs {
+		if _, err := s.cartDiscounts.Get(ctx, c.ProjectID, id); err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				return fmt.Errorf("cart discount %s not found", id)
+			}
+			return err
+		}
+	}
+	if c.CartPredicate != "" {
+		if _, err := predicate.Compile(c.CartPredicate, predicate.CartSchema); err != nil {
+			return fmt.Errorf("invalid cartPredicate: %w", err)
+		}
+	}
+	if c.MaxApplications != nil && *c.MaxApplications < 1 {
+		return errors.New("maxApplications must be positive")
+	}
+	if c.MaxApplicationsPerCustomer != nil && *c.MaxApplicationsPerCustomer < 1 {
+		return errors.New("maxApplicationsPerCustomer must be positive")
+	}
+	if c.ValidFrom != nil && c.ValidUntil != nil && !c.ValidFrom.Before(*c.ValidUntil) {
+		return errors.New("validFrom must be before validUntil")
+	}
+	return nil
+}
+
+func referenceIDs(refs []CartDiscountReference) []string {
+	ids := make([]string, 0, len(refs))
+	seen := map[string]struct{}{}
+	for _, ref := range refs {
+		id := strings.TrimSpace(ref.ID)
+		if id == "" {
+			continue
+		}
+		if _, dup := seen[id]; dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -0,0 +1,172 @@
+package discountcode
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"commercetools-replica/internal/domain"
+	discountcoderepo "commercetools-replica/internal/repository/discountcode"
+)
+
+// codeRepo keeps discount codes by id; the service tests need nothing else.
+type codeRepo struct {
+	discountcoderepo.Repository
+	byID map[string]domain.DiscountCode
+}
+
+func (r *codeRepo) GetByID(_ context.Context, _, id string) (*domain.DiscountCode, error) {
+	c, ok := r.byID[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &c, nil
+}
+
+func (r *codeRepo) Create(_ context.Context, c domain.DiscountCode) (*domain.DiscountCode, error) {
+	c.ID, c.Version = "code-"+c.Code, 1
+	r.byID[c.ID] = c
+	return &c, nil
+}
+
+func (r *codeRepo) Update(_ context.Context, c domain.DiscountCode) (*domain.DiscountCode, error) {
+	c.Version++
+	r.byID[c.ID] = c
+	return &c, nil
+}
+
+// projectCartDiscounts holds cart discount ids per project.
+type projectCartDiscounts map[string][]string
+
+func (s projectCartDiscounts) Get(_ context.Context, projectID, id string) (*domain.CartDiscount, error) {
+	for _, existing := range s[projectID] {
+		if existing == id {
+			return &domain.CartDiscount{ID: id, ProjectID: projectID}, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func newTestService() (*Service, *codeRepo) {
+	repo := &codeRepo{byID: map[string]domain.DiscountCode{}}
+	return New(repo, projectCartDiscounts{"proj": {"cd1", "cd2"}, "other": {"cd9"}}), repo
+}
+
+func updateCode(svc *Service, c *domain.DiscountCode, actions string) (*domain.DiscountCode, error) {
+	in := UpdateInput{Version: c.Version}
+	if err := json.Unmarshal([]byte(actions), &in.Actions); err != nil {
+		return nil, err
+	}
+	return svc.Update(context.Background(), "proj", c.ID, in)
+}
+
+func intPtr(v int) *int { return &v }
+
+func TestServiceCreateResolvesCartDiscounts(t *testing.T) {
+	svc, repo := newTestService()
+	ctx := context.Background()
+
+	c, err := svc.Create(ctx, "proj", DiscountCodeDraft{
+		Code:          " SPRING ",
+		CartDiscounts: []CartDiscountReference{{TypeID: "cart-discount", ID: "cd1"}, {ID: " cd1 "}, {ID: ""}, {ID: "cd2"}},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if c.Code != "SPRING" || !c.IsActive || len(c.CartDiscountIDs) != 2 || c.CartDiscountIDs[0] != "cd1" || c.CartDiscountIDs[1] != "cd2" {
+		t.Fatalf("expected an active SPRING code with cd1 and cd2 once each, got %+v", c)
+	}
+
+	for _, tc := range []struct {
+		refs []CartDiscountReference
+		want string
+	}{
+		{nil, "cartDiscounts required"},
+		{[]CartDiscountReference{{ID: " "}}, "cartDiscounts required"},
+		// Cart discounts of another project are not found.
+		{[]CartDiscountReference{{ID: "cd9"}}, "cart discount cd9 not found"},
+	} {
+		if _, err := svc.Create(ctx, "proj", DiscountCodeDraft{Code: "OTHER", CartDiscounts: tc.refs}); err == nil || err.Error() != tc.want {
+			t.Fatalf("%+v: expected %q, got %v", tc.refs, tc.want, err)
+		}
+	}
+	if _, err := svc.Create(ctx, "proj", DiscountCodeDraft{Code: " ", CartDiscounts: []CartDiscountReference{{ID: "cd1"}}}); err == nil || err.Error() != "code required" {
+		t.Fatalf("expected a code to be required, got %v", err)
+	}
+	if len(repo.byID) != 1 {
+		t.Fatalf("expected only SPRING stored, got %+v", repo.byID)
+	}
+}
+
+func TestServiceApplicationLimits(t *testing.T) {
+	svc, _ := newTestService()
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		draft DiscountCodeDraft
+		want  string
+	}{
+		{DiscountCodeDraft{MaxApplications: intPtr(0)}, "maxApplications must be positive"},
+		{DiscountCodeDraft{MaxApplicationsPerCustomer: intPtr(-1)}, "maxApplicationsPerCustomer must be positive"},
+		{DiscountCodeDraft{CartPredicate: "currency ="}, "invalid cartPredicate: expected value but found end of predicate at position 10"},
+	} {
+		tc.draft.Code = "LIMITED"
+		tc.draft.CartDiscounts = []CartDiscountReference{{ID: "cd1"}}
+		if _, err := svc.Create(ctx, "proj", tc.draft); err == nil || err.Error() != tc.want {
+			t.Fatalf("expected %q, got %v", tc.want, err)
+		}
+	}
+
+	c, err := svc.Create(ctx, "proj", DiscountCodeDraft{
+		Code:                       "LIMITED",
+		CartDiscounts:              []CartDiscountReference{{ID: "cd1"}},
+		MaxApplications:            intPtr(100),
+		MaxApplicationsPerCustomer: intPtr(1),
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	c, err = updateCode(svc, c, `[
+		{"action":"setMaxApplications"},
+		{"action":"setMaxApplicationsPerCustomer","maxApplicationsPerCustomer":2}
+	]`)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if c.MaxApplications != nil || c.MaxApplicationsPerCustomer == nil || *c.MaxApplicationsPerCustomer != 2 {
+		t.Fatalf("expected no overall limit and 2 per customer, got %+v", c)
+	}
+}
+
+func TestServiceUpdateKeepsTheCode(t *testing.T) {
+	svc, repo := newTestService()
+	c, err := svc.Create(context.Background(), "proj", DiscountCodeDraft{Code: "SPRING", CartDiscounts: []CartDiscountReference{{ID: "cd1"}}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// The code itself cannot change, everything around it can.
+	if _, err := updateCode(svc, c, `[{"action":"changeCode","code":"SUMMER"}]`); err == nil || err.Error() != `unsupported action "changeCode"` {
+		t.Fatalf("expected changeCode to be unsupported, got %v", err)
+	}
+	if _, err := updateCode(svc, c, `[{"action":"changeCartDiscounts","cartDiscounts":[{"id":"cd9"}]}]`); err == nil || err.Error() != "cart discount cd9 not found" {
+		t.Fatalf("expected a foreign cart discount to be rejected, got %v", err)
+	}
+	c, err = updateCode(svc, c, `[
+		{"action":"changeCartDiscounts","cartDiscounts":[{"typeId":"cart-discount","id":"cd2"}]},
+		{"action":"changeIsActive","isActive":false},
+		{"action":"setValidFromAndUntil","validFrom":"2026-03-01T00:00:00Z","validUntil":"2026-06-01T00:00:00Z"}
+	]`)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if c.Code != "SPRING" || c.IsActive || len(c.CartDiscountIDs) != 1 || c.CartDiscountIDs[0] != "cd2" || c.ValidUntil == nil {
+		t.Fatalf("unexpected code %+v", c)
+	}
+	if _, err := updateCode(svc, c, `[{"action":"setValidUntil","validUntil":"2026-03-01T00:00:00Z"}]`); err == nil || err.Error() != "validFrom must be before validUntil" {
+		t.Fatalf("expected an empty validity window to be rejected, got %v", err)
+	}
+	if stored := repo.byID[c.ID]; stored.Version != 2 || !stored.ValidUntil.Equal(*c.ValidUntil) {
+		t.Fatalf("expected failed updates not to be stored, got %+v", stored)
+	}
+}
@@ -0,0 +1,296 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"commercetools-replica/internal/domain"
+	inventoryrepo "commercetools-replica/internal/repository/inventory"
+	"commercetools-replica/internal/service/updateaction"
+)
+
+type Service struct {
+	repo     inventoryrepo.Repository
+	channels channelLookup
+}
+
+// channelLookup resolves supply channels; they need the InventorySupply role.
+type channelLookup interface {
+	GetByID(ctx context.Context, projectID, id string) (*domain.Channel, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.Channel, error)
+}
+
+func New(repo inventoryrepo.Repository, channels channelLookup) *Service {
+	return &Service{repo: repo, channels: channels}
+}
+
+// ListPage returns one page of inventory entries, oldest first; limit 0 means no limit.
+func (s *Service) ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.InventoryEntry, int, error) {
+	return s.repo.List(ctx, projectID, limit, offset)
+}
+
+func (s *Service) Get(ctx context.Context, projectID, id string) (*domain.InventoryEntry, error) {
+	return s.repo.GetByID(ctx, projectID, id)
+}
+
+func (s *Service) GetByKey(ctx context.Context, projectID, key string) (*domain.InventoryEntry, error) {
+	return s.repo.GetByKey(ctx, projectID, key)
+}
+
+// ChannelReference points to the supply channel of an entry by id or key.
+type ChannelReference struct {
+	TypeID string `json:"typeId,omitempty"`
+	ID     string `json:"id,omitempty"`
+	Key    string `json:"key,omitempty"`
+}
+
+type InventoryEntryDraft struct {
+	Key               string            `json:"key,omitempty"`
+	SKU               string            `json:"sku"`
+	SupplyChannel     *ChannelReference `json:"supplyChannel,omitempty"`
+	QuantityOnStock   int64             `json:"quantityOnStock"`
+	RestockableInDays *int              `json:"restockableInDays,omitempty"`
+	ExpectedDelivery  *time.Time        `json:"expectedDelivery,omitempty"`
+}
+
+// Create stores a new entry; its whole stock is available.
+func (s *Service) Create(ctx context.Context, projectID string, draft InventoryEntryDraft) (*domain.InventoryEntry, error) {
+	channelID, err := s.supplyChannel(ctx, projectID, draft.SupplyChannel)
+	if err != nil {
+		return nil, err
+	}
+	e := domain.InventoryEntry{
+		ProjectID:         projectID,
+		Key:               strings.TrimSpace(draft.Key),
+		SKU:               strings.TrimSpace(draft.SKU),
+		SupplyChannelID:   channelID,
+		QuantityOnStock:   draft.QuantityOnStock,
+		AvailableQuantity: draft.QuantityOnStock,
+		RestockableInDays: draft.RestockableInDays,
+		ExpectedDelivery:  draft.ExpectedDelivery,
+	}
+	if err := validate(e); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, e)
+}
+
+type UpdateInput struct {
+	Version int            `json:"version"`
+	Actions []UpdateAction `json:"actions"`
+}
+
+type UpdateAction = updateaction.Action
+
+// Update applies the actions to the stored entry if in.Version is still current.
+func (s *Service) Update(ctx context.Context, projectID, id string, in UpdateInput) (*domain.InventoryEntry, error) {
+	if len(in.Actions) == 0 {
+		return nil, errors.New("actions required")
+	}
+	if in.Version <= 0 {
+		return nil, errors.New("version required")
+	}
+	e, err := s.repo.GetByID(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if e.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	for _, action := range in.Actions {
+		if err := s.apply(ctx, e, action); err != nil {
+			return nil, err
+		}
+	}
+	if err := validate(*e); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, *e)
+}
+
+func (s *Service) Delete(ctx context.Context, projectID, id string, version int) (*domain.InventoryEntry, error) {
+	if version <= 0 {
+		return nil, errors.New("version required")
+	}
+	return s.repo.Delete(ctx, projectID, id, version)
+}
+
+// ApplyToProducts sets the availability of every variant with inventory
+// entries, in the current and the staged data.
+func (s *Service) ApplyToProducts(ctx context.Context, projectID string, products []domain.Product) error {
+	var skus []string
+	for i := range products {
+		for _, v := range variantsOf(&products[i]) {
+			if v.SKU != "" {
+				skus = append(skus, v.SKU)
+			}
+		}
+	}
+	if len(skus) == 0 {
+		return nil
+	}
+	entries, err := s.repo.ListBySKUs(ctx, projectID, skus)
+	if err != nil {
+		return err
+	}
+	Apply(entries, products)
+	return nil
+}
+
+// Apply sets Availability in place on the variants whose SKU has entries.
+func Apply(entries []domain.InventoryEntry, products []domain.Product) {
+	bySKU := map[string][]domain.InventoryEntry{}
+	for _, e := range entries {
+		bySKU[e.SKU] = append(bySKU[e.SKU], e)
+	}
+	for i := range products {
+		for _, v := range variantsOf(&products[i]) {
+			v.Availability = availability(bySKU[v.SKU])
+		}
+	}
+}
+
+func availability(entries []domain.InventoryEntry) *domain.VariantAvailability {
+	if len(entries) == 0 {
+		return nil
+	}
+	out := &domain.VariantAvailability{}
+	for _, e := range entries {
+		a := e.Availability()
+		if e.SupplyChannelID == "" {
+			out.IsOnStock, out.RestockableInDays, out.AvailableQuantity = a.IsOnStock, a.RestockableInDays, a.AvailableQuantity
+			continue
+		}
+		if out.Channels == nil {
+			out.Channels = map[string]domain.ChannelAvailability{}
+		}
+		out.Channels[e.SupplyChannelID] = a
+	}
+	return out
+}
+
+func variantsOf(p *domain.Product) []*domain.ProductVariant {
+	var out []*domain.ProductVariant
+	for _, data := range []*domain.ProductData{&p.Current, &p.Staged} {
+		out = append(out, &data.MasterVariant)
+		for j := range data.Variants {
+			out = append(out, &data.Variants[j])
+		}
+	}
+	return out
+}
+
+func (s *Service) apply(ctx context.Context, e *domain.InventoryEntry, action UpdateAction) error {
+	switch strings.ToLower(strings.TrimSpace(action.Action)) {
+	case "addquantity", "removequantity", "changequantity":
+		var a struct {
+			Quantity int64 `json:"quantity"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		if a.Quantity < 0 {
+			return errors.New("quantity must not be negative")
+		}
+		// The available quantity moves with the stock, keeping what orders
+		// already took off it.
+		delta := a.Quantity
+		switch strings.ToLower(strings.TrimSpace(action.Action)) {
+		case "removequantity":
+			delta = -a.Quantity
+		case "changequantity":
+			delta = a.Quantity - e.QuantityOnStock
+		}
+		e.QuantityOnStock += delta
+		e.AvailableQuantity += delta
+	case "setrestockableindays":
+		var a struct {
+			RestockableInDays *int `json:"restockableInDays"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		e.RestockableInDays = a.RestockableInDays
+	case "setexpecteddelivery":
+		var a struct {
+			ExpectedDelivery *time.Time `json:"expectedDelivery"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		e.ExpectedDelivery = a.ExpectedDelivery
+	case "setkey":
+		var a struct {
+			Key string `json:"key"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		e.Key = strings.TrimSpace(a.Key)
+	case "setsupplychannel":
+		var a struct {
+			SupplyChannel *ChannelReference `json:"supplyChannel"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		id, err := s.supplyChannel(ctx, e.ProjectID, a.SupplyChannel)
+		if err != nil {
+			return err
+		}
+		e.SupplyChannelID = id
+	default:
+		return fmt.Errorf("unsupported action %q", action.Action)
+	}
+	return nil
+}
+
+func validate(e domain.InventoryEntry) error {
+	if e.SKU == "" {
+		return errors.New("sku required")
+	}
+	if e.QuantityOnStock < 0 {
+		return errors.New("quantityOnStock must not be negative")
+	}
+	if e.RestockableInDays != nil && *e.RestockableInDays < 0 {
+		return errors.New("restockableInDays must not be negative")
+	}
+	return nil
+}
+
+// supplyChannel returns the id of the referenced channel, or "" for none.
+func (s *Service) supplyChannel(ctx context.Context, projectID string, ref *ChannelReference) (string, error) {
+	if ref == nil {
+		return "", nil
+	}
+	id := strings.TrimSpace(ref.ID)
+	key := strings.TrimSpace(ref.Key)
+	if id == "" && key == "" {
+		return "", errors.New("supply channel id or key required")
+	}
+	if s.channels == nil {
+		return "", errors.New("channel lookup unavailable")
+	}
+	var (
+		c   *domain.Channel
+		err error
+	)
+	if id != "" {
+		c, err = s.channels.GetByID(ctx, projectID, id)
+	} else {
+		c, err = s.channels.GetByKey(ctx, projectID, key)
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return "", errors.New("supply channel not found")
+		}
+		return "", err
+	}
+	if !c.HasRole(domain.ChannelRoleInventorySupply) {
+		return "", fmt.Errorf("channel %s lacks the %s role", c.Key, domain.ChannelRoleInventorySupply)
+	}
+	return c.ID, nil
+}
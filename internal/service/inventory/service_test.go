@@ -0,0 +1,266 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"commercetools-replica/internal/domain"
+	inventoryrepo "commercetools-replica/internal/repository/inventory"
+)
+
+// entryRepo keeps inventory entries by id and records the SKUs looked up
+// for products.
+type entryRepo struct {
+	inventoryrepo.Repository
+	byID   map[string]domain.InventoryEntry
+	lookup []string
+}
+
+func newEntryRepo(entries ...domain.InventoryEntry) *entryRepo {
+	r := &entryRepo{byID: map[string]domain.InventoryEntry{}}
+	for _, e := range entries {
+		r.byID[e.ID] = e
+	}
+	return r
+}
+
+func (r *entryRepo) GetByID(_ context.Context, _, id string) (*domain.InventoryEntry, error) {
+	e, ok := r.byID[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &e, nil
+}
+
+func (r *entryRepo) ListBySKUs(_ context.Context, _ string, skus []string) ([]domain.InventoryEntry, error) {
+	r.lookup = skus
+	var out []domain.InventoryEntry
+	for _, e := range r.byID {
+		for _, sku := range skus {
+			if e.SKU == sku {
+				out = append(out, e)
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+func (r *entryRepo) Create(_ context.Context, e domain.InventoryEntry) (*domain.InventoryEntry, error) {
+	e.ID, e.Version = "entry-"+e.SKU, 1
+	r.byID[e.ID] = e
+	return &e, nil
+}
+
+func (r *entryRepo) Update(_ context.Context, e domain.InventoryEntry) (*domain.InventoryEntry, error) {
+	e.Version++
+	r.byID[e.ID] = e
+	return &e, nil
+}
+
+func updateEntry(svc *Service, e *domain.InventoryEntry, actions string) (*domain.InventoryEntry, error) {
+	in := UpdateInput{Version: e.Version}
+	if err := json.Unmarshal([]byte(actions), &in.Actions); err != nil {
+		return nil, err
+	}
+	return svc.Update(context.Background(), "proj", e.ID, in)
+}
+
+func TestServiceCreateStartsFullyAvailable(t *testing.T) {
+	repo := newEntryRepo()
+	svc := New(repo, nil)
+	ctx := context.Background()
+
+	e, err := svc.Create(ctx, "proj", InventoryEntryDraft{SKU: " sku-1 ", QuantityOnStock: 5})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if e.SKU != "sku-1" || e.AvailableQuantity != 5 || e.SupplyChannelID != "" {
+		t.Fatalf("expected trimmed sku and full availability, got %+v", e)
+	}
+
+	negative := -1
+	for _, tc := range []struct {
+		draft InventoryEntryDraft
+		want  string
+	}{
+		{InventoryEntryDraft{SKU: " "}, "sku required"},
+		{InventoryEntryDraft{SKU: "sku-2", QuantityOnStock: -2}, "quantityOnStock must not be negative"},
+		{InventoryEntryDraft{SKU: "sku-2", RestockableInDays: &negative}, "restockableInDays must not be negative"},
+		// A supply channel needs a lookup.
+		{InventoryEntryDraft{SKU: "sku-2", SupplyChannel: &ChannelReference{Key: "warehouse"}}, "channel lookup unavailable"},
+	} {
+		if _, err := svc.Create(ctx, "proj", tc.draft); err == nil || err.Error() != tc.want {
+			t.Fatalf("expected %q, got %v", tc.want, err)
+		}
+	}
+	if len(repo.byID) != 1 {
+		t.Fatalf("expected only sku-1 stored, got %+v", repo.byID)
+	}
+}
+
+type stubChannels []domain.Channel
+
+func (s stubChannels) GetByID(_ context.Context, _, id string) (*domain.Channel, error) {
+	for i := range s {
+		if s[i].ID == id {
+			return &s[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s stubChannels) GetByKey(_ context.Context, _, key string) (*domain.Channel, error) {
+	for i := range s {
+		if s[i].Key == key {
+			return &s[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func TestServiceSupplyChannel(t *testing.T) {
+	channels := stubChannels{
+		{ID: "ch-1", Key: "warehouse", Roles: []string{domain.ChannelRoleInventorySupply}},
+		{ID: "ch-2", Key: "web", Roles: []string{domain.ChannelRoleProductDistribution}},
+	}
+	svc := New(newEntryRepo(), channels)
+	ctx := context.Background()
+
+	e, err := svc.Create(ctx, "proj", InventoryEntryDraft{SKU: "sku-1", SupplyChannel: &ChannelReference{Key: "warehouse"}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if e.SupplyChannelID != "ch-1" {
+		t.Fatalf("expected the channel id, got %+v", e)
+	}
+	cases := []struct {
+		ref  ChannelReference
+		want string
+	}{
+		{ChannelReference{Key: "web"}, "channel web lacks the InventorySupply role"},
+		{ChannelReference{ID: "nope"}, "supply channel not found"},
+		{ChannelReference{TypeID: "channel"}, "supply channel id or key required"},
+	}
+	for _, tc := range cases {
+		ref := tc.ref
+		if _, err := svc.Create(ctx, "proj", InventoryEntryDraft{SKU: "sku-2", SupplyChannel: &ref}); err == nil || err.Error() != tc.want {
+			t.Fatalf("expected %q, got %v", tc.want, err)
+		}
+	}
+
+	if _, err := updateEntry(svc, e, `[{"action":"setSupplyChannel","supplyChannel":{"id":"ch-2"}}]`); err == nil || err.Error() != "channel web lacks the InventorySupply role" {
+		t.Fatalf("expected the web channel to be refused, got %v", err)
+	}
+	if e, err = updateEntry(svc, e, `[{"action":"setSupplyChannel"}]`); err != nil || e.SupplyChannelID != "" {
+		t.Fatalf("expected the supply channel to be removed, got %+v, %v", e, err)
+	}
+}
+
+func TestServiceQuantityActions(t *testing.T) {
+	// An order took three units off the available quantity.
+	repo := newEntryRepo(domain.InventoryEntry{ID: "entry-1", ProjectID: "proj", Version: 1, SKU: "sku-1", QuantityOnStock: 10, AvailableQuantity: 7})
+	svc := New(repo, nil)
+	e := &domain.InventoryEntry{ID: "entry-1", Version: 1}
+
+	e, err := updateEntry(svc, e, `[
+		{"action":"addQuantity","quantity":5},
+		{"action":"removeQuantity","quantity":2},
+		{"action":"setRestockableInDays","restockableInDays":3}
+	]`)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if e.QuantityOnStock != 13 || e.AvailableQuantity != 10 || e.RestockableInDays == nil || *e.RestockableInDays != 3 {
+		t.Fatalf("expected the reservation kept through add and remove, got %+v", e)
+	}
+
+	// changeQuantity sets the stock and still keeps the three reserved units.
+	e, err = updateEntry(svc, e, `[{"action":"changeQuantity","quantity":4}]`)
+	if err != nil {
+		t.Fatalf("change quantity: %v", err)
+	}
+	if e.QuantityOnStock != 4 || e.AvailableQuantity != 1 || !e.Availability().IsOnStock {
+		t.Fatalf("expected stock 4 and available 1, got %+v", e)
+	}
+	e, err = updateEntry(svc, e, `[{"action":"removeQuantity","quantity":2}]`)
+	if err != nil || e.AvailableQuantity != -1 || e.Availability().IsOnStock {
+		t.Fatalf("expected an oversold entry to be out of stock, got %+v, %v", e, err)
+	}
+
+	for _, tc := range []struct {
+		actions string
+		want    string
+	}{
+		{`[{"action":"removeQuantity","quantity":3}]`, "quantityOnStock must not be negative"},
+		{`[{"action":"addQuantity","quantity":-1}]`, "quantity must not be negative"},
+		{`[{"action":"setRestockableInDays","restockableInDays":-1}]`, "restockableInDays must not be negative"},
+	} {
+		if _, err := updateEntry(svc, e, tc.actions); err == nil || err.Error() != tc.want {
+			t.Fatalf("%s: expected %q, got %v", tc.actions, tc.want, err)
+		}
+	}
+	if stored := repo.byID["entry-1"]; stored.Version != e.Version || stored.QuantityOnStock != 2 {
+		t.Fatalf("expected failed updates not to be stored, got %+v", stored)
+	}
+}
+
+func TestServiceApplyToProducts(t *testing.T) {
+	repo := newEntryRepo(
+		domain.InventoryEntry{ID: "entry-2", SKU: "sku-2", AvailableQuantity: 3},
+		domain.InventoryEntry{ID: "entry-9", SKU: "sku-9", AvailableQuantity: 1},
+	)
+	svc := New(repo, nil)
+	products := []domain.Product{{Current: domain.ProductData{
+		MasterVariant: domain.ProductVariant{ID: 1},
+		Variants:      []domain.ProductVariant{{ID: 2, SKU: "sku-2"}},
+	}}}
+	if err := svc.ApplyToProducts(context.Background(), "proj", products); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if len(repo.lookup) != 1 || repo.lookup[0] != "sku-2" {
+		t.Fatalf("expected only the variant skus to be looked up, got %v", repo.lookup)
+	}
+	if a := products[0].Current.Variants[0].Availability; a == nil || a.AvailableQuantity != 3 {
+		t.Fatalf("expected the entry's availability, got %+v", a)
+	}
+	if products[0].Current.MasterVariant.Availability != nil {
+		t.Fatal("expected no availability for a variant without sku")
+	}
+
+	repo.lookup = nil
+	if err := svc.ApplyToProducts(context.Background(), "proj", []domain.Product{{}}); err != nil || repo.lookup != nil {
+		t.Fatalf("expected no lookup without skus, got %v, %v", repo.lookup, err)
+	}
+}
+
+func TestApplyAvailability(t *testing.T) {
+	restock := 2
+	entries := []domain.InventoryEntry{
+		{SKU: "sku-1", AvailableQuantity: 4, RestockableInDays: &restock},
+		{SKU: "sku-1", SupplyChannelID: "channel-1", AvailableQuantity: 0},
+	}
+	products := []domain.Product{{
+		Current: domain.ProductData{
+			MasterVariant: domain.ProductVariant{ID: 1, SKU: "sku-1"},
+			Variants:      []domain.ProductVariant{{ID: 2, SKU: "sku-2"}},
+		},
+		Staged: domain.ProductData{MasterVariant: domain.ProductVariant{ID: 1, SKU: "sku-1"}},
+	}}
+	Apply(entries, products)
+
+	a := products[0].Current.MasterVariant.Availability
+	if a == nil || !a.IsOnStock || a.AvailableQuantity != 4 || a.RestockableInDays == nil || *a.RestockableInDays != 2 {
+		t.Fatalf("unexpected top-level availability %+v", a)
+	}
+	if ch, ok := a.Channels["channel-1"]; !ok || ch.IsOnStock {
+		t.Fatalf("expected an out-of-stock channel entry, got %+v", a.Channels)
+	}
+	if products[0].Staged.MasterVariant.Availability == nil {
+		t.Fatalf("expected staged availability")
+	}
+	if products[0].Current.Variants[0].Availability != nil {
+		t.Fatalf("expected no availability without entries")
+	}
+}
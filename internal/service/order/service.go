@@ -0,0 +1,110 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"commercetools-replica/internal/domain"
+	orderrepo "commercetools-replica/internal/repository/order"
+)
+
+type Service struct {
+	repo  orderrepo.Repository
+	carts cartGetter
+}
+
+// cartGetter loads the cart an order is created from.
+type cartGetter interface {
+	Get(ctx context.Context, projectID, id string) (*domain.Cart, error)
+}
+
+func New(repo orderrepo.Repository, carts cartGetter) *Service {
+	return &Service{repo: repo, carts: carts}
+}
+
+// OrderFromCartDraft references the cart to order. Carts have no version of
+// their own, so Version is accepted but not checked.
+type OrderFromCartDraft struct {
+	ID          string `json:"id"`
+	Version     int    `json:"version,omitempty"`
+	OrderNumber string `json:"orderNumber,omitempty"`
+}
+
+// Create orders the customer's active cart. A non-empty storeKey only
+// accepts carts of that store, as do the other methods taking one.
+func (s *Service) Create(ctx context.Context, projectID, customerID, storeKey string, draft OrderFromCartDraft) (*domain.Order, error) {
+	return s.createWithOwner(ctx, projectID, storeKey, &customerID, nil, draft)
+}
+
+// CreateAnonymous orders the anonymous session's active cart.
+func (s *Service) CreateAnonymous(ctx context.Context, projectID, anonymousID, storeKey string, draft OrderFromCartDraft) (*domain.Order, error) {
+	return s.createWithOwner(ctx, projectID, storeKey, nil, &anonymousID, draft)
+}
+
+func (s *Service) Get(ctx context.Context, projectID, customerID, storeKey, id string) (*domain.Order, error) {
+	return s.getWithOwner(ctx, projectID, storeKey, id, &customerID, nil)
+}
+
+func (s *Service) GetAnonymous(ctx context.Context, projectID, anonymousID, storeKey, id string) (*domain.Order, error) {
+	return s.getWithOwner(ctx, projectID, storeKey, id, nil, &anonymousID)
+}
+
+// ListPage returns one page of the customer's orders, newest first.
+func (s *Service) ListPage(ctx context.Context, projectID, customerID, storeKey string, limit, offset int) ([]domain.Order, int, error) {
+	return s.repo.ListByCustomer(ctx, projectID, customerID, storeKey, limit, offset)
+}
+
+func (s *Service) ListPageAnonymous(ctx context.Context, projectID, anonymousID, storeKey string, limit, offset int) ([]domain.Order, int, error) {
+	return s.repo.ListByAnonymous(ctx, projectID, anonymousID, storeKey, limit, offset)
+}
+
+func (s *Service) createWithOwner(ctx context.Context, projectID, storeKey string, customerID, anonymousID *string, draft OrderFromCartDraft) (*domain.Order, error) {
+	cartID := strings.TrimSpace(draft.ID)
+	if cartID == "" {
+		return nil, errors.New("cart id required")
+	}
+	cart, err := s.carts.Get(ctx, projectID, cartID)
+	if err != nil {
+		return nil, err
+	}
+	if !ownedBy(*cart, customerID, anonymousID) || !inStore(*cart, storeKey) {
+		return nil, domain.ErrNotFound
+	}
+	if !strings.EqualFold(cart.State, "active") {
+		return nil, errors.New("only active carts can be ordered")
+	}
+	if len(cart.Lines) == 0 {
+		return nil, errors.New("cart has no line items")
+	}
+	return s.repo.Create(ctx, orderrepo.CreateOrderInput{
+		ProjectID:   projectID,
+		OrderNumber: strings.TrimSpace(draft.OrderNumber),
+		Cart:        *cart,
+	})
+}
+
+func (s *Service) getWithOwner(ctx context.Context, projectID, storeKey, id string, customerID, anonymousID *string) (*domain.Order, error) {
+	o, err := s.repo.GetByID(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ownedBy(o.Cart, customerID, anonymousID) || !inStore(o.Cart, storeKey) {
+		return nil, domain.ErrNotFound
+	}
+	return o, nil
+}
+
+func ownedBy(cart domain.Cart, customerID, anonymousID *string) bool {
+	switch {
+	case customerID != nil:
+		return cart.CustomerID != nil && *cart.CustomerID == *customerID
+	case anonymousID != nil:
+		return cart.AnonymousID != nil && *cart.AnonymousID == *anonymousID
+	}
+	return false
+}
+
+func inStore(cart domain.Cart, storeKey string) bool {
+	return storeKey == "" || cart.StoreKey == storeKey
+}
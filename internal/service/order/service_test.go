@@ -0,0 +1,101 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"commercetools-replica/internal/domain"
+	orderrepo "commercetools-replica/internal/repository/order"
+)
+
+type stubRepo struct {
+	created *orderrepo.CreateOrderInput
+	err     error
+}
+
+func (r *stubRepo) Create(_ context.Context, in orderrepo.CreateOrderInput) (*domain.Order, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	r.created = &in
+	return &domain.Order{ID: "order-1", ProjectID: in.ProjectID, Version: 1, OrderNumber: in.OrderNumber, OrderState: domain.OrderStateOpen, Cart: in.Cart}, nil
+}
+
+func (r *stubRepo) GetByID(_ context.Context, _, _ string) (*domain.Order, error) {
+	return nil, domain.ErrNotFound
+}
+
+func (r *stubRepo) ListByCustomer(_ context.Context, _, _, _ string, _, _ int) ([]domain.Order, int, error) {
+	return nil, 0, nil
+}
+
+func (r *stubRepo) ListByAnonymous(_ context.Context, _, _, _ string, _, _ int) ([]domain.Order, int, error) {
+	return nil, 0, nil
+}
+
+type stubCarts struct {
+	carts map[string]domain.Cart
+}
+
+func (s stubCarts) Get(_ context.Context, _, id string) (*domain.Cart, error) {
+	c, ok := s.carts[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &c, nil
+}
+
+func TestServiceCreate(t *testing.T) {
+	customerID, otherID := "cust-1", "cust-2"
+	line := domain.CartLine{ID: "line-1", Quantity: 2}
+	carts := stubCarts{carts: map[string]domain.Cart{
+		"active":  {ID: "active", CustomerID: &customerID, State: "active", Lines: []domain.CartLine{line}},
+		"ordered": {ID: "ordered", CustomerID: &customerID, State: "ordered", Lines: []domain.CartLine{line}},
+		"empty":   {ID: "empty", CustomerID: &customerID, State: "active"},
+		"other":   {ID: "other", CustomerID: &otherID, State: "active", Lines: []domain.CartLine{line}},
+		"uk":      {ID: "uk", CustomerID: &customerID, State: "active", Lines: []domain.CartLine{line}, StoreKey: "uk"},
+	}}
+	repo := &stubRepo{}
+	svc := New(repo, carts)
+	ctx := context.Background()
+
+	cases := []struct {
+		cartID string
+		want   string
+	}{
+		{"", "cart id required"},
+		{"missing", domain.ErrNotFound.Error()},
+		{"other", domain.ErrNotFound.Error()},
+		{"ordered", "only active carts can be ordered"},
+		{"empty", "cart has no line items"},
+	}
+	for _, tc := range cases {
+		if _, err := svc.Create(ctx, "proj", customerID, "", OrderFromCartDraft{ID: tc.cartID}); err == nil || err.Error() != tc.want {
+			t.Fatalf("cart %q: expected %q, got %v", tc.cartID, tc.want, err)
+		}
+	}
+	if _, err := svc.CreateAnonymous(ctx, "proj", "anon-1", "", OrderFromCartDraft{ID: "active"}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected anonymous session not to own the cart, got %v", err)
+	}
+
+	if _, err := svc.Create(ctx, "proj", customerID, "eu", OrderFromCartDraft{ID: "uk"}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected a cart of another store to be hidden, got %v", err)
+	}
+	if _, err := svc.Create(ctx, "proj", customerID, "uk", OrderFromCartDraft{ID: "uk"}); err != nil {
+		t.Fatalf("create in store: %v", err)
+	}
+
+	o, err := svc.Create(ctx, "proj", customerID, "", OrderFromCartDraft{ID: "active", OrderNumber: " 1001 "})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if o.OrderNumber != "1001" || repo.created == nil || repo.created.Cart.ID != "active" || repo.created.ProjectID != "proj" {
+		t.Fatalf("unexpected order %+v from input %+v", o, repo.created)
+	}
+
+	repo.err = &domain.OutOfStockError{SKUs: []string{"sku-1"}}
+	if _, err := svc.Create(ctx, "proj", customerID, "", OrderFromCartDraft{ID: "active"}); !errors.Is(err, domain.ErrOutOfStock) {
+		t.Fatalf("expected out of stock, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"commercetools-replica/internal/domain"
+	tokenrepo "commercetools-replica/internal/repository/token"
+)
+
+type customerStore interface {
+	GetByID(ctx context.Context, projectID, id string) (*domain.Customer, error)
+	Erase(ctx context.Context, projectID, id string, version int, requestedBy string) (*domain.Customer, error)
+}
+
+type cartLister interface {
+	ListByCustomer(ctx context.Context, projectID, customerID string) ([]domain.Cart, error)
+}
+
+type orderLister interface {
+	ListByCustomer(ctx context.Context, projectID, customerID, storeKey string, limit, offset int) ([]domain.Order, int, error)
+}
+
+type tokenLister interface {
+	ListByCustomer(ctx context.Context, projectID, customerID string) ([]tokenrepo.Token, error)
+}
+
+// Service exports and erases the personal data of a customer.
+type Service struct {
+	customers customerStore
+	carts     cartLister
+	orders    orderLister
+	tokens    tokenLister
+	now       func() time.Time
+}
+
+func New(customers customerStore, carts cartLister, orders orderLister, tokens tokenLister) *Service {
+	return &Service{customers: customers, carts: carts, orders: orders, tokens: tokens, now: time.Now}
+}
+
+// TokenInfo describes a token of the customer without its value.
+type TokenInfo struct {
+	Kind      string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Export is everything stored about a customer.
+type Export struct {
+	Customer   domain.Customer
+	Carts      []domain.Cart
+	Orders     []domain.Order
+	Tokens     []TokenInfo
+	ExportedAt time.Time
+}
+
+// Export collects the customer with its carts and orders in every store and
+// the metadata of its tokens.
+func (s *Service) Export(ctx context.Context, projectID, customerID string) (*Export, error) {
+	customer, err := s.customers.GetByID(ctx, projectID, customerID)
+	if err != nil {
+		return nil, err
+	}
+	carts, err := s.carts.ListByCustomer(ctx, projectID, customerID)
+	if err != nil {
+		return nil, err
+	}
+	orders, _, err := s.orders.ListByCustomer(ctx, projectID, customerID, "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := s.tokens.ListByCustomer(ctx, projectID, customerID)
+	if err != nil {
+		return nil, err
+	}
+	out := &Export{
+		Customer:   *customer,
+		Carts:      carts,
+		Orders:     orders,
+		Tokens:     make([]TokenInfo, 0, len(tokens)),
+		ExportedAt: s.now().UTC(),
+	}
+	for _, t := range tokens {
+		out.Tokens = append(out.Tokens, TokenInfo{Kind: t.Kind, CreatedAt: t.CreatedAt, ExpiresAt: t.ExpiresAt})
+	}
+	return out, nil
+}
+
+// Erase anonymises the customer at version together with its carts and
+// orders; requestedBy is domain.ErasureByCustomer or domain.ErasureByAdmin.
+// Order totals are kept.
+func (s *Service) Erase(ctx context.Context, projectID, customerID string, version int, requestedBy string) (*domain.Customer, error) {
+	if version <= 0 {
+		return nil, errors.New("version required")
+	}
+	if requestedBy != domain.ErasureByCustomer && requestedBy != domain.ErasureByAdmin {
+		return nil, errors.New("unknown erasure requester")
+	}
+	return s.customers.Erase(ctx, projectID, customerID, version, requestedBy)
+}
@@ -0,0 +1,119 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"commercetools-replica/internal/domain"
+	tokenrepo "commercetools-replica/internal/repository/token"
+)
+
+type stubCustomers struct {
+	customer    *domain.Customer
+	requestedBy string
+}
+
+func (s *stubCustomers) GetByID(_ context.Context, _, id string) (*domain.Customer, error) {
+	if s.customer == nil || s.customer.ID != id {
+		return nil, domain.ErrNotFound
+	}
+	return s.customer, nil
+}
+
+func (s *stubCustomers) Erase(_ context.Context, _, id string, version int, requestedBy string) (*domain.Customer, error) {
+	if s.customer == nil || s.customer.ID != id {
+		return nil, domain.ErrNotFound
+	}
+	if s.customer.Version != version {
+		return nil, domain.ErrConcurrentModification
+	}
+	s.requestedBy = requestedBy
+	erased := domain.Customer{ID: id, Version: version + 1, Email: "erased-" + id + "@invalid"}
+	s.customer = nil
+	return &erased, nil
+}
+
+type stubCarts []domain.Cart
+
+func (s stubCarts) ListByCustomer(context.Context, string, string) ([]domain.Cart, error) {
+	return s, nil
+}
+
+type stubOrders struct {
+	orders   []domain.Order
+	storeKey string
+	limit    int
+}
+
+func (s *stubOrders) ListByCustomer(_ context.Context, _, _, storeKey string, limit, _ int) ([]domain.Order, int, error) {
+	s.storeKey, s.limit = storeKey, limit
+	return s.orders, len(s.orders), nil
+}
+
+type stubTokens []tokenrepo.Token
+
+func (s stubTokens) ListByCustomer(_ context.Context, projectID, _ string) ([]tokenrepo.Token, error) {
+	var out []tokenrepo.Token
+	for _, t := range s {
+		if t.ProjectID == projectID {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func TestExport(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	customers := &stubCustomers{customer: &domain.Customer{ID: "c1", Version: 3, Email: "me@example.com"}}
+	orders := &stubOrders{orders: []domain.Order{{ID: "o1"}}, storeKey: "unset", limit: -1}
+	svc := New(customers, stubCarts{{ID: "cart1"}, {ID: "cart2"}}, orders, stubTokens{
+		{Token: "secret", ProjectID: "proj", Kind: "access", CreatedAt: created, ExpiresAt: created.Add(time.Hour)},
+		{Token: "foreign", ProjectID: "other", Kind: "refresh"},
+	})
+	svc.now = func() time.Time { return created }
+
+	out, err := svc.Export(context.Background(), "proj", "c1")
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if out.Customer.Email != "me@example.com" || len(out.Carts) != 2 || len(out.Orders) != 1 || !out.ExportedAt.Equal(created) {
+		t.Fatalf("unexpected export: %+v", out)
+	}
+	if orders.storeKey != "" || orders.limit != 0 {
+		t.Fatalf("expected all orders of every store, got store %q limit %d", orders.storeKey, orders.limit)
+	}
+	if len(out.Tokens) != 1 || out.Tokens[0].Kind != "access" || !out.Tokens[0].ExpiresAt.Equal(created.Add(time.Hour)) {
+		t.Fatalf("unexpected tokens: %+v", out.Tokens)
+	}
+	if _, err := svc.Export(context.Background(), "proj", "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestErase(t *testing.T) {
+	ctx := context.Background()
+	customers := &stubCustomers{customer: &domain.Customer{ID: "c1", Version: 3}}
+	svc := New(customers, stubCarts{}, &stubOrders{}, stubTokens{})
+
+	if _, err := svc.Erase(ctx, "proj", "c1", 0, domain.ErasureByCustomer); err == nil {
+		t.Fatalf("expected a missing version to be rejected")
+	}
+	if _, err := svc.Erase(ctx, "proj", "c1", 3, "robot"); err == nil {
+		t.Fatalf("expected an unknown requester to be rejected")
+	}
+	if _, err := svc.Erase(ctx, "proj", "c1", 2, domain.ErasureByAdmin); !errors.Is(err, domain.ErrConcurrentModification) {
+		t.Fatalf("expected ErrConcurrentModification, got %v", err)
+	}
+	erased, err := svc.Erase(ctx, "proj", "c1", 3, domain.ErasureByAdmin)
+	if err != nil {
+		t.Fatalf("erase: %v", err)
+	}
+	if erased.Version != 4 || erased.Email != "erased-c1@invalid" || customers.requestedBy != domain.ErasureByAdmin {
+		t.Fatalf("unexpected erased customer %+v by %q", erased, customers.requestedBy)
+	}
+	if _, err := svc.Export(ctx, "proj", "c1"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected an erased customer to be gone, got %v", err)
+	}
+}
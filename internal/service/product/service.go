@@ -2,17 +2,51 @@ package product
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
 
 	"commercetools-replica/internal/domain"
+	"commercetools-replica/internal/money"
 	productrepo "commercetools-replica/internal/repository/product"
 )
 
 type Service struct {
-	repo productrepo.Repository
+	repo          productrepo.Repository
+	categories    categoryLookup
+	productTypes  productTypeLookup
+	taxCategories taxCategoryLookup
+	groups        customerGroupLookup
 }
 
-func New(repo productrepo.Repository) *Service {
-	return &Service{repo: repo}
+// categoryLookup resolves category references of drafts and update actions.
+type categoryLookup interface {
+	GetByID(ctx context.Context, projectID, id string) (*domain.Category, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.Category, error)
+}
+
+// productTypeLookup resolves the product type whose attribute definitions validate writes.
+type productTypeLookup interface {
+	GetByID(ctx context.Context, projectID, id string) (*domain.ProductType, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.ProductType, error)
+}
+
+// taxCategoryLookup resolves the tax category references of drafts and setTaxCategory.
+type taxCategoryLookup interface {
+	GetByID(ctx context.Context, projectID, id string) (*domain.TaxCategory, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.TaxCategory, error)
+}
+
+// customerGroupLookup resolves the customer groups prices are scoped to.
+type customerGroupLookup interface {
+	GetByID(ctx context.Context, projectID, id string) (*domain.CustomerGroup, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.CustomerGroup, error)
+}
+
+func New(repo productrepo.Repository, categories categoryLookup, productTypes productTypeLookup, taxCategories taxCategoryLookup, groups customerGroupLookup) *Service {
+	return &Service{repo: repo, categories: categories, productTypes: productTypes, taxCategories: taxCategories, groups: groups}
 }
 
 func (s *Service) List(ctx context.Context, projectID string) ([]domain.Product, error) {
@@ -26,3 +60,755 @@ func (s *Service) Get(ctx context.Context, projectID, id string) (*domain.Produc
 func (s *Service) GetBySKU(ctx context.Context, projectID, sku string) (*domain.Product, error) {
 	return s.repo.GetBySKU(ctx, projectID, sku)
 }
+
+func (s *Service) GetByKey(ctx context.Context, projectID, key string) (*domain.Product, error) {
+	return s.repo.GetByKey(ctx, projectID, key)
+}
+
+// GetBySlug returns the product whose current data (or staged data when staged is set)
+// uses slug for locale. Unpublished products only match the staged projection.
+func (s *Service) GetBySlug(ctx context.Context, projectID, locale, slug string, staged bool) (*domain.Product, error) {
+	p, err := s.repo.GetBySlug(ctx, projectID, locale, slug)
+	if err != nil {
+		return nil, err
+	}
+	data := p.Current
+	if staged {
+		data = p.Staged
+	}
+	if data.Slug[locale] != slug || (!staged && !p.Published) {
+		return nil, domain.ErrNotFound
+	}
+	return p, nil
+}
+
+type ResourceIdentifier struct {
+	TypeID string `json:"typeId,omitempty"`
+	ID     string `json:"id,omitempty"`
+	Key    string `json:"key,omitempty"`
+}
+
+type PriceDraft struct {
+	Value         domain.Money        `json:"value"`
+	CustomerGroup *ResourceIdentifier `json:"customerGroup,omitempty"`
+}
+
+type ImageDraft struct {
+	URL string `json:"url"`
+}
+
+type AttributeDraft struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+type VariantDraft struct {
+	SKU        string           `json:"sku,omitempty"`
+	Key        string           `json:"key,omitempty"`
+	Prices     []PriceDraft     `json:"prices,omitempty"`
+	Images     []ImageDraft     `json:"images,omitempty"`
+	Attributes []AttributeDraft `json:"attributes,omitempty"`
+}
+
+type SearchKeyword struct {
+	Text string `json:"text"`
+}
+
+type ProductDraft struct {
+	Key             string                     `json:"key,omitempty"`
+	ProductType     *ResourceIdentifier        `json:"productType,omitempty"`
+	TaxCategory     *ResourceIdentifier        `json:"taxCategory,omitempty"`
+	Name            domain.LocalizedString     `json:"name"`
+	Slug            domain.LocalizedString     `json:"slug"`
+	Description     domain.LocalizedString     `json:"description,omitempty"`
+	MetaTitle       domain.LocalizedString     `json:"metaTitle,omitempty"`
+	MetaDescription domain.LocalizedString     `json:"metaDescription,omitempty"`
+	SearchKeywords  map[string][]SearchKeyword `json:"searchKeywords,omitempty"`
+	Categories      []ResourceIdentifier       `json:"categories,omitempty"`
+	MasterVariant   *VariantDraft              `json:"masterVariant,omitempty"`
+	Variants        []VariantDraft             `json:"variants,omitempty"`
+	Publish         bool                       `json:"publish,omitempty"`
+}
+
+type UpdateInput struct {
+	Version int            `json:"version"`
+	Actions []UpdateAction `json:"actions"`
+}
+
+// UpdateAction keeps the raw payload so each action can decode its own fields;
+// "name" for example is localized for changeName but a plain string for setAttribute.
+type UpdateAction struct {
+	Action string
+	raw    json.RawMessage
+}
+
+func (a *UpdateAction) UnmarshalJSON(b []byte) error {
+	var head struct {
+		Action string `json:"action"`
+	}
+	if err := json.Unmarshal(b, &head); err != nil {
+		return err
+	}
+	a.Action = head.Action
+	a.raw = append(json.RawMessage(nil), b...)
+	return nil
+}
+
+func (a UpdateAction) decode(v interface{}) error {
+	if len(a.raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(a.raw, v); err != nil {
+		return fmt.Errorf("invalid %s action: %w", a.Action, err)
+	}
+	return nil
+}
+
+var slugPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{2,256}$`)
+
+func (s *Service) Create(ctx context.Context, projectID string, draft ProductDraft) (*domain.Product, error) {
+	if draft.Name.IsEmpty() {
+		return nil, errors.New("name required")
+	}
+	if err := validateSlug(draft.Slug); err != nil {
+		return nil, err
+	}
+
+	data := domain.ProductData{
+		Name:            draft.Name,
+		Slug:            draft.Slug,
+		Description:     draft.Description,
+		MetaTitle:       draft.MetaTitle,
+		MetaDescription: draft.MetaDescription,
+		SearchKeywords:  keywordsFromDraft(draft.SearchKeywords),
+	}
+	for _, ref := range draft.Categories {
+		id, err := s.resolveCategory(ctx, projectID, ref)
+		if err != nil {
+			return nil, err
+		}
+		if !containsString(data.CategoryIDs, id) {
+			data.CategoryIDs = append(data.CategoryIDs, id)
+		}
+	}
+
+	master := VariantDraft{}
+	if draft.MasterVariant != nil {
+		master = *draft.MasterVariant
+	}
+	variant, err := s.variantFromDraft(ctx, projectID, 1, master)
+	if err != nil {
+		return nil, err
+	}
+	data.MasterVariant = variant
+	skus := map[string]struct{}{}
+	if variant.SKU != "" {
+		skus[variant.SKU] = struct{}{}
+	}
+	for i, vd := range draft.Variants {
+		v, err := s.variantFromDraft(ctx, projectID, i+2, vd)
+		if err != nil {
+			return nil, err
+		}
+		if v.SKU != "" {
+			if _, dup := skus[v.SKU]; dup {
+				return nil, fmt.Errorf("duplicate sku %s", v.SKU)
+			}
+			skus[v.SKU] = struct{}{}
+		}
+		data.Variants = append(data.Variants, v)
+	}
+
+	var productTypeID string
+	if draft.ProductType != nil {
+		t, err := s.resolveProductType(ctx, projectID, *draft.ProductType)
+		if err != nil {
+			return nil, err
+		}
+		if err := t.ValidateAttributes(&data); err != nil {
+			return nil, err
+		}
+		productTypeID = t.ID
+	}
+	var taxCategoryID string
+	if draft.TaxCategory != nil {
+		tc, err := s.resolveTaxCategory(ctx, projectID, *draft.TaxCategory)
+		if err != nil {
+			return nil, err
+		}
+		taxCategoryID = tc.ID
+	}
+
+	return s.repo.Create(ctx, domain.Product{
+		ProjectID:     projectID,
+		Key:           strings.TrimSpace(draft.Key),
+		ProductTypeID: productTypeID,
+		TaxCategoryID: taxCategoryID,
+		Published:     draft.Publish,
+		Current:       data,
+		Staged:        cloneData(data),
+	})
+}
+
+// Update applies the actions to a copy of the stored product and writes it back if
+// in.Version is still the stored version.
+func (s *Service) Update(ctx context.Context, projectID, id string, in UpdateInput) (*domain.Product, error) {
+	if len(in.Actions) == 0 {
+		return nil, errors.New("actions required")
+	}
+	if in.Version <= 0 {
+		return nil, errors.New("version required")
+	}
+	p, err := s.repo.GetByID(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if p.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+
+	for _, action := range in.Actions {
+		if err := s.apply(ctx, p, action); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.validateAttributes(ctx, p); err != nil {
+		return nil, err
+	}
+	p.HasStagedChanges = !sameData(p.Current, p.Staged)
+	return s.repo.Update(ctx, *p)
+}
+
+// stagedFlag mirrors the commercetools "staged" field, which defaults to true.
+type stagedFlag struct {
+	Staged *bool `json:"staged,omitempty"`
+}
+
+func (f stagedFlag) stagedOnly() bool {
+	return f.Staged == nil || *f.Staged
+}
+
+// targets returns the projections an action writes to: staged only, or both.
+func targets(p *domain.Product, f stagedFlag) []*domain.ProductData {
+	if f.stagedOnly() {
+		return []*domain.ProductData{&p.Staged}
+	}
+	return []*domain.ProductData{&p.Staged, &p.Current}
+}
+
+type variantSelector struct {
+	VariantID int    `json:"variantId,omitempty"`
+	SKU       string `json:"sku,omitempty"`
+}
+
+func (v variantSelector) find(data *domain.ProductData) (*domain.ProductVariant, error) {
+	var variant *domain.ProductVariant
+	switch {
+	case v.VariantID > 0:
+		variant = data.Variant(v.VariantID)
+	case strings.TrimSpace(v.SKU) != "":
+		variant = data.VariantBySKU(strings.TrimSpace(v.SKU))
+	default:
+		return nil, errors.New("variantId or sku required")
+	}
+	if variant == nil {
+		return nil, errors.New("variant not found")
+	}
+	return variant, nil
+}
+
+func (s *Service) apply(ctx context.Context, p *domain.Product, action UpdateAction) error {
+	switch strings.ToLower(strings.TrimSpace(action.Action)) {
+	case "changename":
+		var a struct {
+			stagedFlag
+			Name domain.LocalizedString `json:"name"`
+		}
+		if err := action.decode(&a); err != nil {
+			return err
+		}
+		if a.Name.IsEmpty() {
+			return errors.New("name required")
+		}
+		for _, data := range targets(p, a.stagedFlag) {
+			data.Name = a.Name.Clone()
+		}
+	case "setdescription":
+		var a struct {
+			stagedFlag
+			Description domain.LocalizedString `json:"description"`
+		}
+		if err := action.decode(&a); err != nil {
+			return err
+		}
+		for _, data := range targets(p, a.stagedFlag) {
+			data.Description = a.Description.Clone()
+		}
+	case "changeslug":
+		var a struct {
+			stagedFlag
+			Slug domain.LocalizedString `json:"slug"`
+		}
+		if err := action.decode(&a); err != nil {
+			return err
+		}
+		if err := validateSlug(a.Slug); err != nil {
+			return err
+		}
+		for _, data := range targets(p, a.stagedFlag) {
+			data.Slug = a.Slug.Clone()
+		}
+	case "addvariant":
+		var a struct {
+			stagedFlag
+			VariantDraft
+		}
+		if err := action.decode(&a); err != nil {
+			return err
+		}
+		variant, err := s.variantFromDraft(ctx, p.ProjectID, p.LastVariantID()+1, a.VariantDraft)
+		if err != nil {
+			return err
+		}
+		if variant.SKU != "" && containsString(p.SKUs(), variant.SKU) {
+			return fmt.Errorf("duplicate sku %s", variant.SKU)
+		}
+		for _, data := range targets(p, a.stagedFlag) {
+			data.Variants = append(data.Variants, cloneVariant(variant))
+		}
+	case "setprices":
+		var a struct {
+			stagedFlag
+			variantSelector
+			Prices []PriceDraft `json:"prices"`
+		}
+		if err := action.decode(&a); err != nil {
+			return err
+		}
+		prices, err := s.pricesFromDrafts(ctx, p.ProjectID, a.Prices)
+		if err != nil {
+			return err
+		}
+		for _, data := range targets(p, a.stagedFlag) {
+			variant, err := a.variantSelector.find(data)
+			if err != nil {
+				return err
+			}
+			variant.Prices = append([]domain.Price(nil), prices...)
+		}
+	case "setdiscountedprice":
+		var a struct {
+			stagedFlag
+			PriceID    string `json:"priceId"`
+			Discounted *struct {
+				Value    domain.Money       `json:"value"`
+				Discount ResourceIdentifier `json:"discount"`
+			} `json:"discounted"`
+		}
+		if err := action.decode(&a); err != nil {
+			return err
+		}
+		var discounted *domain.DiscountedPrice
+		if a.Discounted != nil {
+			if strings.TrimSpace(a.Discounted.Discount.ID) == "" {
+				return errors.New("discount id required")
+			}
+			discounted = &domain.DiscountedPrice{
+				Value:      domain.Money{CurrencyCode: strings.ToUpper(strings.TrimSpace(a.Discounted.Value.CurrencyCode)), CentAmount: a.Discounted.Value.CentAmount},
+				DiscountID: strings.TrimSpace(a.Discounted.Discount.ID),
+			}
+		}
+		for _, data := range targets(p, a.stagedFlag) {
+			price := findPrice(data, a.PriceID)
+			if price == nil {
+				return fmt.Errorf("price %s not found", a.PriceID)
+			}
+			if discounted != nil {
+				if discounted.Value.CurrencyCode != price.Value.CurrencyCode {
+					return errors.New("discounted price currency must match the price")
+				}
+				if discounted.Value.CentAmount < 0 || discounted.Value.CentAmount > price.Value.CentAmount {
+					return errors.New("discounted price must be between zero and the price")
+				}
+				copied := *discounted
+				price.Discounted = &copied
+				continue
+			}
+			price.Discounted = nil
+		}
+	case "addtocategory", "removefromcategory":
+		var a struct {
+			stagedFlag
+			Category ResourceIdentifier `json:"category"`
+		}
+		if err := action.decode(&a); err != nil {
+			return err
+		}
+		categoryID, err := s.resolveCategory(ctx, p.ProjectID, a.Category)
+		if err != nil {
+			return err
+		}
+		add := strings.EqualFold(action.Action, "addToCategory")
+		for _, data := range targets(p, a.stagedFlag) {
+			if add {
+				if containsString(data.CategoryIDs, categoryID) {
+					return errors.New("product already in category")
+				}
+				data.CategoryIDs = append(data.CategoryIDs, categoryID)
+				continue
+			}
+			if !containsString(data.CategoryIDs, categoryID) {
+				return errors.New("product not in category")
+			}
+			data.CategoryIDs = removeString(data.CategoryIDs, categoryID)
+		}
+	case "setattribute":
+		var a struct {
+			stagedFlag
+			variantSelector
+			Name  string      `json:"name"`
+			Value interface{} `json:"value"`
+		}
+		if err := action.decode(&a); err != nil {
+			return err
+		}
+		name := strings.TrimSpace(a.Name)
+		if name == "" {
+			return errors.New("attribute name required")
+		}
+		for _, data := range targets(p, a.stagedFlag) {
+			variant, err := a.variantSelector.find(data)
+			if err != nil {
+				return err
+			}
+			if a.Value == nil {
+				delete(variant.Attributes, name)
+				continue
+			}
+			if variant.Attributes == nil {
+				variant.Attributes = map[string]interface{}{}
+			}
+			variant.Attributes[name] = a.Value
+		}
+	case "settaxcategory":
+		// The tax category is not staged; a missing reference removes it.
+		var a struct {
+			TaxCategory *ResourceIdentifier `json:"taxCategory"`
+		}
+		if err := action.decode(&a); err != nil {
+			return err
+		}
+		p.TaxCategoryID = ""
+		if a.TaxCategory != nil {
+			tc, err := s.resolveTaxCategory(ctx, p.ProjectID, *a.TaxCategory)
+			if err != nil {
+				return err
+			}
+			p.TaxCategoryID = tc.ID
+		}
+	case "publish":
+		p.Current = cloneData(p.Staged)
+		p.Published = true
+	case "unpublish":
+		p.Published = false
+	case "revertstagedchanges":
+		p.Staged = cloneData(p.Current)
+	default:
+		return fmt.Errorf("unsupported action %q", action.Action)
+	}
+	return nil
+}
+
+// validateAttributes checks both projections against the product type, if the product has one.
+func (s *Service) validateAttributes(ctx context.Context, p *domain.Product) error {
+	if p.ProductTypeID == "" || s.productTypes == nil {
+		return nil
+	}
+	t, err := s.productTypes.GetByID(ctx, p.ProjectID, p.ProductTypeID)
+	if err != nil {
+		return err
+	}
+	if err := t.ValidateAttributes(&p.Staged); err != nil {
+		return err
+	}
+	return t.ValidateAttributes(&p.Current)
+}
+
+func (s *Service) resolveProductType(ctx context.Context, projectID string, ref ResourceIdentifier) (*domain.ProductType, error) {
+	id := strings.TrimSpace(ref.ID)
+	key := strings.TrimSpace(ref.Key)
+	if id == "" && key == "" {
+		return nil, errors.New("product type id or key required")
+	}
+	if s.productTypes == nil {
+		return nil, errors.New("product type lookup unavailable")
+	}
+	var (
+		t   *domain.ProductType
+		err error
+	)
+	if id != "" {
+		t, err = s.productTypes.GetByID(ctx, projectID, id)
+	} else {
+		t, err = s.productTypes.GetByKey(ctx, projectID, key)
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, errors.New("product type not found")
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *Service) resolveCategory(ctx context.Context, projectID string, ref ResourceIdentifier) (string, error) {
+	id := strings.TrimSpace(ref.ID)
+	key := strings.TrimSpace(ref.Key)
+	if id == "" && key == "" {
+		return "", errors.New("category id or key required")
+	}
+	if s.categories == nil {
+		if id == "" {
+			return "", errors.New("category lookup unavailable")
+		}
+		return id, nil
+	}
+	var (
+		c   *domain.Category
+		err error
+	)
+	if id != "" {
+		c, err = s.categories.GetByID(ctx, projectID, id)
+	} else {
+		c, err = s.categories.GetByKey(ctx, projectID, key)
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return "", errors.New("category not found")
+		}
+		return "", err
+	}
+	return c.ID, nil
+}
+
+func validateSlug(slug domain.LocalizedString) error {
+	if slug.IsEmpty() {
+		return errors.New("slug required")
+	}
+	for locale, v := range slug {
+		if !slugPattern.MatchString(v) {
+			return fmt.Errorf("invalid slug %q for locale %s", v, locale)
+		}
+	}
+	return nil
+}
+
+func (s *Service) variantFromDraft(ctx context.Context, projectID string, id int, d VariantDraft) (domain.ProductVariant, error) {
+	prices, err := s.pricesFromDrafts(ctx, projectID, d.Prices)
+	if err != nil {
+		return domain.ProductVariant{}, err
+	}
+	v := domain.ProductVariant{
+		ID:     id,
+		SKU:    strings.TrimSpace(d.SKU),
+		Key:    strings.TrimSpace(d.Key),
+		Prices: prices,
+	}
+	for _, img := range d.Images {
+		if u := strings.TrimSpace(img.URL); u != "" {
+			v.Images = append(v.Images, u)
+		}
+	}
+	for _, attr := range d.Attributes {
+		name := strings.TrimSpace(attr.Name)
+		if name == "" {
+			return domain.ProductVariant{}, errors.New("attribute name required")
+		}
+		if v.Attributes == nil {
+			v.Attributes = map[string]interface{}{}
+		}
+		v.Attributes[name] = attr.Value
+	}
+	return v, nil
+}
+
+// pricesFromDrafts builds the prices of a variant. Like in commercetools a
+// variant has at most one price per currency and customer group.
+func (s *Service) pricesFromDrafts(ctx context.Context, projectID string, drafts []PriceDraft) ([]domain.Price, error) {
+	var out []domain.Price
+	scopes := map[string]struct{}{}
+	for _, d := range drafts {
+		value, err := priceValue(d.Value)
+		if err != nil {
+			return nil, err
+		}
+		price := domain.Price{Value: value}
+		if d.CustomerGroup != nil {
+			g, err := s.resolveCustomerGroup(ctx, projectID, *d.CustomerGroup)
+			if err != nil {
+				return nil, err
+			}
+			price.CustomerGroupID = g.ID
+		}
+		scope := price.Value.CurrencyCode + "/" + price.CustomerGroupID
+		if _, dup := scopes[scope]; dup {
+			return nil, fmt.Errorf("duplicate price scope for %s", strings.TrimSuffix(scope, "/"))
+		}
+		scopes[scope] = struct{}{}
+		out = append(out, price)
+	}
+	return out, nil
+}
+
+// maxFractionDigits bounds the decimals of high-precision prices.
+const maxFractionDigits = 20
+
+// priceValue validates a price value. Values with more fraction digits than
+// their currency are kept as high-precision money, with the cent amount
+// rounded from the precise amount; others are cent amounts.
+func priceValue(m domain.Money) (domain.Money, error) {
+	currency := strings.ToUpper(strings.TrimSpace(m.CurrencyCode))
+	if len(currency) != 3 {
+		return domain.Money{}, fmt.Errorf("invalid currency code %q", m.CurrencyCode)
+	}
+	out := domain.Money{CurrencyCode: currency, CentAmount: m.CentAmount}
+	if m.FractionDigits > money.FractionDigits(currency) {
+		if m.FractionDigits > maxFractionDigits {
+			return domain.Money{}, fmt.Errorf("fractionDigits must be at most %d", maxFractionDigits)
+		}
+		out.PreciseAmount, out.FractionDigits = m.PreciseAmount, m.FractionDigits
+		out.CentAmount = out.Amount().CentAmount(money.HalfEven)
+	}
+	if out.CentAmount < 0 || out.PreciseAmount < 0 {
+		return domain.Money{}, errors.New("centAmount must not be negative")
+	}
+	return out, nil
+}
+
+// findPrice returns a pointer into data for the price with the given id.
+func findPrice(data *domain.ProductData, id string) *domain.Price {
+	if strings.TrimSpace(id) == "" {
+		return nil
+	}
+	variants := []*domain.ProductVariant{&data.MasterVariant}
+	for i := range data.Variants {
+		variants = append(variants, &data.Variants[i])
+	}
+	for _, v := range variants {
+		for i := range v.Prices {
+			if v.Prices[i].ID == id {
+				return &v.Prices[i]
+			}
+		}
+	}
+	return nil
+}
+
+func keywordsFromDraft(in map[string][]SearchKeyword) domain.LocalizedKeywords {
+	out := domain.LocalizedKeywords{}
+	for locale, words := range in {
+		for _, w := range words {
+			if t := strings.TrimSpace(w.Text); t != "" {
+				out[locale] = append(out[locale], t)
+			}
+		}
+	}
+	return out
+}
+
+// cloneData deep-copies data through its JSON form, which is also how it is stored.
+func cloneData(data domain.ProductData) domain.ProductData {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return data
+	}
+	var out domain.ProductData
+	if err := json.Unmarshal(b, &out); err != nil {
+		return data
+	}
+	return out
+}
+
+func cloneVariant(v domain.ProductVariant) domain.ProductVariant {
+	data := cloneData(domain.ProductData{MasterVariant: v})
+	return data.MasterVariant
+}
+
+func sameData(a, b domain.ProductData) bool {
+	ab, errA := json.Marshal(a)
+	bb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ab) == string(bb)
+}
+
+func containsString(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(list []string, v string) []string {
+	out := list[:0:0]
+	for _, item := range list {
+		if item != v {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func (s *Service) resolveTaxCategory(ctx context.Context, projectID string, ref ResourceIdentifier) (*domain.TaxCategory, error) {
+	id := strings.TrimSpace(ref.ID)
+	key := strings.TrimSpace(ref.Key)
+	if id == "" && key == "" {
+		return nil, errors.New("tax category id or key required")
+	}
+	if s.taxCategories == nil {
+		return nil, errors.New("tax category lookup unavailable")
+	}
+	var (
+		tc  *domain.TaxCategory
+		err error
+	)
+	if id != "" {
+		tc, err = s.taxCategories.GetByID(ctx, projectID, id)
+	} else {
+		tc, err = s.taxCategories.GetByKey(ctx, projectID, key)
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, errors.New("tax category not found")
+		}
+		return nil, err
+	}
+	return tc, nil
+}
+
+func (s *Service) resolveCustomerGroup(ctx context.Context, projectID string, ref ResourceIdentifier) (*domain.CustomerGroup, error) {
+	id := strings.TrimSpace(ref.ID)
+	key := strings.TrimSpace(ref.Key)
+	if id == "" && key == "" {
+		return nil, errors.New("customer group id or key required")
+	}
+	if s.groups == nil {
+		return nil, errors.New("customer group lookup unavailable")
+	}
+	var (
+		g   *domain.CustomerGroup
+		err error
+	)
+	if id != "" {
+		g, err = s.groups.GetByID(ctx, projectID, id)
+	} else {
+		g, err = s.groups.GetByKey(ctx, projectID, key)
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, errors.New("customer group not found")
+		}
+		return nil, err
+	}
+	return g, nil
+}
@@ -0,0 +1,614 @@
+package product
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"commercetools-replica/internal/domain"
+)
+
+// memoryRepo is a lightweight in-memory product repository for tests.
+type memoryRepo struct {
+	byID map[string]domain.Product
+}
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{byID: make(map[string]domain.Product)}
+}
+
+func (r *memoryRepo) ListByProject(_ context.Context, projectID string) ([]domain.Product, error) {
+	var out []domain.Product
+	for _, p := range r.byID {
+		if p.ProjectID == projectID {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (r *memoryRepo) GetByID(_ context.Context, projectID, id string) (*domain.Product, error) {
+	p, ok := r.byID[id]
+	if !ok || p.ProjectID != projectID {
+		return nil, domain.ErrNotFound
+	}
+	clone := p
+	clone.Current = cloneData(p.Current)
+	clone.Staged = cloneData(p.Staged)
+	return &clone, nil
+}
+
+func (r *memoryRepo) GetBySKU(_ context.Context, projectID, sku string) (*domain.Product, error) {
+	for _, p := range r.byID {
+		if p.ProjectID == projectID && containsString(p.SKUs(), sku) {
+			return r.GetByID(context.Background(), projectID, p.ID)
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (r *memoryRepo) GetByKey(_ context.Context, projectID, key string) (*domain.Product, error) {
+	for _, p := range r.byID {
+		if p.ProjectID == projectID && p.Key == key {
+			return r.GetByID(context.Background(), projectID, p.ID)
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (r *memoryRepo) GetBySlug(_ context.Context, projectID, locale, slug string) (*domain.Product, error) {
+	for _, p := range r.byID {
+		if p.ProjectID == projectID && (p.Current.Slug[locale] == slug || p.Staged.Slug[locale] == slug) {
+			return r.GetByID(context.Background(), projectID, p.ID)
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (r *memoryRepo) Upsert(_ context.Context, p domain.Product) (*domain.Product, error) {
+	p.Staged = p.Current
+	p.Published = true
+	r.byID[p.ID] = p
+	return &p, nil
+}
+
+func (r *memoryRepo) Create(_ context.Context, p domain.Product) (*domain.Product, error) {
+	p.ID = "prod-" + p.Key
+	p.Version = 1
+	assignTestPriceIDs(&p.Current)
+	assignTestPriceIDs(&p.Staged)
+	r.byID[p.ID] = p
+	return &p, nil
+}
+
+func (r *memoryRepo) Update(_ context.Context, p domain.Product) (*domain.Product, error) {
+	stored, ok := r.byID[p.ID]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	if stored.Version != p.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	p.Version++
+	assignTestPriceIDs(&p.Current)
+	assignTestPriceIDs(&p.Staged)
+	r.byID[p.ID] = p
+	return &p, nil
+}
+
+// assignTestPriceIDs names prices after their variant and position like the
+// postgres repository assigns ids to new prices.
+func assignTestPriceIDs(data *domain.ProductData) {
+	variants := []*domain.ProductVariant{&data.MasterVariant}
+	for i := range data.Variants {
+		variants = append(variants, &data.Variants[i])
+	}
+	for _, v := range variants {
+		for i := range v.Prices {
+			if v.Prices[i].ID == "" {
+				v.Prices[i].ID = fmt.Sprintf("price-%d-%d", v.ID, i)
+			}
+		}
+	}
+}
+
+func createTestProduct(t *testing.T, svc *Service) *domain.Product {
+	t.Helper()
+	p, err := svc.Create(context.Background(), "proj", ProductDraft{
+		Key:  "shirt",
+		Name: domain.LocalizedString{"en": "Shirt"},
+		Slug: domain.LocalizedString{"en": "shirt"},
+		MasterVariant: &VariantDraft{
+			SKU:    "shirt-1",
+			Prices: []PriceDraft{{Value: domain.Money{CurrencyCode: "eur", CentAmount: 1000}}},
+		},
+		Publish: true,
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	return p
+}
+
+func update(t *testing.T, svc *Service, p *domain.Product, body string) (*domain.Product, error) {
+	t.Helper()
+	var in UpdateInput
+	if err := json.Unmarshal([]byte(body), &in); err != nil {
+		t.Fatalf("decode update: %v", err)
+	}
+	if in.Version == 0 {
+		in.Version = p.Version
+	}
+	return svc.Update(context.Background(), p.ProjectID, p.ID, in)
+}
+
+func TestServiceCreateValidation(t *testing.T) {
+	svc := New(newMemoryRepo(), nil, nil, nil, nil)
+	ctx := context.Background()
+
+	if _, err := svc.Create(ctx, "proj", ProductDraft{Slug: domain.LocalizedString{"en": "slug"}}); err == nil || err.Error() != "name required" {
+		t.Fatalf("expected name error, got %v", err)
+	}
+	if _, err := svc.Create(ctx, "proj", ProductDraft{Name: domain.LocalizedString{"en": "Name"}}); err == nil || err.Error() != "slug required" {
+		t.Fatalf("expected slug error, got %v", err)
+	}
+	if _, err := svc.Create(ctx, "proj", ProductDraft{
+		Name:          domain.LocalizedString{"en": "Name"},
+		Slug:          domain.LocalizedString{"en": "slug"},
+		MasterVariant: &VariantDraft{SKU: "a"},
+		Variants:      []VariantDraft{{SKU: "a"}},
+	}); err == nil || err.Error() != "duplicate sku a" {
+		t.Fatalf("expected duplicate sku error, got %v", err)
+	}
+}
+
+func TestServiceCreateBuildsVariants(t *testing.T) {
+	svc := New(newMemoryRepo(), nil, nil, nil, nil)
+	p := createTestProduct(t, svc)
+
+	if !p.Published || p.HasStagedChanges {
+		t.Fatalf("expected published product without staged changes, got %+v", p)
+	}
+	if p.Current.MasterVariant.ID != 1 || p.Current.MasterVariant.SKU != "shirt-1" {
+		t.Fatalf("unexpected master variant %+v", p.Current.MasterVariant)
+	}
+	if price, ok := p.Current.MasterVariant.PriceFor("EUR"); !ok || price.Value.CentAmount != 1000 {
+		t.Fatalf("expected normalized EUR price, got %+v", p.Current.MasterVariant.Prices)
+	}
+}
+
+func TestPriceValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      domain.Money
+		want    domain.Money
+		wantErr string
+	}{
+		{name: "cent precision", in: domain.Money{CurrencyCode: "eur", CentAmount: 1000, FractionDigits: 2}, want: domain.Money{CurrencyCode: "EUR", CentAmount: 1000}},
+		{name: "yen", in: domain.Money{CurrencyCode: "JPY", CentAmount: 1500}, want: domain.Money{CurrencyCode: "JPY", CentAmount: 1500}},
+		{name: "high precision", in: domain.Money{CurrencyCode: "EUR", PreciseAmount: 12345, FractionDigits: 3}, want: domain.Money{CurrencyCode: "EUR", CentAmount: 1234, PreciseAmount: 12345, FractionDigits: 3}},
+		{name: "high precision yen", in: domain.Money{CurrencyCode: "JPY", PreciseAmount: 1015, FractionDigits: 1}, want: domain.Money{CurrencyCode: "JPY", CentAmount: 102, PreciseAmount: 1015, FractionDigits: 1}},
+		{name: "currency", in: domain.Money{CurrencyCode: "EURO", CentAmount: 1}, wantErr: `invalid currency code "EURO"`},
+		{name: "negative", in: domain.Money{CurrencyCode: "EUR", PreciseAmount: -1, FractionDigits: 3}, wantErr: "centAmount must not be negative"},
+		{name: "too precise", in: domain.Money{CurrencyCode: "EUR", PreciseAmount: 1, FractionDigits: 21}, wantErr: "fractionDigits must be at most 20"},
+	}
+	for _, tt := range tests {
+		got, err := priceValue(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("%s: expected %q, got %v", tt.name, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Fatalf("%s: expected %+v, got %+v (%v)", tt.name, tt.want, got, err)
+		}
+	}
+}
+
+func TestServiceUpdateStagedAndCurrent(t *testing.T) {
+	svc := New(newMemoryRepo(), nil, nil, nil, nil)
+	p := createTestProduct(t, svc)
+
+	staged, err := update(t, svc, p, `{"actions":[{"action":"changeName","name":{"en":"Staged Shirt"}}]}`)
+	if err != nil {
+		t.Fatalf("changeName staged: %v", err)
+	}
+	if staged.Staged.Name.Get("en") != "Staged Shirt" || staged.Current.Name.Get("en") != "Shirt" {
+		t.Fatalf("expected staged-only change, got staged=%v current=%v", staged.Staged.Name, staged.Current.Name)
+	}
+	if !staged.HasStagedChanges || staged.Version != 2 {
+		t.Fatalf("expected staged changes at version 2, got %+v", staged)
+	}
+
+	both, err := update(t, svc, staged, `{"actions":[{"action":"setDescription","description":{"en":"Cotton"},"staged":false}]}`)
+	if err != nil {
+		t.Fatalf("setDescription: %v", err)
+	}
+	if both.Current.Description.Get("en") != "Cotton" || both.Staged.Description.Get("en") != "Cotton" {
+		t.Fatalf("expected description on both projections, got %+v", both)
+	}
+
+	published, err := update(t, svc, both, `{"actions":[{"action":"publish"}]}`)
+	if err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if published.Current.Name.Get("en") != "Staged Shirt" || published.HasStagedChanges {
+		t.Fatalf("expected staged data to be published, got %+v", published)
+	}
+}
+
+func TestServiceUpdateRevertStagedChanges(t *testing.T) {
+	svc := New(newMemoryRepo(), nil, nil, nil, nil)
+	p := createTestProduct(t, svc)
+
+	p, err := update(t, svc, p, `{"actions":[{"action":"changeSlug","slug":{"en":"new-shirt"}}]}`)
+	if err != nil {
+		t.Fatalf("changeSlug: %v", err)
+	}
+	p, err = update(t, svc, p, `{"actions":[{"action":"revertStagedChanges"}]}`)
+	if err != nil {
+		t.Fatalf("revert: %v", err)
+	}
+	if p.Staged.Slug.Get("en") != "shirt" || p.HasStagedChanges {
+		t.Fatalf("expected staged data reverted, got %+v", p.Staged)
+	}
+}
+
+func TestServiceGetBySlug(t *testing.T) {
+	svc := New(newMemoryRepo(), nil, nil, nil, nil)
+	ctx := context.Background()
+	p := createTestProduct(t, svc)
+
+	p, err := update(t, svc, p, `{"actions":[{"action":"changeSlug","slug":{"en":"new-shirt"}}]}`)
+	if err != nil {
+		t.Fatalf("changeSlug: %v", err)
+	}
+	if got, err := svc.GetBySlug(ctx, "proj", "en", "shirt", false); err != nil || got.ID != p.ID {
+		t.Fatalf("expected current slug lookup to match, got %v %v", got, err)
+	}
+	if _, err := svc.GetBySlug(ctx, "proj", "en", "new-shirt", false); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected staged-only slug to be hidden from current, got %v", err)
+	}
+	if _, err := svc.GetBySlug(ctx, "proj", "en", "new-shirt", true); err != nil {
+		t.Fatalf("expected staged slug lookup to match: %v", err)
+	}
+
+	p, err = update(t, svc, p, `{"actions":[{"action":"unpublish"}]}`)
+	if err != nil {
+		t.Fatalf("unpublish: %v", err)
+	}
+	if _, err := svc.GetBySlug(ctx, "proj", "en", "shirt", false); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected unpublished product to be hidden, got %v", err)
+	}
+}
+
+func TestServiceSetDiscountedPrice(t *testing.T) {
+	svc := New(newMemoryRepo(), nil, nil, nil, nil)
+	p := createTestProduct(t, svc)
+	priceID := p.Current.MasterVariant.Prices[0].ID
+
+	p, err := update(t, svc, p, `{"actions":[{"action":"setDiscountedPrice","priceId":"`+priceID+`","staged":false,
+		"discounted":{"value":{"currencyCode":"EUR","centAmount":800},"discount":{"typeId":"product-discount","id":"disc-1"}}}]}`)
+	if err != nil {
+		t.Fatalf("setDiscountedPrice: %v", err)
+	}
+	for _, data := range []domain.ProductData{p.Current, p.Staged} {
+		d := data.MasterVariant.Prices[0].Discounted
+		if d == nil || d.Value.CentAmount != 800 || d.DiscountID != "disc-1" {
+			t.Fatalf("expected discounted price on both projections, got %+v", d)
+		}
+	}
+
+	cases := []struct {
+		body string
+		want string
+	}{
+		{`{"actions":[{"action":"setDiscountedPrice","priceId":"nope"}]}`, "price nope not found"},
+		{`{"actions":[{"action":"setDiscountedPrice","priceId":"` + priceID + `","discounted":{"value":{"currencyCode":"USD","centAmount":800},"discount":{"id":"disc-1"}}}]}`, "discounted price currency must match the price"},
+		{`{"actions":[{"action":"setDiscountedPrice","priceId":"` + priceID + `","discounted":{"value":{"currencyCode":"EUR","centAmount":1200},"discount":{"id":"disc-1"}}}]}`, "discounted price must be between zero and the price"},
+		{`{"actions":[{"action":"setDiscountedPrice","priceId":"` + priceID + `","discounted":{"value":{"currencyCode":"EUR","centAmount":800}}}]}`, "discount id required"},
+	}
+	for _, tc := range cases {
+		if _, err := update(t, svc, p, tc.body); err == nil || err.Error() != tc.want {
+			t.Fatalf("expected %q, got %v", tc.want, err)
+		}
+	}
+
+	p, err = update(t, svc, p, `{"actions":[{"action":"setDiscountedPrice","priceId":"`+priceID+`"}]}`)
+	if err != nil {
+		t.Fatalf("clear discounted: %v", err)
+	}
+	if p.Staged.MasterVariant.Prices[0].Discounted != nil || p.Current.MasterVariant.Prices[0].Discounted == nil {
+		t.Fatalf("expected staged discounted price to be cleared only, got %+v", p)
+	}
+}
+
+func TestServiceUpdateVariantActions(t *testing.T) {
+	svc := New(newMemoryRepo(), nil, nil, nil, nil)
+	p := createTestProduct(t, svc)
+
+	p, err := update(t, svc, p, `{"actions":[
+		{"action":"addVariant","sku":"shirt-2","prices":[{"value":{"currencyCode":"EUR","centAmount":1200}}]},
+		{"action":"setPrices","sku":"shirt-2","prices":[{"value":{"currencyCode":"USD","centAmount":1500}}]},
+		{"action":"setAttribute","variantId":2,"name":"color","value":"red"}
+	]}`)
+	if err != nil {
+		t.Fatalf("variant actions: %v", err)
+	}
+	v := p.Staged.Variant(2)
+	if v == nil || v.SKU != "shirt-2" {
+		t.Fatalf("expected staged variant 2, got %+v", p.Staged.Variants)
+	}
+	if _, ok := v.PriceFor("USD"); !ok || len(v.Prices) != 1 {
+		t.Fatalf("expected prices replaced, got %+v", v.Prices)
+	}
+	if v.Attributes["color"] != "red" {
+		t.Fatalf("expected color attribute, got %+v", v.Attributes)
+	}
+	if p.Current.Variant(2) != nil {
+		t.Fatalf("expected current data untouched, got %+v", p.Current.Variants)
+	}
+
+	if _, err := update(t, svc, p, `{"actions":[{"action":"addVariant","sku":"shirt-1"}]}`); err == nil || err.Error() != "duplicate sku shirt-1" {
+		t.Fatalf("expected duplicate sku error, got %v", err)
+	}
+}
+
+func TestServiceUpdateCategoryActions(t *testing.T) {
+	svc := New(newMemoryRepo(), nil, nil, nil, nil)
+	p := createTestProduct(t, svc)
+
+	p, err := update(t, svc, p, `{"actions":[{"action":"addToCategory","category":{"typeId":"category","id":"c1"}}]}`)
+	if err != nil {
+		t.Fatalf("addToCategory: %v", err)
+	}
+	if len(p.Staged.CategoryIDs) != 1 || p.Staged.CategoryIDs[0] != "c1" {
+		t.Fatalf("expected category c1, got %v", p.Staged.CategoryIDs)
+	}
+	if _, err := update(t, svc, p, `{"actions":[{"action":"addToCategory","category":{"id":"c1"}}]}`); err == nil {
+		t.Fatalf("expected error adding category twice")
+	}
+	p, err = update(t, svc, p, `{"actions":[{"action":"removeFromCategory","category":{"id":"c1"}}]}`)
+	if err != nil {
+		t.Fatalf("removeFromCategory: %v", err)
+	}
+	if len(p.Staged.CategoryIDs) != 0 {
+		t.Fatalf("expected no categories, got %v", p.Staged.CategoryIDs)
+	}
+}
+
+func TestServiceUpdateErrors(t *testing.T) {
+	svc := New(newMemoryRepo(), nil, nil, nil, nil)
+	p := createTestProduct(t, svc)
+
+	if _, err := update(t, svc, p, `{"version":7,"actions":[{"action":"unpublish"}]}`); !errors.Is(err, domain.ErrConcurrentModification) {
+		t.Fatalf("expected concurrent modification, got %v", err)
+	}
+	if _, err := update(t, svc, p, `{"actions":[{"action":"explode"}]}`); err == nil || err.Error() != `unsupported action "explode"` {
+		t.Fatalf("expected unsupported action error, got %v", err)
+	}
+	if _, err := svc.Update(context.Background(), "proj", "missing", UpdateInput{Version: 1, Actions: []UpdateAction{{Action: "publish"}}}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+type stubProductTypes struct {
+	types map[string]domain.ProductType
+}
+
+func (s stubProductTypes) GetByID(_ context.Context, _ string, id string) (*domain.ProductType, error) {
+	for _, t := range s.types {
+		if t.ID == id {
+			clone := t
+			return &clone, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s stubProductTypes) GetByKey(_ context.Context, _ string, key string) (*domain.ProductType, error) {
+	t, ok := s.types[key]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &t, nil
+}
+
+func plantType() stubProductTypes {
+	return stubProductTypes{types: map[string]domain.ProductType{
+		"plant": {
+			ID:  "pt-1",
+			Key: "plant",
+			Attributes: []domain.AttributeDefinition{
+				{Name: "size", Type: domain.AttributeType{Name: domain.AttributeTypeEnum, Values: []domain.AttributeEnumValue{
+					{Key: "s", Label: domain.Localized("en", "Small")},
+					{Key: "l", Label: domain.Localized("en", "Large")},
+				}}, IsRequired: true, AttributeConstraint: domain.AttributeConstraintUnique},
+				{Name: "height", Type: domain.AttributeType{Name: domain.AttributeTypeNumber}},
+				{Name: "care", Type: domain.AttributeType{Name: domain.AttributeTypeLText}, AttributeConstraint: domain.AttributeConstraintSameForAll},
+			},
+		},
+	}}
+}
+
+func TestServiceCreateValidatesAttributes(t *testing.T) {
+	svc := New(newMemoryRepo(), nil, plantType(), nil, nil)
+	ctx := context.Background()
+	draft := func(attrs ...AttributeDraft) ProductDraft {
+		return ProductDraft{
+			Key:           "aloe",
+			ProductType:   &ResourceIdentifier{TypeID: "product-type", Key: "plant"},
+			Name:          domain.LocalizedString{"en": "Aloe"},
+			Slug:          domain.LocalizedString{"en": "aloe"},
+			MasterVariant: &VariantDraft{SKU: "aloe-s", Attributes: attrs},
+		}
+	}
+
+	cases := []struct {
+		name  string
+		attrs []AttributeDraft
+		want  string
+	}{
+		{"missing required", nil, `variant 1: attribute "size" is required`},
+		{"unknown attribute", []AttributeDraft{{Name: "size", Value: "s"}, {Name: "color", Value: "red"}}, `variant 1: attribute "color" is not defined on product type plant`},
+		{"wrong type", []AttributeDraft{{Name: "size", Value: "s"}, {Name: "height", Value: "tall"}}, `variant 1: attribute "height": expected a number`},
+		{"unknown enum value", []AttributeDraft{{Name: "size", Value: "xl"}}, `variant 1: attribute "size": unknown enum value "xl"`},
+	}
+	for _, tc := range cases {
+		if _, err := svc.Create(ctx, "proj", draft(tc.attrs...)); err == nil || err.Error() != tc.want {
+			t.Fatalf("%s: expected %q, got %v", tc.name, tc.want, err)
+		}
+	}
+
+	p, err := svc.Create(ctx, "proj", draft(AttributeDraft{Name: "size", Value: "s"}, AttributeDraft{Name: "height", Value: 12.5}))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if p.ProductTypeID != "pt-1" {
+		t.Fatalf("expected product type link, got %q", p.ProductTypeID)
+	}
+	size, ok := p.Staged.MasterVariant.Attributes["size"].(map[string]interface{})
+	if !ok || size["key"] != "s" || size["label"] != "Small" {
+		t.Fatalf("expected normalized enum value, got %#v", p.Staged.MasterVariant.Attributes["size"])
+	}
+}
+
+func TestServiceUpdateValidatesConstraints(t *testing.T) {
+	svc := New(newMemoryRepo(), nil, plantType(), nil, nil)
+	p, err := svc.Create(context.Background(), "proj", ProductDraft{
+		Key:           "aloe",
+		ProductType:   &ResourceIdentifier{ID: "pt-1"},
+		Name:          domain.LocalizedString{"en": "Aloe"},
+		Slug:          domain.LocalizedString{"en": "aloe"},
+		MasterVariant: &VariantDraft{SKU: "aloe-s", Attributes: []AttributeDraft{{Name: "size", Value: "s"}}},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if _, err := update(t, svc, p, `{"actions":[{"action":"addVariant","sku":"aloe-s2","attributes":[{"name":"size","value":"s"}]}]}`); err == nil || err.Error() != `attribute "size" must be unique, variants 1 and 2 share a value` {
+		t.Fatalf("expected unique constraint error, got %v", err)
+	}
+	if _, err := update(t, svc, p, `{"actions":[
+		{"action":"addVariant","sku":"aloe-l","attributes":[{"name":"size","value":"l"}]},
+		{"action":"setAttribute","variantId":1,"name":"care","value":{"en":"Water weekly"}}
+	]}`); err == nil || err.Error() != `attribute "care" must have the same value in all variants` {
+		t.Fatalf("expected same-for-all error, got %v", err)
+	}
+	if _, err := update(t, svc, p, `{"actions":[{"action":"setAttribute","variantId":1,"name":"size","value":null}]}`); err == nil || err.Error() != `variant 1: attribute "size" is required` {
+		t.Fatalf("expected required error, got %v", err)
+	}
+	if _, err := svc.Create(context.Background(), "proj", ProductDraft{
+		ProductType: &ResourceIdentifier{Key: "missing"},
+		Name:        domain.LocalizedString{"en": "X"},
+		Slug:        domain.LocalizedString{"en": "xx"},
+	}); err == nil || err.Error() != "product type not found" {
+		t.Fatalf("expected product type not found, got %v", err)
+	}
+}
+
+type stubTaxCategories map[string]domain.TaxCategory
+
+func (s stubTaxCategories) GetByID(_ context.Context, _ string, id string) (*domain.TaxCategory, error) {
+	for _, tc := range s {
+		if tc.ID == id {
+			return &tc, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s stubTaxCategories) GetByKey(_ context.Context, _ string, key string) (*domain.TaxCategory, error) {
+	tc, ok := s[key]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &tc, nil
+}
+
+func TestServiceTaxCategory(t *testing.T) {
+	svc := New(newMemoryRepo(), nil, nil, stubTaxCategories{
+		"standard": {ID: "tc-1", Key: "standard"},
+		"reduced":  {ID: "tc-2", Key: "reduced"},
+	}, nil)
+	p, err := svc.Create(context.Background(), "proj", ProductDraft{
+		Key:         "mug",
+		TaxCategory: &ResourceIdentifier{TypeID: "tax-category", Key: "standard"},
+		Name:        domain.LocalizedString{"en": "Mug"},
+		Slug:        domain.LocalizedString{"en": "mug"},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if p.TaxCategoryID != "tc-1" {
+		t.Fatalf("expected tax category tc-1, got %q", p.TaxCategoryID)
+	}
+
+	p, err = update(t, svc, p, `{"actions":[{"action":"setTaxCategory","taxCategory":{"typeId":"tax-category","id":"tc-2"}}]}`)
+	if err != nil || p.TaxCategoryID != "tc-2" || p.HasStagedChanges {
+		t.Fatalf("expected tax category tc-2 without staged changes, got %+v, %v", p, err)
+	}
+	if _, err := update(t, svc, p, `{"actions":[{"action":"setTaxCategory","taxCategory":{"key":"zero"}}]}`); err == nil || err.Error() != "tax category not found" {
+		t.Fatalf("expected tax category not found, got %v", err)
+	}
+	p, err = update(t, svc, p, `{"actions":[{"action":"setTaxCategory"}]}`)
+	if err != nil || p.TaxCategoryID != "" {
+		t.Fatalf("expected tax category removed, got %+v, %v", p, err)
+	}
+}
+
+type stubCustomerGroups map[string]domain.CustomerGroup
+
+func (s stubCustomerGroups) GetByID(_ context.Context, _, id string) (*domain.CustomerGroup, error) {
+	for _, g := range s {
+		if g.ID == id {
+			return &g, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s stubCustomerGroups) GetByKey(_ context.Context, _, key string) (*domain.CustomerGroup, error) {
+	g, ok := s[key]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &g, nil
+}
+
+func TestServiceCustomerGroupPrices(t *testing.T) {
+	svc := New(newMemoryRepo(), nil, nil, nil, stubCustomerGroups{"wholesale": {ID: "group-1", Key: "wholesale"}})
+	eur := domain.Money{CurrencyCode: "EUR", CentAmount: 1000}
+	p, err := svc.Create(context.Background(), "proj", ProductDraft{
+		Name: domain.LocalizedString{"en": "Mug"},
+		Slug: domain.LocalizedString{"en": "mug"},
+		MasterVariant: &VariantDraft{SKU: "mug", Prices: []PriceDraft{
+			{Value: eur},
+			{Value: domain.Money{CurrencyCode: "EUR", CentAmount: 800}, CustomerGroup: &ResourceIdentifier{TypeID: "customer-group", Key: "wholesale"}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	prices := p.Staged.MasterVariant.Prices
+	if len(prices) != 2 || prices[0].CustomerGroupID != "" || prices[1].CustomerGroupID != "group-1" {
+		t.Fatalf("expected a base and a group price, got %+v", prices)
+	}
+
+	cases := []struct {
+		body string
+		want string
+	}{
+		{`{"actions":[{"action":"setPrices","sku":"mug","prices":[{"value":{"currencyCode":"EUR","centAmount":1}},{"value":{"currencyCode":"EUR","centAmount":2}}]}]}`, "duplicate price scope for EUR"},
+		{`{"actions":[{"action":"setPrices","sku":"mug","prices":[{"value":{"currencyCode":"EUR","centAmount":1},"customerGroup":{"id":"group-1"}},{"value":{"currencyCode":"EUR","centAmount":2},"customerGroup":{"key":"wholesale"}}]}]}`, "duplicate price scope for EUR/group-1"},
+		{`{"actions":[{"action":"setPrices","sku":"mug","prices":[{"value":{"currencyCode":"EUR","centAmount":1},"customerGroup":{"key":"retail"}}]}]}`, "customer group not found"},
+	}
+	for _, tc := range cases {
+		if _, err := update(t, svc, p, tc.body); err == nil || err.Error() != tc.want {
+			t.Fatalf("expected %q, got %v", tc.want, err)
+		}
+	}
+}
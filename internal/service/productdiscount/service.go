@@ -0,0 +1,360 @@
+package productdiscount
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"commercetools-replica/internal/domain"
+	"commercetools-replica/internal/predicate"
+	productdiscountrepo "commercetools-replica/internal/repository/productdiscount"
+	"commercetools-replica/internal/service/updateaction"
+)
+
+type Service struct {
+	repo productdiscountrepo.Repository
+	now  func() time.Time
+}
+
+func New(repo productdiscountrepo.Repository) *Service {
+	return &Service{repo: repo, now: time.Now}
+}
+
+// ListPage returns one page of product discounts, highest sortOrder first; limit 0 means no limit.
+func (s *Service) ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.ProductDiscount, int, error) {
+	return s.repo.List(ctx, projectID, limit, offset)
+}
+
+func (s *Service) Get(ctx context.Context, projectID, id string) (*domain.ProductDiscount, error) {
+	return s.repo.GetByID(ctx, projectID, id)
+}
+
+func (s *Service) GetByKey(ctx context.Context, projectID, key string) (*domain.ProductDiscount, error) {
+	return s.repo.GetByKey(ctx, projectID, key)
+}
+
+type ProductDiscountDraft struct {
+	Key         string                      `json:"key,omitempty"`
+	Name        domain.LocalizedString      `json:"name"`
+	Description domain.LocalizedString      `json:"description,omitempty"`
+	Value       domain.ProductDiscountValue `json:"value"`
+	Predicate   string                      `json:"predicate"`
+	SortOrder   string                      `json:"sortOrder"`
+	// IsActive defaults to true like in commercetools.
+	IsActive   *bool      `json:"isActive,omitempty"`
+	ValidFrom  *time.Time `json:"validFrom,omitempty"`
+	ValidUntil *time.Time `json:"validUntil,omitempty"`
+}
+
+func (s *Service) Create(ctx context.Context, projectID string, draft ProductDiscountDraft) (*domain.ProductDiscount, error) {
+	active := true
+	if draft.IsActive != nil {
+		active = *draft.IsActive
+	}
+	d := domain.ProductDiscount{
+		ProjectID:   projectID,
+		Key:         strings.TrimSpace(draft.Key),
+		Name:        draft.Name.Clone(),
+		Description: draft.Description.Clone(),
+		Value:       normalizeValue(draft.Value),
+		Predicate:   strings.TrimSpace(draft.Predicate),
+		SortOrder:   strings.TrimSpace(draft.SortOrder),
+		IsActive:    active,
+		ValidFrom:   draft.ValidFrom,
+		ValidUntil:  draft.ValidUntil,
+	}
+	if err := validate(d); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, d)
+}
+
+type UpdateInput struct {
+	Version int            `json:"version"`
+	Actions []UpdateAction `json:"actions"`
+}
+
+type UpdateAction = updateaction.Action
+
+// Update applies the actions to the stored discount if in.Version is still current.
+func (s *Service) Update(ctx context.Context, projectID, id string, in UpdateInput) (*domain.ProductDiscount, error) {
+	if len(in.Actions) == 0 {
+		return nil, errors.New("actions required")
+	}
+	if in.Version <= 0 {
+		return nil, errors.New("version required")
+	}
+	d, err := s.repo.GetByID(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	for _, action := range in.Actions {
+		if err := apply(d, action); err != nil {
+			return nil, err
+		}
+	}
+	if err := validate(*d); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, *d)
+}
+
+func (s *Service) Delete(ctx context.Context, projectID, id string, version int) (*domain.ProductDiscount, error) {
+	if version <= 0 {
+		return nil, errors.New("version required")
+	}
+	return s.repo.Delete(ctx, projectID, id, version)
+}
+
+func apply(d *domain.ProductDiscount, action UpdateAction) error {
+	switch strings.ToLower(strings.TrimSpace(action.Action)) {
+	case "changename":
+		var a struct {
+			Name domain.LocalizedString `json:"name"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.Name = a.Name.Clone()
+	case "setdescription":
+		var a struct {
+			Description domain.LocalizedString `json:"description"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.Description = a.Description.Clone()
+	case "setkey":
+		var a struct {
+			Key string `json:"key"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.Key = strings.TrimSpace(a.Key)
+	case "changevalue":
+		var a struct {
+			Value domain.ProductDiscountValue `json:"value"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.Value = normalizeValue(a.Value)
+	case "changepredicate":
+		var a struct {
+			Predicate string `json:"predicate"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.Predicate = strings.TrimSpace(a.Predicate)
+	case "changesortorder":
+		var a struct {
+			SortOrder string `json:"sortOrder"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.SortOrder = strings.TrimSpace(a.SortOrder)
+	case "changeisactive":
+		var a struct {
+			IsActive bool `json:"isActive"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.IsActive = a.IsActive
+	case "setvalidfrom":
+		var a struct {
+			ValidFrom *time.Time `json:"validFrom"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.ValidFrom = a.ValidFrom
+	case "setvaliduntil":
+		var a struct {
+			ValidUntil *time.Time `json:"validUntil"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.ValidUntil = a.ValidUntil
+	case "setvalidfromanduntil":
+		var a struct {
+			ValidFrom  *time.Time `json:"validFrom"`
+			ValidUntil *time.Time `json:"validUntil"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		d.ValidFrom, d.ValidUntil = a.ValidFrom, a.ValidUntil
+	default:
+		return fmt.Errorf("unsupported action %q", action.Action)
+	}
+	return nil
+}
+
+// sortOrderPattern matches the commercetools sortOrder format: a decimal between
+// 0 and 1 that does not end in zero.
+var sortOrderPattern = regexp.MustCompile(`^0\.\d*[1-9]$`)
+
+func validate(d domain.ProductDiscount) error {
+	if d.Name.IsEmpty() {
+		return errors.New("name required")
+	}
+	if d.Predicate == "" {
+		return errors.New("predicate required")
+	}
+	if _, err := predicate.Compile(d.Predicate, predicate.ProductSchema); err != nil {
+		return fmt.Errorf("invalid predicate: %w", err)
+	}
+	if !sortOrderPattern.MatchString(d.SortOrder) {
+		return fmt.Errorf("invalid sortOrder %q", d.SortOrder)
+	}
+	if d.ValidFrom != nil && d.ValidUntil != nil && !d.ValidFrom.Before(*d.ValidUntil) {
+		return errors.New("validFrom must be before validUntil")
+	}
+	switch d.Value.Type {
+	case domain.ProductDiscountRelative:
+		if d.Value.Permyriad <= 0 || d.Value.Permyriad > 10000 {
+			return errors.New("permyriad must be between 1 and 10000")
+		}
+	case domain.ProductDiscountAbsolute:
+		if len(d.Value.Money) == 0 {
+			return errors.New("money required for absolute discounts")
+		}
+		seen := map[string]struct{}{}
+		for _, m := range d.Value.Money {
+			if len(m.CurrencyCode) != 3 {
+				return fmt.Errorf("invalid currency code %q", m.CurrencyCode)
+			}
+			if m.CentAmount <= 0 {
+				return errors.New("centAmount must be positive")
+			}
+			if _, dup := seen[m.CurrencyCode]; dup {
+				return fmt.Errorf("duplicate currency %s", m.CurrencyCode)
+			}
+			seen[m.CurrencyCode] = struct{}{}
+		}
+	case domain.ProductDiscountExternal:
+	case "":
+		return errors.New("value type required")
+	default:
+		return fmt.Errorf("unsupported value type %q", d.Value.Type)
+	}
+	return nil
+}
+
+// normalizeValue drops the fields that do not belong to the value type.
+func normalizeValue(v domain.ProductDiscountValue) domain.ProductDiscountValue {
+	out := domain.ProductDiscountValue{Type: strings.TrimSpace(v.Type)}
+	switch out.Type {
+	case domain.ProductDiscountRelative:
+		out.Permyriad = v.Permyriad
+	case domain.ProductDiscountAbsolute:
+		for _, m := range v.Money {
+			out.Money = append(out.Money, domain.Money{CurrencyCode: strings.ToUpper(strings.TrimSpace(m.CurrencyCode)), CentAmount: m.CentAmount})
+		}
+	}
+	return out
+}
+
+// ApplyToProducts sets the discounted price on every price of both projections
+// of the products, using the project's discounts that are valid now.
+func (s *Service) ApplyToProducts(ctx context.Context, projectID string, products []domain.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	discounts, _, err := s.repo.List(ctx, projectID, 0, 0)
+	if err != nil {
+		return err
+	}
+	Apply(discounts, products, s.now())
+	return nil
+}
+
+// Apply sets Price.Discounted in place. A stored external discounted price is
+// kept while its discount is valid; otherwise the valid relative or absolute
+// discount with the highest sortOrder whose predicate matches the price wins.
+func Apply(discounts []domain.ProductDiscount, products []domain.Product, now time.Time) {
+	m := newMatcher(discounts, now)
+	for i := range products {
+		p := &products[i]
+		for _, data := range []*domain.ProductData{&p.Current, &p.Staged} {
+			variants := []*domain.ProductVariant{&data.MasterVariant}
+			for j := range data.Variants {
+				variants = append(variants, &data.Variants[j])
+			}
+			for _, v := range variants {
+				for k := range v.Prices {
+					v.Prices[k].Discounted = m.discounted(*p, *data, *v, v.Prices[k])
+				}
+			}
+		}
+	}
+}
+
+type compiledDiscount struct {
+	domain.ProductDiscount
+	predicate *predicate.Predicate
+}
+
+type matcher struct {
+	discounts []compiledDiscount
+	external  map[string]struct{}
+}
+
+func newMatcher(discounts []domain.ProductDiscount, now time.Time) *matcher {
+	m := &matcher{external: map[string]struct{}{}}
+	for _, d := range discounts {
+		if !d.ValidAt(now) {
+			continue
+		}
+		if d.Value.Type == domain.ProductDiscountExternal {
+			m.external[d.ID] = struct{}{}
+			continue
+		}
+		pred, err := predicate.Parse(d.Predicate)
+		if err != nil {
+			continue
+		}
+		m.discounts = append(m.discounts, compiledDiscount{ProductDiscount: d, predicate: pred})
+	}
+	sort.SliceStable(m.discounts, func(i, j int) bool {
+		return m.discounts[i].SortOrderValue() > m.discounts[j].SortOrderValue()
+	})
+	return m
+}
+
+func (m *matcher) discounted(p domain.Product, data domain.ProductData, v domain.ProductVariant, price domain.Price) *domain.DiscountedPrice {
+	if price.Discounted != nil {
+		if _, ok := m.external[price.Discounted.DiscountID]; ok {
+			out := *price.Discounted
+			return &out
+		}
+	}
+	var env predicate.Fields
+	for _, d := range m.discounts {
+		if env == nil {
+			env = predicate.ProductFields(p, data, v, price)
+		}
+		// Predicates that do not fit the price (type errors) simply do not match.
+		if ok, err := d.predicate.Eval(env); err != nil || !ok {
+			continue
+		}
+		value, ok := d.Value.Apply(price.Value)
+		if !ok {
+			continue
+		}
+		return &domain.DiscountedPrice{Value: value, DiscountID: d.ID}
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package productdiscount
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"commercetools-replica/internal/domain"
+)
+
+// stubRepo lists and returns the stored discounts and records the ones the
+// service writes.
+type stubRepo struct {
+	stored  []domain.ProductDiscount
+	created *domain.ProductDiscount
+	updated *domain.ProductDiscount
+	deleted int
+}
+
+func (r *stubRepo) List(_ context.Context, _ string, _, _ int) ([]domain.ProductDiscount, int, error) {
+	return r.stored, len(r.stored), nil
+}
+
+func (r *stubRepo) GetByID(_ context.Context, _, id string) (*domain.ProductDiscount, error) {
+	for _, d := range r.stored {
+		if d.ID == id {
+			return &d, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (r *stubRepo) GetByKey(_ context.Context, _, _ string) (*domain.ProductDiscount, error) {
+	return nil, domain.ErrNotFound
+}
+
+func (r *stubRepo) Create(_ context.Context, d domain.ProductDiscount) (*domain.ProductDiscount, error) {
+	d.ID, d.Version = "disc-1", 1
+	r.created = &d
+	return &d, nil
+}
+
+func (r *stubRepo) Update(_ context.Context, d domain.ProductDiscount) (*domain.ProductDiscount, error) {
+	r.updated = &d
+	out := d
+	out.Version++
+	return &out, nil
+}
+
+func (r *stubRepo) Delete(_ context.Context, _, id string, version int) (*domain.ProductDiscount, error) {
+	r.deleted++
+	return &domain.ProductDiscount{ID: id, Version: version}, nil
+}
+
+func relativeDraft(key, sortOrder, pred string, permyriad int) ProductDiscountDraft {
+	return ProductDiscountDraft{
+		Key:       key,
+		Name:      domain.Localized("en", key),
+		Value:     domain.ProductDiscountValue{Type: domain.ProductDiscountRelative, Permyriad: permyriad},
+		Predicate: pred,
+		SortOrder: sortOrder,
+	}
+}
+
+func TestServiceCreateValidation(t *testing.T) {
+	repo := &stubRepo{}
+	svc := New(repo)
+	ctx := context.Background()
+
+	valid := relativeDraft(" spring ", "0.5", `sku = "a"`, 1000)
+	valid.Value = domain.ProductDiscountValue{Type: domain.ProductDiscountAbsolute, Money: []domain.Money{{CurrencyCode: " eur", CentAmount: 200}}}
+	if _, err := svc.Create(ctx, "proj", valid); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if d := repo.created; d == nil || d.ProjectID != "proj" || d.Key != "spring" || !d.IsActive || d.Value.Money[0].CurrencyCode != "EUR" {
+		t.Fatalf("expected an active discount with a trimmed key and currency, got %+v", d)
+	}
+
+	inactive := false
+	cases := []struct {
+		mutate func(*ProductDiscountDraft)
+		want   string
+	}{
+		{func(d *ProductDiscountDraft) { d.Name = nil }, "name required"},
+		{func(d *ProductDiscountDraft) { d.Predicate = "" }, "predicate required"},
+		{func(d *ProductDiscountDraft) { d.SortOrder = "1" }, `invalid sortOrder "1"`},
+		{func(d *ProductDiscountDraft) { d.SortOrder = "0.10" }, `invalid sortOrder "0.10"`},
+		{func(d *ProductDiscountDraft) { d.Value.Permyriad = 0 }, "permyriad must be between 1 and 10000"},
+		{func(d *ProductDiscountDraft) {
+			d.Value = domain.ProductDiscountValue{Type: domain.ProductDiscountAbsolute}
+		}, "money required for absolute discounts"},
+		{func(d *ProductDiscountDraft) { d.Value = domain.ProductDiscountValue{Type: "gift"} }, `unsupported value type "gift"`},
+		{func(d *ProductDiscountDraft) {
+			d.IsActive = &inactive
+			from := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+			until := from.Add(-time.Hour)
+			d.ValidFrom, d.ValidUntil = &from, &until
+		}, "validFrom must be before validUntil"},
+	}
+	for _, tc := range cases {
+		repo.created = nil
+		draft := relativeDraft("x", "0.4", `sku = "a"`, 1000)
+		tc.mutate(&draft)
+		if _, err := svc.Create(ctx, "proj", draft); err == nil || err.Error() != tc.want {
+			t.Fatalf("expected %q, got %v", tc.want, err)
+		}
+		if repo.created != nil {
+			t.Fatalf("%s: expected nothing stored, got %+v", tc.want, repo.created)
+		}
+	}
+	if _, err := svc.Create(ctx, "proj", relativeDraft("bad", "0.3", `sku =`, 1000)); err == nil {
+		t.Fatalf("expected predicate error")
+	}
+}
+
+func TestServiceUpdate(t *testing.T) {
+	stored := domain.ProductDiscount{ID: "disc-1", ProjectID: "proj", Version: 3, Name: domain.Localized("en", "Spring"), IsActive: true,
+		Predicate: `sku = "a"`, SortOrder: "0.5", Value: domain.ProductDiscountValue{Type: domain.ProductDiscountRelative, Permyriad: 1000}}
+	repo := &stubRepo{stored: []domain.ProductDiscount{stored}}
+	svc := New(repo)
+	ctx := context.Background()
+
+	var in UpdateInput
+	body := `{"version":3,"actions":[
+		{"action":"changeValue","value":{"type":"absolute","money":[{"currencyCode":"eur","centAmount":200}]}},
+		{"action":"changePredicate","predicate":"sku = \"b\""},
+		{"action":"changeSortOrder","sortOrder":"0.25"},
+		{"action":"changeIsActive","isActive":false},
+		{"action":"setValidFromAndUntil","validFrom":"2025-01-01T00:00:00Z","validUntil":"2025-02-01T00:00:00Z"}
+	]}`
+	if err := json.Unmarshal([]byte(body), &in); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if _, err := svc.Update(ctx, "proj", "disc-1", in); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	d := repo.updated
+	if d == nil || d.Version != 3 || d.Value.Type != domain.ProductDiscountAbsolute || d.Value.Money[0].CurrencyCode != "EUR" ||
+		d.Predicate != `sku = "b"` || d.SortOrder != "0.25" || d.IsActive || d.ValidUntil == nil {
+		t.Fatalf("unexpected discount passed to the repository %+v", d)
+	}
+
+	repo.updated = nil
+	for _, tc := range []struct {
+		body string
+		want string
+	}{
+		{`{"version":2,"actions":[{"action":"changeSortOrder","sortOrder":"0.3"}]}`, domain.ErrConcurrentModification.Error()},
+		{`{"version":3,"actions":[{"action":"changeSortOrder","sortOrder":"3"}]}`, `invalid sortOrder "3"`},
+		{`{"version":3,"actions":[{"action":"changePredicate","predicate":""}]}`, "predicate required"},
+		{`{"version":3,"actions":[{"action":"changeTarget"}]}`, `unsupported action "changeTarget"`},
+		{`{"version":3,"actions":[]}`, "actions required"},
+	} {
+		var in UpdateInput
+		if err := json.Unmarshal([]byte(tc.body), &in); err != nil {
+			t.Fatalf("decode %s: %v", tc.body, err)
+		}
+		if _, err := svc.Update(ctx, "proj", "disc-1", in); err == nil || err.Error() != tc.want {
+			t.Fatalf("%s: expected %q, got %v", tc.body, tc.want, err)
+		}
+	}
+	if repo.updated != nil {
+		t.Fatalf("expected failed updates not to be stored, got %+v", repo.updated)
+	}
+	if _, err := svc.Update(ctx, "proj", "missing", in); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := svc.Delete(ctx, "proj", "disc-1", 0); err == nil || err.Error() != "version required" || repo.deleted != 0 {
+		t.Fatalf("expected the version to be required on delete, got %v", err)
+	}
+}
+
+func TestServiceApplyToProducts(t *testing.T) {
+	repo := &stubRepo{stored: []domain.ProductDiscount{{ID: "all", IsActive: true, SortOrder: "0.5", Predicate: `1 = 1`,
+		Value: domain.ProductDiscountValue{Type: domain.ProductDiscountRelative, Permyriad: 5000}}}}
+	svc := New(repo)
+	products := []domain.Product{discountTestProduct()}
+	if err := svc.ApplyToProducts(context.Background(), "proj", products); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if d := products[0].Current.MasterVariant.Prices[0].Discounted; d == nil || d.DiscountID != "all" || d.Value.CentAmount != 500 {
+		t.Fatalf("expected the project's discount to halve the price, got %+v", d)
+	}
+}
+
+func discountTestProduct() domain.Product {
+	data := domain.ProductData{
+		CategoryIDs: []string{"cat-1"},
+		MasterVariant: domain.ProductVariant{ID: 1, SKU: "a", Prices: []domain.Price{
+			{ID: "eur", Value: domain.Money{CurrencyCode: "EUR", CentAmount: 1000}},
+			{ID: "usd", Value: domain.Money{CurrencyCode: "USD", CentAmount: 999}},
+		}},
+		Variants: []domain.ProductVariant{{ID: 2, SKU: "b", Prices: []domain.Price{
+			{ID: "b-eur", Value: domain.Money{CurrencyCode: "EUR", CentAmount: 2000}},
+		}}},
+	}
+	return domain.Product{ID: "p1", Published: true, Current: data, Staged: data}
+}
+
+func TestApply(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-24 * time.Hour)
+	future := now.Add(24 * time.Hour)
+	discounts := []domain.ProductDiscount{
+		{ID: "cat-10", IsActive: true, SortOrder: "0.2", Predicate: `product.categories.id contains "cat-1"`,
+			Value: domain.ProductDiscountValue{Type: domain.ProductDiscountRelative, Permyriad: 1000}},
+		{ID: "sku-b-abs", IsActive: true, SortOrder: "0.5", Predicate: `sku = "b"`,
+			Value: domain.ProductDiscountValue{Type: domain.ProductDiscountAbsolute, Money: []domain.Money{{CurrencyCode: "EUR", CentAmount: 500}}}},
+		{ID: "usd-only-abs", IsActive: true, SortOrder: "0.9", Predicate: `1 = 1`,
+			Value: domain.ProductDiscountValue{Type: domain.ProductDiscountAbsolute, Money: []domain.Money{{CurrencyCode: "USD", CentAmount: 5000}}}},
+		{ID: "inactive", IsActive: false, SortOrder: "0.99", Predicate: `1 = 1`,
+			Value: domain.ProductDiscountValue{Type: domain.ProductDiscountRelative, Permyriad: 9000}},
+		{ID: "expired", IsActive: true, SortOrder: "0.98", Predicate: `1 = 1`, ValidUntil: &past,
+			Value: domain.ProductDiscountValue{Type: domain.ProductDiscountRelative, Permyriad: 9000}},
+		{ID: "upcoming", IsActive: true, SortOrder: "0.97", Predicate: `1 = 1`, ValidFrom: &future,
+			Value: domain.ProductDiscountValue{Type: domain.ProductDiscountRelative, Permyriad: 9000}},
+	}
+	products := []domain.Product{discountTestProduct()}
+	Apply(discounts, products, now)
+
+	data := products[0].Current
+	cases := []struct {
+		price    domain.Price
+		discount string
+		cents    int64
+	}{
+		{data.MasterVariant.Prices[0], "cat-10", 900},
+		// The USD amount exceeds the price, so the price drops to zero.
+		{data.MasterVariant.Prices[1], "usd-only-abs", 0},
+		// The higher sortOrder wins over the category discount.
+		{data.Variants[0].Prices[0], "sku-b-abs", 1500},
+	}
+	for _, tc := range cases {
+		d := tc.price.Discounted
+		if d == nil || d.DiscountID != tc.discount || d.Value.CentAmount != tc.cents || d.Value.CurrencyCode != tc.price.Value.CurrencyCode {
+			t.Fatalf("price %s: expected %s at %d, got %+v", tc.price.ID, tc.discount, tc.cents, d)
+		}
+	}
+	if products[0].Staged.MasterVariant.Prices[0].Discounted == nil {
+		t.Fatalf("expected staged prices to be discounted too")
+	}
+}
+
+func TestApplyExternal(t *testing.T) {
+	now := time.Now()
+	product := discountTestProduct()
+	product.Current.MasterVariant.Prices[0].Discounted = &domain.DiscountedPrice{Value: domain.Money{CurrencyCode: "EUR", CentAmount: 700}, DiscountID: "ext"}
+	product.Current.MasterVariant.Prices[1].Discounted = &domain.DiscountedPrice{Value: domain.Money{CurrencyCode: "USD", CentAmount: 1}, DiscountID: "gone"}
+	discounts := []domain.ProductDiscount{
+		{ID: "ext", IsActive: true, SortOrder: "0.1", Predicate: `1 = 1`, Value: domain.ProductDiscountValue{Type: domain.ProductDiscountExternal}},
+	}
+	products := []domain.Product{product}
+	Apply(discounts, products, now)
+
+	prices := products[0].Current.MasterVariant.Prices
+	if d := prices[0].Discounted; d == nil || d.DiscountID != "ext" || d.Value.CentAmount != 700 {
+		t.Fatalf("expected external discounted price to be kept, got %+v", d)
+	}
+	if prices[1].Discounted != nil {
+		t.Fatalf("expected discounted price of unknown discount to be dropped, got %+v", prices[1].Discounted)
+	}
+	// External discounts are never matched by predicate.
+	if products[0].Current.Variants[0].Prices[0].Discounted != nil {
+		t.Fatalf("expected no discount on variant without external price")
+	}
+}
@@ -0,0 +1,208 @@
+package productselection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"commercetools-replica/internal/domain"
+	productselectionrepo "commercetools-replica/internal/repository/productselection"
+	"commercetools-replica/internal/service/updateaction"
+)
+
+type Service struct {
+	repo     productselectionrepo.Repository
+	products productLookup
+}
+
+// productLookup resolves the products added to or removed from a selection.
+type productLookup interface {
+	GetByID(ctx context.Context, projectID, id string) (*domain.Product, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.Product, error)
+}
+
+func New(repo productselectionrepo.Repository, products productLookup) *Service {
+	return &Service{repo: repo, products: products}
+}
+
+// ListPage returns one page of product selections, oldest first; limit 0 means no limit.
+func (s *Service) ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.ProductSelection, int, error) {
+	return s.repo.List(ctx, projectID, limit, offset)
+}
+
+func (s *Service) Get(ctx context.Context, projectID, id string) (*domain.ProductSelection, error) {
+	return s.repo.GetByID(ctx, projectID, id)
+}
+
+func (s *Service) GetByKey(ctx context.Context, projectID, key string) (*domain.ProductSelection, error) {
+	return s.repo.GetByKey(ctx, projectID, key)
+}
+
+// ListProducts returns one page of the ids of the selection's products.
+func (s *Service) ListProducts(ctx context.Context, projectID, id string, limit, offset int) ([]string, int, error) {
+	return s.repo.ListProducts(ctx, projectID, id, limit, offset)
+}
+
+type ResourceIdentifier struct {
+	TypeID string `json:"typeId,omitempty"`
+	ID     string `json:"id,omitempty"`
+	Key    string `json:"key,omitempty"`
+}
+
+type ProductSelectionDraft struct {
+	Key  string                 `json:"key,omitempty"`
+	Name domain.LocalizedString `json:"name"`
+}
+
+// Create stores a new, empty selection; addProduct fills it.
+func (s *Service) Create(ctx context.Context, projectID string, draft ProductSelectionDraft) (*domain.ProductSelection, error) {
+	sel := domain.ProductSelection{
+		ProjectID: projectID,
+		Key:       strings.TrimSpace(draft.Key),
+		Name:      draft.Name,
+	}
+	if err := validate(sel); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, sel)
+}
+
+type UpdateInput struct {
+	Version int            `json:"version"`
+	Actions []UpdateAction `json:"actions"`
+}
+
+type UpdateAction = updateaction.Action
+
+// changes collects the products the actions of one update add and remove;
+// a later action on the same product wins.
+type changes struct {
+	add, remove []string
+}
+
+func (c *changes) set(productID string, add bool) {
+	c.add = without(c.add, productID)
+	c.remove = without(c.remove, productID)
+	if add {
+		c.add = append(c.add, productID)
+	} else {
+		c.remove = append(c.remove, productID)
+	}
+}
+
+// Update applies the actions to the stored selection if in.Version is still current.
+func (s *Service) Update(ctx context.Context, projectID, id string, in UpdateInput) (*domain.ProductSelection, error) {
+	if len(in.Actions) == 0 {
+		return nil, errors.New("actions required")
+	}
+	if in.Version <= 0 {
+		return nil, errors.New("version required")
+	}
+	sel, err := s.repo.GetByID(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if sel.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	var c changes
+	for _, action := range in.Actions {
+		if err := s.apply(ctx, sel, &c, action); err != nil {
+			return nil, err
+		}
+	}
+	if err := validate(*sel); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, *sel, c.add, c.remove)
+}
+
+// Delete removes the selection; it fails with domain.ErrReferenceExists while
+// a store uses it.
+func (s *Service) Delete(ctx context.Context, projectID, id string, version int) (*domain.ProductSelection, error) {
+	if version <= 0 {
+		return nil, errors.New("version required")
+	}
+	return s.repo.Delete(ctx, projectID, id, version)
+}
+
+func (s *Service) apply(ctx context.Context, sel *domain.ProductSelection, c *changes, action UpdateAction) error {
+	switch strings.ToLower(strings.TrimSpace(action.Action)) {
+	case "changename":
+		var a struct {
+			Name domain.LocalizedString `json:"name"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		sel.Name = a.Name
+	case "setkey":
+		var a struct {
+			Key string `json:"key"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		sel.Key = strings.TrimSpace(a.Key)
+	case "addproduct", "removeproduct":
+		var a struct {
+			Product ResourceIdentifier `json:"product"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		p, err := s.resolveProduct(ctx, sel.ProjectID, a.Product)
+		if err != nil {
+			return err
+		}
+		c.set(p.ID, strings.EqualFold(strings.TrimSpace(action.Action), "addProduct"))
+	default:
+		return fmt.Errorf("unsupported action %q", action.Action)
+	}
+	return nil
+}
+
+func (s *Service) resolveProduct(ctx context.Context, projectID string, ref ResourceIdentifier) (*domain.Product, error) {
+	id := strings.TrimSpace(ref.ID)
+	key := strings.TrimSpace(ref.Key)
+	if id == "" && key == "" {
+		return nil, errors.New("product id or key required")
+	}
+	if s.products == nil {
+		return nil, errors.New("product lookup unavailable")
+	}
+	var (
+		p   *domain.Product
+		err error
+	)
+	if id != "" {
+		p, err = s.products.GetByID(ctx, projectID, id)
+	} else {
+		p, err = s.products.GetByKey(ctx, projectID, key)
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, errors.New("product not found")
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+func validate(sel domain.ProductSelection) error {
+	if sel.Name.IsEmpty() {
+		return errors.New("name required")
+	}
+	return nil
+}
+
+func without(ids []string, id string) []string {
+	out := ids[:0:0]
+	for _, x := range ids {
+		if x != id {
+			out = append(out, x)
+		}
+	}
+	return out
+}
@@ -0,0 +1,170 @@
+package producttype
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"commercetools-replica/internal/domain"
+	producttyperepo "commercetools-replica/internal/repository/producttype"
+)
+
+type Service struct {
+	repo producttyperepo.Repository
+}
+
+func New(repo producttyperepo.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// ListPage returns one page of product types plus the project total; limit 0 means no limit.
+func (s *Service) ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.ProductType, int, error) {
+	return s.repo.List(ctx, projectID, limit, offset)
+}
+
+func (s *Service) Get(ctx context.Context, projectID, id string) (*domain.ProductType, error) {
+	return s.repo.GetByID(ctx, projectID, id)
+}
+
+func (s *Service) GetByKey(ctx context.Context, projectID, key string) (*domain.ProductType, error) {
+	return s.repo.GetByKey(ctx, projectID, key)
+}
+
+type ProductTypeDraft struct {
+	Key         string                     `json:"key,omitempty"`
+	Name        string                     `json:"name"`
+	Description string                     `json:"description"`
+	Attributes  []AttributeDefinitionDraft `json:"attributes,omitempty"`
+}
+
+type AttributeDefinitionDraft struct {
+	Type                domain.AttributeType   `json:"type"`
+	Name                string                 `json:"name"`
+	Label               domain.LocalizedString `json:"label"`
+	IsRequired          bool                   `json:"isRequired"`
+	AttributeConstraint string                 `json:"attributeConstraint,omitempty"`
+	InputHint           string                 `json:"inputHint,omitempty"`
+	// IsSearchable defaults to true like in commercetools.
+	IsSearchable *bool `json:"isSearchable,omitempty"`
+}
+
+var attributeNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{2,256}$`)
+
+func (s *Service) Create(ctx context.Context, projectID string, draft ProductTypeDraft) (*domain.ProductType, error) {
+	t, err := buildProductType(projectID, draft)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, t)
+}
+
+// buildProductType validates a draft and fills in the commercetools defaults.
+func buildProductType(projectID string, draft ProductTypeDraft) (domain.ProductType, error) {
+	name := strings.TrimSpace(draft.Name)
+	if name == "" {
+		return domain.ProductType{}, errors.New("name required")
+	}
+	t := domain.ProductType{
+		ProjectID:   projectID,
+		Key:         strings.TrimSpace(draft.Key),
+		Name:        name,
+		Description: strings.TrimSpace(draft.Description),
+		Attributes:  []domain.AttributeDefinition{},
+	}
+	seen := map[string]struct{}{}
+	for _, ad := range draft.Attributes {
+		def, err := buildAttributeDefinition(ad)
+		if err != nil {
+			return domain.ProductType{}, err
+		}
+		if _, dup := seen[def.Name]; dup {
+			return domain.ProductType{}, fmt.Errorf("duplicate attribute %s", def.Name)
+		}
+		seen[def.Name] = struct{}{}
+		t.Attributes = append(t.Attributes, def)
+	}
+	return t, nil
+}
+
+func buildAttributeDefinition(d AttributeDefinitionDraft) (domain.AttributeDefinition, error) {
+	name := strings.TrimSpace(d.Name)
+	if !attributeNamePattern.MatchString(name) {
+		return domain.AttributeDefinition{}, fmt.Errorf("invalid attribute name %q", d.Name)
+	}
+	if err := validateAttributeType(d.Type, false); err != nil {
+		return domain.AttributeDefinition{}, fmt.Errorf("attribute %s: %w", name, err)
+	}
+
+	constraint := strings.TrimSpace(d.AttributeConstraint)
+	switch constraint {
+	case "":
+		constraint = domain.AttributeConstraintNone
+	case domain.AttributeConstraintNone, domain.AttributeConstraintUnique, domain.AttributeConstraintCombinationUnique, domain.AttributeConstraintSameForAll:
+	default:
+		return domain.AttributeDefinition{}, fmt.Errorf("attribute %s: invalid attributeConstraint %q", name, d.AttributeConstraint)
+	}
+
+	inputHint := strings.TrimSpace(d.InputHint)
+	switch inputHint {
+	case "":
+		inputHint = "SingleLine"
+	case "SingleLine", "MultiLine":
+	default:
+		return domain.AttributeDefinition{}, fmt.Errorf("attribute %s: invalid inputHint %q", name, d.InputHint)
+	}
+
+	label := d.Label.Clone()
+	if label.IsEmpty() {
+		label = domain.Localized(domain.DefaultLocale, name)
+	}
+	searchable := true
+	if d.IsSearchable != nil {
+		searchable = *d.IsSearchable
+	}
+	return domain.AttributeDefinition{
+		Name:                name,
+		Label:               label,
+		Type:                d.Type,
+		AttributeConstraint: constraint,
+		IsRequired:          d.IsRequired,
+		IsSearchable:        searchable,
+		InputHint:           inputHint,
+	}, nil
+}
+
+func validateAttributeType(t domain.AttributeType, inSet bool) error {
+	switch t.Name {
+	case domain.AttributeTypeBoolean, domain.AttributeTypeText, domain.AttributeTypeLText, domain.AttributeTypeNumber,
+		domain.AttributeTypeMoney, domain.AttributeTypeDate, domain.AttributeTypeTime, domain.AttributeTypeDateTime,
+		domain.AttributeTypeReference:
+		return nil
+	case domain.AttributeTypeEnum, domain.AttributeTypeLEnum:
+		if len(t.Values) == 0 {
+			return fmt.Errorf("%s type requires values", t.Name)
+		}
+		keys := map[string]struct{}{}
+		for _, v := range t.Values {
+			if strings.TrimSpace(v.Key) == "" {
+				return errors.New("enum value key required")
+			}
+			if _, dup := keys[v.Key]; dup {
+				return fmt.Errorf("duplicate enum value %s", v.Key)
+			}
+			keys[v.Key] = struct{}{}
+		}
+		return nil
+	case domain.AttributeTypeSet:
+		if inSet {
+			return errors.New("nested set types are not supported")
+		}
+		if t.ElementType == nil {
+			return errors.New("set type requires elementType")
+		}
+		return validateAttributeType(*t.ElementType, true)
+	case "":
+		return errors.New("type required")
+	}
+	return fmt.Errorf("unsupported type %q", t.Name)
+}
@@ -0,0 +1,375 @@
+package shippingmethod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"commercetools-replica/internal/domain"
+	"commercetools-replica/internal/predicate"
+	shippingmethodrepo "commercetools-replica/internal/repository/shippingmethod"
+	"commercetools-replica/internal/service/updateaction"
+)
+
+type Service struct {
+	repo          shippingmethodrepo.Repository
+	taxCategories taxCategoryLookup
+	zones         zoneLookup
+}
+
+// taxCategoryLookup resolves the tax category a shipping method is taxed with.
+type taxCategoryLookup interface {
+	GetByID(ctx context.Context, projectID, id string) (*domain.TaxCategory, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.TaxCategory, error)
+}
+
+// zoneLookup resolves the zones of zone rates.
+type zoneLookup interface {
+	GetByID(ctx context.Context, projectID, id string) (*domain.Zone, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.Zone, error)
+}
+
+func New(repo shippingmethodrepo.Repository, taxCategories taxCategoryLookup, zones zoneLookup) *Service {
+	return &Service{repo: repo, taxCategories: taxCategories, zones: zones}
+}
+
+// ListPage returns one page of shipping methods, oldest first; limit 0 means no limit.
+func (s *Service) ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.ShippingMethod, int, error) {
+	return s.repo.List(ctx, projectID, limit, offset)
+}
+
+func (s *Service) Get(ctx context.Context, projectID, id string) (*domain.ShippingMethod, error) {
+	return s.repo.GetByID(ctx, projectID, id)
+}
+
+func (s *Service) GetByKey(ctx context.Context, projectID, key string) (*domain.ShippingMethod, error) {
+	return s.repo.GetByKey(ctx, projectID, key)
+}
+
+type ResourceIdentifier struct {
+	TypeID string `json:"typeId,omitempty"`
+	ID     string `json:"id,omitempty"`
+	Key    string `json:"key,omitempty"`
+}
+
+type ZoneRateDraft struct {
+	Zone          ResourceIdentifier    `json:"zone"`
+	ShippingRates []domain.ShippingRate `json:"shippingRates,omitempty"`
+}
+
+type ShippingMethodDraft struct {
+	Key         string             `json:"key,omitempty"`
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	TaxCategory ResourceIdentifier `json:"taxCategory"`
+	ZoneRates   []ZoneRateDraft    `json:"zoneRates,omitempty"`
+	IsDefault   bool               `json:"isDefault,omitempty"`
+	Predicate   string             `json:"predicate,omitempty"`
+}
+
+func (s *Service) Create(ctx context.Context, projectID string, draft ShippingMethodDraft) (*domain.ShippingMethod, error) {
+	m := domain.ShippingMethod{
+		ProjectID:   projectID,
+		Key:         strings.TrimSpace(draft.Key),
+		Name:        strings.TrimSpace(draft.Name),
+		Description: strings.TrimSpace(draft.Description),
+		IsDefault:   draft.IsDefault,
+		Predicate:   strings.TrimSpace(draft.Predicate),
+	}
+	tc, err := s.resolveTaxCategory(ctx, projectID, draft.TaxCategory)
+	if err != nil {
+		return nil, err
+	}
+	m.TaxCategoryID = tc.ID
+	for _, zr := range draft.ZoneRates {
+		z, err := s.resolveZone(ctx, projectID, zr.Zone)
+		if err != nil {
+			return nil, err
+		}
+		rate := domain.ZoneRate{ZoneID: z.ID}
+		for _, r := range zr.ShippingRates {
+			rate.ShippingRates = append(rate.ShippingRates, normalizeRate(r))
+		}
+		m.ZoneRates = append(m.ZoneRates, rate)
+	}
+	if err := validate(m); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, m)
+}
+
+type UpdateInput struct {
+	Version int            `json:"version"`
+	Actions []UpdateAction `json:"actions"`
+}
+
+type UpdateAction = updateaction.Action
+
+// Update applies the actions to the stored shipping method if in.Version is still current.
+func (s *Service) Update(ctx context.Context, projectID, id string, in UpdateInput) (*domain.ShippingMethod, error) {
+	if len(in.Actions) == 0 {
+		return nil, errors.New("actions required")
+	}
+	if in.Version <= 0 {
+		return nil, errors.New("version required")
+	}
+	m, err := s.repo.GetByID(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if m.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	for _, action := range in.Actions {
+		if err := s.apply(ctx, m, action); err != nil {
+			return nil, err
+		}
+	}
+	if err := validate(*m); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, *m)
+}
+
+func (s *Service) Delete(ctx context.Context, projectID, id string, version int) (*domain.ShippingMethod, error) {
+	if version <= 0 {
+		return nil, errors.New("version required")
+	}
+	return s.repo.Delete(ctx, projectID, id, version)
+}
+
+func (s *Service) apply(ctx context.Context, m *domain.ShippingMethod, action UpdateAction) error {
+	switch strings.ToLower(strings.TrimSpace(action.Action)) {
+	case "changename":
+		var a struct {
+			Name string `json:"name"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		m.Name = strings.TrimSpace(a.Name)
+	case "setdescription":
+		var a struct {
+			Description string `json:"description"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		m.Description = strings.TrimSpace(a.Description)
+	case "setkey":
+		var a struct {
+			Key string `json:"key"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		m.Key = strings.TrimSpace(a.Key)
+	case "changetaxcategory":
+		var a struct {
+			TaxCategory ResourceIdentifier `json:"taxCategory"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		tc, err := s.resolveTaxCategory(ctx, m.ProjectID, a.TaxCategory)
+		if err != nil {
+			return err
+		}
+		m.TaxCategoryID = tc.ID
+	case "changeisdefault":
+		var a struct {
+			IsDefault bool `json:"isDefault"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		m.IsDefault = a.IsDefault
+	case "setpredicate":
+		var a struct {
+			Predicate string `json:"predicate"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		m.Predicate = strings.TrimSpace(a.Predicate)
+	case "addzone":
+		var a struct {
+			Zone ResourceIdentifier `json:"zone"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		z, err := s.resolveZone(ctx, m.ProjectID, a.Zone)
+		if err != nil {
+			return err
+		}
+		m.ZoneRates = append(m.ZoneRates, domain.ZoneRate{ZoneID: z.ID})
+	case "removezone":
+		var a struct {
+			Zone ResourceIdentifier `json:"zone"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		i, err := s.zoneRateIndex(ctx, m, a.Zone)
+		if err != nil {
+			return err
+		}
+		m.ZoneRates = append(m.ZoneRates[:i], m.ZoneRates[i+1:]...)
+	case "addshippingrate":
+		var a struct {
+			Zone         ResourceIdentifier  `json:"zone"`
+			ShippingRate domain.ShippingRate `json:"shippingRate"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		i, err := s.zoneRateIndex(ctx, m, a.Zone)
+		if err != nil {
+			return err
+		}
+		m.ZoneRates[i].ShippingRates = append(m.ZoneRates[i].ShippingRates, normalizeRate(a.ShippingRate))
+	case "removeshippingrate":
+		var a struct {
+			Zone         ResourceIdentifier  `json:"zone"`
+			ShippingRate domain.ShippingRate `json:"shippingRate"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		i, err := s.zoneRateIndex(ctx, m, a.Zone)
+		if err != nil {
+			return err
+		}
+		r := normalizeRate(a.ShippingRate)
+		rates := m.ZoneRates[i].ShippingRates
+		for j := range rates {
+			if rates[j].Price.CurrencyCode == r.Price.CurrencyCode && rates[j].Price.CentAmount == r.Price.CentAmount {
+				m.ZoneRates[i].ShippingRates = append(rates[:j], rates[j+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("shipping rate %d %s not found", r.Price.CentAmount, r.Price.CurrencyCode)
+	default:
+		return fmt.Errorf("unsupported action %q", action.Action)
+	}
+	return nil
+}
+
+func validate(m domain.ShippingMethod) error {
+	if m.Name == "" {
+		return errors.New("name required")
+	}
+	if m.Predicate != "" {
+		if _, err := predicate.Compile(m.Predicate, predicate.CartSchema); err != nil {
+			return fmt.Errorf("invalid predicate: %w", err)
+		}
+	}
+	zones := map[string]struct{}{}
+	for _, zr := range m.ZoneRates {
+		if _, dup := zones[zr.ZoneID]; dup {
+			return fmt.Errorf("duplicate zone %s", zr.ZoneID)
+		}
+		zones[zr.ZoneID] = struct{}{}
+		currencies := map[string]struct{}{}
+		for _, r := range zr.ShippingRates {
+			if len(r.Price.CurrencyCode) != 3 {
+				return errors.New("shipping rate currencyCode required")
+			}
+			if r.Price.CentAmount < 0 {
+				return errors.New("shipping rate price must not be negative")
+			}
+			if r.FreeAbove != nil && (r.FreeAbove.CurrencyCode != r.Price.CurrencyCode || r.FreeAbove.CentAmount < 0) {
+				return fmt.Errorf("freeAbove must be a %s amount that is not negative", r.Price.CurrencyCode)
+			}
+			if _, dup := currencies[r.Price.CurrencyCode]; dup {
+				return fmt.Errorf("zone %s has more than one %s rate", zr.ZoneID, r.Price.CurrencyCode)
+			}
+			currencies[r.Price.CurrencyCode] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// normalizeRate keeps only the currency and cents of the rate's amounts.
+func normalizeRate(r domain.ShippingRate) domain.ShippingRate {
+	out := domain.ShippingRate{Price: domain.Money{
+		CurrencyCode: strings.ToUpper(strings.TrimSpace(r.Price.CurrencyCode)),
+		CentAmount:   r.Price.CentAmount,
+	}}
+	if r.FreeAbove != nil {
+		out.FreeAbove = &domain.Money{
+			CurrencyCode: strings.ToUpper(strings.TrimSpace(r.FreeAbove.CurrencyCode)),
+			CentAmount:   r.FreeAbove.CentAmount,
+		}
+	}
+	return out
+}
+
+func (s *Service) zoneRateIndex(ctx context.Context, m *domain.ShippingMethod, ref ResourceIdentifier) (int, error) {
+	z, err := s.resolveZone(ctx, m.ProjectID, ref)
+	if err != nil {
+		return -1, err
+	}
+	for i, zr := range m.ZoneRates {
+		if zr.ZoneID == z.ID {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("zone %s not found on shipping method", z.ID)
+}
+
+func (s *Service) resolveTaxCategory(ctx context.Context, projectID string, ref ResourceIdentifier) (*domain.TaxCategory, error) {
+	id := strings.TrimSpace(ref.ID)
+	key := strings.TrimSpace(ref.Key)
+	if id == "" && key == "" {
+		return nil, errors.New("tax category id or key required")
+	}
+	if s.taxCategories == nil {
+		return nil, errors.New("tax category lookup unavailable")
+	}
+	var (
+		tc  *domain.TaxCategory
+		err error
+	)
+	if id != "" {
+		tc, err = s.taxCategories.GetByID(ctx, projectID, id)
+	} else {
+		tc, err = s.taxCategories.GetByKey(ctx, projectID, key)
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, errors.New("tax category not found")
+		}
+		return nil, err
+	}
+	return tc, nil
+}
+
+func (s *Service) resolveZone(ctx context.Context, projectID string, ref ResourceIdentifier) (*domain.Zone, error) {
+	id := strings.TrimSpace(ref.ID)
+	key := strings.TrimSpace(ref.Key)
+	if id == "" && key == "" {
+		return nil, errors.New("zone id or key required")
+	}
+	if s.zones == nil {
+		return nil, errors.New("zone lookup unavailable")
+	}
+	var (
+		z   *domain.Zone
+		err error
+	)
+	if id != "" {
+		z, err = s.zones.GetByID(ctx, projectID, id)
+	} else {
+		z, err = s.zones.GetByKey(ctx, projectID, key)
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, errors.New("zone not found")
+		}
+		return nil, err
+	}
+	return z, nil
+}
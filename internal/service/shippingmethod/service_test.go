@@ -0,0 +1,251 @@
+package shippingmethod
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"commercetools-replica/internal/domain"
+	shippingmethodrepo "commercetools-replica/internal/repository/shippingmethod"
+)
+
+// methodRepo holds the shipping methods the service created; the service
+// tests do not list or delete them.
+type methodRepo struct {
+	shippingmethodrepo.Repository
+	byID map[string]domain.ShippingMethod
+}
+
+func (r *methodRepo) GetByID(_ context.Context, _, id string) (*domain.ShippingMethod, error) {
+	m, ok := r.byID[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	m.ZoneRates = append([]domain.ZoneRate(nil), m.ZoneRates...)
+	for i := range m.ZoneRates {
+		m.ZoneRates[i].ShippingRates = append([]domain.ShippingRate(nil), m.ZoneRates[i].ShippingRates...)
+	}
+	return &m, nil
+}
+
+func (r *methodRepo) Create(_ context.Context, m domain.ShippingMethod) (*domain.ShippingMethod, error) {
+	m.ID, m.Version = "method-"+m.Key, 1
+	r.byID[m.ID] = m
+	return &m, nil
+}
+
+func (r *methodRepo) Update(_ context.Context, m domain.ShippingMethod) (*domain.ShippingMethod, error) {
+	m.Version++
+	r.byID[m.ID] = m
+	return &m, nil
+}
+
+type stubTaxCategories map[string]domain.TaxCategory
+
+func (s stubTaxCategories) GetByID(_ context.Context, _ string, id string) (*domain.TaxCategory, error) {
+	for _, tc := range s {
+		if tc.ID == id {
+			return &tc, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s stubTaxCategories) GetByKey(_ context.Context, _ string, key string) (*domain.TaxCategory, error) {
+	tc, ok := s[key]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &tc, nil
+}
+
+type stubZones map[string]domain.Zone
+
+func (s stubZones) GetByID(_ context.Context, _ string, id string) (*domain.Zone, error) {
+	for _, z := range s {
+		if z.ID == id {
+			return &z, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s stubZones) GetByKey(_ context.Context, _ string, key string) (*domain.Zone, error) {
+	z, ok := s[key]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &z, nil
+}
+
+func newTestService() (*Service, *methodRepo) {
+	repo := &methodRepo{byID: map[string]domain.ShippingMethod{}}
+	svc := New(repo,
+		stubTaxCategories{"standard": {ID: "tc-1", Key: "standard"}, "reduced": {ID: "tc-2", Key: "reduced"}},
+		stubZones{"europe": {ID: "zone-1", Key: "europe"}, "usa": {ID: "zone-2", Key: "usa"}})
+	return svc, repo
+}
+
+func standardDraft() ShippingMethodDraft {
+	return ShippingMethodDraft{
+		Key:         "standard",
+		Name:        "Standard",
+		TaxCategory: ResourceIdentifier{TypeID: "tax-category", Key: "standard"},
+		ZoneRates: []ZoneRateDraft{{
+			Zone: ResourceIdentifier{TypeID: "zone", Key: "europe"},
+			ShippingRates: []domain.ShippingRate{{
+				Price:     domain.Money{CurrencyCode: "eur", CentAmount: 499},
+				FreeAbove: &domain.Money{CurrencyCode: " eur", CentAmount: 5000},
+			}},
+		}},
+	}
+}
+
+func updateMethod(svc *Service, m *domain.ShippingMethod, actions string) (*domain.ShippingMethod, error) {
+	in := UpdateInput{Version: m.Version}
+	if err := json.Unmarshal([]byte(actions), &in.Actions); err != nil {
+		return nil, err
+	}
+	return svc.Update(context.Background(), "proj", m.ID, in)
+}
+
+func TestServiceCreateResolvesReferences(t *testing.T) {
+	svc, _ := newTestService()
+	ctx := context.Background()
+
+	m, err := svc.Create(ctx, "proj", standardDraft())
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if m.TaxCategoryID != "tc-1" || len(m.ZoneRates) != 1 || m.ZoneRates[0].ZoneID != "zone-1" {
+		t.Fatalf("expected the tax category and zone keys to be resolved to ids, got %+v", m)
+	}
+	if rate := m.RateFor([]string{"zone-1"}, "EUR"); rate == nil || rate.FreeAbove == nil || rate.FreeAbove.CurrencyCode != "EUR" {
+		t.Fatalf("expected upper-case currencies on the EUR rate, got %+v", rate)
+	}
+
+	for _, tc := range []struct {
+		mutate func(*ShippingMethodDraft)
+		want   string
+	}{
+		{func(d *ShippingMethodDraft) { d.TaxCategory = ResourceIdentifier{TypeID: "tax-category"} }, "tax category id or key required"},
+		{func(d *ShippingMethodDraft) { d.TaxCategory = ResourceIdentifier{ID: "tc-9"} }, "tax category not found"},
+		{func(d *ShippingMethodDraft) { d.ZoneRates[0].Zone = ResourceIdentifier{Key: "asia"} }, "zone not found"},
+		{func(d *ShippingMethodDraft) {
+			d.ZoneRates = append(d.ZoneRates, ZoneRateDraft{Zone: ResourceIdentifier{ID: "zone-1"}})
+		}, "duplicate zone zone-1"},
+	} {
+		draft := standardDraft()
+		draft.Key = "other"
+		tc.mutate(&draft)
+		if _, err := svc.Create(ctx, "proj", draft); err == nil || err.Error() != tc.want {
+			t.Fatalf("expected %q, got %v", tc.want, err)
+		}
+	}
+}
+
+func TestServiceCreateValidatesRates(t *testing.T) {
+	svc, repo := newTestService()
+
+	for _, tc := range []struct {
+		rates []domain.ShippingRate
+		want  string
+	}{
+		{[]domain.ShippingRate{{Price: domain.Money{CentAmount: 499}}}, "shipping rate currencyCode required"},
+		{[]domain.ShippingRate{{Price: domain.Money{CurrencyCode: "EUR", CentAmount: -1}}}, "shipping rate price must not be negative"},
+		{[]domain.ShippingRate{{
+			Price:     domain.Money{CurrencyCode: "EUR", CentAmount: 499},
+			FreeAbove: &domain.Money{CurrencyCode: "USD", CentAmount: 5000},
+		}}, "freeAbove must be a EUR amount that is not negative"},
+		{[]domain.ShippingRate{
+			{Price: domain.Money{CurrencyCode: "EUR", CentAmount: 499}},
+			{Price: domain.Money{CurrencyCode: "eur", CentAmount: 999}},
+		}, "zone zone-1 has more than one EUR rate"},
+	} {
+		draft := standardDraft()
+		draft.ZoneRates[0].ShippingRates = tc.rates
+		if _, err := svc.Create(context.Background(), "proj", draft); err == nil || err.Error() != tc.want {
+			t.Fatalf("expected %q, got %v", tc.want, err)
+		}
+	}
+	if len(repo.byID) != 0 {
+		t.Fatalf("expected no shipping method stored, got %+v", repo.byID)
+	}
+}
+
+func TestServiceZoneAndRateActions(t *testing.T) {
+	svc, repo := newTestService()
+	m, err := svc.Create(context.Background(), "proj", standardDraft())
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	m, err = updateMethod(svc, m, `[
+		{"action":"addZone","zone":{"typeId":"zone","key":"usa"}},
+		{"action":"addShippingRate","zone":{"id":"zone-2"},"shippingRate":{"price":{"currencyCode":"usd","centAmount":999}}},
+		{"action":"removeShippingRate","zone":{"key":"europe"},"shippingRate":{"price":{"currencyCode":"eur","centAmount":499}}},
+		{"action":"changeTaxCategory","taxCategory":{"key":"reduced"}}
+	]`)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if m.TaxCategoryID != "tc-2" || len(m.ZoneRates) != 2 || len(m.ZoneRates[0].ShippingRates) != 0 {
+		t.Fatalf("unexpected shipping method %+v", m)
+	}
+	if rate := m.RateFor([]string{"zone-2"}, "USD"); rate == nil || rate.Price.CentAmount != 999 {
+		t.Fatalf("expected a USD rate in zone-2, got %+v", rate)
+	}
+
+	for _, tc := range []struct {
+		actions string
+		want    string
+	}{
+		{`[{"action":"addZone","zone":{"id":"zone-2"}}]`, "duplicate zone zone-2"},
+		{`[{"action":"addShippingRate","zone":{"key":"usa"},"shippingRate":{"price":{"currencyCode":"USD","centAmount":1}}}]`, "zone zone-2 has more than one USD rate"},
+		{`[{"action":"removeShippingRate","zone":{"key":"usa"},"shippingRate":{"price":{"currencyCode":"USD","centAmount":1}}}]`, "shipping rate 1 USD not found"},
+		{`[{"action":"removeZone","zone":{"key":"usa"}},{"action":"removeZone","zone":{"key":"usa"}}]`, "zone zone-2 not found on shipping method"},
+		{`[{"action":"addShippingRate","zone":{"key":"asia"},"shippingRate":{"price":{"currencyCode":"JPY","centAmount":1}}}]`, "zone not found"},
+	} {
+		if _, err := updateMethod(svc, m, tc.actions); err == nil || err.Error() != tc.want {
+			t.Fatalf("%s: expected %q, got %v", tc.actions, tc.want, err)
+		}
+	}
+	if stored := repo.byID[m.ID]; stored.Version != 2 || len(stored.ZoneRates) != 2 {
+		t.Fatalf("expected failed updates not to be stored, got %+v", stored)
+	}
+}
+
+func TestServiceSetPredicate(t *testing.T) {
+	svc, _ := newTestService()
+	draft := standardDraft()
+	draft.Predicate = " totalPrice.centAmount > 100 "
+	m, err := svc.Create(context.Background(), "proj", draft)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if m.Predicate != "totalPrice.centAmount > 100" {
+		t.Fatalf("expected a trimmed predicate, got %q", m.Predicate)
+	}
+
+	if _, err := updateMethod(svc, m, `[{"action":"setPredicate","predicate":"totalPrice ="}]`); err == nil || !strings.HasPrefix(err.Error(), "invalid predicate") {
+		t.Fatalf("expected an invalid predicate to be rejected, got %v", err)
+	}
+	m, err = updateMethod(svc, m, `[{"action":"setPredicate","predicate":""}]`)
+	if err != nil || m.Predicate != "" {
+		t.Fatalf("expected the predicate to be removed, got %+v, %v", m, err)
+	}
+}
+
+func TestShippingRatePriceFor(t *testing.T) {
+	rate := domain.ShippingRate{
+		Price:     domain.Money{CurrencyCode: "EUR", CentAmount: 499},
+		FreeAbove: &domain.Money{CurrencyCode: "EUR", CentAmount: 5000},
+	}
+	if got := rate.PriceFor(4999); got.CentAmount != 499 {
+		t.Fatalf("expected 499 below the threshold, got %d", got.CentAmount)
+	}
+	if got := rate.PriceFor(5000); got.CentAmount != 0 || got.CurrencyCode != "EUR" {
+		t.Fatalf("expected free shipping at the threshold, got %+v", got)
+	}
+}
@@ -0,0 +1,417 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"commercetools-replica/internal/domain"
+	storerepo "commercetools-replica/internal/repository/store"
+	"commercetools-replica/internal/service/updateaction"
+)
+
+type Service struct {
+	repo       storerepo.Repository
+	channels   channelLookup
+	selections selectionLookup
+}
+
+// channelLookup resolves the distribution and supply channels of a store.
+type channelLookup interface {
+	GetByID(ctx context.Context, projectID, id string) (*domain.Channel, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.Channel, error)
+}
+
+// selectionLookup resolves the product selections of a store.
+type selectionLookup interface {
+	GetByID(ctx context.Context, projectID, id string) (*domain.ProductSelection, error)
+	GetByKey(ctx context.Context, projectID, key string) (*domain.ProductSelection, error)
+}
+
+func New(repo storerepo.Repository, channels channelLookup, selections selectionLookup) *Service {
+	return &Service{repo: repo, channels: channels, selections: selections}
+}
+
+// ListPage returns one page of stores, oldest first; limit 0 means no limit.
+func (s *Service) ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.Store, int, error) {
+	return s.repo.List(ctx, projectID, limit, offset)
+}
+
+func (s *Service) Get(ctx context.Context, projectID, id string) (*domain.Store, error) {
+	return s.repo.GetByID(ctx, projectID, id)
+}
+
+func (s *Service) GetByKey(ctx context.Context, projectID, key string) (*domain.Store, error) {
+	return s.repo.GetByKey(ctx, projectID, key)
+}
+
+// ProductIDs returns the assortment of the store: the products of its active
+// product selections, or nil if it sells every product.
+func (s *Service) ProductIDs(ctx context.Context, projectID, storeID string) (map[string]bool, error) {
+	return s.repo.ProductIDs(ctx, projectID, storeID)
+}
+
+type ResourceIdentifier struct {
+	TypeID string `json:"typeId,omitempty"`
+	ID     string `json:"id,omitempty"`
+	Key    string `json:"key,omitempty"`
+}
+
+type StoreCountry struct {
+	Code string `json:"code"`
+}
+
+// ProductSelectionSettingDraft adds a product selection to a store; it only
+// counts towards the assortment once active.
+type ProductSelectionSettingDraft struct {
+	ProductSelection ResourceIdentifier `json:"productSelection"`
+	Active           bool               `json:"active,omitempty"`
+}
+
+type StoreDraft struct {
+	Key                  string                         `json:"key"`
+	Name                 domain.LocalizedString         `json:"name,omitempty"`
+	Languages            []string                       `json:"languages,omitempty"`
+	Countries            []StoreCountry                 `json:"countries,omitempty"`
+	DistributionChannels []ResourceIdentifier           `json:"distributionChannels,omitempty"`
+	SupplyChannels       []ResourceIdentifier           `json:"supplyChannels,omitempty"`
+	ProductSelections    []ProductSelectionSettingDraft `json:"productSelections,omitempty"`
+}
+
+func (s *Service) Create(ctx context.Context, projectID string, draft StoreDraft) (*domain.Store, error) {
+	st := domain.Store{
+		ProjectID: projectID,
+		Key:       strings.TrimSpace(draft.Key),
+		Name:      draft.Name,
+		Languages: draft.Languages,
+		Countries: countryCodes(draft.Countries),
+	}
+	var err error
+	if st.DistributionChannelIDs, err = s.resolveChannels(ctx, projectID, draft.DistributionChannels, domain.ChannelRoleProductDistribution); err != nil {
+		return nil, err
+	}
+	if st.SupplyChannelIDs, err = s.resolveChannels(ctx, projectID, draft.SupplyChannels, domain.ChannelRoleInventorySupply); err != nil {
+		return nil, err
+	}
+	for _, ps := range draft.ProductSelections {
+		sel, err := s.resolveSelection(ctx, projectID, ps.ProductSelection)
+		if err != nil {
+			return nil, err
+		}
+		st.ProductSelections = append(st.ProductSelections, domain.StoreProductSelection{ProductSelectionID: sel.ID, Active: ps.Active})
+	}
+	if err := validate(&st); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, st)
+}
+
+type UpdateInput struct {
+	Version int            `json:"version"`
+	Actions []UpdateAction `json:"actions"`
+}
+
+type UpdateAction = updateaction.Action
+
+// Update applies the actions to the stored store if in.Version is still current.
+func (s *Service) Update(ctx context.Context, projectID, id string, in UpdateInput) (*domain.Store, error) {
+	if len(in.Actions) == 0 {
+		return nil, errors.New("actions required")
+	}
+	if in.Version <= 0 {
+		return nil, errors.New("version required")
+	}
+	st, err := s.repo.GetByID(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if st.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	for _, action := range in.Actions {
+		if err := s.apply(ctx, st, action); err != nil {
+			return nil, err
+		}
+	}
+	if err := validate(st); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, *st)
+}
+
+// Delete removes the store; its carts and orders stay without a store.
+func (s *Service) Delete(ctx context.Context, projectID, id string, version int) (*domain.Store, error) {
+	if version <= 0 {
+		return nil, errors.New("version required")
+	}
+	return s.repo.Delete(ctx, projectID, id, version)
+}
+
+func (s *Service) apply(ctx context.Context, st *domain.Store, action UpdateAction) error {
+	name := strings.ToLower(strings.TrimSpace(action.Action))
+	switch name {
+	case "setname":
+		var a struct {
+			Name domain.LocalizedString `json:"name"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		st.Name = a.Name
+	case "setlanguages":
+		var a struct {
+			Languages []string `json:"languages"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		st.Languages = a.Languages
+	case "setcountries":
+		var a struct {
+			Countries []StoreCountry `json:"countries"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		st.Countries = countryCodes(a.Countries)
+	case "addcountry", "removecountry":
+		var a struct {
+			Country StoreCountry `json:"country"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		code := strings.ToUpper(strings.TrimSpace(a.Country.Code))
+		if name == "addcountry" {
+			st.Countries = append(st.Countries, code)
+			return nil
+		}
+		countries, err := remove(st.Countries, code, "country")
+		if err != nil {
+			return err
+		}
+		st.Countries = countries
+	case "setdistributionchannels", "setsupplychannels":
+		var a struct {
+			DistributionChannels []ResourceIdentifier `json:"distributionChannels"`
+			SupplyChannels       []ResourceIdentifier `json:"supplyChannels"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		var err error
+		if name == "setdistributionchannels" {
+			st.DistributionChannelIDs, err = s.resolveChannels(ctx, st.ProjectID, a.DistributionChannels, domain.ChannelRoleProductDistribution)
+		} else {
+			st.SupplyChannelIDs, err = s.resolveChannels(ctx, st.ProjectID, a.SupplyChannels, domain.ChannelRoleInventorySupply)
+		}
+		return err
+	case "adddistributionchannel", "removedistributionchannel", "addsupplychannel", "removesupplychannel":
+		var a struct {
+			DistributionChannel ResourceIdentifier `json:"distributionChannel"`
+			SupplyChannel       ResourceIdentifier `json:"supplyChannel"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		ref, role, ids := a.DistributionChannel, domain.ChannelRoleProductDistribution, &st.DistributionChannelIDs
+		if strings.HasSuffix(name, "supplychannel") {
+			ref, role, ids = a.SupplyChannel, domain.ChannelRoleInventorySupply, &st.SupplyChannelIDs
+		}
+		resolved, err := s.resolveChannels(ctx, st.ProjectID, []ResourceIdentifier{ref}, role)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(name, "add") {
+			*ids = append(*ids, resolved[0])
+			return nil
+		}
+		kept, err := remove(*ids, resolved[0], "channel")
+		if err != nil {
+			return err
+		}
+		*ids = kept
+	case "setproductselections":
+		var a struct {
+			ProductSelections []ProductSelectionSettingDraft `json:"productSelections"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		st.ProductSelections = nil
+		for _, ps := range a.ProductSelections {
+			sel, err := s.resolveSelection(ctx, st.ProjectID, ps.ProductSelection)
+			if err != nil {
+				return err
+			}
+			st.ProductSelections = append(st.ProductSelections, domain.StoreProductSelection{ProductSelectionID: sel.ID, Active: ps.Active})
+		}
+	case "addproductselection", "removeproductselection", "changeproductselectionactive":
+		var a ProductSelectionSettingDraft
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		sel, err := s.resolveSelection(ctx, st.ProjectID, a.ProductSelection)
+		if err != nil {
+			return err
+		}
+		i := selectionIndex(st.ProductSelections, sel.ID)
+		switch {
+		case name == "addproductselection":
+			st.ProductSelections = append(st.ProductSelections, domain.StoreProductSelection{ProductSelectionID: sel.ID, Active: a.Active})
+		case i < 0:
+			return fmt.Errorf("product selection %s not in store", sel.ID)
+		case name == "removeproductselection":
+			st.ProductSelections = append(st.ProductSelections[:i:i], st.ProductSelections[i+1:]...)
+		default:
+			st.ProductSelections[i].Active = a.Active
+		}
+	default:
+		return fmt.Errorf("unsupported action %q", action.Action)
+	}
+	return nil
+}
+
+// resolveChannels returns the ids of the channels, which need role.
+func (s *Service) resolveChannels(ctx context.Context, projectID string, refs []ResourceIdentifier, role string) ([]string, error) {
+	var ids []string
+	for _, ref := range refs {
+		id := strings.TrimSpace(ref.ID)
+		key := strings.TrimSpace(ref.Key)
+		if id == "" && key == "" {
+			return nil, errors.New("channel id or key required")
+		}
+		if s.channels == nil {
+			return nil, errors.New("channel lookup unavailable")
+		}
+		var (
+			c   *domain.Channel
+			err error
+		)
+		if id != "" {
+			c, err = s.channels.GetByID(ctx, projectID, id)
+		} else {
+			c, err = s.channels.GetByKey(ctx, projectID, key)
+		}
+		if err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				return nil, errors.New("channel not found")
+			}
+			return nil, err
+		}
+		if !c.HasRole(role) {
+			return nil, fmt.Errorf("channel %s lacks the %s role", c.Key, role)
+		}
+		ids = append(ids, c.ID)
+	}
+	return ids, nil
+}
+
+func (s *Service) resolveSelection(ctx context.Context, projectID string, ref ResourceIdentifier) (*domain.ProductSelection, error) {
+	id := strings.TrimSpace(ref.ID)
+	key := strings.TrimSpace(ref.Key)
+	if id == "" && key == "" {
+		return nil, errors.New("product selection id or key required")
+	}
+	if s.selections == nil {
+		return nil, errors.New("product selection lookup unavailable")
+	}
+	var (
+		sel *domain.ProductSelection
+		err error
+	)
+	if id != "" {
+		sel, err = s.selections.GetByID(ctx, projectID, id)
+	} else {
+		sel, err = s.selections.GetByKey(ctx, projectID, key)
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, errors.New("product selection not found")
+		}
+		return nil, err
+	}
+	return sel, nil
+}
+
+var keyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{2,256}$`)
+
+// validate checks the store and normalizes its languages and countries.
+func validate(st *domain.Store) error {
+	if !keyPattern.MatchString(st.Key) {
+		return errors.New("key must be 2 to 256 letters, digits, '-' or '_'")
+	}
+	var languages []string
+	for _, l := range st.Languages {
+		l = strings.TrimSpace(l)
+		if l == "" {
+			return errors.New("language must not be empty")
+		}
+		if containsFold(languages, l) {
+			return fmt.Errorf("duplicate language %s", l)
+		}
+		languages = append(languages, l)
+	}
+	st.Languages = languages
+	var countries []string
+	for _, c := range st.Countries {
+		if len(c) != 2 {
+			return fmt.Errorf("country %q must be a two-letter code", c)
+		}
+		if containsFold(countries, c) {
+			return fmt.Errorf("duplicate country %s", c)
+		}
+		countries = append(countries, c)
+	}
+	st.Countries = countries
+	for _, ids := range [][]string{st.DistributionChannelIDs, st.SupplyChannelIDs} {
+		for i, id := range ids {
+			if containsFold(ids[:i], id) {
+				return fmt.Errorf("duplicate channel %s", id)
+			}
+		}
+	}
+	for i, ps := range st.ProductSelections {
+		if selectionIndex(st.ProductSelections[:i], ps.ProductSelectionID) >= 0 {
+			return fmt.Errorf("duplicate product selection %s", ps.ProductSelectionID)
+		}
+	}
+	return nil
+}
+
+func countryCodes(countries []StoreCountry) []string {
+	var out []string
+	for _, c := range countries {
+		out = append(out, strings.ToUpper(strings.TrimSpace(c.Code)))
+	}
+	return out
+}
+
+func remove(values []string, v, what string) ([]string, error) {
+	for i, x := range values {
+		if strings.EqualFold(x, v) {
+			return append(values[:i:i], values[i+1:]...), nil
+		}
+	}
+	return nil, fmt.Errorf("%s %s not in store", what, v)
+}
+
+func containsFold(values []string, v string) bool {
+	for _, x := range values {
+		if strings.EqualFold(x, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func selectionIndex(selections []domain.StoreProductSelection, id string) int {
+	for i, ps := range selections {
+		if ps.ProductSelectionID == id {
+			return i
+		}
+	}
+	return -1
+}
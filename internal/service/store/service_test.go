@@ -0,0 +1,237 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"commercetools-replica/internal/domain"
+	storerepo "commercetools-replica/internal/repository/store"
+)
+
+// storeRepo holds the one store a test works on.
+type storeRepo struct {
+	storerepo.Repository
+	store *domain.Store
+}
+
+func (r *storeRepo) GetByID(_ context.Context, _, id string) (*domain.Store, error) {
+	if r.store == nil || r.store.ID != id {
+		return nil, domain.ErrNotFound
+	}
+	st := *r.store
+	st.Countries = append([]string(nil), st.Countries...)
+	st.SupplyChannelIDs = append([]string(nil), st.SupplyChannelIDs...)
+	st.DistributionChannelIDs = append([]string(nil), st.DistributionChannelIDs...)
+	st.ProductSelections = append([]domain.StoreProductSelection(nil), st.ProductSelections...)
+	return &st, nil
+}
+
+func (r *storeRepo) Create(_ context.Context, st domain.Store) (*domain.Store, error) {
+	st.ID, st.Version = "store-"+st.Key, 1
+	r.store = &st
+	return &st, nil
+}
+
+func (r *storeRepo) Update(_ context.Context, st domain.Store) (*domain.Store, error) {
+	st.Version++
+	r.store = &st
+	return &st, nil
+}
+
+type stubChannels []domain.Channel
+
+func (s stubChannels) GetByID(_ context.Context, _, id string) (*domain.Channel, error) {
+	for i := range s {
+		if s[i].ID == id {
+			return &s[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s stubChannels) GetByKey(_ context.Context, _, key string) (*domain.Channel, error) {
+	for i := range s {
+		if s[i].Key == key {
+			return &s[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+type stubSelections []domain.ProductSelection
+
+func (s stubSelections) GetByID(_ context.Context, _, id string) (*domain.ProductSelection, error) {
+	for i := range s {
+		if s[i].ID == id {
+			return &s[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s stubSelections) GetByKey(_ context.Context, _, key string) (*domain.ProductSelection, error) {
+	for i := range s {
+		if s[i].Key == key {
+			return &s[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func newTestService() (*Service, *storeRepo) {
+	repo := &storeRepo{}
+	channels := stubChannels{
+		{ID: "ch-1", Key: "uk-warehouse", Roles: []string{domain.ChannelRoleInventorySupply}},
+		{ID: "ch-2", Key: "uk-web", Roles: []string{domain.ChannelRoleProductDistribution}},
+		{ID: "ch-3", Key: "uk-shop", Roles: []string{domain.ChannelRoleInventorySupply, domain.ChannelRoleProductDistribution}},
+	}
+	selections := stubSelections{{ID: "sel-1", Key: "uk-range"}, {ID: "sel-2", Key: "sale"}}
+	return New(repo, channels, selections), repo
+}
+
+func updateStore(svc *Service, st *domain.Store, actions string) (*domain.Store, error) {
+	in := UpdateInput{Version: st.Version}
+	if err := json.Unmarshal([]byte(actions), &in.Actions); err != nil {
+		return nil, err
+	}
+	return svc.Update(context.Background(), "proj", st.ID, in)
+}
+
+func TestServiceCreateNormalizesLocales(t *testing.T) {
+	svc, repo := newTestService()
+	ctx := context.Background()
+
+	st, err := svc.Create(ctx, "proj", StoreDraft{
+		Key:       "uk",
+		Languages: []string{" en-GB ", "cy"},
+		Countries: []StoreCountry{{Code: " gb"}, {Code: "im"}},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !reflect.DeepEqual(st.Languages, []string{"en-GB", "cy"}) || !reflect.DeepEqual(st.Countries, []string{"GB", "IM"}) {
+		t.Fatalf("expected trimmed languages and upper-case countries, got %+v", st)
+	}
+
+	for _, tc := range []struct {
+		draft StoreDraft
+		want  string
+	}{
+		{StoreDraft{Key: "u"}, "key must be 2 to 256 letters, digits, '-' or '_'"},
+		{StoreDraft{Key: "uk.shop"}, "key must be 2 to 256 letters, digits, '-' or '_'"},
+		{StoreDraft{Key: "eu", Languages: []string{"en", "EN"}}, "duplicate language EN"},
+		{StoreDraft{Key: "eu", Languages: []string{" "}}, "language must not be empty"},
+		{StoreDraft{Key: "eu", Countries: []StoreCountry{{Code: "GBR"}}}, `country "GBR" must be a two-letter code`},
+		{StoreDraft{Key: "eu", Countries: []StoreCountry{{Code: "de"}, {Code: "DE"}}}, "duplicate country DE"},
+	} {
+		if _, err := svc.Create(ctx, "proj", tc.draft); err == nil || err.Error() != tc.want {
+			t.Fatalf("%+v: expected %q, got %v", tc.draft, tc.want, err)
+		}
+	}
+	if repo.store.Key != "uk" {
+		t.Fatalf("expected only uk stored, got %+v", repo.store)
+	}
+}
+
+func TestServiceChannelsNeedTheirRole(t *testing.T) {
+	svc, _ := newTestService()
+	ctx := context.Background()
+
+	st, err := svc.Create(ctx, "proj", StoreDraft{
+		Key:                  "uk",
+		SupplyChannels:       []ResourceIdentifier{{Key: "uk-warehouse"}, {ID: "ch-3"}},
+		DistributionChannels: []ResourceIdentifier{{TypeID: "channel", Key: "uk-shop"}},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !reflect.DeepEqual(st.SupplyChannelIDs, []string{"ch-1", "ch-3"}) || !reflect.DeepEqual(st.DistributionChannelIDs, []string{"ch-3"}) {
+		t.Fatalf("unexpected channels %+v", st)
+	}
+	for _, tc := range []struct {
+		draft StoreDraft
+		want  string
+	}{
+		{StoreDraft{Key: "eu", SupplyChannels: []ResourceIdentifier{{Key: "uk-web"}}}, "channel uk-web lacks the InventorySupply role"},
+		{StoreDraft{Key: "eu", DistributionChannels: []ResourceIdentifier{{Key: "uk-warehouse"}}}, "channel uk-warehouse lacks the ProductDistribution role"},
+		{StoreDraft{Key: "eu", DistributionChannels: []ResourceIdentifier{{ID: "nope"}}}, "channel not found"},
+		{StoreDraft{Key: "eu", SupplyChannels: []ResourceIdentifier{{Key: "uk-warehouse"}, {ID: "ch-1"}}}, "duplicate channel ch-1"},
+	} {
+		if _, err := svc.Create(ctx, "proj", tc.draft); err == nil || err.Error() != tc.want {
+			t.Fatalf("expected %q, got %v", tc.want, err)
+		}
+	}
+
+	// The same channel can supply and distribute; each list checks its
+	// own role.
+	st, err = updateStore(svc, st, `[
+		{"action":"removeSupplyChannel","supplyChannel":{"key":"uk-shop"}},
+		{"action":"addDistributionChannel","distributionChannel":{"id":"ch-2"}}
+	]`)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if !reflect.DeepEqual(st.SupplyChannelIDs, []string{"ch-1"}) || !reflect.DeepEqual(st.DistributionChannelIDs, []string{"ch-3", "ch-2"}) {
+		t.Fatalf("unexpected channels after update %+v", st)
+	}
+	for _, tc := range []struct {
+		actions string
+		want    string
+	}{
+		{`[{"action":"addSupplyChannel","supplyChannel":{"key":"uk-web"}}]`, "channel uk-web lacks the InventorySupply role"},
+		{`[{"action":"removeDistributionChannel","distributionChannel":{"key":"uk-warehouse"}}]`, "channel uk-warehouse lacks the ProductDistribution role"},
+		{`[{"action":"removeSupplyChannel","supplyChannel":{"key":"uk-shop"}}]`, "channel ch-3 not in store"},
+		{`[{"action":"setSupplyChannels","supplyChannels":[{"id":"ch-3"},{"id":"ch-3"}]}]`, "duplicate channel ch-3"},
+	} {
+		if _, err := updateStore(svc, st, tc.actions); err == nil || err.Error() != tc.want {
+			t.Fatalf("%s: expected %q, got %v", tc.actions, tc.want, err)
+		}
+	}
+}
+
+func TestServiceProductSelectionActions(t *testing.T) {
+	svc, repo := newTestService()
+	st, err := svc.Create(context.Background(), "proj", StoreDraft{
+		Key:               "uk",
+		ProductSelections: []ProductSelectionSettingDraft{{ProductSelection: ResourceIdentifier{Key: "uk-range"}}},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	st, err = updateStore(svc, st, `[
+		{"action":"changeProductSelectionActive","productSelection":{"key":"uk-range"},"active":true},
+		{"action":"addProductSelection","productSelection":{"id":"sel-2"}}
+	]`)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	want := []domain.StoreProductSelection{{ProductSelectionID: "sel-1", Active: true}, {ProductSelectionID: "sel-2"}}
+	if !reflect.DeepEqual(st.ProductSelections, want) {
+		t.Fatalf("expected %+v, got %+v", want, st.ProductSelections)
+	}
+
+	for _, tc := range []struct {
+		actions string
+		want    string
+	}{
+		{`[{"action":"addProductSelection","productSelection":{"key":"sale"},"active":true}]`, "duplicate product selection sel-2"},
+		{`[{"action":"removeProductSelection","productSelection":{"id":"sel-2"}},{"action":"changeProductSelectionActive","productSelection":{"id":"sel-2"},"active":true}]`, "product selection sel-2 not in store"},
+		{`[{"action":"addProductSelection","productSelection":{"key":"winter"}}]`, "product selection not found"},
+		{`[{"action":"addProductSelection","productSelection":{"typeId":"product-selection"}}]`, "product selection id or key required"},
+	} {
+		if _, err := updateStore(svc, st, tc.actions); err == nil || err.Error() != tc.want {
+			t.Fatalf("%s: expected %q, got %v", tc.actions, tc.want, err)
+		}
+	}
+	if !reflect.DeepEqual(repo.store.ProductSelections, want) {
+		t.Fatalf("expected failed updates not to be stored, got %+v", repo.store.ProductSelections)
+	}
+
+	st, err = updateStore(svc, st, `[{"action":"setProductSelections","productSelections":[]}]`)
+	if err != nil || len(st.ProductSelections) != 0 {
+		t.Fatalf("expected no product selections, got %+v, %v", st, err)
+	}
+}
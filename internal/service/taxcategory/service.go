@@ -0,0 +1,215 @@
+package taxcategory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"commercetools-replica/internal/domain"
+	taxcategoryrepo "commercetools-replica/internal/repository/taxcategory"
+	"commercetools-replica/internal/service/updateaction"
+)
+
+type Service struct {
+	repo taxcategoryrepo.Repository
+}
+
+func New(repo taxcategoryrepo.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// ListPage returns one page of tax categories, oldest first; limit 0 means no limit.
+func (s *Service) ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.TaxCategory, int, error) {
+	return s.repo.List(ctx, projectID, limit, offset)
+}
+
+func (s *Service) Get(ctx context.Context, projectID, id string) (*domain.TaxCategory, error) {
+	return s.repo.GetByID(ctx, projectID, id)
+}
+
+func (s *Service) GetByKey(ctx context.Context, projectID, key string) (*domain.TaxCategory, error) {
+	return s.repo.GetByKey(ctx, projectID, key)
+}
+
+type TaxCategoryDraft struct {
+	Key         string           `json:"key,omitempty"`
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Rates       []domain.TaxRate `json:"rates,omitempty"`
+}
+
+func (s *Service) Create(ctx context.Context, projectID string, draft TaxCategoryDraft) (*domain.TaxCategory, error) {
+	c := domain.TaxCategory{
+		ProjectID:   projectID,
+		Key:         strings.TrimSpace(draft.Key),
+		Name:        strings.TrimSpace(draft.Name),
+		Description: strings.TrimSpace(draft.Description),
+	}
+	for _, r := range draft.Rates {
+		r.ID = ""
+		c.Rates = append(c.Rates, normalizeRate(r))
+	}
+	if err := validate(c); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, c)
+}
+
+type UpdateInput struct {
+	Version int            `json:"version"`
+	Actions []UpdateAction `json:"actions"`
+}
+
+type UpdateAction = updateaction.Action
+
+// Update applies the actions to the stored tax category if in.Version is still current.
+func (s *Service) Update(ctx context.Context, projectID, id string, in UpdateInput) (*domain.TaxCategory, error) {
+	if len(in.Actions) == 0 {
+		return nil, errors.New("actions required")
+	}
+	if in.Version <= 0 {
+		return nil, errors.New("version required")
+	}
+	c, err := s.repo.GetByID(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	for _, action := range in.Actions {
+		if err := apply(c, action); err != nil {
+			return nil, err
+		}
+	}
+	if err := validate(*c); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, *c)
+}
+
+func (s *Service) Delete(ctx context.Context, projectID, id string, version int) (*domain.TaxCategory, error) {
+	if version <= 0 {
+		return nil, errors.New("version required")
+	}
+	return s.repo.Delete(ctx, projectID, id, version)
+}
+
+func apply(c *domain.TaxCategory, action UpdateAction) error {
+	switch strings.ToLower(strings.TrimSpace(action.Action)) {
+	case "changename":
+		var a struct {
+			Name string `json:"name"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		c.Name = strings.TrimSpace(a.Name)
+	case "setdescription":
+		var a struct {
+			Description string `json:"description"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		c.Description = strings.TrimSpace(a.Description)
+	case "setkey":
+		var a struct {
+			Key string `json:"key"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		c.Key = strings.TrimSpace(a.Key)
+	case "addtaxrate":
+		var a struct {
+			TaxRate domain.TaxRate `json:"taxRate"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		a.TaxRate.ID = ""
+		c.Rates = append(c.Rates, normalizeRate(a.TaxRate))
+	case "removetaxrate":
+		var a struct {
+			TaxRateID string `json:"taxRateId"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		i := rateIndex(*c, a.TaxRateID)
+		if i < 0 {
+			return fmt.Errorf("tax rate %s not found", a.TaxRateID)
+		}
+		c.Rates = append(c.Rates[:i], c.Rates[i+1:]...)
+	case "replacetaxrate":
+		var a struct {
+			TaxRateID string         `json:"taxRateId"`
+			TaxRate   domain.TaxRate `json:"taxRate"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		i := rateIndex(*c, a.TaxRateID)
+		if i < 0 {
+			return fmt.Errorf("tax rate %s not found", a.TaxRateID)
+		}
+		// The replacement gets a new id like in commercetools.
+		a.TaxRate.ID = ""
+		c.Rates[i] = normalizeRate(a.TaxRate)
+	default:
+		return fmt.Errorf("unsupported action %q", action.Action)
+	}
+	return nil
+}
+
+func validate(c domain.TaxCategory) error {
+	if c.Name == "" {
+		return errors.New("name required")
+	}
+	seen := map[string]struct{}{}
+	for _, r := range c.Rates {
+		if r.Name == "" {
+			return errors.New("tax rate name required")
+		}
+		if len(r.Country) != 2 {
+			return fmt.Errorf("tax rate %q: country must be a two-letter code", r.Name)
+		}
+		if r.Amount < 0 || r.Amount > 1 {
+			return fmt.Errorf("tax rate %q: amount must be between 0 and 1", r.Name)
+		}
+		if len(r.SubRates) > 0 {
+			var sum float64
+			for _, sub := range r.SubRates {
+				sum += sub.Amount
+			}
+			if math.Abs(sum-r.Amount) > 1e-9 {
+				return fmt.Errorf("tax rate %q: sub rates must add up to the amount", r.Name)
+			}
+		}
+		where := r.Country + "/" + r.State
+		if _, dup := seen[where]; dup {
+			return fmt.Errorf("duplicate tax rate for %s", strings.TrimSuffix(where, "/"))
+		}
+		seen[where] = struct{}{}
+	}
+	return nil
+}
+
+func normalizeRate(r domain.TaxRate) domain.TaxRate {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Country = strings.ToUpper(strings.TrimSpace(r.Country))
+	r.State = strings.TrimSpace(r.State)
+	return r
+}
+
+func rateIndex(c domain.TaxCategory, id string) int {
+	for i, r := range c.Rates {
+		if r.ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,180 @@
+package taxcategory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"commercetools-replica/internal/domain"
+	taxcategoryrepo "commercetools-replica/internal/repository/taxcategory"
+)
+
+// rateRepo stores one tax category and numbers the rates without an id
+// like the postgres repository assigns them.
+type rateRepo struct {
+	taxcategoryrepo.Repository
+	stored *domain.TaxCategory
+	nextID int
+}
+
+func (r *rateRepo) assignRateIDs(rates []domain.TaxRate) {
+	for i := range rates {
+		if rates[i].ID == "" {
+			r.nextID++
+			rates[i].ID = fmt.Sprintf("rate-%d", r.nextID)
+		}
+	}
+}
+
+func (r *rateRepo) GetByID(_ context.Context, _, id string) (*domain.TaxCategory, error) {
+	if r.stored == nil || r.stored.ID != id {
+		return nil, domain.ErrNotFound
+	}
+	c := *r.stored
+	c.Rates = append([]domain.TaxRate(nil), c.Rates...)
+	return &c, nil
+}
+
+func (r *rateRepo) Create(_ context.Context, c domain.TaxCategory) (*domain.TaxCategory, error) {
+	c.ID, c.Version = "tc-1", 1
+	r.assignRateIDs(c.Rates)
+	r.stored = &c
+	return &c, nil
+}
+
+func (r *rateRepo) Update(_ context.Context, c domain.TaxCategory) (*domain.TaxCategory, error) {
+	c.Version++
+	r.assignRateIDs(c.Rates)
+	r.stored = &c
+	return &c, nil
+}
+
+func standardDraft() TaxCategoryDraft {
+	return TaxCategoryDraft{
+		Key:  "standard",
+		Name: "Standard",
+		Rates: []domain.TaxRate{
+			{Name: " 19% MwSt ", Amount: 0.19, IncludedInPrice: true, Country: "de"},
+			{Name: "CA sales tax", Amount: 0.0725, Country: "US", State: "CA"},
+		},
+	}
+}
+
+func TestServiceCreateValidatesRates(t *testing.T) {
+	repo := &rateRepo{}
+	svc := New(repo)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		mutate func(*TaxCategoryDraft)
+		want   string
+	}{
+		{func(d *TaxCategoryDraft) { d.Rates[0].Name = " " }, "tax rate name required"},
+		{func(d *TaxCategoryDraft) { d.Rates[0].Country = "DEU" }, `tax rate "19% MwSt": country must be a two-letter code`},
+		{func(d *TaxCategoryDraft) { d.Rates[0].Amount = 19 }, `tax rate "19% MwSt": amount must be between 0 and 1`},
+		{func(d *TaxCategoryDraft) { d.Rates[0].Amount = -0.01 }, `tax rate "19% MwSt": amount must be between 0 and 1`},
+		{func(d *TaxCategoryDraft) {
+			d.Rates[1].SubRates = []domain.TaxSubRate{{Name: "state", Amount: 0.06}}
+		}, `tax rate "CA sales tax": sub rates must add up to the amount`},
+		{func(d *TaxCategoryDraft) {
+			d.Rates = append(d.Rates, domain.TaxRate{Name: "again", Amount: 0.07, Country: " de"})
+		}, "duplicate tax rate for DE"},
+		{func(d *TaxCategoryDraft) {
+			d.Rates = append(d.Rates, domain.TaxRate{Name: "again", Amount: 0.07, Country: "US", State: "CA"})
+		}, "duplicate tax rate for US/CA"},
+	} {
+		draft := standardDraft()
+		tc.mutate(&draft)
+		if _, err := svc.Create(ctx, "proj", draft); err == nil || err.Error() != tc.want {
+			t.Fatalf("expected %q, got %v", tc.want, err)
+		}
+	}
+	if repo.stored != nil {
+		t.Fatalf("expected nothing stored, got %+v", repo.stored)
+	}
+
+	// Sub rates that add up are fine, and a country rate may sit next to
+	// the rate of one of its states.
+	draft := standardDraft()
+	draft.Rates[1].SubRates = []domain.TaxSubRate{{Name: "state", Amount: 0.06}, {Name: "county", Amount: 0.0125}}
+	draft.Rates = append(draft.Rates, domain.TaxRate{Name: "US", Amount: 0, Country: "US"})
+	c, err := svc.Create(ctx, "proj", draft)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if r := c.RateFor("DE", ""); r == nil || r.Name != "19% MwSt" || r.Country != "DE" {
+		t.Fatalf("expected a normalized DE rate, got %+v", r)
+	}
+}
+
+func TestServiceTaxRateActions(t *testing.T) {
+	repo := &rateRepo{}
+	svc := New(repo)
+	ctx := context.Background()
+	c, err := svc.Create(ctx, "proj", standardDraft())
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	de, ca := c.RateFor("DE", "").ID, c.RateFor("US", "CA").ID
+
+	var in UpdateInput
+	body := fmt.Sprintf(`{"version":1,"actions":[
+		{"action":"replaceTaxRate","taxRateId":%q,"taxRate":{"id":%q,"name":"7%% MwSt","amount":0.07,"includedInPrice":true,"country":"de"}},
+		{"action":"addTaxRate","taxRate":{"id":%q,"name":"5.5%% TVA","amount":0.055,"includedInPrice":true,"country":"fr"}}
+	]}`, de, de, ca)
+	if err := json.Unmarshal([]byte(body), &in); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	c, err = svc.Update(ctx, "proj", c.ID, in)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	// Replaced and added rates get new ids whatever the draft says.
+	if r := c.RateFor("DE", ""); r == nil || r.Amount != 0.07 || r.ID == de || r.ID == "" {
+		t.Fatalf("expected the replaced DE rate under a new id, got %+v", r)
+	}
+	if r := c.RateFor("FR", ""); r == nil || r.ID == ca || r.ID == "" {
+		t.Fatalf("expected the added FR rate under a new id, got %+v", r)
+	}
+	if r := c.RateFor("US", "CA"); r == nil || r.ID != ca {
+		t.Fatalf("expected the CA rate to keep its id, got %+v", r)
+	}
+
+	for _, actions := range []string{
+		fmt.Sprintf(`[{"action":"removeTaxRate","taxRateId":%q}]`, de),
+		fmt.Sprintf(`[{"action":"replaceTaxRate","taxRateId":%q,"taxRate":{"name":"x","amount":0.1,"country":"DE"}}]`, de),
+	} {
+		in := UpdateInput{Version: c.Version}
+		if err := json.Unmarshal([]byte(actions), &in.Actions); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if _, err := svc.Update(ctx, "proj", c.ID, in); err == nil || err.Error() != "tax rate "+de+" not found" {
+			t.Fatalf("%s: expected the old id to be gone, got %v", actions, err)
+		}
+	}
+
+	in = UpdateInput{Version: c.Version}
+	if err := json.Unmarshal([]byte(fmt.Sprintf(`[{"action":"removeTaxRate","taxRateId":%q}]`, ca)), &in.Actions); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if c, err = svc.Update(ctx, "proj", c.ID, in); err != nil || len(c.Rates) != 2 || c.RateFor("US", "CA") != nil {
+		t.Fatalf("expected the CA rate removed, got %+v, %v", c, err)
+	}
+}
+
+func TestRateFor(t *testing.T) {
+	c := domain.TaxCategory{Rates: []domain.TaxRate{
+		{Name: "US", Amount: 0.05, Country: "US"},
+		{Name: "CA", Amount: 0.0725, Country: "US", State: "CA"},
+	}}
+	if r := c.RateFor("us", "CA"); r == nil || r.Name != "CA" {
+		t.Fatalf("expected state rate, got %+v", r)
+	}
+	if r := c.RateFor("US", "NY"); r == nil || r.Name != "US" {
+		t.Fatalf("expected country rate, got %+v", r)
+	}
+	if r := c.RateFor("DE", ""); r != nil {
+		t.Fatalf("expected no rate, got %+v", r)
+	}
+}
@@ -0,0 +1,38 @@
+package updateaction
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Action is one entry of the actions of an update request. It keeps the raw
+// payload so each action can decode its own fields; services name it
+// UpdateAction.
+type Action struct {
+	Action string
+	raw    json.RawMessage
+}
+
+func (a *Action) UnmarshalJSON(b []byte) error {
+	var head struct {
+		Action string `json:"action"`
+	}
+	if err := json.Unmarshal(b, &head); err != nil {
+		return err
+	}
+	a.Action = head.Action
+	a.raw = append(json.RawMessage(nil), b...)
+	return nil
+}
+
+// Decode unmarshals the fields of the action into v; an action built in Go
+// without a payload leaves v alone.
+func (a Action) Decode(v interface{}) error {
+	if len(a.raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(a.raw, v); err != nil {
+		return fmt.Errorf("invalid %s action: %w", a.Action, err)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package updateaction
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestActionDecode(t *testing.T) {
+	var actions []Action
+	if err := json.Unmarshal([]byte(`[{"action":"changeName","name":"Shirt"},{"action":"setKey","key":7}]`), &actions); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(actions) != 2 || actions[0].Action != "changeName" {
+		t.Fatalf("unexpected actions %+v", actions)
+	}
+
+	var name struct {
+		Name string `json:"name"`
+	}
+	if err := actions[0].Decode(&name); err != nil || name.Name != "Shirt" {
+		t.Fatalf("expected the name, got %q, %v", name.Name, err)
+	}
+	var key struct {
+		Key string `json:"key"`
+	}
+	if err := actions[1].Decode(&key); err == nil || !strings.HasPrefix(err.Error(), "invalid setKey action: ") {
+		t.Fatalf("expected the action in the error, got %v", err)
+	}
+	if err := (Action{Action: "publish"}).Decode(&key); err != nil {
+		t.Fatalf("expected an action without payload to decode nothing, got %v", err)
+	}
+}
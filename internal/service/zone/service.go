@@ -0,0 +1,188 @@
+package zone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"commercetools-replica/internal/domain"
+	zonerepo "commercetools-replica/internal/repository/zone"
+	"commercetools-replica/internal/service/updateaction"
+)
+
+type Service struct {
+	repo zonerepo.Repository
+}
+
+func New(repo zonerepo.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// ListPage returns one page of zones, oldest first; limit 0 means no limit.
+func (s *Service) ListPage(ctx context.Context, projectID string, limit, offset int) ([]domain.Zone, int, error) {
+	return s.repo.List(ctx, projectID, limit, offset)
+}
+
+func (s *Service) Get(ctx context.Context, projectID, id string) (*domain.Zone, error) {
+	return s.repo.GetByID(ctx, projectID, id)
+}
+
+func (s *Service) GetByKey(ctx context.Context, projectID, key string) (*domain.Zone, error) {
+	return s.repo.GetByKey(ctx, projectID, key)
+}
+
+type ZoneDraft struct {
+	Key         string            `json:"key,omitempty"`
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Locations   []domain.Location `json:"locations,omitempty"`
+}
+
+func (s *Service) Create(ctx context.Context, projectID string, draft ZoneDraft) (*domain.Zone, error) {
+	z := domain.Zone{
+		ProjectID:   projectID,
+		Key:         strings.TrimSpace(draft.Key),
+		Name:        strings.TrimSpace(draft.Name),
+		Description: strings.TrimSpace(draft.Description),
+	}
+	for _, l := range draft.Locations {
+		z.Locations = append(z.Locations, normalizeLocation(l))
+	}
+	if err := validate(z); err != nil {
+		return nil, err
+	}
+	return s.repo.Create(ctx, z)
+}
+
+type UpdateInput struct {
+	Version int            `json:"version"`
+	Actions []UpdateAction `json:"actions"`
+}
+
+type UpdateAction = updateaction.Action
+
+// Update applies the actions to the stored zone if in.Version is still current.
+func (s *Service) Update(ctx context.Context, projectID, id string, in UpdateInput) (*domain.Zone, error) {
+	if len(in.Actions) == 0 {
+		return nil, errors.New("actions required")
+	}
+	if in.Version <= 0 {
+		return nil, errors.New("version required")
+	}
+	z, err := s.repo.GetByID(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if z.Version != in.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	for _, action := range in.Actions {
+		if err := apply(z, action); err != nil {
+			return nil, err
+		}
+	}
+	if err := validate(*z); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, *z)
+}
+
+func (s *Service) Delete(ctx context.Context, projectID, id string, version int) (*domain.Zone, error) {
+	if version <= 0 {
+		return nil, errors.New("version required")
+	}
+	return s.repo.Delete(ctx, projectID, id, version)
+}
+
+func apply(z *domain.Zone, action UpdateAction) error {
+	switch strings.ToLower(strings.TrimSpace(action.Action)) {
+	case "changename":
+		var a struct {
+			Name string `json:"name"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		z.Name = strings.TrimSpace(a.Name)
+	case "setdescription":
+		var a struct {
+			Description string `json:"description"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		z.Description = strings.TrimSpace(a.Description)
+	case "setkey":
+		var a struct {
+			Key string `json:"key"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		z.Key = strings.TrimSpace(a.Key)
+	case "addlocation":
+		var a struct {
+			Location domain.Location `json:"location"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		z.Locations = append(z.Locations, normalizeLocation(a.Location))
+	case "removelocation":
+		var a struct {
+			Location domain.Location `json:"location"`
+		}
+		if err := action.Decode(&a); err != nil {
+			return err
+		}
+		l := normalizeLocation(a.Location)
+		i := locationIndex(*z, l)
+		if i < 0 {
+			return fmt.Errorf("location %s not found", locationName(l))
+		}
+		z.Locations = append(z.Locations[:i], z.Locations[i+1:]...)
+	default:
+		return fmt.Errorf("unsupported action %q", action.Action)
+	}
+	return nil
+}
+
+func validate(z domain.Zone) error {
+	if z.Name == "" {
+		return errors.New("name required")
+	}
+	seen := map[domain.Location]struct{}{}
+	for _, l := range z.Locations {
+		if len(l.Country) != 2 {
+			return fmt.Errorf("location %q: country must be a two-letter code", locationName(l))
+		}
+		if _, dup := seen[l]; dup {
+			return fmt.Errorf("duplicate location %s", locationName(l))
+		}
+		seen[l] = struct{}{}
+	}
+	return nil
+}
+
+func normalizeLocation(l domain.Location) domain.Location {
+	l.Country = strings.ToUpper(strings.TrimSpace(l.Country))
+	l.State = strings.TrimSpace(l.State)
+	return l
+}
+
+func locationIndex(z domain.Zone, l domain.Location) int {
+	for i, existing := range z.Locations {
+		if existing == l {
+			return i
+		}
+	}
+	return -1
+}
+
+func locationName(l domain.Location) string {
+	if l.State == "" {
+		return l.Country
+	}
+	return l.Country + "/" + l.State
+}
@@ -0,0 +1,208 @@
+package zone
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"commercetools-replica/internal/domain"
+)
+
+// memoryRepo keeps zones by id and checks versions like the postgres
+// repository.
+type memoryRepo struct {
+	byID map[string]domain.Zone
+}
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{byID: make(map[string]domain.Zone)}
+}
+
+func (r *memoryRepo) List(_ context.Context, projectID string, _, _ int) ([]domain.Zone, int, error) {
+	var out []domain.Zone
+	for _, z := range r.byID {
+		if z.ProjectID == projectID {
+			out = append(out, z)
+		}
+	}
+	return out, len(out), nil
+}
+
+func (r *memoryRepo) GetByID(_ context.Context, projectID, id string) (*domain.Zone, error) {
+	z, ok := r.byID[id]
+	if !ok || z.ProjectID != projectID {
+		return nil, domain.ErrNotFound
+	}
+	z.Locations = append([]domain.Location(nil), z.Locations...)
+	return &z, nil
+}
+
+func (r *memoryRepo) GetByKey(ctx context.Context, projectID, key string) (*domain.Zone, error) {
+	for _, z := range r.byID {
+		if z.ProjectID == projectID && z.Key == key {
+			return r.GetByID(ctx, projectID, z.ID)
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (r *memoryRepo) Create(_ context.Context, z domain.Zone) (*domain.Zone, error) {
+	z.ID, z.Version = "zone-"+z.Key, 1
+	r.byID[z.ID] = z
+	return &z, nil
+}
+
+func (r *memoryRepo) Update(_ context.Context, z domain.Zone) (*domain.Zone, error) {
+	stored, ok := r.byID[z.ID]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	if stored.Version != z.Version {
+		return nil, domain.ErrConcurrentModification
+	}
+	z.Version++
+	r.byID[z.ID] = z
+	return &z, nil
+}
+
+func (r *memoryRepo) Delete(_ context.Context, projectID, id string, version int) (*domain.Zone, error) {
+	z, ok := r.byID[id]
+	if !ok || z.ProjectID != projectID {
+		return nil, domain.ErrNotFound
+	}
+	if z.Version != version {
+		return nil, domain.ErrConcurrentModification
+	}
+	delete(r.byID, id)
+	return &z, nil
+}
+
+func createEurope(t *testing.T, svc *Service) *domain.Zone {
+	t.Helper()
+	z, err := svc.Create(context.Background(), "proj", ZoneDraft{
+		Key:       "europe",
+		Name:      "Europe",
+		Locations: []domain.Location{{Country: "DE"}, {Country: "US", State: "CA"}},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	return z
+}
+
+func update(svc *Service, z *domain.Zone, actions string) (*domain.Zone, error) {
+	var in UpdateInput
+	in.Version = z.Version
+	if err := json.Unmarshal([]byte(`{"actions":`+actions+`}`), &in); err != nil {
+		return nil, err
+	}
+	return svc.Update(context.Background(), z.ProjectID, z.ID, in)
+}
+
+func TestServiceCreateNormalizesLocations(t *testing.T) {
+	svc := New(newMemoryRepo())
+
+	z, err := svc.Create(context.Background(), "proj", ZoneDraft{
+		Key:       " dach ",
+		Name:      " DACH ",
+		Locations: []domain.Location{{Country: " de"}, {Country: "at", State: " Tirol "}},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if z.Key != "dach" || z.Name != "DACH" {
+		t.Fatalf("expected trimmed key and name, got %q %q", z.Key, z.Name)
+	}
+	want := []domain.Location{{Country: "DE"}, {Country: "AT", State: "Tirol"}}
+	if len(z.Locations) != len(want) || z.Locations[0] != want[0] || z.Locations[1] != want[1] {
+		t.Fatalf("expected %+v, got %+v", want, z.Locations)
+	}
+}
+
+func TestServiceCreateRejectsBadLocations(t *testing.T) {
+	repo := newMemoryRepo()
+	svc := New(repo)
+
+	for _, tc := range []struct {
+		locations []domain.Location
+		want      string
+	}{
+		{[]domain.Location{{Country: "DEU"}}, `location "DEU": country must be a two-letter code`},
+		{[]domain.Location{{State: "CA"}}, `location "/CA": country must be a two-letter code`},
+		{[]domain.Location{{Country: "de"}, {Country: "DE "}}, "duplicate location DE"},
+		{[]domain.Location{{Country: "US", State: "CA"}, {Country: "us", State: "CA"}}, "duplicate location US/CA"},
+	} {
+		_, err := svc.Create(context.Background(), "proj", ZoneDraft{Name: "Zone", Locations: tc.locations})
+		if err == nil || err.Error() != tc.want {
+			t.Fatalf("%+v: expected %q, got %v", tc.locations, tc.want, err)
+		}
+	}
+	if len(repo.byID) != 0 {
+		t.Fatalf("expected no zone stored, got %+v", repo.byID)
+	}
+
+	// A country and one of its states are different locations.
+	if _, err := svc.Create(context.Background(), "proj", ZoneDraft{Name: "US", Locations: []domain.Location{{Country: "US"}, {Country: "US", State: "CA"}}}); err != nil {
+		t.Fatalf("expected a country and its state to be allowed together, got %v", err)
+	}
+}
+
+func TestServiceUpdateLocations(t *testing.T) {
+	repo := newMemoryRepo()
+	svc := New(repo)
+	z := createEurope(t, svc)
+
+	z, err := update(svc, z, `[
+		{"action":"addLocation","location":{"country":" ch "}},
+		{"action":"removeLocation","location":{"country":"de"}},
+		{"action":"removeLocation","location":{"country":"US","state":" CA "}}
+	]`)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if z.Version != 2 || len(z.Locations) != 1 || z.Locations[0] != (domain.Location{Country: "CH"}) {
+		t.Fatalf("expected only CH at version 2, got %+v", z)
+	}
+
+	// Removing a country does not remove its states, and a failing action
+	// drops the whole update.
+	for _, tc := range []struct {
+		actions string
+		want    string
+	}{
+		{`[{"action":"addLocation","location":{"country":"US","state":"NY"}},{"action":"removeLocation","location":{"country":"US"}}]`, "location US not found"},
+		{`[{"action":"addLocation","location":{"country":"FR"}},{"action":"addLocation","location":{"country":"ch"}}]`, "duplicate location CH"},
+		{`[{"action":"changeName","name":" "}]`, "name required"},
+	} {
+		if _, err := update(svc, z, tc.actions); err == nil || err.Error() != tc.want {
+			t.Fatalf("%s: expected %q, got %v", tc.actions, tc.want, err)
+		}
+	}
+	if stored := repo.byID[z.ID]; stored.Version != 2 || len(stored.Locations) != 1 || stored.Name != "Europe" {
+		t.Fatalf("expected failed updates to leave the zone alone, got %+v", stored)
+	}
+
+	stale := *z
+	stale.Version = 1
+	if _, err := update(svc, &stale, `[{"action":"setKey","key":"eu"}]`); !errors.Is(err, domain.ErrConcurrentModification) {
+		t.Fatalf("expected ErrConcurrentModification, got %v", err)
+	}
+}
+
+func TestZoneContains(t *testing.T) {
+	zones := []domain.Zone{
+		{ID: "us", Locations: []domain.Location{{Country: "US"}}},
+		{ID: "ca", Locations: []domain.Location{{Country: "US", State: "CA"}}},
+		{ID: "eu", Locations: []domain.Location{{Country: "DE"}}},
+	}
+	if ids := domain.ZonesContaining(zones, "us", "CA"); len(ids) != 2 || ids[0] != "us" || ids[1] != "ca" {
+		t.Fatalf("expected country and state zones, got %v", ids)
+	}
+	if ids := domain.ZonesContaining(zones, "US", "NY"); len(ids) != 1 || ids[0] != "us" {
+		t.Fatalf("expected the country zone, got %v", ids)
+	}
+	if ids := domain.ZonesContaining(zones, "FR", ""); len(ids) != 0 {
+		t.Fatalf("expected no zone, got %v", ids)
+	}
+}